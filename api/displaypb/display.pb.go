@@ -0,0 +1,1225 @@
+// gRPC API of the QNAP display service. Other services on the NAS use it to
+// push screens to the front panel, switch its LEDs and follow its buttons.
+//
+// The Go code in displaypb is generated from this file with
+// "make proto"; other languages generate their own, e.g. for Python:
+//
+//   python -m grpc_tools.protoc -I api --python_out=. --grpc_python_out=. api/display.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: display.proto
+
+package displaypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Button is a front panel button
+type Button int32
+
+const (
+	Button_BUTTON_UNSPECIFIED Button = 0
+	Button_BUTTON_ENTER       Button = 1
+	Button_BUTTON_SELECT      Button = 2
+	Button_BUTTON_COPY        Button = 3
+)
+
+// Enum value maps for Button.
+var (
+	Button_name = map[int32]string{
+		0: "BUTTON_UNSPECIFIED",
+		1: "BUTTON_ENTER",
+		2: "BUTTON_SELECT",
+		3: "BUTTON_COPY",
+	}
+	Button_value = map[string]int32{
+		"BUTTON_UNSPECIFIED": 0,
+		"BUTTON_ENTER":       1,
+		"BUTTON_SELECT":      2,
+		"BUTTON_COPY":        3,
+	}
+)
+
+func (x Button) Enum() *Button {
+	p := new(Button)
+	*p = x
+	return p
+}
+
+func (x Button) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Button) Descriptor() protoreflect.EnumDescriptor {
+	return file_display_proto_enumTypes[0].Descriptor()
+}
+
+func (Button) Type() protoreflect.EnumType {
+	return &file_display_proto_enumTypes[0]
+}
+
+func (x Button) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Button.Descriptor instead.
+func (Button) EnumDescriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{0}
+}
+
+// LED is a front panel LED
+type LED int32
+
+const (
+	LED_LED_UNSPECIFIED  LED = 0
+	LED_LED_STATUS_GREEN LED = 1
+	LED_LED_STATUS_RED   LED = 2
+	LED_LED_USB          LED = 3
+	LED_LED_DISK1        LED = 4
+	LED_LED_DISK2        LED = 5
+	LED_LED_DISK3        LED = 6
+	LED_LED_DISK4        LED = 7
+	LED_LED_DISK5        LED = 8
+	LED_LED_DISK6        LED = 9
+)
+
+// Enum value maps for LED.
+var (
+	LED_name = map[int32]string{
+		0: "LED_UNSPECIFIED",
+		1: "LED_STATUS_GREEN",
+		2: "LED_STATUS_RED",
+		3: "LED_USB",
+		4: "LED_DISK1",
+		5: "LED_DISK2",
+		6: "LED_DISK3",
+		7: "LED_DISK4",
+		8: "LED_DISK5",
+		9: "LED_DISK6",
+	}
+	LED_value = map[string]int32{
+		"LED_UNSPECIFIED":  0,
+		"LED_STATUS_GREEN": 1,
+		"LED_STATUS_RED":   2,
+		"LED_USB":          3,
+		"LED_DISK1":        4,
+		"LED_DISK2":        5,
+		"LED_DISK3":        6,
+		"LED_DISK4":        7,
+		"LED_DISK5":        8,
+		"LED_DISK6":        9,
+	}
+)
+
+func (x LED) Enum() *LED {
+	p := new(LED)
+	*p = x
+	return p
+}
+
+func (x LED) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LED) Descriptor() protoreflect.EnumDescriptor {
+	return file_display_proto_enumTypes[1].Descriptor()
+}
+
+func (LED) Type() protoreflect.EnumType {
+	return &file_display_proto_enumTypes[1]
+}
+
+func (x LED) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LED.Descriptor instead.
+func (LED) EnumDescriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{1}
+}
+
+type ShowScreenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Lines are shown one per panel row; extra lines are cut off
+	Lines []string `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	// DurationSec is how long the screen is shown; 0 shows it until a button
+	// is pressed or ClearScreen is called
+	DurationSec uint32 `protobuf:"varint,2,opt,name=duration_sec,json=durationSec,proto3" json:"duration_sec,omitempty"`
+	// CaptureButtons keeps the screen up on button presses and keeps them
+	// from the menu, for clients that follow StreamButtons to run their own
+	// screens
+	CaptureButtons bool `protobuf:"varint,3,opt,name=capture_buttons,json=captureButtons,proto3" json:"capture_buttons,omitempty"`
+}
+
+func (x *ShowScreenRequest) Reset() {
+	*x = ShowScreenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShowScreenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShowScreenRequest) ProtoMessage() {}
+
+func (x *ShowScreenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShowScreenRequest.ProtoReflect.Descriptor instead.
+func (*ShowScreenRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShowScreenRequest) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *ShowScreenRequest) GetDurationSec() uint32 {
+	if x != nil {
+		return x.DurationSec
+	}
+	return 0
+}
+
+func (x *ShowScreenRequest) GetCaptureButtons() bool {
+	if x != nil {
+		return x.CaptureButtons
+	}
+	return false
+}
+
+type ShowScreenResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ShowScreenResponse) Reset() {
+	*x = ShowScreenResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShowScreenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShowScreenResponse) ProtoMessage() {}
+
+func (x *ShowScreenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShowScreenResponse.ProtoReflect.Descriptor instead.
+func (*ShowScreenResponse) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{1}
+}
+
+type ClearScreenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ClearScreenRequest) Reset() {
+	*x = ClearScreenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearScreenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearScreenRequest) ProtoMessage() {}
+
+func (x *ClearScreenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearScreenRequest.ProtoReflect.Descriptor instead.
+func (*ClearScreenRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{2}
+}
+
+type ClearScreenResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ClearScreenResponse) Reset() {
+	*x = ClearScreenResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearScreenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearScreenResponse) ProtoMessage() {}
+
+func (x *ClearScreenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearScreenResponse.ProtoReflect.Descriptor instead.
+func (*ClearScreenResponse) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{3}
+}
+
+type GetScreenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetScreenRequest) Reset() {
+	*x = GetScreenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetScreenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetScreenRequest) ProtoMessage() {}
+
+func (x *GetScreenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetScreenRequest.ProtoReflect.Descriptor instead.
+func (*GetScreenRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{4}
+}
+
+// Screen is what the panel shows
+type Screen struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Lines are the panel rows, padded to the display width
+	Lines []string `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+}
+
+func (x *Screen) Reset() {
+	*x = Screen{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Screen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Screen) ProtoMessage() {}
+
+func (x *Screen) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Screen.ProtoReflect.Descriptor instead.
+func (*Screen) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Screen) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+type SetBacklightRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	On bool `protobuf:"varint,1,opt,name=on,proto3" json:"on,omitempty"`
+}
+
+func (x *SetBacklightRequest) Reset() {
+	*x = SetBacklightRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetBacklightRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBacklightRequest) ProtoMessage() {}
+
+func (x *SetBacklightRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBacklightRequest.ProtoReflect.Descriptor instead.
+func (*SetBacklightRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SetBacklightRequest) GetOn() bool {
+	if x != nil {
+		return x.On
+	}
+	return false
+}
+
+type SetBacklightResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetBacklightResponse) Reset() {
+	*x = SetBacklightResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetBacklightResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBacklightResponse) ProtoMessage() {}
+
+func (x *SetBacklightResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBacklightResponse.ProtoReflect.Descriptor instead.
+func (*SetBacklightResponse) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{7}
+}
+
+type SetLEDRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Led LED  `protobuf:"varint,1,opt,name=led,proto3,enum=qnap.display.v1.LED" json:"led,omitempty"`
+	On  bool `protobuf:"varint,2,opt,name=on,proto3" json:"on,omitempty"`
+}
+
+func (x *SetLEDRequest) Reset() {
+	*x = SetLEDRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetLEDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLEDRequest) ProtoMessage() {}
+
+func (x *SetLEDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLEDRequest.ProtoReflect.Descriptor instead.
+func (*SetLEDRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetLEDRequest) GetLed() LED {
+	if x != nil {
+		return x.Led
+	}
+	return LED_LED_UNSPECIFIED
+}
+
+func (x *SetLEDRequest) GetOn() bool {
+	if x != nil {
+		return x.On
+	}
+	return false
+}
+
+type SetLEDResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetLEDResponse) Reset() {
+	*x = SetLEDResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetLEDResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLEDResponse) ProtoMessage() {}
+
+func (x *SetLEDResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLEDResponse.ProtoReflect.Descriptor instead.
+func (*SetLEDResponse) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{9}
+}
+
+type GetLEDsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetLEDsRequest) Reset() {
+	*x = GetLEDsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLEDsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLEDsRequest) ProtoMessage() {}
+
+func (x *GetLEDsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLEDsRequest.ProtoReflect.Descriptor instead.
+func (*GetLEDsRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{10}
+}
+
+type GetLEDsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Leds []*LEDState `protobuf:"bytes,1,rep,name=leds,proto3" json:"leds,omitempty"`
+}
+
+func (x *GetLEDsResponse) Reset() {
+	*x = GetLEDsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLEDsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLEDsResponse) ProtoMessage() {}
+
+func (x *GetLEDsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLEDsResponse.ProtoReflect.Descriptor instead.
+func (*GetLEDsResponse) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetLEDsResponse) GetLeds() []*LEDState {
+	if x != nil {
+		return x.Leds
+	}
+	return nil
+}
+
+// LEDState is whether an LED is on
+type LEDState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Led LED  `protobuf:"varint,1,opt,name=led,proto3,enum=qnap.display.v1.LED" json:"led,omitempty"`
+	On  bool `protobuf:"varint,2,opt,name=on,proto3" json:"on,omitempty"`
+}
+
+func (x *LEDState) Reset() {
+	*x = LEDState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LEDState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LEDState) ProtoMessage() {}
+
+func (x *LEDState) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LEDState.ProtoReflect.Descriptor instead.
+func (*LEDState) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LEDState) GetLed() LED {
+	if x != nil {
+		return x.Led
+	}
+	return LED_LED_UNSPECIFIED
+}
+
+func (x *LEDState) GetOn() bool {
+	if x != nil {
+		return x.On
+	}
+	return false
+}
+
+type StreamButtonsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StreamButtonsRequest) Reset() {
+	*x = StreamButtonsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamButtonsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamButtonsRequest) ProtoMessage() {}
+
+func (x *StreamButtonsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamButtonsRequest.ProtoReflect.Descriptor instead.
+func (*StreamButtonsRequest) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{13}
+}
+
+// ButtonEvent is a button pressed or released
+type ButtonEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Button  Button                 `protobuf:"varint,1,opt,name=button,proto3,enum=qnap.display.v1.Button" json:"button,omitempty"`
+	Pressed bool                   `protobuf:"varint,2,opt,name=pressed,proto3" json:"pressed,omitempty"`
+	Time    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+}
+
+func (x *ButtonEvent) Reset() {
+	*x = ButtonEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_display_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ButtonEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ButtonEvent) ProtoMessage() {}
+
+func (x *ButtonEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_display_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ButtonEvent.ProtoReflect.Descriptor instead.
+func (*ButtonEvent) Descriptor() ([]byte, []int) {
+	return file_display_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ButtonEvent) GetButton() Button {
+	if x != nil {
+		return x.Button
+	}
+	return Button_BUTTON_UNSPECIFIED
+}
+
+func (x *ButtonEvent) GetPressed() bool {
+	if x != nil {
+		return x.Pressed
+	}
+	return false
+}
+
+func (x *ButtonEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_display_proto protoreflect.FileDescriptor
+
+var file_display_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x0f, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31,
+	0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0x75, 0x0a, 0x11, 0x53, 0x68, 0x6f, 0x77, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x0c,
+	0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x0b, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x63, 0x12,
+	0x27, 0x0a, 0x0f, 0x63, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x5f, 0x62, 0x75, 0x74, 0x74, 0x6f,
+	0x6e, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x63, 0x61, 0x70, 0x74, 0x75, 0x72,
+	0x65, 0x42, 0x75, 0x74, 0x74, 0x6f, 0x6e, 0x73, 0x22, 0x14, 0x0a, 0x12, 0x53, 0x68, 0x6f, 0x77,
+	0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x14,
+	0x0a, 0x12, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x22, 0x15, 0x0a, 0x13, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x53, 0x63, 0x72,
+	0x65, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x12, 0x0a, 0x10, 0x47,
+	0x65, 0x74, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22,
+	0x1e, 0x0a, 0x06, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6e,
+	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x22,
+	0x25, 0x0a, 0x13, 0x53, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x02, 0x6f, 0x6e, 0x22, 0x16, 0x0a, 0x14, 0x53, 0x65, 0x74, 0x42, 0x61, 0x63,
+	0x6b, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x47,
+	0x0a, 0x0d, 0x53, 0x65, 0x74, 0x4c, 0x45, 0x44, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x26, 0x0a, 0x03, 0x6c, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x71,
+	0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x45, 0x44, 0x52, 0x03, 0x6c, 0x65, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6e, 0x22, 0x10, 0x0a, 0x0e, 0x53, 0x65, 0x74, 0x4c, 0x45,
+	0x44, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x10, 0x0a, 0x0e, 0x47, 0x65, 0x74,
+	0x4c, 0x45, 0x44, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x40, 0x0a, 0x0f, 0x47,
+	0x65, 0x74, 0x4c, 0x45, 0x44, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d,
+	0x0a, 0x04, 0x6c, 0x65, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x71,
+	0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x45, 0x44, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x04, 0x6c, 0x65, 0x64, 0x73, 0x22, 0x42, 0x0a,
+	0x08, 0x4c, 0x45, 0x44, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x26, 0x0a, 0x03, 0x6c, 0x65, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69,
+	0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x45, 0x44, 0x52, 0x03, 0x6c, 0x65,
+	0x64, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x6f,
+	0x6e, 0x22, 0x16, 0x0a, 0x14, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x42, 0x75, 0x74, 0x74, 0x6f,
+	0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x88, 0x01, 0x0a, 0x0b, 0x42, 0x75,
+	0x74, 0x74, 0x6f, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x2f, 0x0a, 0x06, 0x62, 0x75, 0x74,
+	0x74, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x71, 0x6e, 0x61, 0x70,
+	0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x75, 0x74, 0x74,
+	0x6f, 0x6e, 0x52, 0x06, 0x62, 0x75, 0x74, 0x74, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x65, 0x64, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04,
+	0x74, 0x69, 0x6d, 0x65, 0x2a, 0x56, 0x0a, 0x06, 0x42, 0x75, 0x74, 0x74, 0x6f, 0x6e, 0x12, 0x16,
+	0x0a, 0x12, 0x42, 0x55, 0x54, 0x54, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49,
+	0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x42, 0x55, 0x54, 0x54, 0x4f, 0x4e,
+	0x5f, 0x45, 0x4e, 0x54, 0x45, 0x52, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x42, 0x55, 0x54, 0x54,
+	0x4f, 0x4e, 0x5f, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x42,
+	0x55, 0x54, 0x54, 0x4f, 0x4e, 0x5f, 0x43, 0x4f, 0x50, 0x59, 0x10, 0x03, 0x2a, 0xab, 0x01, 0x0a,
+	0x03, 0x4c, 0x45, 0x44, 0x12, 0x13, 0x0a, 0x0f, 0x4c, 0x45, 0x44, 0x5f, 0x55, 0x4e, 0x53, 0x50,
+	0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x4c, 0x45, 0x44,
+	0x5f, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x47, 0x52, 0x45, 0x45, 0x4e, 0x10, 0x01, 0x12,
+	0x12, 0x0a, 0x0e, 0x4c, 0x45, 0x44, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x52, 0x45,
+	0x44, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x4c, 0x45, 0x44, 0x5f, 0x55, 0x53, 0x42, 0x10, 0x03,
+	0x12, 0x0d, 0x0a, 0x09, 0x4c, 0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x4b, 0x31, 0x10, 0x04, 0x12,
+	0x0d, 0x0a, 0x09, 0x4c, 0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x4b, 0x32, 0x10, 0x05, 0x12, 0x0d,
+	0x0a, 0x09, 0x4c, 0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x4b, 0x33, 0x10, 0x06, 0x12, 0x0d, 0x0a,
+	0x09, 0x4c, 0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x4b, 0x34, 0x10, 0x07, 0x12, 0x0d, 0x0a, 0x09,
+	0x4c, 0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x4b, 0x35, 0x10, 0x08, 0x12, 0x0d, 0x0a, 0x09, 0x4c,
+	0x45, 0x44, 0x5f, 0x44, 0x49, 0x53, 0x4b, 0x36, 0x10, 0x09, 0x32, 0xd1, 0x04, 0x0a, 0x07, 0x44,
+	0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x12, 0x55, 0x0a, 0x0a, 0x53, 0x68, 0x6f, 0x77, 0x53, 0x63,
+	0x72, 0x65, 0x65, 0x6e, 0x12, 0x22, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70,
+	0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x68, 0x6f, 0x77, 0x53, 0x63, 0x72, 0x65, 0x65,
+	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e,
+	0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x68, 0x6f, 0x77, 0x53,
+	0x63, 0x72, 0x65, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x58, 0x0a,
+	0x0b, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x12, 0x23, 0x2e, 0x71,
+	0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x43,
+	0x6c, 0x65, 0x61, 0x72, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x24, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79,
+	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x47, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x53, 0x63,
+	0x72, 0x65, 0x65, 0x6e, 0x12, 0x21, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70,
+	0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64,
+	0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e,
+	0x12, 0x5b, 0x0a, 0x0c, 0x53, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x6c, 0x69, 0x67, 0x68, 0x74,
+	0x12, 0x24, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e,
+	0x76, 0x31, 0x2e, 0x53, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69,
+	0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b,
+	0x6c, 0x69, 0x67, 0x68, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x49, 0x0a,
+	0x06, 0x53, 0x65, 0x74, 0x4c, 0x45, 0x44, 0x12, 0x1e, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64,
+	0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x74, 0x4c, 0x45, 0x44,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64,
+	0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x74, 0x4c, 0x45, 0x44,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x4c,
+	0x45, 0x44, 0x73, 0x12, 0x1f, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c,
+	0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x45, 0x44, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70,
+	0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x45, 0x44, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x56, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x42, 0x75, 0x74, 0x74, 0x6f, 0x6e, 0x73, 0x12, 0x25, 0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64,
+	0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x42, 0x75, 0x74, 0x74, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c,
+	0x2e, 0x71, 0x6e, 0x61, 0x70, 0x2e, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2e, 0x76, 0x31,
+	0x2e, 0x42, 0x75, 0x74, 0x74, 0x6f, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x2f,
+	0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x71, 0x6e, 0x61,
+	0x70, 0x2f, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f,
+	0x6c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x70, 0x62, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_display_proto_rawDescOnce sync.Once
+	file_display_proto_rawDescData = file_display_proto_rawDesc
+)
+
+func file_display_proto_rawDescGZIP() []byte {
+	file_display_proto_rawDescOnce.Do(func() {
+		file_display_proto_rawDescData = protoimpl.X.CompressGZIP(file_display_proto_rawDescData)
+	})
+	return file_display_proto_rawDescData
+}
+
+var file_display_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_display_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_display_proto_goTypes = []any{
+	(Button)(0),                   // 0: qnap.display.v1.Button
+	(LED)(0),                      // 1: qnap.display.v1.LED
+	(*ShowScreenRequest)(nil),     // 2: qnap.display.v1.ShowScreenRequest
+	(*ShowScreenResponse)(nil),    // 3: qnap.display.v1.ShowScreenResponse
+	(*ClearScreenRequest)(nil),    // 4: qnap.display.v1.ClearScreenRequest
+	(*ClearScreenResponse)(nil),   // 5: qnap.display.v1.ClearScreenResponse
+	(*GetScreenRequest)(nil),      // 6: qnap.display.v1.GetScreenRequest
+	(*Screen)(nil),                // 7: qnap.display.v1.Screen
+	(*SetBacklightRequest)(nil),   // 8: qnap.display.v1.SetBacklightRequest
+	(*SetBacklightResponse)(nil),  // 9: qnap.display.v1.SetBacklightResponse
+	(*SetLEDRequest)(nil),         // 10: qnap.display.v1.SetLEDRequest
+	(*SetLEDResponse)(nil),        // 11: qnap.display.v1.SetLEDResponse
+	(*GetLEDsRequest)(nil),        // 12: qnap.display.v1.GetLEDsRequest
+	(*GetLEDsResponse)(nil),       // 13: qnap.display.v1.GetLEDsResponse
+	(*LEDState)(nil),              // 14: qnap.display.v1.LEDState
+	(*StreamButtonsRequest)(nil),  // 15: qnap.display.v1.StreamButtonsRequest
+	(*ButtonEvent)(nil),           // 16: qnap.display.v1.ButtonEvent
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
+}
+var file_display_proto_depIdxs = []int32{
+	1,  // 0: qnap.display.v1.SetLEDRequest.led:type_name -> qnap.display.v1.LED
+	14, // 1: qnap.display.v1.GetLEDsResponse.leds:type_name -> qnap.display.v1.LEDState
+	1,  // 2: qnap.display.v1.LEDState.led:type_name -> qnap.display.v1.LED
+	0,  // 3: qnap.display.v1.ButtonEvent.button:type_name -> qnap.display.v1.Button
+	17, // 4: qnap.display.v1.ButtonEvent.time:type_name -> google.protobuf.Timestamp
+	2,  // 5: qnap.display.v1.Display.ShowScreen:input_type -> qnap.display.v1.ShowScreenRequest
+	4,  // 6: qnap.display.v1.Display.ClearScreen:input_type -> qnap.display.v1.ClearScreenRequest
+	6,  // 7: qnap.display.v1.Display.GetScreen:input_type -> qnap.display.v1.GetScreenRequest
+	8,  // 8: qnap.display.v1.Display.SetBacklight:input_type -> qnap.display.v1.SetBacklightRequest
+	10, // 9: qnap.display.v1.Display.SetLED:input_type -> qnap.display.v1.SetLEDRequest
+	12, // 10: qnap.display.v1.Display.GetLEDs:input_type -> qnap.display.v1.GetLEDsRequest
+	15, // 11: qnap.display.v1.Display.StreamButtons:input_type -> qnap.display.v1.StreamButtonsRequest
+	3,  // 12: qnap.display.v1.Display.ShowScreen:output_type -> qnap.display.v1.ShowScreenResponse
+	5,  // 13: qnap.display.v1.Display.ClearScreen:output_type -> qnap.display.v1.ClearScreenResponse
+	7,  // 14: qnap.display.v1.Display.GetScreen:output_type -> qnap.display.v1.Screen
+	9,  // 15: qnap.display.v1.Display.SetBacklight:output_type -> qnap.display.v1.SetBacklightResponse
+	11, // 16: qnap.display.v1.Display.SetLED:output_type -> qnap.display.v1.SetLEDResponse
+	13, // 17: qnap.display.v1.Display.GetLEDs:output_type -> qnap.display.v1.GetLEDsResponse
+	16, // 18: qnap.display.v1.Display.StreamButtons:output_type -> qnap.display.v1.ButtonEvent
+	12, // [12:19] is the sub-list for method output_type
+	5,  // [5:12] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_display_proto_init() }
+func file_display_proto_init() {
+	if File_display_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_display_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ShowScreenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ShowScreenResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*ClearScreenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*ClearScreenResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*GetScreenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*Screen); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*SetBacklightRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*SetBacklightResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*SetLEDRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*SetLEDResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*GetLEDsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*GetLEDsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*LEDState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*StreamButtonsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_display_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*ButtonEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_display_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_display_proto_goTypes,
+		DependencyIndexes: file_display_proto_depIdxs,
+		EnumInfos:         file_display_proto_enumTypes,
+		MessageInfos:      file_display_proto_msgTypes,
+	}.Build()
+	File_display_proto = out.File
+	file_display_proto_rawDesc = nil
+	file_display_proto_goTypes = nil
+	file_display_proto_depIdxs = nil
+}
@@ -0,0 +1,385 @@
+// gRPC API of the QNAP display service. Other services on the NAS use it to
+// push screens to the front panel, switch its LEDs and follow its buttons.
+//
+// The Go code in displaypb is generated from this file with
+// "make proto"; other languages generate their own, e.g. for Python:
+//
+//   python -m grpc_tools.protoc -I api --python_out=. --grpc_python_out=. api/display.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: display.proto
+
+package displaypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Display_ShowScreen_FullMethodName    = "/qnap.display.v1.Display/ShowScreen"
+	Display_ClearScreen_FullMethodName   = "/qnap.display.v1.Display/ClearScreen"
+	Display_GetScreen_FullMethodName     = "/qnap.display.v1.Display/GetScreen"
+	Display_SetBacklight_FullMethodName  = "/qnap.display.v1.Display/SetBacklight"
+	Display_SetLED_FullMethodName        = "/qnap.display.v1.Display/SetLED"
+	Display_GetLEDs_FullMethodName       = "/qnap.display.v1.Display/GetLEDs"
+	Display_StreamButtons_FullMethodName = "/qnap.display.v1.Display/StreamButtons"
+)
+
+// DisplayClient is the client API for Display service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Display is the front panel of the NAS
+type DisplayClient interface {
+	// ShowScreen shows lines above the menu until the duration is up, a
+	// button is pressed or ClearScreen is called. A new screen replaces the
+	// one shown.
+	ShowScreen(ctx context.Context, in *ShowScreenRequest, opts ...grpc.CallOption) (*ShowScreenResponse, error)
+	// ClearScreen removes the shown screen, if any
+	ClearScreen(ctx context.Context, in *ClearScreenRequest, opts ...grpc.CallOption) (*ClearScreenResponse, error)
+	// GetScreen returns what the panel shows, whoever drew it
+	GetScreen(ctx context.Context, in *GetScreenRequest, opts ...grpc.CallOption) (*Screen, error)
+	// SetBacklight switches the backlight
+	SetBacklight(ctx context.Context, in *SetBacklightRequest, opts ...grpc.CallOption) (*SetBacklightResponse, error)
+	// SetLED switches one LED
+	SetLED(ctx context.Context, in *SetLEDRequest, opts ...grpc.CallOption) (*SetLEDResponse, error)
+	// GetLEDs returns the state of every LED
+	GetLEDs(ctx context.Context, in *GetLEDsRequest, opts ...grpc.CallOption) (*GetLEDsResponse, error)
+	// StreamButtons sends every button press and release until the client
+	// cancels the call
+	StreamButtons(ctx context.Context, in *StreamButtonsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ButtonEvent], error)
+}
+
+type displayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDisplayClient(cc grpc.ClientConnInterface) DisplayClient {
+	return &displayClient{cc}
+}
+
+func (c *displayClient) ShowScreen(ctx context.Context, in *ShowScreenRequest, opts ...grpc.CallOption) (*ShowScreenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShowScreenResponse)
+	err := c.cc.Invoke(ctx, Display_ShowScreen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *displayClient) ClearScreen(ctx context.Context, in *ClearScreenRequest, opts ...grpc.CallOption) (*ClearScreenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClearScreenResponse)
+	err := c.cc.Invoke(ctx, Display_ClearScreen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *displayClient) GetScreen(ctx context.Context, in *GetScreenRequest, opts ...grpc.CallOption) (*Screen, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Screen)
+	err := c.cc.Invoke(ctx, Display_GetScreen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *displayClient) SetBacklight(ctx context.Context, in *SetBacklightRequest, opts ...grpc.CallOption) (*SetBacklightResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetBacklightResponse)
+	err := c.cc.Invoke(ctx, Display_SetBacklight_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *displayClient) SetLED(ctx context.Context, in *SetLEDRequest, opts ...grpc.CallOption) (*SetLEDResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLEDResponse)
+	err := c.cc.Invoke(ctx, Display_SetLED_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *displayClient) GetLEDs(ctx context.Context, in *GetLEDsRequest, opts ...grpc.CallOption) (*GetLEDsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLEDsResponse)
+	err := c.cc.Invoke(ctx, Display_GetLEDs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *displayClient) StreamButtons(ctx context.Context, in *StreamButtonsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ButtonEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Display_ServiceDesc.Streams[0], Display_StreamButtons_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamButtonsRequest, ButtonEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Display_StreamButtonsClient = grpc.ServerStreamingClient[ButtonEvent]
+
+// DisplayServer is the server API for Display service.
+// All implementations must embed UnimplementedDisplayServer
+// for forward compatibility.
+//
+// Display is the front panel of the NAS
+type DisplayServer interface {
+	// ShowScreen shows lines above the menu until the duration is up, a
+	// button is pressed or ClearScreen is called. A new screen replaces the
+	// one shown.
+	ShowScreen(context.Context, *ShowScreenRequest) (*ShowScreenResponse, error)
+	// ClearScreen removes the shown screen, if any
+	ClearScreen(context.Context, *ClearScreenRequest) (*ClearScreenResponse, error)
+	// GetScreen returns what the panel shows, whoever drew it
+	GetScreen(context.Context, *GetScreenRequest) (*Screen, error)
+	// SetBacklight switches the backlight
+	SetBacklight(context.Context, *SetBacklightRequest) (*SetBacklightResponse, error)
+	// SetLED switches one LED
+	SetLED(context.Context, *SetLEDRequest) (*SetLEDResponse, error)
+	// GetLEDs returns the state of every LED
+	GetLEDs(context.Context, *GetLEDsRequest) (*GetLEDsResponse, error)
+	// StreamButtons sends every button press and release until the client
+	// cancels the call
+	StreamButtons(*StreamButtonsRequest, grpc.ServerStreamingServer[ButtonEvent]) error
+	mustEmbedUnimplementedDisplayServer()
+}
+
+// UnimplementedDisplayServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDisplayServer struct{}
+
+func (UnimplementedDisplayServer) ShowScreen(context.Context, *ShowScreenRequest) (*ShowScreenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShowScreen not implemented")
+}
+func (UnimplementedDisplayServer) ClearScreen(context.Context, *ClearScreenRequest) (*ClearScreenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearScreen not implemented")
+}
+func (UnimplementedDisplayServer) GetScreen(context.Context, *GetScreenRequest) (*Screen, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScreen not implemented")
+}
+func (UnimplementedDisplayServer) SetBacklight(context.Context, *SetBacklightRequest) (*SetBacklightResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBacklight not implemented")
+}
+func (UnimplementedDisplayServer) SetLED(context.Context, *SetLEDRequest) (*SetLEDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLED not implemented")
+}
+func (UnimplementedDisplayServer) GetLEDs(context.Context, *GetLEDsRequest) (*GetLEDsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLEDs not implemented")
+}
+func (UnimplementedDisplayServer) StreamButtons(*StreamButtonsRequest, grpc.ServerStreamingServer[ButtonEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamButtons not implemented")
+}
+func (UnimplementedDisplayServer) mustEmbedUnimplementedDisplayServer() {}
+func (UnimplementedDisplayServer) testEmbeddedByValue()                 {}
+
+// UnsafeDisplayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DisplayServer will
+// result in compilation errors.
+type UnsafeDisplayServer interface {
+	mustEmbedUnimplementedDisplayServer()
+}
+
+func RegisterDisplayServer(s grpc.ServiceRegistrar, srv DisplayServer) {
+	// If the following call pancis, it indicates UnimplementedDisplayServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Display_ServiceDesc, srv)
+}
+
+func _Display_ShowScreen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShowScreenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DisplayServer).ShowScreen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Display_ShowScreen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DisplayServer).ShowScreen(ctx, req.(*ShowScreenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Display_ClearScreen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearScreenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DisplayServer).ClearScreen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Display_ClearScreen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DisplayServer).ClearScreen(ctx, req.(*ClearScreenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Display_GetScreen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScreenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DisplayServer).GetScreen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Display_GetScreen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DisplayServer).GetScreen(ctx, req.(*GetScreenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Display_SetBacklight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBacklightRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DisplayServer).SetBacklight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Display_SetBacklight_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DisplayServer).SetBacklight(ctx, req.(*SetBacklightRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Display_SetLED_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLEDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DisplayServer).SetLED(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Display_SetLED_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DisplayServer).SetLED(ctx, req.(*SetLEDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Display_GetLEDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLEDsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DisplayServer).GetLEDs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Display_GetLEDs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DisplayServer).GetLEDs(ctx, req.(*GetLEDsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Display_StreamButtons_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamButtonsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DisplayServer).StreamButtons(m, &grpc.GenericServerStream[StreamButtonsRequest, ButtonEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Display_StreamButtonsServer = grpc.ServerStreamingServer[ButtonEvent]
+
+// Display_ServiceDesc is the grpc.ServiceDesc for Display service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Display_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "qnap.display.v1.Display",
+	HandlerType: (*DisplayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ShowScreen",
+			Handler:    _Display_ShowScreen_Handler,
+		},
+		{
+			MethodName: "ClearScreen",
+			Handler:    _Display_ClearScreen_Handler,
+		},
+		{
+			MethodName: "GetScreen",
+			Handler:    _Display_GetScreen_Handler,
+		},
+		{
+			MethodName: "SetBacklight",
+			Handler:    _Display_SetBacklight_Handler,
+		},
+		{
+			MethodName: "SetLED",
+			Handler:    _Display_SetLED_Handler,
+		},
+		{
+			MethodName: "GetLEDs",
+			Handler:    _Display_GetLEDs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamButtons",
+			Handler:       _Display_StreamButtons_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "display.proto",
+}
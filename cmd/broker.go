@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/qnap/display-control/internal/broker"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// brokerCommandName is the hidden subcommand the privileged helper runs as
+const brokerCommandName = "broker"
+
+// newBrokerCommand creates the hidden subcommand the service starts as its
+// privileged helper. It is not meant to be run by hand.
+func newBrokerCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:          brokerCommandName,
+		Short:        "Run privileged commands for the service (internal)",
+		Hidden:       true,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			setupLogging()
+			// The helper reads the allow-list from the config file itself
+			// rather than trusting the unprivileged service with it
+			cfg := loadConfiguration()
+			return broker.NewServer(privilegedCommands(cfg)).Serve(broker.HelperConn())
+		},
+	}
+}
+
+// startBroker starts the privileged helper if the service is going to drop
+// root and has commands that need it. It returns nil otherwise.
+func startBroker(cfg *config.Config) (*broker.Client, error) {
+	commands := privilegedCommands(cfg)
+	if cfg.Privileges.User == "" || len(commands) == 0 {
+		return nil, nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the executable for the privileged helper: %w", err)
+	}
+	args := []string{"--config", configFile}
+	if verbose {
+		args = append(args, "--verbose")
+	}
+	client, err := broker.Spawn(executable, append(args, brokerCommandName)...)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithField("commands", len(commands)).Info("Started privileged helper")
+	return client, nil
+}
+
+// privilegedCommands lists the commands the helper may run: menu commands
+// and the copy commands of usb_copy and its import profiles marked
+// privileged, the scrub, eject, port identify and smartctl commands if they
+// are, and the commands unlocking encrypted USB devices. Watch folder
+// commands react to files anyone with share access can drop, so they are
+// never privileged. Each command may only be given the variables it reads.
+func privilegedCommands(cfg *config.Config) []broker.Command {
+	var commands []broker.Command
+	allow := func(command string, env ...string) {
+		commands = append(commands, broker.Command{Command: command, Env: env})
+	}
+	var walk func(item config.MenuItem)
+	walk = func(item config.MenuItem) {
+		if item.Type == "command" && item.Privileged && item.Command != "" {
+			if item.Input != "" {
+				allow(item.Command, "INPUT")
+			} else {
+				allow(item.Command)
+			}
+		}
+		for _, child := range item.Items {
+			walk(child)
+		}
+	}
+	walk(cfg.Menu.MainMenu)
+
+	if cfg.USBCopy.Privileged && cfg.USBCopy.Command != "" {
+		allow(cfg.USBCopy.Command)
+	}
+	if cfg.USBCopy.Privileged {
+		for _, profile := range cfg.USBCopy.Profiles {
+			if profile.Direction != config.CopyExport && profile.Command != "" {
+				allow(profile.Command)
+			}
+		}
+	}
+	if cfg.USBCopy.LUKS.Enabled && cfg.USBCopy.Source != "" {
+		encrypted := luksCommands(cfg)
+		allow(encrypted.Unlock, "DEVICE", "INPUT")
+		allow(encrypted.Mount)
+		allow(encrypted.Lock)
+	}
+	if cfg.Storage.ScrubPrivileged {
+		for _, command := range sysinfo.ScrubCommands() {
+			allow(command, "POOL")
+		}
+	}
+	if cfg.Storage.EjectPrivileged {
+		allow(sysinfo.EjectCommand, "MOUNT")
+	}
+	if cfg.Network.IdentifyPrivileged {
+		allow(sysinfo.IdentifyCommand(cfg.Network.IdentifySeconds), "PORT")
+	}
+	if cfg.SMART.Privileged && len(cfg.SMART.Drives) > 0 {
+		allow(sysinfo.SMARTCommand(), "DRIVE")
+	}
+	return commands
+}
+
+// runCopyCommand runs the copy command of an import, through the helper if
+// it is privileged and one is running
+func runCopyCommand(cfg *config.Config, command string, helper *broker.Client) ([]byte, error) {
+	if cfg.USBCopy.Privileged && helper != nil {
+		return helper.Run(command, nil)
+	}
+	return exec.Command("sh", "-c", command).CombinedOutput()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/qnap/display-control/internal/buzzer"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// usesBuzzer reports whether anything sounds the buzzer: the panel timer of
+// the menu or a rule that beeps
+func usesBuzzer(cfg *config.Config) bool {
+	if cfg.Menu.Enabled {
+		return true
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Beep > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// buzzerDevice returns the PC speaker device the service opens, or "" if it
+// opens none
+func buzzerDevice(cfg *config.Config) string {
+	if !usesBuzzer(cfg) || cfg.Buzzer.Command != "" {
+		return ""
+	}
+	if cfg.Buzzer.Device != "" {
+		return cfg.Buzzer.Device
+	}
+	return buzzer.DefaultDevice
+}
+
+// openBuzzer returns the buzzer the panel timer and the rules sound. It must
+// run before privileges are dropped. A device that cannot be opened is
+// logged and nil is returned; the timer then only shows when it ran out and
+// rules do not beep.
+func openBuzzer(cfg *config.Config) *buzzer.Buzzer {
+	if !usesBuzzer(cfg) {
+		return nil
+	}
+	if cfg.Buzzer.Command != "" {
+		return buzzer.Command(cfg.Buzzer.Command)
+	}
+
+	device := buzzerDevice(cfg)
+	b, err := buzzer.Open(device)
+	if err != nil {
+		logger := logrus.WithError(err)
+		// Boxes without the pcspkr driver have no device
+		if cfg.Buzzer.Device == "" {
+			logger.Debug("No buzzer")
+		} else {
+			logger.Warn("Failed to open buzzer")
+		}
+		return nil
+	}
+	logrus.WithField("device", device).Info("Buzzer opened")
+	return b
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/qnap/display-control/internal/alert"
+	"github.com/qnap/display-control/internal/cluster"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+)
+
+// peerStatusPrefix selects a peer's health as a status item, as in
+// "peer:nas2"
+const peerStatusPrefix = "peer:"
+
+// serverShutdownTimeout is how long requests in progress may take when the
+// service stops
+const serverShutdownTimeout = 2 * time.Second
+
+// newPeerMonitor creates a monitor for the configured peers, not yet polling,
+// or nil without peers
+func newPeerMonitor(cfg *config.Config) (*cluster.Monitor, error) {
+	if len(cfg.Cluster.Peers) == 0 {
+		return nil, nil
+	}
+
+	peers := make([]cluster.Peer, 0, len(cfg.Cluster.Peers))
+	seen := make(map[string]bool)
+	for _, peer := range cfg.Cluster.Peers {
+		if peer.Name == "" || peer.URL == "" {
+			return nil, fmt.Errorf("peer %q needs a name and a url", peer.Name)
+		}
+		if seen[peer.Name] {
+			return nil, fmt.Errorf("duplicate peer name %q", peer.Name)
+		}
+		seen[peer.Name] = true
+		peers = append(peers, cluster.Peer{Name: peer.Name, URL: peer.URL})
+	}
+	interval := time.Duration(cfg.Cluster.PollInterval) * time.Second
+	return cluster.NewMonitor(peers, cfg.Cluster.Token, interval), nil
+}
+
+// startHealthServer serves this node's health summary for the other nodes'
+// dashboards, its recent events and the live event stream. It returns a
+// function stopping the server, or nil when no listen address is configured.
+func startHealthServer(cfg *config.Config, alerts *alert.Manager, eventLog *events.Log) (func(), error) {
+	if cfg.Cluster.Listen == "" {
+		return nil, nil
+	}
+
+	host := sysinfo.NewHostProvider("")
+	storage := sysinfo.NewStorageProvider("", cfg.Storage.Volumes, 0)
+	collect := func() cluster.Health {
+		health := cluster.Health{Alerts: alerts.Active(), VolumePercent: -1}
+		health.Hostname, _ = os.Hostname()
+		if uptime, err := host.Uptime(); err == nil {
+			health.UptimeSec = int64(uptime.Seconds())
+		}
+		if load, err := host.LoadAverage(); err == nil {
+			health.Load = load
+		}
+		if volumes, err := storage.Volumes(); err == nil {
+			for _, volume := range volumes {
+				health.VolumePercent = max(health.VolumePercent, volume.Percent())
+			}
+		}
+		return health
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cluster.HealthPath, cluster.Handler(cfg.Cluster.Token, collect))
+	mux.Handle(events.Path, cluster.RequireToken(cfg.Cluster.Token, events.Handler(eventLog)))
+	mux.Handle(events.StreamPath, cluster.RequireQueryToken(cfg.Cluster.Token, events.StreamHandler(eventLog)))
+
+	server := &http.Server{
+		Addr:              cfg.Cluster.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for health requests: %w", err)
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.WithError(err).Error("Health API stopped")
+		}
+	}()
+	logrus.WithField("address", listener.Addr().String()).Info("Serving health API")
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
+
+// peerStatusItem shows a peer's health on one line
+func peerStatusItem(peers *cluster.Monitor, name string) func() (string, error) {
+	return func() (string, error) {
+		if peers == nil {
+			return "", fmt.Errorf("unknown peer %q", name)
+		}
+		status, err := peers.Status(name)
+		if err != nil {
+			return "", err
+		}
+		return status.Summary(), nil
+	}
+}
+
+// peerConfigured reports whether a peer of that name is configured
+func peerConfigured(cfg *config.Config, name string) bool {
+	for _, peer := range cfg.Cluster.Peers {
+		if peer.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// serialPortPatterns are the devices offered for --port
+var serialPortPatterns = []string{"/dev/ttyS*", "/dev/ttyUSB*", "/dev/ttyACM*"}
+
+// registerFlagCompletions completes the values of the global flags: config
+// files for --config and the serial ports present for --port
+func registerFlagCompletions(rootCmd *cobra.Command) {
+	rootCmd.MarkPersistentFlagFilename("config", "json")
+	rootCmd.RegisterFlagCompletionFunc("port", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var ports []string
+		for _, pattern := range serialPortPatterns {
+			matches, _ := filepath.Glob(pattern)
+			ports = append(ports, matches...)
+		}
+		return ports, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// completeArgs completes each positional argument from its own list of
+// values, and nothing past the last list
+func completeArgs(values ...[]string) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(values) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return values[len(args)], cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// ledNames returns the names the led commands take, e.g. "disk3"
+func ledNames() []string {
+	names := make([]string, 0, controller.Disk6-controller.StatusGreen+1)
+	for led := controller.StatusGreen; led <= controller.Disk6; led++ {
+		names = append(names, led.String())
+	}
+	return names
+}
+
+// completeMacros completes the first argument with the macros of the running
+// service, and nothing without one
+func completeMacros(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// Anything logged would end up among the completions
+	logrus.SetLevel(logrus.PanicLevel)
+	output, err := callService(loadConfiguration(), control.Request{Command: "macro_list"})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		// Lines are "name: buttons (gesture)"
+		if name, _, ok := strings.Cut(line, ": "); ok {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
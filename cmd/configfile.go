@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// newConfigCommand creates the "config" subcommand and its subcommands
+func newConfigCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Check a config file or write a commented default one",
+	}
+
+	var output string
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Write the default config with every setting explained",
+		Long: "Writes the default configuration with a comment above every setting, and lists and " +
+			"sections left empty showing what an entry looks like, commented out. The service " +
+			"reads the comments as blank, so the file can be edited and used as it is.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigGenerate(os.Stdout, output)
+		},
+	}
+	generate.Flags().StringVarP(&output, "output", "o", "", "File to write, which must not exist yet (default stdout)")
+	generate.MarkFlagFilename("output", "json")
+
+	command.AddCommand(
+		&cobra.Command{
+			Use:   "validate [FILE]",
+			Short: "Check a config file for mistakes",
+			Long: "Checks a config file, --config unless FILE is given, and lists keys the service " +
+				"does not know and would ignore, values of the wrong type, and settings it would " +
+				"reject such as unknown menu item types or an unsupported baud rate. It fails if " +
+				"anything was found.",
+			Args: cobra.MaximumNArgs(1),
+			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+			},
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				file := configFile
+				if len(args) == 1 {
+					file = args[0]
+				}
+				return runConfigValidate(os.Stdout, file)
+			},
+		},
+		generate,
+	)
+	return command
+}
+
+// runConfigValidate prints the problems of a config file, one per line
+func runConfigValidate(out io.Writer, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	problems, err := config.Validate(data)
+	if err != nil {
+		return fmt.Errorf("%s is not valid JSON: %w", file, err)
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(out, problem)
+	}
+	switch len(problems) {
+	case 0:
+		fmt.Fprintf(out, "%s is valid\n", file)
+		return nil
+	case 1:
+		return fmt.Errorf("1 problem in %s", file)
+	default:
+		return fmt.Errorf("%d problems in %s", len(problems), file)
+	}
+}
+
+// runConfigGenerate writes the commented default config to output, or out if
+// output is empty
+func runConfigGenerate(out io.Writer, output string) error {
+	var generated bytes.Buffer
+	if err := config.Generate(&generated, config.DefaultConfig()); err != nil {
+		return err
+	}
+	if output == "" {
+		_, err := out.Write(generated.Bytes())
+		return err
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(generated.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s\n", output)
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultMessageDuration is how long the service shows written text unless
+// the request says otherwise
+const defaultMessageDuration = 10 * time.Second
+
+// tokenEnv holds the token for "write --remote", so it stays out of the
+// process list
+const tokenEnv = "QNAP_DISPLAY_TOKEN"
+
+// controlSocket is the control socket's path from the configuration
+func controlSocket(cfg *config.Config) string {
+	if cfg.Control.Socket != "" {
+		return cfg.Control.Socket
+	}
+	return control.DefaultSocket
+}
+
+// listenControl creates the control socket, which has to happen while the
+// service is still root, and the TCP listener if configured. It returns nil
+// when the socket is disabled; requests are answered once serveControl is
+// called.
+func listenControl(cfg *config.Config) (*control.Server, error) {
+	if cfg.Control.Disabled {
+		return nil, nil
+	}
+	server, err := control.Listen(controlSocket(cfg))
+	if err != nil || cfg.Control.Listen == "" {
+		return server, err
+	}
+
+	// A broken TCP setup leaves the socket working
+	allow, err := control.ParseAllow(cfg.Control.Allow)
+	if err == nil {
+		err = server.ListenTCP(cfg.Control.Listen, control.Remote{Token: cfg.Control.Token, Allow: allow, Commands: cfg.Control.RemoteCommands})
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("Control over TCP disabled")
+	}
+	return server, nil
+}
+
+// serveControl answers requests on the control socket. Written text is
+// shown like a prompt without options: above everything but alerts, until a
+// button is pressed or its time is up. Progress bars are shown on the remote
+// layer of screens.
+func serveControl(server *control.Server, prompter *prompt.Prompter, screens *screen.ScreenManager) {
+	handleDisplay(server, prompter, screens)
+	server.Start()
+	logger := logrus.WithField("socket", server.Path())
+	if addr := server.Addr(); addr != nil {
+		logger = logger.WithField("tcp", addr.String())
+	}
+	logger.Info("Serving control socket")
+}
+
+// callService sends a request to the running service. The error wraps
+// control.ErrNoService when no service answers on the control socket.
+func callService(cfg *config.Config, request control.Request) (string, error) {
+	if cfg.Control.Disabled {
+		return "", control.ErrNoService
+	}
+	client, err := control.Dial(controlSocket(cfg))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	return client.Call(request)
+}
+
+// newWriteCommand creates the "write" subcommand
+func newWriteCommand() *cobra.Command {
+	var showFor time.Duration
+	var remotes []string
+	var token string
+
+	command := &cobra.Command{
+		Use:   "write TEXT...",
+		Short: "Show text on the panel",
+		Long: "Shows text on the front panel, each argument on a line of its own. With the service " +
+			"running the text is sent to it over its control socket and shown above the menu until " +
+			"a button is pressed or --duration is up. Without the service the panel is opened " +
+			"directly and the text stays until something else is written. With --remote the text " +
+			"is sent to the services on other hosts over TCP instead.",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(remotes) > 0 {
+				return runRemoteWrite(strings.Join(args, "\n"), showFor, remotes, token)
+			}
+			return runWrite(strings.Join(args, "\n"), showFor)
+		},
+	}
+	command.Flags().DurationVar(&showFor, "duration", defaultMessageDuration, "How long the service shows the text")
+	command.Flags().StringSliceVar(&remotes, "remote", nil, "Hosts to send the text to over TCP, e.g. nas1,nas2:9170")
+	command.Flags().StringVar(&token, "token", "", "The hosts' control token (default $"+tokenEnv+")")
+	return command
+}
+
+// runRemoteWrite sends text to the services on remotes and prints the
+// result for each. It fails if any of them did not show it.
+func runRemoteWrite(text string, showFor time.Duration, remotes []string, token string) error {
+	if showFor < time.Second {
+		return fmt.Errorf("--duration must be at least 1s")
+	}
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	if token == "" {
+		return fmt.Errorf("--token or $%s is needed for --remote", tokenEnv)
+	}
+	setupLogging()
+
+	request := control.Request{Command: "write", Text: text, Duration: int(showFor / time.Second)}
+	failed := 0
+	for _, remote := range remotes {
+		err := writeRemote(remote, token, request)
+		if err != nil {
+			failed++
+			fmt.Printf("%s: %v\n", remote, err)
+			continue
+		}
+		fmt.Printf("%s: shown\n", remote)
+	}
+	if failed > 0 {
+		return fmt.Errorf("text not shown on %d of %d hosts", failed, len(remotes))
+	}
+	return nil
+}
+
+// writeRemote sends a write request to one host
+func writeRemote(remote, token string, request control.Request) error {
+	client, err := control.DialTCP(remote, token)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = client.Call(request)
+	return err
+}
+
+// runWrite shows text through the service, or on the panel itself when the
+// service is not running
+func runWrite(text string, showFor time.Duration) error {
+	if showFor < time.Second {
+		return fmt.Errorf("--duration must be at least 1s")
+	}
+
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	_, err := callService(cfg, control.Request{
+		Command:  "write",
+		Text:     text,
+		Duration: int(showFor / time.Second),
+	})
+	if !errors.Is(err, control.ErrNoService) {
+		return err
+	}
+	logrus.Debug("Service not running, writing to the panel directly")
+
+	systemController, err := controller.NewSystemController(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open the display: %w", err)
+	}
+	defer systemController.Close()
+
+	if err := systemController.GetDisplayController().WriteText(text); err != nil {
+		return fmt.Errorf("failed to write text: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+)
+
+// copyCounter counts the copies of each USB device mounted at the copy
+// source, by its filesystem UUID
+type copyCounter struct {
+	source   string
+	storage  *sysinfo.StorageProvider
+	counters *state.CopyCounters
+}
+
+// copySource is a USB device about to be copied
+type copySource struct {
+	uuid string
+	size uint64
+}
+
+// stateFile is the state store's path from the configuration
+func stateFile(cfg *config.Config) string {
+	if cfg.StateFile != "" {
+		return cfg.StateFile
+	}
+	return state.DefaultPath
+}
+
+// keepsState reports whether anything configured keeps data in the state
+// store: copy counters, SMART samples, the button macros of the menu or the
+// LED states
+func keepsState(cfg *config.Config) bool {
+	return cfg.USBCopy.Source != "" || len(cfg.SMART.Drives) > 0 || cfg.Menu.Enabled || restoresLEDs(cfg)
+}
+
+// openStateStore opens the state store the service shares between its
+// users. It returns nil if nothing keeps state or the store cannot be read;
+// nothing is kept across restarts then.
+func openStateStore(cfg *config.Config) *state.Store {
+	if !keepsState(cfg) {
+		return nil
+	}
+	store, err := state.Open(stateFile(cfg))
+	if err != nil {
+		logrus.WithError(err).Warn("State store disabled, copy counters, SMART trends, macros and LEDs are not kept")
+		return nil
+	}
+	return store
+}
+
+// newCopyCounter keeps the counters in store. It returns nil when the copy
+// source is not configured or there is no store; copies are then not
+// counted.
+func newCopyCounter(cfg *config.Config, store *state.Store) *copyCounter {
+	if cfg.USBCopy.Source == "" || store == nil {
+		return nil
+	}
+	return &copyCounter{
+		source:   cfg.USBCopy.Source,
+		storage:  sysinfo.NewStorageProvider("", nil, 0),
+		counters: state.NewCopyCounters(store),
+	}
+}
+
+// identify finds the device at the copy source and how much data it holds
+func (c *copyCounter) identify() (copySource, error) {
+	uuid, err := c.storage.DeviceUUID(c.source)
+	if err != nil {
+		return copySource{}, err
+	}
+	size, err := sysinfo.DirSize(c.source)
+	if err != nil {
+		return copySource{}, err
+	}
+	return copySource{uuid: uuid, size: size}, nil
+}
+
+// record counts a successful copy of source and returns the summary shown
+// with the result
+func (c *copyCounter) record(source copySource, width int) (string, error) {
+	device, fresh, err := c.counters.Record(source.uuid, source.size)
+	if err != nil {
+		return "", err
+	}
+	logrus.WithFields(logrus.Fields{
+		"uuid":   source.uuid,
+		"copies": device.Copies,
+		"new":    fresh,
+		"total":  device.Bytes,
+	}).Info("Counted USB copy")
+	return state.CopySummary(device.Copies, fresh, width), nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/copyreport"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/qnap/display-control/internal/usbexport"
+	"github.com/sirupsen/logrus"
+)
+
+// copyChoiceTimeout is how long the panel waits for a profile or the
+// confirmation of a copy
+const copyChoiceTimeout = 10 * time.Second
+
+// progressAlternation is how long the second line of an export shows the
+// progress bar, or the throughput and time left, before switching
+const progressAlternation = 3 * time.Second
+
+// chooseCopyProfile asks on the panel which copy profile to run. Without
+// profiles the copy button runs usb_copy.command, after asking if confirm is
+// set. It returns false if the copy was cancelled or not confirmed.
+func chooseCopyProfile(cfg *config.Config, prompter *prompt.Prompter) (config.CopyProfile, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), copyChoiceTimeout)
+	defer cancel()
+
+	profiles := cfg.USBCopy.Profiles
+	if len(profiles) == 0 {
+		profile := config.CopyProfile{Name: "USB copy", Direction: config.CopyImport, Command: cfg.USBCopy.Command, Scan: cfg.USBCopy.Scan,
+			Destination: cfg.USBCopy.Destination, Report: cfg.USBCopy.Report}
+		if !cfg.USBCopy.Confirm {
+			return profile, true
+		}
+		choice, err := prompter.Prompt(ctx, "Start USB copy?", "No", "Yes")
+		return profile, err == nil && choice == 1
+	}
+
+	options := make([]string, 0, len(profiles)+1)
+	for _, profile := range profiles {
+		options = append(options, profile.Name)
+	}
+	choice, err := prompter.Prompt(ctx, "Copy profile?", append(options, "Cancel")...)
+	if err != nil || choice >= len(profiles) {
+		return config.CopyProfile{}, false
+	}
+	profile := profiles[choice]
+	if profile.Direction != config.CopyExport && profile.Command == "" {
+		profile.Command = cfg.USBCopy.Command
+	}
+	return profile, true
+}
+
+// exportSource is the directory a profile exports: its source, or the newest
+// snapshot below it
+func exportSource(profile config.CopyProfile) (string, error) {
+	if profile.Source == "" {
+		return "", errors.New("copy profile has no source")
+	}
+	if profile.LatestSnapshot {
+		return usbexport.LatestSnapshot(profile.Source)
+	}
+	return profile.Source, nil
+}
+
+// exportName is the directory an export creates on the USB device, e.g.
+// "Public-20240501-1200", so repeated exports do not collide
+func exportName(source string, now time.Time) string {
+	return filepath.Base(source) + "-" + now.Format("20060102-1504")
+}
+
+// executeExport copies a profile's share or snapshot to the USB device,
+// drawing a progress bar on the copy screen that alternates with the
+// throughput and time left, and shows the result once lock, if any, locked
+// the encrypted device again
+func executeExport(cfg *config.Config, profile config.CopyProfile, systemController controller.SystemControllerInterface, screens *screen.ScreenManager, prompter *prompt.Prompter, eventLog *events.Log, lock func() error) {
+	logger := logrus.WithField("profile", profile.Name)
+	logger.Info("Starting USB export")
+
+	copyScreen := screens.Layer(screen.PriorityCopy)
+	defer func() {
+		if err := copyScreen.Release(); err != nil {
+			logrus.WithError(err).Error("Failed to restore previous screen")
+		}
+	}()
+	if err := copyScreen.WriteText(profile.Name + "\nChecking space"); err != nil {
+		logger.WithError(err).Error("Failed to show export progress")
+		return
+	}
+
+	if leds := systemController.GetLEDController(); leds != nil {
+		leds.SetLED(controller.USB, true)
+		defer leds.SetLED(controller.USB, false)
+	}
+
+	width := cfg.Display.Width
+	if width <= 0 {
+		width = 16
+	}
+	progress := showCopyProgress(copyScreen, width, logger)
+	scan := func(dir string) bool {
+		ok := scanCopy(cfg, profile, dir, copyScreen, prompter, eventLog)
+		if err := copyScreen.WriteText(profile.Name + "\nChecking space"); err != nil {
+			logger.WithError(err).Debug("Failed to show export progress")
+		}
+		return ok
+	}
+	statusLine, report, err := runExport(cfg, profile, progress, scan, eventLog)
+	if err != nil {
+		logger.WithError(err).Error("USB export failed")
+	} else {
+		logger.Info("USB export completed successfully")
+	}
+	statusLine = lockUSB(lock, statusLine)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := prompter.Prompt(ctx, "USB Export\n"+statusLine); err != nil && ctx.Err() == nil {
+		logger.WithError(err).Error("Failed to show export result")
+	}
+	showCopyReport(cfg, prompter, report)
+}
+
+// showCopyProgress returns a progress showing each status of a copy on the
+// second line of copyScreen, with exportProgressLine
+func showCopyProgress(copyScreen *screen.Layer, width int, logger *logrus.Entry) usbexport.Progress {
+	started := time.Now()
+	var shown string
+	return func(status usbexport.Status) {
+		line := exportProgressLine(status, time.Since(started), width)
+		if line == shown {
+			return
+		}
+		shown = line
+		if err := copyScreen.WriteTextAt(line, 1, 0); err != nil {
+			logger.WithError(err).Debug("Failed to show copy progress")
+		}
+	}
+}
+
+// followImport shows the progress of an import's command on copyScreen
+// until ctx is cancelled, estimated from the space the copy takes at its
+// destination. The returned channel is closed once it stopped. Without a
+// copy source or destination, or with nothing mounted at the source, the
+// screen is left as it is.
+func followImport(ctx context.Context, cfg *config.Config, profile config.CopyProfile, copyScreen *screen.Layer) <-chan struct{} {
+	done := make(chan struct{})
+	destination := importDestination(profile)
+	if cfg.USBCopy.Source == "" || destination == "" {
+		close(done)
+		return done
+	}
+
+	width := cfg.Display.Width
+	if width <= 0 {
+		width = 16
+	}
+	logger := logrus.WithField("profile", profile.Name)
+	go func() {
+		defer close(done)
+		err := usbexport.Follow(ctx, cfg.USBCopy.Source, destination, 0, showCopyProgress(copyScreen, width, logger))
+		if err != nil {
+			logger.WithError(err).Debug("Copy progress not shown")
+		}
+	}()
+	return done
+}
+
+// exportProgressLine renders the second line of a copy at elapsed: the
+// progress bar, and every other progressAlternation the percentage,
+// throughput and time left, e.g. "42% 18M/s 3m12s", once the rate is known
+func exportProgressLine(status usbexport.Status, elapsed time.Duration, width int) string {
+	eta, ok := status.ETA()
+	if !ok || (elapsed/progressAlternation)%2 == 0 {
+		return controller.RenderProgressBarWidth(status.Percent(), width)
+	}
+
+	fields := []string{
+		fmt.Sprintf("%d%%", status.Percent()),
+		sysinfo.FormatBytes(uint64(status.Rate)) + "/s",
+		formatETA(eta),
+	}
+	// Two spaces apart if the line has room, else one
+	if line := strings.Join(fields, "  "); len(line) <= width {
+		return line
+	}
+	return strings.Join(fields, " ")
+}
+
+// formatETA renders the time left in at most two units, e.g. "45s", "3m12s"
+// or "1h05m"
+func formatETA(eta time.Duration) string {
+	switch {
+	case eta < time.Minute:
+		return fmt.Sprintf("%ds", int(eta.Seconds()))
+	case eta < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(eta.Minutes()), int(eta.Seconds())%60)
+	}
+	return fmt.Sprintf("%dh%02dm", int(eta.Hours()), int(eta.Minutes())%60)
+}
+
+// runExport checks the USB device has room for the export and runs it,
+// telling progress about every status. The profile's virus scan runs
+// scan on the source or the exported directory. It returns the line shown
+// with the result, and the report written into the exported directory if the
+// profile wants one.
+func runExport(cfg *config.Config, profile config.CopyProfile, progress usbexport.Progress, scan func(dir string) bool, eventLog *events.Log) (string, *copyreport.Report, error) {
+	source, err := exportSource(profile)
+	if err != nil {
+		return "No source", nil, err
+	}
+	if scansAt(profile, config.ScanBefore) && !scan(source) {
+		return "Not copied", nil, errors.New("virus scan stopped the export")
+	}
+	job, err := usbexport.Prepare(source, cfg.USBCopy.Source, exportName(source, time.Now()))
+	switch {
+	case errors.Is(err, usbexport.ErrNotMounted):
+		return "No USB device", nil, err
+	case errors.Is(err, usbexport.ErrNoSpace):
+		return fmt.Sprintf("Needs %s>%s", sysinfo.FormatBytes(job.Needed), sysinfo.FormatBytes(job.Free)), nil, err
+	case err != nil:
+		return "Export failed", nil, err
+	}
+
+	shown := -1
+	started := time.Now()
+	err = job.Run(context.Background(), func(status usbexport.Status) {
+		progress(status)
+		// Subscribers hear about every whole percent
+		if percent := status.Percent(); percent != shown {
+			shown = percent
+			publishCopyProgress(eventLog, profile.Name, percent, "")
+		}
+	})
+	recordCopyCommand(eventLog, profile.Name, "export "+job.Source+" to "+job.Target, time.Since(started), err)
+	if err != nil {
+		publishCopyProgress(eventLog, profile.Name, shown, "failed")
+		return "Export failed", nil, err
+	}
+	publishCopyProgress(eventLog, profile.Name, 100, "ok")
+	if scansAt(profile, config.ScanAfter) {
+		scan(job.Target)
+	}
+	// Read back from the device, so the report shows what arrived there
+	report := writeCopyReport(profile, job.Target, started, time.Time{}, skippedErrors(job.Skipped)...)
+
+	logrus.WithFields(logrus.Fields{
+		"target":  job.Target,
+		"files":   job.Files,
+		"bytes":   job.Bytes,
+		"skipped": len(job.Skipped),
+	}).Info("Exported to USB device")
+	if len(job.Skipped) > 0 {
+		return fmt.Sprintf("%s, %d skipped", sysinfo.FormatBytes(job.Bytes), len(job.Skipped)), report, nil
+	}
+	return sysinfo.FormatBytes(job.Bytes) + " copied", report, nil
+}
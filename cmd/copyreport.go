@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/copyreport"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/sirupsen/logrus"
+)
+
+// copyReportPageTime is how long each screen of a report summary stays
+const copyReportPageTime = 10 * time.Second
+
+// importDestination is the directory a profile imports to: its destination,
+// else the directory its scan after the copy checks
+func importDestination(profile config.CopyProfile) string {
+	if profile.Destination != "" {
+		return profile.Destination
+	}
+	if profile.Scan != nil {
+		return profile.Scan.Path
+	}
+	return ""
+}
+
+// writeCopyReport reads back the files a copy wrote to destination since
+// since, all of them with a zero since, and writes its report there with
+// problems as its errors. It returns nil if the profile wants no report or
+// the report failed.
+func writeCopyReport(profile config.CopyProfile, destination string, started, since time.Time, problems ...error) *copyreport.Report {
+	if !profile.Report {
+		return nil
+	}
+	logger := logrus.WithField("profile", profile.Name)
+	if destination == "" {
+		logger.Warn("No copy report written, the profile has no destination")
+		return nil
+	}
+
+	direction := profile.Direction
+	if direction == "" {
+		direction = config.CopyImport
+	}
+	report := copyreport.New(profile.Name, direction, destination, started)
+	for _, problem := range problems {
+		report.AddError(problem)
+	}
+	if err := report.Collect(since); err != nil {
+		logger.WithError(err).Error("Failed to write copy report")
+		return nil
+	}
+	path, err := report.Write(time.Now())
+	if err != nil {
+		logger.WithError(err).Error("Failed to write copy report")
+		return nil
+	}
+	logger.WithFields(logrus.Fields{
+		"report": path,
+		"files":  len(report.Files),
+		"errors": len(report.Errors),
+	}).Info("Wrote copy report")
+	return report
+}
+
+// skippedErrors turns the entries an export skipped into report errors
+func skippedErrors(skipped []string) []error {
+	problems := make([]error, len(skipped))
+	for i, path := range skipped {
+		problems[i] = fmt.Errorf("skipped %s", path)
+	}
+	return problems
+}
+
+// showCopyReport offers the summary of a report on the panel and shows it one
+// screen at a time, each until a button is pressed
+func showCopyReport(cfg *config.Config, prompter *prompt.Prompter, report *copyreport.Report) {
+	if report == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), copyChoiceTimeout)
+	choice, err := prompter.Prompt(ctx, "View report?", "No", "Yes")
+	cancel()
+	if err != nil || choice != 1 {
+		return
+	}
+
+	width := cfg.Display.Width
+	if width <= 0 {
+		width = 16
+	}
+	for _, page := range report.Summary(width) {
+		ctx, cancel := context.WithTimeout(context.Background(), copyReportPageTime)
+		_, err := prompter.Prompt(ctx, page)
+		cancel()
+		if err != nil {
+			// Nobody is reading any more
+			return
+		}
+	}
+}
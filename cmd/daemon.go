@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/daemon"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// daemonStartTimeout is how long --daemon waits for the detached service to
+// write its PID file
+const daemonStartTimeout = 10 * time.Second
+
+// daemonStopTimeout is how long stop waits for the service to exit; it
+// restores the panel and stops its monitors first
+const daemonStopTimeout = 30 * time.Second
+
+// pidFile returns the configured PID file
+func pidFile(cfg *config.Config) string {
+	if cfg.PIDFile != "" {
+		return cfg.PIDFile
+	}
+	return daemon.DefaultPIDFile
+}
+
+// runDetached starts the service again detached from the terminal and exits
+// once it has written its PID file, or failed before
+func runDetached() {
+	cfg := loadConfiguration()
+	path := pidFile(cfg)
+	if pid, err := daemon.ReadPIDFile(path); err == nil && daemon.Running(pid) {
+		logrus.WithField("pid", pid).Fatal("Service already running")
+	}
+
+	service, err := daemon.Detach(cfg.Logging.File)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to detach")
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- service.Wait()
+	}()
+
+	deadline := time.After(daemonStartTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			logrus.WithError(err).WithField("log", cfg.Logging.File).Fatal("Service exited while starting")
+		case <-deadline:
+			logrus.WithField("pid", service.Process.Pid).Warn("Service started but wrote no PID file yet")
+			os.Exit(0)
+		case <-ticker.C:
+			if pid, err := daemon.ReadPIDFile(path); err == nil && pid == service.Process.Pid {
+				fmt.Printf("Started with pid %d\n", pid)
+				os.Exit(0)
+			}
+		}
+	}
+}
+
+// startDaemon sets up the detached service and writes its PID file. It
+// returns a function removing the file again.
+func startDaemon(cfg *config.Config) func() {
+	if err := daemon.Setup(); err != nil {
+		logrus.WithError(err).Warn("Failed to change to the root directory")
+	}
+	// A reload while starting must not end the service; the main loop
+	// takes SIGHUP once it runs
+	signal.Ignore(syscall.SIGHUP)
+	path := pidFile(cfg)
+	if err := daemon.WritePIDFile(path); err != nil {
+		logrus.WithError(err).Fatal("Failed to write PID file")
+	}
+	logrus.WithFields(logrus.Fields{
+		"pid":      os.Getpid(),
+		"pid_file": path,
+	}).Info("Running detached")
+
+	return func() {
+		// Without root the file in /run may no longer be removable; the
+		// next start replaces it
+		if err := daemon.RemovePIDFile(path); err != nil {
+			logrus.WithError(err).Debug("Failed to remove PID file")
+		}
+	}
+}
+
+// reopenLog points the detached service's output at the log file again,
+// after it was rotated
+func reopenLog(cfg *config.Config) {
+	if err := daemon.Redirect(cfg.Logging.File); err != nil {
+		logrus.WithError(err).Warn("Failed to reopen log file")
+		return
+	}
+	logrus.WithField("file", cfg.Logging.File).Info("Reopened log file")
+}
+
+func newStopCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the service started with --daemon",
+		Long: "Sends SIGTERM to the service named in the PID file and waits for it to " +
+			"restore the panel and exit. Services run by systemd are stopped with systemctl.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pid, err := signalService(syscall.SIGTERM)
+			if err != nil {
+				return err
+			}
+			if !daemon.WaitExit(pid, daemonStopTimeout) {
+				return fmt.Errorf("pid %d still running after %s", pid, daemonStopTimeout)
+			}
+			fmt.Printf("Stopped pid %d\n", pid)
+			return nil
+		},
+	}
+}
+
+func newReloadCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Reopen the log file of the service started with --daemon",
+		Long: "Sends SIGHUP to the service named in the PID file, which reopens " +
+			"logging.file, e.g. from a logrotate postrotate script.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := signalService(syscall.SIGHUP)
+			return err
+		},
+	}
+}
+
+// signalService sends sig to the service named in the PID file and returns
+// its PID
+func signalService(sig syscall.Signal) (int, error) {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	path := pidFile(loadConfiguration())
+
+	pid, err := daemon.Signal(path, sig)
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return 0, fmt.Errorf("service not running (no process in %s)", path)
+	}
+	return pid, err
+}
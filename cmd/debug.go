@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// debugLEDStep is how long each LED state stays for the eye to check it
+const debugLEDStep = 3 * time.Second
+
+// debugButtonTimeout is how long each button test waits for its button
+const debugButtonTimeout = 15 * time.Second
+
+// debugListenTime is how long "debug buttons" reports presses at the end
+const debugListenTime = 30 * time.Second
+
+// debugTexts are written to check how the panel lays out text
+var debugTexts = []string{
+	"Single line",
+	"Two\nLines",
+	"Very long single line that should be truncated properly",
+	"Long line 1\nLong line 2 also",
+	"",
+	"Empty\n",
+	"\nEmpty first",
+}
+
+// buttonNames are the panel labels of the buttons
+var buttonNames = map[controller.PanelButton]string{
+	controller.ButtonEnter:   "ENTER",
+	controller.ButtonSelect:  "SELECT",
+	controller.ButtonUSBCopy: "USB COPY",
+}
+
+// newDebugCommand creates the "debug" subcommand, whose subcommands walk
+// through the panel hardware step by step for someone watching it
+func newDebugCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "debug",
+		Short: "Walk through the panel hardware while watching it",
+		Long: "Interactive hardware checks for bringing up a new box. Unlike selftest they switch " +
+			"every LED and wait for each button in turn, so someone has to watch the panel. Stop " +
+			"the service first; they open the serial port and I/O ports themselves.",
+	}
+	command.AddCommand(
+		&cobra.Command{
+			Use:   "display",
+			Short: "Switch every LED, wait for each button and write sample texts",
+			Long: "Writes two lines, switches the status, USB and disk LEDs one at a time for " +
+				"3 seconds each, waits for ENTER, SELECT and USB COPY with instructions on the " +
+				"panel and writes texts that check truncation and empty lines.",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runDebugDisplay(os.Stdout)
+			},
+		},
+		&cobra.Command{
+			Use:   "buttons",
+			Short: "Wait for each button and print every button event",
+			Long: "Prints every press and release, waits for ENTER, SELECT and USB COPY in turn and " +
+				"then reports whatever is pressed for 30 seconds, with hints when nothing arrives.",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runDebugButtons(os.Stdout)
+			},
+		},
+	)
+	return command
+}
+
+// openDebugPanel opens the panel as the service does
+func openDebugPanel(out io.Writer) (*controller.SystemController, *config.Config, error) {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+	cfg := loadConfiguration()
+
+	systemController, err := controller.NewSystemController(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the panel: %w", err)
+	}
+	fmt.Fprintf(out, "Panel open: %s\n", displayDescription(cfg))
+	return systemController, cfg, nil
+}
+
+// runDebugDisplay writes to the display, walks through the LEDs and waits for
+// the buttons
+func runDebugDisplay(out io.Writer) error {
+	systemController, _, err := openDebugPanel(out)
+	if err != nil {
+		return err
+	}
+	defer systemController.Close()
+
+	display := systemController.GetDisplayController()
+	prompter := prompt.NewPrompter(display)
+	systemController.SetButtonHandler(func(button controller.PanelButton, pressed bool) {
+		fmt.Fprintf(out, "  button %s pressed=%v\n", buttonNames[button], pressed)
+		prompter.HandleButton(button, pressed)
+	})
+
+	fmt.Fprintln(out, "Two lines")
+	if err := display.WriteText("Line 1 Text\nLine 2 Text"); err != nil {
+		return fmt.Errorf("failed to write to the display: %w", err)
+	}
+	time.Sleep(debugLEDStep)
+
+	if leds := systemController.GetLEDController(); leds != nil {
+		debugLEDs(out, systemController, leds)
+	} else {
+		fmt.Fprintln(out, "LEDs not available (I/O port access needs root)")
+	}
+
+	for _, button := range []controller.PanelButton{controller.ButtonEnter, controller.ButtonSelect, controller.ButtonUSBCopy} {
+		name := buttonNames[button]
+		fmt.Fprintf(out, "Press %s\n", name)
+		ctx, cancel := context.WithTimeout(context.Background(), debugButtonTimeout)
+		_, err := prompter.WaitForButton(ctx, name+" Test\nPress "+name, button)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(out, "  no %s press within %s\n", name, debugButtonTimeout)
+			continue
+		}
+		fmt.Fprintf(out, "  %s OK\n", name)
+	}
+	fmt.Fprintf(out, "Copy button source: %s\n", systemController.CopyButtonSource())
+
+	for _, text := range debugTexts {
+		fmt.Fprintf(out, "Text %q\n", text)
+		if err := display.WriteText(text); err != nil {
+			fmt.Fprintf(out, "  FAILED (%v)\n", err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return nil
+}
+
+// debugLEDs switches each LED on and off in turn, then all disk LEDs at once
+func debugLEDs(out io.Writer, systemController *controller.SystemController, leds controller.LEDControllerInterface) {
+	step := func(description string, err error) {
+		if err != nil {
+			fmt.Fprintf(out, "%s FAILED (%v)\n", description, err)
+		} else {
+			fmt.Fprintln(out, description)
+		}
+		time.Sleep(debugLEDStep)
+	}
+
+	step("Status LED green", leds.SetStatusLED(false, true))
+	step("Status LED red", leds.SetStatusLED(true, false))
+	step("Status LED off", leds.SetStatusLED(false, false))
+	step("Status LED green again", leds.SetStatusLED(false, true))
+	step("USB LED on", leds.SetLED(controller.USB, true))
+	step("USB LED off", leds.SetLED(controller.USB, false))
+	for disk := 1; disk <= 6; disk++ {
+		step(fmt.Sprintf("Disk %d LED on", disk), systemController.SetDiskActivity(disk, true))
+		step(fmt.Sprintf("Disk %d LED off", disk), systemController.SetDiskActivity(disk, false))
+	}
+
+	all := func(on bool) error {
+		for disk := 1; disk <= 6; disk++ {
+			if err := systemController.SetDiskActivity(disk, on); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	step("All disk LEDs on", all(true))
+	step("All disk LEDs off", all(false))
+}
+
+// runDebugButtons waits for each button in turn and then reports every press
+// for a while
+func runDebugButtons(out io.Writer) error {
+	systemController, cfg, err := openDebugPanel(out)
+	if err != nil {
+		return err
+	}
+	defer systemController.Close()
+
+	presses := make(chan controller.PanelButton, 10)
+	systemController.SetButtonHandler(func(button controller.PanelButton, pressed bool) {
+		fmt.Fprintf(out, "  button %s pressed=%v\n", buttonNames[button], pressed)
+		if pressed {
+			select {
+			case presses <- button:
+			default:
+			}
+		}
+	})
+	display := systemController.GetDisplayController()
+
+	received := 0
+	for _, button := range []controller.PanelButton{controller.ButtonEnter, controller.ButtonSelect, controller.ButtonUSBCopy} {
+		name := buttonNames[button]
+		fmt.Fprintf(out, "Press %s\n", name)
+		display.WriteText(name + " Test\nPress " + name)
+
+		select {
+		case pressed := <-presses:
+			received++
+			if pressed == button {
+				fmt.Fprintf(out, "  %s OK\n", name)
+			} else {
+				fmt.Fprintf(out, "  expected %s, got %s\n", name, buttonNames[pressed])
+			}
+		case <-time.After(debugButtonTimeout):
+			fmt.Fprintf(out, "  no %s press within %s\n", name, debugButtonTimeout)
+		}
+		// Presses of the wrong button do not count for the next one
+		for len(presses) > 0 {
+			<-presses
+		}
+		time.Sleep(time.Second)
+	}
+
+	fmt.Fprintf(out, "Press any buttons for %s\n", debugListenTime)
+	display.WriteText("Final Test\nPress any button")
+	deadline := time.After(debugListenTime)
+listen:
+	for {
+		select {
+		case <-presses:
+			received++
+		case <-deadline:
+			break listen
+		}
+	}
+	display.WriteText("Test Complete\nCheck results")
+
+	if received == 0 {
+		fmt.Fprintln(out, "No button events at all. Check that:")
+		fmt.Fprintf(out, "  - %s exists and is not used by the service\n", cfg.SerialPort.Device)
+		fmt.Fprintln(out, "  - this runs as root, which the copy button's I/O port needs")
+		fmt.Fprintln(out, "  - hardware.profile matches the box (see selftest)")
+	}
+	return nil
+}
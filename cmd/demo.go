@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// demoOptions holds the flags of the demo subcommand
+type demoOptions struct {
+	console    bool
+	duration   time.Duration
+	cycles     int
+	frameDelay time.Duration
+}
+
+// newDemoCommand creates the "demo" subcommand
+func newDemoCommand() *cobra.Command {
+	opts := &demoOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "demo",
+		Short: "Cycle demo screens, LED patterns and a simulated copy",
+		Long: "Continuously cycles text screens, animations, LED patterns and simulated copy progress " +
+			"without running any external commands. Useful for showcasing the panel and for " +
+			"long-running serial stability soak tests; write statistics are logged after every cycle.",
+		Args: cobra.NoArgs,
+		// Errors are hardware problems, not usage mistakes
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDemo(opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.console, "console", false, "Render to the terminal instead of the panel hardware")
+	cmd.Flags().DurationVar(&opts.duration, "duration", 0, "Stop after this long (0 = run until interrupted)")
+	cmd.Flags().IntVar(&opts.cycles, "cycles", 0, "Stop after this many cycles (0 = run until interrupted)")
+	cmd.Flags().DurationVar(&opts.frameDelay, "frame-delay", 0, "Delay between animation frames (0 = derive from baud rate)")
+
+	return cmd
+}
+
+// runDemo sets up the display and LEDs and runs demo cycles until stopped
+func runDemo(opts *demoOptions) error {
+	setupLogging()
+	cfg := loadConfiguration()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+
+	var panel screen.Display
+	var leds controller.LEDControllerInterface
+	if opts.console {
+		console := newConsoleDisplay(os.Stdout, cfg.Display.Width, cfg.Display.Height)
+		panel = console
+		leds = console
+	} else {
+		systemController, err := controller.NewSystemController(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize system controller: %w", err)
+		}
+		defer systemController.Close()
+
+		panel = systemController.GetDisplayController()
+		leds = systemController.GetLEDController()
+	}
+
+	counter := &countingDisplay{Display: panel}
+	screens := screen.NewScreenManager(counter, cfg.Display.Width, cfg.Display.Height)
+
+	d := &demo{
+		layer:      screens.Layer(screen.PriorityStatus),
+		leds:       leds,
+		frameDelay: opts.frameDelay,
+	}
+	if d.frameDelay <= 0 {
+		d.frameDelay = screen.FrameDelay(cfg.Display.Width, cfg.Display.Height, cfg.SerialPort.BaudRate)
+	}
+	defer d.layer.Release()
+
+	logrus.WithFields(logrus.Fields{
+		"console":     opts.console,
+		"frame_delay": d.frameDelay,
+	}).Info("Starting demo mode")
+
+	start := time.Now()
+	for cycle := 1; opts.cycles == 0 || cycle <= opts.cycles; cycle++ {
+		failed := d.runCycle(ctx)
+
+		logrus.WithFields(logrus.Fields{
+			"cycle":         cycle,
+			"failed_scenes": failed,
+			"writes":        atomic.LoadUint64(&counter.writes),
+			"write_errors":  atomic.LoadUint64(&counter.errors),
+			"elapsed":       time.Since(start).Round(time.Second),
+		}).Info("Demo cycle complete")
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if leds != nil {
+		leds.SetDiskLEDs(map[int]bool{1: false, 2: false, 3: false, 4: false})
+		leds.SetLED(controller.USB, false)
+		leds.SetStatusLED(false, true)
+	}
+
+	logrus.Info("Demo mode stopped")
+	return nil
+}
+
+// demo runs the individual scenes of a demo cycle
+type demo struct {
+	layer      *screen.Layer
+	leds       controller.LEDControllerInterface
+	frameDelay time.Duration
+}
+
+// demoScene is one step of the demo loop
+type demoScene struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// runCycle plays every scene once and returns how many failed. Failures are
+// logged and the cycle continues, so soak tests keep running through errors.
+func (d *demo) runCycle(ctx context.Context) int {
+	scenes := []demoScene{
+		{"banner", d.banner},
+		{"clock", d.clock},
+		{"marquee", d.marquee},
+		{"animations", d.animations},
+		{"leds", d.ledChase},
+		{"copy", d.simulatedCopy},
+	}
+
+	failed := 0
+	for _, scene := range scenes {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := scene.run(ctx); err != nil && ctx.Err() == nil {
+			failed++
+			logrus.WithError(err).WithField("scene", scene.name).Warn("Demo scene failed")
+		}
+	}
+	return failed
+}
+
+// wait sleeps for the given duration unless the demo is stopped
+func (d *demo) wait(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// animations plays each idle animation for a few seconds
+func (d *demo) animations(ctx context.Context) error {
+	animator := screen.NewAnimator(d.layer, d.frameDelay)
+	defer animator.Stop()
+
+	for _, name := range screen.AnimationNames() {
+		animation, err := screen.NewAnimation(name, "QNAP")
+		if err != nil {
+			return err
+		}
+		animator.Play(animation)
+		if err := d.wait(ctx, 5*time.Second); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// banner shows a static welcome screen
+func (d *demo) banner(ctx context.Context) error {
+	if err := d.layer.WriteText("QNAP Display\nDemo mode"); err != nil {
+		return err
+	}
+	return d.wait(ctx, 3*time.Second)
+}
+
+// clock shows the hostname and a ticking clock
+func (d *demo) clock(ctx context.Context) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "qnap"
+	}
+
+	for i := 0; i < 5; i++ {
+		text := hostname + "\n" + time.Now().Format("15:04:05")
+		if err := d.layer.WriteText(text); err != nil {
+			return err
+		}
+		if err := d.wait(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marquee scrolls a message across the second line
+func (d *demo) marquee(ctx context.Context) error {
+	const message = "Front panel control for QNAP NAS - LCD, buttons, LEDs and USB copy"
+	width, _ := d.layer.Size()
+
+	if err := d.layer.WriteTextAt("Did you know?", 0, 0); err != nil {
+		return err
+	}
+
+	padded := strings.Repeat(" ", width) + message + strings.Repeat(" ", width)
+	for pos := 0; pos+width <= len(padded); pos++ {
+		if err := d.layer.WriteTextAt(padded[pos:pos+width], 1, 0); err != nil {
+			return err
+		}
+		if err := d.wait(ctx, d.frameDelay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ledChase runs a light across the disk LEDs while blinking the status LED
+func (d *demo) ledChase(ctx context.Context) error {
+	if err := d.layer.WriteText("LED pattern\n"); err != nil {
+		return err
+	}
+
+	for step := 0; step < 12; step++ {
+		disk := step%4 + 1
+		if err := d.layer.WriteTextAt(fmt.Sprintf("Disk %d", disk), 1, 0); err != nil {
+			return err
+		}
+
+		if d.leds != nil {
+			states := map[int]bool{1: false, 2: false, 3: false, 4: false}
+			states[disk] = true
+			if err := d.leds.SetDiskLEDs(states); err != nil {
+				return err
+			}
+			if err := d.leds.SetStatusLED(step%2 == 1, step%2 == 0); err != nil {
+				return err
+			}
+		}
+
+		if err := d.wait(ctx, d.frameDelay); err != nil {
+			return err
+		}
+	}
+
+	if d.leds != nil {
+		if err := d.leds.SetDiskLEDs(map[int]bool{1: false, 2: false, 3: false, 4: false}); err != nil {
+			return err
+		}
+		return d.leds.SetStatusLED(false, true)
+	}
+	return nil
+}
+
+// simulatedCopy shows copy progress without touching any storage
+func (d *demo) simulatedCopy(ctx context.Context) error {
+	width, _ := d.layer.Size()
+	if err := d.layer.WriteText("Copying demo.iso\n" + controller.RenderProgressBarWidth(0, width)); err != nil {
+		return err
+	}
+
+	if d.leds != nil {
+		if err := d.leds.SetLED(controller.USB, true); err != nil {
+			return err
+		}
+		defer d.leds.SetLED(controller.USB, false)
+	}
+
+	for percent := 5; percent <= 100; percent += 5 {
+		if err := d.wait(ctx, d.frameDelay); err != nil {
+			return err
+		}
+		if err := d.layer.WriteTextAt(controller.RenderProgressBarWidth(percent, width), 1, 0); err != nil {
+			return err
+		}
+	}
+
+	if err := d.layer.WriteTextAt("Copy complete", 1, 0); err != nil {
+		return err
+	}
+	return d.wait(ctx, 2*time.Second)
+}
+
+// countingDisplay counts writes and write errors for soak testing
+type countingDisplay struct {
+	screen.Display
+	writes uint64
+	errors uint64
+}
+
+// WriteTextAt forwards the write and records its outcome
+func (c *countingDisplay) WriteTextAt(text string, row, col int) error {
+	err := c.Display.WriteTextAt(text, row, col)
+	if err != nil {
+		atomic.AddUint64(&c.errors, 1)
+	} else {
+		atomic.AddUint64(&c.writes, 1)
+	}
+	return err
+}
+
+// consoleDisplay draws the panel and its LEDs on a terminal for demos
+// without hardware
+type consoleDisplay struct {
+	out       io.Writer
+	width     int
+	lines     []string
+	leds      map[controller.PanelLED]bool
+	backlight bool
+	mutex     sync.Mutex
+}
+
+// newConsoleDisplay creates a terminal panel of the given geometry
+func newConsoleDisplay(out io.Writer, width, height int) *consoleDisplay {
+	if width <= 0 {
+		width = 16
+	}
+	if height <= 0 {
+		height = 2
+	}
+
+	return &consoleDisplay{
+		out:       out,
+		width:     width,
+		lines:     make([]string, height),
+		leds:      make(map[controller.PanelLED]bool),
+		backlight: true,
+	}
+}
+
+// WriteTextAt updates a line and redraws the terminal
+func (c *consoleDisplay) WriteTextAt(text string, row, col int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if row < 0 || row >= len(c.lines) {
+		return fmt.Errorf("row %d out of range", row)
+	}
+	line := []rune(fmt.Sprintf("%-*s", c.width, c.lines[row]))
+	for i, r := range text {
+		if col+i < c.width {
+			line[col+i] = r
+		}
+	}
+	c.lines[row] = string(line[:c.width])
+
+	c.draw()
+	return nil
+}
+
+// SetBacklight records the backlight state
+func (c *consoleDisplay) SetBacklight(on bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.backlight = on
+	c.draw()
+	return nil
+}
+
+// SetLED records an LED state
+func (c *consoleDisplay) SetLED(led controller.PanelLED, on bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.leds[led] = on
+	c.draw()
+	return nil
+}
+
+// SetDiskLEDs records the disk LED states
+func (c *consoleDisplay) SetDiskLEDs(states map[int]bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for disk, on := range states {
+		if disk >= 1 && disk <= 6 {
+			c.leds[controller.Disk1+controller.PanelLED(disk-1)] = on
+		}
+	}
+	c.draw()
+	return nil
+}
+
+// SetStatusLED records the status LED states
+func (c *consoleDisplay) SetStatusLED(red bool, green bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.leds[controller.StatusRed] = red
+	c.leds[controller.StatusGreen] = green
+	c.draw()
+	return nil
+}
+
+// GetLEDStates returns the recorded LED states
+func (c *consoleDisplay) GetLEDStates() (map[controller.PanelLED]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	states := make(map[controller.PanelLED]bool, len(c.leds))
+	for led, on := range c.leds {
+		states[led] = on
+	}
+	return states, nil
+}
+
+// Close implements controller.LEDControllerInterface
+func (c *consoleDisplay) Close() error {
+	return nil
+}
+
+// draw clears the terminal and prints the panel. Caller must hold the mutex.
+func (c *consoleDisplay) draw() {
+	border := "+" + strings.Repeat("-", c.width) + "+"
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	b.WriteString(border + "\n")
+	for _, line := range c.lines {
+		fmt.Fprintf(&b, "|%-*s|\n", c.width, line)
+	}
+	b.WriteString(border + "\n")
+
+	names := map[controller.PanelLED]string{
+		controller.StatusGreen: "STATUS-G",
+		controller.StatusRed:   "STATUS-R",
+		controller.USB:         "USB",
+		controller.Disk1:       "D1",
+		controller.Disk2:       "D2",
+		controller.Disk3:       "D3",
+		controller.Disk4:       "D4",
+	}
+	leds := make([]controller.PanelLED, 0, len(names))
+	for led := range names {
+		leds = append(leds, led)
+	}
+	sort.Slice(leds, func(i, j int) bool { return leds[i] < leds[j] })
+
+	for _, led := range leds {
+		state := "o"
+		if c.leds[led] {
+			state = "*"
+		}
+		fmt.Fprintf(&b, "%s:%s ", names[led], state)
+	}
+	if !c.backlight {
+		b.WriteString(" (backlight off)")
+	}
+	b.WriteString("\n")
+
+	fmt.Fprint(c.out, b.String())
+}
+
+var _ controller.LEDControllerInterface = (*consoleDisplay)(nil)
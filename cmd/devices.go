@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/menu"
+	"github.com/qnap/display-control/internal/uevent"
+	"github.com/sirupsen/logrus"
+)
+
+// deviceSettleTime is how long after a block device event the Devices menu
+// is updated, giving the automounter time to mount a stick plugged in
+const deviceSettleTime = 2 * time.Second
+
+// watchDevices updates the menu's Devices submenu on the kernel's block
+// device events. The returned function stops watching; it is nil if the
+// events cannot be received, and the submenu then only changes when it is
+// opened again.
+func watchDevices(menuSystem *menu.MenuSystem) func() {
+	listener, err := uevent.Listen()
+	if err != nil {
+		logrus.WithError(err).Warn("Device events unavailable, Devices menu not kept up to date")
+		return nil
+	}
+
+	var mutex sync.Mutex
+	var settle *time.Timer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			event, err := listener.Read()
+			if err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					logrus.WithError(err).Warn("Stopped reading device events")
+				}
+				return
+			}
+			if event.Subsystem != "block" {
+				continue
+			}
+			logrus.WithFields(logrus.Fields{
+				"action": event.Action,
+				"device": event.DevName,
+			}).Debug("Block device event")
+
+			// Plugging in a stick sends a burst of events; the menu is
+			// updated once after the last
+			mutex.Lock()
+			if settle == nil {
+				settle = time.AfterFunc(deviceSettleTime, menuSystem.DevicesChanged)
+			} else {
+				settle.Reset(deviceSettleTime)
+			}
+			mutex.Unlock()
+		}
+	}()
+
+	return func() {
+		listener.Close()
+		<-done
+		mutex.Lock()
+		if settle != nil {
+			settle.Stop()
+		}
+		mutex.Unlock()
+	}
+}
+
+// ejectsDevices reports whether the menu has the Devices submenu or an item
+// ejecting a device, which unmount filesystems
+func ejectsDevices(cfg *config.Config) bool {
+	if !cfg.Menu.Enabled {
+		return false
+	}
+	var walk func(item config.MenuItem) bool
+	walk = func(item config.MenuItem) bool {
+		if item.Type == "display_command" && (item.Command == "devices" || strings.HasPrefix(item.Command, "eject:")) {
+			return true
+		}
+		for _, child := range item.Items {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(cfg.Menu.MainMenu)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/simulator"
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// progressTimeout removes a progress bar that was not updated for this long,
+// e.g. because the script updating it died
+const progressTimeout = 10 * time.Minute
+
+// scriptDisplay shows what scripts send over the control socket: written
+// text like a prompt without options, and progress bars on the layer of
+// pushed screens
+type scriptDisplay struct {
+	prompter *prompt.Prompter
+	screens  *screen.ScreenManager
+	layer    *screen.Layer
+	logger   *logrus.Entry
+
+	mutex sync.Mutex
+	// cancelWrite removes the written text while it is shown; written
+	// counts the texts, so a text that is gone does not remove its successor
+	cancelWrite context.CancelFunc
+	written     int
+	// progress removes the progress bar when it times out; nil while none is
+	// shown
+	progress *time.Timer
+}
+
+// handleDisplay answers the write and display requests on the control socket
+func handleDisplay(server *control.Server, prompter *prompt.Prompter, screens *screen.ScreenManager) {
+	d := &scriptDisplay{
+		prompter: prompter,
+		screens:  screens,
+		layer:    screens.Layer(screen.PriorityRemote),
+		logger:   logrus.WithField("component", "script_display"),
+	}
+	server.Handle("write", d.write)
+	server.Handle("display_clear", d.clear)
+	server.Handle("display_backlight", d.backlight)
+	server.Handle("display_progress", d.showProgress)
+	server.Handle("display_redraw", d.redraw)
+}
+
+// write shows text above everything but alerts, until a button is pressed or
+// its time is up. It replaces text written before, but does not cover
+// questions waiting for an answer.
+func (d *scriptDisplay) write(request control.Request) (string, error) {
+	if strings.TrimSpace(request.Text) == "" {
+		return "", errors.New("no text to write")
+	}
+	duration := defaultMessageDuration
+	if request.Duration > 0 {
+		duration = time.Duration(request.Duration) * time.Second
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.cancelWrite != nil {
+		d.cancelWrite()
+	} else if d.prompter.Active() {
+		return "", errors.New("the panel is showing a question, try again later")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	d.written++
+	written := d.written
+	d.cancelWrite = cancel
+	go func() {
+		defer cancel()
+		if _, err := d.prompter.Prompt(ctx, request.Text); err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			d.logger.WithError(err).Warn("Failed to show written text")
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if d.written == written {
+			d.cancelWrite = nil
+		}
+	}()
+	return "", nil
+}
+
+// clear removes the written text and the progress bar
+func (d *scriptDisplay) clear(control.Request) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.cancelWrite != nil {
+		d.cancelWrite()
+		d.cancelWrite = nil
+	}
+	return "", d.removeProgress()
+}
+
+// backlight switches the backlight, "on" or "off" in the request's text
+func (d *scriptDisplay) backlight(request control.Request) (string, error) {
+	on, ok := parseOnOff([]byte(request.Text))
+	if !ok {
+		return "", fmt.Errorf("invalid backlight state %q, use on or off", request.Text)
+	}
+	return "", d.layer.SetBacklight(on)
+}
+
+// redraw sends the whole screen to the panel again, for when something
+// else wrote to it
+func (d *scriptDisplay) redraw(control.Request) (string, error) {
+	return "", d.screens.Redraw()
+}
+
+// showProgress shows a progress bar with the request's text above it, until
+// the display is cleared or the bar is not updated for progressTimeout
+func (d *scriptDisplay) showProgress(request control.Request) (string, error) {
+	if request.Percent < 0 || request.Percent > 100 {
+		return "", fmt.Errorf("invalid percentage %d", request.Percent)
+	}
+	width, _ := d.layer.Size()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// The bar stays on the layer even if drawing it fails, and is drawn once
+	// the display is back
+	err := d.layer.WriteText(renderProgress(request.Text, request.Percent, width))
+	if d.progress != nil {
+		d.progress.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(progressTimeout, func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if d.progress != timer {
+			return
+		}
+		d.logger.Info("Progress bar not updated, removing it")
+		if err := d.removeProgress(); err != nil {
+			d.logger.WithError(err).Warn("Failed to remove progress bar")
+		}
+	})
+	d.progress = timer
+	return "", err
+}
+
+// removeProgress releases the layer if a progress bar is shown
+func (d *scriptDisplay) removeProgress() error {
+	if d.progress == nil {
+		return nil
+	}
+	d.progress.Stop()
+	d.progress = nil
+	return d.layer.Release()
+}
+
+// renderProgress writes the text and the percentage on the first line and
+// the bar on the second, e.g. "Backup       40%" above "[=====         ]"
+func renderProgress(text string, percent, width int) string {
+	label := fmt.Sprintf("%d%%", percent)
+	if room := width - len(label) - 1; room > 0 {
+		if runes := []rune(text); len(runes) > room {
+			text = string(runes[:room])
+		}
+		label = fmt.Sprintf("%-*s %s", room, text, label)
+	}
+	return label + "\n" + controller.RenderProgressBarWidth(percent, width)
+}
+
+// newDisplayCommand creates the "display" subcommand and its subcommands
+func newDisplayCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "display",
+		Short: "Write to the panel from scripts",
+		Long: "Puts messages and progress bars on the panel through the running service, so " +
+			"shell scripts need no code of their own. \"display write\" is the same as \"write\".",
+	}
+
+	var text string
+	progress := &cobra.Command{
+		Use:   "progress PERCENT",
+		Short: "Show a progress bar",
+		Long: "Shows a progress bar from 0 to 100 with --text above it, above the menu. Run it " +
+			"again to update the bar; \"display clear\" removes it, and so does the service when " +
+			"the bar is not updated for " + progressTimeout.String() + ".",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			percent, err := strconv.Atoi(strings.TrimSuffix(args[0], "%"))
+			if err != nil || percent < 0 || percent > 100 {
+				return fmt.Errorf("invalid percentage %q, use 0 to 100", args[0])
+			}
+			return runDisplay(control.Request{Command: "display_progress", Text: text, Percent: percent})
+		},
+	}
+	progress.Flags().StringVar(&text, "text", "", "Text above the bar, e.g. Backup")
+
+	command.AddCommand(newWriteCommand(),
+		&cobra.Command{
+			Use:          "clear",
+			Short:        "Remove written text and the progress bar",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runDisplay(control.Request{Command: "display_clear"})
+			},
+		},
+		&cobra.Command{
+			Use:          "backlight on|off",
+			Short:        "Switch the backlight",
+			Args:         cobra.ExactArgs(1),
+			ValidArgs:    []string{"on", "off"},
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if _, ok := parseOnOff([]byte(args[0])); !ok {
+					return fmt.Errorf("invalid backlight state %q, use on or off", args[0])
+				}
+				return runDisplay(control.Request{Command: "display_backlight", Text: args[0]})
+			},
+		},
+		progress,
+		&cobra.Command{
+			Use:   "redraw",
+			Short: "Send the screen to the panel again",
+			Long: "Rewrites every line the panel should show, restoring it after another " +
+				"process wrote to its serial port. The service also does this on its own every " +
+				"redraw_interval_sec seconds.",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runDisplay(control.Request{Command: "display_redraw"})
+			},
+		},
+		&cobra.Command{
+			Use:   "preview TEXT...",
+			Short: "Print text as the panel would show it",
+			Long: "Prints text, each argument on a line of its own, as the panel shows it: " +
+				"transliterated as the config file sets up, cut to the display's width and framed, " +
+				"followed by the characters that were replaced. Glyphs are shown as symbols. It " +
+				"needs neither the service nor the panel.",
+			Args:         cobra.MinimumNArgs(1),
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runDisplayPreview(os.Stdout, args)
+			},
+		},
+	)
+	return command
+}
+
+// runDisplay sends a display request to the service and prints its answer
+func runDisplay(request control.Request) error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	output, err := callService(cfg, request)
+	if errors.Is(err, control.ErrNoService) {
+		return fmt.Errorf("the display commands need the service running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintln(os.Stdout, output)
+	}
+	return nil
+}
+
+// runDisplayPreview prints lines framed as the panel would show them, and
+// what the transliteration replaced in them
+func runDisplayPreview(w io.Writer, lines []string) error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+	transliterator, err := controller.NewTransliterator(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid transliteration: %w", err)
+	}
+
+	width, rows := cfg.Display.Width, cfg.Display.Height
+	if width <= 0 {
+		width = 16
+	}
+	if rows <= 0 {
+		rows = 2
+	}
+	border := "+" + strings.Repeat("-", width) + "+"
+	fmt.Fprintln(w, border)
+	for row := 0; row < rows; row++ {
+		line := ""
+		if row < len(lines) {
+			line = transliterator.Apply(lines[row])
+		}
+		if len(line) > width {
+			line = line[:width]
+		}
+		fmt.Fprintf(w, "|%s%s|\n", simulator.ShowGlyphs(line), strings.Repeat(" ", width-len(line)))
+	}
+	fmt.Fprintln(w, border)
+	if len(lines) > rows {
+		fmt.Fprintf(w, "Lines after the %d of the display are not shown\n", rows)
+	}
+
+	glyphNames := make(map[string]string)
+	for _, name := range controller.GlyphNames() {
+		char, _ := controller.GlyphChar(name)
+		glyphNames[char] = name
+	}
+	for _, replacement := range transliterator.Replacements(strings.Join(lines, "\n")) {
+		to := strconv.Quote(replacement.To)
+		if name, ok := glyphNames[replacement.To]; ok {
+			to = "glyph " + name
+		}
+		if class := translit.Class(replacement.From); class != "" {
+			to += " (" + class + ")"
+		}
+		fmt.Fprintf(w, "%c -> %s\n", replacement.From, to)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+)
+
+// recordButton adds a press or release to the event log
+func recordButton(eventLog *events.Log, button controller.PanelButton, pressed bool) {
+	action := "released"
+	if pressed {
+		action = "pressed"
+	}
+	eventLog.Record(events.KindButton, button.String()+" "+action, events.Fields{"button": button.String(), "action": action})
+}
+
+// recordBreakerEvent adds a change of the serial link to the event log
+func recordBreakerEvent(eventLog *events.Log, event controller.BreakerEvent) {
+	message := "link down"
+	switch event.To {
+	case controller.BreakerClosed:
+		message = "link up"
+	case controller.BreakerHalfOpen:
+		message = "probing link"
+	}
+	fields := events.Fields{
+		"from":     event.From.String(),
+		"to":       event.To.String(),
+		"failures": fmt.Sprint(event.Failures),
+	}
+	if event.Err != nil {
+		fields["error"] = event.Err.Error()
+	}
+	eventLog.Record(events.KindSerial, message, fields)
+}
+
+// recordCopyCommand adds a copy command run or an export of a copy profile,
+// described by command, to the event log
+func recordCopyCommand(eventLog *events.Log, profile, command string, took time.Duration, err error) {
+	fields := events.Fields{
+		"item":        "USB copy",
+		"profile":     profile,
+		"status":      "ok",
+		"duration_ms": fmt.Sprint(took.Milliseconds()),
+	}
+	if err != nil {
+		fields["status"] = "failed"
+		fields["error"] = err.Error()
+	}
+	eventLog.Record(events.KindCommand, command, fields)
+}
+
+// publishLED streams an LED switched on or off to the event subscribers
+func publishLED(eventLog *events.Log, led controller.PanelLED, on bool) {
+	state := "off"
+	if on {
+		state = "on"
+	}
+	eventLog.Publish(events.KindLED, led.String()+" "+state, events.Fields{"led": led.String(), "on": fmt.Sprint(on)})
+}
+
+// publishCopyProgress streams the progress of a USB copy to the event
+// subscribers. Imports only report their start and end; status is set at
+// the end, to "ok" or "failed".
+func publishCopyProgress(eventLog *events.Log, profile string, percent int, status string) {
+	fields := events.Fields{"profile": profile, "percent": fmt.Sprint(percent)}
+	if status != "" {
+		fields["status"] = status
+	}
+	eventLog.Publish(events.KindProgress, fmt.Sprintf("%s %d%%", profile, percent), fields)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/pkg/buttons"
+	"github.com/sirupsen/logrus"
+)
+
+// buttonForwarder relays the presses of some of the panel's buttons to the
+// service on another host
+type buttonForwarder struct {
+	*control.ButtonForwarder
+	buttons   map[controller.PanelButton]bool
+	exclusive bool
+}
+
+// startButtonForwarder starts relaying the configured buttons. It returns
+// nil when no remote is configured.
+func startButtonForwarder(cfg *config.Config) *buttonForwarder {
+	forward := cfg.Control.ForwardButtons
+	if forward.Remote == "" {
+		return nil
+	}
+	if forward.Token == "" {
+		logrus.Warn("Button forwarding disabled, it needs the remote's token")
+		return nil
+	}
+
+	names := forward.Buttons
+	if len(names) == 0 {
+		names = []string{buttons.Enter.String(), buttons.Select.String()}
+	}
+	forwarded := make(map[controller.PanelButton]bool, len(names))
+	for _, name := range names {
+		button, err := buttons.Parse(name)
+		if err != nil {
+			logrus.WithError(err).Warn("Button not forwarded")
+			continue
+		}
+		forwarded[button] = true
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"remote":  forward.Remote,
+		"buttons": names,
+	}).Info("Forwarding buttons")
+	return &buttonForwarder{
+		ButtonForwarder: control.NewButtonForwarder(forward.Remote, forward.Token),
+		buttons:         forwarded,
+		exclusive:       forward.Exclusive,
+	}
+}
+
+// forward relays the event if its button is forwarded. It returns true if
+// this panel should leave the event alone.
+func (f *buttonForwarder) forward(button controller.PanelButton, pressed bool) bool {
+	if f == nil || !f.buttons[button] {
+		return false
+	}
+	f.Forward(button.String(), pressed)
+	return f.exclusive
+}
+
+// handleRemoteButtons answers the "button" requests of other hosts'
+// forwarders, handling each event like one of this panel's own
+func handleRemoteButtons(server *control.Server, handleButton controller.ButtonEventHandler) {
+	server.Handle("button", func(request control.Request) (string, error) {
+		button, err := buttons.Parse(request.Button)
+		if err != nil {
+			return "", err
+		}
+		handleButton(button, request.Pressed)
+		return "", nil
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/qnap/display-control/internal/version"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// serviceHealth answers the status request with what the service is doing
+type serviceHealth struct {
+	cfg      *config.Config
+	system   controller.SystemControllerInterface
+	screens  *screen.ScreenManager
+	eventLog *events.Log
+
+	mutex sync.Mutex
+	// copying is the last progress of the copy running, nil while none is,
+	// and started when its first progress came
+	copying *events.Event
+	started time.Time
+}
+
+// handleStatus answers status requests on the control socket. It returns a
+// function that stops following the copies.
+func handleStatus(server *control.Server, cfg *config.Config, system controller.SystemControllerInterface,
+	screens *screen.ScreenManager, eventLog *events.Log) func() {
+	h := &serviceHealth{cfg: cfg, system: system, screens: screens, eventLog: eventLog}
+
+	// Copy progress is only published, so it is followed rather than queried
+	progress, cancel := eventLog.Subscribe(events.Filter{Kinds: []events.Kind{events.KindProgress}}, 16)
+	go func() {
+		for event := range progress {
+			h.mutex.Lock()
+			if event.Fields["status"] == "" {
+				if h.copying == nil || h.copying.Fields["profile"] != event.Fields["profile"] {
+					h.started = event.Time
+				}
+				event := event
+				h.copying = &event
+			} else {
+				h.copying = nil
+			}
+			h.mutex.Unlock()
+		}
+	}()
+
+	server.Handle("status", h.status)
+	return cancel
+}
+
+// status reports the version and uptime, the serial link, the panel, the
+// last button pressed, the LEDs and the copy running, one per line
+func (h *serviceHealth) status(control.Request) (string, error) {
+	now := time.Now()
+	info := version.Get()
+	lines := []string{
+		fmt.Sprintf("Version:  %s", info),
+		fmt.Sprintf("Uptime:   %s", sysinfo.FormatUptime(info.Uptime)),
+		"Serial:   " + h.serial(),
+	}
+
+	screenName := "none"
+	if active, ok := h.screens.Active(); ok {
+		screenName = active.String()
+	}
+	lines = append(lines, "Screen:   "+screenName)
+	for _, line := range h.screens.Snapshot() {
+		lines = append(lines, "          |"+line+"|")
+	}
+
+	button := "none since the service started"
+	pressed := h.eventLog.Query(events.Filter{Kinds: []events.Kind{events.KindButton}, Limit: 1})
+	if len(pressed) == 1 {
+		event := pressed[0]
+		button = fmt.Sprintf("%s %s ago (%s)", event.Message, since(now, event.Time), event.Time.Format("01-02 15:04:05"))
+	}
+	lines = append(lines, "Button:   "+button, "LEDs:     "+h.leds())
+
+	copying := "none"
+	h.mutex.Lock()
+	if h.copying != nil {
+		copying = fmt.Sprintf("%s %s%%, started %s ago", h.copying.Fields["profile"], h.copying.Fields["percent"],
+			since(now, h.started))
+	}
+	h.mutex.Unlock()
+	lines = append(lines, "Copy:     "+copying)
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// serial describes the serial port and its link, e.g. "/dev/ttyS1, link up,
+// 1204 commands, 3 retried, 0 failed"
+func (h *serviceHealth) serial() string {
+	display := h.system.GetDisplayController()
+	link := "link down"
+	switch display.LinkState() {
+	case controller.BreakerClosed:
+		link = "link up"
+	case controller.BreakerHalfOpen:
+		link = "probing link"
+	}
+	stats := display.FrameStats()
+	return fmt.Sprintf("%s, %s, %d commands, %d retried, %d failed", h.cfg.SerialPort.Device, link,
+		stats.Sent, stats.Retries, stats.Failed)
+}
+
+// leds lists the LEDs switched on, e.g. "on: status-green, disk3"
+func (h *serviceHealth) leds() string {
+	leds := h.system.GetLEDController()
+	if leds == nil {
+		return "none on this panel"
+	}
+	states, err := leds.GetLEDStates()
+	if err != nil {
+		return "unknown: " + err.Error()
+	}
+	if len(states) == 0 {
+		return "unknown, no access to their ports"
+	}
+	var on []controller.PanelLED
+	for led, lit := range states {
+		if lit {
+			on = append(on, led)
+		}
+	}
+	if len(on) == 0 {
+		return "all off"
+	}
+	sort.Slice(on, func(i, j int) bool { return on[i] < on[j] })
+	names := make([]string, len(on))
+	for i, led := range on {
+		names[i] = led.String()
+	}
+	return "on: " + strings.Join(names, ", ")
+}
+
+// since renders how long ago t was, e.g. "3m" or "2h 5m"
+func since(now, t time.Time) string {
+	elapsed := now.Sub(t)
+	if elapsed < time.Minute {
+		return fmt.Sprintf("%ds", int(elapsed/time.Second))
+	}
+	return sysinfo.FormatUptime(elapsed)
+}
+
+// newStatusCommand creates the "status" subcommand
+func newStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Print what the running service is doing",
+		Long: "Asks the running service for its version and uptime, the state of the serial link, " +
+			"the screen shown and its lines, the last button event, the LEDs switched on and " +
+			"the USB copy running.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus()
+		},
+	}
+}
+
+// runStatus prints the status of the running service
+func runStatus() error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	output, err := callService(cfg, control.Request{Command: "status"})
+	if errors.Is(err, control.ErrNoService) {
+		return fmt.Errorf("status needs the service running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, output)
+	return nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/cluster"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/nasapi"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sensor"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/sirupsen/logrus"
+)
+
+// idleApp is what the panel shows while idle: an animation or a status
+// rotation, or nothing at all
+type idleApp struct {
+	name      string
+	animation screen.Animation
+	rotation  *screen.StatusRotation
+}
+
+// none reports whether the app leaves the panel as it is
+func (a idleApp) none() bool {
+	return a.animation == nil && a.rotation == nil
+}
+
+// idleWindow shows app between two times of day, given as the time since
+// midnight
+type idleWindow struct {
+	from, to time.Duration
+	app      idleApp
+}
+
+// contains reports whether the window is open at the time since midnight. A
+// window ending before it starts spans midnight, one ending when it starts
+// lasts all day.
+func (w idleWindow) contains(offset time.Duration) bool {
+	switch {
+	case w.from == w.to:
+		return true
+	case w.from < w.to:
+		return offset >= w.from && offset < w.to
+	}
+	return offset >= w.from || offset < w.to
+}
+
+// idleScreensaver plays the idle app due at the time of day after a period
+// without button presses and stops it on the next press
+type idleScreensaver struct {
+	animator *screen.Animator
+	// fallback plays outside the schedule's windows
+	fallback idleApp
+	schedule []idleWindow
+	timeout  time.Duration
+	// usage replaces timeout with the suggested one once it has enough
+	// presses (nil = the configured timeout)
+	usage *state.Usage
+	// busy keeps the animation away while it returns true, such as while a
+	// timer is shown (nil = never busy)
+	busy func() bool
+
+	// sleep hides the screens the animation replaces, wake restores them
+	sleep func()
+	wake  func()
+
+	mutex        sync.Mutex
+	timer        *time.Timer
+	lastActivity time.Time
+	// playing is the app shown, nil while the panel is in use
+	playing *idleApp
+	// switchTimer changes the app when a window opens or closes
+	switchTimer *time.Timer
+	// swallow holds buttons whose release belongs to a wake-up press
+	swallow map[controller.PanelButton]bool
+	stopped bool
+}
+
+// newIdleScreensaver creates the screensaver drawing on the given layer.
+// Rotations read sensor, peer and NAS items from monitor, peers and nas,
+// which may be nil. It returns nil if no idle animation or schedule is
+// configured.
+func newIdleScreensaver(cfg *config.Config, layer *screen.Layer, monitor *sensor.Monitor, peers *cluster.Monitor, nas *nasapi.Monitor, sleep, wake func()) (*idleScreensaver, error) {
+	if cfg.Display.IdleAnimation == "" && len(cfg.Display.IdleSchedule) == 0 {
+		return nil, nil
+	}
+
+	timeout := state.DefaultIdleTimeout
+	if cfg.Display.IdleTimeout > 0 {
+		timeout = time.Duration(cfg.Display.IdleTimeout) * time.Second
+	}
+
+	text := cfg.Display.IdleText
+	if text == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "QNAP"
+		}
+		text = hostname
+	}
+
+	// Windows showing the same rotation or animation share it
+	apps := map[string]idleApp{}
+	app := func(rotation, animation string) (idleApp, error) {
+		name := "animation:" + animation
+		if rotation != "" {
+			name = "rotation:" + rotation
+		}
+		if app, exists := apps[name]; exists {
+			return app, nil
+		}
+		app := idleApp{name: name}
+		var err error
+		if rotation != "" {
+			app.rotation, err = buildRotation(cfg, rotation, layer, monitor, peers, nas)
+		} else {
+			app.animation, err = screen.NewAnimation(animation, text)
+		}
+		if err != nil {
+			return idleApp{}, err
+		}
+		apps[name] = app
+		return app, nil
+	}
+
+	s := &idleScreensaver{
+		timeout: timeout,
+		sleep:   sleep,
+		wake:    wake,
+		swallow: make(map[controller.PanelButton]bool),
+	}
+	if cfg.Display.IdleAnimation != "" {
+		fallback, err := app("", cfg.Display.IdleAnimation)
+		if err != nil {
+			return nil, err
+		}
+		s.fallback = fallback
+	}
+	for i, window := range cfg.Display.IdleSchedule {
+		from, to, err := window.Span()
+		if err != nil {
+			return nil, fmt.Errorf("idle schedule window %d: %w", i+1, err)
+		}
+		shown, err := app(window.Rotation, window.Animation)
+		if err != nil {
+			return nil, fmt.Errorf("idle schedule window %d: %w", i+1, err)
+		}
+		s.schedule = append(s.schedule, idleWindow{from: from, to: to, app: shown})
+	}
+
+	frameDelay := screen.FrameDelay(cfg.Display.Width, cfg.Display.Height, cfg.SerialPort.BaudRate)
+	s.animator = screen.NewAnimator(layer, frameDelay)
+	return s, nil
+}
+
+// tune takes the idle timeout from the pauses between presses counted by
+// usage, once there are enough. Call it before start.
+func (s *idleScreensaver) tune(usage *state.Usage) {
+	s.usage = usage
+}
+
+// idleTimeout returns the timeout, the suggested one when tuned
+func (s *idleScreensaver) idleTimeout() time.Duration {
+	if s.usage != nil {
+		if suggested, ok := s.usage.Stats().SuggestIdleTimeout(); ok {
+			return suggested
+		}
+	}
+	return s.timeout
+}
+
+// start arms the idle timer
+func (s *idleScreensaver) start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastActivity = time.Now()
+	s.timer = time.AfterFunc(s.idleTimeout(), s.play)
+}
+
+// due returns the app for now, that of the first window open or the
+// fallback, and how long until a window opens or closes
+func (s *idleScreensaver) due(now time.Time) (idleApp, time.Duration) {
+	const day = 24 * time.Hour
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second + time.Duration(now.Nanosecond())
+
+	app, found := s.fallback, false
+	next := day
+	for _, window := range s.schedule {
+		if !found && window.contains(offset) {
+			app, found = window.app, true
+		}
+		for _, edge := range []time.Duration{window.from, window.to} {
+			if until := (edge - offset + day) % day; until > 0 && until < next {
+				next = until
+			}
+		}
+	}
+	return app, next
+}
+
+// play starts the idle app due unless a button was pressed since the timer
+// fired or the panel is busy
+func (s *idleScreensaver) play() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped || s.playing != nil || time.Since(s.lastActivity) < s.idleTimeout() {
+		return
+	}
+	if s.busy != nil && s.busy() {
+		s.timer.Reset(s.idleTimeout())
+		return
+	}
+
+	app, until := s.due(time.Now())
+	if app.none() {
+		// Nothing to show until the next window opens
+		s.timer.Reset(until)
+		return
+	}
+	logrus.WithField("app", app.name).Debug("Panel idle, starting idle app")
+	s.sleep()
+	s.launch(app, until)
+}
+
+// launch starts app and arms the switch to the next app after until. Caller
+// must hold the mutex.
+func (s *idleScreensaver) launch(app idleApp, until time.Duration) {
+	if app.rotation != nil {
+		app.rotation.Start()
+	} else {
+		s.animator.Play(app.animation)
+	}
+	s.playing = &app
+	s.armSwitch(until)
+}
+
+// armSwitch calls switchApp after until, unless the app playing changed by
+// then. Caller must hold the mutex.
+func (s *idleScreensaver) armSwitch(until time.Duration) {
+	playing := s.playing
+	s.switchTimer = time.AfterFunc(until, func() { s.switchApp(playing) })
+}
+
+// halt stops the app playing. Caller must hold the mutex.
+func (s *idleScreensaver) halt() {
+	if s.playing == nil {
+		return
+	}
+	s.switchTimer.Stop()
+	if s.playing.rotation != nil {
+		s.playing.rotation.Stop()
+	}
+	s.animator.Stop()
+	s.playing = nil
+}
+
+// switchApp replaces the app playing, if still playing, when a window
+// opened or closed
+func (s *idleScreensaver) switchApp(playing *idleApp) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped || s.playing != playing {
+		return
+	}
+	app, until := s.due(time.Now())
+	if app.name == playing.name {
+		s.armSwitch(until)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{"from": s.playing.name, "to": app.name}).Debug("Switching idle app")
+	s.halt()
+	if app.none() {
+		s.wake()
+		s.timer.Reset(until)
+		return
+	}
+	s.launch(app, until)
+}
+
+// activity records a button event and returns true if the event only woke
+// the panel and must not be handled further. The copy button is never
+// swallowed, so a copy can be started without waking the panel first.
+func (s *idleScreensaver) activity(button controller.PanelButton, pressed bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !pressed {
+		if s.swallow[button] {
+			delete(s.swallow, button)
+			return true
+		}
+		return false
+	}
+
+	s.lastActivity = time.Now()
+	if s.timer != nil {
+		s.timer.Reset(s.idleTimeout())
+	}
+
+	if s.playing == nil {
+		return false
+	}
+
+	s.halt()
+	s.wake()
+
+	if button == controller.ButtonUSBCopy {
+		return false
+	}
+	s.swallow[button] = true
+	return true
+}
+
+// stop stops the idle app and disarms the idle timer
+func (s *idleScreensaver) stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.halt()
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/systemd"
+	"github.com/qnap/display-control/internal/uinput"
+	"github.com/spf13/cobra"
+)
+
+// installServiceOptions are the flags of the "install-service" subcommand
+type installServiceOptions struct {
+	unitDir  string
+	binary   string
+	user     string
+	writable []string
+	enable   bool
+	print    bool
+	force    bool
+}
+
+// newInstallServiceCommand creates the "install-service" subcommand
+func newInstallServiceCommand() *cobra.Command {
+	opts := &installServiceOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "install-service",
+		Short: "Write a hardened systemd unit for the service",
+		Long: "Writes a systemd unit that runs the service with the configuration file given by --config. " +
+			"The unit keeps only the capability needed for I/O port access, allows only the serial port " +
+			"and /dev/port, and makes the file system read-only except for the --writable paths and the " +
+			"configured watch folders.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInstallService(opts, os.Stdout)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.unitDir, "unit-dir", "/etc/systemd/system", "Directory the unit is written to")
+	cmd.Flags().StringVar(&opts.binary, "binary", "", "Path of the service binary (default: this executable)")
+	cmd.Flags().StringVar(&opts.user, "user", "root", "User the service runs as; other users need the dialout group")
+	cmd.Flags().StringSliceVar(&opts.writable, "writable", []string{"/share"}, "Paths the service and its commands may write (repeatable)")
+	cmd.Flags().BoolVar(&opts.enable, "enable", false, "Reload systemd, then enable and start the service")
+	cmd.Flags().BoolVar(&opts.print, "print", false, "Print the unit instead of writing it")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Replace an existing unit file")
+
+	return cmd
+}
+
+// runInstallService renders the unit from the configuration and writes,
+// prints or enables it
+func runInstallService(opts *installServiceOptions, out io.Writer) error {
+	setupLogging()
+	cfg := loadConfiguration()
+
+	binary := opts.binary
+	if binary == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find the service binary, use --binary: %w", err)
+		}
+		binary = executable
+	}
+	configPath, err := filepath.Abs(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file path: %w", err)
+	}
+
+	// The CPU governor toggle writes to sysfs
+	writable := append([]string{"/sys/devices/system/cpu"}, opts.writable...)
+	for _, watch := range cfg.Watch {
+		writable = append(writable, watch.Path)
+	}
+	if keepsState(cfg) {
+		writable = append(writable, filepath.Dir(stateFile(cfg)))
+	}
+	// Exports are written to the USB device by the service itself
+	for _, profile := range cfg.USBCopy.Profiles {
+		if profile.Direction == config.CopyExport && cfg.USBCopy.Source != "" {
+			writable = append(writable, cfg.USBCopy.Source)
+			break
+		}
+	}
+	// Virus scans move infected files from the scanned directory into the
+	// quarantine
+	profiles := append([]config.CopyProfile{{Scan: cfg.USBCopy.Scan, Destination: cfg.USBCopy.Destination,
+		Report: cfg.USBCopy.Report}}, cfg.USBCopy.Profiles...)
+	for _, profile := range profiles {
+		if profile.Scan == nil || profile.Scan.Quarantine == "" {
+			continue
+		}
+		writable = append(writable, profile.Scan.Quarantine)
+		switch {
+		case profile.Direction != config.CopyExport && scansAt(profile, config.ScanBefore):
+			writable = append(writable, cfg.USBCopy.Source)
+		case profile.Direction != config.CopyExport:
+			writable = append(writable, profile.Scan.Path)
+		case scansAt(profile, config.ScanBefore):
+			writable = append(writable, profile.Source)
+		default:
+			writable = append(writable, cfg.USBCopy.Source)
+		}
+	}
+	// Copy reports go into the directory copied to; exports' is the USB
+	// device, already writable
+	for _, profile := range profiles {
+		if profile.Report && profile.Direction != config.CopyExport && importDestination(profile) != "" {
+			writable = append(writable, importDestination(profile))
+		}
+	}
+	if cfg.Logging.MirrorDisplay && cfg.Logging.DisplayLog != "" {
+		writable = append(writable, filepath.Dir(cfg.Logging.DisplayLog))
+	}
+
+	// The control socket's directory below /run is created by systemd,
+	// anywhere else it has to be writable
+	var runtimeDir string
+	if !cfg.Control.Disabled {
+		socketDir := filepath.Dir(controlSocket(cfg))
+		if dir, found := strings.CutPrefix(socketDir, "/run/"); found {
+			runtimeDir = dir
+		} else {
+			writable = append(writable, socketDir)
+		}
+	}
+
+	// Sensors, OLED panels and LCD backpacks are driven through the i2c-dev
+	// nodes of their buses, HD44780 displays through their GPIO chip
+	panel := []string{cfg.SerialPort.Device}
+	switch cfg.Display.Driver {
+	case controller.DriverSSD1306, controller.DriverSH1106:
+		panel = append(panel, fmt.Sprintf("/dev/i2c-%d", cfg.Display.OLED.Bus))
+	case controller.DriverHD44780:
+		panel = append(panel, controller.GPIOChip(cfg))
+	case controller.DriverPCF8574:
+		panel = append(panel, fmt.Sprintf("/dev/i2c-%d", cfg.Display.PCF8574.Bus))
+	}
+	devices := append([]string{"/dev/port"}, panel...)
+	if cfg.Uinput.Enabled {
+		devices = append(devices, uinput.DevicePath)
+	}
+	if device := buzzerDevice(cfg); device != "" {
+		devices = append(devices, device)
+	}
+	for _, sensorCfg := range cfg.Sensors {
+		devices = append(devices, fmt.Sprintf("/dev/i2c-%d", sensorCfg.Bus))
+	}
+	// smartctl runs inside the sandbox unless the root helper runs it
+	for _, drive := range cfg.SMART.Drives {
+		devices = append(devices, "/dev/"+drive)
+	}
+
+	unit, err := systemd.RenderUnit(systemd.UnitOptions{
+		Binary:           binary,
+		ConfigFile:       configPath,
+		User:             opts.user,
+		Devices:          devices,
+		WritablePaths:    writable,
+		RuntimeDirectory: runtimeDir,
+		// The service switches to privileges.user itself after startup
+		DropPrivileges: cfg.Privileges.User != "",
+		// Encrypted USB devices are unlocked and mounted by the service, and
+		// a service started as root unmounts the devices ejected in the menu
+		MountDevices: cfg.USBCopy.LUKS.Enabled || (opts.user == "root" && ejectsDevices(cfg)),
+	})
+	if err != nil {
+		return err
+	}
+	units := map[string]string{systemd.DefaultUnitName: unit}
+	names := []string{systemd.DefaultUnitName}
+
+	// The power-off screen takes the panel over once the service stopped at
+	// shutdown
+	if cfg.PowerOff.Enabled {
+		powerOff, err := systemd.RenderPowerOffUnit(systemd.UnitOptions{
+			Binary:     binary,
+			ConfigFile: configPath,
+			Devices:    panel,
+		})
+		if err != nil {
+			return err
+		}
+		units[systemd.PowerOffUnitName] = powerOff
+		names = append(names, systemd.PowerOffUnitName)
+	}
+
+	if opts.print {
+		for i, name := range names {
+			if len(names) > 1 {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				fmt.Fprintf(out, "# %s\n", name)
+			}
+			if _, err := io.WriteString(out, units[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		unitPath := filepath.Join(opts.unitDir, name)
+		if _, err := os.Stat(unitPath); err == nil && !opts.force {
+			return fmt.Errorf("%s already exists, use --force to replace it", unitPath)
+		}
+	}
+	for _, name := range names {
+		unitPath := filepath.Join(opts.unitDir, name)
+		if err := os.WriteFile(unitPath, []byte(units[name]), 0644); err != nil {
+			return fmt.Errorf("failed to write unit: %w", err)
+		}
+		fmt.Fprintf(out, "Wrote %s\n", unitPath)
+	}
+
+	// The power-off screen is only enabled; it starts at shutdown
+	commands := [][]string{{"daemon-reload"}, {"enable", "--now", systemd.DefaultUnitName}}
+	if cfg.PowerOff.Enabled {
+		commands = append(commands, []string{"enable", systemd.PowerOffUnitName})
+	}
+	if !opts.enable {
+		hint := "systemctl daemon-reload && systemctl enable --now " + systemd.DefaultUnitName
+		if cfg.PowerOff.Enabled {
+			hint += " && systemctl enable " + systemd.PowerOffUnitName
+		}
+		fmt.Fprintf(out, "Enable it with: %s\n", hint)
+		return nil
+	}
+
+	for _, args := range commands {
+		if output, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("systemctl %v failed: %w: %s", args, err, output)
+		}
+	}
+	fmt.Fprintf(out, "Enabled and started %s\n", systemd.DefaultUnitName)
+	if cfg.PowerOff.Enabled {
+		fmt.Fprintf(out, "Enabled %s\n", systemd.PowerOffUnitName)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/lcdproc"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultLCDprocResume is how long lcdproc screens step aside for the menu
+// when the configuration does not say
+const defaultLCDprocResume = 30 * time.Second
+
+// startLCDproc serves lcdproc clients, with their screens on their own
+// layer. It returns the server, for the button handler, or nil when no
+// listen address is configured.
+func startLCDproc(cfg *config.Config, screens *screen.ScreenManager) (*lcdproc.Server, error) {
+	if cfg.LCDproc.Listen == "" {
+		return nil, nil
+	}
+
+	resume := defaultLCDprocResume
+	if cfg.LCDproc.ResumeAfter > 0 {
+		resume = time.Duration(cfg.LCDproc.ResumeAfter) * time.Second
+	}
+	server, err := lcdproc.Listen(cfg.LCDproc.Listen, screens.Layer(screen.PriorityLCDproc), cfg.Display.Width, cfg.Display.Height, resume)
+	if err != nil {
+		return nil, err
+	}
+	server.Start()
+	logrus.WithField("address", server.Addr().String()).Info("Serving lcdproc clients")
+	return server, nil
+}
+
+// startLCDprocClient connects to the remote LCDd that shows the menu and
+// status screens instead of the panel. It returns nil when none is
+// configured.
+func startLCDprocClient(cfg *config.Config) *lcdproc.Client {
+	if cfg.LCDproc.Server == "" {
+		return nil
+	}
+
+	client := lcdproc.NewClient(cfg.LCDproc.Server, cfg.Display.Width, cfg.Display.Height)
+	client.Start()
+	logrus.WithField("server", cfg.LCDproc.Server).Info("Drawing the panel on an lcdproc server")
+	return client
+}
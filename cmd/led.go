@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ledBlinkInterval is how long a blinking LED stays on and off
+const ledBlinkInterval = 500 * time.Millisecond
+
+// scriptLEDs switches the LEDs scripts ask for over the control socket, e.g.
+// a SMART monitor flagging the bay of a failing disk
+type scriptLEDs struct {
+	leds   controller.LEDControllerInterface
+	logger *logrus.Entry
+
+	mutex sync.Mutex
+	// blinking holds the LEDs blinking, each with the function stopping it
+	blinking map[controller.PanelLED]*ledBlink
+}
+
+// ledBlink is one LED blinking until it is cancelled or its time is up
+type ledBlink struct {
+	cancel context.CancelFunc
+}
+
+// handleLEDs answers the led requests on the control socket. leds is nil on
+// panels without LEDs.
+func handleLEDs(server *control.Server, leds controller.LEDControllerInterface) {
+	s := &scriptLEDs{
+		leds:     leds,
+		logger:   logrus.WithField("component", "script_leds"),
+		blinking: make(map[controller.PanelLED]*ledBlink),
+	}
+	server.Handle("led_set", s.set)
+	server.Handle("led_blink", s.blink)
+	server.Handle("led_get", s.get)
+}
+
+// parse returns the LED named in a request
+func (s *scriptLEDs) parse(request control.Request) (controller.PanelLED, error) {
+	if s.leds == nil {
+		return 0, errors.New("this panel has no LEDs")
+	}
+	return controller.ParseLED(request.LED)
+}
+
+// set switches an LED "on" or "off", the request's text, and stops it
+// blinking
+func (s *scriptLEDs) set(request control.Request) (string, error) {
+	led, err := s.parse(request)
+	if err != nil {
+		return "", err
+	}
+	on, ok := parseOnOff([]byte(request.Text))
+	if !ok {
+		return "", fmt.Errorf("invalid LED state %q, use on or off", request.Text)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopBlinking(led)
+	return "", s.leds.SetLED(led, on)
+}
+
+// blink blinks an LED until it is set, or for the request's duration after
+// which it is back as it was
+func (s *scriptLEDs) blink(request control.Request) (string, error) {
+	led, err := s.parse(request)
+	if err != nil {
+		return "", err
+	}
+	states, err := s.leds.GetLEDStates()
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopBlinking(led)
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if request.Duration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(request.Duration)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	b := &ledBlink{cancel: cancel}
+	s.blinking[led] = b
+	was := states[led]
+	on := !was
+	if err := s.leds.SetLED(led, on); err != nil {
+		s.logger.WithError(err).WithField("led", led.String()).Warn("Failed to blink LED")
+	}
+	go func() {
+		defer cancel()
+
+		ticker := time.NewTicker(ledBlinkInterval)
+		defer ticker.Stop()
+		for {
+			timedOut := false
+			select {
+			case <-ctx.Done():
+				timedOut = true
+			case <-ticker.C:
+			}
+
+			s.mutex.Lock()
+			if s.blinking[led] != b {
+				// Set or blinking again; the new caller owns the LED
+				s.mutex.Unlock()
+				return
+			}
+			on = !on
+			if timedOut {
+				delete(s.blinking, led)
+				on = was
+			}
+			if err := s.leds.SetLED(led, on); err != nil {
+				s.logger.WithError(err).WithField("led", led.String()).Warn("Failed to blink LED")
+			}
+			s.mutex.Unlock()
+			if timedOut {
+				return
+			}
+		}
+	}()
+	return "", nil
+}
+
+// stopBlinking stops an LED blinking, leaving it to the caller. Caller must
+// hold the mutex.
+func (s *scriptLEDs) stopBlinking(led controller.PanelLED) {
+	if b, ok := s.blinking[led]; ok {
+		delete(s.blinking, led)
+		b.cancel()
+	}
+}
+
+// get lists the LEDs with their state, one per line, e.g. "disk3 blinking",
+// or only the LED named in the request
+func (s *scriptLEDs) get(request control.Request) (string, error) {
+	if s.leds == nil {
+		return "", errors.New("this panel has no LEDs")
+	}
+	only := controller.PanelLED(-1)
+	if request.LED != "" {
+		led, err := controller.ParseLED(request.LED)
+		if err != nil {
+			return "", err
+		}
+		only = led
+	}
+	states, err := s.leds.GetLEDStates()
+	if err != nil {
+		return "", err
+	}
+	if len(states) == 0 {
+		return "", errors.New("the LEDs cannot be read, the service has no access to their ports")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var lines []string
+	for led := controller.StatusGreen; led <= controller.Disk6; led++ {
+		on, ok := states[led]
+		if !ok || (only >= 0 && led != only) {
+			continue
+		}
+		state := "off"
+		switch {
+		case s.blinking[led] != nil:
+			state = "blinking"
+		case on:
+			state = "on"
+		}
+		lines = append(lines, led.String()+" "+state)
+	}
+	if only >= 0 && len(lines) == 0 {
+		return "", fmt.Errorf("this panel has no %s LED", only)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// newLEDCommand creates the "led" subcommand and its subcommands
+func newLEDCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "led",
+		Short: "Switch the panel LEDs from scripts",
+		Long: "Switches the LEDs through the running service, e.g. so a SMART monitor can flag the " +
+			"bay of a failing disk. LEDs are named status-green, status-red, usb and disk1 to disk6.",
+	}
+
+	var blinkFor time.Duration
+	blink := &cobra.Command{
+		Use:   "blink NAME",
+		Short: "Blink an LED",
+		Long: "Blinks an LED until \"led set\" switches it, or for --duration after which it is " +
+			"back as it was.",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArgs(ledNames()),
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if blinkFor < 0 {
+				return fmt.Errorf("invalid duration %s", blinkFor)
+			}
+			return runLED(control.Request{Command: "led_blink", LED: args[0], Duration: int(blinkFor.Round(time.Second) / time.Second)})
+		},
+	}
+	blink.Flags().DurationVar(&blinkFor, "duration", 0, "How long the LED blinks (default until it is set)")
+
+	command.AddCommand(
+		&cobra.Command{
+			Use:               "set NAME on|off",
+			Short:             "Switch an LED on or off",
+			Args:              cobra.ExactArgs(2),
+			ValidArgsFunction: completeArgs(ledNames(), []string{"on", "off"}),
+			SilenceUsage:      true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if _, ok := parseOnOff([]byte(args[1])); !ok {
+					return fmt.Errorf("invalid LED state %q, use on or off", args[1])
+				}
+				return runLED(control.Request{Command: "led_set", LED: args[0], Text: args[1]})
+			},
+		},
+		blink,
+		&cobra.Command{
+			Use:               "get [NAME]",
+			Short:             "Print the state of the LEDs",
+			Args:              cobra.MaximumNArgs(1),
+			ValidArgsFunction: completeArgs(ledNames()),
+			SilenceUsage:      true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				request := control.Request{Command: "led_get"}
+				if len(args) == 1 {
+					request.LED = args[0]
+				}
+				return runLED(request)
+			},
+		},
+	)
+	return command
+}
+
+// runLED sends an led request to the service and prints its answer
+func runLED(request control.Request) error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	output, err := callService(cfg, request)
+	if errors.Is(err, control.ErrNoService) {
+		return fmt.Errorf("the led commands need the service running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintln(os.Stdout, output)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/sirupsen/logrus"
+)
+
+// ledSaveInterval is how often changed LED states are written to the state
+// store, so a copy flashing the disk LEDs does not write it all the time
+const ledSaveInterval = 30 * time.Second
+
+// restoresLEDs reports whether the LED states are kept across restarts
+func restoresLEDs(cfg *config.Config) bool {
+	return cfg.Hardware.RestoreLEDs == nil || *cfg.Hardware.RestoreLEDs
+}
+
+// savedLEDs keeps the last state of every LED in the state store
+type savedLEDs struct {
+	states *state.LEDStates
+	logger *logrus.Entry
+	stop   chan struct{}
+	done   chan struct{}
+
+	mutex   sync.Mutex
+	current map[string]bool
+	// dirty is set while current holds changes not written yet
+	dirty bool
+}
+
+// startSavedLEDs writes the LED states told to record to store in the
+// background. It returns nil when LEDs are not restored or there is no
+// store.
+func startSavedLEDs(cfg *config.Config, store *state.Store) *savedLEDs {
+	if !restoresLEDs(cfg) || store == nil {
+		return nil
+	}
+	s := &savedLEDs{
+		states:  state.NewLEDStates(store),
+		logger:  logrus.WithField("component", "saved_leds"),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		current: make(map[string]bool),
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(ledSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.save()
+			}
+		}
+	}()
+	return s
+}
+
+// restore switches the LEDs to their kept states. The status LED is left
+// alone when the panel did not answer at startup: its red light is a fresh
+// health evaluation, and so is everything switched after the restore, e.g.
+// by alerts.
+func (s *savedLEDs) restore(leds controller.LEDControllerInterface, linkUp bool) {
+	kept, err := s.states.Load()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to read kept LED states")
+		return
+	}
+	if leds == nil || len(kept) == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	for name, on := range kept {
+		s.current[name] = on
+	}
+	s.mutex.Unlock()
+
+	restored := 0
+	for led := controller.StatusGreen; led <= controller.Disk6; led++ {
+		on, ok := kept[led.String()]
+		if !ok {
+			continue
+		}
+		if !linkUp && (led == controller.StatusGreen || led == controller.StatusRed) {
+			continue
+		}
+		if err := leds.SetLED(led, on); err != nil {
+			s.logger.WithError(err).WithField("led", led.String()).Warn("Failed to restore LED")
+			continue
+		}
+		restored++
+	}
+	s.logger.WithField("leds", restored).Info("Restored LED states")
+}
+
+// record notes an LED switched, to be written with the next save
+func (s *savedLEDs) record(led controller.PanelLED, on bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if was, ok := s.current[led.String()]; ok && was == on {
+		return
+	}
+	s.current[led.String()] = on
+	s.dirty = true
+}
+
+// save writes the states if they changed
+func (s *savedLEDs) save() {
+	s.mutex.Lock()
+	if !s.dirty {
+		s.mutex.Unlock()
+		return
+	}
+	states := make(map[string]bool, len(s.current))
+	for name, on := range s.current {
+		states[name] = on
+	}
+	s.dirty = false
+	s.mutex.Unlock()
+
+	if err := s.states.Save(states); err != nil {
+		s.logger.WithError(err).Warn("Failed to keep LED states")
+		s.mutex.Lock()
+		s.dirty = true
+		s.mutex.Unlock()
+	}
+}
+
+// Stop writes the last changes and stops saving
+func (s *savedLEDs) Stop() {
+	close(s.stop)
+	<-s.done
+	s.save()
+}
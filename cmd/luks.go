@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qnap/display-control/internal/broker"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/luks"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/qnap/display-control/internal/usbexport"
+	"github.com/sirupsen/logrus"
+)
+
+// passphraseAttempts is how often a wrong passphrase may be entered before
+// the copy is given up
+const passphraseAttempts = 3
+
+// passphraseTimeout is how long entering the passphrase may take
+const passphraseTimeout = 2 * time.Minute
+
+// luksCommands are the commands unlocking encrypted USB devices at the copy
+// source
+func luksCommands(cfg *config.Config) luks.Commands {
+	return luks.NewCommands(cfg.USBCopy.LUKS.KeyFile, cfg.USBCopy.Source)
+}
+
+// unlockUSB unlocks an encrypted USB device and mounts it at the copy
+// source before a copy, asking for the passphrase on the panel unless a key
+// file is configured. Nothing is done when usb_copy.luks is off, a device is
+// mounted there already or no USB disk is encrypted. It returns the function
+// locking the device again, which does nothing when called twice, nil if none
+// was unlocked, and false if the copy cannot go on.
+func unlockUSB(cfg *config.Config, helper *broker.Client, screens *screen.ScreenManager, prompter *prompt.Prompter) (func() error, bool) {
+	if !cfg.USBCopy.LUKS.Enabled || cfg.USBCopy.Source == "" {
+		return nil, true
+	}
+	if usbexport.CheckMounted(cfg.USBCopy.Source) == nil {
+		return nil, true
+	}
+	device, err := luks.FindDevice()
+	if errors.Is(err, luks.ErrNoDevice) {
+		return nil, true
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to look for encrypted USB devices")
+		return nil, true
+	}
+
+	run := sysinfo.RunShell
+	if helper != nil {
+		run = helper.Run
+	}
+	unlocker := luks.NewUnlocker(luksCommands(cfg), run)
+	logger := logrus.WithField("device", device)
+
+	for attempt := 1; attempt <= passphraseAttempts; attempt++ {
+		var passphrase string
+		if cfg.USBCopy.LUKS.KeyFile == "" {
+			ctx, cancel := context.WithTimeout(context.Background(), passphraseTimeout)
+			passphrase, err = prompter.Secret(ctx, "Passphrase", "digits")
+			cancel()
+			if err != nil {
+				logger.WithError(err).Info("Passphrase not entered")
+				return nil, false
+			}
+		}
+
+		err = unlock(unlocker, device, passphrase, screens)
+		if err == nil {
+			logger.Info("Unlocked encrypted USB device")
+			locked := false
+			return func() error {
+				if locked {
+					return nil
+				}
+				locked = true
+				return unlocker.Lock()
+			}, true
+		}
+		if !errors.Is(err, luks.ErrWrongPassphrase) || cfg.USBCopy.LUKS.KeyFile != "" {
+			break
+		}
+		logger.WithField("attempt", attempt).Warn("Wrong passphrase for encrypted USB device")
+		showUnlockResult(prompter, "Wrong passphrase")
+	}
+
+	logger.WithError(err).Error("Failed to unlock encrypted USB device")
+	if !errors.Is(err, luks.ErrWrongPassphrase) {
+		showUnlockResult(prompter, "Unlock failed")
+	}
+	return nil, false
+}
+
+// unlock runs the unlock with a note on the copy screen, since deriving the
+// key takes a few seconds
+func unlock(unlocker *luks.Unlocker, device, passphrase string, screens *screen.ScreenManager) error {
+	layer := screens.Layer(screen.PriorityCopy)
+	defer func() {
+		if err := layer.Release(); err != nil {
+			logrus.WithError(err).Error("Failed to restore previous screen")
+		}
+	}()
+	if err := layer.WriteText("USB Copy\nUnlocking..."); err != nil {
+		logrus.WithError(err).Debug("Failed to show unlock progress")
+	}
+	return unlocker.Unlock(device, passphrase)
+}
+
+// showUnlockResult shows why the device is not unlocked for 3 seconds, or
+// until a button is pressed
+func showUnlockResult(prompter *prompt.Prompter, status string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := prompter.Prompt(ctx, "USB Copy\n"+status); err != nil && ctx.Err() == nil {
+		logrus.WithError(err).Error("Failed to show unlock result")
+	}
+}
+
+// lockUSB locks the device unlocked for a copy, if any. It returns the
+// status line shown with the result when locking failed, so the device is
+// not pulled while still open.
+func lockUSB(lock func() error, statusLine string) string {
+	if lock == nil {
+		return statusLine
+	}
+	if err := lock(); err != nil {
+		logrus.WithError(err).Error("Failed to lock encrypted USB device")
+		return "Lock failed"
+	}
+	logrus.Info("Locked encrypted USB device")
+	return statusLine
+}
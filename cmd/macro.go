@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/menu"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// handleMacros answers macro requests on the control socket. Text names the
+// macro to record, replay or delete.
+func handleMacros(server *control.Server, menuSystem *menu.MenuSystem) {
+	server.Handle("macro_record", func(request control.Request) (string, error) {
+		if err := menuSystem.StartRecording(request.Text, request.Gesture); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Recording %s, hold a button to finish", request.Text), nil
+	})
+	server.Handle("macro_stop", func(request control.Request) (string, error) {
+		macro, err := menuSystem.StopRecording()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Saved %s, %d presses", macro.Name, len(macro.Buttons)), nil
+	})
+	server.Handle("macro_run", func(request control.Request) (string, error) {
+		return "", menuSystem.RunMacro(request.Text)
+	})
+	server.Handle("macro_delete", func(request control.Request) (string, error) {
+		return "", menuSystem.DeleteMacro(request.Text)
+	})
+	server.Handle("macro_list", func(request control.Request) (string, error) {
+		macros, err := menuSystem.Macros()
+		if err != nil {
+			return "", err
+		}
+		lines := make([]string, 0, len(macros))
+		for _, macro := range macros {
+			line := fmt.Sprintf("%s: %s", macro.Name, strings.Join(macro.Buttons, " "))
+			if macro.Gesture != "" {
+				line += fmt.Sprintf(" (%s)", macro.Gesture)
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n"), nil
+	})
+}
+
+// newMacroCommand creates the "macro" subcommand and its subcommands
+func newMacroCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "macro",
+		Short: "Record and replay button presses",
+		Long: "Manages the button macros of the running service. A macro is a recorded sequence " +
+			"of button presses that is replayed from the main menu, from the Macros menu, with " +
+			"this command or with a gesture.",
+	}
+
+	var gesture string
+	record := &cobra.Command{
+		Use:   "record NAME",
+		Short: "Record the next button presses as a macro",
+		Long: "Returns the panel to the main menu and records the following presses. Holding a " +
+			"button, or \"macro stop\", saves the macro.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMacro(control.Request{Command: "macro_record", Text: args[0], Gesture: gesture})
+		},
+	}
+	record.Flags().StringVar(&gesture, "gesture", "", "Gesture replaying the macro, e.g. long_select")
+
+	command.AddCommand(record,
+		&cobra.Command{
+			Use:          "stop",
+			Short:        "Save the macro being recorded",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMacro(control.Request{Command: "macro_stop"})
+			},
+		},
+		&cobra.Command{
+			Use:               "run NAME",
+			Short:             "Replay a macro",
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeMacros,
+			SilenceUsage:      true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMacro(control.Request{Command: "macro_run", Text: args[0]})
+			},
+		},
+		&cobra.Command{
+			Use:          "list",
+			Short:        "List the recorded macros",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMacro(control.Request{Command: "macro_list"})
+			},
+		},
+		&cobra.Command{
+			Use:               "delete NAME",
+			Short:             "Delete a macro",
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeMacros,
+			SilenceUsage:      true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMacro(control.Request{Command: "macro_delete", Text: args[0]})
+			},
+		},
+	)
+	return command
+}
+
+// runMacro sends a macro request to the service and prints its answer
+func runMacro(request control.Request) error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	output, err := callService(cfg, request)
+	if errors.Is(err, control.ErrNoService) {
+		return fmt.Errorf("macros need the service running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintln(os.Stdout, output)
+	}
+	return nil
+}
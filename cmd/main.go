@@ -1,43 +1,93 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"io"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/qnap/display-control/internal/alert"
+	"github.com/qnap/display-control/internal/broker"
 	"github.com/qnap/display-control/internal/config"
 	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/daemon"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/journald"
+	"github.com/qnap/display-control/internal/logging"
 	"github.com/qnap/display-control/internal/menu"
+	"github.com/qnap/display-control/internal/privilege"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/qnap/display-control/internal/version"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// panelAlertKey is the alert raised while a panel that did not answer at
+// boot stays silent
+const panelAlertKey = "panel"
+
+// The flags of the root command; all but --daemon and --simulate apply to
+// every subcommand
 var (
-	configFile = flag.String("config", "/etc/qnap-display/config.json", "Path to configuration file")
-	port       = flag.String("port", "/dev/ttyS1", "Serial port device")
-	baudRate   = flag.Int("baud", 1200, "Serial port baud rate")
-	verbose    = flag.Bool("verbose", false, "Enable verbose logging")
-	daemon     = flag.Bool("daemon", false, "Run as daemon")
+	configFile string
+	port       string
+	baudRate   int
+	verbose    bool
+	daemonMode bool
+	simulate   bool
 )
 
-// executeCopyCommand executes the USB copy command and shows progress
-func executeCopyCommand(cfg *config.Config, systemController *controller.SystemController, menuSystem *menu.MenuSystem) {
-	logrus.Info("Starting USB copy operation")
+// executeCopyCommand executes the USB copy command, or the copy profile
+// chosen on the panel, and shows progress
+func executeCopyCommand(cfg *config.Config, systemController controller.SystemControllerInterface, screens *screen.ScreenManager, prompter *prompt.Prompter, helper *broker.Client, counter *copyCounter, eventLog *events.Log) {
+	profile, ok := chooseCopyProfile(cfg, prompter)
+	if !ok {
+		logrus.Info("USB copy not confirmed")
+		return
+	}
+	lock, ok := unlockUSB(cfg, helper, screens, prompter)
+	if !ok {
+		return
+	}
+	if lock != nil {
+		// Locks the device if the copy gives up early; otherwise it is
+		// locked before the result is shown
+		defer lock()
+	}
+	if profile.Direction == config.CopyExport {
+		executeExport(cfg, profile, systemController, screens, prompter, eventLog, lock)
+		return
+	}
+
+	logrus.WithField("profile", profile.Name).Info("Starting USB copy operation")
 	
-	displayController := systemController.GetDisplayController()
+	// The copy screen preempts the menu and restores it once released
+	copyScreen := screens.Layer(screen.PriorityCopy)
+	defer func() {
+		if err := copyScreen.Release(); err != nil {
+			logrus.WithError(err).Error("Failed to restore previous screen")
+		}
+	}()
+	
+	// A virus scan before the copy checks the USB device first
+	if scansAt(profile, config.ScanBefore) && !scanCopy(cfg, profile, cfg.USBCopy.Source, copyScreen, prompter, eventLog) {
+		logrus.WithField("profile", profile.Name).Warn("USB copy stopped by virus scan")
+		return
+	}
 	
 	// Show "Copy in progress" on first line
-	if err := displayController.WriteTextAt("Copy in progress", 0, 0); err != nil {
+	if err := copyScreen.WriteTextAt("Copy in progress", 0, 0); err != nil {
 		logrus.WithError(err).Error("Failed to show copy progress")
 		return
 	}
 	
 	// Clear second line initially
-	if err := displayController.WriteTextAt("Starting...", 1, 0); err != nil {
+	if err := copyScreen.WriteTextAt("Starting...", 1, 0); err != nil {
 		logrus.WithError(err).Error("Failed to clear second line")
 	}
 	
@@ -47,9 +97,47 @@ func executeCopyCommand(cfg *config.Config, systemController *controller.SystemC
 		defer ledController.SetLED(controller.USB, false)
 	}
 	
+	// The device is identified before the copy, which may unmount it
+	var source copySource
+	var counted bool
+	if counter != nil {
+		identified, err := counter.identify()
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to identify USB device, copy not counted")
+		} else {
+			source, counted = identified, true
+		}
+	}
+
 	// Execute the copy command
-	cmd := exec.Command("sh", "-c", cfg.USBCopy.Command)
-	output, err := cmd.CombinedOutput()
+	started := time.Now()
+	publishCopyProgress(eventLog, profile.Name, 0, "")
+	following, stopFollowing := context.WithCancel(context.Background())
+	followed := followImport(following, cfg, profile, copyScreen)
+	output, err := runCopyCommand(cfg, profile.Command, helper)
+	stopFollowing()
+	<-followed
+	recordCopyCommand(eventLog, profile.Name, profile.Command, time.Since(started), err)
+	if err != nil {
+		publishCopyProgress(eventLog, profile.Name, 0, "failed")
+	} else {
+		publishCopyProgress(eventLog, profile.Name, 100, "ok")
+	}
+	if err == nil && scansAt(profile, config.ScanAfter) {
+		scanCopy(cfg, profile, profile.Scan.Path, copyScreen, prompter, eventLog)
+	}
+
+	// The report reads back what the copy changed in its destination
+	if profile.Report {
+		if err := copyScreen.WriteTextAt("Writing report", 1, 0); err != nil {
+			logrus.WithError(err).Debug("Failed to show copy progress")
+		}
+	}
+	var problems []error
+	if err != nil {
+		problems = append(problems, err)
+	}
+	report := writeCopyReport(profile, importDestination(profile), started, started, problems...)
 	
 	var statusLine string
 	if err != nil {
@@ -59,8 +147,20 @@ func executeCopyCommand(cfg *config.Config, systemController *controller.SystemC
 		logrus.Info("Copy command completed successfully")
 		statusLine = "Copy complete"
 		
-		// Show truncated output if available
-		if len(output) > 0 {
+		// Show how often the device was copied, else truncated output
+		// if available
+		width := cfg.Display.Width
+		if width <= 0 {
+			width = 16
+		}
+		if counted {
+			if summary, err := counter.record(source, width); err != nil {
+				logrus.WithError(err).Warn("Failed to count USB copy")
+			} else {
+				statusLine = summary
+			}
+		}
+		if statusLine == "Copy complete" && len(output) > 0 {
 			outputStr := strings.TrimSpace(string(output))
 			if len(outputStr) > 16 {
 				statusLine = outputStr[:13] + "..."
@@ -69,52 +169,71 @@ func executeCopyCommand(cfg *config.Config, systemController *controller.SystemC
 			}
 		}
 	}
+	statusLine = lockUSB(lock, statusLine)
 	
-	// Show result on second line
-	if err := displayController.WriteTextAt(statusLine, 1, 0); err != nil {
+	// Show the result for 3 seconds, or until a button is pressed
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := prompter.Prompt(ctx, "USB Copy\n"+statusLine); err != nil && ctx.Err() == nil {
 		logrus.WithError(err).Error("Failed to show copy result")
 	}
+	showCopyReport(cfg, prompter, report)
 	
-	// Wait 3 seconds to show the result
-	time.Sleep(3 * time.Second)
-	
-	// Return to menu system if it's running
-	if menuSystem != nil {
-		logrus.Info("Returning to menu system")
-		// Refresh the menu display
-		if err := menuSystem.RefreshDisplay(); err != nil {
-			logrus.WithError(err).Error("Failed to refresh menu display")
-		}
-	} else {
-		// Clear display if no menu system
-		if err := displayController.ClearDisplay(); err != nil {
-			logrus.WithError(err).Error("Failed to clear display")
-		}
-	}
+	logrus.Info("Returning to previous screen")
 }
 
 func main() {
-	var rootCmd = &cobra.Command{
+	if err := newRootCommand().Execute(); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// newRootCommand creates the command tree. The root command runs the service,
+// as the systemd unit starts it; everything else is a subcommand, so the
+// shells can complete them (see "completion").
+func newRootCommand() *cobra.Command {
+	rootCmd := &cobra.Command{
 		Use:   "qnap-display-control",
 		Short: "QNAP Display Control with USB Copy Button Support",
-		Long:  "A high-performance display controller for QNAP devices with USB copy button monitoring",
-		Run:   runMain,
+		Long: "A high-performance display controller for QNAP devices with USB copy button monitoring. " +
+			"Without a command it runs the service; the commands talk to the running service or " +
+			"check the hardware.",
+		Args: cobra.NoArgs,
+		Run:  runMain,
 	}
 
-	rootCmd.Flags().StringVarP(configFile, "config", "c", "/etc/qnap-display/config.json", "Configuration file path")
-	rootCmd.Flags().StringVarP(port, "port", "p", "/dev/ttyS1", "Serial port device")
-	rootCmd.Flags().IntVarP(baudRate, "baud", "b", 1200, "Serial port baud rate")
-	rootCmd.Flags().BoolVarP(verbose, "verbose", "v", false, "Enable verbose logging")
-	rootCmd.Flags().BoolVarP(daemon, "daemon", "d", false, "Run as daemon")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "/etc/qnap-display/config.json", "Configuration file path")
+	rootCmd.PersistentFlags().StringVarP(&port, "port", "p", "/dev/ttyS1", "Serial port device")
+	rootCmd.PersistentFlags().IntVarP(&baudRate, "baud", "b", 1200, "Serial port baud rate")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	rootCmd.Flags().BoolVarP(&daemonMode, "daemon", "d", false, "Run as daemon")
+	rootCmd.Flags().BoolVar(&simulate, "simulate", false, "Show the panel in the terminal instead of opening the hardware")
+	rootCmd.MarkFlagsMutuallyExclusive("daemon", "simulate")
+	registerFlagCompletions(rootCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		logrus.Fatal(err)
-	}
+	rootCmd.AddCommand(newDemoCommand())
+	rootCmd.AddCommand(newSelftestCommand())
+	rootCmd.AddCommand(newDebugCommand())
+	rootCmd.AddCommand(newInstallServiceCommand())
+	rootCmd.AddCommand(newPowerOffCommand())
+	rootCmd.AddCommand(newBrokerCommand())
+	rootCmd.AddCommand(newVersionCommand())
+	rootCmd.AddCommand(newConfigCommand())
+	rootCmd.AddCommand(newWriteCommand())
+	rootCmd.AddCommand(newDisplayCommand())
+	rootCmd.AddCommand(newLEDCommand())
+	rootCmd.AddCommand(newStatusCommand())
+	rootCmd.AddCommand(newMaintenanceCommand())
+	rootCmd.AddCommand(newReadOnlyCommand())
+	rootCmd.AddCommand(newMacroCommand())
+	rootCmd.AddCommand(newStopCommand())
+	rootCmd.AddCommand(newReloadCommand())
+	return rootCmd
 }
 
-func runMain(cmd *cobra.Command, args []string) {
-	// Configure logging
-	if *verbose {
+// setupLogging configures the global logger from the command line flags
+func setupLogging() {
+	if verbose {
 		logrus.SetLevel(logrus.DebugLevel)
 	} else {
 		logrus.SetLevel(logrus.InfoLevel)
@@ -123,94 +242,602 @@ func runMain(cmd *cobra.Command, args []string) {
 	logrus.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 	})
+}
 
-	logrus.Info("Starting QNAP Display Control Service")
+// setupLogBackend switches the global logger to the format and backend
+// configured, once the configuration is loaded
+func setupLogBackend(cfg *config.Config) {
+	formatter, err := logging.Formatter(cfg.Logging.Format)
+	if err != nil {
+		logrus.WithError(err).Warn("Logging text")
+	} else {
+		logrus.SetFormatter(formatter)
+	}
+	if cfg.Logging.Format == "json" || cfg.Logging.Backend == config.LogBackendJournald {
+		logrus.AddHook(logging.ErrorTypeHook{})
+	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig(*configFile)
+	switch cfg.Logging.Backend {
+	case "", config.LogBackendText:
+	case config.LogBackendJournald:
+		if !journald.Available() {
+			logrus.Warn("journald not running, logging to stderr")
+			return
+		}
+		// Entries journald does not take still go to stderr
+		logrus.AddHook(journald.NewHook(journald.New("qnap-display"), os.Stderr))
+		logrus.SetOutput(io.Discard)
+	default:
+		logrus.WithField("backend", cfg.Logging.Backend).Warn("Unknown logging backend, logging to stderr")
+	}
+}
+
+// loadConfiguration loads the config file and applies command line overrides
+func loadConfiguration() *config.Config {
+	cfg, err := config.LoadConfig(configFile)
 	if err != nil {
 		logrus.WithError(err).Warn("Failed to load config file, using defaults")
 		cfg = config.DefaultConfig()
 	}
 
 	// Override config with command line flags
-	if *port != "/dev/ttyS1" {
-		cfg.SerialPort.Device = *port
+	if port != "/dev/ttyS1" {
+		cfg.SerialPort.Device = port
 	}
-	if *baudRate != 1200 {
-		cfg.SerialPort.BaudRate = *baudRate
+	if baudRate != 1200 {
+		cfg.SerialPort.BaudRate = baudRate
+	}
+
+	return cfg
+}
+
+func runMain(cmd *cobra.Command, args []string) {
+	setupLogging()
+
+	// --daemon starts the service again without a terminal; this process
+	// only waits for it to come up
+	detached := daemon.Detached()
+	if daemonMode && !detached {
+		runDetached()
+	}
+
+	// --simulate shows the panel in the terminal instead of opening the
+	// hardware
+	var sim *simulation
+	if simulate {
+		var err error
+		if sim, err = newSimulation(); err != nil {
+			logrus.WithError(err).Fatal("Failed to start the simulator")
+		}
+	}
+
+	logrus.WithField("version", version.Get().String()).Info("Starting QNAP Display Control Service")
+
+	// Load configuration
+	cfg := loadConfiguration()
+	setupLogBackend(cfg)
+	if sim != nil {
+		// Nothing needs root without hardware, so privileges are kept
+		cfg.Privileges.User = ""
+	}
+	if detached {
+		defer startDaemon(cfg)()
+	}
+
+	// Recent buttons, screens, commands and serial link changes are kept for
+	// the events API
+	eventLog := events.NewLog(cfg.Events.Size)
+
+	// The helper for privileged commands has to start while we are root
+	helper, err := startBroker(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to start privileged helper")
+	}
+	if helper != nil {
+		defer helper.Close()
 	}
 
 	// Initialize system controller (includes display and LED controllers)
-	systemController, err := controller.NewSystemController(cfg)
+	var systemController *controller.SystemController
+	if sim != nil {
+		systemController, err = sim.open(cfg)
+	} else {
+		systemController, err = controller.NewSystemController(cfg)
+	}
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize system controller")
 	}
 	defer systemController.Close()
 
+	// I2C sensors are opened now too; polling starts once alerts can be shown
+	sensors, i2cBuses := openSensors(cfg)
+	for _, bus := range i2cBuses {
+		defer bus.Close()
+	}
+
+	// /dev/uinput is only writable by root as well
+	keyboard := openVirtualKeyboard(cfg)
+	if keyboard != nil {
+		defer keyboard.Close()
+	}
+	// and so is the PC speaker
+	buzz := openBuzzer(cfg)
+	if buzz != nil {
+		defer buzz.Close()
+	}
+
+	// Other invocations reach the service over the control socket instead of
+	// opening the serial port again; it is created in /run while still root
+	controlServer, err := listenControl(cfg)
+	if err != nil {
+		logrus.WithError(err).Warn("Control socket disabled")
+	} else if controlServer != nil {
+		defer controlServer.Close()
+	}
+
+	// The serial port and I/O ports are open, root is no longer needed
+	if cfg.Privileges.User != "" {
+		identity, err := privilege.Drop(cfg.Privileges.User)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to drop root privileges")
+		}
+		logrus.WithFields(logrus.Fields{
+			"user": identity.Name,
+			"uid":  identity.UID,
+		}).Info("Dropped root privileges")
+		systemController.PrivilegesDropped()
+	}
+
+	// The state store keeps data across restarts, e.g. copy counters and
+	// the LED states
+	store := openStateStore(cfg)
+
+	// Dashboards following the event stream see the LEDs as they switch, and
+	// the last states are kept across restarts
+	ledStates := startSavedLEDs(cfg, store)
+	systemController.SetLEDHandler(func(led controller.PanelLED, on bool) {
+		publishLED(eventLog, led, on)
+		if ledStates != nil {
+			ledStates.record(led, on)
+		}
+	})
+	if ledStates != nil {
+		defer ledStates.Stop()
+		linkUp := systemController.GetDisplayController().LinkState() == controller.BreakerClosed
+		ledStates.restore(systemController.GetLEDController(), linkUp)
+	}
+
 	displayController := systemController.GetDisplayController()
 
-	// Test display communication first
-	if err := displayController.WriteText("QNAP Starting\nPlease wait..."); err != nil {
-		logrus.WithError(err).Warn("Display test failed, but continuing")
-	} else {
-		logrus.Info("Display communication working")
-		time.Sleep(2 * time.Second) // Show startup message
+	// A remote LCDd can stand in for a broken panel
+	var panel screen.Display = displayController
+	lcdClient := startLCDprocClient(cfg)
+	if lcdClient != nil {
+		defer lcdClient.Close()
+		panel = lcdClient
+	}
+
+	// All writers share the panel through the screen manager
+	screens := screen.NewScreenManager(panel, cfg.Display.Width, cfg.Display.Height)
+	idleScreen := screens.Layer(screen.PriorityIdle)
+	screens.SetSwitchHandler(func(from, to string) {
+		eventLog.Record(events.KindScreen, from+" -> "+to, nil)
+	})
+
+	// What the panel shows can be followed in the log, e.g. on a customer's NAS
+	stopMirror, err := startDisplayMirror(cfg, screens)
+	if err != nil {
+		logrus.WithError(err).Warn("Display mirror disabled")
+	} else if stopMirror != nil {
+		defer stopMirror()
+	}
+
+	// Sensor readings outside their thresholds cover the whole panel until
+	// acknowledged. While the serial link is down the status LED blinks
+	// their severity instead.
+	alerts := alert.NewManager(screens.Layer(screen.PriorityAlert))
+	alerts.SetPageSize(cfg.Display.Width, cfg.Display.Height)
+	if leds := systemController.GetLEDController(); leds != nil {
+		alerts.SetFallbackLEDs(leds)
+	}
+
+	// The panel may have lost its contents while the serial link was down
+	systemController.SetBreakerHandler(func(event controller.BreakerEvent) {
+		recordBreakerEvent(eventLog, event)
+		// A remote LCDd does not depend on the serial link
+		if lcdClient != nil {
+			return
+		}
+		if event.To != controller.BreakerClosed {
+			alerts.SetLinkUp(false)
+			return
+		}
+		if err := screens.Redraw(); err != nil {
+			logrus.WithError(err).Warn("Failed to redraw display after serial link recovered")
+		}
+		alerts.Clear(panelAlertKey)
+		alerts.SetLinkUp(true)
+	})
+
+	// Text another process wrote to the panel's tty is overwritten within
+	// the redraw interval
+	if cfg.Display.RedrawInterval > 0 {
+		defer screens.RedrawEvery(time.Duration(cfg.Display.RedrawInterval) * time.Second)()
+	}
+
+	// A panel that never answered while it was initialized starts with its
+	// link down; the status LED blinks the alert until it comes up. A remote
+	// LCDd standing in for the panel does not need it.
+	if lcdClient == nil && displayController.LinkState() != controller.BreakerClosed {
+		eventLog.Record(events.KindSerial, "panel not answering", nil)
+		alerts.SetLinkUp(false)
+		alerts.RaiseCritical(panelAlertKey, "Panel did not\nanswer at boot")
+	}
+
+	// Maintenance windows hold back alerts and the red LED until they end
+	maintenanceMode := startMaintenance(systemController, screens, alerts, eventLog)
+	defer maintenanceMode.Stop()
+
+	// The splash tests display communication and covers the panel while
+	// the rest starts behind it. Only the local panel can draw its logo.
+	var splashChars controller.CustomChars
+	if lcdClient == nil {
+		splashChars = displayController
+	}
+	showSplash(cfg, screens, splashChars)
+
+	// Peers are polled for the cluster dashboard, and this node's own health
+	// is served to theirs
+	peers, err := newPeerMonitor(cfg)
+	if err != nil {
+		logrus.WithError(err).Warn("Cluster dashboard disabled")
+	} else if peers != nil {
+		peers.Start()
+		defer peers.Stop()
+	}
+	// TrueNAS SCALE or OpenMediaVault on the box report their pools, alerts
+	// and updates; polling starts once the other monitors do
+	nas, err := newNASMonitor(cfg)
+	if err != nil {
+		logrus.WithError(err).Warn("NAS API disabled")
+	}
+	if stopHealthServer, err := startHealthServer(cfg, alerts, eventLog); err != nil {
+		logrus.WithError(err).Warn("Health API disabled")
+	} else if stopHealthServer != nil {
+		defer stopHealthServer()
+	}
+	// Copy button presses, copy results and long presses are posted to
+	// the webhooks
+	if stopWebhooks, err := startWebhooks(cfg, eventLog); err != nil {
+		logrus.WithError(err).Warn("Webhooks disabled")
+	} else if stopWebhooks != nil {
+		defer stopWebhooks()
+	}
+
+	// With a status line, status and menu share the panel, one region each
+	rotation, err := setupStatusLine(cfg, screens, sensors, peers, nas)
+	if err != nil {
+		logrus.WithError(err).Warn("Status line disabled")
+	} else if rotation != nil {
+		rotation.Start()
+		defer rotation.Stop()
+	}
+
+	// Scheduled screens cover the menu until their time is up or a button is pressed
+	scheduler, err := setupScheduler(cfg, screens, sensors, peers, nas)
+	if err != nil {
+		logrus.WithError(err).Warn("Scheduled screens disabled")
+	} else if scheduler != nil {
+		scheduler.Start()
+		defer scheduler.Stop()
+	}
+
+	// Other services push screens, switch LEDs and follow the buttons over gRPC
+	remote, stopRemote, err := startRemoteAPI(cfg, screens, systemController.GetLEDController())
+	if err != nil {
+		logrus.WithError(err).Warn("gRPC API disabled")
+	} else if stopRemote != nil {
+		defer stopRemote()
+	}
+
+	// The panel is published to the MQTT broker, and to Home Assistant
+	if stopMQTT, err := startMQTT(cfg, screens, systemController.GetLEDController(), eventLog); err != nil {
+		logrus.WithError(err).Warn("MQTT disabled")
+	} else if stopMQTT != nil {
+		defer stopMQTT()
+	}
+
+	// lcdproc clients draw their screens above the menu, as if talking to LCDd
+	lcd, err := startLCDproc(cfg, screens)
+	if err != nil {
+		logrus.WithError(err).Warn("lcdproc server disabled")
+	} else if lcd != nil {
+		defer lcd.Close()
+	}
+
+	// Questions are shown above everything but alerts and answered with the buttons
+	prompter := prompt.NewPrompter(screens.Layer(screen.PriorityConfirmation))
+	// The digits of a passphrase must not reach the mirror log, MQTT or the
+	// APIs reading the panel
+	screens.SetConcealed(prompter.SecretOpen)
+
+	// Text written with "qnap-display-control write" is shown like a prompt,
+	// "display progress" bars above the menu, "qnap-display-control
+	// maintenance" switches maintenance mode and "led" the LEDs; "status"
+	// reports what the service is doing
+	if controlServer != nil {
+		handleMaintenance(controlServer, maintenanceMode)
+		handleLEDs(controlServer, systemController.GetLEDController())
+		defer handleStatus(controlServer, cfg, systemController, screens, eventLog)()
+		serveControl(controlServer, prompter, screens)
+	}
+
+	// Copies of each USB device are counted in the state store, which also
+	// keeps a day of SMART samples for the drive trends in the menu
+	copies := newCopyCounter(cfg, store)
+	smartHistory, stopSMART := startSMARTSampler(cfg, store, helper)
+	if stopSMART != nil {
+		defer stopSMART()
+	}
+	// Presses are counted by hour and by the pauses between them, for the
+	// usage screen and the idle timeout they suggest
+	usage := startUsage(cfg, store)
+	if usage != nil {
+		defer usage.Stop()
+	}
+
+	if sensors != nil {
+		sensors.Start()
+		defer sensors.Stop()
+	}
+	if nas != nil {
+		nas.Start(alerts)
+		defer nas.Stop()
+	}
+	// Sensors and NAS API data that stopped refreshing raise a warning
+	if stopStaleWatch := startStaleWatch(cfg, sensors, nas, alerts); stopStaleWatch != nil {
+		defer stopStaleWatch()
+	}
+	// Rules, and the alerts of the sensors, raise alerts and switch LEDs on
+	// the metrics of the sensors, drives, load and NAS API
+	if stopRules := startRules(cfg, sensors, smartHistory, nas, alerts, systemController.GetLEDController(), buzz, maintenanceMode); stopRules != nil {
+		defer stopRules()
 	}
 
 	// Initialize menu system if enabled
 	var menuSystem *menu.MenuSystem
+	menuScreen := screens.Layer(screen.PriorityMenu)
+	// idleText is shown instead of the menu when it is not running
+	idleText := ""
 	if cfg.Menu.Enabled {
-		menuSystem = menu.NewMenuSystem(cfg, displayController)
+		menuSystem = menu.NewMenuSystem(cfg, menuScreen)
+		menuSystem.SetPrompter(prompter)
+		menuSystem.SetActivityLEDs(systemController)
+		menuSystem.SetEventLog(eventLog)
+		if peers != nil {
+			menuSystem.SetPeers(peers)
+		}
+		if helper != nil {
+			menuSystem.SetBroker(helper)
+		}
+		if smartHistory != nil {
+			menuSystem.SetSMARTHistory(smartHistory)
+		}
+		if usage != nil {
+			menuSystem.SetUsage(usage.Usage)
+		}
+		menuSystem.SetMaintenance(maintenanceMode)
+		// A running timer is drawn in big digits on the panel itself
+		if lcdClient == nil {
+			menuSystem.SetBigDigits(displayController)
+		}
+		menuSystem.SetPanelRestart(func() error {
+			return restartPanel(systemController, screens, alerts, lcdClient != nil)
+		})
+		if buzz != nil {
+			menuSystem.SetBuzzer(buzz)
+		}
+		if store != nil {
+			menuSystem.SetMacros(state.NewMacros(store))
+		}
+		if controlServer != nil {
+			handleMacros(controlServer, menuSystem)
+			handleReadOnly(controlServer, menuSystem, eventLog)
+		}
 		if err := menuSystem.Start(); err != nil {
 			logrus.WithError(err).Error("Failed to start menu system")
 			// Fallback to simple display
-			if err := displayController.WriteText("Menu Failed\nBasic Mode"); err != nil {
+			idleText = "Menu Failed\nBasic Mode"
+			if err := idleScreen.WriteText(idleText); err != nil {
 				logrus.WithError(err).Error("Failed to display fallback message")
 			}
 		} else {
 			logrus.Info("Menu system started successfully")
 		}
 		defer menuSystem.Stop()
+		if stopDevices := watchDevices(menuSystem); stopDevices != nil {
+			defer stopDevices()
+		}
 	} else {
 		// Show default message if menu is disabled
-		if err := displayController.WriteText(cfg.Display.DefaultText + "\nMenu Disabled"); err != nil {
+		idleText = cfg.Display.DefaultText + "\nMenu Disabled"
+		if err := idleScreen.WriteText(idleText); err != nil {
 			logrus.WithError(err).Error("Failed to display default message")
 		}
 	}
 
+	// The idle animation or rotation plays on the idle layer; the menu and
+	// the status line step aside for it
+	screensaver, err := newIdleScreensaver(cfg, idleScreen, sensors, peers, nas,
+		func() {
+			if rotation != nil {
+				rotation.Stop()
+			}
+			if idleText == "" {
+				menuScreen.Release()
+			}
+		},
+		func() {
+			if rotation != nil {
+				rotation.Start()
+			}
+			if idleText != "" {
+				idleScreen.WriteText(idleText)
+				return
+			}
+			idleScreen.Release()
+			if err := menuSystem.RefreshDisplay(); err != nil {
+				logrus.WithError(err).Warn("Failed to restore menu after idle animation")
+			}
+		})
+	if err != nil {
+		logrus.WithError(err).Warn("Idle screen disabled")
+	} else if screensaver != nil {
+		if usage != nil && cfg.Display.AutoIdleTimeout {
+			screensaver.tune(usage.Usage)
+		}
+		if menuSystem != nil {
+			screensaver.busy = menuSystem.TimerActive
+		}
+		screensaver.start()
+		defer screensaver.stop()
+	}
+
+	// The event log, the button streams of the gRPC API and other programs
+	// on the virtual keyboard see every press, except those entering a
+	// secret such as a LUKS passphrase, which would give it away
+	observeButton := prompter.Observe(func(button controller.PanelButton, pressed bool) {
+		recordButton(eventLog, button, pressed)
+		if remote != nil {
+			remote.PublishButton(button, pressed)
+		}
+		if keyboard != nil {
+			forwardButton(keyboard, button, pressed)
+		}
+	})
+
+	// The menu gets its presses in order, off the panel's dispatch goroutine
+	menuPresses := startMenuButtons(menuSystem)
+	defer menuPresses.stop()
+
 	// Set up unified button handler for the system controller
-	systemController.SetButtonHandler(func(button controller.PanelButton, pressed bool) {
-		if !pressed {
-			return // Only handle button press events, not releases
+	handleButton := func(button controller.PanelButton, pressed bool) {
+		if !prompter.SecretOpen() {
+			logrus.WithFields(logrus.Fields{
+				"button":  button,
+				"pressed": pressed,
+			}).Debug("Button event received")
+		}
+		observeButton(button, pressed)
+		if usage != nil && pressed {
+			usage.Record(time.Now())
+		}
+
+		// A shown alert is acknowledged by any button
+		if alerts.HandleButton(button, pressed) {
+			return
+		}
+
+		// An open prompt takes every button until it is answered
+		if prompter.HandleButton(button, pressed) {
+			return
 		}
 
-		logrus.WithField("button", button).Info("Button event received")
+		// The startup splash is skipped by any button
+		if screens.HandleSplashButton(pressed) {
+			return
+		}
+
+		// A pushed screen is dismissed by any button, unless it captures them
+		if remote != nil && remote.HandleButton(button, pressed) {
+			return
+		}
+
+		// A scheduled screen is dismissed by any button
+		if scheduler != nil && scheduler.HandleButton(button, pressed) {
+			return
+		}
+
+		// lcdproc clients get the keys they reserved; other presses bring
+		// back the menu
+		if lcd != nil && lcd.HandleButton(button, pressed) {
+			return
+		}
+
+		// The first press after the idle animation started only wakes the panel
+		if screensaver != nil && screensaver.activity(button, pressed) {
+			return
+		}
 
 		switch button {
 		case controller.ButtonEnter:
-			if menuSystem != nil {
-				menuSystem.HandleEnterButton()
-			}
+			// Releases are forwarded too so the menu can detect long presses
+			menuPresses.send(menu.ButtonEnter, pressed)
 		case controller.ButtonSelect:
-			if menuSystem != nil {
-				menuSystem.HandleSelectButton()
-			}
+			menuPresses.send(menu.ButtonSelect, pressed)
 		case controller.ButtonUSBCopy:
+			if !pressed {
+				return
+			}
 			logrus.Info("USB Copy button pressed")
 			// Execute copy command in a goroutine to avoid blocking
-			go executeCopyCommand(cfg, systemController, menuSystem)
+			go executeCopyCommand(cfg, systemController, screens, prompter, helper, copies, eventLog)
+		}
+	}
+	// Presses of the forwarded buttons go to another host's service, and
+	// only there if forwarding is exclusive. Other hosts' forwarded presses
+	// are handled like this panel's own.
+	forwarder := startButtonForwarder(cfg)
+	if forwarder != nil {
+		defer forwarder.Close()
+	}
+	systemController.SetButtonHandler(func(button controller.PanelButton, pressed bool) {
+		// A secret is entered on this panel and never sent on
+		if !prompter.SecretOpen() && forwarder.forward(button, pressed) {
+			return
 		}
+		handleButton(button, pressed)
 	})
+	if controlServer != nil {
+		handleRemoteButtons(controlServer, handleButton)
+	}
+
+	// Keys pressed on a remote LCDd are pressed and released at once
+	if lcdClient != nil {
+		lcdClient.SetKeyHandler(func(button controller.PanelButton) {
+			handleButton(button, true)
+			handleButton(button, false)
+		})
+	}
+
+	// New files in watched directories raise prompts and run hook commands
+	for _, w := range startWatchers(cfg, prompter) {
+		defer w.Close()
+	}
 
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	if sim != nil {
+		defer sim.start(sigChan)()
+	}
 
 	// Main event loop
 	logrus.Info("QNAP Display Control Service started successfully")
+
+	// systemd waits for READY=1 and restarts the service when the panel
+	// reader hangs
+	defer startWatchdog(displayController)()
 	
-	// Wait for shutdown signal
-	sig := <-sigChan
-	logrus.WithField("signal", sig).Info("Received shutdown signal")
+	// Wait for shutdown signal; SIGHUP from "reload" reopens the log file
+	for sig := range sigChan {
+		if sig == syscall.SIGHUP {
+			if detached {
+				reopenLog(cfg)
+			}
+			continue
+		}
+		logrus.WithField("signal", sig).Info("Received shutdown signal")
+		return
+	}
 }
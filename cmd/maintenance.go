@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/qnap/display-control/internal/alert"
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/maintenance"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// startMaintenance creates the maintenance mode. While it is on, alerts are
+// held back, the red status LED stays off and a wrench is drawn in the top
+// right corner of the panel.
+func startMaintenance(systemController *controller.SystemController, screens *screen.ScreenManager, alerts *alert.Manager, eventLog *events.Log) *maintenance.Mode {
+	wrench, _ := controller.GlyphChar("wrench")
+	mode := maintenance.NewMode()
+	mode.OnChange(func(active bool) {
+		badge, state := "", "maintenance off"
+		if active {
+			badge, state = wrench, "maintenance on"
+		}
+		eventLog.Record(events.KindCommand, state, nil)
+		alerts.SetSuppressed(active)
+		if err := systemController.SetQuietStatusLED(active); err != nil {
+			logrus.WithError(err).Warn("Failed to switch the status LED for maintenance")
+		}
+		if err := screens.SetBadge(badge); err != nil {
+			logrus.WithError(err).Warn("Failed to draw the maintenance badge")
+		}
+	})
+	return mode
+}
+
+// restartPanel reopens the panel, the LEDs and the copy button monitor of the
+// running service and draws the screens again on the reopened panel. A panel
+// that does not answer afterwards raises the alert of one that did not
+// answer at boot, unless a remote LCDd stands in for it.
+func restartPanel(systemController *controller.SystemController, screens *screen.ScreenManager, alerts *alert.Manager, remoteLCD bool) error {
+	if err := systemController.Restart(); err != nil {
+		return err
+	}
+	if !remoteLCD && systemController.GetDisplayController().LinkState() != controller.BreakerClosed {
+		alerts.SetLinkUp(false)
+		alerts.RaiseCritical(panelAlertKey, "Panel did not\nanswer restart")
+	}
+	return screens.Redraw()
+}
+
+// handleMaintenance answers maintenance requests on the control socket:
+// "maintenance" starts a window of Duration seconds, or reports the state
+// without one, and "maintenance_off" ends it
+func handleMaintenance(server *control.Server, mode *maintenance.Mode) {
+	server.Handle("maintenance", func(request control.Request) (string, error) {
+		if request.Duration > 0 {
+			if err := mode.Start(time.Duration(request.Duration) * time.Second); err != nil {
+				return "", err
+			}
+		}
+		return mode.Status(), nil
+	})
+	server.Handle("maintenance_off", func(request control.Request) (string, error) {
+		mode.Stop()
+		return mode.Status(), nil
+	})
+}
+
+// newMaintenanceCommand creates the "maintenance" subcommand
+func newMaintenanceCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "maintenance [DURATION|off]",
+		Short: "Hold back alerts while working on the NAS",
+		Long: "Starts a maintenance window of the given duration, e.g. 2h, in the running service. " +
+			"Until it ends, or \"off\" is given, the monitors keep collecting but alerts are not " +
+			"shown, the red status LED stays off and the panel shows a wrench. Without an " +
+			"argument the current state is printed.",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeArgs([]string{"off", "30m", "1h", "2h", "4h"}),
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request := control.Request{Command: "maintenance"}
+			if len(args) == 1 {
+				if args[0] == "off" {
+					request.Command = "maintenance_off"
+				} else {
+					duration, err := time.ParseDuration(args[0])
+					if err != nil {
+						return fmt.Errorf("invalid duration %q", args[0])
+					}
+					if duration < time.Second {
+						return fmt.Errorf("the maintenance window must be at least 1s")
+					}
+					request.Duration = int(duration / time.Second)
+				}
+			}
+			return runMaintenance(request)
+		},
+	}
+}
+
+// runMaintenance sends a maintenance request to the service and prints the
+// resulting state
+func runMaintenance(request control.Request) error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	status, err := callService(cfg, request)
+	if errors.Is(err, control.ErrNoService) {
+		return fmt.Errorf("maintenance mode needs the service running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, status)
+	return nil
+}
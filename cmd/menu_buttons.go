@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/qnap/display-control/internal/menu"
+	"github.com/sirupsen/logrus"
+)
+
+// menuButtonQueue is how many presses may wait for the menu while it runs a
+// command before further ones are dropped
+const menuButtonQueue = 32
+
+// menuButton is a press or release waiting for the menu
+type menuButton struct {
+	button  menu.Button
+	pressed bool
+}
+
+// menuButtons hands presses to the menu in order on a goroutine of its own.
+// The menu may wait for a prompt answered with the following presses, which
+// must not queue behind it on the panel's dispatch goroutine.
+type menuButtons struct {
+	events chan menuButton
+	done   chan struct{}
+}
+
+// startMenuButtons starts handing presses to menuSystem. It returns nil
+// without a menu.
+func startMenuButtons(menuSystem *menu.MenuSystem) *menuButtons {
+	if menuSystem == nil {
+		return nil
+	}
+	m := &menuButtons{
+		events: make(chan menuButton, menuButtonQueue),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-m.done:
+				return
+			case event := <-m.events:
+				menuSystem.HandleButtonEvent(event.button, event.pressed)
+			}
+		}
+	}()
+	return m
+}
+
+// send queues a press or release for the menu
+func (m *menuButtons) send(button menu.Button, pressed bool) {
+	if m == nil {
+		return
+	}
+	select {
+	case m.events <- menuButton{button: button, pressed: pressed}:
+	case <-m.done:
+	default:
+		logrus.WithField("button", button).Warn("Menu busy, button event dropped")
+	}
+}
+
+// stop ends handing presses to the menu
+func (m *menuButtons) stop() {
+	if m != nil {
+		close(m.done)
+	}
+}
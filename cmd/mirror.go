@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/screen"
+)
+
+// startDisplayMirror copies every frame sent to the panel to the debug log
+// and the display log, if one is configured. It returns a function closing
+// the display log, or nil when there is nothing to close.
+func startDisplayMirror(cfg *config.Config, screens *screen.ScreenManager) (func(), error) {
+	if !cfg.Logging.MirrorDisplay {
+		return nil, nil
+	}
+	if cfg.Logging.DisplayLog == "" {
+		screens.SetFrameHandler(screen.NewMirror(nil).Frame)
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(cfg.Logging.DisplayLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open display log: %w", err)
+	}
+	screens.SetFrameHandler(screen.NewMirror(file).Frame)
+	return func() {
+		screens.SetFrameHandler(nil)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/homeassistant"
+	"github.com/qnap/display-control/internal/mqtt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/version"
+	"github.com/sirupsen/logrus"
+)
+
+// mqttDisplayInterval is how often the panel's text is checked for changes
+// to publish
+const mqttDisplayInterval = time.Second
+
+// mqttBuffer is how many events wait to be published before some are missed
+const mqttBuffer = 64
+
+// mqttBridge publishes the panel's state to the broker and carries out the
+// commands sent to it
+type mqttBridge struct {
+	client  *mqtt.Client
+	topics  homeassistant.Topics
+	screens *screen.ScreenManager
+	layer   *screen.Layer
+	leds    controller.LEDControllerInterface
+	logger  *logrus.Entry
+	// discovery is published on every connect; empty without Home Assistant
+	discovery []mqtt.Message
+
+	mutex     sync.Mutex
+	backlight bool
+	// shown is set while text sent to the display topic is on the panel
+	shown bool
+	// text is the panel's text last published
+	text string
+}
+
+// startMQTT publishes the display text, backlight, LEDs and button presses
+// to the configured broker and takes text, backlight and LED commands from
+// it, with Home Assistant discovery if enabled. leds may be nil. It returns
+// a function disconnecting, or nil without a broker.
+func startMQTT(cfg *config.Config, screens *screen.ScreenManager, leds controller.LEDControllerInterface, eventLog *events.Log) (func(), error) {
+	mqttCfg := cfg.MQTT
+	if mqttCfg.Broker == "" {
+		return nil, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "qnap"
+	}
+	clientID := mqttCfg.ClientID
+	if clientID == "" {
+		clientID = "qnap-display-" + hostname
+	}
+	topics := homeassistant.Topics{Base: strings.TrimSuffix(mqttCfg.Topic, "/")}
+	if topics.Base == "" {
+		topics.Base = "qnap-display/" + hostname
+	}
+
+	client, err := mqtt.NewClient(mqtt.Options{
+		Broker:    mqttCfg.Broker,
+		ClientID:  clientID,
+		Username:  mqttCfg.Username,
+		Password:  mqttCfg.Password,
+		KeepAlive: time.Duration(mqttCfg.KeepAlive) * time.Second,
+		Will: &mqtt.Message{
+			Topic:   topics.Availability(),
+			Payload: []byte(homeassistant.PayloadOffline),
+			Retain:  true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bridge := &mqttBridge{
+		client:    client,
+		topics:    topics,
+		screens:   screens,
+		layer:     screens.Layer(screen.PriorityRemote),
+		leds:      leds,
+		logger:    logrus.WithField("component", "mqtt"),
+		backlight: true,
+	}
+	if mqttCfg.HomeAssistant.Enabled {
+		name := mqttCfg.HomeAssistant.Name
+		if name == "" {
+			name = hostname
+		}
+		buttons := []string{
+			controller.ButtonEnter.String(),
+			controller.ButtonSelect.String(),
+			controller.ButtonUSBCopy.String(),
+		}
+		bridge.discovery = homeassistant.Discovery(mqttCfg.HomeAssistant.DiscoveryPrefix, homeassistant.Device{
+			ID:        hostname,
+			Name:      name,
+			Model:     "Front panel",
+			SWVersion: version.Get().Version,
+		}, topics, bridge.ledNames(), buttons)
+	}
+
+	client.Subscribe(topics.DisplayCommand(), bridge.setText)
+	client.Subscribe(topics.BacklightCommand(), bridge.setBacklight)
+	if leds != nil {
+		client.Subscribe(topics.LEDCommand("+"), bridge.setLED)
+	}
+	client.SetConnectHandler(bridge.publishAll)
+	client.Start()
+
+	followed, cancel := eventLog.Subscribe(events.Filter{Kinds: []events.Kind{events.KindButton, events.KindLED}}, mqttBuffer)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		bridge.followEvents(followed)
+	}()
+	go func() {
+		defer wg.Done()
+		bridge.followDisplay(stop)
+	}()
+	bridge.logger.WithFields(logrus.Fields{
+		"broker":         mqttCfg.Broker,
+		"topic":          topics.Base,
+		"home_assistant": mqttCfg.HomeAssistant.Enabled,
+	}).Info("Publishing panel over MQTT")
+
+	return func() {
+		cancel()
+		close(stop)
+		wg.Wait()
+		// A clean disconnect does not publish the will
+		bridge.publish(topics.Availability(), homeassistant.PayloadOffline, true)
+		client.Close()
+	}, nil
+}
+
+// ledNames returns the names of the panel's LEDs in their order, or none
+// without LEDs
+func (b *mqttBridge) ledNames() []string {
+	if b.leds == nil {
+		return nil
+	}
+	states, err := b.leds.GetLEDStates()
+	if err != nil {
+		b.logger.WithError(err).Warn("Failed to read LEDs")
+		return nil
+	}
+	ids := make([]controller.PanelLED, 0, len(states))
+	for led := range states {
+		ids = append(ids, led)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	names := make([]string, 0, len(ids))
+	for _, led := range ids {
+		names = append(names, led.String())
+	}
+	return names
+}
+
+// publish sends a message, logging failures; messages are not queued while
+// the broker is away, the state is published again on connect
+func (b *mqttBridge) publish(topic, payload string, retain bool) {
+	err := b.client.Publish(mqtt.Message{Topic: topic, Payload: []byte(payload), Retain: retain})
+	if err != nil && err != mqtt.ErrNotConnected {
+		b.logger.WithError(err).WithField("topic", topic).Debug("Failed to publish")
+	}
+}
+
+// publishAll publishes the availability, the discovery payloads and the
+// current state after a connect
+func (b *mqttBridge) publishAll() {
+	for _, msg := range b.discovery {
+		if err := b.client.Publish(msg); err != nil {
+			b.logger.WithError(err).Warn("Failed to publish Home Assistant discovery")
+			return
+		}
+	}
+	b.publish(b.topics.Availability(), homeassistant.PayloadOnline, true)
+
+	b.mutex.Lock()
+	b.text = panelText(b.screens.Snapshot())
+	text, backlight := b.text, b.backlight
+	b.mutex.Unlock()
+	b.publish(b.topics.DisplayState(), text, true)
+	b.publish(b.topics.BacklightState(), onOff(backlight), true)
+
+	if b.leds != nil {
+		states, err := b.leds.GetLEDStates()
+		if err != nil {
+			b.logger.WithError(err).Warn("Failed to read LEDs")
+			return
+		}
+		for led, on := range states {
+			b.publish(b.topics.LEDState(led.String()), onOff(on), true)
+		}
+	}
+}
+
+// followDisplay publishes the panel's text whenever it changes, until stop
+// is closed
+func (b *mqttBridge) followDisplay(stop chan struct{}) {
+	ticker := time.NewTicker(mqttDisplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			text := panelText(b.screens.Snapshot())
+			b.mutex.Lock()
+			changed := text != b.text
+			b.text = text
+			b.mutex.Unlock()
+			if changed {
+				b.publish(b.topics.DisplayState(), text, true)
+			}
+		}
+	}
+}
+
+// followEvents publishes button presses and LED changes from the event
+// log until it is cancelled
+func (b *mqttBridge) followEvents(followed <-chan events.Event) {
+	pressed := make(map[string]time.Time)
+	for event := range followed {
+		switch event.Kind {
+		case events.KindLED:
+			b.publish(b.topics.LEDState(event.Fields["led"]), onOff(event.Fields["on"] == "true"), true)
+		case events.KindButton:
+			button := event.Fields["button"]
+			if event.Fields["action"] == "pressed" {
+				pressed[button] = event.Time
+				b.dismissText()
+				continue
+			}
+			since, found := pressed[button]
+			if !found {
+				continue
+			}
+			delete(pressed, button)
+			trigger := homeassistant.TriggerShortPress
+			if event.Time.Sub(since) >= longPressDuration {
+				trigger = homeassistant.TriggerLongPress
+			}
+			b.publish(b.topics.Button(button), trigger, false)
+		}
+	}
+}
+
+// setText shows the text sent to the display command topic above the menu,
+// until a button is pressed; an empty text removes it
+func (b *mqttBridge) setText(msg mqtt.Message) {
+	text := strings.TrimSpace(string(msg.Payload))
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var err error
+	if text == "" {
+		err = b.layer.Release()
+		b.shown = false
+	} else {
+		err = b.layer.WriteText(text)
+		b.shown = err == nil
+	}
+	if err != nil {
+		b.logger.WithError(err).Warn("Failed to show MQTT text")
+	}
+}
+
+// dismissText removes text shown through MQTT, as a button was pressed
+func (b *mqttBridge) dismissText() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.shown {
+		return
+	}
+	b.shown = false
+	if err := b.layer.Release(); err != nil {
+		b.logger.WithError(err).Debug("Failed to remove MQTT text")
+	}
+}
+
+// setBacklight switches the backlight as sent to its command topic
+func (b *mqttBridge) setBacklight(msg mqtt.Message) {
+	on, ok := parseOnOff(msg.Payload)
+	if !ok {
+		b.logger.WithField("payload", string(msg.Payload)).Warn("Invalid backlight command")
+		return
+	}
+	if err := b.layer.SetBacklight(on); err != nil {
+		b.logger.WithError(err).Warn("Failed to switch backlight")
+		return
+	}
+	b.mutex.Lock()
+	b.backlight = on
+	b.mutex.Unlock()
+	b.publish(b.topics.BacklightState(), onOff(on), true)
+}
+
+// setLED switches the LED named in the command topic. Its new state is
+// published from the event log, like every other LED change.
+func (b *mqttBridge) setLED(msg mqtt.Message) {
+	name := strings.TrimSuffix(strings.TrimPrefix(msg.Topic, b.topics.LEDState("")), "/set")
+	on, ok := parseOnOff(msg.Payload)
+	if !ok {
+		b.logger.WithField("payload", string(msg.Payload)).Warn("Invalid LED command")
+		return
+	}
+	states, err := b.leds.GetLEDStates()
+	if err != nil {
+		b.logger.WithError(err).Warn("Failed to read LEDs")
+		return
+	}
+	for led := range states {
+		if led.String() == name {
+			if err := b.leds.SetLED(led, on); err != nil {
+				b.logger.WithError(err).WithField("led", name).Warn("Failed to switch LED")
+			}
+			return
+		}
+	}
+	b.logger.WithField("led", name).Warn("Unknown LED in MQTT command")
+}
+
+// panelText joins the panel's lines for the display topic, without the
+// padding and at most as long as Home Assistant takes
+func panelText(lines []string) string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimRight(line, " ")
+	}
+	text := strings.Join(trimmed, "\n")
+	if runes := []rune(text); len(runes) > homeassistant.MaxText {
+		text = string(runes[:homeassistant.MaxText])
+	}
+	return text
+}
+
+// onOff is the payload of a switch state
+func onOff(on bool) string {
+	if on {
+		return homeassistant.PayloadOn
+	}
+	return homeassistant.PayloadOff
+}
+
+// parseOnOff reads the payload of a switch command
+func parseOnOff(payload []byte) (bool, bool) {
+	switch strings.ToUpper(strings.TrimSpace(string(payload))) {
+	case homeassistant.PayloadOn:
+		return true, true
+	case homeassistant.PayloadOff:
+		return false, true
+	}
+	return false, false
+}
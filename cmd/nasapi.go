@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/nasapi"
+)
+
+// nasStatusPrefix selects part of the NAS system's report as a status item,
+// as in "nas:pools"
+const nasStatusPrefix = "nas:"
+
+// nasRequestTimeout bounds one request to the NAS system's API
+const nasRequestTimeout = 10 * time.Second
+
+// nasStatusItems render the parts of a report that can be status items
+var nasStatusItems = map[string]func(nasapi.Report) string{
+	"pools":  nasapi.Report.PoolSummary,
+	"alerts": nasapi.Report.AlertSummary,
+	"update": nasapi.Report.UpdateSummary,
+}
+
+// newNASMonitor creates a monitor for the API of the configured NAS system,
+// not yet polling, or nil when none is configured
+func newNASMonitor(cfg *config.Config) (*nasapi.Monitor, error) {
+	api := cfg.NASAPI
+	if api.System == "" {
+		return nil, nil
+	}
+	if api.URL == "" {
+		return nil, fmt.Errorf("the %s API needs a url", api.System)
+	}
+
+	client := &http.Client{Timeout: nasRequestTimeout}
+	if api.InsecureTLS {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		client.Transport = transport
+	}
+
+	var source nasapi.Source
+	switch api.System {
+	case "truenas":
+		if api.APIKey == "" {
+			return nil, fmt.Errorf("the TrueNAS API needs an api_key")
+		}
+		source = nasapi.NewTrueNAS(api.URL, api.APIKey, client)
+	case "openmediavault":
+		if api.Username == "" {
+			return nil, fmt.Errorf("the OpenMediaVault API needs a username and password")
+		}
+		source = nasapi.NewOpenMediaVault(api.URL, api.Username, api.Password, client)
+	default:
+		return nil, fmt.Errorf("unknown NAS system %q (available: truenas, openmediavault)", api.System)
+	}
+	interval := time.Duration(api.PollInterval) * time.Second
+	return nasapi.NewMonitor(source, interval), nil
+}
+
+// nasStatusItem shows part of the NAS system's last report on one line; the
+// item is marked once it is stale
+func nasStatusItem(monitor *nasapi.Monitor, render func(nasapi.Report) string) func() (string, error) {
+	return func() (string, error) {
+		if monitor == nil {
+			return "", fmt.Errorf("no NAS API configured")
+		}
+		report, _, err := monitor.Last()
+		if err != nil {
+			return "", err
+		}
+		return render(report), nil
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// powerOffText is the power-off screen when none is configured
+	powerOffText = "Powered off\nHold ENTER: info"
+	// powerOffHold is how long ENTER is held for the boot info
+	powerOffHold = time.Second
+	// bootInfoTime is how long the boot info stays before the power-off
+	// screen is back
+	bootInfoTime = 10 * time.Second
+)
+
+// powerOffScreen shows the power-off screen and, while ENTER is held, the
+// boot info
+type powerOffScreen struct {
+	display controller.DisplayControllerInterface
+	text    string
+	host    *sysinfo.HostProvider
+	logger  *logrus.Entry
+
+	mutex sync.Mutex
+	// hold fires when ENTER was held long enough, back returns from the boot
+	// info; both nil while not running
+	hold *time.Timer
+	back *time.Timer
+	// stopped is set once the panel is being handed back
+	stopped bool
+}
+
+// newPowerOffCommand creates the "poweroff-screen" subcommand
+func newPowerOffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "poweroff-screen",
+		Short: "Show the power-off screen during shutdown",
+		Long: "Shows \"Powered off\" on the panel until the system powers off, for the unit " +
+			"install-service writes with \"poweroff\" enabled, which starts it once the service " +
+			"has stopped at shutdown. Holding ENTER shows when the system booted and how long " +
+			"it was up. It opens only the panel, and closes it when it is told to stop.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPowerOff()
+		},
+	}
+}
+
+// runPowerOff shows the power-off screen until SIGTERM, which systemd sends
+// just before poweroff
+func runPowerOff() error {
+	setupLogging()
+	cfg := loadConfiguration()
+
+	display, err := controller.OpenDisplay(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open the display: %w", err)
+	}
+	s := &powerOffScreen{
+		display: display,
+		text:    powerOffScreenText(cfg),
+		host:    sysinfo.NewHostProvider(""),
+		logger:  logrus.WithField("component", "poweroff_screen"),
+	}
+	display.SetButtonHandler(s.handleButton)
+	display.SetBreakerHandler(s.handleBreaker)
+	if err := display.SetBacklight(true); err != nil {
+		s.logger.WithError(err).Warn("Failed to switch the backlight on")
+	}
+	s.show()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigChan
+	s.logger.WithField("signal", sig).Info("Handing the panel back")
+	return s.close()
+}
+
+// powerOffScreenText returns the configured power-off screen
+func powerOffScreenText(cfg *config.Config) string {
+	if cfg.PowerOff.Text != "" {
+		return cfg.PowerOff.Text
+	}
+	return powerOffText
+}
+
+// show writes the power-off screen
+func (s *powerOffScreen) show() {
+	if err := s.display.WriteText(s.text); err != nil {
+		s.logger.WithError(err).Warn("Failed to show the power-off screen")
+	}
+}
+
+// handleBreaker writes the power-off screen again once the serial link is
+// back, since the panel may have missed it
+func (s *powerOffScreen) handleBreaker(event controller.BreakerEvent) {
+	if event.To != controller.BreakerClosed {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.stopped && s.back == nil {
+		s.show()
+	}
+}
+
+// handleButton shows the boot info once ENTER was held for powerOffHold.
+// Other buttons do nothing, so a press on the way out cannot start anything.
+func (s *powerOffScreen) handleButton(button controller.PanelButton, pressed bool) {
+	if button != controller.ButtonEnter {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return
+	}
+	if !pressed {
+		if s.hold != nil {
+			s.hold.Stop()
+			s.hold = nil
+		}
+		return
+	}
+	if s.hold != nil {
+		return
+	}
+	var hold *time.Timer
+	hold = time.AfterFunc(powerOffHold, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.stopped || s.hold != hold {
+			return
+		}
+		s.hold = nil
+		s.showBootInfo()
+	})
+	s.hold = hold
+}
+
+// showBootInfo shows when the system booted and how long it was up, e.g.
+// "Boot 10-17 04:12" above "Up 3d 4h", for bootInfoTime. Caller must hold
+// the mutex.
+func (s *powerOffScreen) showBootInfo() {
+	uptime, err := s.host.Uptime()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to read the uptime")
+		return
+	}
+	booted := time.Now().Add(-uptime)
+	text := "Boot " + booted.Format("01-02 15:04") + "\nUp " + sysinfo.FormatUptime(uptime)
+	if err := s.display.WriteText(text); err != nil {
+		s.logger.WithError(err).Warn("Failed to show the boot info")
+	}
+
+	if s.back != nil {
+		s.back.Stop()
+	}
+	var back *time.Timer
+	back = time.AfterFunc(bootInfoTime, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.stopped || s.back != back {
+			return
+		}
+		s.back = nil
+		s.show()
+	})
+	s.back = back
+}
+
+// close stops the timers, leaves the power-off screen on the panel and
+// closes it
+func (s *powerOffScreen) close() error {
+	s.mutex.Lock()
+	s.stopped = true
+	for _, timer := range []*time.Timer{s.hold, s.back} {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	showingInfo := s.back != nil
+	s.hold, s.back = nil, nil
+	s.mutex.Unlock()
+
+	if showingInfo {
+		s.show()
+	}
+	return s.display.Close()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/menu"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// readOnlyStatus describes the read-only mode of the menu
+func readOnlyStatus(menuSystem *menu.MenuSystem) string {
+	if menuSystem.ReadOnly() {
+		return "Read-only mode on"
+	}
+	return "Read-only mode off"
+}
+
+// handleReadOnly answers read-only mode requests on the control socket:
+// "read_only_on" and "read_only_off" switch it and "read_only" reports it.
+// Over TCP they need the token like every other request.
+func handleReadOnly(server *control.Server, menuSystem *menu.MenuSystem, eventLog *events.Log) {
+	switchTo := func(readOnly bool) control.Handler {
+		return func(request control.Request) (string, error) {
+			if menuSystem.ReadOnly() != readOnly {
+				menuSystem.SetReadOnly(readOnly)
+				eventLog.Record(events.KindCommand, readOnlyStatus(menuSystem), nil)
+			}
+			return readOnlyStatus(menuSystem), nil
+		}
+	}
+	server.Handle("read_only", func(request control.Request) (string, error) {
+		return readOnlyStatus(menuSystem), nil
+	})
+	server.Handle("read_only_on", switchTo(true))
+	server.Handle("read_only_off", switchTo(false))
+}
+
+// newReadOnlyCommand creates the "read-only" subcommand
+func newReadOnlyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "read-only [on|off]",
+		Short: "Keep the panel menu from running commands",
+		Long: "Switches the read-only mode of the running service's menu. While it is on, the " +
+			"menu can be browsed and files shown, but command and display command items show " +
+			"\"Disabled\" instead of running. The mode lasts until it is switched again or the " +
+			"service restarts with menu.read_only from the config. Without an argument the " +
+			"current state is printed.",
+		Args:         cobra.MaximumNArgs(1),
+		ValidArgs:    []string{"on", "off"},
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request := control.Request{Command: "read_only"}
+			if len(args) == 1 {
+				switch args[0] {
+				case "on":
+					request.Command = "read_only_on"
+				case "off":
+					request.Command = "read_only_off"
+				default:
+					return fmt.Errorf("expected on or off, not %q", args[0])
+				}
+			}
+			return runReadOnly(request)
+		},
+	}
+}
+
+// runReadOnly sends a read-only mode request to the service and prints the
+// resulting state
+func runReadOnly(request control.Request) error {
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	status, err := callService(cfg, request)
+	if errors.Is(err, control.ErrNoService) {
+		return fmt.Errorf("read-only mode needs the service running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, status)
+	return nil
+}
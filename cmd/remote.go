@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/rpc"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+)
+
+// startRemoteAPI serves the gRPC API for other services on the NAS, with
+// pushed screens on their own layer. It returns the service, for the button
+// handler, and a function stopping it, or nil when no listen address is
+// configured.
+func startRemoteAPI(cfg *config.Config, screens *screen.ScreenManager, leds controller.LEDControllerInterface) (*rpc.Server, func(), error) {
+	if cfg.GRPC.Listen == "" {
+		return nil, nil, nil
+	}
+	if cfg.GRPC.Open() {
+		return nil, nil, fmt.Errorf("gRPC API on %s needs a token unless it listens on a loopback address", cfg.GRPC.Listen)
+	}
+
+	remote := rpc.NewServer(screens.Layer(screen.PriorityRemote), screens.Snapshot, leds)
+
+	listener, err := net.Listen("tcp", cfg.GRPC.Listen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen for gRPC calls: %w", err)
+	}
+	server := rpc.NewGRPCServer(remote, cfg.GRPC.Token)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logrus.WithError(err).Error("gRPC API stopped")
+		}
+	}()
+	logrus.WithField("address", listener.Addr().String()).Info("Serving gRPC API")
+
+	return remote, func() {
+		// Button streams only end when their clients go away
+		server.Stop()
+		remote.Close()
+	}, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qnap/display-control/internal/alert"
+	"github.com/qnap/display-control/internal/buzzer"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/maintenance"
+	"github.com/qnap/display-control/internal/nasapi"
+	"github.com/qnap/display-control/internal/rules"
+	"github.com/qnap/display-control/internal/sensor"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+)
+
+// startRules evaluates the configured rules and the alerts of the sensors
+// over the metrics of the sensors, SMART samples, load and NAS API, raising
+// alerts, switching leds (nil without LEDs) and beeping on buzz (nil without
+// a buzzer) except during maintenance. sensors, smart and nas may be nil.
+// Rules that do not parse are logged and skipped. It returns a function
+// stopping the evaluation, or nil without rules.
+func startRules(cfg *config.Config, sensors *sensor.Monitor, smart *state.SMARTHistory, nas *nasapi.Monitor,
+	alerts *alert.Manager, leds controller.LEDControllerInterface, buzz *buzzer.Buzzer, mode *maintenance.Mode) func() {
+	ruleList := sensorRules(cfg)
+	for _, ruleCfg := range cfg.Rules {
+		rule, err := buildRule(ruleCfg)
+		if err != nil {
+			logrus.WithError(err).WithField("rule", ruleCfg.Name).Error("Invalid rule")
+			continue
+		}
+		ruleList = append(ruleList, rule)
+	}
+	if len(ruleList) == 0 {
+		return nil
+	}
+
+	sources := []rules.Source{hostSamples(sysinfo.NewHostProvider(""))}
+	if sensors != nil {
+		sources = append(sources, sensorSamples(sensors))
+	}
+	if smart != nil {
+		sources = append(sources, smartSamples(cfg, smart))
+	}
+	if nas != nil {
+		sources = append(sources, nasSamples(nas))
+	}
+
+	engine, err := rules.NewEngine(ruleList, sources, 0)
+	if err != nil {
+		logrus.WithError(err).Error("Rules disabled")
+		return nil
+	}
+	var switcher rules.LEDs
+	if leds != nil {
+		switcher = leds
+	}
+	var beeper rules.Buzzer
+	if buzz != nil {
+		beeper = quietBuzzer{buzz, mode}
+	}
+	engine.Start(alerts, switcher, beeper)
+	logrus.WithField("rules", len(ruleList)).Info("Evaluating alert rules")
+	return engine.Stop
+}
+
+// buildRule parses a configured rule
+func buildRule(ruleCfg config.RuleConfig) (rules.Rule, error) {
+	if ruleCfg.Name == "" {
+		return rules.Rule{}, fmt.Errorf("rule without a name")
+	}
+	rule := rules.Rule{
+		Name:     ruleCfg.Name,
+		Text:     ruleCfg.Text,
+		Critical: ruleCfg.Critical,
+		Silent:   ruleCfg.Alert != nil && !*ruleCfg.Alert,
+		Beeps:    ruleCfg.Beep,
+	}
+	var err error
+	if rule.When, err = rules.Parse(ruleCfg.When); err != nil {
+		return rules.Rule{}, err
+	}
+	if ruleCfg.Clear != "" {
+		if rule.Clear, err = rules.Parse(ruleCfg.Clear); err != nil {
+			return rules.Rule{}, err
+		}
+	}
+	for _, name := range ruleCfg.LEDs {
+		led, err := controller.ParseLED(name)
+		if err != nil {
+			return rules.Rule{}, err
+		}
+		rule.LEDs = append(rule.LEDs, led)
+	}
+	return rule, nil
+}
+
+// quietBuzzer keeps the beeps of rules back during maintenance, like their
+// alerts
+type quietBuzzer struct {
+	buzzer *buzzer.Buzzer
+	mode   *maintenance.Mode
+}
+
+func (q quietBuzzer) Beep(ctx context.Context, duration time.Duration) error {
+	if q.mode.Active() {
+		return nil
+	}
+	return q.buzzer.Beep(ctx, duration)
+}
+
+// sensorRules turns the alerts of the configured sensors into rules, keeping
+// the keys and texts of their alerts, e.g. "sensor:rack:humidity:above"
+// showing "rack humidity" above "72.0 above 70.0"
+func sensorRules(cfg *config.Config) []rules.Rule {
+	var ruleList []rules.Rule
+	for _, sensorCfg := range cfg.Sensors {
+		for _, alertCfg := range sensorCfg.Alerts {
+			metric := fmt.Sprintf("%s{sensor=%q}", alertCfg.Metric, sensorCfg.Name)
+			limits := []struct {
+				direction     string
+				limit         *float64
+				when, recover string
+				margin        float64
+			}{
+				{"above", alertCfg.Above, ">", "<=", -alertCfg.Hysteresis},
+				{"below", alertCfg.Below, "<", ">=", alertCfg.Hysteresis},
+			}
+			for _, l := range limits {
+				if l.limit == nil {
+					continue
+				}
+				// A metric can have a warning and a critical limit in the
+				// same direction
+				key := fmt.Sprintf("sensor:%s:%s:%s", sensorCfg.Name, alertCfg.Metric, l.direction)
+				if alertCfg.Critical {
+					key += ":critical"
+				}
+				when, err := rules.Parse(metric + " " + l.when + " " + formatLimit(*l.limit))
+				var recovered *rules.Expr
+				if err == nil {
+					recovered, err = rules.Parse(metric + " " + l.recover + " " + formatLimit(*l.limit+l.margin))
+				}
+				if err != nil {
+					logrus.WithError(err).WithField("sensor", sensorCfg.Name).Error("Invalid sensor alert")
+					continue
+				}
+				ruleList = append(ruleList, rules.Rule{
+					Name:     key,
+					When:     when,
+					Clear:    recovered,
+					Key:      key,
+					Text:     fmt.Sprintf("%s %s\n%s %s %.1f", sensorCfg.Name, alertCfg.Metric, rules.ValuePlaceholder, l.direction, *l.limit),
+					Critical: alertCfg.Critical,
+				})
+			}
+		}
+	}
+	return ruleList
+}
+
+// formatLimit writes a limit for an expression
+func formatLimit(limit float64) string {
+	return strconv.FormatFloat(limit, 'g', -1, 64)
+}
+
+// sensorSamples publishes the readings of the sensors as their metric
+// labelled with the sensor, e.g. temperature{sensor="rack"}. Sensors whose
+// last poll failed are left out.
+func sensorSamples(monitor *sensor.Monitor) rules.Source {
+	return func() []rules.Sample {
+		var samples []rules.Sample
+		for _, name := range monitor.Names() {
+			reading, err := monitor.Latest(name)
+			if err != nil {
+				continue
+			}
+			for metric, value := range reading {
+				samples = append(samples, rules.Sample{Name: metric, Labels: map[string]string{"sensor": name}, Value: value})
+			}
+		}
+		return samples
+	}
+}
+
+// smartSamples publishes the newest SMART sample of each drive as
+// disk_<metric>{drive="sda"}, e.g. disk_temperature. Samples older than a
+// few sample intervals, e.g. from before a restart, are left out.
+func smartSamples(cfg *config.Config, history *state.SMARTHistory) rules.Source {
+	maxAge := staleIntervals * defaultSMARTInterval
+	if cfg.SMART.SampleInterval > 0 {
+		maxAge = staleIntervals * time.Duration(cfg.SMART.SampleInterval) * time.Second
+	}
+	return func() []rules.Sample {
+		var samples []rules.Sample
+		for _, drive := range cfg.SMART.Drives {
+			drives, err := history.Samples(drive)
+			if err != nil || len(drives) == 0 {
+				continue
+			}
+			newest := drives[len(drives)-1]
+			if time.Since(newest.Time) > maxAge {
+				continue
+			}
+			for metric, value := range newest.Values {
+				samples = append(samples, rules.Sample{Name: "disk_" + metric, Labels: map[string]string{"drive": drive}, Value: value})
+			}
+		}
+		return samples
+	}
+}
+
+// hostSamples publishes the load averages as load1, load5 and load15
+func hostSamples(host *sysinfo.HostProvider) rules.Source {
+	return func() []rules.Sample {
+		load, err := host.LoadAverage()
+		if err != nil {
+			return nil
+		}
+		return []rules.Sample{
+			{Name: "load1", Value: load[0]},
+			{Name: "load5", Value: load[1]},
+			{Name: "load15", Value: load[2]},
+		}
+	}
+}
+
+// nasSamples publishes the last report of the NAS API: pool_healthy{pool=...}
+// as 1 or 0, nas_alerts counting its alerts and update_available as 1 or 0
+func nasSamples(nas *nasapi.Monitor) rules.Source {
+	return func() []rules.Sample {
+		report, err := nas.Latest()
+		if err != nil {
+			return nil
+		}
+		samples := []rules.Sample{{Name: "nas_alerts", Value: float64(len(report.Alerts))}}
+		for _, pool := range report.Pools {
+			samples = append(samples, rules.Sample{Name: "pool_healthy", Labels: map[string]string{"pool": pool.Name}, Value: boolValue(pool.Healthy)})
+		}
+		if report.Update.Checked {
+			samples = append(samples, rules.Sample{Name: "update_available", Value: boolValue(report.Update.Available)})
+		}
+		return samples
+	}
+}
+
+// boolValue publishes a boolean as 1 or 0
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/qnap/display-control/internal/clamav"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+)
+
+// scanDisplayInterval is how often the file being scanned is shown, so a
+// slow serial panel does not hold up the scan
+const scanDisplayInterval = 250 * time.Millisecond
+
+// scansAt reports whether the profile has a virus scan at stage
+func scansAt(profile config.CopyProfile, stage string) bool {
+	if profile.Scan == nil {
+		return false
+	}
+	if profile.Scan.Stage == "" {
+		return stage == config.ScanAfter
+	}
+	return profile.Scan.Stage == stage
+}
+
+// scanCopy runs the virus scan of a profile on dir, showing the file being
+// scanned on the copy screen and the summary afterwards, e.g. "2 infected"
+// above "quarantined". It returns false when the copy must not go on: the
+// scan failed, or found viruses it could not quarantine.
+func scanCopy(cfg *config.Config, profile config.CopyProfile, dir string, copyScreen *screen.Layer, prompter *prompt.Prompter, eventLog *events.Log) bool {
+	logger := logrus.WithFields(logrus.Fields{"profile": profile.Name, "dir": dir})
+	logger.Info("Scanning for viruses")
+	if err := copyScreen.WriteText("Virus scan\nStarting..."); err != nil {
+		logger.WithError(err).Debug("Failed to show scan progress")
+	}
+
+	// The panel shows the latest file at most every scanDisplayInterval
+	var shown time.Time
+	progress := func(done, total int, path string) {
+		if path == "" || time.Since(shown) < scanDisplayInterval {
+			return
+		}
+		shown = time.Now()
+		line := fmt.Sprintf("%d/%d %s", done+1, total, filepath.Base(path))
+		if err := copyScreen.WriteText("Virus scan\n" + line); err != nil {
+			logger.WithError(err).Debug("Failed to show scan progress")
+		}
+	}
+
+	started := time.Now()
+	client := clamav.NewClient(cfg.USBCopy.Clamd)
+	result, err := client.ScanDir(context.Background(), dir, progress)
+	summary, ok := scanSummary(profile.Scan, result, err)
+	if err == nil && len(result.Infected) > 0 {
+		for _, finding := range result.Infected {
+			logger.WithFields(logrus.Fields{"file": finding.Path, "virus": finding.Virus}).Warn("Virus found")
+		}
+		if profile.Scan.Quarantine != "" {
+			if moved, qerr := clamav.Quarantine(result.Infected, profile.Scan.Quarantine); qerr != nil {
+				logger.WithError(qerr).Error("Failed to quarantine infected files")
+				summary, ok = fmt.Sprintf("%d infected\n%d quarantined", len(result.Infected), moved), false
+				err = qerr
+			}
+		}
+	}
+	recordScan(eventLog, profile.Name, dir, result, time.Since(started), err)
+	if err != nil {
+		logger.WithError(err).Error("Virus scan failed")
+	} else {
+		logger.WithFields(logrus.Fields{
+			"files":     result.Files,
+			"infected":  len(result.Infected),
+			"unscanned": result.Unscanned,
+		}).Info("Virus scan completed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := prompter.Prompt(ctx, summary); err != nil && ctx.Err() == nil {
+		logger.WithError(err).Error("Failed to show scan result")
+	}
+	if err := copyScreen.ClearDisplay(); err != nil {
+		logger.WithError(err).Debug("Failed to clear copy screen")
+	}
+	return ok
+}
+
+// scanSummary renders the two lines shown after a scan, and reports whether
+// the copy may go on
+func scanSummary(scan *config.ScanConfig, result clamav.Result, err error) (string, bool) {
+	switch {
+	case err != nil:
+		return "Virus scan\nfailed", false
+	case len(result.Infected) > 0 && scan.Quarantine != "":
+		return fmt.Sprintf("%d infected\nquarantined", len(result.Infected)), true
+	case len(result.Infected) > 0:
+		// Infected files found after the copy are already there; before it
+		// the copy is stopped
+		return fmt.Sprintf("%d infected\nfound", len(result.Infected)), scan.Stage != config.ScanBefore
+	case result.Unscanned > 0:
+		return fmt.Sprintf("Clean\n%d not scanned", result.Unscanned), true
+	default:
+		return fmt.Sprintf("Clean\n%d files", result.Files), true
+	}
+}
+
+// recordScan adds a virus scan to the event log
+func recordScan(eventLog *events.Log, profile, dir string, result clamav.Result, took time.Duration, err error) {
+	fields := events.Fields{
+		"item":        "Virus scan",
+		"profile":     profile,
+		"status":      "ok",
+		"files":       fmt.Sprint(result.Files),
+		"infected":    fmt.Sprint(len(result.Infected)),
+		"unscanned":   fmt.Sprint(result.Unscanned),
+		"duration_ms": fmt.Sprint(took.Milliseconds()),
+	}
+	if err != nil {
+		fields["status"] = "failed"
+		fields["error"] = err.Error()
+	}
+	eventLog.Record(events.KindCommand, "virus scan "+dir, fields)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/cluster"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/nasapi"
+	"github.com/qnap/display-control/internal/schedule"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sensor"
+)
+
+// defaultScheduleDuration is how long a scheduled screen stays up by default
+const defaultScheduleDuration = time.Minute
+
+// setupScheduler builds the scheduled screens, drawn on their own layer. It
+// returns the scheduler, not yet started, or nil without scheduled screens.
+// Sensor and peer items read from monitor and peers, which may be nil.
+func setupScheduler(cfg *config.Config, screens *screen.ScreenManager, monitor *sensor.Monitor, peers *cluster.Monitor, nas *nasapi.Monitor) (*schedule.Scheduler, error) {
+	if len(cfg.Schedule) == 0 {
+		return nil, nil
+	}
+
+	width, height := cfg.Display.Width, cfg.Display.Height
+	if width <= 0 {
+		width = 16
+	}
+	if height <= 0 {
+		height = 2
+	}
+	abbrev := screen.NewAbbreviator(cfg.Display.Abbreviations)
+
+	entries := make([]schedule.Entry, 0, len(cfg.Schedule))
+	for i, scheduled := range cfg.Schedule {
+		cron, err := schedule.ParseCron(scheduled.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %d: %w", i+1, err)
+		}
+		entry := schedule.Entry{
+			Name:     scheduled.Cron,
+			Cron:     cron,
+			Duration: defaultScheduleDuration,
+			Interval: defaultStatusInterval,
+		}
+		if scheduled.Duration > 0 {
+			entry.Duration = time.Duration(scheduled.Duration) * time.Second
+		}
+		if scheduled.Interval > 0 {
+			entry.Interval = time.Duration(scheduled.Interval) * time.Second
+		}
+
+		switch {
+		case scheduled.Text != "" && len(scheduled.Items) > 0:
+			return nil, fmt.Errorf("schedule %d: set either text or items, not both", i+1)
+		case scheduled.Text != "":
+			text := scheduled.Text
+			entry.Pages = []schedule.Page{func() (string, error) { return text, nil }}
+		case len(scheduled.Items) > 0:
+			items, err := buildStatusItems(cfg, scheduled.Items, monitor, peers, nas)
+			if err != nil {
+				return nil, fmt.Errorf("schedule %d: %w", i+1, err)
+			}
+			for start := 0; start < len(items); start += height {
+				entry.Pages = append(entry.Pages, statusPage(items[start:min(start+height, len(items))], abbrev, width))
+			}
+		default:
+			return nil, fmt.Errorf("schedule %d: set text or items", i+1)
+		}
+		entries = append(entries, entry)
+	}
+
+	return schedule.NewScheduler(screens.Layer(screen.PriorityScheduled), entries), nil
+}
+
+// statusPage shows status items one per line. Items that fail leave their
+// line blank; the page fails only if all of them do.
+func statusPage(items []screen.StatusItem, abbrev *screen.Abbreviator, width int) schedule.Page {
+	return func() (string, error) {
+		lines := make([]string, len(items))
+		var lastErr error
+		for i, item := range items {
+			text, err := item.Render(abbrev, width)
+			if err != nil {
+				lastErr = fmt.Errorf("%s: %w", item.Name, err)
+				continue
+			}
+			lines[i] = text
+		}
+		if lastErr != nil && strings.TrimSpace(strings.Join(lines, "")) == "" {
+			return "", lastErr
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newSelftestCommand creates the "selftest" subcommand
+func newSelftestCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "selftest",
+		Short: "Check the panel hardware and report what is available",
+		Long: "Initializes the display, LEDs and copy button the same way the service does and " +
+			"reports the result of each check, including which source the copy button is read from.",
+		Args: cobra.NoArgs,
+		// Errors are hardware problems, not usage mistakes
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelftest(os.Stdout)
+		},
+	}
+}
+
+// runSelftest runs the hardware checks and prints a report. It fails only if
+// the display, which everything else depends on, cannot be used.
+func runSelftest(out io.Writer) error {
+	setupLogging()
+	if !verbose {
+		// Keep the report readable; failures are reported below
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	report := func(check, result string) {
+		fmt.Fprintf(out, "%-18s %s\n", check, result)
+	}
+
+	fmt.Fprintln(out, "QNAP display self test")
+
+	quirks, err := controller.ButtonQuirksFromConfig(cfg.Hardware)
+	if err != nil {
+		report("Hardware profile", fmt.Sprintf("INVALID (%v), using generic", err))
+	} else {
+		report("Hardware profile", quirks.Profile)
+	}
+
+	systemController, err := controller.NewSystemController(cfg)
+	if err != nil {
+		report("Display", fmt.Sprintf("FAILED (%v)", err))
+		return fmt.Errorf("self test failed: %w", err)
+	}
+	defer systemController.Close()
+
+	report("Display", fmt.Sprintf("OK (%s)", displayDescription(cfg)))
+
+	display := systemController.GetDisplayController()
+	if err := display.WriteText("Self test\nin progress"); err != nil {
+		report("Display write", fmt.Sprintf("FAILED (%v)", err))
+		return fmt.Errorf("self test failed: %w", err)
+	}
+	report("Display write", "OK")
+	report("Serial link", fmt.Sprintf("circuit breaker %s", display.LinkState()))
+	if acks, err := controller.FrameAcksFromConfig(cfg.Hardware); err != nil {
+		report("Acknowledgements", fmt.Sprintf("INVALID (%v), not waiting for answers", err))
+	} else if acks.Enabled {
+		stats := display.FrameStats()
+		report("Acknowledgements", fmt.Sprintf("%d sent, %d acked, %d nacked, %d timed out",
+			stats.Sent, stats.Acked, stats.Nacked, stats.TimedOut))
+	}
+
+	if err := display.RequestButtonState(); err != nil {
+		report("Button state", fmt.Sprintf("FAILED (%v)", err))
+	} else {
+		report("Button state", "OK (request sent)")
+	}
+
+	if leds := systemController.GetLEDController(); leds != nil {
+		if _, err := leds.GetLEDStates(); err != nil {
+			report("LED controller", fmt.Sprintf("FAILED (%v)", err))
+		} else {
+			report("LED controller", "OK")
+		}
+	} else {
+		report("LED controller", "UNAVAILABLE")
+	}
+
+	switch systemController.CopyButtonSource() {
+	case controller.CopyButtonIOPort:
+		report("Copy button", fmt.Sprintf("io-port (0x%x)", cfg.USBCopy.IOPort))
+	case controller.CopyButtonSerial:
+		reason := "I/O port unavailable"
+		if cfg.USBCopy.IOPort == 0 {
+			reason = "no I/O port configured"
+		}
+		report("Copy button", fmt.Sprintf("serial (%s, decoding panel frames)", reason))
+	}
+
+	// Leave the result on the panel briefly before the service takes over
+	display.WriteText("Self test\ncomplete")
+	time.Sleep(time.Second)
+
+	return nil
+}
+
+// displayDescription names the configured panel and where it is attached
+func displayDescription(cfg *config.Config) string {
+	switch cfg.Display.Driver {
+	case controller.DriverSSD1306, controller.DriverSH1106:
+		return fmt.Sprintf("%s on /dev/i2c-%d", cfg.Display.Driver, cfg.Display.OLED.Bus)
+	case controller.DriverHD44780:
+		return fmt.Sprintf("%s on %s", cfg.Display.Driver, controller.GPIOChip(cfg))
+	case controller.DriverPCF8574:
+		return fmt.Sprintf("%s on /dev/i2c-%d", cfg.Display.Driver, cfg.Display.PCF8574.Bus)
+	}
+	if cfg.Hardware.Profile == controller.I2CPanelProfile {
+		return fmt.Sprintf("%s panel on /dev/i2c-%d", controller.I2CPanelProfile, cfg.Hardware.PanelBus)
+	}
+	return fmt.Sprintf("%s @ %d baud", cfg.SerialPort.Device, cfg.SerialPort.BaudRate)
+}
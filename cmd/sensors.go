@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/hardware"
+	"github.com/qnap/display-control/internal/sensor"
+	"github.com/sirupsen/logrus"
+)
+
+// sensorStatusPrefix selects a sensor's reading as a status item, as in
+// "sensor:rack"
+const sensorStatusPrefix = "sensor:"
+
+// openSensors opens the I2C buses of the configured sensors and registers the
+// sensors with a monitor, not yet polling. It must run before privileges are
+// dropped. Sensors that cannot be set up are logged and skipped; the monitor
+// is nil when no sensor is configured. The returned buses are closed by the
+// caller.
+func openSensors(cfg *config.Config) (*sensor.Monitor, []hardware.I2CBus) {
+	if len(cfg.Sensors) == 0 {
+		return nil, nil
+	}
+
+	monitor := sensor.NewMonitor()
+	opened := make(map[int]hardware.I2CBus)
+	var buses []hardware.I2CBus
+	for _, sensorCfg := range cfg.Sensors {
+		logger := logrus.WithFields(logrus.Fields{"sensor": sensorCfg.Name, "bus": sensorCfg.Bus})
+
+		// Sensors on the same bus share the file descriptor
+		bus, exists := opened[sensorCfg.Bus]
+		if !exists {
+			devBus, err := hardware.OpenI2CBus(sensorCfg.Bus)
+			if err != nil {
+				logger.WithError(err).Error("Failed to open I2C bus")
+				continue
+			}
+			bus = devBus
+			opened[sensorCfg.Bus] = bus
+			buses = append(buses, bus)
+		}
+
+		s, err := sensor.New(sensorCfg.Type, bus, sensorCfg.Address)
+		if err == nil {
+			interval := time.Duration(sensorCfg.PollInterval) * time.Second
+			err = monitor.Add(sensorCfg.Name, s, interval)
+		}
+		if err != nil {
+			logger.WithError(err).Error("Failed to set up sensor")
+			continue
+		}
+		logger.WithField("type", sensorCfg.Type).Info("Sensor found")
+	}
+	return monitor, buses
+}
+
+// sensorStatusItem shows the last good reading of a sensor on the status
+// line; the item is marked once it is stale
+func sensorStatusItem(monitor *sensor.Monitor, name string) func() (string, error) {
+	return func() (string, error) {
+		if monitor == nil {
+			return "", fmt.Errorf("unknown sensor %q", name)
+		}
+		reading, _, err := monitor.Last(name)
+		if err != nil {
+			return "", err
+		}
+		return name + " " + reading.Render(), nil
+	}
+}
+
+// sensorConfigured reports whether a sensor of the given name is configured
+func sensorConfigured(cfg *config.Config, name string) bool {
+	for _, sensorCfg := range cfg.Sensors {
+		if sensorCfg.Name == name {
+			return true
+		}
+	}
+	return false
+}
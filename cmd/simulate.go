@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/simulator"
+	"github.com/qnap/display-control/pkg/led"
+	"github.com/sirupsen/logrus"
+)
+
+// simulation is the panel of --simulate: the display, LEDs and buttons kept
+// in memory and shown in the terminal
+type simulation struct {
+	panel *controller.PanelSimulator
+	leds  *led.Mock
+	// logPath is the file the log goes to while the panel is drawn, "" if
+	// it still goes to stderr
+	logPath string
+}
+
+// newSimulation prepares --simulate. The log would scroll the panel away,
+// so while stderr is the terminal it goes to a file instead.
+func newSimulation() (*simulation, error) {
+	if !simulator.IsTerminal(int(os.Stdin.Fd())) || !simulator.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, errors.New("--simulate needs a terminal")
+	}
+
+	sim := &simulation{leds: led.NewMock()}
+	if simulator.IsTerminal(int(os.Stderr.Fd())) {
+		path, err := simulator.LogTo(os.TempDir())
+		if err != nil {
+			return nil, err
+		}
+		sim.logPath = path
+	}
+	return sim, nil
+}
+
+// open creates the system controller on the simulated panel, of the
+// configured size. Without hardware nothing needs root, and other display
+// drivers are replaced by the QNAP panel the terminal shows.
+func (s *simulation) open(cfg *config.Config) (*controller.SystemController, error) {
+	cfg.Privileges.User = ""
+	if cfg.Display.Driver != "" && cfg.Display.Driver != controller.DriverQNAP {
+		logrus.WithField("driver", cfg.Display.Driver).Info("Simulating the QNAP panel instead")
+		cfg.Display.Driver = controller.DriverQNAP
+		cfg.Display.Width, cfg.Display.Height = 16, 2
+	}
+	cols, rows := cfg.Display.Width, cfg.Display.Height
+	if cols <= 0 {
+		cols = 16
+	}
+	if rows <= 0 {
+		rows = 2
+	}
+	s.panel = controller.NewPanelSimulatorWithGeometry(cols, rows)
+	return controller.NewSimulatedSystemController(cfg, s.panel, s.leds)
+}
+
+// start draws the panel in the terminal and presses buttons for keys until
+// the quit key, which stops the service like SIGINT. The returned function
+// stops drawing and restores the terminal.
+func (s *simulation) start(shutdown chan<- os.Signal) func() {
+	restore, err := simulator.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to start the simulator")
+	}
+
+	terminal := simulator.NewTerminal(s.panel, s.leds, os.Stdin, os.Stdout)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := terminal.Run(ctx); err != nil {
+			logrus.WithError(err).Error("Simulator stopped")
+		}
+		select {
+		case shutdown <- os.Interrupt:
+		default:
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		if err := restore(); err != nil {
+			logrus.WithError(err).Warn("Failed to restore the terminal")
+		}
+		fmt.Println()
+		if s.logPath != "" {
+			fmt.Printf("Log written to %s\n", s.logPath)
+		}
+	}
+}
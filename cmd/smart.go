@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/broker"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultSMARTInterval is how often the drives are sampled when the
+// configuration does not say
+const defaultSMARTInterval = 10 * time.Minute
+
+// startSMARTSampler reads the SMART attributes of the configured drives now
+// and then every sample interval, keeping them in the state store for the
+// trends in the menu. It returns the samples and a function stopping it, or
+// nil when no drives are configured or there is no store.
+func startSMARTSampler(cfg *config.Config, store *state.Store, helper *broker.Client) (*state.SMARTHistory, func()) {
+	if len(cfg.SMART.Drives) == 0 || store == nil {
+		return nil, nil
+	}
+
+	var run sysinfo.CommandRunner
+	if cfg.SMART.Privileged && helper != nil {
+		run = helper.Run
+	}
+	provider := sysinfo.NewSMARTProvider(run)
+	history := state.NewSMARTHistory(store)
+	interval := defaultSMARTInterval
+	if cfg.SMART.SampleInterval > 0 {
+		interval = time.Duration(cfg.SMART.SampleInterval) * time.Second
+	}
+
+	sample := func() {
+		for _, drive := range cfg.SMART.Drives {
+			reading, err := provider.Read(drive)
+			if err != nil {
+				logrus.WithError(err).WithField("drive", drive).Warn("Failed to sample SMART attributes")
+				continue
+			}
+			if err := history.Record(drive, reading); err != nil {
+				logrus.WithError(err).WithField("drive", drive).Warn("Failed to keep SMART sample")
+			}
+		}
+	}
+
+	stop := make(chan struct{})
+	var done sync.WaitGroup
+	done.Add(1)
+	go func() {
+		defer done.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sample()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	logrus.WithFields(logrus.Fields{
+		"drives":   cfg.SMART.Drives,
+		"interval": interval,
+	}).Info("Sampling SMART attributes")
+
+	return history, func() {
+		close(stop)
+		done.Wait()
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+)
+
+// showSplash shows the configured startup splash while the service comes up
+// behind it. The logo is drawn with the custom characters of chars; without
+// them, e.g. for a remote LCDd (nil), the splash shows only its text.
+func showSplash(cfg *config.Config, screens *screen.ScreenManager, chars controller.CustomChars) {
+	splash := cfg.Display.Splash
+	if splash.Duration <= 0 {
+		return
+	}
+
+	text := splash.Text
+	var done func()
+	if len(splash.Logo) > 0 && chars != nil {
+		logo, err := controller.LoadLogo(chars, splash.Logo)
+		switch {
+		case errors.Is(err, controller.ErrNoGlyphs):
+			logrus.Debug("Panel has no custom characters, showing the splash without its logo")
+		case err != nil:
+			logrus.WithError(err).Warn("Failed to load the splash logo")
+			restoreGlyphs(chars)
+		default:
+			text = besideLogo(logo, text)
+			done = func() { restoreGlyphs(chars) }
+		}
+	}
+
+	err := screens.ShowSplash(screen.Splash{
+		Text:     text,
+		Duration: time.Duration(splash.Duration) * time.Second,
+		Done:     done,
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Display test failed, but continuing")
+		return
+	}
+	logrus.Info("Display communication working")
+}
+
+// besideLogo places the lines of text to the right of the logo's lines
+func besideLogo(logo []string, text string) string {
+	lines := strings.Split(text, "\n")
+	indent := strings.Repeat(" ", len([]rune(logo[0]))+1)
+	for i := range lines {
+		if i < len(logo) {
+			lines[i] = logo[i] + " " + lines[i]
+		} else {
+			lines[i] = indent + lines[i]
+		}
+	}
+	for i := len(lines); i < len(logo); i++ {
+		lines = append(lines, logo[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// restoreGlyphs defines the glyphs the splash logo replaced again
+func restoreGlyphs(chars controller.CustomChars) {
+	if err := controller.RestoreGlyphs(chars); err != nil {
+		logrus.WithError(err).Warn("Failed to restore glyphs after the splash")
+	}
+}
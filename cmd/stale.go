@@ -0,0 +1,149 @@
+package main
+
+import (
+	"time"
+
+	"github.com/qnap/display-control/internal/alert"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/nasapi"
+	"github.com/qnap/display-control/internal/sensor"
+)
+
+// staleIntervals is how many poll intervals a provider may miss before its
+// data counts as stale, so one slow poll does not raise an alert
+const staleIntervals = 3
+
+// staleCheckInterval is how often the providers are checked for stale data
+const staleCheckInterval = 15 * time.Second
+
+// freshness tells how current the data of a provider polled in the
+// background is, e.g. a sensor or the NAS API
+type freshness struct {
+	// key identifies the alert raised while the data is stale
+	key string
+	// label names the provider in the alert
+	label string
+	// maxAge is how old the data may get
+	maxAge time.Duration
+	// updated returns when the data was last refreshed; zero if never
+	updated func() time.Time
+}
+
+// stale reports whether the data is older than maxAge at now. Data never
+// refreshed counts from since, so a provider gets its first intervals.
+func (f freshness) stale(now, since time.Time) bool {
+	updated := f.updated()
+	if updated.IsZero() {
+		updated = since
+	}
+	return now.Sub(updated) > f.maxAge
+}
+
+// sensorFreshness watches the readings of a configured sensor
+func sensorFreshness(cfg *config.Config, monitor *sensor.Monitor, name string) freshness {
+	interval := sensor.DefaultPollInterval
+	for _, sensorCfg := range cfg.Sensors {
+		if sensorCfg.Name == name && sensorCfg.PollInterval > 0 {
+			interval = time.Duration(sensorCfg.PollInterval) * time.Second
+		}
+	}
+	return freshness{
+		key:    "stale:sensor:" + name,
+		label:  name,
+		maxAge: staleIntervals * interval,
+		updated: func() time.Time {
+			_, updated, _ := monitor.Last(name)
+			return updated
+		},
+	}
+}
+
+// nasFreshness watches the reports of the NAS API
+func nasFreshness(cfg *config.Config, monitor *nasapi.Monitor) freshness {
+	interval := nasapi.DefaultPollInterval
+	if cfg.NASAPI.PollInterval > 0 {
+		interval = time.Duration(cfg.NASAPI.PollInterval) * time.Second
+	}
+	return freshness{
+		key:    "stale:nas",
+		label:  monitor.Name(),
+		maxAge: staleIntervals * interval,
+		updated: func() time.Time {
+			_, updated, _ := monitor.Last()
+			return updated
+		},
+	}
+}
+
+// staleStatus marks a status item stale once its provider's data is
+func staleStatus(f freshness) func() bool {
+	return func() bool {
+		return !f.updated().IsZero() && f.stale(time.Now(), time.Time{})
+	}
+}
+
+// startStaleWatch raises a warning for every sensor and the NAS API while
+// their data is stale, e.g. because a sensor died or a poll hangs, and
+// clears it once they refresh again. sensors and nas may be nil. It returns
+// a function stopping the watch, or nil when there is nothing to watch.
+func startStaleWatch(cfg *config.Config, sensors *sensor.Monitor, nas *nasapi.Monitor, alerts *alert.Manager) func() {
+	var watched []freshness
+	if sensors != nil {
+		for _, name := range sensors.Names() {
+			watched = append(watched, sensorFreshness(cfg, sensors, name))
+		}
+	}
+	if nas != nil {
+		watched = append(watched, nasFreshness(cfg, nas))
+	}
+	if len(watched) == 0 {
+		return nil
+	}
+
+	started := time.Now()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(staleCheckInterval)
+		defer ticker.Stop()
+
+		raised := make(map[string]bool)
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				for _, f := range watched {
+					switch stale := f.stale(now, started); {
+					case stale:
+						alerts.Raise(f.key, staleAlertText(f, now))
+						raised[f.key] = true
+					case raised[f.key]:
+						alerts.Clear(f.key)
+						delete(raised, f.key)
+					}
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// staleAlertText says since when a provider has no current data, e.g.
+// "rack no data" above "since 14:05"
+func staleAlertText(f freshness, now time.Time) string {
+	updated := f.updated()
+	switch {
+	case updated.IsZero():
+		return f.label + " no data\nsince start"
+	case now.Sub(updated) >= 24*time.Hour:
+		return f.label + " no data\nsince " + updated.Format("01-02 15:04")
+	default:
+		return f.label + " no data\nsince " + updated.Format("15:04")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/cluster"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/nasapi"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sensor"
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// defaultStatusInterval is how long each status item is shown by default
+const defaultStatusInterval = 5 * time.Second
+
+// defaultStatusItems are rotated when the configuration names none
+var defaultStatusItems = []string{"hostname", "ip", "uptime", "load"}
+
+// statusItems builds the one-line texts the status rotation can show
+var statusItems = map[string]func(host *sysinfo.HostProvider, cpu *sysinfo.CPUProvider) func() (string, error){
+	"hostname": func(*sysinfo.HostProvider, *sysinfo.CPUProvider) func() (string, error) {
+		return os.Hostname
+	},
+	"ip": func(*sysinfo.HostProvider, *sysinfo.CPUProvider) func() (string, error) {
+		return func() (string, error) {
+			ip, err := sysinfo.PrimaryIPv4()
+			if err != nil {
+				return "", err
+			}
+			return "IP " + ip, nil
+		}
+	},
+	"uptime": func(host *sysinfo.HostProvider, _ *sysinfo.CPUProvider) func() (string, error) {
+		return func() (string, error) {
+			uptime, err := host.Uptime()
+			if err != nil {
+				return "", err
+			}
+			return "Up " + sysinfo.FormatUptime(uptime), nil
+		}
+	},
+	"load": func(host *sysinfo.HostProvider, _ *sysinfo.CPUProvider) func() (string, error) {
+		return func() (string, error) {
+			load, err := host.LoadAverage()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Load %.2f %.2f", load[0], load[1]), nil
+		}
+	},
+	"cpu": func(_ *sysinfo.HostProvider, cpu *sysinfo.CPUProvider) func() (string, error) {
+		return func() (string, error) {
+			status, err := cpu.Status()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("CPU %d MHz", status.FrequencyMHz), nil
+		}
+	},
+	"time": func(*sysinfo.HostProvider, *sysinfo.CPUProvider) func() (string, error) {
+		return func() (string, error) {
+			return time.Now().Format("Mon 02 Jan 15:04"), nil
+		}
+	},
+}
+
+// statusItemNames returns the names of the available status items
+func statusItemNames() []string {
+	names := make([]string, 0, len(statusItems))
+	for name := range statusItems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildStatusItems looks up the named status items. Sensor items read from
+// monitor, peer items from peers and NAS items from nas; any may be nil when
+// none are configured. Sensor and NAS items are marked when their data is
+// stale.
+func buildStatusItems(cfg *config.Config, names []string, monitor *sensor.Monitor, peers *cluster.Monitor, nas *nasapi.Monitor) ([]screen.StatusItem, error) {
+	host, cpu := sysinfo.NewHostProvider(""), sysinfo.NewCPUProvider("")
+	items := make([]screen.StatusItem, 0, len(names))
+	for _, name := range names {
+		if sensorName := strings.TrimPrefix(name, sensorStatusPrefix); sensorName != name {
+			if !sensorConfigured(cfg, sensorName) {
+				return nil, fmt.Errorf("status item %q names no configured sensor", name)
+			}
+			item := screen.StatusItem{Name: name, Text: sensorStatusItem(monitor, sensorName)}
+			if monitor != nil {
+				item.Stale = staleStatus(sensorFreshness(cfg, monitor, sensorName))
+			}
+			items = append(items, item)
+			continue
+		}
+		if peerName := strings.TrimPrefix(name, peerStatusPrefix); peerName != name {
+			if !peerConfigured(cfg, peerName) {
+				return nil, fmt.Errorf("status item %q names no configured peer", name)
+			}
+			items = append(items, screen.StatusItem{Name: name, Text: peerStatusItem(peers, peerName)})
+			continue
+		}
+		if part := strings.TrimPrefix(name, nasStatusPrefix); part != name {
+			render, exists := nasStatusItems[part]
+			if !exists {
+				return nil, fmt.Errorf("unknown status item %q (available: %spools, %salerts, %supdate)", name, nasStatusPrefix, nasStatusPrefix, nasStatusPrefix)
+			}
+			if cfg.NASAPI.System == "" {
+				return nil, fmt.Errorf("status item %q needs nas_api configured", name)
+			}
+			item := screen.StatusItem{Name: name, Text: nasStatusItem(nas, render)}
+			if nas != nil {
+				item.Stale = staleStatus(nasFreshness(cfg, nas))
+			}
+			items = append(items, item)
+			continue
+		}
+		build, exists := statusItems[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown status item %q (available: %s)", name, strings.Join(statusItemNames(), ", ")+", "+sensorStatusPrefix+"<name>, "+peerStatusPrefix+"<name>, "+nasStatusPrefix+"<part>")
+		}
+		items = append(items, screen.StatusItem{Name: name, Text: build(host, cpu)})
+	}
+	return items, nil
+}
+
+// setupStatusLine splits the display between the status rotation and the
+// menu when a status line is configured. It returns the rotation, not yet
+// started, or nil if the whole display is left to the menu. Sensor, peer and
+// NAS items read from monitor, peers and nas, which may be nil.
+func setupStatusLine(cfg *config.Config, screens *screen.ScreenManager, monitor *sensor.Monitor, peers *cluster.Monitor, nas *nasapi.Monitor) (*screen.StatusRotation, error) {
+	if cfg.Display.StatusLine == 0 {
+		return nil, nil
+	}
+
+	// The menu keeps the lines on the other side, so it stays one block
+	height := cfg.Display.Height
+	if height <= 0 {
+		height = 2
+	}
+	statusRow, menuRow := 0, 1
+	switch cfg.Display.StatusLine {
+	case 1:
+	case height:
+		statusRow, menuRow = height-1, 0
+	default:
+		return nil, fmt.Errorf("status line %d must be the first or the last line (1 or %d)", cfg.Display.StatusLine, height)
+	}
+
+	names := cfg.Display.StatusItems
+	if len(names) == 0 {
+		names = defaultStatusItems
+	}
+	items, err := buildStatusItems(cfg, names, monitor, peers, nas)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := screens.SetRegion(screen.PriorityStatus, statusRow, 1); err != nil {
+		return nil, err
+	}
+	if err := screens.SetRegion(screen.PriorityMenu, menuRow, height-1); err != nil {
+		return nil, err
+	}
+	rotation := screen.NewStatusRotation(screens.Layer(screen.PriorityStatus), items, statusInterval(cfg))
+	rotation.SetAbbreviator(screen.NewAbbreviator(cfg.Display.Abbreviations))
+	return rotation, nil
+}
+
+// statusInterval returns how long status items without a dwell time are shown
+func statusInterval(cfg *config.Config) time.Duration {
+	if cfg.Display.StatusInterval > 0 {
+		return time.Duration(cfg.Display.StatusInterval) * time.Second
+	}
+	return defaultStatusInterval
+}
+
+// buildRotation creates the named rotation of the configuration on layer,
+// showing its items in order, each for its dwell time. Sensor, peer and NAS
+// items read from monitor, peers and nas, which may be nil.
+func buildRotation(cfg *config.Config, name string, layer *screen.Layer, monitor *sensor.Monitor, peers *cluster.Monitor, nas *nasapi.Monitor) (*screen.StatusRotation, error) {
+	entries, exists := cfg.Display.Rotations[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown rotation %q", name)
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Item
+	}
+	items, err := buildStatusItems(cfg, names, monitor, peers, nas)
+	if err != nil {
+		return nil, fmt.Errorf("rotation %q: %w", name, err)
+	}
+	for i, entry := range entries {
+		items[i].Dwell = time.Duration(entry.Dwell) * time.Second
+	}
+
+	rotation := screen.NewStatusRotation(layer, items, statusInterval(cfg))
+	rotation.SetAbbreviator(screen.NewAbbreviator(cfg.Display.Abbreviations))
+	return rotation, nil
+}
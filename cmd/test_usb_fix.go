@@ -1,72 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"time"
-)
-
-// USB Copy Button Fix Verification
-func main() {
-	fmt.Println("=== USB Copy Button Fix Verification ===")
-	
-	fmt.Println("\n🔍 Root Cause Analysis:")
-	fmt.Println("   ❌ USB copy button was NOT detected through serial protocol")
-	fmt.Println("   ✅ USB copy button uses hardware I/O port 0xa05 (separate from serial)")
-	fmt.Println("   ❌ System controller was only monitoring ENTER/SELECT via serial")
-	fmt.Println("   ✅ USB copy button requires dedicated hardware monitoring")
-	
-	fmt.Println("\n🔧 Fix Implementation:")
-	fmt.Println("   1. Integrated USBCopyMonitor into SystemController")
-	fmt.Println("   2. Added hardware I/O port monitoring for USB copy button")
-	fmt.Println("   3. Created unified button handler for all button types")
-	fmt.Println("   4. Coordinated serial + hardware button monitoring")
-	
-	fmt.Println("\n📋 Technical Details:")
-	fmt.Println("   • ENTER/SELECT buttons: Serial protocol (0x53, 0x05, 0x00, state)")
-	fmt.Println("   • USB COPY button: Hardware I/O port 0xa05 (bit 0 active-low)")
-	fmt.Println("   • Button monitoring: Dual approach (serial + hardware)")
-	fmt.Println("   • Unified handler: SystemController.SetButtonHandler()")
-	
-	fmt.Println("\n🎯 Code Changes:")
-	fmt.Println("   • SystemController: Added usbMonitor field and initialization")
-	fmt.Println("   • Monitoring: Background goroutine for USB copy button")
-	fmt.Println("   • Button Events: Unified handler receives all button types")
-	fmt.Println("   • Dependencies: Added //internal/monitor to BUILD.bazel")
-	
-	fmt.Println("\n✅ Expected Results:")
-	fmt.Println("   • ENTER button: Detected via serial protocol ✓")
-	fmt.Println("   • SELECT button: Detected via serial protocol ✓")  
-	fmt.Println("   • USB COPY button: Detected via hardware I/O port ✓")
-	fmt.Println("   • All callbacks: Triggered through unified handler ✓")
-	
-	fmt.Println("\n🧪 Testing Protocol:")
-	fmt.Println("   1. Run: sudo ./bazel-bin/cmd/test_buttons_/test_buttons")
-	fmt.Println("   2. Test ENTER button (should work via serial)")
-	fmt.Println("   3. Test SELECT button (should work via serial)")
-	fmt.Println("   4. Test USB COPY button (should work via hardware I/O)")
-	fmt.Println("   5. Verify all callbacks are triggered correctly")
-	
-	fmt.Println("\n📊 Hardware Requirements:")
-	fmt.Println("   • Root privileges: Required for I/O port access")
-	fmt.Println("   • Serial port: /dev/ttyS1 for ENTER/SELECT buttons")
-	fmt.Println("   • I/O port: 0xa05 for USB copy button")
-	fmt.Println("   • QNAP hardware: TS-670 Pro or compatible")
-	
-	fmt.Println("\n🔄 Architecture Changes:")
-	fmt.Println("   Before: DisplayController → ButtonHandler")
-	fmt.Println("   After:  SystemController → Unified ButtonHandler")
-	fmt.Println("          ├── DisplayController (ENTER/SELECT)")
-	fmt.Println("          └── USBCopyMonitor (USB COPY)")
-	
-	fmt.Println("\n💡 Key Insight:")
-	fmt.Println("   The USB copy button was never detected because it uses")
-	fmt.Println("   a completely different hardware interface (I/O ports)")
-	fmt.Println("   rather than the serial protocol used by other buttons.")
-	fmt.Println("   This requires dual monitoring systems working together.")
-	
-	time.Sleep(2 * time.Second)
-	
-	fmt.Println("\n✅ USB Copy Button Fix Complete!")
-	fmt.Println("   The system now properly monitors ALL three button types")
-	fmt.Println("   using the appropriate hardware interfaces for each.")
-}
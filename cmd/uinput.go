@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/uinput"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultUinputName is the virtual keyboard's name when none is configured
+const defaultUinputName = "QNAP front panel"
+
+// uinputKeys maps the panel buttons to the keys of the virtual keyboard
+var uinputKeys = map[controller.PanelButton]uint16{
+	controller.ButtonEnter:   uinput.KeyEnter,
+	controller.ButtonSelect:  uinput.KeyDown,
+	controller.ButtonUSBCopy: uinput.KeyCopy,
+}
+
+// openVirtualKeyboard creates the virtual keyboard when it is enabled. It
+// must run before privileges are dropped. A keyboard that cannot be created
+// is logged and nil is returned; the panel works without it.
+func openVirtualKeyboard(cfg *config.Config) *uinput.Keyboard {
+	if !cfg.Uinput.Enabled {
+		return nil
+	}
+
+	name := cfg.Uinput.Name
+	if name == "" {
+		name = defaultUinputName
+	}
+	keys := make([]uint16, 0, len(uinputKeys))
+	for _, key := range uinputKeys {
+		keys = append(keys, key)
+	}
+	keyboard, err := uinput.Open(name, keys)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create virtual keyboard")
+		return nil
+	}
+	logrus.WithField("name", name).Info("Virtual keyboard created")
+	return keyboard
+}
+
+// forwardButton sends a panel button event to the virtual keyboard
+func forwardButton(keyboard *uinput.Keyboard, button controller.PanelButton, pressed bool) {
+	key, ok := uinputKeys[button]
+	if !ok {
+		return
+	}
+	if err := keyboard.Key(key, pressed); err != nil {
+		logrus.WithError(err).Warn("Failed to forward button to virtual keyboard")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/sirupsen/logrus"
+)
+
+// usageSaveInterval is how often the counted presses are written to the
+// state store
+const usageSaveInterval = time.Minute
+
+// panelUsage counts the button presses and writes them to the state store
+// in the background
+type panelUsage struct {
+	*state.Usage
+	logger *logrus.Entry
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// startUsage loads the usage statistics from store. It returns nil when
+// they are disabled or there is no store.
+func startUsage(cfg *config.Config, store *state.Store) *panelUsage {
+	if store == nil || (cfg.Display.UsageStats != nil && !*cfg.Display.UsageStats) {
+		return nil
+	}
+	logger := logrus.WithField("component", "usage_stats")
+	usage, err := state.NewUsage(store)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read usage stats, not counting presses")
+		return nil
+	}
+
+	u := &panelUsage{
+		Usage:  usage,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(u.done)
+		ticker := time.NewTicker(usageSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-u.stop:
+				return
+			case <-ticker.C:
+				u.save()
+			}
+		}
+	}()
+	return u
+}
+
+// save writes the presses counted since the last save
+func (u *panelUsage) save() {
+	if err := u.Save(); err != nil {
+		u.logger.WithError(err).Warn("Failed to keep usage stats")
+	}
+}
+
+// Stop writes the last presses and stops saving
+func (u *panelUsage) Stop() {
+	close(u.stop)
+	<-u.done
+	u.save()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/qnap/display-control/internal/control"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/version"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// versionShowTime is how long "version --show-on-lcd" keeps the version on
+// the panel before handing it back
+const versionShowTime = 10 * time.Second
+
+// newVersionCommand creates the "version" subcommand
+func newVersionCommand() *cobra.Command {
+	var showOnLCD bool
+	var showFor time.Duration
+
+	command := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version and git commit of this build",
+		Long: "Prints the version, git commit and Go version of this build. With --show-on-lcd the " +
+			"version and commit are also written to the front panel, to check upgrades from the " +
+			"front of the rack. A running service shows them for --duration; otherwise the panel is " +
+			"opened directly.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVersion(os.Stdout, showOnLCD, showFor)
+		},
+	}
+	command.Flags().BoolVar(&showOnLCD, "show-on-lcd", false, "Also write the version and commit to the panel")
+	command.Flags().DurationVar(&showFor, "duration", versionShowTime, "How long the panel shows the version")
+	return command
+}
+
+// runVersion prints the build and optionally shows it on the panel for the
+// given time
+func runVersion(out io.Writer, showOnLCD bool, showFor time.Duration) error {
+	info := version.Get()
+	fmt.Fprintf(out, "qnap-display-control %s\n", info)
+	if !showOnLCD {
+		return nil
+	}
+
+	setupLogging()
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+	cfg := loadConfiguration()
+
+	// The running service holds the serial port, so it is asked to show them
+	_, err := callService(cfg, control.Request{
+		Command:  "write",
+		Text:     info.Panel(),
+		Duration: max(1, int(showFor/time.Second)),
+	})
+	if !errors.Is(err, control.ErrNoService) {
+		return err
+	}
+
+	systemController, err := controller.NewSystemController(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open the display: %w", err)
+	}
+	defer systemController.Close()
+
+	if err := systemController.GetDisplayController().WriteText(info.Panel()); err != nil {
+		return fmt.Errorf("failed to write the version: %w", err)
+	}
+	time.Sleep(showFor)
+	return nil
+}
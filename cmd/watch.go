@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/watcher"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultWatchPromptTimeout is used when a watch does not set a prompt timeout
+const defaultWatchPromptTimeout = 60 * time.Second
+
+// watchResultTime is how long the outcome of a prompted command is shown
+const watchResultTime = 3 * time.Second
+
+// watchAction runs the configured prompt and command for an arrived file
+type watchAction struct {
+	cfg      config.WatchConfig
+	prompter *prompt.Prompter
+	timeout  time.Duration
+}
+
+// startWatchers starts a watcher for every configured directory. Watches that
+// cannot be started are logged and skipped.
+func startWatchers(cfg *config.Config, prompter *prompt.Prompter) []*watcher.Watcher {
+	var watchers []*watcher.Watcher
+	for _, watchCfg := range cfg.Watch {
+		logger := logrus.WithField("path", watchCfg.Path)
+		if watchCfg.Prompt == "" && watchCfg.Command == "" {
+			logger.Warn("Watch has neither a prompt nor a command, skipping")
+			continue
+		}
+
+		action := &watchAction{cfg: watchCfg, prompter: prompter, timeout: defaultWatchPromptTimeout}
+		if watchCfg.PromptTimeout > 0 {
+			action.timeout = time.Duration(watchCfg.PromptTimeout) * time.Second
+		}
+
+		interval := time.Duration(watchCfg.PollInterval) * time.Millisecond
+		w, err := watcher.NewWatcher(watchCfg.Path, watchCfg.Pattern, interval, action.handle)
+		if err == nil {
+			err = w.Start()
+		}
+		if err != nil {
+			logger.WithError(err).Error("Failed to start watch")
+			continue
+		}
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
+
+// handle asks the operator, if configured, and runs the hook command
+func (a *watchAction) handle(ctx context.Context, event watcher.Event) {
+	logger := logrus.WithField("file", event.Path)
+
+	if a.cfg.Prompt != "" {
+		text := expandWatchTemplate(a.cfg.Prompt, event)
+		options := []string{"OK"}
+		if a.cfg.Command != "" {
+			options = []string{"No", "Yes"}
+		}
+
+		promptCtx, cancel := context.WithTimeout(ctx, a.timeout)
+		choice, err := a.prompter.Prompt(promptCtx, text, options...)
+		cancel()
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			logger.Info("Watch prompt not answered")
+			return
+		case err != nil:
+			logger.WithError(err).Debug("Watch prompt cancelled")
+			return
+		case options[choice] == "No":
+			logger.Info("Watch prompt declined")
+			return
+		}
+		logger.WithField("answer", options[choice]).Info("Watch prompt answered")
+	}
+
+	if a.cfg.Command == "" {
+		return
+	}
+
+	// The file name reaches the command only through the environment, so a
+	// crafted name cannot inject shell syntax
+	cmd := exec.CommandContext(ctx, "sh", "-c", a.cfg.Command)
+	cmd.Env = append(os.Environ(),
+		"WATCH_FILE="+event.Path,
+		"WATCH_NAME="+event.Name,
+		"WATCH_DIR="+a.cfg.Path,
+	)
+	output, err := cmd.CombinedOutput()
+
+	status := "Done"
+	if err != nil {
+		logger.WithError(err).WithField("output", strings.TrimSpace(string(output))).Error("Watch command failed")
+		status = "Failed"
+	} else {
+		logger.Info("Watch command completed")
+	}
+
+	// Nobody is at the panel to read the outcome of an unprompted command
+	if a.cfg.Prompt == "" {
+		return
+	}
+	resultCtx, cancel := context.WithTimeout(ctx, watchResultTime)
+	defer cancel()
+	if _, err := a.prompter.Prompt(resultCtx, event.Name+"\n"+status); err != nil && resultCtx.Err() == nil {
+		logger.WithError(err).Error("Failed to show watch command result")
+	}
+}
+
+// expandWatchTemplate replaces {file} with the name of the arrived file
+func expandWatchTemplate(template string, event watcher.Event) string {
+	return strings.ReplaceAll(template, "{file}", event.Name)
+}
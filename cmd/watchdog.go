@@ -0,0 +1,85 @@
+package main
+
+import (
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/systemd"
+	"github.com/sirupsen/logrus"
+)
+
+// panelReader is a display controller reading the panel in the background,
+// like the serial DisplayController
+type panelReader interface {
+	LastRead() time.Time
+}
+
+// startWatchdog tells systemd the service is ready and, when the unit has
+// WatchdogSec, feeds the watchdog at half its interval as long as the panel
+// reader keeps reading. A read hanging in the driver thus ends in a restart
+// instead of a dead panel. It returns a function telling systemd the
+// service is stopping.
+func startWatchdog(display controller.DisplayControllerInterface) func() {
+	logger := logrus.WithField("component", "watchdog")
+	if sent, err := systemd.Notify(systemd.NotifyReady); err != nil {
+		logger.WithError(err).Warn("Failed to notify systemd")
+	} else if !sent {
+		logger.Debug("Not started by systemd with Type=notify")
+	}
+
+	interval, ok := systemd.WatchdogInterval()
+	if !ok {
+		return notifyStopping
+	}
+	reader, _ := display.(panelReader)
+	logger.WithFields(logrus.Fields{
+		"interval":     interval,
+		"panel_reader": reader != nil,
+	}).Info("Feeding systemd watchdog")
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		hung := false
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if reader != nil {
+					if since := now.Sub(reader.LastRead()); since > interval/2 {
+						if !hung {
+							logger.WithField("since", since.Round(time.Second)).Error("Panel reader hangs, no longer feeding watchdog")
+							hung = true
+						}
+						continue
+					}
+					if hung {
+						logger.Info("Panel reader recovered")
+						hung = false
+					}
+				}
+				if _, err := systemd.Notify(systemd.NotifyWatchdog); err != nil {
+					logger.WithError(err).Warn("Failed to feed watchdog")
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		notifyStopping()
+	}
+}
+
+// notifyStopping tells systemd the service is shutting down
+func notifyStopping() {
+	if _, err := systemd.Notify(systemd.NotifyStopping); err != nil {
+		logrus.WithError(err).Debug("Failed to notify systemd")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/webhook"
+)
+
+// webhookBuffer is how many events wait to be turned into webhooks before
+// some are missed
+const webhookBuffer = 64
+
+// longPressDuration is how long a button is held for a long_press webhook,
+// as long as for a long press in the menu
+const longPressDuration = time.Second
+
+// startWebhooks posts the events the webhooks ask for, following the button
+// presses and copies in the event log. It returns a function stopping them,
+// or nil without webhooks.
+func startWebhooks(cfg *config.Config, eventLog *events.Log) (func(), error) {
+	if len(cfg.Webhooks) == 0 {
+		return nil, nil
+	}
+	hooks := make([]webhook.Hook, 0, len(cfg.Webhooks))
+	for _, hookCfg := range cfg.Webhooks {
+		hook := webhook.Hook{
+			URL:        hookCfg.URL,
+			Events:     hookCfg.Events,
+			Headers:    hookCfg.Headers,
+			Timeout:    time.Duration(hookCfg.Timeout) * time.Second,
+			Retries:    hookCfg.Retries,
+			RetryDelay: time.Duration(hookCfg.RetryDelay) * time.Second,
+		}
+		if err := hook.Validate(); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	dispatcher := webhook.NewDispatcher(hooks)
+	dispatcher.Start()
+	followed, cancel := eventLog.Subscribe(events.Filter{Kinds: []events.Kind{events.KindButton, events.KindCommand}}, webhookBuffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pressed := make(map[string]time.Time)
+		for event := range followed {
+			if name, fields, ok := webhookEvent(event, pressed); ok {
+				dispatcher.Dispatch(name, fields)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		dispatcher.Stop()
+	}, nil
+}
+
+// webhookEvent turns an event of the log into the webhook event posted for
+// it, if any. pressed keeps when the buttons held were pressed.
+func webhookEvent(event events.Event, pressed map[string]time.Time) (string, map[string]string, bool) {
+	switch event.Kind {
+	case events.KindButton:
+		button := event.Fields["button"]
+		if event.Fields["action"] == "pressed" {
+			pressed[button] = event.Time
+			if button == controller.ButtonUSBCopy.String() {
+				return webhook.EventCopyButton, map[string]string{"button": button}, true
+			}
+			return "", nil, false
+		}
+		since, found := pressed[button]
+		delete(pressed, button)
+		if held := event.Time.Sub(since); found && held >= longPressDuration {
+			return webhook.EventLongPress, map[string]string{"button": button, "held_ms": fmt.Sprint(held.Milliseconds())}, true
+		}
+	case events.KindCommand:
+		if event.Fields["item"] != "USB copy" {
+			return "", nil, false
+		}
+		fields := map[string]string{
+			"profile":     event.Fields["profile"],
+			"command":     event.Message,
+			"duration_ms": event.Fields["duration_ms"],
+		}
+		if event.Fields["status"] == "ok" {
+			return webhook.EventCopyDone, fields, true
+		}
+		fields["error"] = event.Fields["error"]
+		return webhook.EventCopyFailed, fields, true
+	}
+	return "", nil, false
+}
@@ -0,0 +1,380 @@
+// Package alert shows alerts raised by the monitors on the LCD.
+//
+// Alerts are identified by a key. Raising a key that is already active only
+// updates its text, so a monitor can re-raise on every poll. The newest alert
+// that has not been acknowledged is shown on the alert layer; a button press
+// acknowledges it and shows the next one, or gives the panel back. An
+// acknowledged alert stays active, without being shown again, until it is
+// cleared.
+//
+// Once the panel's size is known, an alert too long for it is shown page by
+// page: SELECT turns the page and any other button acknowledges it.
+//
+// While the display link is down alerts cannot be seen, so the status LED
+// blinks the severity of the worst pending alert instead, and alerts cleared
+// before anyone saw them stay queued until they have been shown and
+// acknowledged.
+//
+// While suppressed, e.g. during maintenance, alerts are still raised and
+// cleared but neither shown nor blinked; the pending ones appear when the
+// suppression ends.
+package alert
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/sirupsen/logrus"
+)
+
+// Display is where alerts are drawn. screen.Layer satisfies it.
+type Display interface {
+	WriteText(text string) error
+	Release() error
+}
+
+// Severity is how urgent an alert is
+type Severity int
+
+const (
+	Warning Severity = iota
+	Critical
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s == Critical {
+		return "critical"
+	}
+	return "warning"
+}
+
+// alert is an active alert, or a cleared one still queued to be shown
+type alert struct {
+	key          string
+	text         string
+	severity     Severity
+	acknowledged bool
+	// held alerts were pending while the link was down and are kept, even
+	// when cleared, until they have been shown
+	held    bool
+	cleared bool
+}
+
+// Manager keeps the active alerts and shows the newest unacknowledged one
+type Manager struct {
+	display Display
+	logger  *logrus.Entry
+
+	mutex  sync.Mutex
+	alerts []*alert
+	// shown is the alert on the display, nil when the display is released
+	shown *alert
+	// width and height are the panel's size, 0 when unknown
+	width, height int
+	// pager holds the pages of the shown alert when its text does not fit,
+	// made from pagedText
+	pager     *screen.Pager
+	pagedText string
+	// swallow holds buttons whose release belongs to an acknowledging press
+	swallow map[controller.PanelButton]bool
+
+	// linkDown is set while the display cannot be written; leds then show
+	// the pending alerts (nil = no LED fallback)
+	linkDown bool
+	leds     *blinker
+
+	// suppressed keeps alerts off the display and the LEDs
+	suppressed bool
+}
+
+// NewManager creates a manager drawing on the given display
+func NewManager(display Display) *Manager {
+	return &Manager{
+		display: display,
+		logger:  logrus.WithField("component", "alert"),
+		swallow: make(map[controller.PanelButton]bool),
+	}
+}
+
+// SetFallbackLEDs blinks the status LED in a pattern for the worst pending
+// alert while the display link is down
+func (m *Manager) SetFallbackLEDs(leds LEDs) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.leds = newBlinker(leds)
+	m.updateLEDs()
+}
+
+// SetPageSize pages alerts whose text does not fit a panel of the given
+// size
+func (m *Manager) SetPageSize(width, height int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.width, m.height = width, height
+	if m.shown != nil {
+		m.show(m.shown)
+	}
+}
+
+// SetLinkUp tells the manager whether the display can be written. Alerts
+// raised while it cannot are held until they have been shown; when the link
+// comes back the newest pending alert is shown again.
+func (m *Manager) SetLinkUp(up bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.linkDown == !up {
+		return
+	}
+	m.linkDown = !up
+	if m.linkDown {
+		m.logger.Warn("Display link down, alerts are held")
+		for _, a := range m.alerts {
+			if !a.acknowledged {
+				a.held = true
+			}
+		}
+	} else {
+		m.logger.Info("Display link up, showing held alerts")
+		m.render()
+	}
+	m.updateLEDs()
+}
+
+// SetSuppressed holds back alerts from the display and the LEDs. They are
+// still kept, and the newest pending one is shown once the suppression ends.
+func (m *Manager) SetSuppressed(suppressed bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.suppressed == suppressed {
+		return
+	}
+	m.suppressed = suppressed
+	if suppressed {
+		m.logger.Info("Alerts suppressed")
+	} else {
+		m.logger.Info("Alerts no longer suppressed")
+	}
+	m.render()
+	m.updateLEDs()
+}
+
+// Raise activates a warning or updates the text of an active alert
+func (m *Manager) Raise(key, text string) {
+	m.raise(key, text, Warning)
+}
+
+// RaiseCritical activates a critical alert or updates the text of an active
+// one
+func (m *Manager) RaiseCritical(key, text string) {
+	m.raise(key, text, Critical)
+}
+
+// raise activates or updates an alert
+func (m *Manager) raise(key, text string, severity Severity) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, a := range m.alerts {
+		if a.key == key {
+			a.text = text
+			a.severity = severity
+			// Raised again before the held copy was seen
+			a.cleared = false
+			if a == m.shown && !m.suppressed {
+				m.show(a)
+			}
+			m.updateLEDs()
+			return
+		}
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"alert":    key,
+		"severity": severity,
+	}).Warn("Alert raised")
+	m.alerts = append(m.alerts, &alert{key: key, text: text, severity: severity, held: m.linkDown})
+	m.render()
+	m.updateLEDs()
+}
+
+// Clear deactivates an alert. Clearing an inactive key does nothing. A held
+// alert stays queued until it has been shown and acknowledged.
+func (m *Manager) Clear(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, a := range m.alerts {
+		if a.key != key || a.cleared {
+			continue
+		}
+		if a.held {
+			m.logger.WithField("alert", key).Info("Alert cleared, kept until shown")
+			a.cleared = true
+			return
+		}
+		m.logger.WithField("alert", key).Info("Alert cleared")
+		m.alerts = append(m.alerts[:i], m.alerts[i+1:]...)
+		m.render()
+		m.updateLEDs()
+		return
+	}
+}
+
+// Active returns the keys of the active alerts, oldest first. Cleared alerts
+// still queued to be shown are not active.
+func (m *Manager) Active() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]string, 0, len(m.alerts))
+	for _, a := range m.alerts {
+		if !a.cleared {
+			keys = append(keys, a.key)
+		}
+	}
+	return keys
+}
+
+// HandleButton acknowledges the shown alert on a button press, or turns the
+// page of a paged alert on SELECT. It returns true if the event was consumed;
+// the release of a consumed press is consumed too.
+func (m *Manager) HandleButton(button controller.PanelButton, pressed bool) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !pressed {
+		if m.swallow[button] {
+			delete(m.swallow, button)
+			return true
+		}
+		return false
+	}
+	// Nobody can have read an alert while the link is down
+	if m.shown == nil || m.linkDown {
+		return false
+	}
+
+	m.swallow[button] = true
+	if m.pager != nil && button == controller.ButtonSelect {
+		m.pager.Next()
+		m.write()
+		return true
+	}
+
+	m.logger.WithField("alert", m.shown.key).Info("Alert acknowledged")
+	m.shown.acknowledged = true
+	if m.shown.cleared {
+		m.remove(m.shown)
+	}
+	m.render()
+	m.updateLEDs()
+	return true
+}
+
+// remove drops an alert from the list. Caller must hold the mutex.
+func (m *Manager) remove(target *alert) {
+	for i, a := range m.alerts {
+		if a == target {
+			m.alerts = append(m.alerts[:i], m.alerts[i+1:]...)
+			return
+		}
+	}
+}
+
+// updateLEDs blinks the pattern of the worst unacknowledged alert while the
+// link is down and stops blinking otherwise. Caller must hold the mutex.
+func (m *Manager) updateLEDs() {
+	if m.leds == nil {
+		return
+	}
+
+	pending, worst := false, Warning
+	for _, a := range m.alerts {
+		if !a.acknowledged {
+			pending = true
+			if a.severity > worst {
+				worst = a.severity
+			}
+		}
+	}
+	if m.linkDown && pending && !m.suppressed {
+		m.leds.play(worst)
+		return
+	}
+	m.leds.stop(m.linkDown)
+}
+
+// render shows the newest unacknowledged alert or releases the display.
+// Caller must hold the mutex.
+func (m *Manager) render() {
+	for i := len(m.alerts) - 1; i >= 0 && !m.suppressed; i-- {
+		if !m.alerts[i].acknowledged {
+			m.show(m.alerts[i])
+			return
+		}
+	}
+
+	if m.shown != nil {
+		m.shown = nil
+		m.pager = nil
+		if err := m.display.Release(); err != nil {
+			m.logger.WithError(err).Warn("Failed to release alert screen")
+		}
+	}
+}
+
+// show draws an alert. Once it reached a working display it is no longer
+// held. Caller must hold the mutex.
+func (m *Manager) show(a *alert) {
+	// A re-raise with the same text keeps the page being read
+	if a != m.shown || a.text != m.pagedText || m.pager == nil {
+		m.pager = nil
+		if !m.fits(a.text) {
+			m.pager = screen.NewPager(a.text, m.width, m.height)
+		}
+		m.pagedText = a.text
+	}
+	m.shown = a
+	if m.write() && !m.linkDown {
+		a.held = false
+	}
+}
+
+// write draws the shown alert, or its current page. It returns false if the
+// display could not be written. Caller must hold the mutex.
+func (m *Manager) write() bool {
+	text := m.shown.text
+	if m.pager != nil {
+		text = m.pager.Render()
+	}
+	if err := m.display.WriteText(text); err != nil {
+		m.logger.WithError(err).Error("Failed to show alert")
+		return false
+	}
+	return true
+}
+
+// fits reports whether text can be shown whole on the panel; it always does
+// while the panel's size is unknown. Caller must hold the mutex.
+func (m *Manager) fits(text string) bool {
+	if m.width <= 0 || m.height <= 0 {
+		return true
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > m.height {
+		return false
+	}
+	for _, line := range lines {
+		if screen.TextWidth(line) > m.width {
+			return false
+		}
+	}
+	return true
+}
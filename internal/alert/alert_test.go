@@ -0,0 +1,250 @@
+package alert
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingDisplay remembers what is shown; "" once released
+type recordingDisplay struct {
+	text     string
+	releases int
+}
+
+func (d *recordingDisplay) WriteText(text string) error {
+	d.text = text
+	return nil
+}
+
+func (d *recordingDisplay) Release() error {
+	d.text = ""
+	d.releases++
+	return nil
+}
+
+// press presses and releases a button, reporting whether both were consumed
+func press(m *Manager, button controller.PanelButton) bool {
+	pressed := m.HandleButton(button, true)
+	released := m.HandleButton(button, false)
+	return pressed && released
+}
+
+func TestManager(t *testing.T) {
+	display := &recordingDisplay{}
+	m := NewManager(display)
+
+	assert.False(t, press(m, controller.ButtonEnter), "buttons pass through without alerts")
+
+	m.Raise("rack:humidity", "Rack humidity\n72 above 70")
+	assert.Equal(t, "Rack humidity\n72 above 70", display.text)
+
+	// Re-raising only updates the text
+	m.Raise("rack:humidity", "Rack humidity\n73 above 70")
+	assert.Equal(t, "Rack humidity\n73 above 70", display.text)
+	assert.Equal(t, []string{"rack:humidity"}, m.Active())
+
+	// The newest alert is shown first
+	m.Raise("rack:temperature", "Rack temperature\n41 above 40")
+	assert.Equal(t, "Rack temperature\n41 above 40", display.text)
+
+	// Acknowledging shows the next unacknowledged alert, then the panel is
+	// given back
+	assert.True(t, press(m, controller.ButtonSelect))
+	assert.Equal(t, "Rack humidity\n73 above 70", display.text)
+	assert.True(t, press(m, controller.ButtonEnter))
+	assert.Equal(t, "", display.text)
+	assert.Equal(t, 1, display.releases)
+	assert.False(t, press(m, controller.ButtonEnter))
+
+	// Acknowledged alerts stay active but quiet until cleared
+	m.Raise("rack:humidity", "Rack humidity\n74 above 70")
+	assert.Equal(t, "", display.text)
+	assert.Len(t, m.Active(), 2)
+
+	m.Clear("rack:humidity")
+	m.Clear("unknown")
+	assert.Equal(t, []string{"rack:temperature"}, m.Active())
+
+	// A cleared alert that comes back is shown again
+	m.Raise("rack:humidity", "Rack humidity\n75 above 70")
+	assert.Equal(t, "Rack humidity\n75 above 70", display.text)
+
+	// Clearing the shown alert releases the display
+	m.Clear("rack:humidity")
+	assert.Equal(t, "", display.text)
+	assert.Equal(t, 2, display.releases)
+}
+
+// recordingLEDs remembers the status LED states that were set
+type recordingLEDs struct {
+	mutex  sync.Mutex
+	states []string
+}
+
+func (l *recordingLEDs) SetStatusLED(red bool, green bool) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	state := "off"
+	switch {
+	case red && green:
+		state = "both"
+	case red:
+		state = "red"
+	case green:
+		state = "green"
+	}
+	l.states = append(l.states, state)
+	return nil
+}
+
+// last returns the latest state and how often red was switched on
+func (l *recordingLEDs) last() (string, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	reds := 0
+	for _, state := range l.states {
+		if state == "red" {
+			reds++
+		}
+	}
+	if len(l.states) == 0 {
+		return "", 0
+	}
+	return l.states[len(l.states)-1], reds
+}
+
+func TestManager_Paged(t *testing.T) {
+	display := &recordingDisplay{}
+	m := NewManager(display)
+	m.SetPageSize(16, 2)
+
+	m.Raise("rack:humidity", "Rack humidity\n72 above 70")
+	assert.Equal(t, "Rack humidity\n72 above 70", display.text, "text that fits is not paged")
+	assert.True(t, press(m, controller.ButtonEnter))
+
+	// SELECT turns the pages of a long alert, other buttons acknowledge it
+	m.RaiseCritical("pool:tank", "Pool tank\nDEGRADED")
+	m.RaiseCritical("nas:disk", "Disk 3 has 12 pending sectors")
+	assert.Equal(t, "Disk 3 has 12\nPage 1/2", display.text)
+	assert.True(t, press(m, controller.ButtonSelect))
+	assert.Equal(t, "pending sectors\nPage 2/2", display.text)
+
+	m.RaiseCritical("nas:disk", "Disk 3 has 12 pending sectors")
+	assert.Equal(t, "pending sectors\nPage 2/2", display.text, "a re-raise keeps the page")
+	m.RaiseCritical("nas:disk", "Disk 3 has 14 pending sectors")
+	assert.Equal(t, "Disk 3 has 14\nPage 1/2", display.text, "new text starts over")
+
+	assert.True(t, press(m, controller.ButtonEnter))
+	assert.Equal(t, "Pool tank\nDEGRADED", display.text)
+
+	// A one-line panel shows no indicator
+	m.SetPageSize(16, 1)
+	assert.Equal(t, "Pool tank", display.text)
+	assert.True(t, press(m, controller.ButtonSelect))
+	assert.Equal(t, "DEGRADED", display.text)
+}
+
+func TestManager_LinkDown(t *testing.T) {
+	display := &recordingDisplay{}
+	leds := &recordingLEDs{}
+	m := NewManager(display)
+	m.SetFallbackLEDs(leds)
+
+	m.SetLinkUp(false)
+	state, _ := leds.last()
+	assert.Equal(t, "", state, "the LED is left alone without pending alerts")
+
+	// A critical alert flashes red fast
+	m.RaiseCritical("ups", "UPS\non battery")
+	assert.Eventually(t, func() bool {
+		_, reds := leds.last()
+		return reds >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	// Nobody can acknowledge what the display could not show, and clearing
+	// it keeps it queued
+	assert.False(t, press(m, controller.ButtonEnter))
+	m.Clear("ups")
+	assert.Empty(t, m.Active())
+	m.Raise("rack:temperature", "Rack temperature\n41 above 40")
+
+	// Back up, the held alerts are shown newest first and the LED turns
+	// green
+	m.SetLinkUp(true)
+	assert.Equal(t, "Rack temperature\n41 above 40", display.text)
+	state, _ = leds.last()
+	assert.Equal(t, "green", state)
+
+	assert.True(t, press(m, controller.ButtonEnter))
+	assert.Equal(t, "UPS\non battery", display.text, "the cleared alert is shown once")
+	assert.True(t, press(m, controller.ButtonEnter))
+	assert.Equal(t, "", display.text)
+	assert.Equal(t, []string{"rack:temperature"}, m.Active())
+
+	// Alerts raised while the link is up are dropped when cleared
+	m.Raise("rack:humidity", "Rack humidity\n72 above 70")
+	m.Clear("rack:humidity")
+	assert.Equal(t, "", display.text)
+	assert.Equal(t, []string{"rack:temperature"}, m.Active())
+}
+
+func TestManager_LEDPatterns(t *testing.T) {
+	leds := &recordingLEDs{}
+	m := NewManager(&recordingDisplay{})
+	m.SetFallbackLEDs(leds)
+	m.SetLinkUp(false)
+
+	// A warning alternates red and green
+	m.Raise("rack:humidity", "Rack humidity\n72 above 70")
+	assert.Eventually(t, func() bool {
+		state, _ := leds.last()
+		return state == "green"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The worst pending alert sets the pattern, and a held warning keeps
+	// blinking after it cleared
+	m.Clear("rack:humidity")
+	m.RaiseCritical("ups", "UPS\non battery")
+	_, before := leds.last()
+	assert.Eventually(t, func() bool {
+		_, reds := leds.last()
+		return reds >= before+3
+	}, time.Second, 10*time.Millisecond)
+
+	m.SetLinkUp(true)
+	state, _ := leds.last()
+	assert.Equal(t, "green", state)
+}
+
+func TestManager_Suppressed(t *testing.T) {
+	display := &recordingDisplay{}
+	leds := &recordingLEDs{}
+	m := NewManager(display)
+	m.SetFallbackLEDs(leds)
+
+	m.Raise("rack:humidity", "Rack humidity\n72 above 70")
+	m.SetSuppressed(true)
+	assert.Equal(t, "", display.text, "the shown alert is taken down")
+	assert.False(t, press(m, controller.ButtonEnter), "buttons pass through")
+
+	// Alerts are still kept, but not shown or blinked
+	m.SetLinkUp(false)
+	m.RaiseCritical("ups", "UPS\non battery")
+	m.Raise("rack:humidity", "Rack humidity\n73 above 70")
+	assert.Equal(t, "", display.text)
+	assert.Equal(t, []string{"rack:humidity", "ups"}, m.Active())
+	time.Sleep(200 * time.Millisecond)
+	_, reds := leds.last()
+	assert.Equal(t, 0, reds)
+
+	// The pending alerts appear when the suppression ends
+	m.SetLinkUp(true)
+	m.SetSuppressed(false)
+	assert.Equal(t, "UPS\non battery", display.text)
+}
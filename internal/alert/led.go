@@ -0,0 +1,100 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LEDs is the status LED pair. controller.LEDControllerInterface satisfies it.
+type LEDs interface {
+	SetStatusLED(red bool, green bool) error
+}
+
+// ledStep is one state of a status LED pattern
+type ledStep struct {
+	red, green bool
+	duration   time.Duration
+}
+
+// ledPatterns tell the severities apart without the display: a warning
+// alternates red and green once a second, a critical alert flashes red fast
+var ledPatterns = map[Severity][]ledStep{
+	Warning: {
+		{red: true, duration: time.Second},
+		{green: true, duration: time.Second},
+	},
+	Critical: {
+		{red: true, duration: 150 * time.Millisecond},
+		{duration: 150 * time.Millisecond},
+	},
+}
+
+// blinker plays one LED pattern at a time in the background
+type blinker struct {
+	leds   LEDs
+	logger *logrus.Entry
+
+	playing bool
+	current Severity
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+// newBlinker creates a blinker for the status LEDs
+func newBlinker(leds LEDs) *blinker {
+	return &blinker{leds: leds, logger: logrus.WithField("component", "alert_leds")}
+}
+
+// play switches to the pattern of a severity. Playing the pattern that is
+// already running does nothing.
+func (b *blinker) play(severity Severity) {
+	if b.playing && b.current == severity {
+		return
+	}
+	b.halt()
+
+	b.playing, b.current = true, severity
+	b.quit, b.done = make(chan struct{}), make(chan struct{})
+	go b.run(ledPatterns[severity], b.quit, b.done)
+}
+
+// stop ends the pattern and leaves the steady state of the link: red while it
+// is down, green once it is up again. Stopping a stopped blinker does nothing.
+func (b *blinker) stop(linkDown bool) {
+	if !b.playing {
+		return
+	}
+	b.halt()
+	if err := b.leds.SetStatusLED(linkDown, !linkDown); err != nil {
+		b.logger.WithError(err).Warn("Failed to restore status LED")
+	}
+}
+
+// halt ends the running pattern and waits for it
+func (b *blinker) halt() {
+	if !b.playing {
+		return
+	}
+	close(b.quit)
+	<-b.done
+	b.playing = false
+}
+
+// run cycles through the steps until quit is closed
+func (b *blinker) run(steps []ledStep, quit, done chan struct{}) {
+	defer close(done)
+
+	for i := 0; ; i = (i + 1) % len(steps) {
+		step := steps[i]
+		if err := b.leds.SetStatusLED(step.red, step.green); err != nil {
+			b.logger.WithError(err).Debug("Failed to set status LED")
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-time.After(step.duration):
+		}
+	}
+}
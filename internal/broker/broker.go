@@ -0,0 +1,174 @@
+// Package broker runs vetted commands in a helper process that keeps root
+// while the service itself runs unprivileged. The service and the helper
+// talk over a socket pair; the helper only runs commands from its own
+// allow-list, so a compromised service cannot escalate to anything else.
+package broker
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRequestSize bounds a request line so the helper cannot be made to
+// buffer arbitrary amounts of data
+const maxRequestSize = 64 * 1024
+
+// Command is a command the helper may run, with the environment variables
+// a request may set for it. Anything else, PATH and LD_PRELOAD in
+// particular, comes from the helper.
+type Command struct {
+	Command string
+	Env     []string
+}
+
+// Request asks the helper to run a command
+type Request struct {
+	Command string   `json:"command"`
+	Env     []string `json:"env,omitempty"`
+}
+
+// Response carries the combined output of a command and, if it failed, why
+type Response struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ErrNotAllowed is returned for commands that are not on the allow-list
+var ErrNotAllowed = errors.New("command not allowed")
+
+// Server answers requests in the helper process
+type Server struct {
+	// allowed maps the commands to the variables they may be given
+	allowed map[string]map[string]bool
+	logger  *logrus.Entry
+}
+
+// NewServer creates a server that runs only the given commands. A command
+// listed twice may be given the variables of both.
+func NewServer(commands []Command) *Server {
+	allowed := make(map[string]map[string]bool, len(commands))
+	for _, command := range commands {
+		if allowed[command.Command] == nil {
+			allowed[command.Command] = make(map[string]bool)
+		}
+		for _, name := range command.Env {
+			allowed[command.Command][name] = true
+		}
+	}
+	return &Server{
+		allowed: allowed,
+		logger:  logrus.WithField("component", "broker"),
+	}
+}
+
+// Serve answers requests one at a time until conn is closed. It returns nil
+// when the other end goes away and an error for malformed requests.
+func (s *Server) Serve(conn io.ReadWriter) error {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var request Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			return fmt.Errorf("malformed request: %w", err)
+		}
+		if err := encoder.Encode(s.handle(request)); err != nil {
+			return fmt.Errorf("failed to send response: %w", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// handle vets and runs a single request
+func (s *Server) handle(request Request) Response {
+	logger := s.logger.WithField("command", request.Command)
+	allowedEnv, ok := s.allowed[request.Command]
+	if !ok {
+		logger.Warn("Refused command that is not on the allow-list")
+		return Response{Error: ErrNotAllowed.Error()}
+	}
+
+	env := os.Environ()
+	for _, variable := range request.Env {
+		name, _, found := strings.Cut(variable, "=")
+		if !found || !allowedEnv[name] {
+			logger.WithField("variable", name).Warn("Refused environment variable")
+			return Response{Error: fmt.Sprintf("environment variable %q not allowed", name)}
+		}
+		env = append(env, variable)
+	}
+
+	logger.Info("Running privileged command")
+	cmd := exec.Command("sh", "-c", request.Command)
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	response := Response{Output: string(output)}
+	if err != nil {
+		logger.WithError(err).Warn("Privileged command failed")
+		response.Error = err.Error()
+	}
+	return response
+}
+
+// Client sends requests to the helper. It is safe for concurrent use;
+// requests are answered in turn.
+type Client struct {
+	mutex   sync.Mutex
+	conn    io.ReadWriteCloser
+	encoder *json.Encoder
+	decoder *json.Decoder
+	// helper is the process started by Spawn, nil for NewClient
+	helper *helper
+}
+
+// NewClient creates a client talking to a server over conn
+func NewClient(conn io.ReadWriteCloser) *Client {
+	return &Client{
+		conn:    conn,
+		encoder: json.NewEncoder(conn),
+		decoder: json.NewDecoder(conn),
+	}
+}
+
+// Run has the helper run command with the extra environment variables and
+// returns its combined output. A command that ran but failed returns its
+// output along with the error.
+func (c *Client) Run(command string, env []string) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.encoder.Encode(Request{Command: command, Env: env}); err != nil {
+		return nil, fmt.Errorf("failed to send request to privileged helper: %w", err)
+	}
+	var response Response
+	if err := c.decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("no response from privileged helper: %w", err)
+	}
+	if response.Error == ErrNotAllowed.Error() {
+		return nil, ErrNotAllowed
+	}
+	if response.Error != "" {
+		return []byte(response.Output), errors.New(response.Error)
+	}
+	return []byte(response.Output), nil
+}
+
+// Close closes the connection, which makes the helper exit once it has
+// finished the current command, and reaps a spawned helper
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	if c.helper != nil {
+		c.helper.stop()
+	}
+	return err
+}
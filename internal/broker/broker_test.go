@@ -0,0 +1,105 @@
+package broker
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperEnv makes the test binary act as a spawned helper
+const helperEnv = "BROKER_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) != "" {
+		if err := NewServer([]Command{{Command: "echo helper"}}).Serve(HelperConn()); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startServer serves the commands over an in-memory connection
+func startServer(t *testing.T, commands ...Command) *Client {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- NewServer(commands).Serve(serverConn) }()
+
+	client := NewClient(clientConn)
+	t.Cleanup(func() {
+		client.Close()
+		assert.NoError(t, <-done)
+	})
+	return client
+}
+
+func TestClientRun(t *testing.T) {
+	client := startServer(t, Command{Command: "echo hello"},
+		Command{Command: `printf '%s' "$INPUT"`, Env: []string{"INPUT"}},
+		Command{Command: "echo oops; exit 3"})
+
+	output, err := client.Run("echo hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+
+	output, err = client.Run(`printf '%s' "$INPUT"`, []string{"INPUT=my folder; rm -rf /"})
+	require.NoError(t, err)
+	assert.Equal(t, "my folder; rm -rf /", string(output))
+
+	output, err = client.Run("echo oops; exit 3", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3")
+	assert.Equal(t, "oops\n", string(output))
+}
+
+func TestClientRun_Refused(t *testing.T) {
+	client := startServer(t, Command{Command: "echo hello"},
+		Command{Command: `printf '%s' "$PATH"`, Env: []string{"INPUT"}},
+		Command{Command: `printf '%s' "$POOL"`, Env: []string{"POOL"}},
+		Command{Command: `printf '%s' "$POOL"`, Env: []string{"PORT"}})
+
+	_, err := client.Run("echo hello; id", nil)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	_, err = client.Run(`printf '%s' "$PATH"`, []string{"PATH=/tmp/evil"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"PATH" not allowed`)
+
+	_, err = client.Run("echo hello", []string{"INPUT"})
+	assert.Error(t, err)
+
+	// Variables are allowed per command
+	_, err = client.Run("echo hello", []string{"INPUT=x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"INPUT" not allowed`)
+
+	output, err := client.Run(`printf '%s' "$POOL"`, []string{"POOL=tank", "PORT=eth0"})
+	require.NoError(t, err, "a command listed twice gets the variables of both")
+	assert.Equal(t, "tank", string(output))
+
+	// The connection is still usable after refusals
+	output, err = client.Run("echo hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestSpawn(t *testing.T) {
+	t.Setenv(helperEnv, "1")
+	client, err := Spawn(os.Args[0], "-test.run=^$")
+	require.NoError(t, err)
+
+	output, err := client.Run("echo helper", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "helper\n", string(output))
+
+	_, err = client.Run("echo other", nil)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	require.NoError(t, client.Close())
+	assert.True(t, client.helper.cmd.ProcessState.Success(), "helper exits cleanly once closed")
+}
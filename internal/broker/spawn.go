@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// HelperFD is the descriptor the helper finds its end of the socket pair on
+const HelperFD = 3
+
+// helperStopTimeout is how long a helper may take to finish its current
+// command after the service closed the connection
+const helperStopTimeout = 5 * time.Second
+
+// helper is a spawned helper process
+type helper struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// Spawn starts the helper by running name with args, connected through a
+// socket pair passed as descriptor HelperFD. It must be called while the
+// service still runs as root, since the helper inherits its privileges.
+func Spawn(name string, args ...string) (*Client, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket pair: %w", err)
+	}
+	local := os.NewFile(uintptr(fds[0]), "broker")
+	remote := os.NewFile(uintptr(fds[1]), "broker-helper")
+	defer remote.Close()
+
+	cmd := exec.Command(name, args...)
+	cmd.ExtraFiles = []*os.File{remote}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		local.Close()
+		return nil, fmt.Errorf("failed to start privileged helper: %w", err)
+	}
+
+	h := &helper{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		close(h.done)
+	}()
+
+	client := NewClient(local)
+	client.helper = h
+	return client, nil
+}
+
+// HelperConn returns the helper's end of the socket pair
+func HelperConn() *os.File {
+	return os.NewFile(HelperFD, "broker")
+}
+
+// stop waits for the helper to exit after the connection was closed and
+// kills it if it is stuck in a command
+func (h *helper) stop() {
+	select {
+	case <-h.done:
+	case <-time.After(helperStopTimeout):
+		h.cmd.Process.Kill()
+		<-h.done
+	}
+}
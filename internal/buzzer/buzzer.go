@@ -0,0 +1,117 @@
+// Package buzzer sounds the NAS's buzzer through the PC speaker input device
+// of the kernel's pcspkr driver, or through a command on systems that reach
+// it otherwise.
+package buzzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultDevice is the input device of the pcspkr driver
+const DefaultDevice = "/dev/input/by-path/platform-pcspkr-event-spkr"
+
+// DefaultPitch is the tone of a beep in Hz
+const DefaultPitch = 2000
+
+// Event types and codes from linux/input-event-codes.h
+const (
+	evSnd   = 0x12
+	sndTone = 0x02
+)
+
+// inputEvent is struct input_event
+type inputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Buzzer beeps, one beep at a time
+type Buzzer struct {
+	file *os.File
+	// events receives the input events, the file except in tests
+	events io.Writer
+	// command runs once per beep instead, through sh -c
+	command string
+	mutex   sync.Mutex
+}
+
+// Open opens the PC speaker device at path. It stays open, so the buzzer
+// still sounds after root privileges are dropped.
+func Open(path string) (*Buzzer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &Buzzer{file: file, events: file}, nil
+}
+
+// Command returns a buzzer that runs command for every beep
+func Command(command string) *Buzzer {
+	return &Buzzer{command: command}
+}
+
+// Beep sounds the buzzer for duration, or until ctx is cancelled. A command
+// decides itself how long it beeps.
+func (b *Buzzer) Beep(ctx context.Context, duration time.Duration) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.command != "" {
+		output, err := exec.CommandContext(ctx, "sh", "-c", b.command).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("buzzer command failed: %w: %s", err, bytes.TrimSpace(output))
+		}
+		return nil
+	}
+	if b.events == nil {
+		return errors.New("buzzer is closed")
+	}
+
+	if err := b.tone(DefaultPitch); err != nil {
+		return err
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	return b.tone(0)
+}
+
+// tone starts a tone of the given pitch, or stops it for 0
+func (b *Buzzer) tone(hz int32) error {
+	var event bytes.Buffer
+	binary.Write(&event, binary.NativeEndian, &inputEvent{Type: evSnd, Code: sndTone, Value: hz})
+	if _, err := b.events.Write(event.Bytes()); err != nil {
+		return fmt.Errorf("failed to sound buzzer: %w", err)
+	}
+	return nil
+}
+
+// Close closes the device
+func (b *Buzzer) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	b.events = nil
+	return err
+}
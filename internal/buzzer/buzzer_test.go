@@ -0,0 +1,73 @@
+package buzzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuzzer_Beep(t *testing.T) {
+	var events bytes.Buffer
+	b := &Buzzer{events: &events}
+
+	start := time.Now()
+	require.NoError(t, b.Beep(context.Background(), 20*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	var got []inputEvent
+	for events.Len() > 0 {
+		var event inputEvent
+		require.NoError(t, binary.Read(&events, binary.NativeEndian, &event))
+		got = append(got, event)
+	}
+	assert.Equal(t, []inputEvent{
+		{Type: evSnd, Code: sndTone, Value: DefaultPitch},
+		{Type: evSnd, Code: sndTone, Value: 0},
+	}, got)
+}
+
+func TestBuzzer_BeepCancelled(t *testing.T) {
+	var events bytes.Buffer
+	b := &Buzzer{events: &events}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	require.NoError(t, b.Beep(ctx, time.Minute))
+	assert.Less(t, time.Since(start), time.Second)
+	// The tone is stopped all the same
+	assert.Equal(t, 2*binary.Size(inputEvent{}), events.Len())
+}
+
+func TestBuzzer_Command(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "beeped")
+	b := Command("echo beep >> " + marker)
+
+	require.NoError(t, b.Beep(context.Background(), time.Second))
+	require.NoError(t, b.Beep(context.Background(), time.Second))
+	data, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	assert.Equal(t, "beep\nbeep\n", string(data))
+
+	err = Command("echo no buzzer >&2; exit 1").Beep(context.Background(), time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no buzzer")
+	assert.NoError(t, b.Close())
+}
+
+func TestBuzzer_Closed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spkr")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	b, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+	assert.Error(t, b.Beep(context.Background(), time.Millisecond))
+	assert.NoError(t, b.Close())
+}
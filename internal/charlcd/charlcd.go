@@ -0,0 +1,102 @@
+// Package charlcd encodes the command sets of off-the-shelf character display
+// modules and decodes their key reports.
+//
+// Modules from Matrix Orbital and CrystalFontz attach over USB as a serial
+// port, so they use the same transport as the QNAP panel; only the bytes
+// differ. A Protocol turns display operations into those bytes and the bytes
+// the module sends back into key events.
+package charlcd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Key identifies a key on a module's keypad
+type Key int
+
+const (
+	KeyUp Key = iota
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEnter
+	KeyExit
+)
+
+// String returns the name of the key
+func (k Key) String() string {
+	switch k {
+	case KeyUp:
+		return "up"
+	case KeyDown:
+		return "down"
+	case KeyLeft:
+		return "left"
+	case KeyRight:
+		return "right"
+	case KeyEnter:
+		return "enter"
+	case KeyExit:
+		return "exit"
+	default:
+		return fmt.Sprintf("key(%d)", int(k))
+	}
+}
+
+// KeyEvent is a key being pressed or released
+type KeyEvent struct {
+	Key     Key
+	Pressed bool
+}
+
+// Protocol is the command set of a display module
+type Protocol interface {
+	// Init returns the commands that prepare the module after it is opened
+	Init() []byte
+	// Line writes a whole row, truncated and padded to width characters
+	Line(row, width int, text string) []byte
+	// Backlight switches the backlight
+	Backlight(on bool) []byte
+	// Glyph defines custom character slot from eight pixel rows, the lowest
+	// five bits of each
+	Glyph(slot int, rows [8]byte) []byte
+	// DecodeKeys parses key reports from received bytes. It returns the
+	// events and how many bytes were consumed; the rest is an incomplete
+	// report to be completed by the next read.
+	DecodeKeys(data []byte) (events []KeyEvent, consumed int)
+}
+
+// protocols create the supported protocols by name
+var protocols = map[string]func() Protocol{
+	"matrix-orbital": func() Protocol { return NewMatrixOrbital() },
+	"crystalfontz":   func() Protocol { return NewCrystalFontz() },
+}
+
+// Names returns the names of the supported protocols
+func Names() []string {
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the protocol of the given name
+func New(name string) (Protocol, error) {
+	create, exists := protocols[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown display protocol %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return create(), nil
+}
+
+// fitLine truncates and pads text to width characters
+func fitLine(text string, width int) string {
+	if len(text) > width {
+		return text[:width]
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
@@ -0,0 +1,79 @@
+package charlcd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range Names() {
+		protocol, err := New(name)
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, protocol.Init(), name)
+	}
+
+	_, err := New("lcdproc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crystalfontz, matrix-orbital")
+}
+
+func TestMatrixOrbital(t *testing.T) {
+	p := NewMatrixOrbital()
+
+	assert.Equal(t, append([]byte{0xFE, 0x47, 1, 2}, "Hi      "...), p.Line(1, 8, "Hi"))
+	assert.Equal(t, append([]byte{0xFE, 0x47, 1, 1}, "Hell"...), p.Line(0, 4, "Hello"))
+	assert.Equal(t, []byte{0xFE, 0x42, 0x00}, p.Backlight(true))
+	assert.Equal(t, []byte{0xFE, 0x46}, p.Backlight(false))
+	assert.Equal(t, []byte{0xFE, 0x4E, 2, 1, 2, 3, 4, 5, 6, 7, 8}, p.Glyph(2, [8]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+
+	events, consumed := p.DecodeKeys([]byte("EzH"))
+	assert.Equal(t, 3, consumed)
+	assert.Equal(t, []KeyEvent{
+		{Key: KeyEnter, Pressed: true}, {Key: KeyEnter, Pressed: false},
+		{Key: KeyDown, Pressed: true}, {Key: KeyDown, Pressed: false},
+	}, events)
+}
+
+func TestCrystalFontz_Packets(t *testing.T) {
+	// CRC-16/X-25 check value
+	assert.Equal(t, uint16(0x906E), cfCRC([]byte("123456789")))
+
+	p := NewCrystalFontz()
+	line := p.Line(1, 16, "Hello")
+	assert.Equal(t, []byte{cfWrite, 18, 0, 1}, line[:4])
+	assert.Equal(t, "Hello           ", string(line[4:20]))
+	assert.Len(t, line, 22)
+
+	assert.Equal(t, []byte{cfBacklight, 1, 100}, p.Backlight(true)[:3])
+	assert.Equal(t, []byte{cfGlyph, 9, 3}, p.Glyph(3, [8]byte{})[:3])
+
+	// Packets decode as their own replies would: a valid CRC
+	events, consumed := p.DecodeKeys(p.Init())
+	assert.Empty(t, events)
+	assert.Equal(t, 4, consumed)
+}
+
+func TestCrystalFontz_DecodeKeys(t *testing.T) {
+	p := NewCrystalFontz()
+	enterPress := cfPacket(cfKeyReport, 5)
+	enterRelease := cfPacket(cfKeyReport, 11)
+	reply := cfPacket(cfWrite | 0x40)
+
+	var stream []byte
+	stream = append(stream, enterPress...)
+	stream = append(stream, 0x55) // noise
+	stream = append(stream, reply...)
+	stream = append(stream, enterRelease...)
+	stream = append(stream, cfPacket(cfKeyReport, 2)[:3]...) // incomplete
+
+	events, consumed := p.DecodeKeys(stream)
+	assert.Equal(t, []KeyEvent{{Key: KeyEnter, Pressed: true}, {Key: KeyEnter, Pressed: false}}, events)
+	assert.Equal(t, len(stream)-3, consumed, "the incomplete packet is kept for the next read")
+
+	corrupt := cfPacket(cfKeyReport, 1)
+	corrupt[3] ^= 0xFF
+	events, _ = p.DecodeKeys(corrupt)
+	assert.Empty(t, events, "packets with a bad CRC are dropped")
+}
@@ -0,0 +1,117 @@
+package charlcd
+
+import "encoding/binary"
+
+// CrystalFontz packet types and limits. Replies to commands have 0x40 or,
+// for errors, 0xC0 added to the command type; the key report is the only
+// report used.
+const (
+	cfClear        = 0x06
+	cfGlyph        = 0x09
+	cfBacklight    = 0x0E
+	cfWrite        = 0x1F
+	cfKeyReport    = 0x80
+	cfMaxData      = 22
+	cfPacketFrame  = 4 // type, length and the two CRC bytes
+	cfBacklightOn  = 100
+	cfBacklightOff = 0
+)
+
+// cfKeys maps the key activity codes of the key report: 1-6 are presses,
+// 7-12 the releases of the same keys
+var cfKeys = [...]Key{KeyUp, KeyDown, KeyLeft, KeyRight, KeyEnter, KeyExit}
+
+// CrystalFontz speaks the packet protocol of the CFA631, CFA633 and CFA635
+// modules: a type byte, a length byte, up to 22 data bytes and a CRC.
+type CrystalFontz struct{}
+
+// NewCrystalFontz returns the CrystalFontz packet protocol
+func NewCrystalFontz() *CrystalFontz {
+	return &CrystalFontz{}
+}
+
+// Init clears the screen
+func (p *CrystalFontz) Init() []byte {
+	return cfPacket(cfClear)
+}
+
+// Line writes the text at column 0 of the row
+func (p *CrystalFontz) Line(row, width int, text string) []byte {
+	data := append([]byte{0, byte(row)}, fitLine(text, width)...)
+	if len(data) > cfMaxData {
+		data = data[:cfMaxData]
+	}
+	return cfPacket(cfWrite, data...)
+}
+
+// Backlight switches the backlight to full brightness or off
+func (p *CrystalFontz) Backlight(on bool) []byte {
+	if on {
+		return cfPacket(cfBacklight, cfBacklightOn)
+	}
+	return cfPacket(cfBacklight, cfBacklightOff)
+}
+
+// Glyph defines a custom character
+func (p *CrystalFontz) Glyph(slot int, rows [8]byte) []byte {
+	return cfPacket(cfGlyph, append([]byte{byte(slot)}, rows[:]...)...)
+}
+
+// DecodeKeys parses key reports and skips command replies. A packet with a
+// bad CRC loses its first byte so the parser can find the next packet.
+func (p *CrystalFontz) DecodeKeys(data []byte) ([]KeyEvent, int) {
+	var events []KeyEvent
+	consumed := 0
+	for len(data)-consumed >= cfPacketFrame {
+		packet := data[consumed:]
+		length := int(packet[1])
+		if length > cfMaxData {
+			consumed++
+			continue
+		}
+		size := length + cfPacketFrame
+		if len(packet) < size {
+			break
+		}
+		if binary.LittleEndian.Uint16(packet[2+length:]) != cfCRC(packet[:2+length]) {
+			consumed++
+			continue
+		}
+		consumed += size
+
+		if packet[0] != cfKeyReport || length != 1 {
+			continue
+		}
+		code := int(packet[2])
+		switch {
+		case code >= 1 && code <= len(cfKeys):
+			events = append(events, KeyEvent{Key: cfKeys[code-1], Pressed: true})
+		case code > len(cfKeys) && code <= 2*len(cfKeys):
+			events = append(events, KeyEvent{Key: cfKeys[code-len(cfKeys)-1], Pressed: false})
+		}
+	}
+	return events, consumed
+}
+
+// cfPacket builds a packet with its CRC
+func cfPacket(command byte, data ...byte) []byte {
+	packet := append([]byte{command, byte(len(data))}, data...)
+	return binary.LittleEndian.AppendUint16(packet, cfCRC(packet))
+}
+
+// cfCRC is the CRC-16 the modules use: the reflected CCITT polynomial,
+// starting at 0xFFFF and inverted at the end
+func cfCRC(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
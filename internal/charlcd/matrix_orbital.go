@@ -0,0 +1,69 @@
+package charlcd
+
+// Matrix Orbital commands start with this byte
+const moCommand = 0xFE
+
+// moKeys maps the key codes of the usual Matrix Orbital keypads (LK162-12,
+// LK204-7T and their 5 or 6 key layouts) to keys
+var moKeys = map[byte]Key{
+	'B': KeyUp,
+	'H': KeyDown,
+	'D': KeyLeft,
+	'C': KeyRight,
+	'E': KeyEnter,
+	'A': KeyExit,
+}
+
+// MatrixOrbital speaks the LK/GLK command set. Its keypads report a press as
+// a single ASCII character and never report releases, so every press is
+// decoded as a press followed by a release.
+type MatrixOrbital struct{}
+
+// NewMatrixOrbital returns the Matrix Orbital protocol
+func NewMatrixOrbital() *MatrixOrbital {
+	return &MatrixOrbital{}
+}
+
+// Init turns off line wrapping and scrolling, which would move text written
+// to the last column, turns on key reporting and clears the screen
+func (p *MatrixOrbital) Init() []byte {
+	return []byte{
+		moCommand, 0x44, // auto line wrap off
+		moCommand, 0x52, // auto scroll off
+		moCommand, 0x41, // auto transmit key presses on
+		moCommand, 0x58, // clear screen
+	}
+}
+
+// Line moves the cursor to the start of the row (positions count from 1)
+// and writes the text
+func (p *MatrixOrbital) Line(row, width int, text string) []byte {
+	command := []byte{moCommand, 0x47, 1, byte(row + 1)}
+	return append(command, fitLine(text, width)...)
+}
+
+// Backlight switches the backlight on without a timeout, or off
+func (p *MatrixOrbital) Backlight(on bool) []byte {
+	if on {
+		return []byte{moCommand, 0x42, 0x00}
+	}
+	return []byte{moCommand, 0x46}
+}
+
+// Glyph defines a custom character
+func (p *MatrixOrbital) Glyph(slot int, rows [8]byte) []byte {
+	command := []byte{moCommand, 0x4E, byte(slot)}
+	return append(command, rows[:]...)
+}
+
+// DecodeKeys turns every known key code into a press and a release. Other
+// bytes are skipped.
+func (p *MatrixOrbital) DecodeKeys(data []byte) ([]KeyEvent, int) {
+	var events []KeyEvent
+	for _, code := range data {
+		if key, ok := moKeys[code]; ok {
+			events = append(events, KeyEvent{Key: key, Pressed: true}, KeyEvent{Key: key, Pressed: false})
+		}
+	}
+	return events, len(data)
+}
@@ -0,0 +1,280 @@
+// Package clamav scans files with a running clamd, for the virus scan stage
+// of the USB copy.
+//
+// Files are streamed to clamd with its INSTREAM command rather than passed
+// by path, so clamd needs no access to the USB device or the shares.
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultAddress is the socket of clamd on Debian based systems
+const DefaultAddress = "/var/run/clamav/clamd.ctl"
+
+// chunkSize is how much of a file is sent to clamd at once
+const chunkSize = 64 * 1024
+
+// requestTimeout bounds scanning one file without any progress
+const requestTimeout = time.Minute
+
+// ErrTooLarge is returned by Scan for files above clamd's
+// StreamMaxLength, which it refuses to scan
+var ErrTooLarge = errors.New("file too large for clamd")
+
+// Client talks to clamd
+type Client struct {
+	network string
+	address string
+}
+
+// NewClient returns a client for clamd at address: a unix socket path, or
+// host:port for TCP ("" = DefaultAddress)
+func NewClient(address string) *Client {
+	if address == "" {
+		address = DefaultAddress
+	}
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	return &Client{network: network, address: address}
+}
+
+// dial connects to clamd
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach clamd: %w", err)
+	}
+	return conn, nil
+}
+
+// Ping checks that clamd answers
+func (c *Client) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+	return nil
+}
+
+// Scan streams r to clamd and returns the name of the virus found in it, or
+// "" when it is clean
+func (c *Client) Scan(ctx context.Context, r io.Reader) (string, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", err
+	}
+	buffer := make([]byte, 4+chunkSize)
+	for {
+		n, readErr := r.Read(buffer[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buffer, uint32(n))
+			conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+			if _, err := conn.Write(buffer[:4+n]); err != nil {
+				// clamd closes the connection once the stream is too long
+				if reply, replyErr := readReply(conn); replyErr == nil {
+					return parseReply(reply)
+				}
+				return "", err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return "", readErr
+		}
+	}
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return "", err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return "", err
+	}
+	return parseReply(reply)
+}
+
+// readReply reads one reply of a z command, which ends with a zero byte
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// parseReply turns a reply to INSTREAM, e.g. "stream: OK" or
+// "stream: Eicar-Signature FOUND", into the virus found
+func parseReply(reply string) (string, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	case strings.Contains(result, "size limit exceeded"):
+		return "", ErrTooLarge
+	default:
+		return "", fmt.Errorf("clamd: %s", strings.TrimSuffix(result, " ERROR"))
+	}
+}
+
+// Finding is an infected file
+type Finding struct {
+	Path  string
+	Virus string
+}
+
+// Result sums up a scanned directory
+type Result struct {
+	// Files counts the regular files below the directory
+	Files int
+	// Infected are the files a virus was found in
+	Infected []Finding
+	// Unscanned counts the files clamd could not scan, e.g. ones above its
+	// size limit or unreadable ones
+	Unscanned int
+}
+
+// Progress is told about every file before it is scanned
+type Progress func(done, total int, path string)
+
+// ScanDir scans the regular files below dir, telling progress about each.
+// Files that cannot be scanned are counted; an error means clamd could not
+// be reached or the scan was cancelled.
+func (c *Client) ScanDir(ctx context.Context, dir string, progress Progress) (Result, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	result := Result{Files: len(files)}
+	for i, path := range files {
+		if progress != nil {
+			progress(i, len(files), path)
+		}
+		virus, err := c.scanFile(ctx, path)
+		switch {
+		case ctx.Err() != nil:
+			return result, ctx.Err()
+		case errors.Is(err, ErrTooLarge), errors.Is(err, fs.ErrPermission), errors.Is(err, fs.ErrNotExist):
+			result.Unscanned++
+		case err != nil:
+			return result, err
+		case virus != "":
+			result.Infected = append(result.Infected, Finding{Path: path, Virus: virus})
+		}
+	}
+	if progress != nil {
+		progress(len(files), len(files), "")
+	}
+	return result, nil
+}
+
+// scanFile scans one file
+func (c *Client) scanFile(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return c.Scan(ctx, f)
+}
+
+// Quarantine moves the infected files into dir, created if needed, where
+// only their owner can read them. Names taken already get a number, e.g.
+// "setup.exe.1". It returns how many were moved.
+func Quarantine(findings []Finding, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return 0, fmt.Errorf("failed to create quarantine %s: %w", dir, err)
+	}
+	moved := 0
+	for _, finding := range findings {
+		target := filepath.Join(dir, filepath.Base(finding.Path))
+		for n := 1; ; n++ {
+			if _, err := os.Lstat(target); errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			target = filepath.Join(dir, fmt.Sprintf("%s.%d", filepath.Base(finding.Path), n))
+		}
+		if err := move(finding.Path, target); err != nil {
+			return moved, fmt.Errorf("failed to quarantine %s: %w", finding.Path, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// move renames source to target, copying it when they are on different file
+// systems, e.g. the USB device and the NAS
+func move(source, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return os.Chmod(target, 0600)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+	return os.Remove(source)
+}
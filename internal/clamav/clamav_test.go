@@ -0,0 +1,167 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startClamd serves a fake clamd on a unix socket that finds a virus in
+// every stream containing "EICAR" and refuses streams above limit bytes
+func startClamd(t *testing.T, limit int) string {
+	t.Helper()
+
+	// Socket paths are limited to about 100 bytes, too short for TempDir
+	dir, err := os.MkdirTemp("", "clamd")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "clamd.ctl")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, limit)
+		}
+	}()
+	return socket
+}
+
+// serveClamd answers one command
+func serveClamd(conn net.Conn, limit int) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+	switch command {
+	case "zPING\x00":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		var stream bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&stream, reader, int64(size)); err != nil {
+				return
+			}
+			if stream.Len() > limit {
+				conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+		}
+		if strings.Contains(stream.String(), "EICAR") {
+			conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			return
+		}
+		conn.Write([]byte("stream: OK\x00"))
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func TestClient_Scan(t *testing.T) {
+	client := NewClient(startClamd(t, 1<<20))
+	require.NoError(t, client.Ping(context.Background()))
+
+	virus, err := client.Scan(context.Background(), strings.NewReader("holiday photos"))
+	require.NoError(t, err)
+	assert.Empty(t, virus)
+
+	virus, err = client.Scan(context.Background(), strings.NewReader("X5O!P%@AP EICAR test"))
+	require.NoError(t, err)
+	assert.Equal(t, "Eicar-Test-Signature", virus)
+
+	// Several chunks
+	virus, err = client.Scan(context.Background(), strings.NewReader(strings.Repeat("a", 3*chunkSize)+"EICAR"))
+	require.NoError(t, err)
+	assert.Equal(t, "Eicar-Test-Signature", virus)
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "missing.ctl"))
+	assert.Error(t, client.Ping(context.Background()))
+	_, err := client.Scan(context.Background(), strings.NewReader("data"))
+	assert.Error(t, err)
+}
+
+func TestNewClient(t *testing.T) {
+	assert.Equal(t, &Client{network: "unix", address: DefaultAddress}, NewClient(""))
+	assert.Equal(t, &Client{network: "tcp", address: "127.0.0.1:3310"}, NewClient("127.0.0.1:3310"))
+}
+
+func TestParseReply(t *testing.T) {
+	virus, err := parseReply("stream: OK")
+	require.NoError(t, err)
+	assert.Empty(t, virus)
+
+	_, err = parseReply("INSTREAM size limit exceeded. ERROR")
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, err = parseReply("stream: Can't allocate memory ERROR")
+	require.Error(t, err)
+	assert.Equal(t, "clamd: Can't allocate memory", err.Error())
+}
+
+func TestScanDirAndQuarantine(t *testing.T) {
+	client := NewClient(startClamd(t, 100))
+	dir := t.TempDir()
+	files := map[string]string{
+		"photo.jpg":        "holiday",
+		"setup.exe":        "EICAR",
+		"docs/readme.txt":  "hello",
+		"docs/setup.exe":   "EICAR again",
+		"docs/archive.zip": strings.Repeat("z", 200),
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	var seen []string
+	result, err := client.ScanDir(context.Background(), dir, func(done, total int, path string) {
+		assert.Equal(t, 5, total)
+		seen = append(seen, path)
+	})
+	require.NoError(t, err)
+	assert.Len(t, seen, 6, "every file and the end")
+	assert.Equal(t, 5, result.Files)
+	assert.Equal(t, 1, result.Unscanned, "the archive is above the size limit")
+	require.Len(t, result.Infected, 2)
+	assert.Equal(t, filepath.Join(dir, "docs/setup.exe"), result.Infected[0].Path)
+	assert.Equal(t, "Eicar-Test-Signature", result.Infected[0].Virus)
+
+	quarantine := filepath.Join(t.TempDir(), "quarantine")
+	moved, err := Quarantine(result.Infected, quarantine)
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+	assert.NoFileExists(t, filepath.Join(dir, "setup.exe"))
+	assert.FileExists(t, filepath.Join(quarantine, "setup.exe"))
+	assert.FileExists(t, filepath.Join(quarantine, "setup.exe.1"), "the second one does not replace the first")
+	info, err := os.Stat(filepath.Join(quarantine, "setup.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHealth = Health{
+	Hostname:      "nas2",
+	UptimeSec:     273845,
+	Load:          [3]float64{0.52, 0.40, 0.31},
+	Alerts:        []string{"rack/temperature"},
+	VolumePercent: 81,
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler("secret", func() Health { return testHealth }))
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+HealthPath, nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var health Health
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&health))
+	assert.Equal(t, testHealth, health)
+
+	response, err = http.Get(server.URL + HealthPath)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode, "the token is required")
+
+	response, err = http.Post(server.URL+HealthPath, "application/json", nil)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestRequireQueryToken(t *testing.T) {
+	server := httptest.NewServer(RequireQueryToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer server.Close()
+
+	for query, want := range map[string]int{
+		"?token=secret": http.StatusNoContent,
+		"?token=wrong":  http.StatusUnauthorized,
+		"":              http.StatusUnauthorized,
+	} {
+		response, err := http.Get(server.URL + "/events/stream" + query)
+		require.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, want, response.StatusCode, "query %q without a header", query)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/events/stream", nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode, "the header still works")
+}
+
+func TestMonitor(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	handler := Handler("secret", func() Health { return testHealth })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	monitor := NewMonitor([]Peer{{Name: "nas2", URL: server.URL + "/"}}, "secret", 20*time.Millisecond)
+	assert.Equal(t, []string{"nas2"}, monitor.Names())
+	status, err := monitor.Status("nas2")
+	require.NoError(t, err)
+	assert.Equal(t, "nas2         ...\nwaiting", status.Render(16), "nothing is known before the first poll")
+
+	monitor.Start()
+	defer monitor.Stop()
+	assert.Eventually(t, func() bool {
+		status, _ := monitor.Status("nas2")
+		return status.Err == nil
+	}, time.Second, 5*time.Millisecond)
+	status, _ = monitor.Status("nas2")
+	assert.Equal(t, testHealth, status.Health)
+	assert.False(t, status.Seen.IsZero())
+
+	// A peer going away keeps its last summary
+	up.Store(false)
+	assert.Eventually(t, func() bool {
+		status, _ := monitor.Status("nas2")
+		return status.Err != nil
+	}, time.Second, 5*time.Millisecond)
+	status, _ = monitor.Status("nas2")
+	assert.Equal(t, testHealth, status.Health)
+	assert.Equal(t, "nas2 down", status.Summary())
+
+	_, err = monitor.Status("nas3")
+	assert.Error(t, err)
+}
+
+func TestPeerStatusRender(t *testing.T) {
+	status := PeerStatus{Name: "nas2", Health: Health{Load: [3]float64{0.52}, VolumePercent: 81}}
+	assert.Equal(t, "nas2          OK\nLoad 0.52    81%", status.Render(16))
+	assert.Equal(t, "nas2 OK 0.52", status.Summary())
+
+	status.Health.VolumePercent = -1
+	status.Health.Alerts = []string{"rack/temperature", "rack/humidity"}
+	assert.Equal(t, "nas2    2 alerts\nLoad 0.52", status.Render(16))
+	assert.Equal(t, "nas2 2 alerts", status.Summary())
+
+	status = PeerStatus{Name: "backup-server-02", Err: errors.New("connection refused")}
+	assert.Equal(t, "backup-serv down\nnever seen", status.Render(16))
+	status.Seen = time.Date(2026, time.October, 16, 18, 4, 0, 0, time.Local)
+	assert.Equal(t, "backup-serv down\nseen 10-16 18:04", status.Render(16))
+}
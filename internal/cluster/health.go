@@ -0,0 +1,74 @@
+// Package cluster turns the panel into a small dashboard of several nodes.
+//
+// Every node can serve its own health summary over HTTP. A node with peers
+// configured polls their summaries in the background and keeps the latest
+// one of each, so the display never waits for the network.
+package cluster
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// HealthPath is where a node serves its health summary
+const HealthPath = "/api/health"
+
+// Health is the health summary of a node
+type Health struct {
+	Hostname  string     `json:"hostname"`
+	UptimeSec int64      `json:"uptime_sec"`
+	Load      [3]float64 `json:"load"`
+	// Alerts are the keys of the node's active alerts
+	Alerts []string `json:"alerts"`
+	// VolumePercent is how full the fullest volume is; -1 when unknown
+	VolumePercent int `json:"volume_percent"`
+}
+
+// Handler serves the summary returned by collect at HealthPath. With a token
+// set, requests must send it as "Authorization: Bearer <token>".
+func Handler(token string, collect func() Health) http.Handler {
+	serve := RequireToken(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(collect())
+	}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		serve.ServeHTTP(w, r)
+	})
+	return mux
+}
+
+// RequireToken passes only requests sending token as "Authorization: Bearer
+// <token>" on to handler. An empty token lets every request through.
+func RequireToken(token string, handler http.Handler) http.Handler {
+	if token == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// RequireQueryToken is RequireToken also accepting the token as a "token"
+// query parameter when no Authorization header is sent, since browsers
+// cannot set headers on a WebSocket
+func RequireQueryToken(token string, handler http.Handler) http.Handler {
+	required := RequireToken(token, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if query := r.URL.Query().Get("token"); query != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+query)
+		}
+		required.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,268 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPollInterval is how often peers are asked for their health when no
+// interval is set
+const DefaultPollInterval = 15 * time.Second
+
+// requestTimeout bounds one health request, so a hanging peer does not hold
+// up the next poll
+const requestTimeout = 5 * time.Second
+
+// maxHealthSize bounds the summary read from a peer
+const maxHealthSize = 64 * 1024
+
+// errNotPolled is the state of a peer before its first poll finished
+var errNotPolled = errors.New("not polled yet")
+
+// Peer is another node whose health is shown
+type Peer struct {
+	Name string
+	// URL is the base address of the peer's API, e.g. "http://nas2:9180"
+	URL string
+}
+
+// PeerStatus is what is known about a peer
+type PeerStatus struct {
+	Name string
+	// Health is the last summary the peer sent
+	Health Health
+	// Seen is when the peer last answered; zero if it never did
+	Seen time.Time
+	// Err is why the last poll failed, nil if it succeeded
+	Err error
+}
+
+// state is a word or two on the peer's condition
+func (s PeerStatus) state() string {
+	switch {
+	case errors.Is(s.Err, errNotPolled):
+		return "..."
+	case s.Err != nil:
+		return "down"
+	case len(s.Health.Alerts) == 1:
+		return "1 alert"
+	case len(s.Health.Alerts) > 1:
+		return fmt.Sprintf("%d alerts", len(s.Health.Alerts))
+	}
+	return "OK"
+}
+
+// Summary renders the status on one line, e.g. "nas2 OK 0.52" or
+// "nas2 down"
+func (s PeerStatus) Summary() string {
+	if s.Err != nil || len(s.Health.Alerts) > 0 {
+		return s.Name + " " + s.state()
+	}
+	return fmt.Sprintf("%s OK %.2f", s.Name, s.Health.Load[0])
+}
+
+// Render shows the status on two lines of the given width: the name with
+// its condition, then the load with the fullest volume's usage, or when an
+// unreachable peer was last seen
+func (s PeerStatus) Render(width int) string {
+	line1 := spread(s.Name, s.state(), width)
+
+	var line2 string
+	switch {
+	case errors.Is(s.Err, errNotPolled):
+		line2 = "waiting"
+	case s.Err != nil && s.Seen.IsZero():
+		line2 = "never seen"
+	case s.Err != nil:
+		line2 = "seen " + s.Seen.Format("01-02 15:04")
+	default:
+		volume := ""
+		if s.Health.VolumePercent >= 0 {
+			volume = fmt.Sprintf("%d%%", s.Health.VolumePercent)
+		}
+		line2 = spread(fmt.Sprintf("Load %.2f", s.Health.Load[0]), volume, width)
+	}
+	return line1 + "\n" + line2
+}
+
+// spread puts left and right at the edges of a line of the given width,
+// shortening left if they do not fit
+func spread(left, right string, width int) string {
+	if right == "" {
+		return left
+	}
+	room := width - len(right) - 1
+	if room < 1 {
+		return left + " " + right
+	}
+	if len(left) > room {
+		left = left[:room]
+	}
+	return left + strings.Repeat(" ", width-len(left)-len(right)) + right
+}
+
+// Monitor polls the health of the peers and keeps the latest of each
+type Monitor struct {
+	peers    []Peer
+	token    string
+	interval time.Duration
+	client   *http.Client
+	logger   *logrus.Entry
+
+	mutex    sync.Mutex
+	statuses map[string]*PeerStatus
+	cancel   context.CancelFunc
+	done     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor asking the peers every interval (0 for
+// DefaultPollInterval), sending token if it is set
+func NewMonitor(peers []Peer, token string, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	statuses := make(map[string]*PeerStatus, len(peers))
+	for _, peer := range peers {
+		statuses[peer.Name] = &PeerStatus{Name: peer.Name, Err: errNotPolled}
+	}
+	return &Monitor{
+		peers:    peers,
+		token:    token,
+		interval: interval,
+		client:   &http.Client{Timeout: requestTimeout},
+		logger:   logrus.WithField("component", "cluster"),
+		statuses: statuses,
+	}
+}
+
+// Names returns the peer names in the order they were configured
+func (m *Monitor) Names() []string {
+	names := make([]string, len(m.peers))
+	for i, peer := range m.peers {
+		names[i] = peer.Name
+	}
+	return names
+}
+
+// Start polls every peer in the background
+func (m *Monitor) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	for _, peer := range m.peers {
+		peer := peer
+		m.done.Add(1)
+		go func() {
+			defer m.done.Done()
+			m.run(ctx, peer)
+		}()
+	}
+}
+
+// Stop ends polling and waits for requests in progress
+func (m *Monitor) Stop() {
+	m.mutex.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		m.done.Wait()
+	}
+}
+
+// Status returns what is known about a peer
+func (m *Monitor) Status(name string) (PeerStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	status, exists := m.statuses[name]
+	if !exists {
+		return PeerStatus{}, fmt.Errorf("unknown peer %q", name)
+	}
+	return *status, nil
+}
+
+// run polls a peer until ctx is cancelled
+func (m *Monitor) run(ctx context.Context, peer Peer) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.poll(ctx, peer)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll asks a peer for its health once. Going down and coming back are
+// logged once each rather than on every poll.
+func (m *Monitor) poll(ctx context.Context, peer Peer) {
+	health, err := m.fetch(ctx, peer)
+	if ctx.Err() != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	status := m.statuses[peer.Name]
+	logger := m.logger.WithField("peer", peer.Name)
+	if err != nil {
+		if status.Err == nil || errors.Is(status.Err, errNotPolled) {
+			logger.WithError(err).Warn("Peer unreachable")
+		}
+		status.Err = err
+		return
+	}
+	if status.Err != nil && !errors.Is(status.Err, errNotPolled) {
+		logger.Info("Peer reachable again")
+	}
+	status.Health, status.Seen, status.Err = health, time.Now(), nil
+}
+
+// fetch requests a peer's health summary
+func (m *Monitor) fetch(ctx context.Context, peer Peer) (Health, error) {
+	var health Health
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(peer.URL, "/")+HealthPath, nil)
+	if err != nil {
+		return health, err
+	}
+	if m.token != "" {
+		request.Header.Set("Authorization", "Bearer "+m.token)
+	}
+	response, err := m.client.Do(request)
+	if err != nil {
+		return health, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return health, fmt.Errorf("health request failed: %s", response.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxHealthSize)).Decode(&health); err != nil {
+		return health, fmt.Errorf("malformed health summary: %w", err)
+	}
+	return health, nil
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"net"
 	"os"
+	"time"
+
+	"github.com/qnap/display-control/internal/translit"
 )
 
 // Config represents the application configuration
@@ -12,6 +18,54 @@ type Config struct {
 	Display    DisplayConfig    `json:"display"`
 	Logging    LoggingConfig    `json:"logging"`
 	Menu       MenuConfig       `json:"menu"`
+	Hardware   HardwareConfig   `json:"hardware"`
+	// Watch lists directories whose new files trigger prompts or commands
+	Watch []WatchConfig `json:"watch,omitempty"`
+	// Privileges controls dropping root once the hardware is open
+	Privileges PrivilegesConfig `json:"privileges,omitempty"`
+	// Sensors lists ambient sensors on the I2C header
+	Sensors []SensorConfig `json:"sensors,omitempty"`
+	// Uinput mirrors the panel buttons on a virtual keyboard
+	Uinput UinputConfig `json:"uinput,omitempty"`
+	// Storage configures the storage browser menu
+	Storage StorageConfig `json:"storage,omitempty"`
+	// Network configures the network port screens
+	Network NetworkConfig `json:"network,omitempty"`
+	// Schedule lists screens shown at times given by cron expressions
+	Schedule []ScheduleConfig `json:"schedule,omitempty"`
+	// Cluster shows the health of peer nodes and serves this node's own
+	Cluster ClusterConfig `json:"cluster,omitempty"`
+	// StateFile keeps data across restarts, such as the per-device copy
+	// counters ("" = /var/lib/qnap-display/state.json)
+	StateFile string `json:"state_file,omitempty"`
+	// PIDFile is written by the service run with --daemon, for the stop and
+	// reload commands ("" = /run/qnap-display.pid)
+	PIDFile string `json:"pid_file,omitempty"`
+	// Control is the socket other invocations reach the running service on
+	Control ControlConfig `json:"control,omitempty"`
+	// Events keeps the service's recent internal events for the events API
+	Events EventsConfig `json:"events,omitempty"`
+	// GRPC serves the display, LEDs and buttons to other services
+	GRPC GRPCConfig `json:"grpc,omitempty"`
+	// SMART samples drive attributes for the trends in the menu
+	SMART SMARTConfig `json:"smart,omitempty"`
+	// LCDproc lets lcdproc clients drive the panel like LCDd
+	LCDproc LCDprocConfig `json:"lcdproc,omitempty"`
+	// NASAPI reads pools, alerts and updates from TrueNAS SCALE or
+	// OpenMediaVault running on the box
+	NASAPI NASAPIConfig `json:"nas_api,omitempty"`
+	// Webhooks post copy button presses, copy results and long presses to
+	// other services
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+	// MQTT publishes the panel to an MQTT broker, e.g. for Home Assistant
+	MQTT MQTTConfig `json:"mqtt,omitempty"`
+	// Rules raise alerts and switch LEDs on conditions over the metrics of
+	// the sensors, SMART samples, load and NAS API
+	Rules []RuleConfig `json:"rules,omitempty"`
+	// PowerOff keeps a screen on the panel while the system shuts down
+	PowerOff PowerOffConfig `json:"poweroff,omitempty"`
+	// Buzzer sounds when a panel timer runs out
+	Buzzer BuzzerConfig `json:"buzzer,omitempty"`
 }
 
 // SerialPortConfig contains serial port settings
@@ -19,6 +73,13 @@ type SerialPortConfig struct {
 	Device   string `json:"device"`
 	BaudRate int    `json:"baud_rate"`
 	Timeout  int    `json:"timeout_ms"`
+	// ErrorThreshold consecutive write errors pause display writes (default 5)
+	ErrorThreshold int `json:"error_threshold,omitempty"`
+	// ProbeInterval is how often a paused link is probed, in ms (default 5000)
+	ProbeInterval int `json:"probe_interval_ms,omitempty"`
+	// InitAttempts is how often the panel is initialized at startup until it
+	// answers, waiting longer each time (default 6)
+	InitAttempts int `json:"init_attempts,omitempty"`
 }
 
 // USBCopyConfig contains USB copy button settings
@@ -27,15 +88,701 @@ type USBCopyConfig struct {
 	PollInterval int    `json:"poll_interval_ms"`
 	Enabled     bool   `json:"enabled"`
 	Command     string `json:"command"`
+	// Confirm asks on the panel before starting the copy
+	Confirm     bool   `json:"confirm"`
+	// Privileged runs the copy through the root helper when privileges are
+	// dropped
+	Privileged bool `json:"privileged,omitempty"`
+	// Source is where the USB device is mounted. When set, copies are
+	// counted per device and the result shows how much data was new.
+	Source string `json:"source,omitempty"`
+	// Profiles are offered on the panel when the copy button is pressed;
+	// without them the button runs Command
+	Profiles []CopyProfile `json:"profiles,omitempty"`
+	// LUKS unlocks encrypted USB devices before a copy
+	LUKS LUKSConfig `json:"luks,omitempty"`
+	// Clamd is the socket or host:port of clamd for virus scans (default
+	// /var/run/clamav/clamd.ctl)
+	Clamd string `json:"clamd,omitempty"`
+	// Scan is the virus scan of the copy run without profiles
+	Scan *ScanConfig `json:"scan,omitempty"`
+	// Destination and Report are those of the copy run without profiles
+	Destination string `json:"destination,omitempty"`
+	Report      bool   `json:"report,omitempty"`
+}
+
+// LUKSConfig unlocks a LUKS encrypted USB device and mounts it at
+// usb_copy.source for the copy, locking it again afterwards
+type LUKSConfig struct {
+	Enabled bool `json:"enabled"`
+	// KeyFile unlocks the device; without it a numeric passphrase is entered
+	// on the panel
+	KeyFile string `json:"key_file,omitempty"`
+}
+
+// CopyProfile is a copy chosen on the panel when the copy button is pressed
+type CopyProfile struct {
+	Name string `json:"name"`
+	// Direction is "import" (default, USB to NAS) or "export" (NAS to USB)
+	Direction string `json:"direction,omitempty"`
+	// Command runs an import instead of usb_copy.command
+	Command string `json:"command,omitempty"`
+	// Source is the directory an export copies to the USB device mounted at
+	// usb_copy.source, e.g. a share
+	Source string `json:"source,omitempty"`
+	// LatestSnapshot exports the newest directory below Source instead, e.g.
+	// with Source the share's snapshot directory
+	LatestSnapshot bool `json:"latest_snapshot,omitempty"`
+	// Scan runs a virus scan with clamd before or after the copy
+	Scan *ScanConfig `json:"scan,omitempty"`
+	// Destination is the directory an import copies to, which its report
+	// goes into; without it scan.path is used
+	Destination string `json:"destination,omitempty"`
+	// Report writes the files copied with their checksums, the time taken
+	// and the errors into the directory copied to, and offers a summary on
+	// the panel
+	Report bool `json:"report,omitempty"`
 }
 
+// ScanConfig is the virus scan stage of a copy
+type ScanConfig struct {
+	// Stage is "after" (default), scanning what was copied, or "before",
+	// scanning the source before anything is copied
+	Stage string `json:"stage,omitempty"`
+	// Path is the directory an import copies to, scanned after it; imports
+	// scanned before scan usb_copy.source, exports their source or the
+	// directory they create
+	Path string `json:"path,omitempty"`
+	// Quarantine is the directory infected files are moved to. Without it
+	// they are only reported, and a scan before the copy stops it.
+	Quarantine string `json:"quarantine,omitempty"`
+}
+
+// Virus scan stages
+const (
+	ScanBefore = "before"
+	ScanAfter  = "after"
+)
+
+// Copy profile directions
+const (
+	CopyImport = "import"
+	CopyExport = "export"
+)
+
 // DisplayConfig contains display settings
 type DisplayConfig struct {
+	// Driver selects the panel: "qnap" (default, the serial front panel),
+	// "qnap-lcm" (the framed protocol of newer A125 panels), "ssd1306" or "sh1106" (I2C OLED modules, see OLED), "matrix-orbital"
+	// or "crystalfontz" (USB character modules on SerialPort), "hd44780"
+	// (a character LCD on GPIO lines, see GPIO) or "pcf8574" (a character
+	// LCD with an I2C backpack, see PCF8574)
+	Driver       string `json:"driver,omitempty"`
 	Width        int    `json:"width"`
 	Height       int    `json:"height"`
 	BacklightPin int    `json:"backlight_pin"`
 	Contrast     int    `json:"contrast"`
 	DefaultText  string `json:"default_text"`
+	// Splash is shown at startup while the service comes up
+	Splash SplashConfig `json:"splash"`
+	// ProgressUpdatesPerSec caps how often ShowProgress redraws the bar
+	ProgressUpdatesPerSec int `json:"progress_updates_per_sec"`
+	// RedrawInterval rewrites the whole screen every so many seconds, in
+	// case another process wrote to the panel's tty; 0 disables it
+	RedrawInterval int `json:"redraw_interval_sec,omitempty"`
+	// IdleAnimation plays after IdleTimeout seconds without button presses:
+	// "snake", "bounce" or "" to disable
+	IdleAnimation string `json:"idle_animation"`
+	IdleTimeout   int    `json:"idle_timeout_sec"`
+	// AutoIdleTimeout replaces IdleTimeout with the one suggested by the
+	// pauses between button presses, once enough were counted
+	AutoIdleTimeout bool `json:"auto_idle_timeout,omitempty"`
+	// UsageStats counts the button presses by hour of the day and the
+	// pauses between them for the usage_stats screen (default true)
+	UsageStats *bool `json:"usage_stats,omitempty"`
+	// IdleText is bounced by the "bounce" animation; the hostname when empty
+	IdleText string `json:"idle_text,omitempty"`
+	// StatusLine dedicates a line (1 = top, or the bottom line) to the status
+	// rotation; the menu gets the other lines. 0 leaves the whole display to
+	// the menu.
+	StatusLine int `json:"status_line,omitempty"`
+	// StatusItems are shown in turn: "hostname", "ip", "uptime", "load",
+	// "cpu" and "time" (default: hostname, ip, uptime, load)
+	StatusItems []string `json:"status_items,omitempty"`
+	// StatusInterval is how long each status item is shown, in seconds (default 5)
+	StatusInterval int `json:"status_interval_sec,omitempty"`
+	// Rotations are named groups of status items, shown in the order listed
+	// while the panel is idle, see IdleSchedule
+	Rotations map[string][]RotationItem `json:"rotations,omitempty"`
+	// IdleSchedule picks what the panel shows while idle by the time of day;
+	// outside its windows IdleAnimation plays
+	IdleSchedule []IdleWindow `json:"idle_schedule,omitempty"`
+	// Abbreviations add to or replace the built-in abbreviations used when a
+	// status item or menu line is too long, e.g. {"volume": "vol"}. Mapping
+	// a word to itself keeps it.
+	Abbreviations map[string]string `json:"abbreviations,omitempty"`
+	// OLED describes the module used by the OLED drivers. Width and Height
+	// above are its character grid.
+	OLED OLEDConfig `json:"oled,omitempty"`
+	// GPIO wires the display of the "hd44780" driver. Width and Height
+	// above are its size.
+	GPIO GPIODisplayConfig `json:"gpio,omitempty"`
+	// PCF8574 locates the backpack of the "pcf8574" driver. Width and
+	// Height above are the display's size.
+	PCF8574 PCF8574Config `json:"pcf8574,omitempty"`
+	// Transliteration maps the characters beyond ASCII that the panel
+	// cannot show
+	Transliteration TransliterationConfig `json:"transliteration,omitempty"`
+}
+
+// RotationItem is one status item of a rotation
+type RotationItem struct {
+	// Item is the status item, one of those StatusItems takes
+	Item string `json:"item"`
+	// Dwell is how long the item is shown, in seconds (default
+	// StatusInterval)
+	Dwell int `json:"dwell_sec,omitempty"`
+}
+
+// SplashConfig is the screen shown at startup until its time is up or a
+// button is pressed
+type SplashConfig struct {
+	// Text is shown, rows separated by "\n", to the right of the logo if
+	// there is one
+	Text string `json:"text"`
+	// Duration is how long the splash is shown, in seconds (default 2, 0
+	// disables it)
+	Duration int `json:"duration_sec"`
+	// Logo is pixel art drawn with custom characters at the left of the
+	// splash: rows of pixels from the top, "#" lit and "." dark, at most 16
+	// rows of 20 pixels. Panels without custom characters show only the text.
+	Logo []string `json:"logo,omitempty"`
+}
+
+// IdleWindow is a time of day during which the idle panel shows a rotation
+// or an animation other than IdleAnimation
+type IdleWindow struct {
+	// From and To are "15:04" times; a window ending before it starts
+	// spans midnight
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Rotation names one of Rotations; Animation is shown instead when set
+	Rotation  string `json:"rotation,omitempty"`
+	Animation string `json:"animation,omitempty"`
+}
+
+// Span returns the start and end of the window as times since midnight
+func (w IdleWindow) Span() (from, to time.Duration, err error) {
+	if from, err = timeOfDay(w.From); err != nil {
+		return 0, 0, err
+	}
+	if to, err = timeOfDay(w.To); err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
+// timeOfDay parses a "15:04" time into the time since midnight
+func timeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// TransliterationConfig adds to the built-in transliteration, which shows
+// accented Latin letters, typographic punctuation, arrows, common symbols and
+// a few emoji as ASCII. A replacement written "{name}" is one of the panel's
+// glyphs, e.g. "{bar3}".
+type TransliterationConfig struct {
+	// Classes replace every character of a class, "arrows", "emoji" or
+	// "symbols" (e.g. the degree sign), with one replacement
+	Classes map[string]string `json:"classes,omitempty"`
+	// Characters replace single characters, before the classes, e.g.
+	// {"°": "'"}
+	Characters map[string]string `json:"characters,omitempty"`
+	// Fallback replaces characters nothing maps (default "?"); "" drops
+	// them
+	Fallback *string `json:"fallback,omitempty"`
+	// Language spells its letters its way, "de" (e.g. "ä" as "ae") or "da"
+	// (e.g. "å" as "aa"), instead of as their base letters
+	Language string `json:"language,omitempty"`
+}
+
+// Options returns the transliteration options, with glyph resolving glyph
+// names
+func (t TransliterationConfig) Options(glyph func(name string) (string, bool)) translit.Options {
+	return translit.Options{
+		Classes:    t.Classes,
+		Characters: t.Characters,
+		Fallback:   t.Fallback,
+		Language:   t.Language,
+		Glyph:      glyph,
+	}
+}
+
+// OLEDConfig describes an I2C OLED module
+type OLEDConfig struct {
+	// Bus is the N of /dev/i2c-N
+	Bus int `json:"bus"`
+	// Address is the 7 bit device address (default 0x3C)
+	Address uint16 `json:"address,omitempty"`
+	// Width and Height are in pixels (default 128x64)
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Flip rotates the picture for modules mounted upside down
+	Flip bool `json:"flip,omitempty"`
+}
+
+// PCF8574Config locates the PCF8574 I/O expander on the backpack of a
+// character LCD, wired the usual way: P0 RS, P1 R/W, P2 E, P3 backlight and
+// P4-P7 D4-D7
+type PCF8574Config struct {
+	// Bus is the N of /dev/i2c-N
+	Bus int `json:"bus"`
+	// Address is the 7 bit device address (default 0x27; PCF8574A
+	// backpacks use 0x3F)
+	Address uint16 `json:"address,omitempty"`
+}
+
+// GPIODisplayConfig names the GPIO lines an HD44780 display is wired to in
+// 4-bit mode, by their offsets on the chip; its R/W pin goes to ground
+type GPIODisplayConfig struct {
+	// Chip is the GPIO character device (default /dev/gpiochip0)
+	Chip string `json:"chip,omitempty"`
+	// RS and E are the lines of the display's RS and E pins
+	RS int `json:"rs"`
+	E  int `json:"e"`
+	// Data are the lines of D4, D5, D6 and D7
+	Data [4]int `json:"data"`
+	// Backlight switches the backlight, e.g. through a transistor; without
+	// it switching the backlight off blanks the display
+	Backlight *int `json:"backlight,omitempty"`
+	// Enter and Select are buttons that connect their line to ground
+	Enter  *int `json:"enter,omitempty"`
+	Select *int `json:"select,omitempty"`
+}
+
+// HardwareConfig selects the panel hardware profile and overrides its quirks.
+// Pointer fields left unset keep the profile's value.
+type HardwareConfig struct {
+	// Profile names a built-in profile: "generic" (default), "state-frame",
+	// "prefixed-frame", "slow-refresh" or "a125-i2c" (panel MCU on SMBus,
+	// see PanelBus)
+	Profile string `json:"profile"`
+	// PanelBus is the N of /dev/i2c-N the a125-i2c panel is attached to
+	PanelBus int `json:"panel_i2c_bus,omitempty"`
+	// PanelAddress is the 7 bit address of the a125-i2c panel (default 0x3A)
+	PanelAddress uint16 `json:"panel_i2c_address,omitempty"`
+	// CopyDuplicateWindow suppresses repeated copy presses within this many ms
+	CopyDuplicateWindow *int `json:"copy_duplicate_window_ms,omitempty"`
+	// CopyFramePrefixes lists first bytes of separate copy frames, e.g. [85, 67] for 0x55/0x43
+	CopyFramePrefixes []int `json:"copy_frame_prefixes,omitempty"`
+
+	// Bit positions (0-7) of the buttons in the state byte of 0x53 frames
+	EnterBit  *int `json:"enter_bit,omitempty"`
+	SelectBit *int `json:"select_bit,omitempty"`
+	CopyBit   *int `json:"copy_bit,omitempty"`
+
+	// Inverted buttons read as pressed when their bit is 0 (active low)
+	EnterInverted  *bool `json:"enter_inverted,omitempty"`
+	SelectInverted *bool `json:"select_inverted,omitempty"`
+	CopyInverted   *bool `json:"copy_inverted,omitempty"`
+	// CopyActiveLow is the former name of CopyInverted, used when that is
+	// not set.
+	//
+	// Deprecated: use CopyInverted.
+	CopyActiveLow *bool `json:"copy_active_low,omitempty"`
+
+	// GlyphCommand is the byte sequence that starts a CGRAM upload on panels
+	// whose firmware supports custom characters. Each glyph is sent as these
+	// bytes, the slot number and eight pixel rows. Menu icons are hidden
+	// without it.
+	GlyphCommand []int `json:"glyph_command,omitempty"`
+
+	// CommandGap pauses this many ms after every command sent to the panel,
+	// for firmware that drops bytes while it refreshes the LCD
+	CommandGap *int `json:"command_gap_ms,omitempty"`
+	// CommandGaps replace CommandGap for "line", "backlight", "glyph" or
+	// "request" commands
+	CommandGaps map[string]int `json:"command_gaps_ms,omitempty"`
+
+	// AckByte is the byte (0-255) the panel answers every accepted command
+	// with. When set, each command waits for its answer before the next one
+	// is sent; leave it unset for panels that do not acknowledge commands.
+	AckByte *int `json:"ack_byte,omitempty"`
+	// NackByte is the byte the panel answers a rejected command with
+	NackByte *int `json:"nack_byte,omitempty"`
+	// AckTimeout is how long to wait for an answer, in ms (default 200)
+	AckTimeout int `json:"ack_timeout_ms,omitempty"`
+	// AckRetries is how often a rejected or unanswered command is sent
+	// again before the write fails (default 2)
+	AckRetries *int `json:"ack_retries,omitempty"`
+
+	// RestoreLEDs keeps the LED states in the state store and switches the
+	// LEDs back on at startup (default true)
+	RestoreLEDs *bool `json:"restore_leds,omitempty"`
+}
+
+// WatchConfig triggers a panel prompt, a hook command or both when a file
+// arrives in a directory
+type WatchConfig struct {
+	Path string `json:"path"`
+	// Pattern limits the watch to matching file names, e.g. "*.pdf"
+	Pattern string `json:"pattern,omitempty"`
+	// PollInterval is how often the directory is checked, in ms (default 2000)
+	PollInterval int `json:"poll_interval_ms,omitempty"`
+	// Prompt is shown on the panel with {file} replaced by the file name. With
+	// a Command it is a Yes/No question and the command only runs on Yes;
+	// without one it is acknowledged with OK.
+	Prompt string `json:"prompt,omitempty"`
+	// PromptTimeout drops an unanswered prompt as No, in seconds (default 60)
+	PromptTimeout int `json:"prompt_timeout_sec,omitempty"`
+	// Command runs via sh -c with WATCH_FILE, WATCH_NAME and WATCH_DIR set
+	Command string `json:"command,omitempty"`
+}
+
+// PrivilegesConfig names the user the service switches to after opening the
+// serial port and I/O ports. Commands then run as that user, except those
+// marked privileged, which a helper that stays root runs on its behalf.
+type PrivilegesConfig struct {
+	// User is a user name or numeric uid; empty keeps running as root
+	User string `json:"user,omitempty"`
+}
+
+// SensorConfig describes an I2C ambient sensor. Its reading can be added to
+// the status line as "sensor:<name>" and its thresholds raise panel alerts,
+// as rules over its metrics.
+type SensorConfig struct {
+	// Name identifies the sensor in status items and alerts
+	Name string `json:"name"`
+	// Type is "sht3x" or "bme280" (which also reads BMP280s)
+	Type string `json:"type"`
+	// Bus is the N of /dev/i2c-N
+	Bus int `json:"bus"`
+	// Address is the 7 bit device address; 0 uses the type's usual one
+	// (0x44 for sht3x, 0x76 for bme280)
+	Address uint16 `json:"address,omitempty"`
+	// PollInterval is how often the sensor is read, in seconds (default 30)
+	PollInterval int `json:"poll_interval_sec,omitempty"`
+	// Alerts are shorthands for rules over the sensor's metrics
+	Alerts []ThresholdConfig `json:"alerts,omitempty"`
+}
+
+// ThresholdConfig raises an alert while a metric ("temperature", "humidity"
+// or "pressure") is above or below a limit
+type ThresholdConfig struct {
+	Metric string   `json:"metric"`
+	Above  *float64 `json:"above,omitempty"`
+	Below  *float64 `json:"below,omitempty"`
+	// Hysteresis is how far back inside the limit the value must come before
+	// the alert clears
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+	// Critical raises a critical alert, which the status LED flashes fast
+	// while the display is unreachable
+	Critical bool `json:"critical,omitempty"`
+}
+
+// UinputConfig creates a virtual keyboard that sends KEY_ENTER, KEY_DOWN and
+// KEY_COPY for the ENTER, SELECT and copy buttons. The buttons keep working
+// on the panel as well.
+type UinputConfig struct {
+	Enabled bool `json:"enabled"`
+	// Name is the device name other programs see (default "QNAP front panel")
+	Name string `json:"name,omitempty"`
+}
+
+// BuzzerConfig selects how the NAS's buzzer is sounded. The PC speaker device
+// of the pcspkr driver is used unless a command is given.
+type BuzzerConfig struct {
+	// Device is the PC speaker input device (default
+	// /dev/input/by-path/platform-pcspkr-event-spkr)
+	Device string `json:"device,omitempty"`
+	// Command runs once per beep instead, for buzzers the kernel does not
+	// drive
+	Command string `json:"command,omitempty"`
+}
+
+// StorageConfig selects the volumes the storage browser lists. Shares are the
+// top level directories of a volume; their sizes are counted in the
+// background and cached.
+type StorageConfig struct {
+	// Volumes lists mount points to show; empty shows every disk backed or
+	// network filesystem
+	Volumes []string `json:"volumes,omitempty"`
+	// ShareCacheTTL is how long a counted share size is reused, in seconds
+	// (default 600)
+	ShareCacheTTL int `json:"share_cache_sec,omitempty"`
+	// ScrubPrivileged runs the zpool and btrfs scrub commands through the
+	// root helper when privileges are dropped
+	ScrubPrivileged bool `json:"scrub_privileged,omitempty"`
+	// EjectPrivileged unmounts removable devices ejected from the Devices
+	// menu through the root helper when privileges are dropped
+	EjectPrivileged bool `json:"eject_privileged,omitempty"`
+	// ScrubLEDs are the disk LEDs (1-6) that flash in turn while a scrub
+	// started from the menu runs (default 1-4)
+	ScrubLEDs []int `json:"scrub_leds,omitempty"`
+}
+
+// NetworkConfig configures blinking a port's LEDs to find it in the rack
+type NetworkConfig struct {
+	// IdentifySeconds is how long the LEDs blink (default 10)
+	IdentifySeconds int `json:"identify_sec,omitempty"`
+	// IdentifyPrivileged runs ethtool --identify through the root helper when
+	// privileges are dropped
+	IdentifyPrivileged bool `json:"identify_privileged,omitempty"`
+}
+
+// ScheduleConfig is a screen shown at the times of a cron expression, above
+// the menu and below copies, prompts and alerts, until its duration is over
+// or a button is pressed. It shows either a message or status items.
+type ScheduleConfig struct {
+	// Cron is "minute hour day-of-month month day-of-week", e.g.
+	// "0 18 * * fri", or @hourly, @daily, @weekly, @monthly or @yearly
+	Cron string `json:"cron"`
+	// Text is the message, lines separated by "\n"
+	Text string `json:"text,omitempty"`
+	// Items are status items, as in Display.StatusItems, shown one per line
+	// and a page at a time
+	Items []string `json:"items,omitempty"`
+	// Duration is how long the screen stays up, in seconds (default 60)
+	Duration int `json:"duration_sec,omitempty"`
+	// Interval is how long each page of items is shown, in seconds
+	// (default 5)
+	Interval int `json:"interval_sec,omitempty"`
+}
+
+// ClusterConfig makes the panel a dashboard of several nodes: the node
+// serves its health summary on Listen and polls the summaries of its Peers
+type ClusterConfig struct {
+	// Listen is the address the health API is served on, e.g. ":9180";
+	// empty serves nothing
+	Listen string `json:"listen,omitempty"`
+	// Token is a shared secret the API requires and the peers are sent;
+	// empty leaves the API open
+	Token string `json:"token,omitempty"`
+	// Peers are the nodes shown on the dashboard
+	Peers []PeerConfig `json:"peers,omitempty"`
+	// PollInterval is how often each peer is asked, in seconds (default 15)
+	PollInterval int `json:"poll_interval_sec,omitempty"`
+	// Interval is how long the dashboard shows each peer, in seconds
+	// (default 5)
+	Interval int `json:"interval_sec,omitempty"`
+}
+
+// ControlConfig configures the control socket
+type ControlConfig struct {
+	// Socket is the path of the unix socket ("" =
+	// /run/qnap-display/control.sock)
+	Socket string `json:"socket,omitempty"`
+	// Disabled serves no socket; other invocations then have to open the
+	// serial port themselves
+	Disabled bool `json:"disabled,omitempty"`
+	// Listen also serves the requests over TCP on this address, e.g.
+	// ":9170", for a management host; empty serves nothing
+	Listen string `json:"listen,omitempty"`
+	// Token is the shared secret requests over TCP have to carry; TCP is
+	// not served without one
+	Token string `json:"token,omitempty"`
+	// Allow lists the addresses and networks, e.g. "10.0.0.0/24", TCP
+	// clients may connect from; TCP is not served without any
+	Allow []string `json:"allow,omitempty"`
+	// RemoteCommands are the commands TCP clients may send (default
+	// "write"), e.g. "button" for a host forwarding its buttons
+	RemoteCommands []string `json:"remote_commands,omitempty"`
+	// ForwardButtons relays the panel's buttons to the service on another
+	// host, e.g. a headless backup target in another room
+	ForwardButtons ButtonForwardConfig `json:"forward_buttons,omitempty"`
+}
+
+// ButtonForwardConfig relays presses on this panel to the control over TCP
+// of another host's service, which handles them like its own
+type ButtonForwardConfig struct {
+	// Remote is the host, e.g. "backup" or "backup:9170"; empty forwards
+	// nothing
+	Remote string `json:"remote,omitempty"`
+	// Token is the token of the remote's control over TCP
+	Token string `json:"token,omitempty"`
+	// Buttons are the buttons forwarded, of "enter", "select" and "copy"
+	// (default enter and select)
+	Buttons []string `json:"buttons,omitempty"`
+	// Exclusive keeps the forwarded buttons from this panel's own menu,
+	// alerts and prompts
+	Exclusive bool `json:"exclusive,omitempty"`
+}
+
+// EventsConfig configures the event log. The events are served on the
+// health API's address (cluster.listen).
+type EventsConfig struct {
+	// Size is how many of the most recent events are kept (default 1000)
+	Size int `json:"size,omitempty"`
+}
+
+// WebhookConfig is a URL panel events are posted to as JSON
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Events are "copy_button", "copy_done", "copy_failed" and
+	// "long_press"; empty posts all of them
+	Events []string `json:"events,omitempty"`
+	// Headers are sent with every request, e.g. an Authorization header
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timeout bounds one request, in seconds (default 10)
+	Timeout int `json:"timeout_sec,omitempty"`
+	// Retries is how often a failed request is repeated (default 0)
+	Retries int `json:"retries,omitempty"`
+	// RetryDelay is the wait before a repeat, in seconds (default 5)
+	RetryDelay int `json:"retry_delay_sec,omitempty"`
+}
+
+// MQTTConfig connects the service to an MQTT broker
+type MQTTConfig struct {
+	// Broker is the broker's address, "tcp://host:1883" or
+	// "tls://host:8883"; empty connects to none
+	Broker   string `json:"broker,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// ClientID identifies the service to the broker (default
+	// "qnap-display-<hostname>")
+	ClientID string `json:"client_id,omitempty"`
+	// Topic is below which the panel's state and commands are (default
+	// "qnap-display/<hostname>")
+	Topic string `json:"topic,omitempty"`
+	// KeepAlive is how often the connection is checked, in seconds
+	// (default 60)
+	KeepAlive int `json:"keep_alive_sec,omitempty"`
+	// HomeAssistant publishes discovery payloads for the panel
+	HomeAssistant HomeAssistantConfig `json:"home_assistant,omitempty"`
+}
+
+// HomeAssistantConfig configures Home Assistant MQTT discovery
+type HomeAssistantConfig struct {
+	Enabled bool `json:"enabled"`
+	// DiscoveryPrefix is Home Assistant's discovery topic (default
+	// "homeassistant")
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+	// Name is the device's name in Home Assistant (default the hostname)
+	Name string `json:"name,omitempty"`
+}
+
+// GRPCConfig configures the gRPC API defined in api/display.proto
+type GRPCConfig struct {
+	// Listen is the address the API is served on, e.g. "127.0.0.1:9190";
+	// empty serves nothing
+	Listen string `json:"listen,omitempty"`
+	// Token is a shared secret calls must send as "authorization: Bearer
+	// <token>" metadata; empty leaves the API open, which is only allowed
+	// on a loopback address
+	Token string `json:"token,omitempty"`
+}
+
+// Open reports whether the API would be served without a token to other
+// hosts than this one
+func (g GRPCConfig) Open() bool {
+	if g.Listen == "" || g.Token != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(g.Listen)
+	if err != nil {
+		return true
+	}
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
+}
+
+// LCDprocConfig configures the LCDd compatible server, and the client that
+// draws on a remote LCDd instead of the panel
+type LCDprocConfig struct {
+	// Listen is the address lcdproc clients connect to, usually
+	// "127.0.0.1:13666"; empty serves nothing
+	Listen string `json:"listen,omitempty"`
+	// ResumeAfter is how many seconds the screens of lcdproc clients step
+	// aside for the menu after a button press no client reserved (default 30)
+	ResumeAfter int `json:"resume_after_sec,omitempty"`
+	// Server is the address of a remote LCDd, e.g. "192.168.1.20:13666",
+	// that shows the menu and status screens instead of the panel; empty
+	// draws on the panel
+	Server string `json:"server,omitempty"`
+}
+
+// NASAPIConfig reads pool health, alerts and update availability from the
+// API of the operating system running on the box instead of QTS
+type NASAPIConfig struct {
+	// System is "truenas" (SCALE) or "openmediavault"; empty reads nothing
+	System string `json:"system,omitempty"`
+	// URL is the address of the system's web interface, e.g.
+	// "https://localhost"
+	URL string `json:"url,omitempty"`
+	// APIKey authenticates with TrueNAS
+	APIKey string `json:"api_key,omitempty"`
+	// Username and Password log in to OpenMediaVault
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// InsecureTLS accepts the self-signed certificate the web interface
+	// usually has
+	InsecureTLS bool `json:"insecure_tls,omitempty"`
+	// PollInterval is how often the API is asked, in seconds (default 60)
+	PollInterval int `json:"poll_interval_sec,omitempty"`
+}
+
+// SMARTConfig selects the drives whose SMART attributes are sampled and kept
+// in the state file for a day
+type SMARTConfig struct {
+	// Drives are device names below /dev, e.g. "sda"; empty samples nothing
+	Drives []string `json:"drives,omitempty"`
+	// SampleInterval is how often the drives are read, in seconds (default
+	// 600)
+	SampleInterval int `json:"sample_interval_sec,omitempty"`
+	// Privileged runs smartctl through the root helper when privileges are
+	// dropped
+	Privileged bool `json:"privileged,omitempty"`
+}
+
+// RuleConfig raises an alert, switches LEDs or both while an expression over
+// the metrics holds, e.g.
+//
+//	disk_temperature{drive="sdc"} > 55 && temperature{sensor="rack"} > 35
+type RuleConfig struct {
+	// Name identifies the rule in the log and is the default alert text
+	Name string `json:"name"`
+	When string `json:"when"`
+	// Clear ends the rule, e.g. a few degrees below the limit of When;
+	// empty ends it as soon as When no longer holds
+	Clear string `json:"clear,omitempty"`
+	// Text is the alert text; "{value}" is replaced by the metric's value
+	Text     string `json:"text,omitempty"`
+	Critical bool   `json:"critical,omitempty"`
+	// LEDs are switched on while the rule holds, e.g. "disk3" or
+	// "status-red"
+	LEDs []string `json:"leds,omitempty"`
+	// Alert false only switches the LEDs (default true)
+	Alert *bool `json:"alert,omitempty"`
+	// Beep sounds the buzzer this many times when the rule is raised, up
+	// to MaxRuleBeeps
+	Beep int `json:"beep,omitempty"`
+}
+
+// MaxRuleBeeps is the most beeps a rule may sound
+const MaxRuleBeeps = 5
+
+// PowerOffConfig is the screen the panel shows once the service stopped at
+// shutdown
+type PowerOffConfig struct {
+	// Enabled has install-service also install the unit showing the screen
+	Enabled bool `json:"enabled,omitempty"`
+	// Text is the screen, one panel line per text line ("" = "Powered off"
+	// above "Hold ENTER: info")
+	Text string `json:"text,omitempty"`
+}
+
+// PeerConfig is a node shown on the cluster dashboard
+type PeerConfig struct {
+	Name string `json:"name"`
+	// URL is the base address of the peer's API, e.g. "http://nas2:9180"
+	URL string `json:"url"`
 }
 
 // LoggingConfig contains logging settings
@@ -45,24 +792,76 @@ type LoggingConfig struct {
 	MaxSize  int    `json:"max_size_mb"`
 	MaxAge   int    `json:"max_age_days"`
 	Compress bool   `json:"compress"`
+	// MirrorDisplay logs every frame sent to the panel at debug level
+	MirrorDisplay bool `json:"mirror_display,omitempty"`
+	// DisplayLog also appends the mirrored frames to this file ("" = the
+	// log only)
+	DisplayLog string `json:"display_log,omitempty"`
+	// Backend is where the service logs: LogBackendText or
+	// LogBackendJournald (default text)
+	Backend string `json:"backend,omitempty"`
+	// Format of the lines written to stderr: "text" or "json" for log
+	// collectors (default text)
+	Format string `json:"format,omitempty"`
 }
 
+// Logging backends
+const (
+	// LogBackendText writes text lines to stderr
+	LogBackendText = "text"
+	// LogBackendJournald sends entries with their fields to journald
+	LogBackendJournald = "journald"
+)
+
 // MenuConfig contains menu system configuration
 type MenuConfig struct {
 	Enabled     bool       `json:"enabled"`
 	MainMenu    MenuItem   `json:"main_menu"`
 	ButtonDelay int        `json:"button_delay_ms"`
+	// Shortcuts bind button gestures at the root menu to menu items
+	Shortcuts   []ShortcutConfig `json:"shortcuts,omitempty"`
+	// ReadOnly starts the menu in read-only mode, where it can be browsed but
+	// command and display_command items show "Disabled" instead of running
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// ShortcutConfig binds a gesture such as "triple_select" or "long_enter" to a
+// menu item or a recorded button macro. Target is the slash separated path of
+// item keys from the main menu, e.g. "storage" or "network/ip"; Macro is the
+// name of a macro and takes precedence.
+type ShortcutConfig struct {
+	Gesture string `json:"gesture"`
+	Target  string `json:"target,omitempty"`
+	Macro   string `json:"macro,omitempty"`
 }
 
 // MenuItem represents a single menu item
 type MenuItem struct {
 	Title       string            `json:"title"`
 	Description string            `json:"description"`
-	Type        string            `json:"type"` // "submenu", "command", "display_command", or "back"
+	Type        string            `json:"type"` // "submenu", "command", "display_command", "file", or "back"
 	Command     string            `json:"command,omitempty"`
+	OutputMode  string            `json:"output_mode,omitempty"` // "scroll" (default) or "paged"
+	Confirm     string            `json:"confirm,omitempty"`     // question asked before running a command
+	Icon        string            `json:"icon,omitempty"`        // "gear", "disk", "network", "power" or "wrench"
+	// Input is the label of a text read with the character picker before the
+	// command runs; the command gets the text in $INPUT
+	Input        string `json:"input,omitempty"`
+	InputCharset string `json:"input_charset,omitempty"` // "name" (default), "digits" or "text"
+	// Privileged runs the command through the root helper when privileges
+	// are dropped; other commands run as the unprivileged user
+	Privileged bool `json:"privileged,omitempty"`
+	// Path is the text file a "file" item shows, read again on every visit
+	Path        string              `json:"path,omitempty"`
 	Items       map[string]MenuItem `json:"items,omitempty"`
 }
 
+// Output modes for command results
+const (
+	OutputModeScroll = "scroll"
+	OutputModePaged  = "paged"
+)
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
@@ -76,6 +875,7 @@ func DefaultConfig() *Config {
 			PollInterval: 50,
 			Enabled:     true,
 			Command:     "TIMESTAMP=$(date +%Y%m%d%H%M%S) && mkdir -p /mnt/pool/Multimedia/usb-copy$TIMESTAMP && cp -r /media/usb/* /mnt/pool/Multimedia/usb-copy$TIMESTAMP/ && sync && sleep 10",
+			Source:      "/media/usb",
 		},
 		Display: DisplayConfig{
 			Width:        16,
@@ -83,6 +883,16 @@ func DefaultConfig() *Config {
 			BacklightPin: -1,
 			Contrast:     128,
 			DefaultText:  "QNAP Ready",
+			Splash: SplashConfig{
+				Text:     "QNAP Starting\nPlease wait...",
+				Duration: 2,
+			},
+			ProgressUpdatesPerSec: 2,
+			RedrawInterval: 60,
+			IdleTimeout:  300,
+		},
+		Hardware: HardwareConfig{
+			Profile: "generic",
 		},
 		Logging: LoggingConfig{
 			Level:    "info",
@@ -102,12 +912,14 @@ func DefaultConfig() *Config {
 					"system": {
 						Title:       "System Info",
 						Description: "Show system information",
+						Icon:        "gear",
 						Type:        "command",
 						Command:     "uname -a",
 					},
 					"network": {
 						Title:       "Network",
 						Description: "Network configuration",
+						Icon:        "network",
 						Type:        "submenu",
 						Items: map[string]MenuItem{
 							"ip": {
@@ -122,6 +934,12 @@ func DefaultConfig() *Config {
 								Type:        "command",
 								Command:     "ping -c 1 8.8.8.8",
 							},
+							"ports": {
+								Title:       "Ports",
+								Description: "Port link, ENTER blinks the LEDs",
+								Type:        "display_command",
+								Command:     "network_ports",
+							},
 							"back": {
 								Title:       "← Back",
 								Description: "Return to main menu",
@@ -147,6 +965,50 @@ func DefaultConfig() *Config {
 								Type:        "display_command",
 								Command:     "backlight_off",
 							},
+							"about": {
+								Title:       "About",
+								Description: "Version and commit of this build",
+								Type:        "display_command",
+								Command:     "about",
+							},
+							"usage": {
+								Title:       "Usage Stats",
+								Description: "Button presses and idle timeout",
+								Type:        "display_command",
+								Command:     "usage_stats",
+							},
+							"timer": {
+								Title:       "Timer",
+								Description: "Stopwatch and countdown",
+								Type:        "display_command",
+								Command:     "timer",
+							},
+							"back": {
+								Title:       "← Back",
+								Description: "Return to main menu",
+								Type:        "back",
+								Command:     "",
+							},
+						},
+					},
+					"cpu": {
+						Title:       "CPU",
+						Description: "CPU frequency and governor",
+						Type:        "submenu",
+						Items: map[string]MenuItem{
+							"status": {
+								Title:       "CPU Status",
+								Description: "Show frequency, governor and throttling",
+								Type:        "display_command",
+								Command:     "cpu_status",
+							},
+							"governor": {
+								Title:       "Toggle Governor",
+								Description: "Switch between powersave and performance",
+								Type:        "display_command",
+								Command:     "cpu_governor_toggle",
+								Confirm:     "Switch governor?",
+							},
 							"back": {
 								Title:       "← Back",
 								Description: "Return to main menu",
@@ -158,14 +1020,25 @@ func DefaultConfig() *Config {
 					"storage": {
 						Title:       "Storage",
 						Description: "Storage information",
-						Type:        "command",
-						Command:     "df -h",
+						Icon:        "disk",
+						Type:        "display_command",
+						Command:     "storage_browser",
+					},
+					"removable": {
+						Title:       "Devices",
+						Description: "Eject removable devices",
+						Icon:        "disk",
+						Type:        "display_command",
+						Command:     "devices",
 					},
 					"reboot": {
 						Title:       "Reboot",
 						Description: "Restart system",
+						Icon:        "power",
 						Type:        "command",
 						Command:     "systemctl reboot",
+						Confirm:     "Reboot now?",
+						Privileged:  true,
 					},
 				},
 			},
@@ -173,7 +1046,8 @@ func DefaultConfig() *Config {
 	}
 }
 
-// LoadConfig loads configuration from a JSON file
+// LoadConfig loads configuration from a JSON file. Lines may end in //
+// comments, as in the file Generate writes.
 func LoadConfig(filename string) (*Config, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -181,13 +1055,41 @@ func LoadConfig(filename string) (*Config, error) {
 	}
 
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := json.Unmarshal(stripComments(data), &config); err != nil {
 		return nil, err
 	}
 
 	return &config, nil
 }
 
+// stripComments blanks out // comments outside strings, keeping the offsets
+// so errors still point at the right line
+func stripComments(data []byte) []byte {
+	if !bytes.Contains(data, []byte("//")) {
+		return data
+	}
+	data = bytes.Clone(data)
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case inString && escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case inString && c == '"':
+			inString = false
+		case inString:
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for ; i < len(data) && data[i] != '\n'; i++ {
+				data[i] = ' '
+			}
+		}
+	}
+	return data
+}
+
 // SaveConfig saves configuration to a JSON file
 func (c *Config) SaveConfig(filename string) error {
 	data, err := json.MarshalIndent(c, "", "  ")
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// source is config.go, whose doc comments Generate writes above the keys, so
+// they cannot drift from the settings they describe
+//
+//go:embed config.go
+var source string
+
+var (
+	docsOnce sync.Once
+	docs     map[string]string
+)
+
+// typeDocs returns the doc comments of the config types by name, and of
+// their fields by "Type.Field"
+func typeDocs() map[string]string {
+	docsOnce.Do(func() {
+		docs = make(map[string]string)
+		file, err := parser.ParseFile(token.NewFileSet(), "config.go", source, parser.ParseComments)
+		if err != nil {
+			// It compiled, so it parses
+			panic(err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil {
+					doc = gen.Doc
+				}
+				docs[typeSpec.Name.Name] = doc.Text()
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range structType.Fields.List {
+					doc := field.Doc.Text()
+					if doc == "" {
+						doc = field.Comment.Text()
+					}
+					for _, name := range field.Names {
+						docs[typeSpec.Name.Name+"."+name.Name] = doc
+					}
+				}
+			}
+		}
+	})
+	return docs
+}
+
+// Generate writes cfg as a config file with the doc comment of every setting
+// above its key, as // comments LoadConfig skips. Lists left empty and
+// sections left unset show what an entry looks like, commented out.
+func Generate(w io.Writer, cfg *Config) error {
+	g := &generator{docs: typeDocs()}
+	g.buf.WriteString("// Configuration of qnap-display-control. Lines starting with // are comments.\n")
+	g.value(reflect.ValueOf(cfg).Elem(), "", false)
+	g.buf.WriteString("\n")
+	_, err := w.Write(g.buf.Bytes())
+	return err
+}
+
+// generator renders a config value as commented JSON
+type generator struct {
+	buf  bytes.Buffer
+	docs map[string]string
+	// example is set while rendering an entry that is commented out, whose
+	// own empty lists and sections are not expanded again
+	example bool
+}
+
+// value writes v, its first line continuing the current one and the others
+// indented by indent. Within an entry of a list or map, such as a menu item,
+// the keys left out when empty are left out.
+func (g *generator) value(v reflect.Value, indent string, entry bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		g.object(v, indent, entry)
+	case reflect.Ptr:
+		if v.IsNil() {
+			g.buf.WriteString("null")
+			return
+		}
+		g.value(v.Elem(), indent, entry)
+	case reflect.Slice:
+		elem := v.Type().Elem()
+		switch {
+		case v.Len() == 0 && elem.Kind() == reflect.Struct && !g.example:
+			g.buf.WriteString("[\n")
+			g.comment(g.render(elem), indent+"  ")
+			g.buf.WriteString(indent + "]")
+		case v.Len() == 0:
+			g.buf.WriteString("[]")
+		case elem.Kind() != reflect.Struct:
+			g.scalar(v)
+		default:
+			g.buf.WriteString("[\n")
+			for i := 0; i < v.Len(); i++ {
+				g.buf.WriteString(indent + "  ")
+				g.value(v.Index(i), indent+"  ", true)
+				g.separator(i, v.Len())
+			}
+			g.buf.WriteString(indent + "]")
+		}
+	case reflect.Map:
+		switch {
+		case v.Len() == 0:
+			g.buf.WriteString("{}")
+			return
+		case v.Type().Elem().Kind() != reflect.Struct:
+			g.scalar(v)
+			return
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		g.buf.WriteString("{\n")
+		for i, key := range keys {
+			g.buf.WriteString(indent + "  ")
+			g.scalar(reflect.ValueOf(key))
+			g.buf.WriteString(": ")
+			g.value(v.MapIndex(reflect.ValueOf(key)), indent+"  ", true)
+			g.separator(i, len(keys))
+		}
+		g.buf.WriteString(indent + "}")
+	default:
+		g.scalar(v)
+	}
+}
+
+// object writes the fields of a struct, each below its doc comment
+func (g *generator) object(v reflect.Value, indent string, entry bool) {
+	t := v.Type()
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if jsonName(field) == "" || entry && strings.Contains(field.Tag.Get("json"), ",omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+		fields = append(fields, i)
+	}
+
+	g.buf.WriteString("{\n")
+	for n, i := range fields {
+		field := t.Field(i)
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		doc := g.docs[t.Name()+"."+field.Name]
+		if doc == "" && fieldType.Kind() == reflect.Struct {
+			doc = g.docs[fieldType.Name()]
+		}
+		g.comment(doc, indent+"  ")
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Ptr && value.IsNil() && fieldType.Kind() == reflect.Struct && !g.example {
+			g.comment("e.g.\n"+g.render(fieldType), indent+"  ")
+		}
+		g.buf.WriteString(indent + "  ")
+		g.scalar(reflect.ValueOf(jsonName(field)))
+		g.buf.WriteString(": ")
+		g.value(value, indent+"  ", entry)
+		g.separator(n, len(fields))
+	}
+	g.buf.WriteString(indent + "}")
+}
+
+// render returns an entry of type t with every key at its zero value
+func (g *generator) render(t reflect.Type) string {
+	example := &generator{docs: g.docs, example: true}
+	example.value(reflect.New(t).Elem(), "", false)
+	return example.buf.String()
+}
+
+// comment writes text as // comment lines
+func (g *generator) comment(text string, indent string) {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		g.buf.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n")
+	}
+}
+
+// scalar writes v as JSON on one line
+func (g *generator) scalar(v reflect.Value) {
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	// Commands keep their && and redirections readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v.Interface()); err != nil {
+		// Config values are plain JSON types
+		panic(err)
+	}
+	g.buf.Write(bytes.TrimSuffix(line.Bytes(), []byte("\n")))
+}
+
+// separator ends entry i of n, with a comma unless it is the last
+func (g *generator) separator(i, n int) {
+	if i < n-1 {
+		g.buf.WriteString(",")
+	}
+	g.buf.WriteString("\n")
+}
@@ -0,0 +1,380 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/qnap/display-control/pkg/buttons"
+)
+
+// Problem is something wrong with a config file, at the JSON path of the key
+// it concerns, e.g. "usb_copy.profiles[1].direction"
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// baudRates are the rates the serial port can be opened at
+var baudRates = []int{50, 75, 110, 134, 150, 200, 300, 600, 1200, 1800, 2400, 4800, 9600, 19200, 38400, 57600,
+	115200, 230400, 460800, 500000, 576000, 921600, 1000000, 1152000, 1500000, 2000000, 2500000, 3000000,
+	3500000, 4000000}
+
+// glyphNames are the panel glyphs a transliteration can name
+var glyphNames = []string{"gear", "disk", "network", "power", "wrench", "bar1", "bar2", "bar3"}
+
+// slotNames are the custom character slots a transliteration can name
+var slotNames = []string{"slot0", "slot1", "slot2", "slot3", "slot4", "slot5", "slot6", "slot7"}
+
+// Menu item types
+var menuTypes = []string{"submenu", "command", "display_command", "file", "back"}
+
+// Validate checks the contents of a config file as LoadConfig reads them. It
+// reports keys the service does not know, which it would ignore, values of
+// the wrong type and settings it would reject, such as an unknown menu item
+// type or baud rate. It fails only if the file is no JSON at all.
+func Validate(data []byte) ([]Problem, error) {
+	data = stripComments(data)
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, syntaxError(data, err)
+	}
+
+	problems := unknownKeys(document, reflect.TypeOf(Config{}), "")
+	// Values of the wrong type are skipped, so the rest is still checked
+	var cfg Config
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(data, &cfg); errors.As(err, &typeErr) {
+		problems = append(problems, Problem{typeErr.Field, fmt.Sprintf("expected %s, not %s", typeErr.Type, typeErr.Value)})
+	} else if err != nil {
+		problems = append(problems, Problem{"", err.Error()})
+	}
+	return append(problems, cfg.check()...), nil
+}
+
+// syntaxError turns a JSON syntax error into one naming its line
+func syntaxError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %w", line, err)
+}
+
+// unknownKeys returns the keys below value that t, the type it is decoded
+// into, has no field for
+func unknownKeys(value any, t reflect.Type, path string) []Problem {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var problems []Problem
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := fields[key]
+			if !ok {
+				problems = append(problems, Problem{join(path, key), "unknown key"})
+				continue
+			}
+			problems = append(problems, unknownKeys(object[key], field.Type, join(path, key))...)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for key, element := range object {
+			problems = append(problems, unknownKeys(element, t.Elem(), join(path, key))...)
+		}
+		sort.Slice(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+	case reflect.Slice:
+		array, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		for i, element := range array {
+			problems = append(problems, unknownKeys(element, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return problems
+}
+
+// jsonFields returns the fields of a struct by their JSON key
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := jsonName(field); name != "" {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// jsonName returns the key of a struct field, or "" if JSON leaves it out
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// join appends a key to a JSON path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// check reports settings the service would reject
+func (c *Config) check() []Problem {
+	var problems []Problem
+	if !slices.Contains(baudRates, c.SerialPort.BaudRate) {
+		problems = append(problems, Problem{"serial_port.baud_rate",
+			fmt.Sprintf("unsupported baud rate %d, use e.g. 1200, 9600 or 115200", c.SerialPort.BaudRate)})
+	}
+
+	for i, profile := range c.USBCopy.Profiles {
+		path := fmt.Sprintf("usb_copy.profiles[%d]", i)
+		if !oneOf(profile.Direction, CopyImport, CopyExport) {
+			problems = append(problems, Problem{path + ".direction", fmt.Sprintf("unknown direction %q, use import or export", profile.Direction)})
+		}
+		if profile.Direction == CopyExport && profile.Source == "" {
+			problems = append(problems, Problem{path + ".source", "an export needs a source"})
+		}
+		if profile.Scan != nil && !oneOf(profile.Scan.Stage, ScanBefore, ScanAfter) {
+			problems = append(problems, Problem{path + ".scan.stage", fmt.Sprintf("unknown stage %q, use before or after", profile.Scan.Stage)})
+		}
+	}
+
+	if !oneOf(c.Logging.Backend, LogBackendText, LogBackendJournald) {
+		problems = append(problems, Problem{"logging.backend", fmt.Sprintf("unknown backend %q, use text or journald", c.Logging.Backend)})
+	}
+	if !oneOf(c.Logging.Format, "text", "json") {
+		problems = append(problems, Problem{"logging.format", fmt.Sprintf("unknown format %q, use text or json", c.Logging.Format)})
+	}
+
+	if c.Control.Listen != "" && c.Control.Token == "" {
+		problems = append(problems, Problem{"control.token", "control over TCP needs a token"})
+	}
+	if c.Control.Listen != "" && len(c.Control.Allow) == 0 {
+		problems = append(problems, Problem{"control.allow", "control over TCP needs the hosts allowed to connect"})
+	}
+	problems = append(problems, c.Control.ForwardButtons.check("control.forward_buttons")...)
+	if c.GRPC.Open() {
+		problems = append(problems, Problem{"grpc.token", "a gRPC API other hosts can reach needs a token"})
+	}
+
+	for i, rule := range c.Rules {
+		if rule.Beep < 0 || rule.Beep > MaxRuleBeeps {
+			problems = append(problems, Problem{fmt.Sprintf("rules[%d].beep", i), fmt.Sprintf("%d beeps, use 0 to %d", rule.Beep, MaxRuleBeeps)})
+		}
+	}
+
+	if c.Display.Driver == "hd44780" {
+		problems = append(problems, c.Display.GPIO.check("display.gpio")...)
+	}
+
+	problems = append(problems, c.Display.checkIdleSchedule()...)
+	problems = append(problems, c.Display.Splash.check("display.splash")...)
+
+	glyph := func(name string) (string, bool) {
+		return name, slices.Contains(glyphNames, name) || slices.Contains(slotNames, name)
+	}
+	if _, err := translit.New(c.Display.Transliteration.Options(glyph)); err != nil {
+		problems = append(problems, Problem{"display.transliteration", err.Error()})
+	}
+
+	return append(problems, c.Menu.MainMenu.check("menu.main_menu", true)...)
+}
+
+// checkIdleSchedule reports rotations without items and idle windows that
+// cannot be placed or name no rotation
+func (d DisplayConfig) checkIdleSchedule() []Problem {
+	var problems []Problem
+	names := make([]string, 0, len(d.Rotations))
+	for name := range d.Rotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := "display.rotations." + name
+		if len(d.Rotations[name]) == 0 {
+			problems = append(problems, Problem{path, "a rotation needs items"})
+		}
+		for i, item := range d.Rotations[name] {
+			if item.Item == "" {
+				problems = append(problems, Problem{fmt.Sprintf("%s[%d].item", path, i), "missing"})
+			}
+			if item.Dwell < 0 {
+				problems = append(problems, Problem{fmt.Sprintf("%s[%d].dwell_sec", path, i), "must not be negative"})
+			}
+		}
+	}
+
+	for i, window := range d.IdleSchedule {
+		path := fmt.Sprintf("display.idle_schedule[%d]", i)
+		if _, err := timeOfDay(window.From); err != nil {
+			problems = append(problems, Problem{path + ".from", err.Error()})
+		}
+		if _, err := timeOfDay(window.To); err != nil {
+			problems = append(problems, Problem{path + ".to", err.Error()})
+		}
+		switch _, exists := d.Rotations[window.Rotation]; {
+		case window.Rotation == "" && window.Animation == "":
+			problems = append(problems, Problem{path, "a window needs a rotation or an animation"})
+		case window.Rotation != "" && window.Animation != "":
+			problems = append(problems, Problem{path, "a window shows either a rotation or an animation"})
+		case window.Rotation != "" && !exists:
+			problems = append(problems, Problem{path + ".rotation", fmt.Sprintf("unknown rotation %q", window.Rotation)})
+		}
+	}
+	return problems
+}
+
+// Limits of the splash logo, two rows of four custom characters
+const (
+	logoMaxRows  = 16
+	logoMaxWidth = 20
+)
+
+// check reports a negative duration and a logo the panel cannot draw
+func (s SplashConfig) check(path string) []Problem {
+	var problems []Problem
+	if s.Duration < 0 {
+		problems = append(problems, Problem{join(path, "duration_sec"), "must not be negative"})
+	}
+	if len(s.Logo) > logoMaxRows {
+		problems = append(problems, Problem{join(path, "logo"), fmt.Sprintf("%d rows, at most %d fit", len(s.Logo), logoMaxRows)})
+	}
+	for i, pixels := range s.Logo {
+		rowPath := fmt.Sprintf("%s.logo[%d]", path, i)
+		if len(pixels) > logoMaxWidth {
+			problems = append(problems, Problem{rowPath, fmt.Sprintf("%d pixels wide, at most %d fit", len(pixels), logoMaxWidth)})
+		}
+		if strings.Trim(pixels, "#. ") != "" {
+			problems = append(problems, Problem{rowPath, `use "#" for lit and "." for dark pixels`})
+		}
+	}
+	return problems
+}
+
+// check reports a remote without its token and buttons the panel does not
+// have
+func (f ButtonForwardConfig) check(path string) []Problem {
+	var problems []Problem
+	if f.Remote != "" && f.Token == "" {
+		problems = append(problems, Problem{join(path, "token"), "forwarding needs the remote's token"})
+	}
+	for i, name := range f.Buttons {
+		if _, err := buttons.Parse(name); err != nil {
+			problems = append(problems, Problem{fmt.Sprintf("%s.buttons[%d]", path, i), err.Error()})
+		}
+	}
+	return problems
+}
+
+// check reports lines wired to more than one pin or not on the chip
+func (g GPIODisplayConfig) check(path string) []Problem {
+	var problems []Problem
+	pins := map[int]string{}
+	wire := func(pin string, line int) {
+		switch other, taken := pins[line]; {
+		case line < 0:
+			problems = append(problems, Problem{join(path, pin), fmt.Sprintf("invalid line %d", line)})
+		case taken:
+			problems = append(problems, Problem{join(path, pin), fmt.Sprintf("line %d is already %s", line, other)})
+		default:
+			pins[line] = pin
+		}
+	}
+	wire("rs", g.RS)
+	wire("e", g.E)
+	for i, line := range g.Data {
+		wire(fmt.Sprintf("data[%d]", i), line)
+	}
+	if g.Backlight != nil {
+		wire("backlight", *g.Backlight)
+	}
+	if g.Enter != nil {
+		wire("enter", *g.Enter)
+	}
+	if g.Select != nil {
+		wire("select", *g.Select)
+	}
+	return problems
+}
+
+// check reports menu items the menu cannot show or run, below and including
+// this one. The main menu is a submenu whatever its type.
+func (m MenuItem) check(path string, root bool) []Problem {
+	var problems []Problem
+	switch {
+	case root:
+	case m.Type == "":
+		problems = append(problems, Problem{path + ".type", "missing, use one of " + strings.Join(menuTypes, ", ")})
+	case !oneOf(m.Type, menuTypes...):
+		problems = append(problems, Problem{path + ".type", fmt.Sprintf("unknown type %q, use one of %s", m.Type, strings.Join(menuTypes, ", "))})
+	case (m.Type == "command" || m.Type == "display_command") && m.Command == "":
+		problems = append(problems, Problem{path + ".command", "a " + m.Type + " item needs a command"})
+	case m.Type == "file" && m.Path == "":
+		problems = append(problems, Problem{path + ".path", "a file item needs a path"})
+	}
+	if !oneOf(m.OutputMode, OutputModeScroll, OutputModePaged) {
+		problems = append(problems, Problem{path + ".output_mode", fmt.Sprintf("unknown output mode %q, use scroll or paged", m.OutputMode)})
+	}
+	if !oneOf(m.InputCharset, "name", "digits", "text") {
+		problems = append(problems, Problem{path + ".input_charset", fmt.Sprintf("unknown character set %q, use name, digits or text", m.InputCharset)})
+	}
+	if !oneOf(m.Icon, "gear", "disk", "network", "power", "wrench") {
+		problems = append(problems, Problem{path + ".icon", fmt.Sprintf("unknown icon %q, use gear, disk, network, power or wrench", m.Icon)})
+	}
+
+	keys := make([]string, 0, len(m.Items))
+	for key := range m.Items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		problems = append(problems, m.Items[key].check(path+".items."+key, false)...)
+	}
+	return problems
+}
+
+// oneOf reports whether value is empty, the default, or one of allowed
+func oneOf(value string, allowed ...string) bool {
+	return value == "" || slices.Contains(allowed, value)
+}
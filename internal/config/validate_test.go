@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problemStrings renders problems for comparison
+func problemStrings(problems []Problem) []string {
+	lines := make([]string, len(problems))
+	for i, problem := range problems {
+		lines[i] = problem.String()
+	}
+	return lines
+}
+
+func TestValidate(t *testing.T) {
+	data := []byte(`{
+  "serial_port": {"device": "/dev/ttyS1", "baud_rate": 1201},
+  "usb_copy": {
+    "profiles": [
+      {"name": "Import", "destinaton": "/share/Photos"},
+      {"name": "Export", "direction": "export"}
+    ]
+  },
+  "display": {"width": "16"},
+  "menu": {
+    "main_menu": {
+      "title": "Main Menu",
+      "type": "submenu",
+      "items": {
+        "ip": {"title": "IP", "type": "comand", "command": "hostname -I"},
+        "log": {"title": "Log", "type": "file"},
+        "down": {"title": "Shutdown", "type": "command", "command": "poweroff", "icon": "power", "colour": "red"}
+      }
+    }
+  },
+  "verbose": true
+}`)
+
+	problems, err := Validate(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"menu.main_menu.items.down.colour: unknown key",
+		"usb_copy.profiles[0].destinaton: unknown key",
+		"verbose: unknown key",
+		"display.width: expected int, not string",
+		"serial_port.baud_rate: unsupported baud rate 1201, use e.g. 1200, 9600 or 115200",
+		"usb_copy.profiles[1].source: an export needs a source",
+		`menu.main_menu.items.ip.type: unknown type "comand", use one of submenu, command, display_command, file, back`,
+		"menu.main_menu.items.log.path: a file item needs a path",
+	}, problemStrings(problems))
+}
+
+func TestValidate_Transliteration(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"transliteration": {"characters": {"°": "{degree}"}}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`display.transliteration: character °: unknown glyph "degree"`}, problemStrings(problems))
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"transliteration": {"classes": {"arrows": "{bar3}", "emoji": "*"}}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"transliteration": {"characters": {"°": "{slot6}"}, "language": "de"}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"transliteration": {"characters": {"°": "{slot8}"}, "language": "fr"}}}`))
+	require.NoError(t, err)
+	assert.Len(t, problems, 1, "the first invalid setting is reported")
+}
+
+func TestValidate_GPIO(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"driver": "hd44780",
+		"gpio": {"rs": 25, "e": 24, "data": [23, 17, 18, 22], "enter": 17, "select": -1}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"display.gpio.enter: line 17 is already data[1]",
+		"display.gpio.select: invalid line -1",
+	}, problemStrings(problems))
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"driver": "hd44780",
+		"gpio": {"rs": 25, "e": 24, "data": [23, 17, 18, 22], "enter": 5, "select": 6}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestValidate_IdleSchedule(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {
+		"rotations": {"night": [{"item": "time", "dwell_sec": -1}], "empty": []},
+		"idle_schedule": [
+			{"from": "22:00", "to": "7:00", "rotation": "night"},
+			{"from": "25:00", "to": "08:00", "rotation": "day"},
+			{"from": "08:00", "to": "09:00"}
+		]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"display.rotations.empty: a rotation needs items",
+		"display.rotations.night[0].dwell_sec: must not be negative",
+		`display.idle_schedule[1].from: invalid time "25:00", use HH:MM`,
+		`display.idle_schedule[1].rotation: unknown rotation "day"`,
+		"display.idle_schedule[2]: a window needs a rotation or an animation",
+	}, problemStrings(problems))
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {
+		"rotations": {"night": [{"item": "time", "dwell_sec": 30}, {"item": "nas:alerts"}]},
+		"idle_schedule": [{"from": "22:00", "to": "07:00", "rotation": "night"},
+			{"from": "12:00", "to": "13:00", "animation": "snake"}]}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestValidate_Splash(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"splash": {
+		"duration_sec": -1, "logo": ["#.#.#.#.#.#.#.#.#.#.#", "#x#"]}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"display.splash.duration_sec: must not be negative",
+		"display.splash.logo[0]: 21 pixels wide, at most 20 fit",
+		`display.splash.logo[1]: use "#" for lit and "." for dark pixels`,
+	}, problemStrings(problems))
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "display": {"splash": {
+		"text": "Backup NAS\nStarting", "duration_sec": 5, "logo": [".##.", "#..#", "#..#", ".##."]}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestValidate_ForwardButtons(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "control": {"forward_buttons": {
+		"remote": "backup:9170", "buttons": ["enter", "menu"]}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"control.forward_buttons.token: forwarding needs the remote's token",
+		`control.forward_buttons.buttons[1]: unknown button "menu" (available: enter, select, copy)`,
+	}, problemStrings(problems))
+}
+
+func TestValidate_ControlTCP(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "control": {"listen": ":9170"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"control.token: control over TCP needs a token",
+		"control.allow: control over TCP needs the hosts allowed to connect",
+	}, problemStrings(problems))
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "control": {"listen": ":9170",
+		"token": "change-me", "allow": ["10.0.0.0/24"], "remote_commands": ["write", "button"]}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestValidate_GRPCToken(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "grpc": {"listen": ":9190"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"grpc.token: a gRPC API other hosts can reach needs a token"}, problemStrings(problems))
+
+	for _, listen := range []string{"127.0.0.1:9190", "[::1]:9190", "localhost:9190"} {
+		problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "grpc": {"listen": "` + listen + `"}}`))
+		require.NoError(t, err)
+		assert.Empty(t, problems, "%s needs no token", listen)
+	}
+
+	problems, err = Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "grpc": {"listen": "0.0.0.0:9190", "token": "change-me"}}`))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestValidate_RuleBeeps(t *testing.T) {
+	problems, err := Validate([]byte(`{"serial_port": {"baud_rate": 1200}, "rules": [
+		{"name": "Hot", "when": "load1 > 4", "beep": 2},
+		{"name": "Wet", "when": "humidity > 95", "beep": 10},
+		{"name": "Dry", "when": "humidity < 20", "beep": -1}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"rules[1].beep: 10 beeps, use 0 to 5",
+		"rules[2].beep: -1 beeps, use 0 to 5",
+	}, problemStrings(problems))
+}
+
+func TestValidate_Syntax(t *testing.T) {
+	_, err := Validate([]byte("{\n  \"serial_port\": {\n    \"device\": \"/dev/ttyS1\",\n  }\n}"))
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "line 4: "), err.Error())
+}
+
+func TestGenerate(t *testing.T) {
+	var generated bytes.Buffer
+	require.NoError(t, Generate(&generated, DefaultConfig()))
+	assert.Contains(t, generated.String(), "  // SerialPortConfig contains serial port settings\n  \"serial_port\": {\n")
+	// The commands keep their && readable
+	assert.Contains(t, generated.String(), "&& sync")
+
+	problems, err := Validate(generated.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, problemStrings(problems))
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, generated.Bytes(), 0o644))
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	defaults := DefaultConfig()
+	assert.Equal(t, defaults.SerialPort, cfg.SerialPort)
+	assert.Equal(t, defaults.USBCopy.Command, cfg.USBCopy.Command)
+	assert.Equal(t, defaults.Menu.MainMenu, cfg.Menu.MainMenu)
+	assert.Empty(t, cfg.Menu.Shortcuts, "shortcuts are only a commented example")
+	assert.Nil(t, cfg.USBCopy.Scan)
+}
+
+func TestStripComments(t *testing.T) {
+	data := []byte(`{"url": "http://nas2:9180", // the peer
+  "path": "a\"//b" // escaped quote
+}`)
+	stripped := stripComments(data)
+	assert.Len(t, stripped, len(data), "offsets are kept")
+	lines := strings.Split(string(stripped), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	assert.Equal(t, []string{`{"url": "http://nas2:9180",`, `  "path": "a\"//b"`, `}`}, lines)
+}
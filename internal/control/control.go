@@ -0,0 +1,365 @@
+// Package control is the running service's control socket. The service
+// holds the serial port, so other invocations of the program, such as
+// "qnap-display-control write", send their requests over a unix domain
+// socket instead of opening the port a second time.
+//
+// The protocol is line based: each request is a JSON object on a line of
+// its own, answered by one JSON object on a line. A connection may carry
+// any number of requests, answered in turn.
+//
+// Some requests may also be served over TCP with ListenTCP, so a management
+// host can write to the panels on the LAN. Requests over TCP prove they
+// know a shared token, are only taken from the allowed networks and are
+// limited to the commands listed for TCP.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSocket is where the service listens unless configured otherwise
+const DefaultSocket = "/run/qnap-display/control.sock"
+
+// socketMode limits the socket to root and the service's group
+const socketMode = 0660
+
+// maxRequestSize bounds a request line so a client cannot make the service
+// buffer arbitrary amounts of data
+const maxRequestSize = 64 * 1024
+
+// idleTimeout closes connections that send nothing for this long
+const idleTimeout = time.Minute
+
+// requestTimeout bounds how long a client waits for an answer
+const requestTimeout = 10 * time.Second
+
+// Request asks the service to do something
+type Request struct {
+	Command string `json:"command"`
+	// Text is shown by the write command, one panel line per text line, or
+	// names a macro
+	Text string `json:"text,omitempty"`
+	// Duration is how long the text is shown, a maintenance window lasts or
+	// an LED blinks, in seconds
+	Duration int `json:"duration_sec,omitempty"`
+	// Gesture replays a recorded macro, e.g. "long_select"
+	Gesture string `json:"gesture,omitempty"`
+	// Percent is the value of a progress bar, 0 to 100
+	Percent int `json:"percent,omitempty"`
+	// LED names the LED to switch, e.g. "disk3"
+	LED string `json:"led,omitempty"`
+	// Button is pressed or released by the button command, e.g. "enter"
+	Button  string `json:"button,omitempty"`
+	Pressed bool   `json:"pressed,omitempty"`
+	// Token authenticates requests over TCP, as the HMAC of the
+	// connection's nonce keyed with the shared token; the socket ignores it
+	Token string `json:"token,omitempty"`
+}
+
+// Response carries the output of a request and, if it failed, why
+type Response struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Nonce is the challenge a TCP connection starts with, answered by the
+	// token of its requests
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// Handler performs a request and returns its output
+type Handler func(request Request) (string, error)
+
+// ErrNoService is returned by Dial when no service listens on the socket
+var ErrNoService = errors.New("the display service is not running")
+
+// ErrUnknownCommand is returned for commands without a handler
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Server answers requests on the control socket
+type Server struct {
+	path     string
+	listener net.Listener
+	// remote is the TCP listener, if any
+	remote *remoteListener
+	logger *logrus.Entry
+
+	mutex    sync.Mutex
+	handlers map[string]Handler
+	conns    map[net.Conn]bool
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// Listen creates the socket at path. A socket left behind by a service that
+// is gone is replaced; one another service still answers on is an error.
+// Requests are only answered once Start is called, so the socket can be
+// created while the service still runs as root and served later.
+func Listen(path string) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another service is listening on %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+
+	return &Server{
+		path:     path,
+		listener: listener,
+		logger:   logrus.WithField("component", "control"),
+		handlers: make(map[string]Handler),
+		conns:    make(map[net.Conn]bool),
+	}, nil
+}
+
+// Path returns the socket's path
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Handle sets the handler of a command, replacing any earlier one
+func (s *Server) Handle(command string, handler Handler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers[command] = handler
+}
+
+// Start accepts connections in the background until Close is called
+func (s *Server) Start() {
+	s.wg.Add(1)
+	go s.accept(s.listener, nil)
+	if s.remote != nil {
+		s.wg.Add(1)
+		go s.accept(s.remote.listener, s.remote)
+	}
+}
+
+// accept serves the connections of one listener; remote is nil for the
+// socket
+func (s *Server) accept(listener net.Listener, remote *remoteListener) {
+	defer s.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !s.isClosed() {
+				s.logger.WithError(err).WithField("address", listener.Addr().String()).Error("Control socket stopped accepting connections")
+			}
+			return
+		}
+		if remote != nil && !remote.allowed(conn.RemoteAddr()) {
+			s.logger.WithField("remote", conn.RemoteAddr().String()).Warn("Refused control connection from a host not allowed")
+			conn.Close()
+			continue
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			if err := s.serve(conn, remote); err != nil {
+				s.logger.WithError(err).WithField("remote", conn.RemoteAddr().String()).Warn("Control connection failed")
+			}
+		}()
+	}
+}
+
+// Close stops accepting connections, closes the open ones and removes the
+// socket. Handlers still running are waited for.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	err := s.listener.Close()
+	if s.remote != nil {
+		s.remote.listener.Close()
+	}
+	s.wg.Wait()
+	return err
+}
+
+// isClosed reports whether Close was called
+func (s *Server) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closed
+}
+
+// track records an open connection; it returns false once the server is
+// closed
+func (s *Server) track(conn net.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = true
+	return true
+}
+
+// untrack closes and forgets a connection
+func (s *Server) untrack(conn net.Conn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	conn.Close()
+	delete(s.conns, conn)
+}
+
+// serve answers the requests of one connection until the client goes away.
+// It returns an error for malformed requests, and for requests over TCP
+// without the token.
+func (s *Server) serve(conn net.Conn, remote *remoteListener) error {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	encoder := json.NewEncoder(conn)
+
+	var nonce string
+	if remote != nil {
+		var err error
+		if nonce, err = newNonce(); err != nil {
+			return fmt.Errorf("failed to create challenge: %w", err)
+		}
+		if err := encoder.Encode(Response{Nonce: nonce}); err != nil {
+			return fmt.Errorf("failed to send challenge: %w", err)
+		}
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		var request Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			encoder.Encode(Response{Error: "malformed request"})
+			return fmt.Errorf("malformed request: %w", err)
+		}
+		if remote != nil && !remote.authorized(conn.RemoteAddr(), nonce, request.Token) {
+			encoder.Encode(Response{Error: ErrUnauthorized.Error()})
+			return ErrUnauthorized
+		}
+		if remote != nil && !remote.serves(request.Command) {
+			s.logger.WithField("command", request.Command).WithField("remote", conn.RemoteAddr().String()).Warn("Refused command not served over TCP")
+			encoder.Encode(Response{Error: fmt.Sprintf("command %q is not served over TCP", request.Command)})
+			continue
+		}
+		if err := encoder.Encode(s.handle(request)); err != nil {
+			return fmt.Errorf("failed to send response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// handle runs a single request
+func (s *Server) handle(request Request) Response {
+	s.mutex.Lock()
+	handler := s.handlers[request.Command]
+	s.mutex.Unlock()
+
+	logger := s.logger.WithField("command", request.Command)
+	if handler == nil {
+		logger.Warn("Refused unknown command")
+		return Response{Error: fmt.Sprintf("%s %q", ErrUnknownCommand, request.Command)}
+	}
+
+	logger.Debug("Handling control request")
+	output, err := handler(request)
+	response := Response{Output: output}
+	if err != nil {
+		logger.WithError(err).Warn("Control request failed")
+		response.Error = err.Error()
+	}
+	return response
+}
+
+// Client sends requests to the service. It is safe for concurrent use;
+// requests are answered in turn.
+type Client struct {
+	mutex sync.Mutex
+	conn  net.Conn
+	// token is sent with every request, for TCP
+	token   string
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+// Dial connects to the service listening at path. It returns an error
+// wrapping ErrNoService if there is none, in which case the caller may open
+// the display itself.
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("%w (no one listens on %s)", ErrNoService, path)
+		}
+		return nil, fmt.Errorf("failed to connect to the display service: %w", err)
+	}
+	return &Client{
+		conn:    conn,
+		encoder: json.NewEncoder(conn),
+		decoder: json.NewDecoder(conn),
+	}, nil
+}
+
+// Call sends a request and returns its output. A request the service
+// refused or failed to perform returns its error text as the error.
+func (c *Client) Call(request Request) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.conn.SetDeadline(time.Now().Add(requestTimeout))
+	if c.token != "" {
+		request.Token = c.token
+	}
+	if err := c.encoder.Encode(request); err != nil {
+		return "", fmt.Errorf("failed to send request to the display service: %w", err)
+	}
+	var response Response
+	if err := c.decoder.Decode(&response); err != nil {
+		return "", fmt.Errorf("no response from the display service: %w", err)
+	}
+	if response.Error != "" {
+		return response.Output, errors.New(response.Error)
+	}
+	return response.Output, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
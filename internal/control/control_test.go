@@ -0,0 +1,112 @@
+package control
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves a write handler recording its requests on a socket in
+// a temporary directory
+func startServer(t *testing.T) (*Server, chan Request) {
+	t.Helper()
+
+	server, err := Listen(filepath.Join(t.TempDir(), "run", "control.sock"))
+	require.NoError(t, err)
+	requests := make(chan Request, 10)
+	server.Handle("write", func(request Request) (string, error) {
+		if request.Text == "" {
+			return "", errors.New("nothing to write")
+		}
+		requests <- request
+		return "shown", nil
+	})
+	server.Start()
+	t.Cleanup(func() { server.Close() })
+	return server, requests
+}
+
+func TestClientCall(t *testing.T) {
+	server, requests := startServer(t)
+
+	info, err := os.Stat(server.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketMode), info.Mode().Perm())
+
+	client, err := Dial(server.Path())
+	require.NoError(t, err)
+	defer client.Close()
+
+	output, err := client.Call(Request{Command: "write", Text: "Hello\nWorld", Duration: 5})
+	require.NoError(t, err)
+	assert.Equal(t, "shown", output)
+	assert.Equal(t, Request{Command: "write", Text: "Hello\nWorld", Duration: 5}, <-requests)
+
+	t.Run("Handler error", func(t *testing.T) {
+		_, err := client.Call(Request{Command: "write"})
+		require.Error(t, err)
+		assert.Equal(t, "nothing to write", err.Error())
+	})
+
+	t.Run("Unknown command", func(t *testing.T) {
+		_, err := client.Call(Request{Command: "reboot"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrUnknownCommand.Error())
+	})
+}
+
+func TestDialWithoutService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	_, err := Dial(path)
+	assert.ErrorIs(t, err, ErrNoService, "no socket")
+
+	// A socket nobody listens on is left behind by a crashed service
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	_, err = Dial(path)
+	assert.ErrorIs(t, err, ErrNoService, "stale socket")
+}
+
+func TestListen(t *testing.T) {
+	t.Run("Replaces a stale socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "control.sock")
+		listener, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		listener.(*net.UnixListener).SetUnlinkOnClose(false)
+		listener.Close()
+
+		server, err := Listen(path)
+		require.NoError(t, err)
+		server.Close()
+	})
+
+	t.Run("Refuses a socket in use", func(t *testing.T) {
+		server, _ := startServer(t)
+		_, err := Listen(server.Path())
+		assert.Error(t, err)
+	})
+
+	t.Run("Close removes the socket", func(t *testing.T) {
+		server, err := Listen(filepath.Join(t.TempDir(), "control.sock"))
+		require.NoError(t, err)
+		server.Start()
+
+		client, err := Dial(server.Path())
+		require.NoError(t, err)
+		defer client.Close()
+
+		require.NoError(t, server.Close())
+		_, err = os.Stat(server.Path())
+		assert.True(t, os.IsNotExist(err))
+		_, err = client.Call(Request{Command: "write", Text: "late"})
+		assert.Error(t, err, "open connections are closed")
+	})
+}
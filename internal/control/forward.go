@@ -0,0 +1,121 @@
+package control
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// forwardQueue is how many button events wait for the remote at most; later
+// ones are dropped rather than pressed long after the fact
+const forwardQueue = 32
+
+// redialInterval is how long a forwarder that could not connect drops
+// events before it tries again
+const redialInterval = 5 * time.Second
+
+// ButtonForwarder relays button events to the service on another host with
+// "button" requests over TCP. Events are sent in order on one connection,
+// which is opened again when the remote closed it. While the remote cannot
+// be reached events are dropped, so a press is never replayed late.
+type ButtonForwarder struct {
+	address string
+	token   string
+	events  chan Request
+	done    chan struct{}
+	exited  chan struct{}
+	once    sync.Once
+	logger  *logrus.Entry
+
+	// client is the connection to the remote (nil = none)
+	client *Client
+	// nextDial is when a failed connection may be tried again
+	nextDial time.Time
+}
+
+// NewButtonForwarder starts relaying the events passed to Forward to the
+// service at address, e.g. "backup:9170", authenticated with token
+func NewButtonForwarder(address, token string) *ButtonForwarder {
+	f := &ButtonForwarder{
+		address: address,
+		token:   token,
+		events:  make(chan Request, forwardQueue),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+		logger:  logrus.WithFields(logrus.Fields{"component": "button_forwarder", "remote": address}),
+	}
+	go f.run()
+	return f
+}
+
+// Forward queues a press or release of the named button for the remote. It
+// returns false if the event was dropped because too many are waiting.
+func (f *ButtonForwarder) Forward(button string, pressed bool) bool {
+	select {
+	case f.events <- Request{Command: "button", Button: button, Pressed: pressed}:
+		return true
+	default:
+		f.logger.WithField("button", button).Warn("Dropped button event, the remote is not keeping up")
+		return false
+	}
+}
+
+// Close stops forwarding; events still queued are dropped
+func (f *ButtonForwarder) Close() {
+	f.once.Do(func() { close(f.done) })
+	<-f.exited
+	if f.client != nil {
+		f.client.Close()
+	}
+}
+
+// run sends the queued events until Close
+func (f *ButtonForwarder) run() {
+	defer close(f.exited)
+	for {
+		select {
+		case <-f.done:
+			return
+		case request := <-f.events:
+			f.send(request)
+		}
+	}
+}
+
+// send delivers one event. A connection the remote closed, e.g. after its
+// idle timeout, is opened again once before the event is given up.
+func (f *ButtonForwarder) send(request Request) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if !f.connect() {
+			return
+		}
+		_, err := f.client.Call(request)
+		if err == nil {
+			return
+		}
+		f.logger.WithError(err).Debug("Failed to forward button event")
+		f.client.Close()
+		f.client = nil
+	}
+	f.logger.WithField("button", request.Button).Warn("Dropped button event the remote did not take")
+}
+
+// connect opens the connection to the remote unless it is open, reporting
+// whether it is. After a failure it waits redialInterval before trying again.
+func (f *ButtonForwarder) connect() bool {
+	if f.client != nil {
+		return true
+	}
+	if time.Now().Before(f.nextDial) {
+		return false
+	}
+	client, err := DialTCP(f.address, f.token)
+	if err != nil {
+		f.logger.WithError(err).Warn("Failed to connect for forwarding buttons")
+		f.nextDial = time.Now().Add(redialInterval)
+		return false
+	}
+	f.client = client
+	return true
+}
@@ -0,0 +1,93 @@
+package control
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveButton waits for a forwarded button event
+func receiveButton(t *testing.T, requests chan Request) Request {
+	t.Helper()
+
+	select {
+	case request := <-requests:
+		return request
+	case <-time.After(time.Second):
+		t.Fatal("button event was not forwarded")
+		return Request{}
+	}
+}
+
+func TestButtonForwarder(t *testing.T) {
+	server, _ := startRemote(t, []string{"127.0.0.1"}, "button")
+	requests := make(chan Request, 10)
+	server.Handle("button", func(request Request) (string, error) {
+		requests <- request
+		return "", nil
+	})
+
+	forwarder := NewButtonForwarder(server.Addr().String(), "secret")
+	defer forwarder.Close()
+
+	assert.True(t, forwarder.Forward("enter", true))
+	assert.True(t, forwarder.Forward("enter", false))
+	press, release := receiveButton(t, requests), receiveButton(t, requests)
+	assert.Equal(t, "button", press.Command)
+	assert.Equal(t, "enter", press.Button)
+	assert.True(t, press.Pressed)
+	assert.Equal(t, "enter", release.Button)
+	assert.False(t, release.Pressed)
+
+}
+
+func TestButtonForwarder_ConnectionClosed(t *testing.T) {
+	// The remote answers one request per connection, as if each
+	// connection ran into its idle timeout
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	requests := make(chan Request, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var request Request
+			json.NewEncoder(conn).Encode(Response{Nonce: "1234"})
+			if json.NewDecoder(conn).Decode(&request) == nil {
+				requests <- request
+				json.NewEncoder(conn).Encode(Response{})
+			}
+			conn.Close()
+		}
+	}()
+
+	forwarder := NewButtonForwarder(listener.Addr().String(), "secret")
+	defer forwarder.Close()
+
+	for _, button := range []string{"enter", "select", "enter"} {
+		assert.True(t, forwarder.Forward(button, true))
+		assert.Equal(t, button, receiveButton(t, requests).Button)
+	}
+}
+
+func TestButtonForwarder_Unreachable(t *testing.T) {
+	server, _ := startRemote(t, []string{"127.0.0.1"})
+	address := server.Addr().String()
+	server.Close()
+
+	forwarder := NewButtonForwarder(address, "secret")
+	defer forwarder.Close()
+
+	// Events are dropped instead of waiting for the remote
+	for i := 0; i < 2*forwardQueue; i++ {
+		forwarder.Forward("enter", i%2 == 0)
+	}
+	assert.Eventually(t, func() bool { return len(forwarder.events) == 0 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,223 @@
+package control
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the TCP port DialTCP uses for addresses without one
+const DefaultPort = "9170"
+
+// dialTimeout bounds connecting to a panel over TCP
+const dialTimeout = 5 * time.Second
+
+// ErrUnauthorized is returned for requests over TCP without the right token
+var ErrUnauthorized = errors.New("unauthorized")
+
+// DefaultRemoteCommands are the commands served over TCP unless
+// Remote.Commands lists others: remote hosts may only push messages
+var DefaultRemoteCommands = []string{"write"}
+
+// maxAuthFailures wrong tokens from one host shut it out for authBlock
+const (
+	maxAuthFailures = 5
+	authBlock       = 10 * time.Minute
+)
+
+// authDelay is how long a wrong token is held before it is answered, to
+// slow down guessing
+var authDelay = time.Second
+
+// Remote configures the TCP listener
+type Remote struct {
+	// Token is the shared secret every request has to prove it knows
+	Token string
+	// Allow lists the networks clients may connect from; it must not be
+	// empty
+	Allow []*net.IPNet
+	// Commands are the commands TCP clients may send; empty serves
+	// DefaultRemoteCommands
+	Commands []string
+}
+
+// remoteListener is the TCP listener and how its requests are checked
+type remoteListener struct {
+	Remote
+	listener net.Listener
+
+	mutex sync.Mutex
+	// failures counts the wrong tokens of each host since its last right
+	// one, and blocked is when hosts with too many may connect again
+	failures map[string]int
+	blocked  map[string]time.Time
+}
+
+// ListenTCP also serves requests over TCP on address, e.g. ":9170", for the
+// commands in remote.Commands only. The token is never sent: each
+// connection starts with a nonce, and requests carry an HMAC of it keyed
+// with the token. It has to be called before Start.
+func (s *Server) ListenTCP(address string, remote Remote) error {
+	if remote.Token == "" {
+		return errors.New("control over TCP needs a token")
+	}
+	if len(remote.Allow) == 0 {
+		return errors.New("control over TCP needs the hosts allowed to connect")
+	}
+	if len(remote.Commands) == 0 {
+		remote.Commands = DefaultRemoteCommands
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for control over TCP: %w", err)
+	}
+	s.remote = &remoteListener{
+		Remote:   remote,
+		listener: listener,
+		failures: make(map[string]int),
+		blocked:  make(map[string]time.Time),
+	}
+	return nil
+}
+
+// Addr returns the TCP listener's address, or nil without one
+func (s *Server) Addr() net.Addr {
+	if s.remote == nil {
+		return nil
+	}
+	return s.remote.listener.Addr()
+}
+
+// allowed reports whether a client at addr may connect: it is in one of
+// the allowed networks and not shut out for wrong tokens
+func (r *remoteListener) allowed(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	r.mutex.Lock()
+	until, blocked := r.blocked[tcp.IP.String()]
+	r.mutex.Unlock()
+	if blocked && time.Now().Before(until) {
+		return false
+	}
+
+	for _, network := range r.Allow {
+		if network.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// serves reports whether TCP clients may send command
+func (r *remoteListener) serves(command string) bool {
+	return slices.Contains(r.Commands, command)
+}
+
+// authorized reports whether proof is the HMAC of the connection's nonce
+// keyed with the token. Wrong proofs are counted for the host at addr and
+// answered late; too many shut it out.
+func (r *remoteListener) authorized(addr net.Addr, nonce, proof string) bool {
+	host := addr.String()
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		host = tcp.IP.String()
+	}
+	expected := prove(r.Token, nonce)
+	if hmac.Equal([]byte(proof), []byte(expected)) {
+		r.mutex.Lock()
+		delete(r.failures, host)
+		r.mutex.Unlock()
+		return true
+	}
+
+	r.mutex.Lock()
+	r.failures[host]++
+	if r.failures[host] >= maxAuthFailures {
+		r.blocked[host] = time.Now().Add(authBlock)
+		delete(r.failures, host)
+	}
+	r.mutex.Unlock()
+	time.Sleep(authDelay)
+	return false
+}
+
+// newNonce returns the random challenge a TCP connection starts with
+func newNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+// prove returns what requests on a connection with nonce carry as their
+// token: the HMAC-SHA256 of the nonce keyed with the shared token
+func prove(token, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseAllow reads an allowlist of addresses, e.g. "192.168.1.10", and
+// networks, e.g. "10.0.0.0/24"
+func ParseAllow(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q in allowlist", entry)
+			}
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q in allowlist", entry)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
+
+// DialTCP connects to a service serving control over TCP at address, e.g.
+// "nas1" or "nas1:9170", and proves with every request that it knows token
+func DialTCP(address, token string) (*Client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, DefaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	decoder := json.NewDecoder(conn)
+	var challenge Response
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	if err := decoder.Decode(&challenge); err != nil || challenge.Nonce == "" {
+		conn.Close()
+		return nil, fmt.Errorf("no challenge from %s, is it serving control over TCP?", address)
+	}
+	return &Client{
+		conn:    conn,
+		token:   prove(token, challenge.Nonce),
+		encoder: json.NewEncoder(conn),
+		decoder: decoder,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package control
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startRemote serves a write handler over TCP too, to clients from allow
+// and for commands (default write)
+func startRemote(t *testing.T, allow []string, commands ...string) (*Server, chan Request) {
+	t.Helper()
+
+	server, err := Listen(filepath.Join(t.TempDir(), "control.sock"))
+	require.NoError(t, err)
+	networks, err := ParseAllow(allow)
+	require.NoError(t, err)
+	require.NoError(t, server.ListenTCP("127.0.0.1:0", Remote{Token: "secret", Allow: networks, Commands: commands}))
+
+	requests := make(chan Request, 10)
+	server.Handle("write", func(request Request) (string, error) {
+		requests <- request
+		return "shown", nil
+	})
+	server.Start()
+	t.Cleanup(func() { server.Close() })
+	return server, requests
+}
+
+// quickAuth answers wrong tokens at once for the length of a test
+func quickAuth(t *testing.T) {
+	delay := authDelay
+	authDelay = 0
+	t.Cleanup(func() { authDelay = delay })
+}
+
+func TestListenTCP(t *testing.T) {
+	quickAuth(t)
+	server, requests := startRemote(t, []string{"127.0.0.1"})
+
+	client, err := DialTCP(server.Addr().String(), "secret")
+	require.NoError(t, err)
+	defer client.Close()
+	output, err := client.Call(Request{Command: "write", Text: "Backup finished"})
+	require.NoError(t, err)
+	assert.Equal(t, "shown", output)
+	request := <-requests
+	assert.Equal(t, "Backup finished", request.Text)
+	assert.NotContains(t, request.Token, "secret", "the token is not sent")
+
+	t.Run("Only write", func(t *testing.T) {
+		server.Handle("button", func(request Request) (string, error) {
+			requests <- request
+			return "", nil
+		})
+		_, err := client.Call(Request{Command: "button", Button: "enter", Pressed: true})
+		assert.EqualError(t, err, `command "button" is not served over TCP`)
+		assert.Empty(t, requests)
+		_, err = client.Call(Request{Command: "write", Text: "Still connected"})
+		assert.NoError(t, err)
+		<-requests
+	})
+
+	t.Run("Replayed token", func(t *testing.T) {
+		other, err := DialTCP(server.Addr().String(), "guess")
+		require.NoError(t, err)
+		defer other.Close()
+		other.token = request.Token
+		_, err = other.Call(Request{Command: "write", Text: "Replayed"})
+		assert.Error(t, err, "the proof only fits its own connection's nonce")
+		assert.Empty(t, requests)
+	})
+
+	t.Run("Wrong token", func(t *testing.T) {
+		client, err := DialTCP(server.Addr().String(), "guess")
+		require.NoError(t, err)
+		defer client.Close()
+		_, err = client.Call(Request{Command: "write", Text: "Hacked"})
+		require.Error(t, err)
+		assert.Equal(t, ErrUnauthorized.Error(), err.Error())
+		_, err = client.Call(Request{Command: "write", Text: "Hacked"})
+		assert.Error(t, err, "the connection is closed")
+		assert.Empty(t, requests)
+	})
+
+	t.Run("Socket needs no token", func(t *testing.T) {
+		client, err := Dial(server.Path())
+		require.NoError(t, err)
+		defer client.Close()
+		_, err = client.Call(Request{Command: "write", Text: "Local"})
+		assert.NoError(t, err)
+		<-requests
+	})
+
+	t.Run("No token configured", func(t *testing.T) {
+		server, err := Listen(filepath.Join(t.TempDir(), "control.sock"))
+		require.NoError(t, err)
+		defer server.Close()
+		assert.Error(t, server.ListenTCP("127.0.0.1:0", Remote{}))
+		allow, _ := ParseAllow([]string{"127.0.0.1"})
+		assert.NoError(t, server.ListenTCP("127.0.0.1:0", Remote{Token: "secret", Allow: allow}))
+	})
+
+	t.Run("No hosts allowed", func(t *testing.T) {
+		server, err := Listen(filepath.Join(t.TempDir(), "control.sock"))
+		require.NoError(t, err)
+		defer server.Close()
+		assert.Error(t, server.ListenTCP("127.0.0.1:0", Remote{Token: "secret"}))
+	})
+}
+
+func TestListenTCP_WrongTokens(t *testing.T) {
+	quickAuth(t)
+	server, requests := startRemote(t, []string{"127.0.0.1"})
+
+	for i := 0; i < maxAuthFailures; i++ {
+		client, err := DialTCP(server.Addr().String(), "guess")
+		require.NoError(t, err)
+		_, err = client.Call(Request{Command: "write", Text: "Hacked"})
+		assert.Error(t, err)
+		client.Close()
+	}
+
+	// The host is shut out, even with the right token
+	client, err := DialTCP(server.Addr().String(), "secret")
+	if err == nil {
+		defer client.Close()
+		_, err = client.Call(Request{Command: "write", Text: "Too late"})
+	}
+	assert.Error(t, err)
+	assert.Empty(t, requests)
+
+	remote := server.remote
+	remote.mutex.Lock()
+	remote.blocked["127.0.0.1"] = time.Now()
+	remote.mutex.Unlock()
+	client, err = DialTCP(server.Addr().String(), "secret")
+	require.NoError(t, err, "hosts are let in again after a while")
+	defer client.Close()
+	_, err = client.Call(Request{Command: "write", Text: "Back"})
+	assert.NoError(t, err)
+	<-requests
+}
+
+func TestListenTCP_Allow(t *testing.T) {
+	server, requests := startRemote(t, []string{"192.0.2.0/24"})
+
+	_, err := DialTCP(server.Addr().String(), "secret")
+	assert.Error(t, err, "localhost is not allowed")
+	assert.Empty(t, requests)
+}
+
+func TestParseAllow(t *testing.T) {
+	networks, err := ParseAllow([]string{"192.168.1.10", "10.0.0.0/24", "fd00::1"})
+	require.NoError(t, err)
+	require.Len(t, networks, 3)
+	assert.True(t, networks[0].Contains(net.ParseIP("192.168.1.10")))
+	assert.False(t, networks[0].Contains(net.ParseIP("192.168.1.11")))
+	assert.True(t, networks[1].Contains(net.ParseIP("::ffff:10.0.0.7")), "IPv4 clients of a dual stack listener")
+	assert.True(t, networks[2].Contains(net.ParseIP("fd00::1")))
+
+	_, err = ParseAllow([]string{"nas.local"})
+	assert.Error(t, err)
+	_, err = ParseAllow([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+)
+
+const (
+	// defaultAckTimeout is used when the configuration does not set a timeout
+	defaultAckTimeout = 200 * time.Millisecond
+	// defaultAckRetries is used when the configuration does not set retries
+	defaultAckRetries = 2
+)
+
+// ErrNotAcknowledged is returned by display writes when the panel rejected a
+// command, or did not answer it, on every attempt
+var ErrNotAcknowledged = errors.New("panel did not acknowledge command")
+
+// FrameAcks describes how a panel confirms the commands it receives. Panels
+// that acknowledge commands answer each one with a single byte; a command is
+// only followed by the next once its answer has arrived.
+type FrameAcks struct {
+	// Enabled is set for panels that acknowledge commands
+	Enabled bool
+	// Ack is the answer to an accepted command
+	Ack byte
+	// Nack is the answer to a rejected command, if HasNack is set
+	Nack    byte
+	HasNack bool
+	// Timeout is how long to wait for an answer
+	Timeout time.Duration
+	// Retries is how often a rejected or unanswered command is sent again
+	Retries int
+}
+
+// FrameAcksFromConfig resolves the acknowledgement settings of the hardware
+// configuration. The answer bytes must not start any frame the panel sends on
+// its own.
+func FrameAcksFromConfig(cfg config.HardwareConfig) (FrameAcks, error) {
+	if cfg.AckByte == nil {
+		if cfg.NackByte != nil {
+			return FrameAcks{}, fmt.Errorf("nack_byte requires ack_byte")
+		}
+		return FrameAcks{}, nil
+	}
+
+	quirks, err := ButtonQuirksFromConfig(cfg)
+	if err != nil {
+		return FrameAcks{}, err
+	}
+	answerByte := func(name string, value int) (byte, error) {
+		if value < 0 || value > 0xFF {
+			return 0, fmt.Errorf("%s %d is not a byte", name, value)
+		}
+		if qnapproto.Reserved(byte(value)) || quirks.isCopyFramePrefix(byte(value)) {
+			return 0, fmt.Errorf("%s 0x%02x collides with the panel protocol", name, value)
+		}
+		return byte(value), nil
+	}
+
+	acks := FrameAcks{Enabled: true, Timeout: defaultAckTimeout, Retries: defaultAckRetries}
+	if acks.Ack, err = answerByte("ack_byte", *cfg.AckByte); err != nil {
+		return FrameAcks{}, err
+	}
+	if cfg.NackByte != nil {
+		if acks.Nack, err = answerByte("nack_byte", *cfg.NackByte); err != nil {
+			return FrameAcks{}, err
+		}
+		if acks.Nack == acks.Ack {
+			return FrameAcks{}, fmt.Errorf("ack_byte and nack_byte must differ")
+		}
+		acks.HasNack = true
+	}
+
+	if cfg.AckTimeout < 0 {
+		return FrameAcks{}, fmt.Errorf("ack_timeout_ms must not be negative, got %d", cfg.AckTimeout)
+	}
+	if cfg.AckTimeout > 0 {
+		acks.Timeout = time.Duration(cfg.AckTimeout) * time.Millisecond
+	}
+	if cfg.AckRetries != nil {
+		if *cfg.AckRetries < 0 {
+			return FrameAcks{}, fmt.Errorf("ack_retries must not be negative, got %d", *cfg.AckRetries)
+		}
+		acks.Retries = *cfg.AckRetries
+	}
+	return acks, nil
+}
+
+// FrameStats counts the answers to acknowledged commands since the
+// controller started. All counts stay zero for panels that do not
+// acknowledge commands.
+type FrameStats struct {
+	// Sent counts commands written, including retries
+	Sent uint64
+	// Acked, Nacked and TimedOut count how each of them was answered
+	Acked    uint64
+	Nacked   uint64
+	TimedOut uint64
+	// Retries counts commands sent again after a rejection or timeout
+	Retries uint64
+	// Failed counts commands given up on after the last retry
+	Failed uint64
+}
+
+// frameCounters are the live counts behind FrameStats
+type frameCounters struct {
+	sent, acked, nacked, timedOut, retries, failed atomic.Uint64
+}
+
+// snapshot returns the current counts
+func (c *frameCounters) snapshot() FrameStats {
+	return FrameStats{
+		Sent:     c.sent.Load(),
+		Acked:    c.acked.Load(),
+		Nacked:   c.nacked.Load(),
+		TimedOut: c.timedOut.Load(),
+		Retries:  c.retries.Load(),
+		Failed:   c.failed.Load(),
+	}
+}
+
+// FrameStats returns the acknowledgement counts of the commands sent so far
+func (dc *DisplayController) FrameStats() FrameStats {
+	return dc.frameCounters.snapshot()
+}
+
+// deliverAnswer hands an acknowledgement read from the panel to the waiting
+// writer. An answer nobody waits for, e.g. to a link probe, is dropped.
+func (dc *DisplayController) deliverAnswer(accepted bool) {
+	select {
+	case dc.answers <- accepted:
+	default:
+	}
+}
+
+// awaitAnswer waits for the panel to answer the command just written
+func (dc *DisplayController) awaitAnswer() error {
+	timer := time.NewTimer(dc.acks.Timeout)
+	defer timer.Stop()
+
+	select {
+	case accepted := <-dc.answers:
+		if accepted {
+			dc.frameCounters.acked.Add(1)
+			return nil
+		}
+		dc.frameCounters.nacked.Add(1)
+		return fmt.Errorf("%w: command rejected", ErrNotAcknowledged)
+	case <-timer.C:
+		dc.frameCounters.timedOut.Add(1)
+		return fmt.Errorf("%w: no answer within %v", ErrNotAcknowledged, dc.acks.Timeout)
+	case <-dc.stopChan:
+		return fmt.Errorf("display controller closed while waiting for an answer")
+	}
+}
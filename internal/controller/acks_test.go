@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAck  = 0x06
+	testNack = 0x15
+)
+
+// answeringPort answers every command written to it like a panel that
+// acknowledges commands: with the next byte of its script, or with answer
+// once the script is used up. A zero byte leaves the command unanswered.
+type answeringPort struct {
+	*serial.MockSerialPort
+	mutex   sync.Mutex
+	script  []byte
+	answer  byte
+	pending []byte
+	writes  [][]byte
+}
+
+func (p *answeringPort) Write(data []byte) error {
+	if err := p.MockSerialPort.Write(data); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.writes = append(p.writes, append([]byte(nil), data...))
+	answer := p.answer
+	if len(p.script) > 0 {
+		answer, p.script = p.script[0], p.script[1:]
+	}
+	if answer != 0 {
+		p.pending = append(p.pending, answer)
+	}
+	return nil
+}
+
+func (p *answeringPort) ReadAvailable() ([]byte, error) {
+	data, err := p.MockSerialPort.ReadAvailable()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	data = append(data, p.pending...)
+	p.pending = nil
+	return data, nil
+}
+
+// expect sets the answers to the next commands and forgets earlier writes
+func (p *answeringPort) expect(answers ...byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.script = answers
+	p.writes = nil
+}
+
+// newAckedTestController creates a controller for a panel acknowledging
+// every command unless told otherwise
+func newAckedTestController(t *testing.T) (*DisplayController, *answeringPort) {
+	t.Helper()
+
+	ack, nack, retries := testAck, testNack, 1
+	cfg := config.DefaultConfig()
+	cfg.Hardware.AckByte = &ack
+	cfg.Hardware.NackByte = &nack
+	cfg.Hardware.AckTimeout = 100
+	cfg.Hardware.AckRetries = &retries
+
+	port := &answeringPort{MockSerialPort: serial.NewMockSerialPort(), answer: testAck}
+	dc, err := NewDisplayControllerWithPort(cfg, port)
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+	return dc, port
+}
+
+func TestFrameAcksFromConfig(t *testing.T) {
+	byteValue := func(value int) *int { return &value }
+
+	acks, err := FrameAcksFromConfig(config.HardwareConfig{})
+	require.NoError(t, err)
+	assert.False(t, acks.Enabled, "commands are not confirmed unless configured")
+
+	acks, err = FrameAcksFromConfig(config.HardwareConfig{AckByte: byteValue(testAck)})
+	require.NoError(t, err)
+	assert.Equal(t, FrameAcks{Enabled: true, Ack: testAck, Timeout: defaultAckTimeout, Retries: defaultAckRetries}, acks)
+
+	acks, err = FrameAcksFromConfig(config.HardwareConfig{
+		AckByte:    byteValue(testAck),
+		NackByte:   byteValue(testNack),
+		AckTimeout: 50,
+		AckRetries: byteValue(0),
+	})
+	require.NoError(t, err)
+	assert.True(t, acks.HasNack)
+	assert.Equal(t, byte(testNack), acks.Nack)
+	assert.Equal(t, 0, acks.Retries)
+
+	invalid := []config.HardwareConfig{
+		{NackByte: byteValue(testNack)},
+		{AckByte: byteValue(256)},
+		{AckByte: byteValue(0x53)},
+		{AckByte: byteValue(0x55)}, // copy frame prefix of the generic profile
+		{AckByte: byteValue(testAck), NackByte: byteValue(testAck)},
+		{AckByte: byteValue(testAck), AckTimeout: -1},
+		{AckByte: byteValue(testAck), AckRetries: byteValue(-1)},
+	}
+	for _, cfg := range invalid {
+		_, err := FrameAcksFromConfig(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestDisplayController_AcknowledgedWrites(t *testing.T) {
+	t.Run("Commands wait for their answer", func(t *testing.T) {
+		dc, port := newAckedTestController(t)
+		before := dc.FrameStats()
+
+		port.expect()
+		require.NoError(t, dc.WriteText("Line 1\nLine 2"))
+
+		port.mutex.Lock()
+		writes := port.writes
+		port.mutex.Unlock()
+		assert.Len(t, writes, 2, "each line is sent on its own")
+		stats := dc.FrameStats()
+		assert.Equal(t, before.Sent+2, stats.Sent)
+		assert.Equal(t, before.Acked+2, stats.Acked)
+		assert.Equal(t, before.Retries, stats.Retries)
+	})
+
+	t.Run("Rejected commands are sent again", func(t *testing.T) {
+		dc, port := newAckedTestController(t)
+		before := dc.FrameStats()
+
+		port.expect(testNack, testAck)
+		require.NoError(t, dc.SetBacklight(true))
+
+		port.mutex.Lock()
+		writes := port.writes
+		port.mutex.Unlock()
+		require.Len(t, writes, 2)
+		assert.Equal(t, writes[0], writes[1])
+		stats := dc.FrameStats()
+		assert.Equal(t, before.Nacked+1, stats.Nacked)
+		assert.Equal(t, before.Retries+1, stats.Retries)
+		assert.Equal(t, before.Failed, stats.Failed)
+	})
+
+	t.Run("Unanswered commands fail after the retries", func(t *testing.T) {
+		dc, port := newAckedTestController(t)
+		before := dc.FrameStats()
+
+		port.expect(0, 0)
+		err := dc.SetBacklight(true)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrNotAcknowledged))
+
+		stats := dc.FrameStats()
+		assert.Equal(t, before.TimedOut+2, stats.TimedOut)
+		assert.Equal(t, before.Failed+1, stats.Failed)
+
+		// The panel answers again, and so does the next write
+		port.expect()
+		assert.NoError(t, dc.SetBacklight(true))
+	})
+}
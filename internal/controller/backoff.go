@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRetryStopped is returned by BackoffRetry when it was stopped before an
+// attempt succeeded
+var ErrRetryStopped = errors.New("retry stopped")
+
+// Backoff is an exponential backoff between attempts: the first wait is
+// Initial and every further one doubles, up to Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	// Attempts is how often to try in total; 0 tries until stopped
+	Attempts int
+}
+
+// delay returns the wait before the attempt with the given index, counted
+// from 0; the first attempt does not wait
+func (b Backoff) delay(attempt int) time.Duration {
+	if attempt == 0 {
+		return 0
+	}
+	wait := b.Initial
+	for i := 1; i < attempt && (b.Max <= 0 || wait < b.Max); i++ {
+		wait *= 2
+	}
+	if b.Max > 0 && wait > b.Max {
+		wait = b.Max
+	}
+	return wait
+}
+
+// BackoffRetry calls attempt with its index until it succeeds, the attempts
+// run out or stop is closed, waiting as backoff says in between. It returns
+// nil on success, ErrRetryStopped when stopped, and otherwise the error of
+// the last attempt.
+func BackoffRetry(backoff Backoff, stop <-chan struct{}, attempt func(n int) error) error {
+	var err error
+	for n := 0; backoff.Attempts <= 0 || n < backoff.Attempts; n++ {
+		if wait := backoff.delay(n); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-stop:
+				timer.Stop()
+				return ErrRetryStopped
+			case <-timer.C:
+			}
+		}
+		if err = attempt(n); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Max: 16 * time.Second}
+
+	var delays []time.Duration
+	for attempt := 0; attempt < 7; attempt++ {
+		delays = append(delays, backoff.delay(attempt))
+	}
+	assert.Equal(t, []time.Duration{
+		0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 16 * time.Second,
+	}, delays)
+}
+
+func TestBackoffRetry(t *testing.T) {
+	backoff := Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Attempts: 4}
+
+	t.Run("Stops at the first success", func(t *testing.T) {
+		var attempts []int
+		err := BackoffRetry(backoff, nil, func(n int) error {
+			attempts = append(attempts, n)
+			if n < 2 {
+				return errors.New("not yet")
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2}, attempts)
+	})
+
+	t.Run("Returns the last error once the attempts run out", func(t *testing.T) {
+		calls := 0
+		err := BackoffRetry(backoff, nil, func(n int) error {
+			calls++
+			return fmt.Errorf("attempt %d", n)
+		})
+		assert.EqualError(t, err, "attempt 3")
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("Stops waiting when stopped", func(t *testing.T) {
+		stop := make(chan struct{})
+		close(stop)
+		calls := 0
+		err := BackoffRetry(Backoff{Initial: time.Hour}, stop, func(int) error {
+			calls++
+			return errors.New("not yet")
+		})
+		assert.Equal(t, ErrRetryStopped, err)
+		assert.Equal(t, 1, calls, "the first attempt does not wait")
+	})
+}
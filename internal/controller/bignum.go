@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BigNumberRows is the height of the big digits in display rows
+const BigNumberRows = 2
+
+// CustomChars defines the custom characters of a panel. The display
+// controllers satisfy it.
+type CustomChars interface {
+	DefineCustomChar(slot int, bitmap [8]byte) error
+}
+
+// bigFont are the blocks the big digits are built of, loaded into the
+// custom character slots in this order. They replace the first glyphs while
+// loaded.
+var bigFont = [][8]byte{
+	{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}, // full block
+	{0x1F, 0x1F, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x00}, // top bar
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x1F, 0x1F, 0x1F}, // bottom bar
+	{0x1F, 0x1F, 0x1F, 0x00, 0x00, 0x1F, 0x1F, 0x1F}, // top and bottom bars
+	{0x00, 0x00, 0x00, 0x00, 0x0E, 0x0E, 0x0E, 0x00}, // upper colon dot
+	{0x00, 0x0E, 0x0E, 0x0E, 0x00, 0x00, 0x00, 0x00}, // lower colon dot
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x0E, 0x0E, 0x0E}, // point
+}
+
+// bigGlyphs spell each character of the big font in two rows, "F" for the
+// full block, "T" the top bar, "B" the bottom bar, "M" both bars, "u" and
+// "l" the colon dots and "p" the point. The bottom bar of the upper row is
+// the middle stroke of a digit.
+var bigGlyphs = map[rune][BigNumberRows]string{
+	'0': {"FTF", "FBF"},
+	'1': {"TF ", "BFB"},
+	'2': {"MMF", "FBB"},
+	'3': {"MMF", "BBF"},
+	'4': {"FBF", "  F"},
+	'5': {"FMM", "BBF"},
+	'6': {"FMM", "FBF"},
+	'7': {"TTF", "  F"},
+	'8': {"FMF", "FBF"},
+	'9': {"FMF", "BBF"},
+	':': {"u", "l"},
+	'.': {" ", "p"},
+	'-': {"BB", "  "},
+	' ': {" ", " "},
+}
+
+// bigBlocks map the letters of bigGlyphs to the slots of bigFont
+var bigBlocks = map[byte]int{'F': 0, 'T': 1, 'B': 2, 'M': 3, 'u': 4, 'l': 5, 'p': 6}
+
+// RenderBigNumber returns the rows drawing text in big digits, centred in
+// width. Digits are one column apart; ':', '.', '-' and ' ' may separate
+// them. The rows show the big digits once LoadBigFont loaded their blocks.
+// Text wider than width is an error, e.g. "1:00:00" on a 16 column panel.
+func RenderBigNumber(text string, width int) ([]string, error) {
+	var rows [BigNumberRows]strings.Builder
+	previousDigit := false
+	for _, char := range text {
+		glyph, ok := bigGlyphs[char]
+		if !ok {
+			return nil, fmt.Errorf("no big digit for %q", char)
+		}
+		digit := char >= '0' && char <= '9'
+		for row := range rows {
+			if digit && previousDigit {
+				rows[row].WriteByte(' ')
+			}
+			for i := 0; i < len(glyph[row]); i++ {
+				if slot, ok := bigBlocks[glyph[row][i]]; ok {
+					rows[row].WriteRune(rune(glyphCodeBase + slot))
+				} else {
+					rows[row].WriteByte(' ')
+				}
+			}
+		}
+		previousDigit = digit
+	}
+
+	length := rows[0].Len()
+	if length > width {
+		return nil, fmt.Errorf("%q is %d columns wide in big digits, the display %d", text, length, width)
+	}
+	margin := strings.Repeat(" ", (width-length)/2)
+	lines := make([]string, BigNumberRows)
+	for row := range rows {
+		lines[row] = margin + rows[row].String()
+	}
+	return lines, nil
+}
+
+// LoadBigFont defines the blocks of the big digits in the custom character
+// slots, taking the place of the first glyphs until RestoreGlyphs
+func LoadBigFont(chars CustomChars) error {
+	for slot, bitmap := range bigFont {
+		if err := chars.DefineCustomChar(slot, bitmap); err != nil {
+			return fmt.Errorf("failed to load big font: %w", err)
+		}
+	}
+	return nil
+}
+
+// RestoreGlyphs defines the built-in glyphs again after LoadBigFont or
+// LoadLogo
+func RestoreGlyphs(chars CustomChars) error {
+	for slot := range glyphs {
+		if err := chars.DefineCustomChar(slot, glyphs[slot].Rows); err != nil {
+			return fmt.Errorf("failed to restore glyphs: %w", err)
+		}
+	}
+	return nil
+}
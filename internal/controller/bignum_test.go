@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedChars keeps the custom characters defined
+type recordedChars map[int][8]byte
+
+func (r recordedChars) DefineCustomChar(slot int, bitmap [8]byte) error {
+	r[slot] = bitmap
+	return nil
+}
+
+func TestRenderBigNumber(t *testing.T) {
+	lines, err := RenderBigNumber("23:59", 16)
+	require.NoError(t, err)
+	// Blocks: 0 full, 1 top, 2 bottom, 3 both bars, 4/5 colon dots
+	assert.Equal(t, []string{
+		"\x03\x03\x00 \x03\x03\x00\x04\x00\x03\x03 \x00\x03\x00",
+		"\x00\x02\x02 \x02\x02\x00\x05\x02\x02\x00 \x02\x02\x00",
+	}, lines)
+
+	// Centred, digits one column apart
+	lines, err = RenderBigNumber("10", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "    \x01\x00  \x00\x01\x00", lines[0])
+	assert.Equal(t, "    \x02\x00\x02 \x00\x02\x00", lines[1])
+
+	_, err = RenderBigNumber("1:00:00", 16)
+	assert.Error(t, err, "23 columns do not fit")
+	_, err = RenderBigNumber("12a", 16)
+	assert.Error(t, err)
+}
+
+func TestLoadBigFont(t *testing.T) {
+	chars := recordedChars{}
+	require.NoError(t, LoadBigFont(chars))
+	assert.Len(t, chars, len(bigFont))
+	assert.Equal(t, bigFont[3], chars[3])
+
+	require.NoError(t, RestoreGlyphs(chars))
+	gear, _ := GlyphChar("gear")
+	assert.Equal(t, glyphs[0].Rows, chars[int(gear[0])])
+	assert.Equal(t, glyphs[6].Rows, chars[6])
+
+	// Panels without custom characters cannot show them
+	dc, _ := newTestDisplayController(t)
+	assert.ErrorIs(t, LoadBigFont(dc), ErrNoGlyphs)
+}
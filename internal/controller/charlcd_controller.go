@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/charlcd"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/sirupsen/logrus"
+)
+
+// Display drivers for USB character display modules
+const (
+	DriverMatrixOrbital = "matrix-orbital"
+	DriverCrystalFontz  = "crystalfontz"
+)
+
+// charLCDButtons maps module keys to panel buttons: the confirming key acts
+// as ENTER and the key moving down the list as SELECT
+var charLCDButtons = map[charlcd.Key]PanelButton{
+	charlcd.KeyEnter: ButtonEnter,
+	charlcd.KeyRight: ButtonEnter,
+	charlcd.KeyDown:  ButtonSelect,
+}
+
+// charLCDPollInterval is how often the port is read for key reports
+const charLCDPollInterval = 50 * time.Millisecond
+
+// CharLCDController shows the display on a character display module attached
+// as a serial port, such as Matrix Orbital and CrystalFontz USB modules. Its
+// keypad drives the same buttons as the QNAP panel. Failed writes are
+// returned; there is no circuit breaker, so the link always reads as closed.
+type CharLCDController struct {
+	port       serial.SerialPortInterface
+	protocol   charlcd.Protocol
+	cols, rows int
+	logger     *logrus.Entry
+	translit   *translit.Transliterator
+
+	writeMutex    sync.Mutex // serializes writes so updates arrive whole
+	buttonHandler ButtonEventHandler
+	handlerMutex  sync.RWMutex
+	stopChan      chan struct{}
+	closeOnce     sync.Once
+	// marquees scroll text through rows, see ScrollText
+	marquees Marquees
+}
+
+// NewCharLCDController opens the serial port of the module selected by
+// Display.Driver
+func NewCharLCDController(cfg *config.Config) (*CharLCDController, error) {
+	port, err := serial.NewSerialPort(cfg.SerialPort.Device, cfg.SerialPort.BaudRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize serial port: %w", err)
+	}
+	dc, err := NewCharLCDControllerWithPort(cfg, port)
+	if err != nil {
+		port.Close()
+		return nil, err
+	}
+	return dc, nil
+}
+
+// NewCharLCDControllerWithPort creates a module controller on an already
+// opened serial port (a real port or a mock for testing)
+func NewCharLCDControllerWithPort(cfg *config.Config, port serial.SerialPortInterface) (*CharLCDController, error) {
+	protocol, err := charlcd.New(cfg.Display.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	cols, rows := cfg.Display.Width, cfg.Display.Height
+	if cols <= 0 {
+		cols = displayWidth
+	}
+	if rows <= 0 {
+		rows = displayRows
+	}
+	dc := &CharLCDController{
+		port:     port,
+		protocol: protocol,
+		cols:     cols,
+		rows:     rows,
+		logger:   logrus.WithFields(logrus.Fields{"component": "charlcd_display", "driver": cfg.Display.Driver}),
+		stopChan: make(chan struct{}),
+	}
+	dc.translit = newTransliterator(cfg, dc.logger)
+
+	// Glyphs live in the module's own CGRAM at the codes the menu uses
+	setup := protocol.Init()
+	for slot, glyph := range glyphs {
+		setup = append(setup, protocol.Glyph(glyphCodeBase+slot, glyph.Rows)...)
+	}
+	setup = append(setup, protocol.Backlight(true)...)
+	if err := dc.write(setup); err != nil {
+		return nil, fmt.Errorf("failed to initialize display: %w", err)
+	}
+	if cfg.Display.DefaultText != "" {
+		if err := dc.WriteText(cfg.Display.DefaultText); err != nil {
+			dc.logger.WithError(err).Warn("Failed to write default text")
+		}
+	}
+
+	go dc.monitorKeys()
+
+	dc.logger.Info("Character display initialized successfully")
+	return dc, nil
+}
+
+// Update lets fn compose a display update and sends it as one write.
+// Nothing is sent if fn returns an error.
+func (dc *CharLCDController) Update(fn func(update *DisplayUpdate) error) error {
+	update := newDisplayUpdate(dc.rows, false, dc.translit)
+	if err := fn(update); err != nil {
+		return err
+	}
+
+	var batch []byte
+	if update.backlight != nil && !*update.backlight {
+		batch = append(batch, dc.protocol.Backlight(false)...)
+	}
+	for row, line := range update.lines {
+		if line != nil {
+			batch = append(batch, dc.protocol.Line(row, dc.cols, *line)...)
+		}
+	}
+	if update.backlight != nil && *update.backlight {
+		batch = append(batch, dc.protocol.Backlight(true)...)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := dc.write(batch); err != nil {
+		return fmt.Errorf("failed to send display update: %w", err)
+	}
+	return nil
+}
+
+// WriteText replaces all lines with newline separated text
+func (dc *CharLCDController) WriteText(text string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetText(text)
+		return nil
+	})
+}
+
+// WriteTextAt replaces a line. Like the serial panel, the whole line is
+// written, so col is ignored.
+func (dc *CharLCDController) WriteTextAt(text string, row, col int) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		return update.SetLine(row, text)
+	})
+}
+
+// WriteLines replaces several lines, keyed by row, in a single update
+func (dc *CharLCDController) WriteLines(lines map[int]string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		for row, text := range lines {
+			if err := update.SetLine(row, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ScrollText scrolls text through row, see Marquees.ScrollText
+func (dc *CharLCDController) ScrollText(row int, text string, speed time.Duration) error {
+	return dc.marquees.ScrollText(dc, dc.cols, row, text, speed)
+}
+
+// StopScroll stops the marquee on row, leaving its last step shown
+func (dc *CharLCDController) StopScroll(row int) {
+	dc.marquees.StopScroll(row)
+}
+
+// ClearDisplay clears every line
+func (dc *CharLCDController) ClearDisplay() error {
+	return dc.WriteText("")
+}
+
+// SetBacklight switches the backlight
+func (dc *CharLCDController) SetBacklight(on bool) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetBacklight(on)
+		return nil
+	})
+}
+
+// ShowCopyStatus displays copy operation status
+func (dc *CharLCDController) ShowCopyStatus(status string) error {
+	return dc.WriteText("USB Copy\n" + status)
+}
+
+// ShowProgress draws a progress bar on the second line. USB modules take
+// writes at full speed, so redraws are not rate limited.
+func (dc *CharLCDController) ShowProgress(percent int) error {
+	return dc.WriteTextAt(RenderProgressBarWidth(percent, dc.cols), progressRow, 0)
+}
+
+// DefineCustomChar replaces the custom character in slot (0 to 7) in the
+// module's CGRAM
+func (dc *CharLCDController) DefineCustomChar(slot int, bitmap [8]byte) error {
+	if err := checkGlyphSlot(slot); err != nil {
+		return err
+	}
+	if err := dc.write(dc.protocol.Glyph(glyphCodeBase+slot, maskGlyph(bitmap))); err != nil {
+		return fmt.Errorf("failed to define custom character: %w", err)
+	}
+	return nil
+}
+
+// SetButtonHandler sets the callback function for keypad presses
+func (dc *CharLCDController) SetButtonHandler(handler ButtonEventHandler) {
+	dc.handlerMutex.Lock()
+	dc.buttonHandler = handler
+	dc.handlerMutex.Unlock()
+}
+
+// RequestButtonState does nothing; the modules report keys on their own
+func (dc *CharLCDController) RequestButtonState() error {
+	return nil
+}
+
+// SetSerialCopyDetection does nothing; the keypads have no copy button
+func (dc *CharLCDController) SetSerialCopyDetection(enabled bool) {}
+
+// SerialCopyDetection always reports false
+func (dc *CharLCDController) SerialCopyDetection() bool {
+	return false
+}
+
+// SetBreakerHandler is accepted for compatibility; there is no circuit breaker
+func (dc *CharLCDController) SetBreakerHandler(handler BreakerEventHandler) {}
+
+// LinkState always reports a closed breaker
+func (dc *CharLCDController) LinkState() BreakerState {
+	return BreakerClosed
+}
+
+// FrameStats always reports zero counts; these panels do not acknowledge
+// commands
+func (dc *CharLCDController) FrameStats() FrameStats {
+	return FrameStats{}
+}
+
+// Close stops key monitoring and closes the port
+func (dc *CharLCDController) Close() error {
+	var err error
+	dc.closeOnce.Do(func() {
+		dc.logger.Info("Closing character display")
+		dc.marquees.StopAll()
+		close(dc.stopChan)
+		err = dc.port.Close()
+	})
+	return err
+}
+
+// write sends raw bytes to the module
+func (dc *CharLCDController) write(data []byte) error {
+	dc.writeMutex.Lock()
+	defer dc.writeMutex.Unlock()
+
+	return dc.port.Write(data)
+}
+
+// monitorKeys reads key reports until the controller is closed
+func (dc *CharLCDController) monitorKeys() {
+	var buffer []byte
+	for {
+		select {
+		case <-dc.stopChan:
+			return
+		default:
+		}
+
+		data, err := dc.port.ReadAvailable()
+		if err != nil || len(data) == 0 {
+			time.Sleep(charLCDPollInterval)
+			continue
+		}
+
+		buffer = append(buffer, data...)
+		events, consumed := dc.protocol.DecodeKeys(buffer)
+		buffer = append(buffer[:0], buffer[consumed:]...)
+		for _, event := range events {
+			dc.handleKey(event)
+		}
+	}
+}
+
+// handleKey forwards a key mapped to a panel button
+func (dc *CharLCDController) handleKey(event charlcd.KeyEvent) {
+	button, ok := charLCDButtons[event.Key]
+	if !ok {
+		dc.logger.WithField("key", event.Key).Debug("Ignoring unmapped key")
+		return
+	}
+
+	dc.handlerMutex.RLock()
+	handler := dc.buttonHandler
+	dc.handlerMutex.RUnlock()
+
+	dc.logger.WithFields(logrus.Fields{
+		"button":      button.String(),
+		"button_id":   int(button),
+		"pressed":     event.Pressed,
+		"has_handler": handler != nil,
+	}).Info("Button event triggered")
+
+	if handler != nil {
+		handler(button, event.Pressed)
+	}
+}
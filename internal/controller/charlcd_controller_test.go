@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCharLCDController(t *testing.T) {
+	cfg := &config.Config{Display: config.DisplayConfig{Driver: DriverMatrixOrbital, Width: 20, Height: 4}}
+	port := serial.NewMockSerialPort()
+	dc, err := NewCharLCDControllerWithPort(cfg, port)
+	require.NoError(t, err)
+	defer dc.Close()
+
+	setup := port.GetWrittenData()
+	assert.Equal(t, []byte{0xFE, 0x44}, setup[:2])
+	assert.Contains(t, string(setup), string([]byte{0xFE, 0x4E, 0x00}), "the first glyph is uploaded")
+
+	port.ClearWrittenData()
+	require.NoError(t, dc.WriteLines(map[int]string{3: "Bottom"}))
+	assert.Equal(t, append([]byte{0xFE, 0x47, 1, 4}, "Bottom              "...), port.GetWrittenData())
+	assert.Error(t, dc.WriteTextAt("x", 4, 0))
+
+	pressed := make(chan PanelButton, 4)
+	dc.SetButtonHandler(func(button PanelButton, down bool) {
+		if down {
+			pressed <- button
+		}
+	})
+	port.SetReadData([]byte("EH"))
+	for _, want := range []PanelButton{ButtonEnter, ButtonSelect} {
+		select {
+		case button := <-pressed:
+			assert.Equal(t, want, button)
+		case <-time.After(time.Second):
+			t.Fatal("key press not delivered")
+		}
+	}
+}
+
+func TestCharLCDController_UnknownDriver(t *testing.T) {
+	cfg := &config.Config{Display: config.DisplayConfig{Driver: "lcdproc"}}
+	_, err := NewCharLCDControllerWithPort(cfg, serial.NewMockSerialPort())
+	assert.Error(t, err)
+}
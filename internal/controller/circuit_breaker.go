@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BreakerState is the state of the serial link circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed means the link is healthy and writes go through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means too many writes failed; writes are refused until a probe succeeds
+	BreakerOpen
+	// BreakerHalfOpen means a probe was sent and the panel's answer is awaited
+	BreakerHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("state(%d)", int(s))
+	}
+}
+
+// BreakerEvent reports a circuit breaker transition
+type BreakerEvent struct {
+	From BreakerState
+	To   BreakerState
+	// Failures is the number of consecutive failed writes
+	Failures int
+	// Err is the write error that caused the transition, if any
+	Err error
+}
+
+// BreakerEventHandler is a callback function for circuit breaker transitions
+type BreakerEventHandler func(event BreakerEvent)
+
+// ErrLinkDown is returned by display writes while the circuit breaker is open
+var ErrLinkDown = errors.New("serial link down, circuit breaker open")
+
+const (
+	// defaultErrorThreshold is used when the configuration does not set a threshold
+	defaultErrorThreshold = 5
+	// defaultProbeInterval is used when the configuration does not set an interval
+	defaultProbeInterval = 5 * time.Second
+)
+
+// circuitBreaker counts consecutive serial write failures. Once the threshold
+// is reached it opens and refuses writes; periodic probes move it to half-open
+// and any answer from the panel closes it again. Every method that changes the
+// state returns the resulting transition, if there was one.
+type circuitBreaker struct {
+	threshold int
+
+	mutex    sync.Mutex
+	state    BreakerState
+	failures int
+}
+
+// newCircuitBreaker creates a closed breaker opening after threshold failures
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = defaultErrorThreshold
+	}
+	return &circuitBreaker{threshold: threshold}
+}
+
+// State returns the current state
+func (b *circuitBreaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+// allow reports whether a regular write may be attempted
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state == BreakerClosed
+}
+
+// record counts the result of a regular write
+func (b *circuitBreaker) record(err error) (BreakerEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		return BreakerEvent{}, false
+	}
+
+	b.failures++
+	if b.state != BreakerClosed || b.failures < b.threshold {
+		return BreakerEvent{}, false
+	}
+	return b.transition(BreakerOpen, err), true
+}
+
+// trip opens the breaker at once, for a panel that never answered
+func (b *circuitBreaker) trip(err error) (BreakerEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != BreakerClosed {
+		return BreakerEvent{}, false
+	}
+	return b.transition(BreakerOpen, err), true
+}
+
+// probing moves an open breaker to half-open before a probe is sent
+func (b *circuitBreaker) probing() (BreakerEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != BreakerOpen {
+		return BreakerEvent{}, false
+	}
+	return b.transition(BreakerHalfOpen, nil), true
+}
+
+// probeFailed reopens the breaker when a probe could not be sent
+func (b *circuitBreaker) probeFailed(err error) (BreakerEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != BreakerHalfOpen {
+		return BreakerEvent{}, false
+	}
+	b.failures++
+	return b.transition(BreakerOpen, err), true
+}
+
+// responded closes the breaker because the panel sent data
+func (b *circuitBreaker) responded() (BreakerEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerClosed {
+		return BreakerEvent{}, false
+	}
+	event := b.transition(BreakerClosed, nil)
+	b.failures = 0
+	return event, true
+}
+
+// transition changes the state. Caller must hold the mutex.
+func (b *circuitBreaker) transition(to BreakerState, err error) BreakerEvent {
+	event := BreakerEvent{From: b.state, To: to, Failures: b.failures, Err: err}
+	b.state = to
+	return event
+}
+
+// SetBreakerHandler sets the callback function for circuit breaker transitions.
+// Events are delivered in order from a single goroutine.
+func (dc *DisplayController) SetBreakerHandler(handler BreakerEventHandler) {
+	dc.handlerMutex.Lock()
+	dc.breakerHandler = handler
+	dc.handlerMutex.Unlock()
+}
+
+// LinkState returns the state of the serial link circuit breaker
+func (dc *DisplayController) LinkState() BreakerState {
+	return dc.breaker.State()
+}
+
+// probeLink sends a button state request while the breaker is not closed.
+// The request bypasses the breaker; the panel's answer closes it.
+func (dc *DisplayController) probeLink() {
+	if dc.breaker.State() == BreakerClosed {
+		return
+	}
+	if event, changed := dc.breaker.probing(); changed {
+		dc.notifyBreaker(event)
+	}
+
+	dc.writeMutex.Lock()
+	err := dc.serialPort.Write(dc.driver.RequestState())
+	dc.writeMutex.Unlock()
+
+	if err != nil {
+		if event, changed := dc.breaker.probeFailed(err); changed {
+			dc.notifyBreaker(event)
+		}
+	}
+}
+
+// notifyBreaker logs a transition and queues it for the breaker handler
+func (dc *DisplayController) notifyBreaker(event BreakerEvent) {
+	logger := dc.logger.WithFields(logrus.Fields{
+		"from":     event.From,
+		"to":       event.To,
+		"failures": event.Failures,
+	})
+	switch event.To {
+	case BreakerOpen:
+		if event.From == BreakerClosed {
+			logger.WithError(event.Err).Error("Serial link failing, pausing display writes")
+		} else {
+			logger.WithError(event.Err).Debug("Serial link probe failed")
+		}
+	case BreakerHalfOpen:
+		logger.Debug("Probing serial link")
+	case BreakerClosed:
+		logger.Info("Serial link recovered, resuming display writes")
+	}
+
+	select {
+	case dc.breakerEvents <- event:
+	default:
+		dc.logger.Warn("Circuit breaker event queue full, dropping event")
+	}
+}
+
+// dispatchBreakerEvents delivers queued transitions until the controller closes
+func (dc *DisplayController) dispatchBreakerEvents() {
+	for {
+		select {
+		case <-dc.stopChan:
+			return
+		case event := <-dc.breakerEvents:
+			// Restore the glyphs before the handler redraws the screen;
+			// a panel that came up late also missed the button setup
+			if event.To == BreakerClosed {
+				if dc.setupPending.CompareAndSwap(true, false) {
+					dc.enableButtonReporting()
+				}
+				dc.reloadGlyphs()
+			}
+
+			dc.handlerMutex.RLock()
+			handler := dc.breakerHandler
+			dc.handlerMutex.RUnlock()
+
+			if handler != nil {
+				handler(event)
+			}
+		}
+	}
+}
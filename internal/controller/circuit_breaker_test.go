@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(3)
+	writeErr := errors.New("write failed")
+
+	t.Run("Opens after consecutive failures", func(t *testing.T) {
+		_, changed := b.record(writeErr)
+		assert.False(t, changed)
+		_, changed = b.record(nil)
+		assert.False(t, changed, "a success resets the count")
+
+		b.record(writeErr)
+		b.record(writeErr)
+		event, changed := b.record(writeErr)
+		require.True(t, changed)
+		assert.Equal(t, BreakerEvent{From: BreakerClosed, To: BreakerOpen, Failures: 3, Err: writeErr}, event)
+		assert.False(t, b.allow())
+	})
+
+	t.Run("Failed probe reopens", func(t *testing.T) {
+		event, changed := b.probing()
+		require.True(t, changed)
+		assert.Equal(t, BreakerHalfOpen, event.To)
+		assert.False(t, b.allow(), "regular writes wait for the panel's answer")
+
+		event, changed = b.probeFailed(writeErr)
+		require.True(t, changed)
+		assert.Equal(t, BreakerEvent{From: BreakerHalfOpen, To: BreakerOpen, Failures: 4, Err: writeErr}, event)
+	})
+
+	t.Run("Panel response closes", func(t *testing.T) {
+		b.probing()
+		event, changed := b.responded()
+		require.True(t, changed)
+		assert.Equal(t, BreakerEvent{From: BreakerHalfOpen, To: BreakerClosed, Failures: 4}, event)
+		assert.True(t, b.allow())
+
+		_, changed = b.responded()
+		assert.False(t, changed)
+	})
+
+	assert.Equal(t, defaultErrorThreshold, newCircuitBreaker(0).threshold)
+}
+
+// waitForBreakerEvent waits for the next breaker transition or fails the test
+func waitForBreakerEvent(t *testing.T, events chan BreakerEvent) BreakerEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for circuit breaker event")
+		return BreakerEvent{}
+	}
+}
+
+func TestDisplayController_CircuitBreaker(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SerialPort.ErrorThreshold = 2
+	cfg.SerialPort.ProbeInterval = 20
+	dc, mockPort := newTestDisplayControllerWithConfig(t, cfg)
+
+	events := make(chan BreakerEvent, 64)
+	dc.SetBreakerHandler(func(event BreakerEvent) { events <- event })
+
+	mockPort.SetWriteError(assert.AnError)
+	dc.WriteText("one")
+	dc.WriteText("two")
+
+	event := waitForBreakerEvent(t, events)
+	assert.Equal(t, BreakerClosed, event.From)
+	assert.Equal(t, BreakerOpen, event.To)
+	assert.ErrorIs(t, dc.WriteText("refused"), ErrLinkDown)
+
+	// Probes keep failing while the port does
+	assert.Equal(t, BreakerHalfOpen, waitForBreakerEvent(t, events).To)
+	assert.Equal(t, BreakerOpen, waitForBreakerEvent(t, events).To)
+
+	// The port recovers and the panel answers a probe
+	mockPort.SetWriteError(nil)
+	mockPort.SetReadData([]byte{0x53, 0x05, 0x00, 0xFF})
+	for event.To != BreakerClosed {
+		event = waitForBreakerEvent(t, events)
+	}
+	assert.Equal(t, BreakerClosed, dc.LinkState())
+
+	mockPort.ClearWrittenData()
+	require.NoError(t, dc.WriteText("back"))
+	assert.Contains(t, string(mockPort.GetWrittenData()), "back")
+}
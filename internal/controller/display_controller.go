@@ -1,58 +1,114 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
 	"github.com/qnap/display-control/internal/serial"
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/qnap/display-control/pkg/buttons"
 	"github.com/sirupsen/logrus"
 )
 
-// PanelButton represents available QNAP panel buttons
-type PanelButton int
+// PanelButton represents available QNAP panel buttons. It lives in
+// pkg/buttons so other programs can use it.
+type PanelButton = buttons.Button
 
 const (
-	ButtonEnter PanelButton = iota
-	ButtonSelect
-	ButtonUSBCopy
+	ButtonEnter   = buttons.Enter
+	ButtonSelect  = buttons.Select
+	ButtonUSBCopy = buttons.Copy
 )
 
 // ButtonEventHandler is a callback function for button events
-type ButtonEventHandler func(button PanelButton, pressed bool)
+type ButtonEventHandler = buttons.Handler
 
 // DisplayController manages the LCD display
 type DisplayController struct {
-	serialPort       *serial.SerialPort
+	serialPort      serial.SerialPortInterface
+	driver          DisplayDriver // encodes the commands of the panel type
+	cols, rows      int
+	translit        *translit.Transliterator
+	writeMutex      sync.Mutex // serializes panel writes so batches arrive whole
 	config          *config.Config
 	logger          *logrus.Entry
 	buttonHandler   ButtonEventHandler
+	handlerMutex    sync.RWMutex
+	buttonEvents    chan buttonEvent // presses and releases in the order read, for the handler
 	lastButtonState map[PanelButton]bool
+	stopChan        chan struct{}
+	closeOnce       sync.Once
+	progress        progressState
+	quirks          ButtonQuirks
+	lastCopyPress   time.Time   // last copy press seen, including suppressed duplicates
+	copySuppressed  bool        // a duplicate copy press was dropped; drop its release too
+	serialCopy      atomic.Bool // report copy presses decoded from serial frames
+	breaker         *circuitBreaker
+	probeInterval   time.Duration
+	breakerHandler  BreakerEventHandler // guarded by handlerMutex
+	breakerEvents   chan BreakerEvent
+	glyphsLoaded    bool     // set once during initialization
+	glyphUpload     [][]byte // CGRAM upload commands, sent again after link recovery, guarded by glyphMutex
+	glyphMutex      sync.Mutex
+	pacing          WritePacing
+	nextWrite       time.Time // earliest time for the next paced command, guarded by writeMutex
+	acks            FrameAcks
+	answers         chan bool // acknowledgements read from the panel, true = accepted
+	frameCounters   frameCounters
+	lastAnswer      atomic.Int64 // when the panel last sent anything, in Unix nanoseconds
+	lastRead        atomic.Int64 // when monitorButtons last finished a read, in Unix nanoseconds
+	setupPending    atomic.Bool  // the panel never answered its setup, send it again once it does
+	marquees        Marquees     // text scrolled through rows by ScrollText
 }
 
-// NewDisplayController creates a new display controller
+// buttonEventQueue is how many button events may wait for a slow handler
+// before further ones are dropped
+const buttonEventQueue = 32
+
+// buttonEvent is a press or release waiting for the button handler
+type buttonEvent struct {
+	button  PanelButton
+	pressed bool
+}
+
+// defaultProgressUpdatesPerSec is used when the configuration does not set a rate
+const defaultProgressUpdatesPerSec = 2
+
+// progressState coalesces ShowProgress redraws
+type progressState struct {
+	mutex    sync.Mutex
+	lastBar  string      // bar currently shown on the panel
+	lastSent time.Time   // when the last bar was written
+	pending  string      // newest bar waiting for the rate limit to expire
+	timer    *time.Timer // fires flushProgress for the pending bar
+	// generation counts the timers armed; a flush only acts for the
+	// timer of its own generation
+	generation int
+}
+
+// NewDisplayController creates a new display controller on the configured
+// serial port, or on the I2C bus for panels using the a125-i2c profile
 func NewDisplayController(cfg *config.Config) (*DisplayController, error) {
 	logger := logrus.WithField("component", "display_controller")
 
+	if cfg.Hardware.Profile == I2CPanelProfile {
+		panel, err := openI2CPanel(cfg.Hardware)
+		if err != nil {
+			return nil, err
+		}
+		return newDisplayController(cfg, panel, startupBackoff(cfg))
+	}
+
 	serialPort, err := serial.NewSerialPort(cfg.SerialPort.Device, cfg.SerialPort.BaudRate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize serial port: %w", err)
 	}
 
-	dc := &DisplayController{
-		serialPort:      serialPort,
-		config:         cfg,
-		logger:         logger,
-		lastButtonState: make(map[PanelButton]bool),
-	}
-
-	// Initialize display
-	if err := dc.initializeDisplay(); err != nil {
-		serialPort.Close()
-		return nil, fmt.Errorf("failed to initialize display: %w", err)
-	}
-
 	// Verify serial configuration
 	if !serialPort.IsConfigValid() {
 		logger.Warn("Serial port configuration may not be optimal for QNAP display")
@@ -60,49 +116,137 @@ func NewDisplayController(cfg *config.Config) (*DisplayController, error) {
 		logger.Debug("Serial port configured with 8N1 (8 data bits, no parity, 1 stop bit)")
 	}
 
-	// Start button monitoring in background
+	return newDisplayController(cfg, serialPort, startupBackoff(cfg))
+}
+
+// NewDisplayControllerWithPort creates a display controller on top of an
+// already opened serial port (a real port or a mock for testing). It does
+// not wait for the panel to answer.
+func NewDisplayControllerWithPort(cfg *config.Config, port serial.SerialPortInterface) (*DisplayController, error) {
+	return newDisplayController(cfg, port, nil)
+}
+
+// newDisplayController creates a display controller on port. With a startup
+// backoff the panel is initialized again until it answers; without one it is
+// initialized once.
+func newDisplayController(cfg *config.Config, port serial.SerialPortInterface, startup *Backoff) (*DisplayController, error) {
+	logger := logrus.WithField("component", "display_controller")
+
+	if port == nil {
+		return nil, fmt.Errorf("serial port is required")
+	}
+
+	quirks, err := ButtonQuirksFromConfig(cfg.Hardware)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid hardware configuration, using generic profile")
+		quirks, _ = ButtonQuirksFromConfig(config.HardwareConfig{})
+	}
+
+	pacing, err := WritePacingFromConfig(cfg.Hardware)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid write pacing, sending updates unpaced")
+		pacing = WritePacing{}
+	}
+
+	acks, err := FrameAcksFromConfig(cfg.Hardware)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid acknowledgement settings, sending commands unconfirmed")
+		acks = FrameAcks{}
+	}
+
+	driver, err := openDisplayDriver(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cols, rows := driver.Geometry()
+
+	probeInterval := defaultProbeInterval
+	if cfg.SerialPort.ProbeInterval > 0 {
+		probeInterval = time.Duration(cfg.SerialPort.ProbeInterval) * time.Millisecond
+	}
+
+	dc := &DisplayController{
+		serialPort:      port,
+		driver:          driver,
+		cols:            cols,
+		rows:            rows,
+		translit:        newTransliterator(cfg, logger),
+		config:          cfg,
+		logger:          logger,
+		lastButtonState: make(map[PanelButton]bool),
+		stopChan:        make(chan struct{}),
+		quirks:          quirks,
+		pacing:          pacing,
+		acks:            acks,
+		answers:         make(chan bool, 1),
+		breaker:         newCircuitBreaker(cfg.SerialPort.ErrorThreshold),
+		probeInterval:   probeInterval,
+		breakerEvents:   make(chan BreakerEvent, 16),
+		buttonEvents:    make(chan buttonEvent, buttonEventQueue),
+	}
+	dc.serialCopy.Store(true)
+
+	// Read the panel before initializing it, acknowledgements arrive through
+	// the reader
 	go dc.monitorButtons()
 
+	// Initialize display
+	if startup == nil {
+		if err := dc.initializeDisplay(); err != nil {
+			close(dc.stopChan)
+			port.Close()
+			return nil, fmt.Errorf("failed to initialize display: %w", err)
+		}
+	} else if err := dc.initializeUntilAnswered(*startup); err != nil {
+		// Keep running: the probes find the panel once it comes up
+		logger.WithError(err).Error("Panel did not answer, display writes paused until it does")
+		dc.setupPending.Store(true)
+		dc.breaker.trip(err)
+	}
+
+	go dc.pollPanel()
+	go dc.dispatchBreakerEvents()
+	go dc.dispatchButtonEvents()
+
 	logger.Info("Display controller initialized successfully")
 	return dc, nil
 }
 
-// Close closes the display controller and cleans up resources
+// Close stops button monitoring, closes the display controller and cleans up resources
 func (dc *DisplayController) Close() error {
-	dc.logger.Info("Closing display controller")
-	if dc.serialPort != nil {
-		return dc.serialPort.Close()
-	}
-	return nil
+	var err error
+	dc.closeOnce.Do(func() {
+		dc.logger.Info("Closing display controller")
+		dc.marquees.StopAll()
+		close(dc.stopChan)
+		dc.resetProgress()
+		if dc.serialPort != nil {
+			err = dc.serialPort.Close()
+		}
+	})
+	return err
 }
 
 // initializeDisplay sets up the LCD display
 func (dc *DisplayController) initializeDisplay() error {
 	dc.logger.Debug("Initializing QNAP LCD display")
 
-	// Based on qnapctl reference: enable button state reporting
-	// Send the command to enable button state reporting first
-	buttonStateCmd := []byte{0x4D, 0x06}
-	if err := dc.serialPort.Write(buttonStateCmd); err != nil {
-		dc.logger.WithError(err).Warn("Failed to enable button state reporting")
-	} else {
-		dc.logger.Info("Button state reporting enabled successfully")
-	}
+	// Based on qnapctl reference: enable button state reporting first
+	dc.enableButtonReporting()
 
 	// Give the controller time to process the command
 	time.Sleep(100 * time.Millisecond)
 
+	dc.loadGlyphs()
+
 	// Turn on backlight using correct QNAP protocol
 	if err := dc.SetBacklight(true); err != nil {
 		dc.logger.WithError(err).Debug("Failed to turn on backlight")
 	}
 
 	// Clear both lines using correct QNAP protocol
-	if err := dc.WriteTextAt("", 0, 0); err != nil {
-		dc.logger.WithError(err).Warn("Failed to clear line 0")
-	}
-	if err := dc.WriteTextAt("", 1, 0); err != nil {
-		dc.logger.WithError(err).Warn("Failed to clear line 1")
+	if err := dc.ClearDisplay(); err != nil {
+		dc.logger.WithError(err).Warn("Failed to clear display")
 	}
 
 	// Show default text if specified
@@ -120,72 +264,56 @@ func (dc *DisplayController) initializeDisplay() error {
 	return nil
 }
 
-// WriteText writes text to the display
-func (dc *DisplayController) WriteText(text string) error {
-	dc.logger.WithField("text", text).Debug("Writing text to display")
-
-	// Split text by newlines first, then handle line wrapping
-	lines := strings.Split(text, "\n")
-	
-	// Ensure we have exactly 2 lines for the 2-line display
-	displayLines := make([]string, 2)
-	
-	// Handle the lines
-	if len(lines) >= 1 {
-		displayLines[0] = lines[0]
-	}
-	if len(lines) >= 2 {
-		displayLines[1] = lines[1]
+// enableButtonReporting sends the driver's setup, which asks the QNAP panel
+// to report button changes on its own
+func (dc *DisplayController) enableButtonReporting() {
+	setup := dc.driver.Init()
+	if len(setup) == 0 {
+		return
 	}
-	
-	// Truncate lines that are too long
-	for i := range displayLines {
-		if len(displayLines[i]) > 16 {
-			displayLines[i] = displayLines[i][:16]
-		}
+	if err := dc.write(setup); err != nil {
+		dc.logger.WithError(err).Warn("Failed to enable button state reporting")
+	} else {
+		dc.logger.Info("Button state reporting enabled successfully")
 	}
+}
 
-	// Write each line using the QNAP line command format
-	for i, line := range displayLines {
-		if err := dc.WriteTextAt(line, i, 0); err != nil {
-			return fmt.Errorf("failed to write line %d: %w", i, err)
-		}
-	}
+// WriteText writes text to the display. Both lines are sent as one update.
+func (dc *DisplayController) WriteText(text string) error {
+	dc.logger.WithField("text", text).Debug("Writing text to display")
 
-	return nil
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetText(text)
+		return nil
+	})
 }
 
 // WriteTextAt writes text at a specific position
 func (dc *DisplayController) WriteTextAt(text string, row, col int) error {
+	// Any other writer replaces the progress bar, so forget what it showed
+	if row == progressRow {
+		dc.resetProgress()
+	}
+	return dc.writeLine(text, row, col)
+}
+
+// writeLine sends a single line to the panel
+func (dc *DisplayController) writeLine(text string, row, col int) error {
 	dc.logger.WithFields(logrus.Fields{
 		"text": text,
 		"row":  row,
 		"col":  col,
 	}).Debug("Writing text at position")
 
-	// Validate row (0 or 1 for 2-line display)
-	if row < 0 || row > 1 {
-		return fmt.Errorf("invalid row: %d. Must be 0 or 1", row)
+	if row < 0 || row >= dc.rows {
+		return fmt.Errorf("invalid row: %d. Must be between 0 and %d", row, dc.rows-1)
 	}
 
-	const LCD_CHARS_PER_LINE = 16
-	
-	// Truncate and pad text to fit LCD width
-	displayText := text
-	if len(displayText) > LCD_CHARS_PER_LINE {
-		displayText = displayText[:LCD_CHARS_PER_LINE]
-	}
-	// Pad with spaces to fill the line
-	for len(displayText) < LCD_CHARS_PER_LINE {
-		displayText += " "
+	text = dc.translit.Apply(ExpandGlyphs(text))
+	if !dc.glyphsLoaded {
+		text = stripGlyphs(text)
 	}
-
-	// Use correct QNAP protocol: 0x4D, 0x0C, line, 0x10, followed by 16 characters
-	// This is the verified protocol from qnapctl reference implementation
-	command := []byte{0x4D, 0x0C, byte(row), 0x10}
-	command = append(command, []byte(displayText)...)
-
-	if err := dc.serialPort.Write(command); err != nil {
+	if err := dc.write(dc.driver.WriteLine(row, text)); err != nil {
 		dc.logger.WithError(err).WithField("line", row).Warn("Failed to write text using QNAP protocol")
 		return err
 	}
@@ -194,36 +322,31 @@ func (dc *DisplayController) WriteTextAt(text string, row, col int) error {
 	return nil
 }
 
+// ScrollText scrolls text through row, see Marquees.ScrollText
+func (dc *DisplayController) ScrollText(row int, text string, speed time.Duration) error {
+	return dc.marquees.ScrollText(dc, dc.cols, row, text, speed)
+}
+
+// StopScroll stops the marquee on row, leaving its last step shown
+func (dc *DisplayController) StopScroll(row int) {
+	dc.marquees.StopScroll(row)
+}
+
 // ClearDisplay clears the entire display
 func (dc *DisplayController) ClearDisplay() error {
 	dc.logger.Debug("Clearing display")
 
-	// Clear both lines by writing empty text to each line
-	if err := dc.WriteTextAt("", 0, 0); err != nil {
-		return fmt.Errorf("failed to clear line 0: %w", err)
-	}
-	
-	if err := dc.WriteTextAt("", 1, 0); err != nil {
-		return fmt.Errorf("failed to clear line 1: %w", err)
-	}
-
-	return nil
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetText("")
+		return nil
+	})
 }
 
 // SetBacklight controls the display backlight (if supported)
 func (dc *DisplayController) SetBacklight(on bool) error {
 	dc.logger.WithField("on", on).Debug("Setting backlight")
 
-	// Use correct QNAP protocol: 0x4D, 0x5E, on/off
-	// This is the verified protocol from qnapctl reference implementation
-	var cmd []byte
-	if on {
-		cmd = []byte{0x4D, 0x5E, 0x01} // Backlight on
-	} else {
-		cmd = []byte{0x4D, 0x5E, 0x00} // Backlight off
-	}
-
-	if err := dc.serialPort.Write(cmd); err != nil {
+	if err := dc.write(dc.driver.SetBacklight(on)); err != nil {
 		return fmt.Errorf("failed to set backlight: %w", err)
 	}
 
@@ -234,23 +357,121 @@ func (dc *DisplayController) SetBacklight(on bool) error {
 func (dc *DisplayController) ShowCopyStatus(status string) error {
 	dc.logger.WithField("status", status).Info("Showing copy status")
 
-	// Line 1: "USB Copy"
-	if err := dc.WriteTextAt("USB Copy", 0, 0); err != nil {
-		return err
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetText("USB Copy\n" + status)
+		return nil
+	})
+}
+
+// progressRow is the display line used for the progress bar
+const progressRow = 1
+
+// ShowProgress displays a progress bar (simplified).
+// Calls are coalesced: the panel is only written when the rendered bar
+// changes, and at most Display.ProgressUpdatesPerSec times per second. A bar
+// held back by the rate limit is written once the limit expires, so the last
+// reported progress always reaches the panel.
+func (dc *DisplayController) ShowProgress(percent int) error {
+	progressBar := RenderProgressBarWidth(percent, dc.cols)
+
+	dc.progress.mutex.Lock()
+	defer dc.progress.mutex.Unlock()
+
+	if progressBar == dc.progress.lastBar {
+		// Already shown; drop any older pending bar
+		dc.cancelPendingProgress()
+		return nil
+	}
+
+	interval := dc.progressInterval()
+	elapsed := time.Since(dc.progress.lastSent)
+	if elapsed < interval {
+		dc.progress.pending = progressBar
+		if dc.progress.timer == nil {
+			dc.progress.generation++
+			generation := dc.progress.generation
+			dc.progress.timer = time.AfterFunc(interval-elapsed, func() { dc.flushProgress(generation) })
+		}
+		return nil
 	}
 
-	// Line 2: Status message
-	if err := dc.WriteTextAt(status, 1, 0); err != nil {
+	dc.cancelPendingProgress()
+	return dc.sendProgress(progressBar)
+}
+
+// progressInterval returns the minimum time between two progress redraws
+func (dc *DisplayController) progressInterval() time.Duration {
+	rate := defaultProgressUpdatesPerSec
+	if dc.config != nil && dc.config.Display.ProgressUpdatesPerSec > 0 {
+		rate = dc.config.Display.ProgressUpdatesPerSec
+	}
+	return time.Second / time.Duration(rate)
+}
+
+// sendProgress writes a bar to the panel. Caller must hold the progress mutex.
+func (dc *DisplayController) sendProgress(progressBar string) error {
+	dc.logger.WithField("bar", progressBar).Debug("Showing progress")
+
+	// Show progress on second line using QNAP line command
+	if err := dc.writeLine(progressBar, progressRow, 0); err != nil {
 		return err
 	}
 
+	dc.progress.lastBar = progressBar
+	dc.progress.lastSent = time.Now()
 	return nil
 }
 
-// ShowProgress displays a progress bar (simplified)
-func (dc *DisplayController) ShowProgress(percent int) error {
-	dc.logger.WithField("percent", percent).Debug("Showing progress")
+// flushProgress writes the pending bar once the rate limit has expired. A
+// flush whose timer was cancelled, or replaced while it waited for the
+// mutex, does nothing.
+func (dc *DisplayController) flushProgress(generation int) {
+	dc.progress.mutex.Lock()
+	defer dc.progress.mutex.Unlock()
+
+	if dc.progress.timer == nil || generation != dc.progress.generation {
+		return
+	}
+	dc.progress.timer = nil
+	if dc.progress.pending == "" {
+		return
+	}
+
+	progressBar := dc.progress.pending
+	dc.progress.pending = ""
+	if err := dc.sendProgress(progressBar); err != nil {
+		dc.logger.WithError(err).Warn("Failed to write pending progress")
+	}
+}
+
+// cancelPendingProgress drops a bar waiting for the rate limit.
+// Caller must hold the progress mutex.
+func (dc *DisplayController) cancelPendingProgress() {
+	dc.progress.pending = ""
+	if dc.progress.timer != nil {
+		dc.progress.timer.Stop()
+		dc.progress.timer = nil
+	}
+}
 
+// resetProgress forgets the shown bar and drops any pending redraw
+func (dc *DisplayController) resetProgress() {
+	dc.progress.mutex.Lock()
+	defer dc.progress.mutex.Unlock()
+
+	dc.cancelPendingProgress()
+	dc.progress.lastBar = ""
+}
+
+// RenderProgressBar renders a percentage as the 16 character bar of the
+// QNAP panel
+func RenderProgressBar(percent int) string {
+	return RenderProgressBarWidth(percent, displayWidth)
+}
+
+// RenderProgressBarWidth renders a percentage as the bar ShowProgress draws
+// on a display width characters wide
+func RenderProgressBarWidth(percent, width int) string {
 	if percent < 0 {
 		percent = 0
 	}
@@ -258,8 +479,7 @@ func (dc *DisplayController) ShowProgress(percent int) error {
 		percent = 100
 	}
 
-	// Calculate progress bar width (for 16 character display)
-	barWidth := 14 // Leave space for [ ]
+	barWidth := max(width-2, 1) // Leave space for [ ]
 	filled := (percent * barWidth) / 100
 
 	progressBar := "["
@@ -272,25 +492,21 @@ func (dc *DisplayController) ShowProgress(percent int) error {
 	}
 	progressBar += "]"
 
-	// Show progress on second line using QNAP line command
-	if err := dc.WriteTextAt(progressBar, 1, 0); err != nil {
-		return err
-	}
-
-	return nil
+	return progressBar
 }
 
 // SetButtonHandler sets the callback function for button events
 func (dc *DisplayController) SetButtonHandler(handler ButtonEventHandler) {
 	dc.logger.Info("Button handler set")
+	dc.handlerMutex.Lock()
 	dc.buttonHandler = handler
+	dc.handlerMutex.Unlock()
 }
 
 // RequestButtonState manually requests current button state from the QNAP controller
 func (dc *DisplayController) RequestButtonState() error {
 	// Send button state request command
-	buttonStateRequestCmd := []byte{0x4D, 0x05}
-	if err := dc.serialPort.Write(buttonStateRequestCmd); err != nil {
+	if err := dc.write(dc.driver.RequestState()); err != nil {
 		return fmt.Errorf("failed to request button state: %w", err)
 	}
 	
@@ -298,28 +514,52 @@ func (dc *DisplayController) RequestButtonState() error {
 	return nil
 }
 
-// monitorButtons monitors button presses in the background
-func (dc *DisplayController) monitorButtons() {
-	dc.logger.Info("Starting button monitoring")
-
-	// Buffer to accumulate partial messages
-	messageBuffer := make([]byte, 0, 32)
-	
+// pollPanel periodically requests the button state and probes the link
+// while the circuit breaker is open. It runs apart from monitorButtons, so
+// a request waiting for its acknowledgement never stops the reader.
+func (dc *DisplayController) pollPanel() {
 	// Timer for periodic button state requests
 	buttonRequestTicker := time.NewTicker(500 * time.Millisecond)
 	defer buttonRequestTicker.Stop()
 
+	// Timer for probing the link while the circuit breaker is open
+	probeTicker := time.NewTicker(dc.probeInterval)
+	defer probeTicker.Stop()
+
 	for {
 		select {
+		case <-dc.stopChan:
+			return
+
 		case <-buttonRequestTicker.C:
 			// Periodically request button state to ensure we get updates
 			if err := dc.RequestButtonState(); err != nil {
 				dc.logger.WithError(err).Debug("Failed to request button state")
 			}
-			
+
+		case <-probeTicker.C:
+			dc.probeLink()
+		}
+	}
+}
+
+// monitorButtons reads the panel in the background and handles its frames
+func (dc *DisplayController) monitorButtons() {
+	dc.logger.Info("Starting button monitoring")
+
+	// Buffer to accumulate partial messages
+	messageBuffer := make([]byte, 0, 32)
+
+	for {
+		select {
+		case <-dc.stopChan:
+			dc.logger.Info("Button monitoring stopped")
+			return
+
 		default:
 			// Use ReadAvailable for non-blocking read
 			data, err := dc.serialPort.ReadAvailable()
+			dc.lastRead.Store(time.Now().UnixNano())
 			if err != nil {
 				dc.logger.WithError(err).Debug("Error reading button data")
 				time.Sleep(50 * time.Millisecond)
@@ -331,6 +571,12 @@ func (dc *DisplayController) monitorButtons() {
 				continue
 			}
 
+			// Anything from the panel proves the link works
+			dc.lastAnswer.Store(time.Now().UnixNano())
+			if event, changed := dc.breaker.responded(); changed {
+				dc.notifyBreaker(event)
+			}
+
 			// Append new data to buffer
 			messageBuffer = append(messageBuffer, data...)
 
@@ -351,76 +597,96 @@ func (dc *DisplayController) monitorButtons() {
 	}
 }
 
+// LastRead returns when the button monitor last finished reading the
+// panel, whether or not anything arrived; zero before the first read. It
+// stops advancing when a read hangs in the driver.
+func (dc *DisplayController) LastRead() time.Time {
+	nanos := dc.lastRead.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
 // processMessageBuffer processes accumulated data for complete button messages
 func (dc *DisplayController) processMessageBuffer(buffer *[]byte) {
-	for len(*buffer) >= 4 {
-		// Look for standard button message: 0x53, 0x05, 0x00, button_state
-		if (*buffer)[0] == 0x53 && (*buffer)[1] == 0x05 && (*buffer)[2] == 0x00 {
-			buttonState := (*buffer)[3]
-			dc.logger.WithField("button_state", fmt.Sprintf("0x%02x", buttonState)).Info("Parsing button state")
-			dc.parseButtonState(buttonState)
-			
-			// Remove processed message from buffer
-			*buffer = (*buffer)[4:]
-			continue
+	for len(*buffer) > 0 {
+		consumed := dc.parseFrame(*buffer)
+		if consumed == 0 {
+			// Incomplete frame, wait for more data
+			break
 		}
-		
-		// Look for alternative button message formats
-		if (*buffer)[0] == 0x4D {
-			// QNAP command response - might contain button info
-			if len(*buffer) >= 3 {
-				dc.logger.WithField("qnap_response", fmt.Sprintf("% 02x", (*buffer)[:3])).Debug("QNAP response received")
-				// Remove this message
-				*buffer = (*buffer)[3:]
-				continue
-			}
+		*buffer = (*buffer)[consumed:]
+	}
+
+	// Prevent buffer from growing too large
+	if len(*buffer) > 16 {
+		dc.logger.Warn("Message buffer too large, clearing")
+		*buffer = (*buffer)[:0]
+	}
+}
+
+// parseFrame handles the frame at the start of buffer and returns how many
+// bytes it used, or 0 if the frame is not complete yet
+func (dc *DisplayController) parseFrame(buffer []byte) int {
+	frame, n, err := dc.driver.DecodeFrame(buffer)
+	switch frame := frame.(type) {
+	case nil:
+	case qnapproto.ButtonState:
+		dc.logger.WithField("button_state", fmt.Sprintf("0x%02x", frame.State)).Info("Parsing button state")
+		dc.parseButtonState(frame.State)
+		return n
+	default:
+		// Command responses carry nothing the controller needs
+		dc.logger.WithField("qnap_response", fmt.Sprintf("% 02x", frame.Encode())).Debug("QNAP response received")
+		return n
+	}
+	if errors.Is(err, qnapproto.ErrShort) {
+		return 0
+	}
+
+	switch {
+	case dc.acks.Enabled && buffer[0] == dc.acks.Ack:
+		dc.deliverAnswer(true)
+		return 1
+
+	case dc.acks.HasNack && buffer[0] == dc.acks.Nack:
+		dc.logger.Debug("Panel rejected a command")
+		dc.deliverAnswer(false)
+		return 1
+
+	case dc.quirks.isCopyFramePrefix(buffer[0]):
+		// Separate copy button frame used by some firmware
+		if len(buffer) < dc.quirks.CopyFrameLength {
+			return 0
 		}
-		
-		// Look for copy button specific message (may use different protocol)
-		if (*buffer)[0] == 0x55 || (*buffer)[0] == 0x43 { // 'U' or 'C' for USB/Copy
-			if len(*buffer) >= 2 {
-				dc.logger.WithField("copy_message", fmt.Sprintf("% 02x", (*buffer)[:2])).Info("Potential copy button message")
-				// Parse as copy button press
-				dc.triggerButtonEvent(ButtonUSBCopy, true)
-				time.Sleep(100 * time.Millisecond) // Debounce
-				dc.triggerButtonEvent(ButtonUSBCopy, false)
-				*buffer = (*buffer)[2:]
-				continue
-			}
+		dc.logger.WithField("copy_message", fmt.Sprintf("% 02x", buffer[:dc.quirks.CopyFrameLength])).Debug("Copy button frame received")
+		if !dc.serialCopy.Load() {
+			return dc.quirks.CopyFrameLength
 		}
-		
-		// If we don't recognize the message, remove first byte and try again
-		dc.logger.WithField("unknown_byte", fmt.Sprintf("0x%02x", (*buffer)[0])).Debug("Unknown message byte, discarding")
-		*buffer = (*buffer)[1:]
-		
-		// Prevent buffer from growing too large
-		if len(*buffer) > 16 {
-			dc.logger.Warn("Message buffer too large, clearing")
-			*buffer = (*buffer)[:0]
-			break
+		if !dc.acceptCopyPress(true) {
+			// The frame carries no release, so there is none to drop
+			dc.copySuppressed = false
+			return dc.quirks.CopyFrameLength
 		}
+		dc.triggerButtonEvent(ButtonUSBCopy, true)
+		time.Sleep(100 * time.Millisecond) // Debounce
+		dc.triggerButtonEvent(ButtonUSBCopy, false)
+		return dc.quirks.CopyFrameLength
 	}
+
+	// Unrecognized byte, drop it and resynchronize on the next one
+	dc.logger.WithField("unknown_byte", fmt.Sprintf("0x%02x", buffer[0])).Debug("Unknown message byte, discarding")
+	return 1
 }
 
 // parseButtonState parses the button state byte and triggers events
 func (dc *DisplayController) parseButtonState(state byte) {
-	// Based on qnapctl reference, button bits are:
-	// Bit 0 (0x01): ENTER button (inverted logic - 0 = pressed)
-	// Bit 1 (0x02): SELECT button (inverted logic - 0 = pressed)  
-	// Bit 2 (0x04): USB COPY button (may use different logic)
-	
-	const (
-		buttonEnterBit  = 0x01
-		buttonSelectBit = 0x02
-		buttonUSBCopyBit = 0x04
-	)
-
-	// QNAP uses inverted logic for ENTER and SELECT buttons (0 = pressed)
-	enterPressed := (state & buttonEnterBit) == 0
-	selectPressed := (state & buttonSelectBit) == 0
-	
-	// USB copy button may use normal logic (1 = pressed) - test both
-	usbCopyPressed := (state & buttonUSBCopyBit) != 0
+	// Bit positions and polarity come from the hardware profile; by default
+	// ENTER is bit 0 and SELECT bit 1 (both 0 = pressed), USB COPY bit 2 (1 = pressed)
+	enterPressed := dc.quirks.enterPressed(state)
+	selectPressed := dc.quirks.selectPressed(state)
+	usbCopyPressed := dc.quirks.copyPressed(state)
 
 	dc.logger.WithFields(logrus.Fields{
 		"state_hex":      fmt.Sprintf("0x%02x", state),
@@ -439,11 +705,54 @@ func (dc *DisplayController) parseButtonState(state byte) {
 		dc.triggerButtonEvent(ButtonSelect, selectPressed)
 	}
 
-	if dc.checkButtonStateChange(ButtonUSBCopy, usbCopyPressed) {
-		dc.triggerButtonEvent(ButtonUSBCopy, usbCopyPressed)
+	if dc.quirks.CopyInStateFrame && dc.serialCopy.Load() && dc.checkButtonStateChange(ButtonUSBCopy, usbCopyPressed) {
+		if dc.acceptCopyPress(usbCopyPressed) {
+			dc.triggerButtonEvent(ButtonUSBCopy, usbCopyPressed)
+		}
 	}
 }
 
+// SetSerialCopyDetection enables or disables reporting the copy button from
+// serial frames. It is disabled while the copy button is read from the I/O
+// port, so a press is not reported twice.
+func (dc *DisplayController) SetSerialCopyDetection(enabled bool) {
+	dc.serialCopy.Store(enabled)
+	dc.logger.WithFields(logrus.Fields{
+		"enabled": enabled,
+		"profile": dc.quirks.Profile,
+	}).Info("Serial copy button detection changed")
+}
+
+// SerialCopyDetection reports whether the copy button is decoded from serial frames
+func (dc *DisplayController) SerialCopyDetection() bool {
+	return dc.serialCopy.Load()
+}
+
+// acceptCopyPress applies the duplicate suppression window to a copy button
+// transition. A press within the window of the previous one is dropped along
+// with its release. Dropped presses restart the window, so frames repeated
+// for as long as the button is held count as a single press.
+func (dc *DisplayController) acceptCopyPress(pressed bool) bool {
+	if !pressed {
+		if dc.copySuppressed {
+			dc.copySuppressed = false
+			return false
+		}
+		return true
+	}
+
+	now := time.Now()
+	last := dc.lastCopyPress
+	dc.lastCopyPress = now
+
+	if dc.quirks.CopyDuplicateWindow > 0 && !last.IsZero() && now.Sub(last) < dc.quirks.CopyDuplicateWindow {
+		dc.logger.WithField("profile", dc.quirks.Profile).Debug("Suppressing duplicate copy button press")
+		dc.copySuppressed = true
+		return false
+	}
+	return true
+}
+
 // checkButtonStateChange checks if a button state has changed
 func (dc *DisplayController) checkButtonStateChange(button PanelButton, pressed bool) bool {
 	lastState, exists := dc.lastButtonState[button]
@@ -456,36 +765,57 @@ func (dc *DisplayController) checkButtonStateChange(button PanelButton, pressed
 
 // triggerButtonEvent triggers a button event if handler is set
 func (dc *DisplayController) triggerButtonEvent(button PanelButton, pressed bool) {
-	buttonName := ""
-	switch button {
-	case ButtonEnter:
-		buttonName = "ENTER"
-	case ButtonSelect:
-		buttonName = "SELECT"
-	case ButtonUSBCopy:
-		buttonName = "USB_COPY"
-	default:
-		buttonName = "UNKNOWN"
-	}
+	dc.handlerMutex.RLock()
+	handler := dc.buttonHandler
+	dc.handlerMutex.RUnlock()
 
 	dc.logger.WithFields(logrus.Fields{
-		"button":      buttonName,
+		"button":      button.String(),
 		"button_id":   int(button),
 		"pressed":     pressed,
-		"has_handler": dc.buttonHandler != nil,
+		"has_handler": handler != nil,
 	}).Info("Button event triggered")
 
-	if dc.buttonHandler != nil {
-		// Call handler in a separate goroutine to prevent blocking
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					dc.logger.WithField("panic", r).Error("Button handler panicked")
-				}
-			}()
-			dc.buttonHandler(button, pressed)
-		}()
-	} else {
+	if handler == nil {
 		dc.logger.Warn("No button handler set - button event ignored")
+		return
 	}
+
+	// The handler runs on the dispatch goroutine so a slow one does not hold
+	// up reading the panel, and sees the events in the order they were read
+	select {
+	case dc.buttonEvents <- buttonEvent{button: button, pressed: pressed}:
+	default:
+		dc.logger.WithField("button", button.String()).Warn("Button event queue full, dropping event")
+	}
+}
+
+// dispatchButtonEvents calls the button handler with the queued events, one
+// after the other, until the controller closes
+func (dc *DisplayController) dispatchButtonEvents() {
+	for {
+		select {
+		case <-dc.stopChan:
+			return
+		case event := <-dc.buttonEvents:
+			dc.handlerMutex.RLock()
+			handler := dc.buttonHandler
+			dc.handlerMutex.RUnlock()
+
+			if handler != nil {
+				dc.callButtonHandler(handler, event)
+			}
+		}
+	}
+}
+
+// callButtonHandler calls handler with event, logging a panic instead of
+// ending the dispatch goroutine
+func (dc *DisplayController) callButtonHandler(handler ButtonEventHandler, event buttonEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			dc.logger.WithField("panic", r).Error("Button handler panicked")
+		}
+	}()
+	handler(event.button, event.pressed)
 }
@@ -1,26 +1,62 @@
 package controller
 
 import (
+	"bytes"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// MockSerialPort for testing
-type MockSerialPort struct {
-	mock.Mock
+// newTestDisplayController creates a controller backed by a mock serial port
+func newTestDisplayController(t *testing.T) (*DisplayController, *serial.MockSerialPort) {
+	t.Helper()
+	return newTestDisplayControllerWithConfig(t, config.DefaultConfig())
 }
 
-func (m *MockSerialPort) Write(data []byte) error {
-	args := m.Called(data)
-	return args.Error(0)
+// newTestDisplayControllerWithConfig creates a mock-backed controller for a specific configuration
+func newTestDisplayControllerWithConfig(t *testing.T, cfg *config.Config) (*DisplayController, *serial.MockSerialPort) {
+	t.Helper()
+
+	mockPort := serial.NewMockSerialPort()
+	dc, err := NewDisplayControllerWithPort(cfg, mockPort)
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+
+	mockPort.ClearWrittenData()
+	return dc, mockPort
+}
+
+// lineCommand builds the expected QNAP line command for a padded line
+func lineCommand(row int, text string) []byte {
+	cmd := []byte{0x4D, 0x0C, byte(row), byte(len(text))}
+	return append(cmd, []byte(text)...)
 }
 
-func (m *MockSerialPort) Close() error {
-	args := m.Called()
-	return args.Error(0)
+// collectButtonEvents installs a handler that forwards events to a channel
+func collectButtonEvents(dc *DisplayController) chan buttonEvent {
+	events := make(chan buttonEvent, 16)
+	dc.SetButtonHandler(func(button PanelButton, pressed bool) {
+		events <- buttonEvent{button: button, pressed: pressed}
+	})
+	return events
+}
+
+// waitForEvent waits for the next button event or fails the test
+func waitForEvent(t *testing.T, events chan buttonEvent) buttonEvent {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for button event")
+		return buttonEvent{}
+	}
 }
 
 func TestNewDisplayController(t *testing.T) {
@@ -54,57 +90,540 @@ func TestNewDisplayController(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewDisplayController(tt.config)
+			dc, err := NewDisplayController(tt.config)
 			if tt.expectError {
 				assert.Error(t, err)
 			} else {
 				// Note: This test may fail if no actual serial port is available
-				// In a real test environment, you'd mock the serial port
 				if err != nil {
 					t.Logf("Expected test to pass but got error (may be due to missing hardware): %v", err)
+				} else {
+					dc.Close()
 				}
 			}
 		})
 	}
 }
 
-func TestDisplayController_WriteText(t *testing.T) {
-	// This test would require a mock serial port implementation
-	// For now, we'll test the error cases
-	
-	t.Run("Nil controller", func(t *testing.T) {
-		var dc *DisplayController
-		// Skip nil test that causes panic
-		if dc == nil {
-			t.Skip("Skipping nil controller test to avoid panic")
-		}
+func TestNewDisplayControllerWithPort(t *testing.T) {
+	t.Run("Nil port", func(t *testing.T) {
+		_, err := NewDisplayControllerWithPort(config.DefaultConfig(), nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Initialization sequence", func(t *testing.T) {
+		mockPort := serial.NewMockSerialPort()
+		dc, err := NewDisplayControllerWithPort(config.DefaultConfig(), mockPort)
+		require.NoError(t, err)
+		defer dc.Close()
+
+		written := mockPort.GetWrittenData()
+
+		// Button state reporting must be enabled before anything else
+		assert.True(t, bytes.HasPrefix(written, []byte{0x4D, 0x06}))
+		assert.True(t, bytes.Contains(written, []byte{0x4D, 0x5E, 0x01}), "backlight should be switched on")
+		assert.True(t, bytes.Contains(written, lineCommand(0, "                ")), "line 0 should be cleared")
+		assert.True(t, bytes.Contains(written, lineCommand(1, "                ")), "line 1 should be cleared")
+		assert.True(t, bytes.Contains(written, lineCommand(0, "QNAP Ready      ")), "default text should be shown")
+	})
+
+	t.Run("Test message without default text", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Display.DefaultText = ""
+
+		mockPort := serial.NewMockSerialPort()
+		dc, err := NewDisplayControllerWithPort(cfg, mockPort)
+		require.NoError(t, err)
+		defer dc.Close()
+
+		written := mockPort.GetWrittenData()
+		assert.True(t, bytes.Contains(written, lineCommand(0, "QNAP Display    ")))
+		assert.True(t, bytes.Contains(written, lineCommand(1, "Ready           ")))
+	})
+
+	t.Run("Write errors are not fatal", func(t *testing.T) {
+		mockPort := serial.NewMockSerialPort()
+		mockPort.SetWriteError(assert.AnError)
+
+		dc, err := NewDisplayControllerWithPort(config.DefaultConfig(), mockPort)
+		require.NoError(t, err)
+		dc.Close()
 	})
 }
 
-func TestDisplayController_ClearDisplay(t *testing.T) {
-	// Similar to WriteText, this would need mock implementation
-	t.Run("Nil controller", func(t *testing.T) {
-		var dc *DisplayController
-		if dc == nil {
-			t.Skip("Skipping nil controller test to avoid panic")
-		}
+func TestDisplayController_Close(t *testing.T) {
+	mockPort := serial.NewMockSerialPort()
+	dc, err := NewDisplayControllerWithPort(config.DefaultConfig(), mockPort)
+	require.NoError(t, err)
+
+	assert.NoError(t, dc.Close())
+	assert.False(t, mockPort.IsOpen())
+
+	// Second close should be a no-op
+	assert.NoError(t, dc.Close())
+}
+
+func TestDisplayController_WriteTextAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		row      int
+		expected []byte
+	}{
+		{
+			name:     "Short text is padded",
+			text:     "Hello",
+			row:      0,
+			expected: lineCommand(0, "Hello           "),
+		},
+		{
+			name:     "Long text is truncated",
+			text:     "This text is far too long",
+			row:      1,
+			expected: lineCommand(1, "This text is far"),
+		},
+		{
+			name:     "Empty text clears the line",
+			text:     "",
+			row:      1,
+			expected: lineCommand(1, "                "),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc, mockPort := newTestDisplayController(t)
+
+			require.NoError(t, dc.WriteTextAt(tt.text, tt.row, 0))
+			assert.True(t, bytes.HasPrefix(mockPort.GetWrittenData(), tt.expected))
+		})
+	}
+
+	t.Run("Invalid row", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		assert.Error(t, dc.WriteTextAt("Test", 2, 0))
+		assert.Error(t, dc.WriteTextAt("Test", -1, 0))
+		assert.False(t, bytes.Contains(mockPort.GetWrittenData(), []byte("Test")))
+	})
+
+	t.Run("Write error", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+		mockPort.SetWriteError(assert.AnError)
+
+		assert.Error(t, dc.WriteTextAt("Test", 0, 0))
 	})
 }
 
+func TestDisplayController_WriteText(t *testing.T) {
+	dc, mockPort := newTestDisplayController(t)
+
+	require.NoError(t, dc.WriteText("Line 1\nLine 2\nLine 3"))
+
+	written := mockPort.GetWrittenData()
+	assert.True(t, bytes.Contains(written, lineCommand(0, "Line 1          ")))
+	assert.True(t, bytes.Contains(written, lineCommand(1, "Line 2          ")))
+	assert.False(t, bytes.Contains(written, []byte("Line 3")))
+}
+
+func TestDisplayController_Geometry(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Display.Width, cfg.Display.Height = 20, 4
+	dc, mockPort := newTestDisplayControllerWithConfig(t, cfg)
+
+	require.NoError(t, dc.WriteText("Line 1\nLine 2\nLine 3\nLine 4 is just too long"))
+	written := mockPort.GetWrittenData()
+	assert.True(t, bytes.Contains(written, lineCommand(0, "Line 1              ")))
+	assert.True(t, bytes.Contains(written, lineCommand(2, "Line 3              ")))
+	assert.True(t, bytes.Contains(written, lineCommand(3, "Line 4 is just too l")))
+
+	mockPort.ClearWrittenData()
+	require.NoError(t, dc.WriteTextAt("Bottom", 3, 0))
+	assert.True(t, bytes.Contains(mockPort.GetWrittenData(), lineCommand(3, "Bottom              ")))
+	assert.Error(t, dc.WriteTextAt("Below", 4, 0))
+
+	mockPort.ClearWrittenData()
+	require.NoError(t, dc.ShowProgress(50))
+	assert.True(t, bytes.Contains(mockPort.GetWrittenData(), lineCommand(1, "[=========         ]")),
+		"the bar spans the width")
+}
+
+func TestDisplayController_Transliteration(t *testing.T) {
+	dc, mockPort := newTestDisplayController(t)
+
+	// Multi-byte characters would be cut and padded wrong as bytes
+	require.NoError(t, dc.WriteText("Größe 21°C\n↑ 5 Mbit/s"))
+	written := mockPort.GetWrittenData()
+	assert.True(t, bytes.Contains(written, lineCommand(0, "Grosse 21oC     ")))
+	assert.True(t, bytes.Contains(written, lineCommand(1, "^ 5 Mbit/s      ")))
+
+	mockPort.ClearWrittenData()
+	require.NoError(t, dc.WriteTextAt("Café", 1, 0))
+	assert.Equal(t, lineCommand(1, "Cafe            "), mockPort.GetWrittenData())
+
+	// Glyph replacements are dropped while the panel has no glyphs
+	cfg := config.DefaultConfig()
+	cfg.Display.Transliteration.Classes = map[string]string{"arrows": "{bar3}"}
+	dc, mockPort = newTestDisplayControllerWithConfig(t, cfg)
+	require.NoError(t, dc.WriteTextAt("↑ up", 0, 0))
+	assert.Equal(t, lineCommand(0, " up             "), mockPort.GetWrittenData())
+
+	// Languages spell their letters, and custom characters replace others
+	cfg = config.DefaultConfig()
+	cfg.Display.Transliteration.Language = "de"
+	cfg.Display.Transliteration.Characters = map[string]string{"°": "{slot6}"}
+	dc, mockPort = newTestDisplayControllerWithConfig(t, cfg)
+	require.NoError(t, dc.WriteTextAt("Größe 21°C", 0, 0))
+	assert.Equal(t, lineCommand(0, "Groesse 21C     "), mockPort.GetWrittenData(), "glyphs are dropped without a glyph command")
+}
+
+func TestDisplayController_ClearDisplay(t *testing.T) {
+	dc, mockPort := newTestDisplayController(t)
+
+	require.NoError(t, dc.ClearDisplay())
+
+	written := mockPort.GetWrittenData()
+	assert.True(t, bytes.Contains(written, lineCommand(0, "                ")))
+	assert.True(t, bytes.Contains(written, lineCommand(1, "                ")))
+}
+
+func TestDisplayController_SetBacklight(t *testing.T) {
+	dc, mockPort := newTestDisplayController(t)
+
+	require.NoError(t, dc.SetBacklight(false))
+	assert.True(t, bytes.HasPrefix(mockPort.GetWrittenData(), []byte{0x4D, 0x5E, 0x00}))
+
+	mockPort.SetWriteError(assert.AnError)
+	assert.Error(t, dc.SetBacklight(true))
+}
+
 func TestDisplayController_ShowCopyStatus(t *testing.T) {
-	t.Run("Nil controller", func(t *testing.T) {
-		var dc *DisplayController
-		if dc == nil {
-			t.Skip("Skipping nil controller test to avoid panic")
-		}
+	dc, mockPort := newTestDisplayController(t)
+
+	require.NoError(t, dc.ShowCopyStatus("Copying..."))
+
+	written := mockPort.GetWrittenData()
+	assert.True(t, bytes.Contains(written, lineCommand(0, "USB Copy        ")))
+	assert.True(t, bytes.Contains(written, lineCommand(1, "Copying...      ")))
+}
+
+// countingPort records every Write call separately, and when it happened
+type countingPort struct {
+	*serial.MockSerialPort
+	writes [][]byte
+	times  []time.Time
+	mutex  sync.Mutex
+}
+
+func (p *countingPort) Write(data []byte) error {
+	p.mutex.Lock()
+	p.writes = append(p.writes, append([]byte(nil), data...))
+	p.times = append(p.times, time.Now())
+	p.mutex.Unlock()
+	return p.MockSerialPort.Write(data)
+}
+
+func TestDisplayController_Update(t *testing.T) {
+	t.Run("Lines and backlight are sent in one write", func(t *testing.T) {
+		port := &countingPort{MockSerialPort: serial.NewMockSerialPort()}
+		dc, err := NewDisplayControllerWithPort(config.DefaultConfig(), port)
+		require.NoError(t, err)
+		t.Cleanup(func() { dc.Close() })
+		port.mutex.Lock()
+		port.writes = nil
+		port.mutex.Unlock()
+
+		require.NoError(t, dc.Update(func(update *DisplayUpdate) error {
+			update.SetBacklight(true)
+			update.SetText("Line 1\nLine 2")
+			return nil
+		}))
+
+		expected := append(lineCommand(0, "Line 1          "), lineCommand(1, "Line 2          ")...)
+		expected = append(expected, 0x4D, 0x5E, 0x01)
+		port.mutex.Lock()
+		defer port.mutex.Unlock()
+		require.Len(t, port.writes, 1)
+		assert.Equal(t, expected, port.writes[0], "backlight turns on after the new lines")
+	})
+
+	t.Run("Backlight turns off before the lines change", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		require.NoError(t, dc.Update(func(update *DisplayUpdate) error {
+			update.SetBacklight(false)
+			return update.SetLine(1, "Bye")
+		}))
+
+		expected := append([]byte{0x4D, 0x5E, 0x00}, lineCommand(1, "Bye             ")...)
+		assert.Equal(t, expected, mockPort.GetWrittenData())
+	})
+
+	t.Run("Failed composition sends nothing", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		err := dc.Update(func(update *DisplayUpdate) error {
+			update.SetText("Half\ndone")
+			return update.SetLine(2, "Bad row")
+		})
+		assert.Error(t, err)
+		assert.Empty(t, mockPort.GetWrittenData())
+	})
+
+	t.Run("WriteLines only touches the given rows", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		require.NoError(t, dc.WriteLines(map[int]string{1: "Second"}))
+		assert.Equal(t, lineCommand(1, "Second          "), mockPort.GetWrittenData())
 	})
 }
 
 func TestDisplayController_ShowProgress(t *testing.T) {
-	t.Run("Nil controller", func(t *testing.T) {
-		var dc *DisplayController
-		if dc == nil {
-			t.Skip("Skipping nil controller test to avoid panic")
+	tests := []struct {
+		name     string
+		percent  int
+		expected string
+	}{
+		{"Zero", 0, "[              ]"},
+		{"Half", 50, "[=======       ]"},
+		{"Full", 100, "[==============]"},
+		{"Clamped below", -10, "[              ]"},
+		{"Clamped above", 150, "[==============]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc, mockPort := newTestDisplayController(t)
+
+			require.NoError(t, dc.ShowProgress(tt.percent))
+			assert.True(t, bytes.HasPrefix(mockPort.GetWrittenData(), lineCommand(1, tt.expected)))
+		})
+	}
+}
+
+func TestDisplayController_ShowProgressCoalescing(t *testing.T) {
+	progressWrites := func(mockPort *serial.MockSerialPort) int {
+		return bytes.Count(mockPort.GetWrittenData(), []byte{0x4D, 0x0C, 0x01, 0x10})
+	}
+
+	t.Run("Unchanged bar is not redrawn", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		require.NoError(t, dc.ShowProgress(50))
+		require.NoError(t, dc.ShowProgress(50))
+		// 51% renders the same bar as 50%
+		require.NoError(t, dc.ShowProgress(51))
+		assert.Equal(t, 1, progressWrites(mockPort))
+	})
+
+	t.Run("Rapid updates are rate limited", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+		dc.config.Display.ProgressUpdatesPerSec = 10
+
+		for percent := 0; percent <= 100; percent += 10 {
+			require.NoError(t, dc.ShowProgress(percent))
 		}
+
+		// Only the first bar goes out immediately
+		assert.Equal(t, 1, progressWrites(mockPort))
+		assert.True(t, bytes.HasSuffix(mockPort.GetWrittenData(), lineCommand(1, "[              ]")))
+
+		// The newest bar is flushed once the limit expires; the ones in between are dropped
+		assert.Eventually(t, func() bool { return progressWrites(mockPort) == 2 }, time.Second, 10*time.Millisecond)
+		assert.True(t, bytes.Contains(mockPort.GetWrittenData(), lineCommand(1, "[==============]")))
+
+		time.Sleep(200 * time.Millisecond)
+		assert.Equal(t, 2, progressWrites(mockPort))
+	})
+
+	t.Run("Returning to the shown bar cancels the pending one", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+		dc.config.Display.ProgressUpdatesPerSec = 10
+
+		require.NoError(t, dc.ShowProgress(0))
+		require.NoError(t, dc.ShowProgress(50))
+		require.NoError(t, dc.ShowProgress(0))
+
+		time.Sleep(200 * time.Millisecond)
+		assert.Equal(t, 1, progressWrites(mockPort))
+	})
+
+	t.Run("A late flush leaves the next timer alone", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+		dc.config.Display.ProgressUpdatesPerSec = 10
+
+		require.NoError(t, dc.ShowProgress(0))
+		require.NoError(t, dc.ShowProgress(50))
+
+		// The timer fires at the interval boundary, but its flush waits for
+		// the mutex while ShowProgress sends a bar and arms the next timer
+		dc.progress.mutex.Lock()
+		dc.progress.timer.Stop()
+		late := dc.progress.generation
+		dc.progress.mutex.Unlock()
+		time.Sleep(dc.progressInterval())
+		require.NoError(t, dc.ShowProgress(100))
+		require.NoError(t, dc.ShowProgress(50))
+		assert.Equal(t, 2, progressWrites(mockPort))
+
+		dc.flushProgress(late)
+		assert.Equal(t, 2, progressWrites(mockPort), "the pending bar waits for the interval")
+		assert.Eventually(t, func() bool { return progressWrites(mockPort) == 3 }, time.Second, 10*time.Millisecond,
+			"the next timer still writes it")
+		assert.True(t, bytes.HasSuffix(mockPort.GetWrittenData(), lineCommand(1, "[=======       ]")))
+	})
+
+	t.Run("Other writers reset the shown bar", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		require.NoError(t, dc.ShowProgress(50))
+		require.NoError(t, dc.WriteTextAt("Status", 1, 0))
+		dc.progress.lastSent = time.Time{}
+		require.NoError(t, dc.ShowProgress(50))
+
+		// Bar, status text and the bar again
+		assert.Equal(t, 3, progressWrites(mockPort))
 	})
 }
+
+func TestDisplayController_RequestButtonState(t *testing.T) {
+	dc, mockPort := newTestDisplayController(t)
+
+	require.NoError(t, dc.RequestButtonState())
+	assert.True(t, bytes.Contains(mockPort.GetWrittenData(), []byte{0x4D, 0x05}))
+}
+
+func TestDisplayController_ProcessMessageBuffer(t *testing.T) {
+	t.Run("ENTER press and release", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		events := collectButtonEvents(dc)
+
+		// All bits high: nothing pressed except the non-inverted copy bit
+		buffer := []byte{0x53, 0x05, 0x00, 0xFB}
+		dc.processMessageBuffer(&buffer)
+		assert.Empty(t, buffer)
+		for i := 0; i < 3; i++ {
+			waitForEvent(t, events)
+		}
+
+		// ENTER pressed (bit 0 low)
+		buffer = []byte{0x53, 0x05, 0x00, 0xFA}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonEnter, true}, waitForEvent(t, events))
+
+		// ENTER released
+		buffer = []byte{0x53, 0x05, 0x00, 0xFB}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonEnter, false}, waitForEvent(t, events))
+	})
+
+	t.Run("Repeated state does not re-trigger", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x53, 0x05, 0x00, 0xF9, 0x53, 0x05, 0x00, 0xF9}
+		dc.processMessageBuffer(&buffer)
+		for i := 0; i < 3; i++ {
+			waitForEvent(t, events)
+		}
+
+		select {
+		case ev := <-events:
+			t.Fatalf("unexpected event %+v", ev)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Events reach a slow handler in order", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		dc.lastButtonState[ButtonUSBCopy] = false
+		events := make(chan buttonEvent, 16)
+		dc.SetButtonHandler(func(button PanelButton, pressed bool) {
+			time.Sleep(5 * time.Millisecond)
+			events <- buttonEvent{button: button, pressed: pressed}
+		})
+
+		// Quick taps of ENTER and SELECT
+		for _, frame := range []byte{0xFA, 0xFB, 0xF9, 0xFB, 0xFA, 0xFB} {
+			buffer := []byte{0x53, 0x05, 0x00, frame}
+			dc.processMessageBuffer(&buffer)
+		}
+
+		for _, want := range []buttonEvent{
+			{ButtonEnter, true}, {ButtonEnter, false},
+			{ButtonSelect, true}, {ButtonSelect, false},
+			{ButtonEnter, true}, {ButtonEnter, false},
+		} {
+			assert.Equal(t, want, waitForEvent(t, events))
+		}
+	})
+
+	t.Run("SELECT press after leading garbage", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		dc.lastButtonState[ButtonUSBCopy] = false
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x00, 0x53, 0x05, 0x00, 0xF9}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonSelect, true}, waitForEvent(t, events))
+		assert.Empty(t, buffer)
+	})
+
+	t.Run("QNAP command response is skipped", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+
+		buffer := []byte{0x4D, 0x01, 0x02, 0x53}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, []byte{0x53}, buffer)
+	})
+
+	t.Run("Copy button frame", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x55, 0x01, 0x00, 0x00}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonUSBCopy, true}, waitForEvent(t, events))
+		assert.Equal(t, buttonEvent{ButtonUSBCopy, false}, waitForEvent(t, events))
+	})
+
+	t.Run("Incomplete frame is kept", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+
+		buffer := []byte{0x53, 0x05, 0x00}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, []byte{0x53, 0x05, 0x00}, buffer)
+	})
+}
+
+func TestDisplayController_MonitorButtons(t *testing.T) {
+	dc, mockPort := newTestDisplayController(t)
+	dc.lastButtonState[ButtonEnter] = false
+	dc.lastButtonState[ButtonSelect] = false
+	dc.lastButtonState[ButtonUSBCopy] = false
+	events := collectButtonEvents(dc)
+
+	// Frames arriving on the wire are picked up by the background monitor
+	mockPort.SetReadData([]byte{0x53, 0x05, 0x00, 0xFA})
+	assert.Equal(t, buttonEvent{ButtonEnter, true}, waitForEvent(t, events))
+}
+
+func TestDisplayController_LastRead(t *testing.T) {
+	dc, _ := newTestDisplayController(t)
+
+	// The monitor reads even while the panel is silent
+	assert.Eventually(t, func() bool {
+		return time.Since(dc.LastRead()) < 200*time.Millisecond
+	}, time.Second, 10*time.Millisecond)
+
+	// Let a read under way finish
+	dc.Close()
+	time.Sleep(100 * time.Millisecond)
+	stopped := dc.LastRead()
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, stopped, dc.LastRead(), "no reads once closed")
+}
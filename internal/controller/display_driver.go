@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/sirupsen/logrus"
+)
+
+// DisplayDriver is the command set of a character panel on the serial port:
+// it turns display operations into the bytes the panel takes. The display
+// controller does everything around them (batching, pacing,
+// acknowledgements, the circuit breaker and glyph reloads), so another panel
+// type only needs a driver registered in displayDrivers.
+type DisplayDriver interface {
+	// Init returns the commands that prepare the panel after it is opened,
+	// nil if it needs none
+	Init() []byte
+	// WriteLine writes a whole row, truncated and padded to the width
+	WriteLine(row int, text string) []byte
+	// SetBacklight switches the backlight
+	SetBacklight(on bool) []byte
+	// Geometry returns the characters per line and the number of lines
+	Geometry() (cols, rows int)
+	// DefineGlyph defines custom character slot from eight pixel rows, the
+	// lowest five bits of each. It returns nil if the panel has no custom
+	// characters.
+	DefineGlyph(slot int, rows [8]byte) []byte
+	// Kind classifies a command for write pacing: "line", "backlight",
+	// "glyph" or "request"
+	Kind(command []byte) string
+	// RequestState asks the panel for its button state
+	RequestState() []byte
+	// DecodeFrame decodes the panel frame at the start of buffer and returns
+	// it with its length: button reports as a qnapproto.ButtonState in the
+	// layout of the hardware profile, anything else the controller may
+	// ignore. Errors are qnapproto.ErrShort for a frame not complete yet and
+	// qnapproto.ErrUnknown for bytes that start no frame.
+	DecodeFrame(buffer []byte) (qnapproto.Frame, int, error)
+}
+
+// displayDrivers create the drivers of the panels the display controller
+// runs, by Display.Driver
+var displayDrivers = map[string]func(cfg *config.Config) DisplayDriver{
+	DriverQNAP:    newQNAPDriver,
+	DriverQNAPLCM: newLCMDriver,
+}
+
+// openDisplayDriver creates the driver selected by Display.Driver, the QNAP
+// panel if none is
+func openDisplayDriver(cfg *config.Config) (DisplayDriver, error) {
+	name := cfg.Display.Driver
+	if name == "" {
+		name = DriverQNAP
+	}
+	newDriver, ok := displayDrivers[name]
+	if !ok {
+		return nil, fmt.Errorf("display driver %q does not run on the serial panel controller", name)
+	}
+	return newDriver(cfg), nil
+}
+
+// displayDriverNames returns the names of the registered drivers, sorted
+func displayDriverNames() []string {
+	names := make([]string, 0, len(displayDrivers))
+	for name := range displayDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// qnapDriver is the serial protocol of the QNAP front panel, encoded by
+// qnapproto
+type qnapDriver struct {
+	// glyphPrefix starts a CGRAM upload, nil on panels whose firmware has
+	// no custom characters
+	glyphPrefix []byte
+	cols, rows  int
+}
+
+// newQNAPDriver creates the QNAP panel driver for the configured size, 16x2
+// by default. Custom characters are only defined if the configuration names
+// the panel's CGRAM upload command.
+func newQNAPDriver(cfg *config.Config) DisplayDriver {
+	prefix, err := glyphCommandPrefix(cfg.Hardware.GlyphCommand)
+	if err != nil {
+		logrus.WithField("component", "display_controller").WithError(err).Warn("Invalid glyph command, icons disabled")
+		prefix = nil
+	}
+	cols, rows := cfg.Display.Width, cfg.Display.Height
+	if cols <= 0 {
+		cols = displayWidth
+	}
+	if rows <= 0 {
+		rows = displayRows
+	}
+	return &qnapDriver{glyphPrefix: prefix, cols: cols, rows: rows}
+}
+
+// Init turns on button state reporting, so the panel sends button changes
+// on its own
+func (d *qnapDriver) Init() []byte {
+	return qnapproto.EnableReporting{}.Encode()
+}
+
+// WriteLine builds the line command
+func (d *qnapDriver) WriteLine(row int, text string) []byte {
+	return qnapproto.Line{Row: row, Text: text, Width: d.cols}.Encode()
+}
+
+// SetBacklight builds the backlight command
+func (d *qnapDriver) SetBacklight(on bool) []byte {
+	return qnapproto.Backlight{On: on}.Encode()
+}
+
+// Geometry returns the configured size of the panel
+func (d *qnapDriver) Geometry() (int, int) {
+	return d.cols, d.rows
+}
+
+// DefineGlyph builds the CGRAM upload of a glyph
+func (d *qnapDriver) DefineGlyph(slot int, rows [8]byte) []byte {
+	if len(d.glyphPrefix) == 0 {
+		return nil
+	}
+	return qnapproto.DefineGlyph{Prefix: d.glyphPrefix, Slot: slot, Rows: rows}.Encode()
+}
+
+// Kind classifies a command by its leading bytes
+func (d *qnapDriver) Kind(command []byte) string {
+	switch {
+	case len(d.glyphPrefix) > 0 && bytes.HasPrefix(command, d.glyphPrefix):
+		return commandGlyph
+	case bytes.HasPrefix(command, []byte{qnapproto.CommandPrefix, qnapproto.CodeLine}):
+		return commandLine
+	case bytes.HasPrefix(command, []byte{qnapproto.CommandPrefix, qnapproto.CodeBacklight}):
+		return commandBacklight
+	}
+	return commandRequest
+}
+
+// RequestState builds the button state request
+func (d *qnapDriver) RequestState() []byte {
+	return qnapproto.RequestState{}.Encode()
+}
+
+// DecodeFrame decodes state frames and command responses
+func (d *qnapDriver) DecodeFrame(buffer []byte) (qnapproto.Frame, int, error) {
+	return qnapproto.DecodeFrame(buffer)
+}
+
+// lcmDriver is the framed LCM protocol of the newer A125 panels. Unlike the
+// older panels they define custom characters without configuration.
+type lcmDriver struct{}
+
+func newLCMDriver(cfg *config.Config) DisplayDriver {
+	return lcmDriver{}
+}
+
+// Init turns on key reports
+func (lcmDriver) Init() []byte {
+	return qnapproto.LCMEnableReporting{}.Encode()
+}
+
+// WriteLine builds the line frame
+func (lcmDriver) WriteLine(row int, text string) []byte {
+	return qnapproto.LCMLine{Row: row, Text: text}.Encode()
+}
+
+// SetBacklight builds the backlight frame
+func (lcmDriver) SetBacklight(on bool) []byte {
+	return qnapproto.LCMBacklight{On: on}.Encode()
+}
+
+// Geometry returns the 16x2 of the panel
+func (lcmDriver) Geometry() (int, int) {
+	return displayWidth, displayRows
+}
+
+// DefineGlyph builds the glyph frame
+func (lcmDriver) DefineGlyph(slot int, rows [8]byte) []byte {
+	return qnapproto.LCMDefineGlyph{Slot: slot, Rows: rows}.Encode()
+}
+
+// Kind classifies a frame by its command code
+func (lcmDriver) Kind(command []byte) string {
+	if len(command) < 2 {
+		return commandRequest
+	}
+	switch command[1] {
+	case qnapproto.LCMCodeLine:
+		return commandLine
+	case qnapproto.LCMCodeBacklight:
+		return commandBacklight
+	case qnapproto.LCMCodeDefineGlyph:
+		return commandGlyph
+	}
+	return commandRequest
+}
+
+// RequestState builds the key request
+func (lcmDriver) RequestState() []byte {
+	return qnapproto.LCMRequestKeys{}.Encode()
+}
+
+// DecodeFrame decodes key reports, as the state byte of the standard
+// profile, and acknowledgements
+func (lcmDriver) DecodeFrame(buffer []byte) (qnapproto.Frame, int, error) {
+	message, n, err := qnapproto.DecodeLCM(buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if keys, ok := message.(qnapproto.LCMKeys); ok {
+		return keys.ButtonState(), n, nil
+	}
+	return message, n, nil
+}
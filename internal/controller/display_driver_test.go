@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textDriver is a panel taking readable commands, 20x4 and without custom
+// characters
+type textDriver struct{}
+
+func (textDriver) Init() []byte { return []byte("init;") }
+
+func (textDriver) WriteLine(row int, text string) []byte {
+	return []byte(fmt.Sprintf("line %d %s;", row, text))
+}
+
+func (textDriver) SetBacklight(on bool) []byte { return []byte(fmt.Sprintf("light %t;", on)) }
+
+func (textDriver) Geometry() (int, int) { return 20, 4 }
+
+func (textDriver) DefineGlyph(slot int, rows [8]byte) []byte { return nil }
+
+func (textDriver) Kind(command []byte) string { return commandRequest }
+
+func (textDriver) RequestState() []byte { return []byte("state?;") }
+
+func (textDriver) DecodeFrame(buffer []byte) (qnapproto.Frame, int, error) {
+	return qnapproto.DecodeFrame(buffer)
+}
+
+func TestQNAPDriver(t *testing.T) {
+	cfg := config.DefaultConfig()
+	driver := newQNAPDriver(cfg)
+
+	cols, rows := driver.Geometry()
+	assert.Equal(t, 16, cols)
+	assert.Equal(t, 2, rows)
+	assert.Equal(t, []byte{0x4D, 0x06}, driver.Init())
+	assert.Equal(t, lineCommand(1, "Hi              "), driver.WriteLine(1, "Hi"))
+	assert.Equal(t, []byte{0x4D, 0x5E, 0x00}, driver.SetBacklight(false))
+	assert.Nil(t, driver.DefineGlyph(0, glyphs[0].Rows), "no glyphs without the upload command")
+
+	cfg.Hardware.GlyphCommand = []int{0x4D, 0x26}
+	driver = newQNAPDriver(cfg)
+	glyph := driver.DefineGlyph(2, glyphs[2].Rows)
+	assert.Equal(t, append([]byte{0x4D, 0x26, 0x02}, glyphs[2].Rows[:]...), glyph)
+
+	assert.Equal(t, commandLine, driver.Kind(driver.WriteLine(0, "Hi")))
+	assert.Equal(t, commandBacklight, driver.Kind(driver.SetBacklight(true)))
+	assert.Equal(t, commandGlyph, driver.Kind(glyph))
+	assert.Equal(t, commandRequest, driver.Kind([]byte{0x4D, 0x05}))
+	assert.Equal(t, commandRequest, newQNAPDriver(config.DefaultConfig()).Kind(glyph))
+}
+
+func TestLCMDriver(t *testing.T) {
+	driver := newLCMDriver(config.DefaultConfig())
+
+	assert.Equal(t, qnapproto.LCMEnableReporting{}.Encode(), driver.Init())
+	assert.Equal(t, qnapproto.LCMRequestKeys{}.Encode(), driver.RequestState())
+	glyph := driver.DefineGlyph(2, glyphs[2].Rows)
+	assert.Equal(t, qnapproto.LCMDefineGlyph{Slot: 2, Rows: glyphs[2].Rows}.Encode(), glyph,
+		"glyphs need no configured command")
+
+	assert.Equal(t, commandLine, driver.Kind(driver.WriteLine(0, "Hi")))
+	assert.Equal(t, commandBacklight, driver.Kind(driver.SetBacklight(true)))
+	assert.Equal(t, commandGlyph, driver.Kind(glyph))
+	assert.Equal(t, commandRequest, driver.Kind(driver.RequestState()))
+
+	// Key reports come out as state bytes of the standard profile
+	frame, n, err := driver.DecodeFrame(qnapproto.LCMKeys{Keys: qnapproto.LCMKeyEnter}.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, qnapproto.ButtonState{State: 0x02}, frame)
+	assert.Equal(t, 5, n)
+	_, _, err = driver.DecodeFrame([]byte{0x53, 0x05, 0x00, 0x02})
+	assert.ErrorIs(t, err, qnapproto.ErrUnknown)
+}
+
+func TestDisplayController_LCM(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Display.Driver = DriverQNAPLCM
+	port := serial.NewMockSerialPort()
+	dc, err := NewDisplayControllerWithPort(cfg, port)
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+
+	written := port.GetWrittenData()
+	assert.Equal(t, qnapproto.LCMEnableReporting{}.Encode(), written[:4])
+	assert.Contains(t, string(written), string(qnapproto.LCMLine{Row: 0, Text: "QNAP Ready"}.Encode()))
+
+	dc.lastButtonState[ButtonEnter] = false
+	dc.lastButtonState[ButtonSelect] = false
+	dc.lastButtonState[ButtonUSBCopy] = false
+	events := collectButtonEvents(dc)
+	port.SetReadData(append(qnapproto.LCMAck{Code: qnapproto.LCMCodeLine}.Encode(),
+		qnapproto.LCMKeys{Keys: qnapproto.LCMKeySelect}.Encode()...))
+	assert.Equal(t, buttonEvent{ButtonSelect, true}, waitForEvent(t, events))
+}
+
+func TestDisplayController_Driver(t *testing.T) {
+	displayDrivers["text"] = func(cfg *config.Config) DisplayDriver { return textDriver{} }
+	t.Cleanup(func() { delete(displayDrivers, "text") })
+
+	cfg := config.DefaultConfig()
+	cfg.Display.Driver = "text"
+	cfg.Display.DefaultText = "Ready"
+	port := serial.NewMockSerialPort()
+	dc, err := NewDisplayControllerWithPort(cfg, port)
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+
+	assert.Equal(t, "init;light true;line 0 ;line 1 ;line 2 ;line 3 ;"+
+		"line 0 Ready;line 1 ;line 2 ;line 3 ;", string(port.GetWrittenData()))
+
+	port.ClearWrittenData()
+	require.NoError(t, dc.WriteTextAt("Third", 2, 0))
+	assert.Error(t, dc.WriteTextAt("Fifth", 4, 0))
+	require.NoError(t, dc.SetBacklight(false))
+	assert.Equal(t, "line 2 Third;light false;", string(port.GetWrittenData()))
+
+	cfg.Display.Driver = "vfd"
+	_, err = NewDisplayControllerWithPort(cfg, serial.NewMockSerialPort())
+	assert.Error(t, err)
+}
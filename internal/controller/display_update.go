@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// displayRows is the number of lines on the panel
+	displayRows = qnapproto.Rows
+	// displayWidth is the number of characters per line
+	displayWidth = qnapproto.Width
+)
+
+// DisplayUpdate collects line and backlight changes that Update sends to the
+// panel as a single write. Lines that are not set keep their contents.
+type DisplayUpdate struct {
+	// lines has one entry per display row, nil for rows left unchanged
+	lines     []*string
+	backlight *bool
+	// stripGlyphs drops glyph characters the panel has no CGRAM data for
+	stripGlyphs bool
+	// translit replaces the characters the panel cannot show
+	translit *translit.Transliterator
+}
+
+// newDisplayUpdate creates an empty update for a display with the given
+// number of rows
+func newDisplayUpdate(rows int, stripGlyphs bool, transliterator *translit.Transliterator) *DisplayUpdate {
+	return &DisplayUpdate{lines: make([]*string, rows), stripGlyphs: stripGlyphs, translit: transliterator}
+}
+
+// NewTransliterator creates the transliteration configured in
+// Display.Transliteration, with glyph replacements shown by the built-in
+// glyphs, "{name}", or the custom character slots, "{slotN}"
+func NewTransliterator(cfg *config.Config) (*translit.Transliterator, error) {
+	return translit.New(cfg.Display.Transliteration.Options(glyphEscape))
+}
+
+// newTransliterator is NewTransliterator falling back to the built-in table
+// if the configuration is invalid
+func newTransliterator(cfg *config.Config, logger *logrus.Entry) *translit.Transliterator {
+	t, err := NewTransliterator(cfg)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid transliteration, using the built-in one")
+		t, _ = translit.New(translit.Options{})
+	}
+	return t
+}
+
+// SetLine replaces a whole line. Glyph escapes in text are expanded, see
+// ExpandGlyphs.
+func (u *DisplayUpdate) SetLine(row int, text string) error {
+	if row < 0 || row >= len(u.lines) {
+		return fmt.Errorf("invalid row: %d. Must be between 0 and %d", row, len(u.lines)-1)
+	}
+	text = u.translit.Apply(ExpandGlyphs(text))
+	u.lines[row] = &text
+	return nil
+}
+
+// SetText replaces all lines with newline separated text. Missing lines are
+// cleared and extra lines are dropped.
+func (u *DisplayUpdate) SetText(text string) {
+	lines := strings.Split(text, "\n")
+	for row := 0; row < len(u.lines); row++ {
+		line := ""
+		if row < len(lines) {
+			line = lines[row]
+		}
+		u.SetLine(row, line)
+	}
+}
+
+// SetBacklight switches the backlight as part of the update
+func (u *DisplayUpdate) SetBacklight(on bool) {
+	u.backlight = &on
+}
+
+// encode builds the panel commands for the update with driver. A backlight
+// being switched off goes dark before the lines change and one being
+// switched on lights up after, so the old and new contents are never seen
+// mixed.
+func (u *DisplayUpdate) encode(driver DisplayDriver) [][]byte {
+	var commands [][]byte
+	if u.backlight != nil && !*u.backlight {
+		commands = append(commands, driver.SetBacklight(false))
+	}
+	for row, line := range u.lines {
+		if line == nil {
+			continue
+		}
+		text := *line
+		if u.stripGlyphs {
+			text = stripGlyphs(text)
+		}
+		commands = append(commands, driver.WriteLine(row, text))
+	}
+	if u.backlight != nil && *u.backlight {
+		commands = append(commands, driver.SetBacklight(true))
+	}
+	return commands
+}
+
+// Update lets fn compose a display update and sends it to the panel as one
+// batch, so no other writer's output can land between its lines. Nothing is
+// sent if fn returns an error.
+func (dc *DisplayController) Update(fn func(update *DisplayUpdate) error) error {
+	update := newDisplayUpdate(dc.rows, !dc.glyphsLoaded, dc.translit)
+	if err := fn(update); err != nil {
+		return err
+	}
+
+	commands := update.encode(dc.driver)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	// Any other writer replaces the progress bar, so forget what it showed
+	if update.lines[progressRow] != nil {
+		dc.resetProgress()
+	}
+
+	dc.logger.WithField("commands", len(commands)).Debug("Sending display update")
+	if err := dc.write(commands...); err != nil {
+		return fmt.Errorf("failed to send display update: %w", err)
+	}
+	return nil
+}
+
+// WriteLines replaces several lines, keyed by row, in a single update
+func (dc *DisplayController) WriteLines(lines map[int]string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		for row, text := range lines {
+			if err := update.SetLine(row, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// write sends commands to the panel. Writes are serialized so that a batch
+// always reaches the panel in one piece, and refused while the circuit
+// breaker is open.
+func (dc *DisplayController) write(commands ...[]byte) error {
+	if !dc.breaker.allow() {
+		return ErrLinkDown
+	}
+
+	dc.writeMutex.Lock()
+	err := dc.send(commands)
+	dc.writeMutex.Unlock()
+
+	if event, changed := dc.breaker.record(err); changed {
+		dc.notifyBreaker(event)
+	}
+	return err
+}
+
+// send writes commands to the port: all at once, or one by one when writes
+// are paced or acknowledged. Caller must hold writeMutex.
+func (dc *DisplayController) send(commands [][]byte) error {
+	if !dc.pacing.Enabled() && !dc.acks.Enabled {
+		return dc.serialPort.Write(bytes.Join(commands, nil))
+	}
+
+	for _, command := range commands {
+		if err := dc.sendCommand(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendCommand writes a single command after the pacing gap of the previous
+// one. On panels that acknowledge commands it waits for the answer and sends
+// a rejected or unanswered command again, up to the configured retries.
+func (dc *DisplayController) sendCommand(command []byte) error {
+	for attempt := 0; ; attempt++ {
+		if wait := time.Until(dc.nextWrite); wait > 0 {
+			time.Sleep(wait)
+		}
+		// Forget an answer that arrived too late for the previous command
+		select {
+		case <-dc.answers:
+		default:
+		}
+
+		if err := dc.serialPort.Write(command); err != nil {
+			return err
+		}
+		gap := dc.pacing.gap(dc.driver.Kind(command))
+		dc.nextWrite = time.Now().Add(transmitTime(len(command), dc.config.SerialPort.BaudRate) + gap)
+		if !dc.acks.Enabled {
+			return nil
+		}
+
+		dc.frameCounters.sent.Add(1)
+		err := dc.awaitAnswer()
+		if err == nil || !errors.Is(err, ErrNotAcknowledged) {
+			return err
+		}
+		if attempt >= dc.acks.Retries {
+			dc.frameCounters.failed.Add(1)
+			stats := dc.frameCounters.snapshot()
+			dc.logger.WithError(err).WithFields(logrus.Fields{
+				"command":   fmt.Sprintf("% 02x", command),
+				"nacked":    stats.Nacked,
+				"timed_out": stats.TimedOut,
+				"failed":    stats.Failed,
+			}).Warn("Giving up on unacknowledged command")
+			return err
+		}
+		dc.frameCounters.retries.Add(1)
+		dc.logger.WithError(err).WithField("attempt", attempt+1).Debug("Resending unacknowledged command")
+	}
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Glyph is a 5x8 pixel custom character stored in the panel's CGRAM
+type Glyph struct {
+	Name string
+	// Rows are the pixel rows from top to bottom, the lowest 5 bits of each
+	Rows [8]byte
+}
+
+// glyphs are the built-in icons, the maintenance badge and sparkline bars. A glyph's index is its
+// CGRAM slot; panels have eight.
+var glyphs = []Glyph{
+	{Name: "gear", Rows: [8]byte{0x00, 0x15, 0x0E, 0x1B, 0x0E, 0x15, 0x00, 0x00}},
+	{Name: "disk", Rows: [8]byte{0x0E, 0x11, 0x0E, 0x11, 0x11, 0x11, 0x0E, 0x00}},
+	{Name: "network", Rows: [8]byte{0x04, 0x0E, 0x04, 0x1F, 0x11, 0x1B, 0x1B, 0x00}},
+	{Name: "power", Rows: [8]byte{0x04, 0x15, 0x15, 0x11, 0x11, 0x0E, 0x00, 0x00}},
+	// Shown in a corner of the panel during maintenance
+	{Name: "wrench", Rows: [8]byte{0x05, 0x07, 0x0E, 0x1C, 0x18, 0x10, 0x00, 0x00}},
+	// Bars of rising height for sparklines, 3, 5 and 8 rows
+	{Name: "bar1", Rows: [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x1F, 0x1F, 0x1F}},
+	{Name: "bar2", Rows: [8]byte{0x00, 0x00, 0x00, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}},
+	{Name: "bar3", Rows: [8]byte{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}},
+}
+
+// GlyphSlots is the number of custom characters a panel holds
+const GlyphSlots = 8
+
+// ErrNoGlyphs is returned by DefineCustomChar on a panel that cannot define
+// custom characters, such as a QNAP panel without a glyph_command
+var ErrNoGlyphs = errors.New("panel has no custom characters")
+
+// glyphCodeBase is the character code of CGRAM slot 0 in display text. The
+// upper mirror of the slots at 8-15 is not used because it overlaps with tab
+// and newline, and line commands carry their length, so NUL is safe.
+const glyphCodeBase = 0x00
+
+// GlyphNames returns the names of the built-in glyphs
+func GlyphNames() []string {
+	names := make([]string, len(glyphs))
+	for i, glyph := range glyphs {
+		names[i] = glyph.Name
+	}
+	return names
+}
+
+// GlyphChar returns the one character string that shows the named glyph
+func GlyphChar(name string) (string, bool) {
+	for slot, glyph := range glyphs {
+		if glyph.Name == name {
+			return string(rune(glyphCodeBase + slot)), true
+		}
+	}
+	return "", false
+}
+
+// ExpandGlyphs replaces the glyph escapes in text with the characters that
+// show them: "{name}" for a built-in glyph, e.g. "{bar3}", and "{slotN}" for
+// custom character slot N, e.g. one defined with DefineCustomChar. "{{" is a
+// literal "{"; braces around anything else are left as they are.
+func ExpandGlyphs(text string) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		out.WriteString(text[:start])
+		text = text[start:]
+		if strings.HasPrefix(text, "{{") {
+			out.WriteByte('{')
+			text = text[2:]
+			continue
+		}
+		if end := strings.IndexByte(text, '}'); end > 0 {
+			if char, ok := glyphEscape(text[1:end]); ok {
+				out.WriteString(char)
+				text = text[end+1:]
+				continue
+			}
+		}
+		out.WriteByte('{')
+		text = text[1:]
+	}
+	out.WriteString(text)
+	return out.String()
+}
+
+// glyphEscape returns the character of a glyph escape without its braces
+func glyphEscape(name string) (string, bool) {
+	if char, ok := GlyphChar(name); ok {
+		return char, true
+	}
+	digits, ok := strings.CutPrefix(name, "slot")
+	if !ok || len(digits) != 1 {
+		return "", false
+	}
+	slot, err := strconv.Atoi(digits)
+	if err != nil || checkGlyphSlot(slot) != nil {
+		return "", false
+	}
+	return string(rune(glyphCodeBase + slot)), true
+}
+
+// checkGlyphSlot rejects slots the panels do not have
+func checkGlyphSlot(slot int) error {
+	if slot < 0 || slot >= GlyphSlots {
+		return fmt.Errorf("invalid custom character slot %d, use 0 to %d", slot, GlyphSlots-1)
+	}
+	return nil
+}
+
+// maskGlyph keeps the five pixels of each row
+func maskGlyph(bitmap [8]byte) [8]byte {
+	for i := range bitmap {
+		bitmap[i] &= 0x1F
+	}
+	return bitmap
+}
+
+// stripGlyphs removes glyph characters from text, for panels without
+// uploaded glyphs
+func stripGlyphs(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= glyphCodeBase && r < glyphCodeBase+rune(len(glyphs)) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// glyphCommandPrefix validates the configured CGRAM upload prefix
+func glyphCommandPrefix(values []int) ([]byte, error) {
+	prefix := make([]byte, 0, len(values))
+	for _, value := range values {
+		if value < 0 || value > 0xFF {
+			return nil, fmt.Errorf("glyph command byte %d is not a byte", value)
+		}
+		prefix = append(prefix, byte(value))
+	}
+	return prefix, nil
+}
+
+// loadGlyphs uploads the built-in glyphs if the driver can define them; the
+// QNAP panel needs its CGRAM upload command named in the configuration.
+// Without it, or if the upload fails, glyph characters are dropped from
+// display text.
+func (dc *DisplayController) loadGlyphs() {
+	var upload [][]byte
+	for slot, glyph := range glyphs {
+		command := dc.driver.DefineGlyph(slot, glyph.Rows)
+		if command == nil {
+			return
+		}
+		upload = append(upload, command)
+	}
+	if err := dc.write(upload...); err != nil {
+		dc.logger.WithError(err).Warn("Failed to upload glyphs, icons disabled")
+		return
+	}
+
+	dc.glyphMutex.Lock()
+	dc.glyphUpload = upload
+	dc.glyphMutex.Unlock()
+	dc.glyphsLoaded = true
+	dc.logger.WithField("glyphs", len(glyphs)).Debug("Glyphs uploaded")
+}
+
+// reloadGlyphs uploads the glyphs again, since a panel that lost power while
+// the serial link was down has lost its CGRAM contents too
+func (dc *DisplayController) reloadGlyphs() {
+	if !dc.glyphsLoaded {
+		return
+	}
+	dc.glyphMutex.Lock()
+	upload := append([][]byte(nil), dc.glyphUpload...)
+	dc.glyphMutex.Unlock()
+
+	if err := dc.write(upload...); err != nil {
+		dc.logger.WithError(err).Warn("Failed to upload glyphs again")
+	}
+}
+
+// DefineCustomChar replaces the custom character in slot (0 to 7) with
+// bitmap, its pixel rows from top to bottom in the lowest five bits of each.
+// Text shows it as "{slotN}", or by the name of the built-in glyph it
+// replaces. It returns ErrNoGlyphs if the panel's glyphs are not uploaded.
+func (dc *DisplayController) DefineCustomChar(slot int, bitmap [8]byte) error {
+	if err := checkGlyphSlot(slot); err != nil {
+		return err
+	}
+	if !dc.glyphsLoaded {
+		return ErrNoGlyphs
+	}
+
+	// The glyph is sent again with the others after the link recovers
+	dc.glyphMutex.Lock()
+	defer dc.glyphMutex.Unlock()
+
+	command := dc.driver.DefineGlyph(slot, maskGlyph(bitmap))
+	if err := dc.write(command); err != nil {
+		return fmt.Errorf("failed to define custom character: %w", err)
+	}
+	dc.glyphUpload[slot] = command
+	return nil
+}
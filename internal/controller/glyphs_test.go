@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlyphChar(t *testing.T) {
+	assert.Equal(t, []string{"gear", "disk", "network", "power", "wrench", "bar1", "bar2", "bar3"}, GlyphNames())
+
+	gear, ok := GlyphChar("gear")
+	require.True(t, ok)
+	assert.Equal(t, "\x00", gear)
+	power, _ := GlyphChar("power")
+	assert.Equal(t, "\x03", power)
+
+	_, ok = GlyphChar("rocket")
+	assert.False(t, ok)
+
+	assert.Equal(t, ">System\n\t", stripGlyphs(">"+gear+"System\n"+power+"\t"))
+}
+
+func TestExpandGlyphs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{"{gear} System", "\x00 System"},
+		{"21{slot7}C", "21\x07C"},
+		{"{bar1}{bar2}{bar3}", "\x05\x06\x07"},
+		{"{{gear}", "{gear}"},
+		{"{slot8} {slotx} {rocket} {", "{slot8} {slotx} {rocket} {"},
+		{"a}b{", "a}b{"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ExpandGlyphs(tt.text), tt.text)
+	}
+}
+
+func TestDisplayController_Glyphs(t *testing.T) {
+	gear, _ := GlyphChar("gear")
+
+	t.Run("Stripped without glyph command", func(t *testing.T) {
+		dc, mockPort := newTestDisplayController(t)
+
+		require.NoError(t, dc.WriteTextAt(">"+gear+"System", 1, 0))
+		assert.Equal(t, lineCommand(1, ">System         "), mockPort.GetWrittenData())
+	})
+
+	t.Run("Uploaded with glyph command", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.GlyphCommand = []int{0x4D, 0x26}
+		mockPort := serial.NewMockSerialPort()
+		dc, err := NewDisplayControllerWithPort(cfg, mockPort)
+		require.NoError(t, err)
+		defer dc.Close()
+
+		// Each glyph: prefix, slot, eight rows
+		upload := append([]byte{0x4D, 0x26, 0x00}, glyphs[0].Rows[:]...)
+		upload = append(upload, 0x4D, 0x26, 0x01)
+		assert.True(t, bytes.Contains(mockPort.GetWrittenData(), upload))
+
+		mockPort.ClearWrittenData()
+		require.NoError(t, dc.WriteText(">"+gear+"System"))
+		assert.Equal(t, append(lineCommand(0, ">\x00System        "), lineCommand(1, "                ")...),
+			mockPort.GetWrittenData())
+	})
+
+	t.Run("Custom character", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.GlyphCommand = []int{0x4D, 0x26}
+		dc, mockPort := newTestDisplayControllerWithConfig(t, cfg)
+
+		degree := [8]byte{0x06, 0x09, 0x09, 0x06, 0xE0}
+		require.NoError(t, dc.DefineCustomChar(3, degree))
+		assert.Equal(t, []byte{0x4D, 0x26, 0x03, 0x06, 0x09, 0x09, 0x06, 0x00, 0x00, 0x00, 0x00}, mockPort.GetWrittenData(),
+			"only the five pixel bits are sent")
+		assert.Equal(t, mockPort.GetWrittenData(), dc.glyphUpload[3], "sent again after recovery")
+		assert.Error(t, dc.DefineCustomChar(GlyphSlots, degree))
+
+		mockPort.ClearWrittenData()
+		require.NoError(t, dc.WriteTextAt("21{slot3}C", 0, 0))
+		assert.Equal(t, lineCommand(0, "21\x03C            "), mockPort.GetWrittenData())
+	})
+
+	t.Run("No custom characters without glyph command", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		assert.ErrorIs(t, dc.DefineCustomChar(0, [8]byte{}), ErrNoGlyphs)
+	})
+
+	t.Run("Invalid glyph command disables icons", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.GlyphCommand = []int{0x4D, 300}
+		dc, mockPort := newTestDisplayControllerWithConfig(t, cfg)
+
+		require.NoError(t, dc.WriteTextAt(gear+"X", 0, 0))
+		assert.Equal(t, lineCommand(0, "X               "), mockPort.GetWrittenData())
+	})
+}
@@ -0,0 +1,419 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/hardware"
+	"github.com/qnap/display-control/internal/hd44780"
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/sirupsen/logrus"
+)
+
+// DriverHD44780 selects an HD44780 character LCD on GPIO lines
+const DriverHD44780 = "hd44780"
+
+// DefaultGPIOChip is the GPIO chip used when Display.GPIO names none
+const DefaultGPIOChip = "/dev/gpiochip0"
+
+// gpioButtonPollInterval is how often the button lines are read. A change
+// counts once two reads in a row agree, which debounces the contacts.
+const gpioButtonPollInterval = 20 * time.Millisecond
+
+// gpioConsumer labels the requested lines, e.g. in gpioinfo
+const gpioConsumer = "qnap-display"
+
+// HD44780Controller shows the display on an HD44780 character LCD wired to
+// GPIO lines or a PCF8574 backpack, with optional buttons on GPIO lines as
+// ENTER and SELECT. There is no serial link, so the link always reads as
+// closed.
+type HD44780Controller struct {
+	device *hd44780.Device
+	lines  hardware.GPIOLines
+	// backlight and buttons are nil if not wired
+	backlight hardware.GPIOLines
+	buttons   hardware.GPIOLines
+	// buttonOrder are the panel buttons by their bit in the button lines
+	buttonOrder []PanelButton
+	cols, rows  int
+	logger      *logrus.Entry
+	translit    *translit.Transliterator
+
+	// mutex serializes drawing so an update reaches the display whole
+	mutex         sync.Mutex
+	buttonHandler ButtonEventHandler
+	handlerMutex  sync.RWMutex
+	stopChan      chan struct{}
+	closeOnce     sync.Once
+	// marquees scroll text through rows, see ScrollText
+	marquees Marquees
+}
+
+// NewHD44780Controller requests the GPIO lines in Display.GPIO and
+// initializes the display
+func NewHD44780Controller(cfg *config.Config) (*HD44780Controller, error) {
+	wiring := cfg.Display.GPIO
+	chip := GPIOChip(cfg)
+
+	offsets := make([]int, hd44780.Lines)
+	offsets[hd44780.LineRS] = wiring.RS
+	offsets[hd44780.LineE] = wiring.E
+	copy(offsets[hd44780.LineD4:], wiring.Data[:])
+	lines, err := hardware.RequestGPIOOutputs(chip, offsets, gpioConsumer)
+	if err != nil {
+		return nil, err
+	}
+
+	var backlight, buttons hardware.GPIOLines
+	closeAll := func() {
+		lines.Close()
+		if backlight != nil {
+			backlight.Close()
+		}
+	}
+	if wiring.Backlight != nil {
+		backlightLine, err := hardware.RequestGPIOOutputs(chip, []int{*wiring.Backlight}, gpioConsumer)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		backlight = backlightLine
+	}
+	if buttonOffsets, _ := gpioButtons(wiring); len(buttonOffsets) > 0 {
+		buttonLines, err := hardware.RequestGPIOInputs(chip, buttonOffsets, gpioConsumer)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		buttons = buttonLines
+	}
+
+	dc, err := NewHD44780ControllerWithLines(cfg, lines, backlight, buttons)
+	if err != nil {
+		closeAll()
+		if buttons != nil {
+			buttons.Close()
+		}
+		return nil, err
+	}
+	return dc, nil
+}
+
+// GPIOChip returns the GPIO chip the display is wired to
+func GPIOChip(cfg *config.Config) string {
+	if cfg.Display.GPIO.Chip == "" {
+		return DefaultGPIOChip
+	}
+	return cfg.Display.GPIO.Chip
+}
+
+// gpioButtons returns the offsets of the wired buttons and the panel
+// buttons they are, in the same order
+func gpioButtons(wiring config.GPIODisplayConfig) ([]int, []PanelButton) {
+	var offsets []int
+	var order []PanelButton
+	if wiring.Enter != nil {
+		offsets = append(offsets, *wiring.Enter)
+		order = append(order, ButtonEnter)
+	}
+	if wiring.Select != nil {
+		offsets = append(offsets, *wiring.Select)
+		order = append(order, ButtonSelect)
+	}
+	return offsets, order
+}
+
+// NewHD44780ControllerWithLines creates an HD44780 controller on already
+// requested lines (real ones or fakes for testing). backlight and buttons
+// may be nil.
+func NewHD44780ControllerWithLines(cfg *config.Config, lines, backlight, buttons hardware.GPIOLines) (*HD44780Controller, error) {
+	logger := logrus.WithField("component", "hd44780_display")
+
+	cols, rows := cfg.Display.Width, cfg.Display.Height
+	if cols <= 0 {
+		cols = displayWidth
+	}
+	if rows <= 0 {
+		rows = displayRows
+	}
+	device, err := hd44780.Open(lines, cols, rows)
+	if err != nil {
+		return nil, err
+	}
+	// Glyphs live in the display's CGRAM at the codes the menu uses
+	for slot, glyph := range glyphs {
+		if err := device.DefineGlyph(glyphCodeBase+slot, glyph.Rows); err != nil {
+			return nil, fmt.Errorf("failed to define glyphs: %w", err)
+		}
+	}
+
+	_, order := gpioButtons(cfg.Display.GPIO)
+	dc := &HD44780Controller{
+		device:      device,
+		lines:       lines,
+		backlight:   backlight,
+		buttons:     buttons,
+		buttonOrder: order,
+		cols:        cols,
+		rows:        rows,
+		logger:      logger,
+		translit:    newTransliterator(cfg, logger),
+		stopChan:    make(chan struct{}),
+	}
+	if err := dc.SetBacklight(true); err != nil {
+		logger.WithError(err).Warn("Failed to switch the backlight on")
+	}
+	if cfg.Display.DefaultText != "" {
+		if err := dc.WriteText(cfg.Display.DefaultText); err != nil {
+			logger.WithError(err).Warn("Failed to write default text")
+		}
+	}
+	if buttons != nil {
+		go dc.monitorButtons()
+	}
+
+	logger.WithFields(logrus.Fields{
+		"cols":    cols,
+		"rows":    rows,
+		"buttons": len(order),
+	}).Info("HD44780 display initialized successfully")
+	return dc, nil
+}
+
+// Update lets fn compose a display update and draws it line by line. Nothing
+// is drawn if fn returns an error.
+func (dc *HD44780Controller) Update(fn func(update *DisplayUpdate) error) error {
+	update := newDisplayUpdate(dc.rows, false, dc.translit)
+	if err := fn(update); err != nil {
+		return err
+	}
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if update.backlight != nil && !*update.backlight {
+		if err := dc.switchBacklight(false); err != nil {
+			return err
+		}
+	}
+	for row, line := range update.lines {
+		if line != nil {
+			if err := dc.device.WriteLine(row, *line); err != nil {
+				return fmt.Errorf("failed to send display update: %w", err)
+			}
+		}
+	}
+	if update.backlight != nil && *update.backlight {
+		return dc.switchBacklight(true)
+	}
+	return nil
+}
+
+// switchBacklight switches the backlight line, or the display itself if
+// there is none. Caller must hold mutex.
+func (dc *HD44780Controller) switchBacklight(on bool) error {
+	if dc.backlight == nil {
+		if err := dc.device.SetDisplay(on); err != nil {
+			return fmt.Errorf("failed to switch the display: %w", err)
+		}
+		return nil
+	}
+	value := uint64(0)
+	if on {
+		value = 1
+	}
+	if err := dc.backlight.SetValues(value, 1); err != nil {
+		return fmt.Errorf("failed to switch the backlight: %w", err)
+	}
+	return nil
+}
+
+// WriteText replaces all lines with newline separated text
+func (dc *HD44780Controller) WriteText(text string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetText(text)
+		return nil
+	})
+}
+
+// WriteTextAt replaces a line. Like the serial panel, the whole line is
+// written, so col is ignored.
+func (dc *HD44780Controller) WriteTextAt(text string, row, col int) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		return update.SetLine(row, text)
+	})
+}
+
+// WriteLines replaces several lines, keyed by row, in a single update
+func (dc *HD44780Controller) WriteLines(lines map[int]string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		for row, text := range lines {
+			if err := update.SetLine(row, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ScrollText scrolls text through row, see Marquees.ScrollText
+func (dc *HD44780Controller) ScrollText(row int, text string, speed time.Duration) error {
+	return dc.marquees.ScrollText(dc, dc.cols, row, text, speed)
+}
+
+// StopScroll stops the marquee on row, leaving its last step shown
+func (dc *HD44780Controller) StopScroll(row int) {
+	dc.marquees.StopScroll(row)
+}
+
+// ClearDisplay clears every line
+func (dc *HD44780Controller) ClearDisplay() error {
+	return dc.WriteText("")
+}
+
+// SetBacklight switches the backlight
+func (dc *HD44780Controller) SetBacklight(on bool) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetBacklight(on)
+		return nil
+	})
+}
+
+// ShowCopyStatus displays copy operation status
+func (dc *HD44780Controller) ShowCopyStatus(status string) error {
+	return dc.WriteText("USB Copy\n" + status)
+}
+
+// ShowProgress draws a progress bar on the second line. GPIO writes are
+// fast, so redraws are not rate limited.
+func (dc *HD44780Controller) ShowProgress(percent int) error {
+	return dc.WriteTextAt(RenderProgressBarWidth(percent, dc.cols), progressRow, 0)
+}
+
+// DefineCustomChar replaces the custom character in slot (0 to 7) in the
+// display's CGRAM
+func (dc *HD44780Controller) DefineCustomChar(slot int, bitmap [8]byte) error {
+	if err := checkGlyphSlot(slot); err != nil {
+		return err
+	}
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if err := dc.device.DefineGlyph(glyphCodeBase+slot, maskGlyph(bitmap)); err != nil {
+		return fmt.Errorf("failed to define custom character: %w", err)
+	}
+	return nil
+}
+
+// SetButtonHandler sets the callback function for the button lines
+func (dc *HD44780Controller) SetButtonHandler(handler ButtonEventHandler) {
+	dc.handlerMutex.Lock()
+	dc.buttonHandler = handler
+	dc.handlerMutex.Unlock()
+}
+
+// RequestButtonState does nothing; the button lines are read continuously
+func (dc *HD44780Controller) RequestButtonState() error {
+	return nil
+}
+
+// SetSerialCopyDetection does nothing; there is no copy button
+func (dc *HD44780Controller) SetSerialCopyDetection(enabled bool) {}
+
+// SerialCopyDetection always reports false
+func (dc *HD44780Controller) SerialCopyDetection() bool {
+	return false
+}
+
+// SetBreakerHandler is accepted for compatibility; there is no serial link
+// that could fail
+func (dc *HD44780Controller) SetBreakerHandler(handler BreakerEventHandler) {}
+
+// LinkState always reports a closed breaker
+func (dc *HD44780Controller) LinkState() BreakerState {
+	return BreakerClosed
+}
+
+// FrameStats always reports zero counts; the display does not acknowledge
+// anything
+func (dc *HD44780Controller) FrameStats() FrameStats {
+	return FrameStats{}
+}
+
+// Close stops reading the buttons, blanks the display and releases the
+// lines
+func (dc *HD44780Controller) Close() error {
+	var err error
+	dc.closeOnce.Do(func() {
+		dc.logger.Info("Closing HD44780 display")
+		dc.marquees.StopAll()
+		close(dc.stopChan)
+		dc.mutex.Lock()
+		if offErr := dc.switchBacklight(false); offErr != nil {
+			dc.logger.WithError(offErr).Warn("Failed to switch the backlight off")
+		}
+		dc.mutex.Unlock()
+		if dc.buttons != nil {
+			dc.buttons.Close()
+		}
+		if dc.backlight != nil {
+			dc.backlight.Close()
+		}
+		err = dc.lines.Close()
+	})
+	return err
+}
+
+// monitorButtons reads the button lines until the controller is closed and
+// reports changes that held for two reads
+func (dc *HD44780Controller) monitorButtons() {
+	ticker := time.NewTicker(gpioButtonPollInterval)
+	defer ticker.Stop()
+
+	var reported, previous uint64
+	for {
+		select {
+		case <-dc.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		values, err := dc.buttons.Values()
+		if err != nil {
+			dc.logger.WithError(err).Debug("Failed to read button lines")
+			continue
+		}
+		stable := values == previous
+		previous = values
+		if !stable || values == reported {
+			continue
+		}
+		for bit, button := range dc.buttonOrder {
+			mask := uint64(1) << bit
+			if values&mask != reported&mask {
+				dc.triggerButton(button, values&mask != 0)
+			}
+		}
+		reported = values
+	}
+}
+
+// triggerButton forwards a button change to the handler
+func (dc *HD44780Controller) triggerButton(button PanelButton, pressed bool) {
+	dc.handlerMutex.RLock()
+	handler := dc.buttonHandler
+	dc.handlerMutex.RUnlock()
+
+	dc.logger.WithFields(logrus.Fields{
+		"button":      button.String(),
+		"button_id":   int(button),
+		"pressed":     pressed,
+		"has_handler": handler != nil,
+	}).Info("Button event triggered")
+
+	if handler != nil {
+		handler(button, pressed)
+	}
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGPIOLines records the values driven on lines and returns set values
+// when read
+type fakeGPIOLines struct {
+	mu     sync.Mutex
+	sets   int
+	last   uint64
+	read   uint64
+	closed bool
+}
+
+func (l *fakeGPIOLines) SetValues(bits, mask uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sets++
+	l.last = l.last&^mask | bits&mask
+	return nil
+}
+
+func (l *fakeGPIOLines) Values() (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.read, nil
+}
+
+func (l *fakeGPIOLines) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+func (l *fakeGPIOLines) press(values uint64) {
+	l.mu.Lock()
+	l.read = values
+	l.mu.Unlock()
+}
+
+func TestHD44780Controller(t *testing.T) {
+	enter, sel := 17, 27
+	cfg := &config.Config{Display: config.DisplayConfig{
+		Driver: DriverHD44780,
+		GPIO:   config.GPIODisplayConfig{Enter: &enter, Select: &sel},
+	}}
+	lines, backlight, buttons := &fakeGPIOLines{}, &fakeGPIOLines{}, &fakeGPIOLines{}
+	dc, err := NewHD44780ControllerWithLines(cfg, lines, backlight, buttons)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), backlight.last, "the backlight is switched on")
+
+	before := lines.sets
+	require.NoError(t, dc.WriteTextAt("Hello", 0, 0))
+	assert.Greater(t, lines.sets, before)
+	assert.Error(t, dc.WriteTextAt("x", 2, 0))
+
+	require.NoError(t, dc.SetBacklight(false))
+	assert.Equal(t, uint64(0), backlight.last)
+
+	events := make(chan PanelButton, 4)
+	dc.SetButtonHandler(func(button PanelButton, pressed bool) {
+		if pressed {
+			events <- button
+		}
+	})
+	// SELECT is the second button line
+	buttons.press(0b10)
+	select {
+	case button := <-events:
+		assert.Equal(t, ButtonSelect, button)
+	case <-time.After(time.Second):
+		t.Fatal("no button event")
+	}
+
+	assert.Equal(t, BreakerClosed, dc.LinkState())
+	require.NoError(t, dc.Close())
+	require.NoError(t, dc.Close())
+	assert.True(t, lines.closed)
+	assert.True(t, buttons.closed)
+}
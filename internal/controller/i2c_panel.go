@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/hardware"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/qnap/display-control/internal/serial"
+)
+
+// I2CPanelProfile is the hardware profile of front panels whose MCU is
+// attached over SMBus instead of the UART
+const I2CPanelProfile = "a125-i2c"
+
+// DefaultI2CPanelAddress is the panel MCU's address when the configuration
+// does not set one
+const DefaultI2CPanelAddress = 0x3A
+
+// Registers of the panel MCU
+const (
+	// i2cPanelButtons holds the button state, laid out like the state byte of
+	// 0x53 frames
+	i2cPanelButtons = 0x00
+	// i2cPanelBacklight switches the backlight, 1 = on
+	i2cPanelBacklight = 0x01
+	// i2cPanelText is the text buffer, one displayWidth byte block per line
+	i2cPanelText = 0x10
+)
+
+// I2CPanelPort speaks the serial panel protocol to a front panel MCU on an
+// I2C bus. Commands written to it become register writes, and the button
+// register is read back as the 0x53 state frames a serial panel sends, so
+// DisplayController works on top of it unchanged.
+type I2CPanelPort struct {
+	bus  hardware.I2CBus
+	addr uint16
+
+	mutex    sync.Mutex
+	pending  []byte // state frames waiting to be read
+	state    byte   // last button state queued
+	reported bool   // a button state has been queued
+	closed   bool
+}
+
+// NewI2CPanelPort creates a port for the panel MCU at addr (0 =
+// DefaultI2CPanelAddress) on an already opened bus
+func NewI2CPanelPort(bus hardware.I2CBus, addr uint16) *I2CPanelPort {
+	if addr == 0 {
+		addr = DefaultI2CPanelAddress
+	}
+	return &I2CPanelPort{bus: bus, addr: addr}
+}
+
+// openI2CPanel opens the bus configured for the panel MCU
+func openI2CPanel(cfg config.HardwareConfig) (*I2CPanelPort, error) {
+	bus, err := hardware.OpenI2CBus(cfg.PanelBus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open panel bus: %w", err)
+	}
+	return NewI2CPanelPort(bus, cfg.PanelAddress), nil
+}
+
+// Write performs the panel commands in data, which may hold several
+func (p *I2CPanelPort) Write(data []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("i2c panel is closed")
+	}
+	for len(data) > 0 {
+		n, err := p.execute(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// execute performs the command at the start of data and returns its length.
+// Caller must hold the mutex.
+func (p *I2CPanelPort) execute(data []byte) (int, error) {
+	command, n, err := qnapproto.DecodeCommand(data)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported panel command: %w", err)
+	}
+
+	switch command := command.(type) {
+	case qnapproto.Line:
+		if command.Row >= displayRows {
+			return 0, fmt.Errorf("invalid row: %d", command.Row)
+		}
+		text := command.Text[:min(len(command.Text), displayWidth)]
+		register := byte(i2cPanelText + command.Row*displayWidth)
+		return n, p.bus.Tx(p.addr, append([]byte{register}, text...), nil)
+
+	case qnapproto.Backlight:
+		value := byte(0)
+		if command.On {
+			value = 1
+		}
+		return n, p.bus.Tx(p.addr, []byte{i2cPanelBacklight, value}, nil)
+
+	case qnapproto.RequestState:
+		// Answered with a state frame even if the state has not changed,
+		// like the serial panel does
+		state, err := p.readButtons()
+		if err != nil {
+			return 0, err
+		}
+		p.queueState(state)
+
+	case qnapproto.EnableReporting:
+		// The button register is always readable
+	}
+	return n, nil
+}
+
+// readButtons reads the button register. Caller must hold the mutex.
+func (p *I2CPanelPort) readButtons() (byte, error) {
+	state := make([]byte, 1)
+	if err := p.bus.Tx(p.addr, []byte{i2cPanelButtons}, state); err != nil {
+		return 0, err
+	}
+	return state[0], nil
+}
+
+// queueState queues a state frame. Caller must hold the mutex.
+func (p *I2CPanelPort) queueState(state byte) {
+	p.pending = append(p.pending, qnapproto.ButtonState{State: state}.Encode()...)
+	p.state = state
+	p.reported = true
+}
+
+// poll reads the button register and queues a state frame when it changed.
+// Caller must hold the mutex.
+func (p *I2CPanelPort) poll() error {
+	if p.closed {
+		return fmt.Errorf("i2c panel is closed")
+	}
+	state, err := p.readButtons()
+	if err != nil {
+		return err
+	}
+	if !p.reported || state != p.state {
+		p.queueState(state)
+	}
+	return nil
+}
+
+// Read reads queued state frames into buffer
+func (p *I2CPanelPort) Read(buffer []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := p.poll(); err != nil {
+		return 0, err
+	}
+	n := copy(buffer, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// ReadAvailable returns all queued state frames
+func (p *I2CPanelPort) ReadAvailable() ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := p.poll(); err != nil {
+		return nil, err
+	}
+	data := p.pending
+	p.pending = nil
+	return data, nil
+}
+
+// WriteString writes raw panel commands
+func (p *I2CPanelPort) WriteString(text string) error {
+	return p.Write([]byte(text))
+}
+
+// WriteText shows two lines; the position is ignored like the panel's line
+// commands do
+func (p *I2CPanelPort) WriteText(line1, line2 string, col, row int) error {
+	return p.Write(append(qnapproto.Line{Row: 0, Text: line1}.Encode(), qnapproto.Line{Row: 1, Text: line2}.Encode()...))
+}
+
+// IsConnected reports whether the bus is still open
+func (p *I2CPanelPort) IsConnected() bool {
+	return p.IsOpen()
+}
+
+// IsOpen reports whether the bus is still open
+func (p *I2CPanelPort) IsOpen() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return !p.closed
+}
+
+// Close closes the bus
+func (p *I2CPanelPort) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	return p.bus.Close()
+}
+
+// Compile-time check that the panel port can replace the serial port
+var _ serial.SerialPortInterface = (*I2CPanelPort)(nil)
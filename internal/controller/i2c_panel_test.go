@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePanelMCU emulates the registers of an a125-i2c panel
+type fakePanelMCU struct {
+	mutex     sync.Mutex
+	registers [256]byte
+	addr      uint16
+}
+
+func newFakePanelMCU() *fakePanelMCU {
+	mcu := &fakePanelMCU{}
+	mcu.registers[i2cPanelButtons] = 0x03 // ENTER and SELECT released
+	return mcu
+}
+
+func (m *fakePanelMCU) Tx(addr uint16, w, r []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.addr = addr
+	register := w[0]
+	copy(m.registers[register:], w[1:])
+	copy(r, m.registers[register:])
+	return nil
+}
+
+func (m *fakePanelMCU) Close() error {
+	return nil
+}
+
+// line returns the text buffer of a row
+func (m *fakePanelMCU) line(row int) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	start := i2cPanelText + row*displayWidth
+	return string(m.registers[start : start+displayWidth])
+}
+
+// address returns the address of the last transfer
+func (m *fakePanelMCU) address() uint16 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.addr
+}
+
+// setButtons changes the button register
+func (m *fakePanelMCU) setButtons(state byte) {
+	m.mutex.Lock()
+	m.registers[i2cPanelButtons] = state
+	m.mutex.Unlock()
+}
+
+func TestI2CPanelPort(t *testing.T) {
+	mcu := newFakePanelMCU()
+	port := NewI2CPanelPort(mcu, 0)
+
+	t.Run("Commands become register writes", func(t *testing.T) {
+		batch := append(qnapproto.Line{Row: 0, Text: "Hello"}.Encode(), qnapproto.Line{Row: 1, Text: "World"}.Encode()...)
+		require.NoError(t, port.Write(append(batch, qnapproto.Backlight{On: true}.Encode()...)))
+
+		assert.Equal(t, uint16(DefaultI2CPanelAddress), mcu.address())
+		assert.Equal(t, "Hello           ", mcu.line(0))
+		assert.Equal(t, "World           ", mcu.line(1))
+		assert.Equal(t, byte(0x01), mcu.registers[i2cPanelBacklight])
+
+		require.NoError(t, port.WriteText("Line 1", "Line 2", 0, 0))
+		assert.Equal(t, "Line 2          ", mcu.line(1))
+
+		assert.Error(t, port.Write([]byte{0x4D, 0x0C, 0x02, 0x01, 'x'}), "invalid row")
+		assert.Error(t, port.Write([]byte{0x4D, 0x0C, 0x00, 0x10, 'x'}), "truncated line")
+		assert.Error(t, port.Write([]byte{0x4D, 0x26, 0x00}), "unsupported command")
+	})
+
+	t.Run("Button register is read as state frames", func(t *testing.T) {
+		data, err := port.ReadAvailable()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x53, 0x05, 0x00, 0x03}, data, "the first read reports the state")
+
+		data, err = port.ReadAvailable()
+		require.NoError(t, err)
+		assert.Empty(t, data, "an unchanged state is not reported again")
+
+		mcu.setButtons(0x02)
+		data, err = port.ReadAvailable()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x53, 0x05, 0x00, 0x02}, data)
+
+		// A state request is always answered
+		require.NoError(t, port.Write([]byte{0x4D, 0x05}))
+		data, err = port.ReadAvailable()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x53, 0x05, 0x00, 0x02}, data)
+	})
+
+	require.NoError(t, port.Close())
+	assert.False(t, port.IsOpen())
+	assert.Error(t, port.Write(qnapproto.Backlight{On: false}.Encode()))
+	_, err := port.ReadAvailable()
+	assert.Error(t, err)
+}
+
+func TestDisplayController_I2CPanel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Hardware.Profile = I2CPanelProfile
+	mcu := newFakePanelMCU()
+
+	dc, err := NewDisplayControllerWithPort(cfg, NewI2CPanelPort(mcu, 0x3B))
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+
+	pressed := make(chan PanelButton, 4)
+	dc.SetButtonHandler(func(button PanelButton, isPressed bool) {
+		if isPressed {
+			pressed <- button
+		}
+	})
+
+	require.NoError(t, dc.WriteText("NAS ready\n10.0.0.2"))
+	assert.Equal(t, uint16(0x3B), mcu.address())
+	assert.Equal(t, "NAS ready       ", mcu.line(0))
+	assert.Equal(t, "10.0.0.2        ", mcu.line(1))
+
+	mcu.setButtons(0x02) // ENTER pressed (active low)
+	select {
+	case button := <-pressed:
+		assert.Equal(t, ButtonEnter, button)
+	case <-time.After(time.Second):
+		t.Fatal("button press was not reported")
+	}
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/qnap/display-control/internal/monitor"
+	"github.com/qnap/display-control/pkg/led"
+)
+
+// DisplayControllerInterface defines the display operations offered to consumers
+type DisplayControllerInterface interface {
+	WriteText(text string) error
+	WriteTextAt(text string, row, col int) error
+	WriteLines(lines map[int]string) error
+	Update(fn func(update *DisplayUpdate) error) error
+	ClearDisplay() error
+	SetBacklight(on bool) error
+	ShowCopyStatus(status string) error
+	ShowProgress(percent int) error
+	ScrollText(row int, text string, speed time.Duration) error
+	StopScroll(row int)
+	DefineCustomChar(slot int, bitmap [8]byte) error
+	SetButtonHandler(handler ButtonEventHandler)
+	RequestButtonState() error
+	SetSerialCopyDetection(enabled bool)
+	SerialCopyDetection() bool
+	SetBreakerHandler(handler BreakerEventHandler)
+	LinkState() BreakerState
+	FrameStats() FrameStats
+	Close() error
+}
+
+// LEDControllerInterface defines the LED operations offered to consumers
+type LEDControllerInterface = led.Controller
+
+// SystemControllerInterface defines the system-level operations offered to consumers
+type SystemControllerInterface interface {
+	GetDisplayController() DisplayControllerInterface
+	GetLEDController() LEDControllerInterface
+	GetUSBCopyMonitor() *monitor.USBCopyMonitor
+	SetButtonHandler(handler ButtonEventHandler)
+	SetDiskActivity(diskNum int, active bool) error
+	FlashDiskLED(diskNum int, duration time.Duration)
+	SetSystemStatus(status string, isError bool) error
+	ShowProgress(percent int, flashDisks bool) error
+	CopyButtonSource() CopyButtonSource
+	SetBreakerHandler(handler BreakerEventHandler)
+	Close() error
+}
+
+// Compile-time checks that the concrete controllers satisfy their interfaces
+var (
+	_ DisplayControllerInterface = (*DisplayController)(nil)
+	_ DisplayControllerInterface = (*OLEDDisplayController)(nil)
+	_ DisplayControllerInterface = (*CharLCDController)(nil)
+	_ DisplayControllerInterface = (*HD44780Controller)(nil)
+	_ LEDControllerInterface     = (*LEDController)(nil)
+	_ SystemControllerInterface  = (*SystemController)(nil)
+)
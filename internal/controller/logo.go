@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logo limits: pixel art of up to two rows of four custom characters, all
+// the slots a panel holds
+const (
+	LogoMaxRows  = 16
+	LogoMaxWidth = 20
+)
+
+// LoadLogo defines the pixel art of art in the custom character slots and
+// returns the text drawing it, one line per display row. Each string of art
+// is a row of pixels from the top, "#" lit and "." or " " dark, at most
+// LogoMaxRows rows of LogoMaxWidth pixels. The logo replaces the glyphs until
+// RestoreGlyphs.
+func LoadLogo(chars CustomChars, art []string) ([]string, error) {
+	cells, err := logoCells(art)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, len(cells))
+	slot := 0
+	for row := range cells {
+		var line strings.Builder
+		for _, bitmap := range cells[row] {
+			if err := chars.DefineCustomChar(slot, bitmap); err != nil {
+				return nil, fmt.Errorf("failed to load logo: %w", err)
+			}
+			line.WriteRune(rune(glyphCodeBase + slot))
+			slot++
+		}
+		lines[row] = line.String()
+	}
+	return lines, nil
+}
+
+// logoCells cuts art into the bitmaps of the characters drawing it, by
+// display row and column
+func logoCells(art []string) ([][][8]byte, error) {
+	if len(art) == 0 || len(art) > LogoMaxRows {
+		return nil, fmt.Errorf("a logo has 1 to %d rows, not %d", LogoMaxRows, len(art))
+	}
+	width := 0
+	for _, pixels := range art {
+		if len(pixels) > LogoMaxWidth {
+			return nil, fmt.Errorf("logo row %q is wider than %d pixels", pixels, LogoMaxWidth)
+		}
+		width = max(width, len(pixels))
+	}
+
+	cells := make([][][8]byte, (len(art)+7)/8)
+	for row := range cells {
+		cells[row] = make([][8]byte, (width+4)/5)
+	}
+	for y, pixels := range art {
+		for x, pixel := range []byte(pixels) {
+			switch pixel {
+			case '#':
+				cells[y/8][x/5][y%8] |= 0x10 >> (x % 5)
+			case '.', ' ':
+			default:
+				return nil, fmt.Errorf("logo pixel %q is neither \"#\" nor \".\"", pixel)
+			}
+		}
+	}
+	return cells, nil
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLogo(t *testing.T) {
+	chars := recordedChars{}
+	lines, err := LoadLogo(chars, []string{
+		"#....#",
+		".#...",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"#####",
+	})
+	require.NoError(t, err)
+	// Two display rows of two characters, slots numbered row by row
+	assert.Equal(t, []string{"\x00\x01", "\x02\x03"}, lines)
+	assert.Equal(t, [8]byte{0x10, 0x08}, chars[0])
+	assert.Equal(t, [8]byte{0x10}, chars[1])
+	assert.Equal(t, [8]byte{0x1F}, chars[2])
+	assert.Equal(t, [8]byte{}, chars[3])
+
+	_, err = LoadLogo(chars, []string{"#.#.#.#.#.#.#.#.#.#.#"})
+	assert.Error(t, err, "21 pixels wide")
+	_, err = LoadLogo(chars, make([]string, 17))
+	assert.Error(t, err, "17 rows")
+	_, err = LoadLogo(chars, []string{"#x"})
+	assert.Error(t, err)
+
+	dc, _ := newTestDisplayController(t)
+	_, err = LoadLogo(dc, []string{"#"})
+	assert.ErrorIs(t, err, ErrNoGlyphs)
+}
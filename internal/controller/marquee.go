@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultScrollSpeed is how long each step of a marquee is shown unless
+// ScrollText is given a speed
+const DefaultScrollSpeed = 500 * time.Millisecond
+
+// LineWriter writes text on a row, replacing the whole row when col is 0.
+// The display controllers and the screen layers are line writers.
+type LineWriter interface {
+	WriteTextAt(text string, row, col int) error
+}
+
+// Marquees scroll text too long for a row through it, one marquee per row,
+// each on a goroutine of its own. The display controllers scroll their rows
+// with them, and a writer drawing through a screen layer can keep its own.
+// The zero value is ready to use.
+type Marquees struct {
+	mutex sync.Mutex
+	rows  map[int]*marquee
+}
+
+// marquee is the goroutine scrolling one row
+type marquee struct {
+	stop   chan struct{}
+	exited chan struct{}
+}
+
+// ScrollText shows text on row of writer, which is width characters wide.
+// Text that fits is written once, padded to the row; longer text moves one character to the
+// left every speed (DefaultScrollSpeed if not positive) until it has left
+// the row, and then starts over. It scrolls until StopScroll or StopAll, or
+// until ScrollText is called for the row again; other writes to the row are
+// overwritten by the next step. The first step is written before ScrollText
+// returns, so an invalid row is returned as an error.
+func (m *Marquees) ScrollText(writer LineWriter, width, row int, text string, speed time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stop(row)
+	chars := []rune(text)
+	if err := writer.WriteTextAt(ScrollWindow(chars, 0, width), row, 0); err != nil {
+		return err
+	}
+	if len(chars) <= width {
+		return nil
+	}
+	if speed <= 0 {
+		speed = DefaultScrollSpeed
+	}
+
+	scroll := &marquee{stop: make(chan struct{}), exited: make(chan struct{})}
+	if m.rows == nil {
+		m.rows = make(map[int]*marquee)
+	}
+	m.rows[row] = scroll
+	go scroll.run(writer, width, row, chars, speed)
+	return nil
+}
+
+// StopScroll stops the marquee on row, leaving its last step shown. A
+// step being written is finished first.
+func (m *Marquees) StopScroll(row int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stop(row)
+}
+
+// StopAll stops every marquee, e.g. before the display is closed
+func (m *Marquees) StopAll() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for row := range m.rows {
+		m.stop(row)
+	}
+}
+
+// stop ends the marquee on row and waits for it. Caller must hold the
+// mutex.
+func (m *Marquees) stop(row int) {
+	scroll, ok := m.rows[row]
+	if !ok {
+		return
+	}
+	delete(m.rows, row)
+	close(scroll.stop)
+	<-scroll.exited
+}
+
+// run writes the steps of the marquee until it is stopped. A failed write
+// is tried again at the next step.
+func (s *marquee) run(writer LineWriter, width, row int, chars []rune, speed time.Duration) {
+	defer close(s.exited)
+	ticker := time.NewTicker(speed)
+	defer ticker.Stop()
+
+	position := 0
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		position++
+		if position > len(chars) {
+			position = 0
+		}
+		writer.WriteTextAt(ScrollWindow(chars, position, width), row, 0)
+	}
+}
+
+// ScrollWindow returns the width characters of text from position on,
+// padded with spaces once the text ends
+func ScrollWindow(text []rune, position, width int) string {
+	if position >= len(text) {
+		return strings.Repeat(" ", width)
+	}
+	window := text[position:min(position+width, len(text))]
+	return string(window) + strings.Repeat(" ", width-len(window))
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedRows keeps every line written to each row
+type recordedRows struct {
+	mutex sync.Mutex
+	rows  map[int][]string
+}
+
+func (r *recordedRows) WriteTextAt(text string, row, col int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if row < 0 || row > 1 {
+		return fmt.Errorf("invalid row: %d", row)
+	}
+	if r.rows == nil {
+		r.rows = make(map[int][]string)
+	}
+	r.rows[row] = append(r.rows[row], text)
+	return nil
+}
+
+func (r *recordedRows) written(row int) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]string(nil), r.rows[row]...)
+}
+
+func TestScrollWindow(t *testing.T) {
+	text := []rune("Grüße aus")
+	assert.Equal(t, "Grüße", ScrollWindow(text, 0, 5))
+	assert.Equal(t, "aus  ", ScrollWindow(text, 6, 5))
+	assert.Equal(t, "     ", ScrollWindow(text, 9, 5))
+}
+
+func TestMarquees(t *testing.T) {
+	var marquees Marquees
+	rows := &recordedRows{}
+
+	// Text that fits is written once
+	require.NoError(t, marquees.ScrollText(rows, 8, 1, "Short", time.Millisecond))
+	assert.Equal(t, []string{"Short   "}, rows.written(1))
+
+	require.NoError(t, marquees.ScrollText(rows, 8, 0, "Copying photos", time.Millisecond))
+	assert.Eventually(t, func() bool { return len(rows.written(0)) >= 17 }, time.Second, time.Millisecond)
+	marquees.StopScroll(0)
+	steps := rows.written(0)
+	assert.Equal(t, []string{"Copying ", "opying p", "pying ph"}, steps[:3])
+	assert.Equal(t, "        ", steps[14], "the text leaves the row")
+	assert.Equal(t, "Copying ", steps[15], "and starts over")
+
+	// Nothing is written once stopped
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, rows.written(0), len(steps))
+
+	t.Run("Invalid row", func(t *testing.T) {
+		assert.Error(t, marquees.ScrollText(rows, 8, 2, "Copying photos", time.Millisecond))
+	})
+
+	t.Run("A new marquee replaces the old one", func(t *testing.T) {
+		require.NoError(t, marquees.ScrollText(rows, 8, 0, "First long text", time.Hour))
+		require.NoError(t, marquees.ScrollText(rows, 8, 0, "Second long text", time.Millisecond))
+		assert.Len(t, marquees.rows, 1)
+		marquees.StopAll()
+		assert.Empty(t, marquees.rows)
+	})
+}
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/hardware"
+	"github.com/qnap/display-control/internal/oled"
+	"github.com/qnap/display-control/internal/translit"
+	"github.com/sirupsen/logrus"
+)
+
+// Display drivers selectable with Display.Driver
+const (
+	DriverQNAP    = "qnap"
+	DriverQNAPLCM = "qnap-lcm"
+	DriverSSD1306 = string(oled.SSD1306)
+	DriverSH1106  = string(oled.SH1106)
+)
+
+// OLEDDisplayController shows the character display on an I2C OLED module.
+// The module has no buttons and no serial link, so button handlers are never
+// called and the link always reads as closed.
+type OLEDDisplayController struct {
+	bus    hardware.I2CBus
+	device *oled.Device
+	grid   *oled.TextGrid
+	cols   int
+	rows   int
+	logger *logrus.Entry
+	// translit replaces the characters the font does not have
+	translit *translit.Transliterator
+
+	// mutex serializes drawing so an update reaches the module whole
+	mutex     sync.Mutex
+	closeOnce sync.Once
+	// marquees scroll text through rows, see ScrollText
+	marquees Marquees
+}
+
+// NewOLEDDisplayController opens the OLED module configured in Display.OLED
+func NewOLEDDisplayController(cfg *config.Config) (*OLEDDisplayController, error) {
+	bus, err := hardware.OpenI2CBus(cfg.Display.OLED.Bus)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := NewOLEDDisplayControllerWithBus(cfg, bus)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+	return dc, nil
+}
+
+// NewOLEDDisplayControllerWithBus creates an OLED display controller on an
+// already opened bus (a real bus or a fake for testing)
+func NewOLEDDisplayControllerWithBus(cfg *config.Config, bus hardware.I2CBus) (*OLEDDisplayController, error) {
+	logger := logrus.WithField("component", "oled_display")
+
+	device, err := oled.Open(bus, oled.Options{
+		Chip:    oled.Chip(cfg.Display.Driver),
+		Address: cfg.Display.OLED.Address,
+		Width:   cfg.Display.OLED.Width,
+		Height:  cfg.Display.OLED.Height,
+		Flip:    cfg.Display.OLED.Flip,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cols, rows := cfg.Display.Width, cfg.Display.Height
+	if cols <= 0 {
+		cols = displayWidth
+	}
+	if rows <= 0 {
+		rows = displayRows
+	}
+	grid, err := oled.NewTextGrid(device, cols, rows)
+	if err != nil {
+		device.SetPower(false)
+		return nil, err
+	}
+	// Glyphs are drawn from the same bitmaps the LCD gets in CGRAM
+	for slot, glyph := range glyphs {
+		grid.SetGlyph(rune(glyphCodeBase+slot), glyph.Rows)
+	}
+
+	dc := &OLEDDisplayController{
+		bus:      bus,
+		device:   device,
+		grid:     grid,
+		cols:     cols,
+		rows:     rows,
+		logger:   logger,
+		translit: newTransliterator(cfg, logger),
+	}
+	if cfg.Display.DefaultText != "" {
+		if err := dc.WriteText(cfg.Display.DefaultText); err != nil {
+			logger.WithError(err).Warn("Failed to write default text")
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"driver": cfg.Display.Driver,
+		"cols":   cols,
+		"rows":   rows,
+	}).Info("OLED display initialized successfully")
+	return dc, nil
+}
+
+// Update lets fn compose a display update and draws it in one flush. Nothing
+// is drawn if fn returns an error.
+func (dc *OLEDDisplayController) Update(fn func(update *DisplayUpdate) error) error {
+	update := newDisplayUpdate(dc.rows, false, dc.translit)
+	if err := fn(update); err != nil {
+		return err
+	}
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if update.backlight != nil && !*update.backlight {
+		if err := dc.device.SetPower(false); err != nil {
+			return fmt.Errorf("failed to switch the display off: %w", err)
+		}
+	}
+	for row, line := range update.lines {
+		if line != nil {
+			dc.grid.SetLine(row, *line)
+		}
+	}
+	if err := dc.grid.Flush(); err != nil {
+		return fmt.Errorf("failed to send display update: %w", err)
+	}
+	if update.backlight != nil && *update.backlight {
+		if err := dc.device.SetPower(true); err != nil {
+			return fmt.Errorf("failed to switch the display on: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteText replaces all lines with newline separated text
+func (dc *OLEDDisplayController) WriteText(text string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetText(text)
+		return nil
+	})
+}
+
+// WriteTextAt replaces a line. Like the serial panel, the whole line is
+// written, so col is ignored.
+func (dc *OLEDDisplayController) WriteTextAt(text string, row, col int) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		return update.SetLine(row, text)
+	})
+}
+
+// WriteLines replaces several lines, keyed by row, in a single update
+func (dc *OLEDDisplayController) WriteLines(lines map[int]string) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		for row, text := range lines {
+			if err := update.SetLine(row, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ScrollText scrolls text through row, see Marquees.ScrollText
+func (dc *OLEDDisplayController) ScrollText(row int, text string, speed time.Duration) error {
+	return dc.marquees.ScrollText(dc, dc.cols, row, text, speed)
+}
+
+// StopScroll stops the marquee on row, leaving its last step shown
+func (dc *OLEDDisplayController) StopScroll(row int) {
+	dc.marquees.StopScroll(row)
+}
+
+// ClearDisplay clears every line
+func (dc *OLEDDisplayController) ClearDisplay() error {
+	return dc.WriteText("")
+}
+
+// SetBacklight switches the display on or off; OLEDs have no backlight
+func (dc *OLEDDisplayController) SetBacklight(on bool) error {
+	return dc.Update(func(update *DisplayUpdate) error {
+		update.SetBacklight(on)
+		return nil
+	})
+}
+
+// ShowCopyStatus displays copy operation status
+func (dc *OLEDDisplayController) ShowCopyStatus(status string) error {
+	return dc.WriteText("USB Copy\n" + status)
+}
+
+// ShowProgress draws a progress bar on the second line. Redraws only send
+// the changed pages, so they are not rate limited.
+func (dc *OLEDDisplayController) ShowProgress(percent int) error {
+	return dc.WriteTextAt(RenderProgressBarWidth(percent, dc.cols), progressRow, 0)
+}
+
+// DefineCustomChar replaces the bitmap drawn for the custom character in
+// slot (0 to 7). Characters already drawn keep their shape until their line
+// is written again.
+func (dc *OLEDDisplayController) DefineCustomChar(slot int, bitmap [8]byte) error {
+	if err := checkGlyphSlot(slot); err != nil {
+		return err
+	}
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	dc.grid.SetGlyph(rune(glyphCodeBase+slot), maskGlyph(bitmap))
+	return nil
+}
+
+// SetButtonHandler is accepted for compatibility; the module has no buttons
+func (dc *OLEDDisplayController) SetButtonHandler(handler ButtonEventHandler) {}
+
+// RequestButtonState does nothing; the module has no buttons
+func (dc *OLEDDisplayController) RequestButtonState() error {
+	return nil
+}
+
+// SetSerialCopyDetection does nothing; there are no serial frames to decode
+func (dc *OLEDDisplayController) SetSerialCopyDetection(enabled bool) {}
+
+// SerialCopyDetection always reports false
+func (dc *OLEDDisplayController) SerialCopyDetection() bool {
+	return false
+}
+
+// SetBreakerHandler is accepted for compatibility; there is no serial link
+// that could fail
+func (dc *OLEDDisplayController) SetBreakerHandler(handler BreakerEventHandler) {}
+
+// LinkState always reports a closed breaker
+func (dc *OLEDDisplayController) LinkState() BreakerState {
+	return BreakerClosed
+}
+
+// FrameStats always reports zero counts; the bus confirms every transfer
+// itself
+func (dc *OLEDDisplayController) FrameStats() FrameStats {
+	return FrameStats{}
+}
+
+// Close switches the display off and closes the bus
+func (dc *OLEDDisplayController) Close() error {
+	var err error
+	dc.closeOnce.Do(func() {
+		dc.logger.Info("Closing OLED display")
+		dc.marquees.StopAll()
+		dc.mutex.Lock()
+		if powerErr := dc.device.SetPower(false); powerErr != nil {
+			dc.logger.WithError(powerErr).Warn("Failed to switch the display off")
+		}
+		dc.mutex.Unlock()
+		err = dc.bus.Close()
+	})
+	return err
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeI2CBus records writes to an OLED module
+type fakeI2CBus struct {
+	writes [][]byte
+	closed bool
+}
+
+func (b *fakeI2CBus) Tx(addr uint16, w, r []byte) error {
+	b.writes = append(b.writes, append([]byte(nil), w...))
+	return nil
+}
+
+func (b *fakeI2CBus) Close() error {
+	b.closed = true
+	return nil
+}
+
+// dataWrites counts the page data writes
+func (b *fakeI2CBus) dataWrites() int {
+	count := 0
+	for _, w := range b.writes {
+		if w[0] == 0x40 {
+			count++
+		}
+	}
+	return count
+}
+
+func TestOLEDDisplayController(t *testing.T) {
+	cfg := &config.Config{Display: config.DisplayConfig{Driver: DriverSSD1306}}
+	bus := &fakeI2CBus{}
+	dc, err := NewOLEDDisplayControllerWithBus(cfg, bus)
+	require.NoError(t, err)
+
+	// A 16x2 grid gives each line four of the eight pages; the font is
+	// centered in them and drawn on the middle two
+	bus.writes = nil
+	require.NoError(t, dc.WriteTextAt("Hello", 0, 0))
+	assert.Equal(t, 2, bus.dataWrites())
+	assert.Error(t, dc.WriteTextAt("x", 2, 0))
+
+	bus.writes = nil
+	require.NoError(t, dc.WriteLines(map[int]string{0: "Hello", 1: "World"}))
+	assert.Equal(t, 2, bus.dataWrites(), "the unchanged first line is not sent again")
+
+	bus.writes = nil
+	require.NoError(t, dc.SetBacklight(false))
+	assert.Equal(t, [][]byte{{0x00, 0xAE}}, bus.writes)
+
+	assert.Equal(t, BreakerClosed, dc.LinkState())
+	require.NoError(t, dc.Close())
+	require.NoError(t, dc.Close())
+	assert.True(t, bus.closed)
+}
+
+func TestOLEDDisplayController_GridTooLarge(t *testing.T) {
+	cfg := &config.Config{Display: config.DisplayConfig{
+		Driver: DriverSH1106,
+		Width:  16,
+		Height: 8,
+		OLED:   config.OLEDConfig{Height: 32},
+	}}
+	_, err := NewOLEDDisplayControllerWithBus(cfg, &fakeI2CBus{})
+	assert.Error(t, err, "eight rows do not fit 32 pixels")
+}
+
+func TestNewDisplay_UnknownDriver(t *testing.T) {
+	_, err := OpenDisplay(&config.Config{Display: config.DisplayConfig{Driver: "st7920"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "qnap, qnap-lcm, ssd1306, sh1106, matrix-orbital, crystalfontz, hd44780, pcf8574")
+}
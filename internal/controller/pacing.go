@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+)
+
+// Panel command kinds, as named in the command_gaps_ms setting
+const (
+	commandLine      = "line"
+	commandBacklight = "backlight"
+	commandGlyph     = "glyph"
+	commandRequest   = "request"
+)
+
+// commandKinds lists the command kinds that can be paced
+var commandKinds = []string{commandBacklight, commandGlyph, commandLine, commandRequest}
+
+// WritePacing spaces out the commands sent to the panel. Some panel firmware
+// drops bytes that arrive while it refreshes the LCD, which shows as an
+// occasional garbled line; a pause after each command gives the refresh time
+// to finish.
+type WritePacing struct {
+	// CommandGap is the pause after every command, counted from when its
+	// last byte has left the port
+	CommandGap time.Duration
+	// Gaps replace CommandGap for the named command kinds
+	Gaps map[string]time.Duration
+}
+
+// profilePacing is the pacing of the built-in hardware profiles; profiles
+// not listed send every update in one write
+var profilePacing = map[string]WritePacing{
+	// slow-refresh panels refresh the LCD after every line they receive
+	"slow-refresh": {
+		CommandGap: 10 * time.Millisecond,
+		Gaps:       map[string]time.Duration{commandLine: 40 * time.Millisecond},
+	},
+}
+
+// WritePacingFromConfig resolves the pacing of the configured hardware
+// profile and applies the configured gaps on top of it
+func WritePacingFromConfig(cfg config.HardwareConfig) (WritePacing, error) {
+	name := cfg.Profile
+	if name == "" {
+		name = DefaultHardwareProfile
+	}
+	if _, exists := hardwareProfiles[name]; !exists {
+		return WritePacing{}, fmt.Errorf("unknown hardware profile %q (available: %s)",
+			name, strings.Join(HardwareProfiles(), ", "))
+	}
+
+	profile := profilePacing[name]
+	pacing := WritePacing{CommandGap: profile.CommandGap, Gaps: make(map[string]time.Duration)}
+	for kind, gap := range profile.Gaps {
+		pacing.Gaps[kind] = gap
+	}
+
+	if cfg.CommandGap != nil {
+		if *cfg.CommandGap < 0 {
+			return WritePacing{}, fmt.Errorf("command_gap_ms must not be negative, got %d", *cfg.CommandGap)
+		}
+		pacing.CommandGap = time.Duration(*cfg.CommandGap) * time.Millisecond
+	}
+	kinds := make([]string, 0, len(cfg.CommandGaps))
+	for kind := range cfg.CommandGaps {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		gap := cfg.CommandGaps[kind]
+		if !isCommandKind(kind) {
+			return WritePacing{}, fmt.Errorf("unknown command %q in command_gaps_ms (available: %s)",
+				kind, strings.Join(commandKinds, ", "))
+		}
+		if gap < 0 {
+			return WritePacing{}, fmt.Errorf("gap for %s commands must not be negative, got %d", kind, gap)
+		}
+		pacing.Gaps[kind] = time.Duration(gap) * time.Millisecond
+	}
+
+	return pacing, nil
+}
+
+// isCommandKind reports whether kind names a pacable command
+func isCommandKind(kind string) bool {
+	for _, known := range commandKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether any command is followed by a pause
+func (p WritePacing) Enabled() bool {
+	if p.CommandGap > 0 {
+		return true
+	}
+	for _, gap := range p.Gaps {
+		if gap > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gap returns the pause after a command of the given kind
+func (p WritePacing) gap(kind string) time.Duration {
+	if gap, exists := p.Gaps[kind]; exists {
+		return gap
+	}
+	return p.CommandGap
+}
+
+// transmitTime is how long the port takes to send n bytes at 8N1, ten bits
+// per byte
+func transmitTime(n, baudRate int) time.Duration {
+	if baudRate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * 10 * time.Second / time.Duration(baudRate)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePacingFromConfig(t *testing.T) {
+	t.Run("Profiles", func(t *testing.T) {
+		pacing, err := WritePacingFromConfig(config.HardwareConfig{})
+		require.NoError(t, err)
+		assert.False(t, pacing.Enabled(), "the generic profile sends updates in one write")
+
+		pacing, err = WritePacingFromConfig(config.HardwareConfig{Profile: "slow-refresh"})
+		require.NoError(t, err)
+		assert.True(t, pacing.Enabled())
+		assert.Equal(t, 40*time.Millisecond, pacing.gap(commandLine))
+		assert.Equal(t, 10*time.Millisecond, pacing.gap(commandBacklight))
+
+		_, err = WritePacingFromConfig(config.HardwareConfig{Profile: "ts-999"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		gap := 5
+		pacing, err := WritePacingFromConfig(config.HardwareConfig{
+			Profile:     "slow-refresh",
+			CommandGap:  &gap,
+			CommandGaps: map[string]int{"glyph": 20, "line": 0},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Millisecond, pacing.gap(commandRequest))
+		assert.Equal(t, 20*time.Millisecond, pacing.gap(commandGlyph))
+		assert.Equal(t, time.Duration(0), pacing.gap(commandLine))
+
+		// Overrides never leak into the built-in profile
+		assert.Equal(t, 40*time.Millisecond, profilePacing["slow-refresh"].Gaps[commandLine])
+	})
+
+	t.Run("Invalid gaps", func(t *testing.T) {
+		negative := -1
+		_, err := WritePacingFromConfig(config.HardwareConfig{CommandGap: &negative})
+		assert.Error(t, err)
+
+		_, err = WritePacingFromConfig(config.HardwareConfig{CommandGaps: map[string]int{"cursor": 5}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "backlight, glyph, line, request")
+	})
+}
+
+func TestTransmitTime(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, transmitTime(12, 1200))
+	assert.Equal(t, time.Duration(0), transmitTime(12, 0))
+}
+
+func TestDisplayController_PacedWrites(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SerialPort.BaudRate = 115200
+	cfg.Hardware.CommandGaps = map[string]int{"line": 30}
+
+	port := &countingPort{MockSerialPort: serial.NewMockSerialPort()}
+	dc, err := NewDisplayControllerWithPort(cfg, port)
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+	port.mutex.Lock()
+	port.writes, port.times = nil, nil
+	port.mutex.Unlock()
+
+	require.NoError(t, dc.Update(func(update *DisplayUpdate) error {
+		update.SetBacklight(true)
+		update.SetText("Line 1\nLine 2")
+		return nil
+	}))
+
+	port.mutex.Lock()
+	defer port.mutex.Unlock()
+	require.Len(t, port.writes, 3, "each command is written on its own")
+	assert.Equal(t, lineCommand(0, "Line 1          "), port.writes[0])
+	assert.Equal(t, lineCommand(1, "Line 2          "), port.writes[1])
+	assert.Equal(t, []byte{0x4D, 0x5E, 0x01}, port.writes[2])
+	assert.GreaterOrEqual(t, port.times[1].Sub(port.times[0]), 30*time.Millisecond)
+	assert.GreaterOrEqual(t, port.times[2].Sub(port.times[1]), 30*time.Millisecond)
+}
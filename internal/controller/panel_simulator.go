@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/qnap/display-control/internal/serial"
+)
+
+// simulatorIdleState is the state byte of the generic profile with no button
+// held: ENTER and SELECT read 1 when released, the copy button 0
+const simulatorIdleState = 0x03
+
+// PanelSimulator is a QNAP front panel in memory, to run DisplayController
+// without hardware. It decodes the line and backlight commands into the
+// screen a panel would show and answers button state requests like the
+// panel's MCU, with the buttons laid out as in the generic profile.
+type PanelSimulator struct {
+	mutex     sync.Mutex
+	cols      int
+	lines     []string
+	backlight bool
+	state     byte
+	pending   []byte
+	frames    []string
+	closed    bool
+}
+
+// NewPanelSimulator creates a blank 16x2 panel with no button held
+func NewPanelSimulator() *PanelSimulator {
+	return NewPanelSimulatorWithGeometry(displayWidth, displayRows)
+}
+
+// NewPanelSimulatorWithGeometry creates a blank panel of cols characters
+// and rows lines, such as a 20x4 one, with no button held
+func NewPanelSimulatorWithGeometry(cols, rows int) *PanelSimulator {
+	s := &PanelSimulator{state: simulatorIdleState, cols: cols, lines: make([]string, rows)}
+	for row := range s.lines {
+		s.lines[row] = strings.Repeat(" ", cols)
+	}
+	return s
+}
+
+// Screen returns the text the panel shows, one line per row padded to the
+// display width
+func (s *PanelSimulator) Screen() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return strings.Join(s.lines, "\n")
+}
+
+// Lines returns the rows the panel shows, padded to the display width
+func (s *PanelSimulator) Lines() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.lines...)
+}
+
+// Frames returns every screen the panel showed, in order. A write that left
+// the screen as it was adds no frame.
+func (s *PanelSimulator) Frames() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.frames...)
+}
+
+// Backlight reports whether the backlight is on
+func (s *PanelSimulator) Backlight() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.backlight
+}
+
+// Press holds a button down and reports the new state to the controller
+func (s *PanelSimulator) Press(button PanelButton) error {
+	return s.setButton(button, true)
+}
+
+// Release lets go of a button and reports the new state to the controller
+func (s *PanelSimulator) Release(button PanelButton) error {
+	return s.setButton(button, false)
+}
+
+// setButton changes the state byte for button and queues a state frame
+func (s *PanelSimulator) setButton(button PanelButton, pressed bool) error {
+	var bit byte
+	var activeLow bool
+	switch button {
+	case ButtonEnter:
+		bit, activeLow = 1<<0, true
+	case ButtonSelect:
+		bit, activeLow = 1<<1, true
+	case ButtonUSBCopy:
+		bit = 1 << 2
+	default:
+		return fmt.Errorf("unknown button %d", button)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if pressed != activeLow {
+		s.state |= bit
+	} else {
+		s.state &^= bit
+	}
+	s.queueState()
+	return nil
+}
+
+// queueState queues a frame with the current button state. Caller must hold
+// the mutex.
+func (s *PanelSimulator) queueState() {
+	s.pending = append(s.pending, qnapproto.ButtonState{State: s.state}.Encode()...)
+}
+
+// Write performs the panel commands in data, which may hold several
+func (s *PanelSimulator) Write(data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("panel simulator is closed")
+	}
+	before := strings.Join(s.lines, "\n")
+	for len(data) > 0 {
+		n, err := s.execute(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	if after := strings.Join(s.lines, "\n"); after != before {
+		s.frames = append(s.frames, after)
+	}
+	return nil
+}
+
+// execute performs the command at the start of data and returns its length.
+// Caller must hold the mutex.
+func (s *PanelSimulator) execute(data []byte) (int, error) {
+	command, n, err := qnapproto.DecodeCommand(data)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported panel command: %w", err)
+	}
+
+	switch command := command.(type) {
+	case qnapproto.Line:
+		if command.Row >= len(s.lines) {
+			return 0, fmt.Errorf("invalid row: %d", command.Row)
+		}
+		text := command.Text[:min(len(command.Text), s.cols)]
+		s.lines[command.Row] = text + strings.Repeat(" ", s.cols-len(text))
+
+	case qnapproto.Backlight:
+		s.backlight = command.On
+
+	case qnapproto.RequestState:
+		s.queueState()
+
+	case qnapproto.EnableReporting:
+		// State changes are always queued
+	}
+	return n, nil
+}
+
+// Read reads queued state frames into buffer
+func (s *PanelSimulator) Read(buffer []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return 0, fmt.Errorf("panel simulator is closed")
+	}
+	n := copy(buffer, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// ReadAvailable returns all queued state frames
+func (s *PanelSimulator) ReadAvailable() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, fmt.Errorf("panel simulator is closed")
+	}
+	data := s.pending
+	s.pending = nil
+	return data, nil
+}
+
+// WriteString writes raw panel commands
+func (s *PanelSimulator) WriteString(text string) error {
+	return s.Write([]byte(text))
+}
+
+// WriteText shows two lines; the position is ignored like the panel's line
+// commands do
+func (s *PanelSimulator) WriteText(line1, line2 string, col, row int) error {
+	return s.Write(append(qnapproto.Line{Row: 0, Text: line1}.Encode(), qnapproto.Line{Row: 1, Text: line2}.Encode()...))
+}
+
+// IsConnected reports whether the simulator is still open
+func (s *PanelSimulator) IsConnected() bool {
+	return s.IsOpen()
+}
+
+// IsOpen reports whether the simulator is still open
+func (s *PanelSimulator) IsOpen() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return !s.closed
+}
+
+// Close closes the simulator; later reads and writes fail
+func (s *PanelSimulator) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+// reopen opens a closed simulator again, as opening the serial port again
+// would, keeping what the panel shows
+func (s *PanelSimulator) reopen() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = false
+}
+
+// Compile-time check that the simulator can replace the serial port
+var _ serial.SerialPortInterface = (*PanelSimulator)(nil)
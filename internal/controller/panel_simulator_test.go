@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/qnap/display-control/internal/testutil"
+	"github.com/qnap/display-control/pkg/led"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanelSimulator(t *testing.T) {
+	panel := NewPanelSimulator()
+
+	batch := append(qnapproto.Line{Row: 0, Text: "Hello"}.Encode(), qnapproto.Line{Row: 1, Text: "World"}.Encode()...)
+	require.NoError(t, panel.Write(append(batch, qnapproto.Backlight{On: true}.Encode()...)))
+	assert.Equal(t, "Hello           \nWorld           ", panel.Screen())
+	assert.True(t, panel.Backlight())
+
+	require.NoError(t, panel.Write(qnapproto.Line{Row: 1, Text: "World"}.Encode()))
+	require.NoError(t, panel.WriteText("Line 1", "Line 2", 0, 0))
+	assert.Equal(t, []string{
+		"Hello           \nWorld           ",
+		"Line 1          \nLine 2          ",
+	}, panel.Frames(), "a write that changes nothing adds no frame")
+
+	assert.Error(t, panel.Write([]byte{0x4D, 0x0C, 0x02, 0x01, 'x'}), "invalid row")
+	assert.Error(t, panel.Write([]byte{0x4D, 0x26, 0x00}), "unsupported command")
+
+	require.NoError(t, panel.Write([]byte{0x4D, 0x05}))
+	require.NoError(t, panel.Press(ButtonSelect))
+	require.NoError(t, panel.Release(ButtonSelect))
+	require.NoError(t, panel.Press(ButtonUSBCopy))
+	data, err := panel.ReadAvailable()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x53, 0x05, 0x00, 0x03, // answer to the state request
+		0x53, 0x05, 0x00, 0x01, // SELECT down (active low)
+		0x53, 0x05, 0x00, 0x03,
+		0x53, 0x05, 0x00, 0x07, // copy down (active high)
+	}, data)
+
+	require.NoError(t, panel.Close())
+	assert.Error(t, panel.Write(qnapproto.Backlight{On: false}.Encode()))
+}
+
+func TestDisplayController_PanelSimulator(t *testing.T) {
+	panel := NewPanelSimulator()
+	dc, err := NewDisplayControllerWithPort(config.DefaultConfig(), panel)
+	require.NoError(t, err)
+	t.Cleanup(func() { dc.Close() })
+
+	pressed := make(chan PanelButton, 4)
+	dc.SetButtonHandler(func(button PanelButton, isPressed bool) {
+		if isPressed {
+			pressed <- button
+		}
+	})
+
+	require.NoError(t, dc.WriteText("NAS ready\n10.0.0.2"))
+	testutil.AssertScreen(t, panel, "NAS ready", "10.0.0.2")
+
+	require.NoError(t, panel.Press(ButtonEnter))
+	select {
+	case button := <-pressed:
+		assert.Equal(t, ButtonEnter, button)
+	case <-time.After(time.Second):
+		t.Fatal("button press was not reported")
+	}
+}
+
+func TestNewSimulatedSystemController(t *testing.T) {
+	panel := NewPanelSimulator()
+	leds := led.NewMock()
+	sc, err := NewSimulatedSystemController(config.DefaultConfig(), panel, leds)
+	require.NoError(t, err)
+	t.Cleanup(func() { sc.Close() })
+
+	assert.Equal(t, CopyButtonSerial, sc.CopyButtonSource())
+	states, err := leds.GetLEDStates()
+	require.NoError(t, err)
+	assert.True(t, states[StatusGreen], "the simulated panel answers, so the status LED is green")
+	assert.False(t, states[StatusRed])
+
+	pressed := make(chan PanelButton, 4)
+	sc.SetButtonHandler(func(button PanelButton, isPressed bool) {
+		if isPressed {
+			pressed <- button
+		}
+	})
+	require.NoError(t, panel.Press(ButtonUSBCopy))
+	select {
+	case button := <-pressed:
+		assert.Equal(t, ButtonUSBCopy, button)
+	case <-time.After(time.Second):
+		t.Fatal("copy button press was not reported")
+	}
+
+	require.NoError(t, sc.SetDiskActivity(4, true))
+	states, err = leds.GetLEDStates()
+	require.NoError(t, err)
+	assert.True(t, states[Disk4])
+}
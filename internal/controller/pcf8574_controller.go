@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/hardware"
+)
+
+// DriverPCF8574 selects an HD44780 character LCD behind a PCF8574 I2C
+// backpack
+const DriverPCF8574 = "pcf8574"
+
+// DefaultPCF8574Address is the backpack's address when the configuration
+// does not set one
+const DefaultPCF8574Address = 0x27
+
+// Pins of the PCF8574 on the usual backpack; R/W (P1) is held low
+const (
+	pcf8574RS        = 0
+	pcf8574E         = 2
+	pcf8574Backlight = 3
+	pcf8574D4        = 4
+)
+
+// NewPCF8574Controller opens the backpack configured in Display.PCF8574.
+// The display is an HD44780, driven through the expander's pins like on GPIO
+// lines, so it shares the HD44780 controller; the backpack has no buttons.
+func NewPCF8574Controller(cfg *config.Config) (*HD44780Controller, error) {
+	bus, err := hardware.OpenI2CBus(cfg.Display.PCF8574.Bus)
+	if err != nil {
+		return nil, err
+	}
+	return NewPCF8574ControllerWithBus(cfg, bus)
+}
+
+// NewPCF8574ControllerWithBus creates a backpack controller on an already
+// opened bus (a real bus or a fake for testing). The bus is closed with the
+// controller, or right away if the display cannot be initialized.
+func NewPCF8574ControllerWithBus(cfg *config.Config, bus hardware.I2CBus) (*HD44780Controller, error) {
+	addr := cfg.Display.PCF8574.Address
+	if addr == 0 {
+		addr = DefaultPCF8574Address
+	}
+	expander := hardware.NewPCF8574(bus, addr)
+
+	// The lines are in the order of the hd44780 line indexes
+	lines, err := expander.Lines(pcf8574RS, pcf8574E, pcf8574D4, pcf8574D4+1, pcf8574D4+2, pcf8574D4+3)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+	backlight, err := expander.Lines(pcf8574Backlight)
+	if err != nil {
+		lines.Close()
+		return nil, err
+	}
+
+	dc, err := NewHD44780ControllerWithLines(cfg, lines, backlight, nil)
+	if err != nil {
+		lines.Close()
+		backlight.Close()
+		return nil, err
+	}
+	return dc, nil
+}
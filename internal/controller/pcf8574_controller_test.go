@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPCF8574Controller(t *testing.T) {
+	cfg := &config.Config{Display: config.DisplayConfig{Driver: DriverPCF8574}}
+	bus := &fakeI2CBus{}
+	dc, err := NewPCF8574ControllerWithBus(cfg, bus)
+	require.NoError(t, err)
+	last := bus.writes[len(bus.writes)-1]
+	assert.Equal(t, []byte{1 << pcf8574Backlight}, last, "the backlight is on, all else low")
+
+	// Every nibble is three writes: E low, high and low again
+	bus.writes = nil
+	require.NoError(t, dc.WriteTextAt("Hi", 1, 0))
+	assert.Len(t, bus.writes, (1+displayWidth)*2*3)
+	// The high nibble of the address command 0xC0 (row 1) is clocked in
+	assert.Equal(t, []byte{1<<pcf8574Backlight | 1<<pcf8574E | 0xC<<pcf8574D4}, bus.writes[1])
+
+	require.NoError(t, dc.SetBacklight(false))
+	assert.Equal(t, byte(0), bus.writes[len(bus.writes)-1][0]&(1<<pcf8574Backlight))
+
+	require.NoError(t, dc.Close())
+	assert.True(t, bus.closed)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import "sync"
+
+// quietLEDs keeps the red status LED off while quiet. The last requested
+// status is remembered so it can be restored when quiet ends.
+type quietLEDs struct {
+	LEDControllerInterface
+
+	mutex      sync.Mutex
+	quiet      bool
+	red, green bool
+}
+
+// newQuietLEDs wraps an LED controller
+func newQuietLEDs(leds LEDControllerInterface) *quietLEDs {
+	return &quietLEDs{LEDControllerInterface: leds}
+}
+
+// SetLED switches a single LED; the red status LED stays off while quiet
+func (q *quietLEDs) SetLED(led PanelLED, on bool) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	switch led {
+	case StatusRed:
+		q.red = on
+		if q.quiet {
+			return nil
+		}
+	case StatusGreen:
+		q.green = on
+	}
+	return q.LEDControllerInterface.SetLED(led, on)
+}
+
+// SetStatusLED sets the status LED pair. While quiet a red status shows
+// green instead, so the panel does not look idle.
+func (q *quietLEDs) SetStatusLED(red bool, green bool) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.red, q.green = red, green
+	return q.apply()
+}
+
+// setQuiet turns quiet on or off and updates the status LED
+func (q *quietLEDs) setQuiet(quiet bool) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.quiet == quiet {
+		return nil
+	}
+	q.quiet = quiet
+	return q.apply()
+}
+
+// apply shows the remembered status. Caller must hold the mutex.
+func (q *quietLEDs) apply() error {
+	if q.quiet {
+		return q.LEDControllerInterface.SetStatusLED(false, q.red || q.green)
+	}
+	return q.LEDControllerInterface.SetStatusLED(q.red, q.green)
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/qnapproto"
+)
+
+// ButtonQuirks describes how a panel's firmware reports its buttons.
+// Panels differ: the bit positions and polarity of the state byte vary between
+// models, and the copy button is reported through a bit in the regular 0x53
+// state frame, through separate two byte frames starting with 0x55 ('U') or
+// 0x43 ('C'), or both. Some panels also repeat their frames for as long as the
+// button is held.
+type ButtonQuirks struct {
+	// Profile is the name of the hardware profile the quirks came from
+	Profile string
+
+	// Bit positions of the buttons in the state byte
+	EnterBit  uint
+	SelectBit uint
+	CopyBit   uint
+	// Inverted buttons are pressed when their bit is 0
+	EnterInverted  bool
+	SelectInverted bool
+	CopyInverted   bool
+
+	// CopyInStateFrame reports the copy button through CopyBit of state frames
+	CopyInStateFrame bool
+	// CopyFramePrefixes are first bytes of separate copy press frames
+	CopyFramePrefixes []byte
+	// CopyFrameLength is the total length of a separate copy frame
+	CopyFrameLength int
+	// CopyDuplicateWindow suppresses copy presses this soon after the previous one
+	CopyDuplicateWindow time.Duration
+}
+
+// DefaultHardwareProfile is used when the configuration does not name one
+const DefaultHardwareProfile = "generic"
+
+// withStandardBits applies the state byte layout from the qnapctl reference:
+// ENTER on bit 0 and SELECT on bit 1 (both active low), copy on bit 2 (active high)
+func withStandardBits(q ButtonQuirks) ButtonQuirks {
+	q.EnterBit, q.EnterInverted = 0, true
+	q.SelectBit, q.SelectInverted = 1, true
+	q.CopyBit, q.CopyInverted = 2, false
+	return q
+}
+
+// hardwareProfiles are the built-in button quirk sets
+var hardwareProfiles = map[string]ButtonQuirks{
+	// generic accepts every known copy button encoding
+	"generic": withStandardBits(ButtonQuirks{
+		CopyInStateFrame:  true,
+		CopyFramePrefixes: []byte{0x55, 0x43},
+		CopyFrameLength:   2,
+	}),
+	// state-frame panels only use the state bit but repeat frames while held
+	"state-frame": withStandardBits(ButtonQuirks{
+		CopyInStateFrame:    true,
+		CopyDuplicateWindow: 300 * time.Millisecond,
+	}),
+	// slow-refresh panels decode buttons like generic ones but need paced
+	// writes, see profilePacing
+	"slow-refresh": withStandardBits(ButtonQuirks{
+		CopyInStateFrame:  true,
+		CopyFramePrefixes: []byte{0x55, 0x43},
+		CopyFrameLength:   2,
+	}),
+	// a125-i2c panels are read over SMBus, see I2CPanelPort; their button
+	// register has the state byte layout
+	I2CPanelProfile: withStandardBits(ButtonQuirks{
+		CopyInStateFrame: true,
+	}),
+	// prefixed-frame panels send 0x55/0x43 frames, repeated while held
+	"prefixed-frame": withStandardBits(ButtonQuirks{
+		CopyFramePrefixes:   []byte{0x55, 0x43},
+		CopyFrameLength:     2,
+		CopyDuplicateWindow: 500 * time.Millisecond,
+	}),
+}
+
+// HardwareProfiles returns the names of the built-in hardware profiles
+func HardwareProfiles() []string {
+	names := make([]string, 0, len(hardwareProfiles))
+	for name := range hardwareProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ButtonQuirksFromConfig resolves the configured hardware profile and applies
+// any per-quirk overrides on top of it
+func ButtonQuirksFromConfig(cfg config.HardwareConfig) (ButtonQuirks, error) {
+	name := cfg.Profile
+	if name == "" {
+		name = DefaultHardwareProfile
+	}
+
+	profile, exists := hardwareProfiles[name]
+	if !exists {
+		return ButtonQuirks{}, fmt.Errorf("unknown hardware profile %q (available: %s)",
+			name, strings.Join(HardwareProfiles(), ", "))
+	}
+
+	quirks := profile
+	quirks.Profile = name
+	quirks.CopyFramePrefixes = append([]byte(nil), profile.CopyFramePrefixes...)
+
+	if cfg.CopyDuplicateWindow != nil {
+		quirks.CopyDuplicateWindow = time.Duration(*cfg.CopyDuplicateWindow) * time.Millisecond
+	}
+	if err := applyBitOverrides(&quirks, cfg); err != nil {
+		return ButtonQuirks{}, err
+	}
+	if cfg.CopyFramePrefixes != nil {
+		quirks.CopyFramePrefixes = quirks.CopyFramePrefixes[:0]
+		for _, prefix := range cfg.CopyFramePrefixes {
+			if prefix < 0 || prefix > 0xFF {
+				return ButtonQuirks{}, fmt.Errorf("copy frame prefix %d is not a byte", prefix)
+			}
+			if qnapproto.Reserved(byte(prefix)) {
+				return ButtonQuirks{}, fmt.Errorf("copy frame prefix 0x%02x collides with the panel protocol", prefix)
+			}
+			quirks.CopyFramePrefixes = append(quirks.CopyFramePrefixes, byte(prefix))
+		}
+		if quirks.CopyFrameLength == 0 {
+			quirks.CopyFrameLength = 2
+		}
+	}
+
+	return quirks, nil
+}
+
+// isCopyFramePrefix reports whether b starts a separate copy frame
+func (q ButtonQuirks) isCopyFramePrefix(b byte) bool {
+	for _, prefix := range q.CopyFramePrefixes {
+		if b == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBitOverrides replaces the profile's bit layout with configured values
+// and checks that the buttons still use distinct bits
+func applyBitOverrides(q *ButtonQuirks, cfg config.HardwareConfig) error {
+	bits := []struct {
+		name   string
+		value  *int
+		target *uint
+	}{
+		{"enter_bit", cfg.EnterBit, &q.EnterBit},
+		{"select_bit", cfg.SelectBit, &q.SelectBit},
+		{"copy_bit", cfg.CopyBit, &q.CopyBit},
+	}
+	for _, bit := range bits {
+		if bit.value == nil {
+			continue
+		}
+		if *bit.value < 0 || *bit.value > 7 {
+			return fmt.Errorf("%s must be between 0 and 7, got %d", bit.name, *bit.value)
+		}
+		*bit.target = uint(*bit.value)
+	}
+
+	if cfg.EnterInverted != nil {
+		q.EnterInverted = *cfg.EnterInverted
+	}
+	if cfg.SelectInverted != nil {
+		q.SelectInverted = *cfg.SelectInverted
+	}
+	if cfg.CopyInverted != nil {
+		q.CopyInverted = *cfg.CopyInverted
+	} else if cfg.CopyActiveLow != nil {
+		q.CopyInverted = *cfg.CopyActiveLow
+	}
+
+	if q.EnterBit == q.SelectBit || (q.CopyInStateFrame && (q.CopyBit == q.EnterBit || q.CopyBit == q.SelectBit)) {
+		return fmt.Errorf("buttons must use distinct bits (enter=%d, select=%d, copy=%d)",
+			q.EnterBit, q.SelectBit, q.CopyBit)
+	}
+	return nil
+}
+
+// bitPressed decodes one button from the state byte
+func bitPressed(state byte, bit uint, inverted bool) bool {
+	set := state&(1<<bit) != 0
+	return set != inverted
+}
+
+// enterPressed decodes the ENTER button from a state byte
+func (q ButtonQuirks) enterPressed(state byte) bool {
+	return bitPressed(state, q.EnterBit, q.EnterInverted)
+}
+
+// selectPressed decodes the SELECT button from a state byte
+func (q ButtonQuirks) selectPressed(state byte) bool {
+	return bitPressed(state, q.SelectBit, q.SelectInverted)
+}
+
+// copyPressed decodes the copy button from a state byte
+func (q ButtonQuirks) copyPressed(state byte) bool {
+	return bitPressed(state, q.CopyBit, q.CopyInverted)
+}
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestButtonQuirksFromConfig(t *testing.T) {
+	t.Run("Empty profile uses generic", func(t *testing.T) {
+		quirks, err := ButtonQuirksFromConfig(config.HardwareConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, "generic", quirks.Profile)
+		assert.True(t, quirks.CopyInStateFrame)
+		assert.Equal(t, []byte{0x55, 0x43}, quirks.CopyFramePrefixes)
+	})
+
+	t.Run("Unknown profile", func(t *testing.T) {
+		_, err := ButtonQuirksFromConfig(config.HardwareConfig{Profile: "ts-999"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "prefixed-frame")
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		window := 50
+		inverted := true
+		quirks, err := ButtonQuirksFromConfig(config.HardwareConfig{
+			Profile:             "state-frame",
+			CopyDuplicateWindow: &window,
+			CopyInverted:        &inverted,
+			CopyFramePrefixes:   []int{0x55},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 50*time.Millisecond, quirks.CopyDuplicateWindow)
+		assert.True(t, quirks.CopyInverted)
+		assert.Equal(t, []byte{0x55}, quirks.CopyFramePrefixes)
+		assert.Equal(t, 2, quirks.CopyFrameLength)
+
+		// Overrides never leak into the built-in profile
+		assert.Empty(t, hardwareProfiles["state-frame"].CopyFramePrefixes)
+	})
+
+	t.Run("Bit mapping", func(t *testing.T) {
+		enterBit, selectBit, copyBit := 4, 5, 6
+		notInverted := false
+		quirks, err := ButtonQuirksFromConfig(config.HardwareConfig{
+			EnterBit:       &enterBit,
+			SelectBit:      &selectBit,
+			CopyBit:        &copyBit,
+			EnterInverted:  &notInverted,
+			SelectInverted: &notInverted,
+		})
+		require.NoError(t, err)
+
+		assert.True(t, quirks.enterPressed(0x10))
+		assert.False(t, quirks.enterPressed(0x01))
+		assert.True(t, quirks.selectPressed(0x20))
+		assert.True(t, quirks.copyPressed(0x40))
+		assert.False(t, quirks.copyPressed(0x04))
+	})
+
+	t.Run("Invalid bits", func(t *testing.T) {
+		bit := 8
+		_, err := ButtonQuirksFromConfig(config.HardwareConfig{EnterBit: &bit})
+		assert.Error(t, err)
+
+		bit = 1
+		_, err = ButtonQuirksFromConfig(config.HardwareConfig{EnterBit: &bit})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "distinct")
+
+		// The copy bit may overlap when the profile ignores it
+		bit = 0
+		_, err = ButtonQuirksFromConfig(config.HardwareConfig{Profile: "prefixed-frame", CopyBit: &bit})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid prefixes", func(t *testing.T) {
+		_, err := ButtonQuirksFromConfig(config.HardwareConfig{CopyFramePrefixes: []int{0x100}})
+		assert.Error(t, err)
+
+		_, err = ButtonQuirksFromConfig(config.HardwareConfig{CopyFramePrefixes: []int{0x53}})
+		assert.Error(t, err)
+	})
+}
+
+// expectNoEvent fails if a button event arrives within a short grace period
+func expectNoEvent(t *testing.T, events chan buttonEvent) {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDisplayController_CopyQuirks(t *testing.T) {
+	t.Run("state-frame ignores prefixed frames", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.Profile = "state-frame"
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x55, 0x01}
+		dc.processMessageBuffer(&buffer)
+		assert.Empty(t, buffer)
+		expectNoEvent(t, events)
+	})
+
+	t.Run("state-frame suppresses a flapping copy bit", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.Profile = "state-frame"
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		dc.lastButtonState[ButtonUSBCopy] = false
+		events := collectButtonEvents(dc)
+
+		// The copy bit drops out briefly while the button is held
+		buffer := []byte{
+			0x53, 0x05, 0x00, 0xFF,
+			0x53, 0x05, 0x00, 0xFB,
+			0x53, 0x05, 0x00, 0xFF,
+			0x53, 0x05, 0x00, 0xFB,
+		}
+		dc.processMessageBuffer(&buffer)
+
+		// Handlers run concurrently, so only the set of events is deterministic
+		received := []buttonEvent{waitForEvent(t, events), waitForEvent(t, events)}
+		assert.ElementsMatch(t, []buttonEvent{{ButtonUSBCopy, true}, {ButtonUSBCopy, false}}, received)
+		expectNoEvent(t, events)
+	})
+
+	t.Run("prefixed-frame reports repeated frames once", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.Profile = "prefixed-frame"
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x43, 0x01, 0x43, 0x01, 0x43, 0x01}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonUSBCopy, true}, waitForEvent(t, events))
+		assert.Equal(t, buttonEvent{ButtonUSBCopy, false}, waitForEvent(t, events))
+		expectNoEvent(t, events)
+
+		// The copy bit of state frames is ignored by this profile
+		buffer = []byte{0x53, 0x05, 0x00, 0xFF}
+		dc.processMessageBuffer(&buffer)
+		expectNoEvent(t, events)
+	})
+
+	t.Run("Inverted copy bit", func(t *testing.T) {
+		inverted := true
+		cfg := config.DefaultConfig()
+		cfg.Hardware.CopyInverted = &inverted
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		dc.lastButtonState[ButtonUSBCopy] = false
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x53, 0x05, 0x00, 0xFB}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonUSBCopy, true}, waitForEvent(t, events))
+	})
+
+	t.Run("Deprecated copy_active_low", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		require.NoError(t, json.Unmarshal([]byte(`{"profile": "generic", "copy_active_low": true}`), &cfg.Hardware))
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		dc.lastButtonState[ButtonUSBCopy] = false
+		events := collectButtonEvents(dc)
+
+		buffer := []byte{0x53, 0x05, 0x00, 0xFB}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonUSBCopy, true}, waitForEvent(t, events))
+	})
+
+	t.Run("Remapped ENTER and SELECT", func(t *testing.T) {
+		enterBit, selectBit := 3, 2
+		copyBit := 4
+		cfg := config.DefaultConfig()
+		cfg.Hardware.EnterBit = &enterBit
+		cfg.Hardware.SelectBit = &selectBit
+		cfg.Hardware.CopyBit = &copyBit
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		dc.lastButtonState[ButtonEnter] = false
+		dc.lastButtonState[ButtonSelect] = false
+		dc.lastButtonState[ButtonUSBCopy] = false
+		events := collectButtonEvents(dc)
+
+		// Bit 3 low: ENTER pressed; bits 0/1 are no longer buttons
+		buffer := []byte{0x53, 0x05, 0x00, 0xE4}
+		dc.processMessageBuffer(&buffer)
+		assert.Equal(t, buttonEvent{ButtonEnter, true}, waitForEvent(t, events))
+		expectNoEvent(t, events)
+	})
+
+	t.Run("Unknown profile falls back to generic", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Hardware.Profile = "does-not-exist"
+		dc, _ := newTestDisplayControllerWithConfig(t, cfg)
+		assert.Equal(t, "generic", dc.quirks.Profile)
+	})
+}
@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/monitor"
+)
+
+// ErrRestartUnsupported is returned by Restart on a system controller that
+// cannot open its hardware again
+var ErrRestartUnsupported = errors.New("restarting the panel is not supported")
+
+// hardwareOpeners open the panel, the LEDs and the copy button monitor again
+// for Restart. A nil opener keeps what was opened at startup.
+type hardwareOpeners struct {
+	display    func() (DisplayControllerInterface, error)
+	leds       func() (LEDControllerInterface, error)
+	usbMonitor func() (*monitor.USBCopyMonitor, error)
+}
+
+// swappableDisplay passes every call on to the current panel, which Restart
+// replaces with one opened again. Screens, the menu and the other holders of
+// the display thus keep working with the new panel.
+type swappableDisplay struct {
+	mutex      sync.RWMutex
+	display    DisplayControllerInterface
+	restarting bool
+}
+
+// newSwappableDisplay wraps the panel opened at startup
+func newSwappableDisplay(display DisplayControllerInterface) *swappableDisplay {
+	return &swappableDisplay{display: display}
+}
+
+// current returns the panel calls go to
+func (s *swappableDisplay) current() DisplayControllerInterface {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.display
+}
+
+// setRestarting marks the panel as being closed and opened again
+func (s *swappableDisplay) setRestarting(restarting bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.restarting = restarting
+}
+
+// swap makes display the current panel, ending the restart
+func (s *swappableDisplay) swap(display DisplayControllerInterface) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.display = display
+	s.restarting = false
+}
+
+// The methods of DisplayControllerInterface pass the call on to the current
+// panel
+
+func (s *swappableDisplay) WriteText(text string) error {
+	return s.current().WriteText(text)
+}
+
+func (s *swappableDisplay) WriteTextAt(text string, row, col int) error {
+	return s.current().WriteTextAt(text, row, col)
+}
+
+func (s *swappableDisplay) WriteLines(lines map[int]string) error {
+	return s.current().WriteLines(lines)
+}
+
+func (s *swappableDisplay) Update(fn func(update *DisplayUpdate) error) error {
+	return s.current().Update(fn)
+}
+
+func (s *swappableDisplay) ClearDisplay() error {
+	return s.current().ClearDisplay()
+}
+
+func (s *swappableDisplay) SetBacklight(on bool) error {
+	return s.current().SetBacklight(on)
+}
+
+func (s *swappableDisplay) ShowCopyStatus(status string) error {
+	return s.current().ShowCopyStatus(status)
+}
+
+func (s *swappableDisplay) ShowProgress(percent int) error {
+	return s.current().ShowProgress(percent)
+}
+
+func (s *swappableDisplay) ScrollText(row int, text string, speed time.Duration) error {
+	return s.current().ScrollText(row, text, speed)
+}
+
+func (s *swappableDisplay) StopScroll(row int) {
+	s.current().StopScroll(row)
+}
+
+func (s *swappableDisplay) DefineCustomChar(slot int, bitmap [8]byte) error {
+	return s.current().DefineCustomChar(slot, bitmap)
+}
+
+func (s *swappableDisplay) SetButtonHandler(handler ButtonEventHandler) {
+	s.current().SetButtonHandler(handler)
+}
+
+func (s *swappableDisplay) RequestButtonState() error {
+	return s.current().RequestButtonState()
+}
+
+func (s *swappableDisplay) SetSerialCopyDetection(enabled bool) {
+	s.current().SetSerialCopyDetection(enabled)
+}
+
+func (s *swappableDisplay) SerialCopyDetection() bool {
+	return s.current().SerialCopyDetection()
+}
+
+func (s *swappableDisplay) SetBreakerHandler(handler BreakerEventHandler) {
+	s.current().SetBreakerHandler(handler)
+}
+
+func (s *swappableDisplay) LinkState() BreakerState {
+	return s.current().LinkState()
+}
+
+func (s *swappableDisplay) FrameStats() FrameStats {
+	return s.current().FrameStats()
+}
+
+func (s *swappableDisplay) Close() error {
+	return s.current().Close()
+}
+
+// LastRead returns when the current panel was last read. Panels that are not
+// read in the background, and a panel being restarted, report now, so the
+// systemd watchdog does not take them for a hung reader.
+func (s *swappableDisplay) LastRead() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	reader, ok := s.display.(interface{ LastRead() time.Time })
+	if !ok || s.restarting {
+		return time.Now()
+	}
+	return reader.LastRead()
+}
+
+// swappableLEDs passes every call on to the current LED controller, which
+// Restart replaces with one opened again
+type swappableLEDs struct {
+	mutex sync.RWMutex
+	leds  LEDControllerInterface
+}
+
+// current returns the LED controller calls go to
+func (s *swappableLEDs) current() LEDControllerInterface {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.leds
+}
+
+// swap makes leds the current LED controller and returns the previous one
+func (s *swappableLEDs) swap(leds LEDControllerInterface) LEDControllerInterface {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous := s.leds
+	s.leds = leds
+	return previous
+}
+
+// The methods of LEDControllerInterface pass the call on to the current
+// controller
+
+func (s *swappableLEDs) SetLED(led PanelLED, on bool) error {
+	return s.current().SetLED(led, on)
+}
+
+func (s *swappableLEDs) SetDiskLEDs(states map[int]bool) error {
+	return s.current().SetDiskLEDs(states)
+}
+
+func (s *swappableLEDs) SetStatusLED(red bool, green bool) error {
+	return s.current().SetStatusLED(red, green)
+}
+
+func (s *swappableLEDs) GetLEDStates() (map[PanelLED]bool, error) {
+	return s.current().GetLEDStates()
+}
+
+func (s *swappableLEDs) Close() error {
+	return s.current().Close()
+}
+
+// Restart closes the panel and opens it again, and does the same for the
+// LEDs and the copy button monitor, without stopping the service: after a
+// driver was reloaded, or when the panel misbehaves. Holders of the display
+// and LED controllers keep working with the reopened hardware; the panel
+// shows its startup text until they draw again.
+//
+// Once privileges were dropped (see PrivilegesDropped) only the panel is
+// opened again; the LEDs and the monitor are kept as they were. LEDs and a
+// monitor that cannot be opened again are kept too and only logged. A panel
+// that cannot be opened again is returned as an error and leaves display
+// writes failing until a later Restart succeeds.
+func (sc *SystemController) Restart() error {
+	if sc.panel == nil || sc.openers.display == nil {
+		return ErrRestartUnsupported
+	}
+	sc.restartMutex.Lock()
+	defer sc.restartMutex.Unlock()
+
+	sc.logger.Info("Restarting panel, LEDs and copy button monitor")
+
+	// The old panel has to let go of the port before it is opened again
+	sc.panel.setRestarting(true)
+	if err := sc.panel.current().Close(); err != nil {
+		sc.logger.WithError(err).Warn("Failed to close display controller")
+	}
+	display, err := sc.openers.display()
+	if err != nil {
+		sc.panel.setRestarting(false)
+		return fmt.Errorf("failed to open display controller again: %w", err)
+	}
+	display.SetButtonHandler(sc.handleDisplayButtonEvent)
+	display.SetBreakerHandler(sc.handleBreakerEvent)
+	sc.panel.swap(display)
+
+	if sc.privilegesDropped {
+		sc.logger.Info("Privileges were dropped, keeping the LEDs and copy button monitor opened at startup")
+	} else {
+		sc.restartLEDs()
+		sc.restartUSBMonitor()
+	}
+	sc.selectCopyButtonSource()
+
+	if sc.led != nil {
+		linkUp := display.LinkState() == BreakerClosed
+		sc.led.SetStatusLED(!linkUp, linkUp)
+	}
+
+	sc.logger.Info("Panel restarted")
+	return nil
+}
+
+// PrivilegesDropped tells the controller the service gave up root. The
+// LEDs and the copy button's I/O port cannot be opened without it, so
+// Restart no longer tries and keeps the ones opened at startup.
+func (sc *SystemController) PrivilegesDropped() {
+	sc.restartMutex.Lock()
+	defer sc.restartMutex.Unlock()
+
+	sc.privilegesDropped = true
+}
+
+// restartLEDs opens the LED controller again and shows the last switched
+// states on it. The new controller is opened before the old one is closed,
+// so a failure keeps the old one.
+func (sc *SystemController) restartLEDs() {
+	if sc.leds == nil || sc.openers.leds == nil {
+		return
+	}
+	leds, err := sc.openers.leds()
+	if err != nil {
+		sc.logger.WithError(err).Warn("Failed to open LED controller again, keeping the old one")
+		return
+	}
+	if err := sc.leds.swap(leds).Close(); err != nil {
+		sc.logger.WithError(err).Warn("Failed to close LED controller")
+	}
+	if sc.ledEvents != nil {
+		if err := sc.ledEvents.restore(); err != nil {
+			sc.logger.WithError(err).Warn("Failed to restore LED states")
+		}
+	}
+}
+
+// restartUSBMonitor opens the copy button monitor again. Like the LEDs, the
+// old monitor is kept if the new one cannot be opened.
+func (sc *SystemController) restartUSBMonitor() {
+	if sc.openers.usbMonitor == nil {
+		return
+	}
+	usbMonitor, err := sc.openers.usbMonitor()
+	if err != nil {
+		sc.logger.WithError(err).Warn("Failed to open USB copy monitor again, keeping the old one")
+		return
+	}
+
+	sc.sourceMutex.Lock()
+	previous := sc.usbMonitor
+	sc.usbMonitor = usbMonitor
+	sc.sourceMutex.Unlock()
+
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			sc.logger.WithError(err).Warn("Failed to close USB copy monitor")
+		}
+	}
+	go sc.monitorUSBCopyButton(usbMonitor)
+}
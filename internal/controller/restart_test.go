@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/testutil"
+	"github.com/qnap/display-control/pkg/led"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemController_Restart(t *testing.T) {
+	panel := NewPanelSimulator()
+	sc, err := NewSimulatedSystemController(config.DefaultConfig(), panel, led.NewMock())
+	require.NoError(t, err)
+	t.Cleanup(func() { sc.Close() })
+
+	display := sc.GetDisplayController()
+	pressed := make(chan PanelButton, 4)
+	sc.SetButtonHandler(func(button PanelButton, isPressed bool) {
+		if isPressed {
+			pressed <- button
+		}
+	})
+
+	require.NoError(t, sc.Restart())
+	assert.True(t, panel.IsOpen())
+
+	// Writes and buttons go through the same display as before
+	require.NoError(t, display.WriteText("Back\nagain"))
+	testutil.AssertScreen(t, panel, "Back", "again")
+	require.NoError(t, panel.Press(ButtonEnter))
+	select {
+	case button := <-pressed:
+		assert.Equal(t, ButtonEnter, button)
+	case <-time.After(time.Second):
+		t.Fatal("button press was not reported after the restart")
+	}
+	assert.Equal(t, BreakerClosed, display.LinkState())
+}
+
+func TestSystemController_RestartLEDs(t *testing.T) {
+	dc, _ := newTestDisplayController(t)
+	first, second := newFakeLEDController(), newFakeLEDController()
+	leds := &swappableLEDs{leds: first}
+	watched := newWatchedLEDs(leds)
+	sc := &SystemController{
+		display:   newSwappableDisplay(dc),
+		led:       newQuietLEDs(watched),
+		ledEvents: watched,
+		logger:    logrus.WithField("component", "system_controller"),
+		leds:      leds,
+	}
+	sc.panel = sc.display.(*swappableDisplay)
+	sc.openers = hardwareOpeners{
+		display: func() (DisplayControllerInterface, error) {
+			dc, _ := newTestDisplayController(t)
+			return dc, nil
+		},
+		leds: func() (LEDControllerInterface, error) { return second, nil },
+	}
+
+	require.NoError(t, sc.led.SetLED(Disk2, true))
+	require.NoError(t, sc.Restart())
+
+	assert.True(t, second.leds[Disk2], "the last states are shown on the reopened LEDs")
+	assert.True(t, second.statusGrn)
+	require.NoError(t, sc.led.SetLED(Disk5, true))
+	assert.True(t, second.leds[Disk5])
+	assert.False(t, first.leds[Disk5])
+
+	t.Run("LEDs that fail to open are kept", func(t *testing.T) {
+		sc.openers.leds = func() (LEDControllerInterface, error) { return nil, errors.New("no /dev/port") }
+		require.NoError(t, sc.Restart())
+		require.NoError(t, sc.led.SetLED(Disk6, true))
+		assert.True(t, second.leds[Disk6])
+	})
+
+	t.Run("LEDs are kept once privileges were dropped", func(t *testing.T) {
+		third := newFakeLEDController()
+		sc.openers.leds = func() (LEDControllerInterface, error) { return third, nil }
+		sc.PrivilegesDropped()
+		t.Cleanup(func() { sc.privilegesDropped = false })
+
+		require.NoError(t, sc.Restart())
+		require.NoError(t, sc.led.SetLED(Disk1, true))
+		assert.True(t, second.leds[Disk1])
+		assert.False(t, third.leds[Disk1], "the LEDs were not opened again")
+	})
+
+	t.Run("Panel that fails to open", func(t *testing.T) {
+		sc.openers.display = func() (DisplayControllerInterface, error) { return nil, errors.New("no such device") }
+		assert.Error(t, sc.Restart())
+	})
+}
+
+func TestSystemController_RestartUnsupported(t *testing.T) {
+	sc := &SystemController{logger: logrus.WithField("component", "system_controller")}
+	assert.ErrorIs(t, sc.Restart(), ErrRestartUnsupported)
+}
+
+func TestSwappableDisplay_LastRead(t *testing.T) {
+	dc, _ := newTestDisplayController(t)
+	swappable := newSwappableDisplay(dc)
+	assert.Equal(t, dc.LastRead(), swappable.LastRead())
+
+	// A restart is not a hung reader
+	swappable.setRestarting(true)
+	assert.WithinDuration(t, time.Now(), swappable.LastRead(), time.Second)
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+)
+
+// ErrPanelNotReady means the panel did not answer after being initialized
+var ErrPanelNotReady = errors.New("panel not answering")
+
+// defaultInitAttempts is how often a panel is initialized before the
+// service carries on without it, when the configuration does not say. With
+// the waits of startupBackoff and panelAnswerTimeout that takes about 40
+// seconds.
+const defaultInitAttempts = 6
+
+// panelAnswerTimeout is how long an initialization waits for the panel to
+// answer a button state request
+const panelAnswerTimeout = time.Second
+
+// startupBackoff returns the waits between initializations of a panel whose
+// MCU may still be booting
+func startupBackoff(cfg *config.Config) *Backoff {
+	attempts := cfg.SerialPort.InitAttempts
+	if attempts <= 0 {
+		attempts = defaultInitAttempts
+	}
+	return &Backoff{Initial: time.Second, Max: 16 * time.Second, Attempts: attempts}
+}
+
+// initializeUntilAnswered initializes the display and asks for the button
+// state until the panel answers, waiting longer after each silent attempt.
+// At cold boot the panel's MCU may not be ready yet and drops the setup.
+func (dc *DisplayController) initializeUntilAnswered(backoff Backoff) error {
+	err := BackoffRetry(backoff, dc.stopChan, func(attempt int) error {
+		if attempt > 0 {
+			dc.logger.WithField("attempt", attempt+1).Warn("Panel not answering, initializing again")
+		}
+		since := time.Now()
+		if err := dc.initializeDisplay(); err != nil {
+			return err
+		}
+		if err := dc.RequestButtonState(); err != nil {
+			dc.logger.WithError(err).Debug("Failed to request button state")
+		}
+		return dc.waitForAnswer(since, panelAnswerTimeout)
+	})
+	if err != nil {
+		return err
+	}
+	dc.logger.Debug("Panel answered")
+	return nil
+}
+
+// waitForAnswer waits until the panel has sent anything after since
+func (dc *DisplayController) waitForAnswer(since time.Time, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if dc.lastAnswer.Load() >= since.UnixNano() {
+			return nil
+		}
+		select {
+		case <-dc.stopChan:
+			return ErrRetryStopped
+		case <-deadline.C:
+			return ErrPanelNotReady
+		case <-ticker.C:
+		}
+	}
+}
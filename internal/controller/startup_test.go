@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/serial"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bootingPort is a panel whose MCU ignores the first button state requests
+// while it boots and answers every later one
+type bootingPort struct {
+	*serial.MockSerialPort
+	mutex    sync.Mutex
+	silent   int
+	requests int
+}
+
+func (p *bootingPort) Write(data []byte) error {
+	if err := p.MockSerialPort.Write(data); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if bytes.Equal(data, []byte{0x4D, 0x05}) {
+		p.requests++
+		if p.requests > p.silent {
+			p.SetReadData([]byte{0x53, 0x05, 0x00, 0x00})
+		}
+	}
+	return nil
+}
+
+// wakeUp makes the panel answer from its next request on
+func (p *bootingPort) wakeUp() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.silent = 0
+}
+
+func TestInitializeUntilAnswered(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SerialPort.ProbeInterval = 20
+	backoff := &Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Attempts: 3}
+	setup := []byte{0x4D, 0x06}
+
+	t.Run("Initializes again until the panel answers", func(t *testing.T) {
+		port := &bootingPort{MockSerialPort: serial.NewMockSerialPort(), silent: 1}
+		dc, err := newDisplayController(cfg, port, backoff)
+		require.NoError(t, err)
+		defer dc.Close()
+
+		assert.Equal(t, BreakerClosed, dc.LinkState())
+		assert.False(t, dc.setupPending.Load())
+		assert.Equal(t, 2, bytes.Count(port.GetWrittenData(), setup), "the setup is sent with every initialization")
+	})
+
+	t.Run("Carries on with the link down and sends the setup once the panel answers", func(t *testing.T) {
+		port := &bootingPort{MockSerialPort: serial.NewMockSerialPort(), silent: 1000}
+		dc, err := newDisplayController(cfg, port, backoff)
+		require.NoError(t, err, "a silent panel does not stop the service")
+		defer dc.Close()
+
+		assert.Equal(t, BreakerOpen, dc.LinkState())
+		assert.True(t, dc.setupPending.Load())
+		before := bytes.Count(port.GetWrittenData(), setup)
+
+		port.wakeUp()
+		require.Eventually(t, func() bool {
+			return dc.LinkState() == BreakerClosed && !dc.setupPending.Load()
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			return bytes.Count(port.GetWrittenData(), setup) == before+1
+		}, time.Second, 10*time.Millisecond)
+	})
+}
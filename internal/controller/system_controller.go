@@ -2,6 +2,8 @@ package controller
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/qnap/display-control/internal/config"
@@ -9,46 +11,140 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// CopyButtonSource identifies where copy button presses are read from
+type CopyButtonSource string
+
+const (
+	// CopyButtonIOPort reads the button directly from the I/O port
+	CopyButtonIOPort CopyButtonSource = "io-port"
+	// CopyButtonSerial decodes the button from the panel's serial frames
+	CopyButtonSerial CopyButtonSource = "serial"
+)
+
 // SystemController manages the overall QNAP system components
 type SystemController struct {
-	display      *DisplayController
-	led          *LEDController
+	display      DisplayControllerInterface
+	led          LEDControllerInterface
+	// ledEvents reports the LEDs switched; nil without LED support
+	ledEvents    *watchedLEDs
 	usbMonitor   *monitor.USBCopyMonitor
 	config       *config.Config
 	logger       *logrus.Entry
 	buttonHandler ButtonEventHandler
+	breakerHandler BreakerEventHandler
+	handlerMutex  sync.RWMutex
+	copySource   CopyButtonSource
+	// sourceMutex also guards usbMonitor, which Restart replaces
+	sourceMutex  sync.RWMutex
+	// panel and leds are display and led as opened by NewSystemController,
+	// which Restart opens again with openers; nil when it cannot
+	panel        *swappableDisplay
+	leds         *swappableLEDs
+	openers      hardwareOpeners
+	restartMutex sync.Mutex
+	// privilegesDropped keeps Restart from opening the LEDs and the copy
+	// button's I/O port, which need root; guarded by restartMutex
+	privilegesDropped bool
 }
 
 // NewSystemController creates a new system controller
 func NewSystemController(cfg *config.Config) (*SystemController, error) {
 	logger := logrus.WithField("component", "system_controller")
 
+	openers := hardwareOpeners{
+		display: func() (DisplayControllerInterface, error) { return OpenDisplay(cfg) },
+		leds: func() (LEDControllerInterface, error) {
+			leds, err := NewLEDController()
+			if err != nil {
+				return nil, err
+			}
+			return leds, nil
+		},
+	}
+	if cfg.USBCopy.IOPort != 0 {
+		openers.usbMonitor = func() (*monitor.USBCopyMonitor, error) { return openUSBCopyMonitor(cfg.USBCopy.IOPort) }
+	}
+
 	// Initialize display controller
-	display, err := NewDisplayController(cfg)
+	display, err := openers.display()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize display controller: %w", err)
 	}
+	panel := newSwappableDisplay(display)
 
 	// Initialize LED controller
-	led, err := NewLEDController()
+	var led LEDControllerInterface
+	var ledEvents *watchedLEDs
+	var leds *swappableLEDs
+	ledController, err := openers.leds()
 	if err != nil {
 		logger.WithError(err).Warn("LED controller initialization failed, continuing without LED support")
-		led = nil
+	} else {
+		leds = &swappableLEDs{leds: ledController}
+		ledEvents = newWatchedLEDs(leds)
+		led = newQuietLEDs(ledEvents)
 	}
 
 	// Initialize USB copy monitor
 	var usbMonitor *monitor.USBCopyMonitor
-	if cfg.USBCopy.IOPort != 0 {
-		usbMonitor, err = monitor.NewUSBCopyMonitor(cfg.USBCopy.IOPort)
+	if openers.usbMonitor != nil {
+		usbMonitor, err = openers.usbMonitor()
 		if err != nil {
-			logger.WithError(err).Warn("USB copy monitor initialization failed, continuing without USB copy support")
+			logger.WithError(err).Warn("USB copy monitor unavailable, falling back to serial copy button")
 			usbMonitor = nil
 		}
 	}
 
+	sc := newSystemController(cfg, panel, led, ledEvents, usbMonitor, logger)
+	sc.panel, sc.leds, sc.openers = panel, leds, openers
+	return sc, nil
+}
+
+// openUSBCopyMonitor opens the copy button's I/O port and reads it once
+func openUSBCopyMonitor(port uint16) (*monitor.USBCopyMonitor, error) {
+	usbMonitor, err := monitor.NewUSBCopyMonitor(port)
+	if err != nil {
+		return nil, err
+	}
+	// The port may open but not be readable (e.g. no I/O privileges in a container)
+	if _, err := usbMonitor.IsButtonPressed(); err != nil {
+		usbMonitor.Close()
+		return nil, fmt.Errorf("cannot read the I/O port: %w", err)
+	}
+	return usbMonitor, nil
+}
+
+// NewSimulatedSystemController creates a system controller on a simulated
+// panel and LEDs, to run the service without hardware. The copy button is
+// decoded from the panel's serial frames.
+func NewSimulatedSystemController(cfg *config.Config, panel *PanelSimulator, leds LEDControllerInterface) (*SystemController, error) {
+	logger := logrus.WithField("component", "system_controller")
+
+	display, err := NewDisplayControllerWithPort(cfg, panel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize display controller: %w", err)
+	}
+	swappable := newSwappableDisplay(display)
+	ledEvents := newWatchedLEDs(leds)
+	sc := newSystemController(cfg, swappable, newQuietLEDs(ledEvents), ledEvents, nil, logger)
+
+	// Restarts reopen the simulated panel; the LEDs are kept
+	sc.panel = swappable
+	sc.openers.display = func() (DisplayControllerInterface, error) {
+		panel.reopen()
+		return NewDisplayControllerWithPort(cfg, panel)
+	}
+	return sc, nil
+}
+
+// newSystemController wires up the opened display, LEDs and copy button
+// monitor and sets their initial state
+func newSystemController(cfg *config.Config, display DisplayControllerInterface, led LEDControllerInterface,
+	ledEvents *watchedLEDs, usbMonitor *monitor.USBCopyMonitor, logger *logrus.Entry) *SystemController {
 	sc := &SystemController{
 		display:    display,
 		led:        led,
+		ledEvents:  ledEvents,
 		usbMonitor: usbMonitor,
 		config:     cfg,
 		logger:     logger,
@@ -57,9 +153,15 @@ func NewSystemController(cfg *config.Config) (*SystemController, error) {
 	// Set up button handler for display buttons (ENTER/SELECT)
 	display.SetButtonHandler(sc.handleDisplayButtonEvent)
 
+	// Show serial link failures on the status LED
+	display.SetBreakerHandler(sc.handleBreakerEvent)
+
+	// Read the copy button from exactly one source
+	sc.selectCopyButtonSource()
+
 	// Start USB copy button monitoring if available
 	if sc.usbMonitor != nil {
-		go sc.monitorUSBCopyButton()
+		go sc.monitorUSBCopyButton(sc.usbMonitor)
 	}
 
 	// Initialize system state
@@ -68,15 +170,35 @@ func NewSystemController(cfg *config.Config) (*SystemController, error) {
 	}
 
 	logger.Info("System controller initialized successfully")
-	return sc, nil
+	return sc
+}
+
+// OpenDisplay opens the panel selected by Display.Driver
+func OpenDisplay(cfg *config.Config) (DisplayControllerInterface, error) {
+	switch cfg.Display.Driver {
+	case DriverSSD1306, DriverSH1106:
+		return NewOLEDDisplayController(cfg)
+	case DriverMatrixOrbital, DriverCrystalFontz:
+		return NewCharLCDController(cfg)
+	case DriverHD44780:
+		return NewHD44780Controller(cfg)
+	case DriverPCF8574:
+		return NewPCF8574Controller(cfg)
+	}
+	// Panels on the serial port only differ in their driver
+	if _, ok := displayDrivers[cfg.Display.Driver]; ok || cfg.Display.Driver == "" {
+		return NewDisplayController(cfg)
+	}
+	available := append(displayDriverNames(), DriverSSD1306, DriverSH1106, DriverMatrixOrbital, DriverCrystalFontz, DriverHD44780, DriverPCF8574)
+	return nil, fmt.Errorf("unknown display driver %q (available: %s)", cfg.Display.Driver, strings.Join(available, ", "))
 }
 
 // Close closes the system controller and cleans up resources
 func (sc *SystemController) Close() error {
 	sc.logger.Info("Closing system controller")
 
-	if sc.usbMonitor != nil {
-		if err := sc.usbMonitor.Close(); err != nil {
+	if usbMonitor := sc.GetUSBCopyMonitor(); usbMonitor != nil {
+		if err := usbMonitor.Close(); err != nil {
 			sc.logger.WithError(err).Error("Failed to close USB copy monitor")
 		}
 	}
@@ -97,30 +219,107 @@ func (sc *SystemController) Close() error {
 }
 
 // GetDisplayController returns the display controller
-func (sc *SystemController) GetDisplayController() *DisplayController {
+func (sc *SystemController) GetDisplayController() DisplayControllerInterface {
 	return sc.display
 }
 
-// GetLEDController returns the LED controller
-func (sc *SystemController) GetLEDController() *LEDController {
+// GetLEDController returns the LED controller, or nil if LEDs are unavailable
+func (sc *SystemController) GetLEDController() LEDControllerInterface {
 	return sc.led
 }
 
 // GetUSBCopyMonitor returns the USB copy monitor
 func (sc *SystemController) GetUSBCopyMonitor() *monitor.USBCopyMonitor {
+	sc.sourceMutex.RLock()
+	defer sc.sourceMutex.RUnlock()
+
 	return sc.usbMonitor
 }
 
+// CopyButtonSource reports where copy button presses are currently read from
+func (sc *SystemController) CopyButtonSource() CopyButtonSource {
+	sc.sourceMutex.RLock()
+	defer sc.sourceMutex.RUnlock()
+
+	return sc.copySource
+}
+
+// selectCopyButtonSource prefers the I/O port monitor and falls back to
+// decoding the copy button from serial frames when the port is unavailable
+func (sc *SystemController) selectCopyButtonSource() {
+	sc.sourceMutex.Lock()
+	source := CopyButtonSerial
+	if sc.usbMonitor != nil {
+		source = CopyButtonIOPort
+	}
+	sc.copySource = source
+	sc.sourceMutex.Unlock()
+
+	if sc.display != nil {
+		sc.display.SetSerialCopyDetection(source == CopyButtonSerial)
+	}
+
+	sc.logger.WithField("source", source).Info("Copy button source selected")
+}
+
 // SetButtonHandler sets a unified button handler for all button types
 func (sc *SystemController) SetButtonHandler(handler ButtonEventHandler) {
 	sc.buttonHandler = handler
 }
 
+// SetBreakerHandler sets a handler for serial link circuit breaker transitions.
+// The status LED is updated before the handler is called.
+func (sc *SystemController) SetBreakerHandler(handler BreakerEventHandler) {
+	sc.handlerMutex.Lock()
+	sc.breakerHandler = handler
+	sc.handlerMutex.Unlock()
+}
+
+// handleBreakerEvent shows a failing serial link as a red status LED
+func (sc *SystemController) handleBreakerEvent(event BreakerEvent) {
+	if sc.led != nil {
+		switch {
+		case event.To == BreakerOpen && event.From == BreakerClosed:
+			sc.led.SetStatusLED(true, false)
+		case event.To == BreakerClosed:
+			sc.led.SetStatusLED(false, true)
+		}
+	}
+
+	sc.handlerMutex.RLock()
+	handler := sc.breakerHandler
+	sc.handlerMutex.RUnlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
+
+// SetLEDHandler sets a handler told every LED switched on or off, whoever
+// switched it
+func (sc *SystemController) SetLEDHandler(handler LEDEventHandler) {
+	if sc.ledEvents != nil {
+		sc.ledEvents.setHandler(handler)
+	}
+}
+
+// SetQuietStatusLED keeps the red status LED off, e.g. during maintenance.
+// When quiet ends the last requested status is shown again.
+func (sc *SystemController) SetQuietStatusLED(quiet bool) error {
+	leds, ok := sc.led.(*quietLEDs)
+	if !ok {
+		return nil
+	}
+	return leds.setQuiet(quiet)
+}
+
 // initializeSystem sets up the initial system state
 func (sc *SystemController) initializeSystem() error {
 	if sc.led != nil {
 		// Set initial LED states
-		sc.led.SetStatusLED(false, true) // Green status LED on
+		// Green status LED on, red for a panel that did not answer at startup
+		linkUp := sc.display.LinkState() == BreakerClosed
+		sc.led.SetStatusLED(!linkUp, linkUp)
 		sc.led.SetLED(USB, false)        // USB LED off
 		
 		// Turn off all disk LEDs initially
@@ -164,11 +363,12 @@ func (sc *SystemController) handleDisplayButtonEvent(button PanelButton, pressed
 	}
 }
 
-// monitorUSBCopyButton monitors the hardware USB copy button
-func (sc *SystemController) monitorUSBCopyButton() {
+// monitorUSBCopyButton monitors the hardware USB copy button until
+// usbMonitor is closed
+func (sc *SystemController) monitorUSBCopyButton(usbMonitor *monitor.USBCopyMonitor) {
 	sc.logger.Info("Starting USB copy button monitoring")
 	
-	err := sc.usbMonitor.MonitorButtonPresses(func() {
+	err := usbMonitor.MonitorButtonPresses(func() {
 		sc.logger.WithFields(logrus.Fields{
 			"button":  "USB_COPY",
 			"pressed": true,
@@ -194,19 +394,19 @@ func (sc *SystemController) monitorUSBCopyButton() {
 
 // handleEnterButton handles ENTER button presses
 func (sc *SystemController) handleEnterButton() {
-	sc.logger.Debug("ENTER button pressed")
+	sc.logger.WithField("button", ButtonEnter.String()).Debug("ENTER button pressed")
 	// This will be handled by the menu system
 }
 
 // handleSelectButton handles SELECT button presses
 func (sc *SystemController) handleSelectButton() {
-	sc.logger.Debug("SELECT button pressed")
+	sc.logger.WithField("button", ButtonSelect.String()).Debug("SELECT button pressed")
 	// This will be handled by the menu system
 }
 
 // handleUSBCopyButton handles USB COPY button presses
 func (sc *SystemController) handleUSBCopyButton() {
-	sc.logger.Info("USB COPY button pressed")
+	sc.logger.WithField("button", ButtonUSBCopy.String()).Info("USB COPY button pressed")
 	
 	if sc.led != nil {
 		// Flash USB LED to indicate copy operation
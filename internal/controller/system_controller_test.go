@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/qnap/display-control/internal/monitor"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLEDController records LED changes for assertions
+type fakeLEDController struct {
+	leds      map[PanelLED]bool
+	statusRed bool
+	statusGrn bool
+}
+
+func newFakeLEDController() *fakeLEDController {
+	return &fakeLEDController{leds: make(map[PanelLED]bool)}
+}
+
+func (f *fakeLEDController) SetLED(led PanelLED, on bool) error {
+	f.leds[led] = on
+	return nil
+}
+
+func (f *fakeLEDController) SetDiskLEDs(states map[int]bool) error {
+	for disk, on := range states {
+		f.leds[Disk1+PanelLED(disk-1)] = on
+	}
+	return nil
+}
+
+func (f *fakeLEDController) SetStatusLED(red bool, green bool) error {
+	f.statusRed = red
+	f.statusGrn = green
+	return nil
+}
+
+func (f *fakeLEDController) GetLEDStates() (map[PanelLED]bool, error) {
+	return f.leds, nil
+}
+
+func (f *fakeLEDController) Close() error {
+	return nil
+}
+
+func TestSystemController_WithFakeLEDs(t *testing.T) {
+	leds := newFakeLEDController()
+	sc := &SystemController{
+		led:    leds,
+		logger: logrus.WithField("component", "system_controller"),
+	}
+
+	t.Run("Disk activity", func(t *testing.T) {
+		assert.NoError(t, sc.SetDiskActivity(3, true))
+		assert.True(t, leds.leds[Disk3])
+
+		assert.Error(t, sc.SetDiskActivity(7, true))
+	})
+
+	t.Run("System status", func(t *testing.T) {
+		assert.NoError(t, sc.SetSystemStatus("Disk failure", true))
+		assert.True(t, leds.statusRed)
+		assert.False(t, leds.statusGrn)
+	})
+
+	t.Run("Progress flashes disks", func(t *testing.T) {
+		assert.NoError(t, sc.ShowProgress(50, true))
+		assert.True(t, leds.leds[Disk1])
+		assert.True(t, leds.leds[Disk3])
+		assert.False(t, leds.leds[Disk5])
+	})
+
+	t.Run("Getters expose interfaces", func(t *testing.T) {
+		assert.Equal(t, LEDControllerInterface(leds), sc.GetLEDController())
+		assert.Nil(t, sc.GetDisplayController())
+	})
+}
+
+// idleIOPort reports the copy button as released
+type idleIOPort struct{}
+
+func (idleIOPort) ReadByte() (byte, error) { return 0x01, nil }
+func (idleIOPort) Close() error            { return nil }
+
+func TestSystemController_CopyButtonSource(t *testing.T) {
+	t.Run("I/O port monitor disables serial detection", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		sc := &SystemController{
+			display:    dc,
+			usbMonitor: monitor.NewUSBCopyMonitorWithIOPort(0xa05, idleIOPort{}),
+			logger:     logrus.WithField("component", "system_controller"),
+		}
+		t.Cleanup(func() { sc.usbMonitor.Close() })
+
+		sc.selectCopyButtonSource()
+		assert.Equal(t, CopyButtonIOPort, sc.CopyButtonSource())
+		assert.False(t, dc.SerialCopyDetection())
+	})
+
+	t.Run("Missing monitor falls back to serial frames", func(t *testing.T) {
+		dc, _ := newTestDisplayController(t)
+		dc.SetSerialCopyDetection(false)
+		sc := &SystemController{
+			display: dc,
+			logger:  logrus.WithField("component", "system_controller"),
+		}
+
+		sc.selectCopyButtonSource()
+		assert.Equal(t, CopyButtonSerial, sc.CopyButtonSource())
+		assert.True(t, dc.SerialCopyDetection())
+	})
+}
+
+func TestDisplayController_SerialCopyDetectionDisabled(t *testing.T) {
+	dc, _ := newTestDisplayController(t)
+	dc.lastButtonState[ButtonEnter] = false
+	dc.lastButtonState[ButtonSelect] = false
+	dc.lastButtonState[ButtonUSBCopy] = false
+	dc.SetSerialCopyDetection(false)
+	events := collectButtonEvents(dc)
+
+	// Copy frames are consumed without events, other buttons still work
+	buffer := []byte{0x55, 0x01, 0x53, 0x05, 0x00, 0xFE}
+	dc.processMessageBuffer(&buffer)
+	assert.Empty(t, buffer)
+	assert.Equal(t, buttonEvent{ButtonEnter, true}, waitForEvent(t, events))
+	expectNoEvent(t, events)
+}
+
+func TestSystemController_BreakerEvents(t *testing.T) {
+	leds := newFakeLEDController()
+	sc := &SystemController{
+		led:    leds,
+		logger: logrus.WithField("component", "system_controller"),
+	}
+
+	var forwarded []BreakerState
+	sc.SetBreakerHandler(func(event BreakerEvent) {
+		forwarded = append(forwarded, event.To)
+	})
+
+	sc.handleBreakerEvent(BreakerEvent{From: BreakerClosed, To: BreakerOpen})
+	assert.True(t, leds.statusRed)
+	assert.False(t, leds.statusGrn)
+
+	// Probing does not change the LED
+	sc.handleBreakerEvent(BreakerEvent{From: BreakerOpen, To: BreakerHalfOpen})
+	assert.True(t, leds.statusRed)
+
+	sc.handleBreakerEvent(BreakerEvent{From: BreakerHalfOpen, To: BreakerClosed})
+	assert.False(t, leds.statusRed)
+	assert.True(t, leds.statusGrn)
+
+	assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}, forwarded)
+}
+
+func TestSystemController_QuietStatusLED(t *testing.T) {
+	leds := newFakeLEDController()
+	sc := &SystemController{
+		led:    newQuietLEDs(leds),
+		logger: logrus.WithField("component", "system_controller"),
+	}
+
+	assert.NoError(t, sc.SetQuietStatusLED(true))
+	sc.handleBreakerEvent(BreakerEvent{From: BreakerClosed, To: BreakerOpen})
+	assert.False(t, leds.statusRed, "red stays off while quiet")
+	assert.True(t, leds.statusGrn)
+
+	assert.NoError(t, sc.led.SetLED(StatusRed, true))
+	assert.False(t, leds.leds[StatusRed])
+
+	// The failure is shown once quiet ends
+	assert.NoError(t, sc.SetQuietStatusLED(false))
+	assert.True(t, leds.statusRed)
+	assert.False(t, leds.statusGrn)
+}
+
+func TestSystemController_LEDHandler(t *testing.T) {
+	leds := newFakeLEDController()
+	watched := newWatchedLEDs(leds)
+	sc := &SystemController{
+		led:       newQuietLEDs(watched),
+		ledEvents: watched,
+		logger:    logrus.WithField("component", "system_controller"),
+	}
+
+	var switched []string
+	sc.SetLEDHandler(func(led PanelLED, on bool) {
+		switched = append(switched, fmt.Sprintf("%s %v", led, on))
+	})
+
+	assert.NoError(t, sc.led.SetStatusLED(false, true))
+	assert.NoError(t, sc.led.SetDiskLEDs(map[int]bool{2: true, 9: true}))
+	assert.NoError(t, sc.led.SetLED(Disk2, true), "unchanged, not reported")
+	assert.NoError(t, sc.led.SetLED(Disk2, false))
+	assert.Equal(t, []string{"status-green true", "status-red false", "disk2 true", "disk2 false"}, switched)
+
+	// Red held back while quiet is not reported as on
+	switched = nil
+	assert.NoError(t, sc.SetQuietStatusLED(true))
+	assert.NoError(t, sc.led.SetStatusLED(true, false))
+	assert.Empty(t, switched)
+}
@@ -0,0 +1,107 @@
+package controller
+
+import "sync"
+
+// LEDEventHandler is told every LED switched on or off
+type LEDEventHandler func(led PanelLED, on bool)
+
+// watchedLEDs tells a handler about the LEDs that changed. Switching an LED
+// to the state it already has is not reported, so disk activity that keeps
+// a LED lit does not flood the handler.
+type watchedLEDs struct {
+	LEDControllerInterface
+
+	mutex   sync.Mutex
+	handler LEDEventHandler
+	// states are the last switched states; LEDs never switched are missing
+	states map[PanelLED]bool
+}
+
+// newWatchedLEDs wraps an LED controller
+func newWatchedLEDs(leds LEDControllerInterface) *watchedLEDs {
+	return &watchedLEDs{LEDControllerInterface: leds, states: make(map[PanelLED]bool)}
+}
+
+// setHandler sets the handler (nil = none)
+func (w *watchedLEDs) setHandler(handler LEDEventHandler) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.handler = handler
+}
+
+// SetLED switches a single LED
+func (w *watchedLEDs) SetLED(led PanelLED, on bool) error {
+	if err := w.LEDControllerInterface.SetLED(led, on); err != nil {
+		return err
+	}
+	w.report(map[PanelLED]bool{led: on})
+	return nil
+}
+
+// SetDiskLEDs switches disk LEDs by disk number
+func (w *watchedLEDs) SetDiskLEDs(states map[int]bool) error {
+	if err := w.LEDControllerInterface.SetDiskLEDs(states); err != nil {
+		return err
+	}
+	changed := make(map[PanelLED]bool, len(states))
+	for diskNum, on := range states {
+		if diskNum >= 1 && diskNum <= 6 {
+			changed[Disk1+PanelLED(diskNum-1)] = on
+		}
+	}
+	w.report(changed)
+	return nil
+}
+
+// SetStatusLED sets the status LED pair
+func (w *watchedLEDs) SetStatusLED(red bool, green bool) error {
+	if err := w.LEDControllerInterface.SetStatusLED(red, green); err != nil {
+		return err
+	}
+	w.report(map[PanelLED]bool{StatusRed: red, StatusGreen: green})
+	return nil
+}
+
+// report tells the handler about the LEDs whose state changed, in LED order
+func (w *watchedLEDs) report(switched map[PanelLED]bool) {
+	w.mutex.Lock()
+	handler := w.handler
+	var changed []PanelLED
+	for led := StatusGreen; led <= Disk6; led++ {
+		on, ok := switched[led]
+		if !ok {
+			continue
+		}
+		if was, known := w.states[led]; !known || was != on {
+			changed = append(changed, led)
+		}
+		w.states[led] = on
+	}
+	w.mutex.Unlock()
+
+	if handler == nil {
+		return
+	}
+	for _, led := range changed {
+		handler(led, switched[led])
+	}
+}
+
+// restore switches the wrapped controller's LEDs to the last switched
+// states, e.g. on a controller opened again. Nothing is reported.
+func (w *watchedLEDs) restore() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for led := StatusGreen; led <= Disk6; led++ {
+		on, known := w.states[led]
+		if !known {
+			continue
+		}
+		if err := w.LEDControllerInterface.SetLED(led, on); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Package copyreport writes the report of a USB copy into the directory it
+// copied to, so users have evidence of what was copied: every file with its
+// size and SHA-256, how long the copy took and what went wrong.
+//
+// A report is two files named after the time the copy finished: a JSON file
+// with everything, and a .sha256 file listing the checksums as sha256sum
+// writes them, so "sha256sum -c" checks the copy later on.
+package copyreport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/sysinfo"
+	"golang.org/x/sys/unix"
+)
+
+// Prefix starts the names of the report files, which later reports leave out
+const Prefix = "copy-report-"
+
+// coarseClock is how far inode times may lag behind time.Now, the kernel
+// taking them from a coarser clock
+const coarseClock = time.Second
+
+// Report is the report of one copy
+type Report struct {
+	Profile   string `json:"profile"`
+	Direction string `json:"direction"`
+	// Destination is the directory the copy went to and the report is
+	// written to
+	Destination string    `json:"destination"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	// Duration is Finished less Started, e.g. "2m5s"
+	Duration string `json:"duration"`
+	Files    []File `json:"files"`
+	Bytes    uint64 `json:"bytes"`
+	// Errors are what went wrong, e.g. the copy failing or a file that could
+	// not be read back
+	Errors []string `json:"errors,omitempty"`
+}
+
+// File is one file copied, its path relative to the destination
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// New starts the report of a copy to destination
+func New(profile, direction, destination string, started time.Time) *Report {
+	return &Report{Profile: profile, Direction: direction, Destination: destination, Started: started}
+}
+
+// AddError records something that went wrong with the copy
+func (r *Report) AddError(err error) {
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// Collect reads back the regular files below the destination whose inode
+// changed since since, give or take coarseClock, which copy tools cannot
+// backdate as they do modification times, and checksums them. A zero since
+// takes every file.
+// Files that cannot be read are recorded as errors; only a destination that
+// cannot be walked fails Collect.
+func (r *Report) Collect(since time.Time) error {
+	buffer := make([]byte, 1024*1024)
+	var cutoff time.Time
+	if !since.IsZero() {
+		cutoff = since.Add(-coarseClock)
+	}
+	err := filepath.WalkDir(r.Destination, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == r.Destination {
+				return err
+			}
+			r.AddError(err)
+			return nil
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), Prefix) {
+			return nil
+		}
+		relative, err := filepath.Rel(r.Destination, path)
+		if err != nil {
+			return err
+		}
+
+		var stat unix.Stat_t
+		if err := unix.Lstat(path, &stat); err != nil {
+			r.AddError(err)
+			return nil
+		}
+		if time.Unix(stat.Ctim.Unix()).Before(cutoff) {
+			return nil
+		}
+		sum, err := checksum(path, buffer)
+		if err != nil {
+			r.AddError(err)
+			return nil
+		}
+		r.Files = append(r.Files, File{Path: relative, Size: stat.Size, SHA256: sum})
+		r.Bytes += uint64(stat.Size)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read back %s: %w", r.Destination, err)
+	}
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	return nil
+}
+
+// checksum returns the SHA-256 of a file in hex
+func checksum(path string, buffer []byte) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.CopyBuffer(hash, f, buffer); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Write finishes the report and writes it to the destination, flushed so
+// the device can be pulled once Write returns. It returns the path of the
+// JSON file.
+func (r *Report) Write(finished time.Time) (string, error) {
+	r.Finished = finished
+	r.Duration = finished.Sub(r.Started).Round(time.Second).String()
+	if r.Files == nil {
+		r.Files = []File{}
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	var sums strings.Builder
+	for _, file := range r.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", file.SHA256, file.Path)
+	}
+
+	base := filepath.Join(r.Destination, Prefix+finished.Format("20060102-150405"))
+	if err := writeFile(base+".sha256", []byte(sums.String())); err != nil {
+		return "", err
+	}
+	if err := writeFile(base+".json", append(data, '\n')); err != nil {
+		return "", err
+	}
+	return base + ".json", nil
+}
+
+// writeFile writes a new file and flushes it
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write copy report: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write copy report: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to flush copy report: %w", err)
+	}
+	return f.Close()
+}
+
+// summaryErrors is how many errors Summary shows, the rest being counted
+const summaryErrors = 3
+
+// Summary renders the report for the panel, one screen of two lines each,
+// e.g. "12 files" above "3.4G", lines cut to width
+func (r *Report) Summary(width int) []string {
+	pages := [][2]string{
+		{fmt.Sprintf("%d files", len(r.Files)), sysinfo.FormatBytes(r.Bytes)},
+		{"Took " + r.Duration, fmt.Sprintf("%d errors", len(r.Errors))},
+	}
+	for i, message := range r.Errors {
+		if i == summaryErrors {
+			pages = append(pages, [2]string{"Error:", fmt.Sprintf("%d more", len(r.Errors)-i)})
+			break
+		}
+		pages = append(pages, [2]string{"Error:", message})
+	}
+
+	summary := make([]string, len(pages))
+	for i, page := range pages {
+		summary[i] = cut(page[0], width) + "\n" + cut(page[1], width)
+	}
+	return summary
+}
+
+// cut shortens a line to width
+func cut(line string, width int) string {
+	if width > 0 && len(line) > width {
+		return line[:width]
+	}
+	return line
+}
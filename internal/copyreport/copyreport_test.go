@@ -0,0 +1,92 @@
+package copyreport
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFileT(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestReport(t *testing.T) {
+	destination := t.TempDir()
+	writeFileT(t, filepath.Join(destination, "a.txt"), "hello")
+	writeFileT(t, filepath.Join(destination, "sub", "b.txt"), "world!")
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(destination, "link")))
+
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	report := New("Photos", "import", destination, started)
+	require.NoError(t, report.Collect(time.Time{}))
+	report.AddError(errors.New("exit status 23"))
+	path, err := report.Write(started.Add(125 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(destination, "copy-report-20240501-120205.json"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written Report
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, "Photos", written.Profile)
+	assert.Equal(t, "2m5s", written.Duration)
+	assert.Equal(t, uint64(11), written.Bytes)
+	assert.Equal(t, []string{"exit status 23"}, written.Errors)
+	assert.Equal(t, []File{
+		{Path: "a.txt", Size: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{Path: "sub/b.txt", Size: 6, SHA256: "711e9609339e92b03ddc0a211827dba421f38f9ed8b9d806e1ffdd8c15ffa03d"},
+	}, written.Files)
+
+	sums, err := os.ReadFile(strings.TrimSuffix(path, ".json") + ".sha256")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  a.txt\n"+
+		"711e9609339e92b03ddc0a211827dba421f38f9ed8b9d806e1ffdd8c15ffa03d  sub/b.txt\n", string(sums))
+
+	// A second report leaves the first one out
+	again := New("Photos", "import", destination, started)
+	require.NoError(t, again.Collect(time.Time{}))
+	assert.Len(t, again.Files, 2)
+}
+
+func TestReport_CollectSince(t *testing.T) {
+	destination := t.TempDir()
+	writeFileT(t, filepath.Join(destination, "earlier.txt"), "imported last week")
+	time.Sleep(coarseClock + 100*time.Millisecond)
+	started := time.Now()
+	writeFileT(t, filepath.Join(destination, "new.txt"), "imported now")
+	// Copy tools keep the modification times of the source
+	old := started.Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(destination, "new.txt"), old, old))
+
+	report := New("Photos", "import", destination, started)
+	require.NoError(t, report.Collect(started))
+	require.Len(t, report.Files, 1)
+	assert.Equal(t, "new.txt", report.Files[0].Path)
+
+	assert.Error(t, New("Photos", "import", filepath.Join(destination, "missing"), started).Collect(started))
+}
+
+func TestReport_Summary(t *testing.T) {
+	report := &Report{Files: make([]File, 12), Bytes: 3 << 30, Duration: "2m5s"}
+	assert.Equal(t, []string{"12 files\n3.0G", "Took 2m5s\n0 errors"}, report.Summary(16))
+
+	for _, message := range []string{"exit status 23", "open a.txt: permission denied", "c", "d", "e"} {
+		report.AddError(errors.New(message))
+	}
+	assert.Equal(t, []string{
+		"12 files\n3.0G",
+		"Took 2m5s\n5 errors",
+		"Error:\nexit status 23",
+		"Error:\nopen a.txt: perm",
+		"Error:\nc",
+		"Error:\n2 more",
+	}, report.Summary(16))
+}
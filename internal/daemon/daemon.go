@@ -0,0 +1,183 @@
+// Package daemon runs the service detached from the terminal on systems
+// without systemd, and finds it again through its PID file to stop or
+// reload it.
+//
+// Go cannot fork a running process, so Detach starts the program again in a
+// new session with DetachedEnv set; the new process calls Setup and writes
+// the PID file, and the one started from the terminal exits.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultPIDFile is where the PID of a detached service is written unless
+// configured otherwise
+const DefaultPIDFile = "/run/qnap-display.pid"
+
+// DetachedEnv is set for the process Detach starts
+const DetachedEnv = "QNAP_DISPLAY_DETACHED"
+
+// Umask is the file mode mask of the detached service
+const Umask = 0o022
+
+// ErrNotRunning is returned when the PID file names no running process
+var ErrNotRunning = errors.New("service not running")
+
+// Detached reports whether this process was started by Detach. The variable
+// is removed again, so commands run by the service do not see it.
+func Detached() bool {
+	detached := os.Getenv(DetachedEnv) == "1"
+	os.Unsetenv(DetachedEnv)
+	return detached
+}
+
+// Detach starts this program again with the same arguments in a new
+// session, without a terminal, its output appended to logFile ("" =
+// discarded). It returns the new process; waiting for it reports an early
+// exit.
+func Detach(logFile string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find executable: %w", err)
+	}
+
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		return nil, err
+	}
+	defer null.Close()
+	output, err := OpenLog(logFile)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), DetachedEnv+"=1")
+	cmd.Dir = "/"
+	cmd.Stdin = null
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start detached service: %w", err)
+	}
+	return cmd, nil
+}
+
+// Setup sets the umask and working directory of the detached service, so
+// it neither keeps a mount busy nor depends on the caller's umask
+func Setup() error {
+	unix.Umask(Umask)
+	return os.Chdir("/")
+}
+
+// OpenLog opens the file the detached service writes to, or /dev/null for
+// ""
+func OpenLog(path string) (*os.File, error) {
+	if path == "" {
+		return os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, nil
+}
+
+// Redirect points stdout and stderr at path, e.g. after logrotate moved the
+// file away
+func Redirect(path string) error {
+	file, err := OpenLog(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, fd := range []int{1, 2} {
+		if err := unix.Dup3(int(file.Fd()), fd, 0); err != nil {
+			return fmt.Errorf("failed to redirect output: %w", err)
+		}
+	}
+	return nil
+}
+
+// WritePIDFile writes this process's PID to path. It fails if the file
+// names another process still running; files left behind by a crash are
+// replaced.
+func WritePIDFile(path string) error {
+	if pid, err := ReadPIDFile(path); err == nil && pid != os.Getpid() && Running(pid) {
+		return fmt.Errorf("already running with pid %d (%s)", pid, path)
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644)
+}
+
+// RemovePIDFile removes path if it still names this process
+func RemovePIDFile(path string) error {
+	pid, err := ReadPIDFile(path)
+	if err != nil || pid != os.Getpid() {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// ReadPIDFile returns the PID written to path
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file %s", path)
+	}
+	return pid, nil
+}
+
+// Running reports whether a process with pid exists. Processes of other
+// users count as running.
+func Running(pid int) bool {
+	err := unix.Kill(pid, 0)
+	return err == nil || errors.Is(err, unix.EPERM)
+}
+
+// Signal sends sig to the process named in the PID file at path and
+// returns its PID
+func Signal(path string, sig syscall.Signal) (int, error) {
+	pid, err := ReadPIDFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNotRunning
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !Running(pid) {
+		return pid, ErrNotRunning
+	}
+	if err := unix.Kill(pid, sig); err != nil {
+		return pid, fmt.Errorf("failed to signal pid %d: %w", pid, err)
+	}
+	return pid, nil
+}
+
+// WaitExit waits until the process with pid is gone, at most timeout
+func WaitExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for Running(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qnap-display.pid")
+
+	require.NoError(t, WritePIDFile(path))
+	pid, err := ReadPIDFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	require.NoError(t, RemovePIDFile(path))
+	assert.NoFileExists(t, path)
+}
+
+func TestWritePIDFile_Running(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qnap-display.pid")
+
+	// Another instance still running keeps its PID file
+	other := exec.Command("sleep", "10")
+	require.NoError(t, other.Start())
+	t.Cleanup(func() {
+		other.Process.Kill()
+		other.Wait()
+	})
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(other.Process.Pid)+"\n"), 0o644))
+	assert.Error(t, WritePIDFile(path))
+
+	// and does not lose it to this one
+	require.NoError(t, RemovePIDFile(path))
+	assert.FileExists(t, path)
+
+	// A file left behind by a crash is replaced
+	other.Process.Kill()
+	other.Wait()
+	require.NoError(t, WritePIDFile(path))
+}
+
+func TestReadPIDFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qnap-display.pid")
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o644))
+
+	_, err := ReadPIDFile(path)
+	assert.Error(t, err)
+}
+
+func TestSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qnap-display.pid")
+
+	_, err := Signal(path, syscall.SIGTERM)
+	assert.ErrorIs(t, err, ErrNotRunning)
+
+	service := exec.Command("sleep", "10")
+	require.NoError(t, service.Start())
+	exited := make(chan struct{})
+	go func() {
+		service.Wait()
+		close(exited)
+	}()
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(service.Process.Pid)), 0o644))
+
+	pid, err := Signal(path, syscall.SIGTERM)
+	require.NoError(t, err)
+	assert.Equal(t, service.Process.Pid, pid)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process not stopped")
+	}
+	assert.True(t, WaitExit(pid, time.Second))
+}
+
+func TestDetached(t *testing.T) {
+	t.Setenv(DetachedEnv, "1")
+	assert.True(t, Detached())
+	assert.False(t, Detached(), "the variable is not passed on")
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Path is where the events are served
+const Path = "/api/events"
+
+// Response is the answer to an events request
+type Response struct {
+	Events []Event `json:"events"`
+	// Size is how many events the log keeps
+	Size int `json:"size"`
+	// Dropped is how many older events are no longer kept
+	Dropped uint64 `json:"dropped"`
+}
+
+// Handler serves the events of log at Path, oldest first. Query parameters
+// filter them:
+//
+//	kind=button,command   only these kinds
+//	since=10m             recorded in the last 10 minutes, or since an
+//	                      RFC 3339 time such as 2024-05-01T14:00:00Z
+//	after=120             after sequence number 120, for polling
+//	contains=backup       message or a field contains the text
+//	limit=50              only the newest 50
+func Handler(log *Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		filter, err := ParseFilter(r.URL.Query(), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{
+			Events:  log.Query(filter),
+			Size:    log.Size(),
+			Dropped: log.Dropped(),
+		})
+	})
+}
+
+// ParseFilter reads a filter from the query parameters Handler takes. A
+// relative since is counted back from now.
+func ParseFilter(query url.Values, now time.Time) (Filter, error) {
+	var filter Filter
+
+	if kinds := query.Get("kind"); kinds != "" {
+		for _, name := range strings.Split(kinds, ",") {
+			kind, err := parseKind(strings.TrimSpace(name))
+			if err != nil {
+				return Filter{}, err
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	if since := query.Get("since"); since != "" {
+		if ago, err := time.ParseDuration(since); err == nil {
+			filter.Since = now.Add(-ago)
+		} else if at, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = at
+		} else {
+			return Filter{}, fmt.Errorf("invalid since %q, want a duration such as 10m or an RFC 3339 time", since)
+		}
+	}
+
+	if after := query.Get("after"); after != "" {
+		seq, err := strconv.ParseUint(after, 10, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid after %q, want a sequence number", after)
+		}
+		filter.After = seq
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return Filter{}, fmt.Errorf("invalid limit %q", limit)
+		}
+		filter.Limit = n
+	}
+
+	filter.Contains = query.Get("contains")
+	return filter, nil
+}
+
+// parseKind checks the name of a kind
+func parseKind(name string) (Kind, error) {
+	for _, kind := range Kinds {
+		if Kind(name) == kind {
+			return kind, nil
+		}
+	}
+	names := make([]string, len(Kinds))
+	for i, kind := range Kinds {
+		names[i] = string(kind)
+	}
+	return "", fmt.Errorf("unknown kind %q, want one of %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,234 @@
+// Package events keeps the service's recent internal events, such as button
+// presses, screen switches, commands run and serial link failures, in a
+// ring buffer. When the panel is reported to have "frozen at some point",
+// the events around that time show what it was doing.
+//
+// Subscribers follow the events live, e.g. a web dashboard of the panel
+// over the WebSocket at StreamPath. Frequent events such as LED changes and
+// copy progress are only published to them, not kept.
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSize is how many events are kept unless configured otherwise
+const DefaultSize = 1000
+
+// Kind is the kind of an event
+type Kind string
+
+const (
+	// KindButton is a panel button pressed or released
+	KindButton Kind = "button"
+	// KindScreen is a switch of the visible screen, e.g. from the menu to a
+	// copy in progress
+	KindScreen Kind = "screen"
+	// KindCommand is a command run from the menu or the copy button
+	KindCommand Kind = "command"
+	// KindSerial is a change of the serial link, e.g. writes failing
+	KindSerial Kind = "serial"
+	// KindMenu is a move to another menu item
+	KindMenu Kind = "menu"
+	// KindProgress is the progress of a USB copy; published only
+	KindProgress Kind = "progress"
+	// KindLED is an LED switched on or off; published only
+	KindLED Kind = "led"
+)
+
+// Kinds are all kinds of events
+var Kinds = []Kind{KindButton, KindScreen, KindCommand, KindSerial, KindMenu, KindProgress, KindLED}
+
+// Fields are details of an event, such as the command that ran
+type Fields map[string]string
+
+// Event is something that happened in the service
+type Event struct {
+	// Seq numbers the events in the order they were recorded or published,
+	// from 1
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Kind    Kind      `json:"kind"`
+	Message string    `json:"message"`
+	Fields  Fields    `json:"fields,omitempty"`
+}
+
+// Filter selects events. The zero Filter selects all of them.
+type Filter struct {
+	// Kinds keeps the events of these kinds; empty keeps every kind
+	Kinds []Kind
+	// Since keeps the events recorded at or after this time
+	Since time.Time
+	// After keeps the events after this sequence number, for polling
+	After uint64
+	// Contains keeps the events whose message or field values contain this
+	// text, ignoring case
+	Contains string
+	// Limit keeps only this many of the newest matching events; 0 keeps all
+	Limit int
+}
+
+// matches reports whether the filter selects event
+func (f Filter) matches(event Event) bool {
+	if event.Seq <= f.After || event.Time.Before(f.Since) {
+		return false
+	}
+	if len(f.Kinds) > 0 {
+		found := false
+		for _, kind := range f.Kinds {
+			found = found || kind == event.Kind
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Contains == "" {
+		return true
+	}
+	text := strings.ToLower(f.Contains)
+	if strings.Contains(strings.ToLower(event.Message), text) {
+		return true
+	}
+	for _, value := range event.Fields {
+		if strings.Contains(strings.ToLower(value), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Log is a ring buffer of the most recent events. It is safe for concurrent
+// use.
+type Log struct {
+	mutex  sync.Mutex
+	events []Event
+	// next is where the next event goes once the buffer is full
+	next int
+	seq  uint64
+	// recorded counts the events kept, for Dropped
+	recorded    uint64
+	subscribers map[*subscriber]bool
+	// now returns the current time; replaced in tests
+	now func() time.Time
+}
+
+// subscriber is a follower of the events a filter selects
+type subscriber struct {
+	filter Filter
+	events chan Event
+}
+
+// NewLog creates a log keeping the last size events (DefaultSize if size is
+// not positive)
+func NewLog(size int) *Log {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Log{
+		events:      make([]Event, 0, size),
+		subscribers: make(map[*subscriber]bool),
+		now:         time.Now,
+	}
+}
+
+// Record adds an event, dropping the oldest one if the log is full. fields
+// may be nil.
+func (l *Log) Record(kind Kind, message string, fields Fields) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	event := l.publish(kind, message, fields)
+	l.recorded++
+	if len(l.events) < cap(l.events) {
+		l.events = append(l.events, event)
+		return
+	}
+	l.events[l.next] = event
+	l.next = (l.next + 1) % len(l.events)
+}
+
+// Publish sends an event to the subscribers without keeping it, for events
+// too frequent for the log such as LED changes
+func (l *Log) Publish(kind Kind, message string, fields Fields) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.publish(kind, message, fields)
+}
+
+// publish numbers an event and sends it to the subscribers selecting it. A
+// subscriber whose buffer is full misses it rather than hold up the
+// service. Caller must hold the mutex.
+func (l *Log) publish(kind Kind, message string, fields Fields) Event {
+	l.seq++
+	event := Event{Seq: l.seq, Time: l.now(), Kind: kind, Message: message, Fields: fields}
+	for sub := range l.subscribers {
+		if !sub.filter.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+	return event
+}
+
+// Subscribe follows the events recorded or published from now on that
+// filter selects; its Limit is ignored. Up to buffer events are queued for
+// a slow reader, later ones are missed. cancel ends the subscription and
+// closes the channel.
+func (l *Log) Subscribe(filter Filter, buffer int) (events <-chan Event, cancel func()) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	sub := &subscriber{filter: filter, events: make(chan Event, buffer)}
+	l.subscribers[sub] = true
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+
+			delete(l.subscribers, sub)
+			close(sub.events)
+		})
+	}
+}
+
+// Query returns the events the filter selects, oldest first
+func (l *Log) Query(filter Filter) []Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	matched := make([]Event, 0)
+	for i := range l.events {
+		event := l.events[(l.next+i)%len(l.events)]
+		if filter.matches(event) {
+			matched = append(matched, event)
+		}
+	}
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[len(matched)-filter.Limit:]
+	}
+	return matched
+}
+
+// Dropped returns how many events were recorded but are no longer kept
+func (l *Log) Dropped() uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.recorded - uint64(len(l.events))
+}
+
+// Size returns how many events the log keeps
+func (l *Log) Size() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return cap(l.events)
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// start is when the test logs record their first event
+var start = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// newTestLog creates a log whose clock advances a minute per event
+func newTestLog(size int) *Log {
+	log := NewLog(size)
+	now := start.Add(-time.Minute)
+	log.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	return log
+}
+
+// messages returns the messages of events
+func messages(events []Event) []string {
+	out := make([]string, len(events))
+	for i, event := range events {
+		out[i] = event.Message
+	}
+	return out
+}
+
+func TestLogRing(t *testing.T) {
+	log := newTestLog(3)
+	assert.Empty(t, log.Query(Filter{}))
+
+	log.Record(KindButton, "enter pressed", nil)
+	log.Record(KindButton, "enter released", nil)
+	assert.Equal(t, []string{"enter pressed", "enter released"}, messages(log.Query(Filter{})))
+	assert.Zero(t, log.Dropped())
+
+	log.Record(KindScreen, "menu -> copy", nil)
+	log.Record(KindCommand, "rsync", Fields{"status": "ok"})
+	log.Record(KindScreen, "copy -> menu", nil)
+	all := log.Query(Filter{})
+	assert.Equal(t, []string{"menu -> copy", "rsync", "copy -> menu"}, messages(all), "oldest events are dropped")
+	assert.Equal(t, uint64(3), all[0].Seq)
+	assert.Equal(t, start.Add(2*time.Minute), all[0].Time)
+	assert.Equal(t, uint64(2), log.Dropped())
+	assert.Equal(t, 3, log.Size())
+}
+
+func TestLogQuery(t *testing.T) {
+	log := newTestLog(10)
+	log.Record(KindButton, "enter pressed", nil)
+	log.Record(KindCommand, "df -h", Fields{"status": "ok"})
+	log.Record(KindSerial, "link down", Fields{"error": "write timeout"})
+	log.Record(KindButton, "select pressed", nil)
+	log.Record(KindCommand, "backup.sh", Fields{"status": "failed"})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"kinds", Filter{Kinds: []Kind{KindCommand, KindSerial}}, []string{"df -h", "link down", "backup.sh"}},
+		{"since", Filter{Since: start.Add(3 * time.Minute)}, []string{"select pressed", "backup.sh"}},
+		{"after", Filter{After: 3}, []string{"select pressed", "backup.sh"}},
+		{"contains message", Filter{Contains: "PRESSED"}, []string{"enter pressed", "select pressed"}},
+		{"contains field", Filter{Contains: "timeout"}, []string{"link down"}},
+		{"limit keeps the newest", Filter{Kinds: []Kind{KindButton, KindCommand}, Limit: 2}, []string{"select pressed", "backup.sh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, messages(log.Query(tt.filter)))
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	now := start.Add(time.Hour)
+
+	filter, err := ParseFilter(url.Values{
+		"kind":     {"button, serial"},
+		"since":    {"10m"},
+		"after":    {"42"},
+		"contains": {"copy"},
+		"limit":    {"5"},
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, Filter{
+		Kinds:    []Kind{KindButton, KindSerial},
+		Since:    now.Add(-10 * time.Minute),
+		After:    42,
+		Contains: "copy",
+		Limit:    5,
+	}, filter)
+
+	filter, err = ParseFilter(url.Values{"since": {"2024-05-01T14:00:00Z"}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, start, filter.Since)
+
+	for _, query := range []url.Values{
+		{"kind": {"keyboard"}},
+		{"since": {"yesterday"}},
+		{"after": {"-1"}},
+		{"limit": {"many"}},
+	} {
+		_, err := ParseFilter(query, now)
+		assert.Error(t, err, query.Encode())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	log := newTestLog(2)
+	log.Record(KindButton, "enter pressed", nil)
+	log.Record(KindCommand, "df -h", Fields{"status": "ok"})
+	log.Record(KindButton, "enter released", nil)
+	server := httptest.NewServer(Handler(log))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + Path + "?kind=command")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var response Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, Response{
+		Events:  []Event{{Seq: 2, Time: start.Add(time.Minute), Kind: KindCommand, Message: "df -h", Fields: Fields{"status": "ok"}}},
+		Size:    2,
+		Dropped: 1,
+	}, response)
+
+	resp, err = http.Get(server.URL + Path + "?kind=keyboard")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+Path, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestSubscribe(t *testing.T) {
+	log := newTestLog(10)
+	events, cancel := log.Subscribe(Filter{Kinds: []Kind{KindLED, KindCommand}}, 2)
+
+	log.Record(KindButton, "enter pressed", nil)
+	log.Publish(KindLED, "usb on", nil)
+	log.Record(KindCommand, "df -h", nil)
+	log.Publish(KindLED, "usb off", nil)
+	assert.Equal(t, "usb on", (<-events).Message)
+	assert.Equal(t, "df -h", (<-events).Message)
+	assert.Empty(t, events, "missed while the buffer was full")
+
+	// Published events are numbered but not kept
+	assert.Equal(t, []string{"enter pressed", "df -h"}, messages(log.Query(Filter{})))
+	assert.Equal(t, uint64(3), log.Query(Filter{})[1].Seq)
+	assert.Zero(t, log.Dropped())
+
+	cancel()
+	cancel()
+	_, open := <-events
+	assert.False(t, open)
+	log.Publish(KindLED, "usb on", nil)
+}
+
+func TestStreamHandler(t *testing.T) {
+	log := newTestLog(10)
+	log.Record(KindButton, "enter pressed", nil)
+	log.Record(KindMenu, "Main Menu > System", nil)
+	server := httptest.NewServer(StreamHandler(log))
+	defer server.Close()
+	address := "ws" + strings.TrimPrefix(server.URL, "http") + StreamPath
+
+	// Reconnecting after seq 1 sends the kept events since, then live ones
+	conn, err := websocket.Dial(address+"?after=1&kind=menu,led", "", server.URL)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var event Event
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, "Main Menu > System", event.Message)
+
+	require.Eventually(t, func() bool {
+		log.mutex.Lock()
+		defer log.mutex.Unlock()
+		return len(log.subscribers) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	log.Record(KindButton, "select pressed", nil)
+	log.Publish(KindLED, "disk1 on", Fields{"led": "disk1", "on": "true"})
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, KindLED, event.Kind)
+	assert.Equal(t, Fields{"led": "disk1", "on": "true"}, event.Fields)
+
+	// The subscription ends with the connection
+	conn.Close()
+	require.Eventually(t, func() bool {
+		log.mutex.Lock()
+		defer log.mutex.Unlock()
+		return len(log.subscribers) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	resp, err := http.Get(server.URL + StreamPath + "?kind=keyboard")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// StreamPath is where the events are streamed over a WebSocket
+const StreamPath = "/events"
+
+// streamBuffer is how many events are queued for a slow stream client
+// before it misses some
+const streamBuffer = 256
+
+// streamWriteTimeout bounds sending one event, so a client that stopped
+// reading is dropped
+const streamWriteTimeout = 10 * time.Second
+
+// StreamHandler streams the events of log over a WebSocket at StreamPath as
+// they happen, one JSON encoded Event per text message, including those
+// only published. The query parameters of Handler select them; with after
+// or since, the events kept since then are sent first, so a dashboard that
+// reconnects with the last sequence number it saw misses nothing kept.
+//
+// Any origin may connect, since dashboards are served from elsewhere; the
+// events API token protects the stream like the rest of the API.
+func StreamHandler(log *Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter, err := ParseFilter(r.URL.Query(), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		server := websocket.Server{
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler:   func(conn *websocket.Conn) { stream(conn, log, filter) },
+		}
+		server.ServeHTTP(w, r)
+	})
+}
+
+// stream sends the events to one client until it goes away
+func stream(conn *websocket.Conn, log *Log, filter Filter) {
+	defer conn.Close()
+
+	// Subscribing before the backlog is read leaves no gap between them
+	events, cancel := log.Subscribe(filter, streamBuffer)
+	defer cancel()
+	var sent uint64
+	if filter.After > 0 || !filter.Since.IsZero() {
+		for _, event := range log.Query(filter) {
+			if err := send(conn, event); err != nil {
+				return
+			}
+			sent = event.Seq
+		}
+	}
+
+	// Nothing is expected from the client; reading notices it closing and
+	// answers its pings
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event := <-events:
+			if event.Seq <= sent {
+				continue
+			}
+			if err := send(conn, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes one event as a text message
+func send(conn *websocket.Conn, event Event) error {
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return websocket.JSON.Send(conn, event)
+}
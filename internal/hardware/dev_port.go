@@ -0,0 +1,53 @@
+package hardware
+
+import (
+	"fmt"
+	"os"
+)
+
+// DevPortPath is the character device that exposes the I/O ports
+const DevPortPath = "/dev/port"
+
+// DevPort reads and writes I/O ports through a /dev/port handle that stays
+// open. The kernel only checks privileges when the device is opened, so the
+// handle keeps working after the process has dropped root.
+type DevPort struct {
+	file *os.File
+}
+
+// OpenDevPort opens /dev/port for reading and writing
+func OpenDevPort() (*DevPort, error) {
+	return openDevPort(DevPortPath)
+}
+
+// openDevPort opens the given device (a regular file in tests)
+func openDevPort(path string) (*DevPort, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &DevPort{file: file}, nil
+}
+
+// ReadPort reads a byte from an I/O port. Positioned reads keep concurrent
+// callers from moving each other's offset.
+func (d *DevPort) ReadPort(port uint16) (byte, error) {
+	buffer := make([]byte, 1)
+	if _, err := d.file.ReadAt(buffer, int64(port)); err != nil {
+		return 0, fmt.Errorf("failed to read from port %x: %w", port, err)
+	}
+	return buffer[0], nil
+}
+
+// WritePort writes a byte to an I/O port
+func (d *DevPort) WritePort(port uint16, value byte) error {
+	if _, err := d.file.WriteAt([]byte{value}, int64(port)); err != nil {
+		return fmt.Errorf("failed to write to port %x: %w", port, err)
+	}
+	return nil
+}
+
+// Close closes the device
+func (d *DevPort) Close() error {
+	return d.file.Close()
+}
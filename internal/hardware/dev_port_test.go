@@ -0,0 +1,37 @@
+package hardware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevPort(t *testing.T) {
+	// A regular file stands in for /dev/port; offsets are port numbers
+	path := filepath.Join(t.TempDir(), "port")
+	require.NoError(t, os.WriteFile(path, make([]byte, 0x10), 0600))
+
+	dev, err := openDevPort(path)
+	require.NoError(t, err)
+	defer dev.Close()
+
+	require.NoError(t, dev.WritePort(0x0a, 0x5c))
+	value, err := dev.ReadPort(0x0a)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x5c), value)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x5c), contents[0x0a])
+
+	_, err = dev.ReadPort(0x20)
+	assert.Error(t, err)
+}
+
+func TestOpenDevPort_Missing(t *testing.T) {
+	_, err := openDevPort(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package hardware
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// GPIO character device uAPI v2 (linux/gpio.h), which x/sys does not have
+const (
+	gpioLinesMax     = 64
+	gpioConsumerSize = 32
+
+	gpioGetLineIoctl   = 0xC250B407 // GPIO_V2_GET_LINE_IOCTL
+	gpioGetValuesIoctl = 0xC010B40E // GPIO_V2_LINE_GET_VALUES_IOCTL
+	gpioSetValuesIoctl = 0xC010B40F // GPIO_V2_LINE_SET_VALUES_IOCTL
+
+	gpioFlagActiveLow = 1 << 1
+	gpioFlagInput     = 1 << 2
+	gpioFlagOutput    = 1 << 3
+	gpioFlagPullUp    = 1 << 8
+)
+
+// gpioLineAttribute is struct gpio_v2_line_config_attribute
+type gpioLineAttribute struct {
+	ID      uint32
+	Padding uint32
+	Value   uint64
+	Mask    uint64
+}
+
+// gpioLineConfig is struct gpio_v2_line_config
+type gpioLineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [10]gpioLineAttribute
+}
+
+// gpioLineRequest is struct gpio_v2_line_request
+type gpioLineRequest struct {
+	Offsets         [gpioLinesMax]uint32
+	Consumer        [gpioConsumerSize]byte
+	Config          gpioLineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	Padding         [5]uint32
+	FD              int32
+}
+
+// gpioLineValues is struct gpio_v2_line_values
+type gpioLineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+// GPIOLines are lines of a GPIO chip requested together. Bit i of the
+// values is the line at index i of the request.
+type GPIOLines interface {
+	// SetValues drives the lines in mask to the values in bits
+	SetValues(bits, mask uint64) error
+	// Values reads the lines
+	Values() (uint64, error)
+	Close() error
+}
+
+// GPIOChipLines are lines requested from a GPIO chip's character device
+type GPIOChipLines struct {
+	file *os.File
+	mask uint64
+}
+
+// RequestGPIOOutputs requests lines of chip, e.g. /dev/gpiochip0, by their
+// offsets as outputs driven low. The lines stay requested after root
+// privileges are dropped.
+func RequestGPIOOutputs(chip string, offsets []int, consumer string) (*GPIOChipLines, error) {
+	return requestGPIOLines(chip, offsets, consumer, gpioFlagOutput)
+}
+
+// RequestGPIOInputs requests lines of chip as inputs for buttons that
+// connect them to ground: they are pulled up and read as 1 while pressed
+func RequestGPIOInputs(chip string, offsets []int, consumer string) (*GPIOChipLines, error) {
+	return requestGPIOLines(chip, offsets, consumer, gpioFlagInput|gpioFlagPullUp|gpioFlagActiveLow)
+}
+
+func requestGPIOLines(chip string, offsets []int, consumer string, flags uint64) (*GPIOChipLines, error) {
+	if len(offsets) == 0 || len(offsets) > gpioLinesMax {
+		return nil, fmt.Errorf("cannot request %d GPIO lines", len(offsets))
+	}
+	file, err := os.OpenFile(chip, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", chip, err)
+	}
+	defer file.Close()
+
+	var request gpioLineRequest
+	for i, offset := range offsets {
+		request.Offsets[i] = uint32(offset)
+	}
+	copy(request.Consumer[:gpioConsumerSize-1], consumer)
+	request.Config.Flags = flags
+	request.NumLines = uint32(len(offsets))
+	if err := gpioIoctl(file.Fd(), gpioGetLineIoctl, unsafe.Pointer(&request)); err != nil {
+		return nil, fmt.Errorf("failed to request GPIO lines %v of %s: %w", offsets, chip, err)
+	}
+	return &GPIOChipLines{
+		file: os.NewFile(uintptr(request.FD), chip),
+		mask: 1<<len(offsets) - 1,
+	}, nil
+}
+
+// SetValues drives the lines in mask to the values in bits
+func (l *GPIOChipLines) SetValues(bits, mask uint64) error {
+	values := gpioLineValues{Bits: bits, Mask: mask & l.mask}
+	if err := gpioIoctl(l.file.Fd(), gpioSetValuesIoctl, unsafe.Pointer(&values)); err != nil {
+		return fmt.Errorf("failed to set GPIO lines: %w", err)
+	}
+	return nil
+}
+
+// Values reads the lines
+func (l *GPIOChipLines) Values() (uint64, error) {
+	values := gpioLineValues{Mask: l.mask}
+	if err := gpioIoctl(l.file.Fd(), gpioGetValuesIoctl, unsafe.Pointer(&values)); err != nil {
+		return 0, fmt.Errorf("failed to read GPIO lines: %w", err)
+	}
+	return values.Bits & l.mask, nil
+}
+
+// Close releases the lines
+func (l *GPIOChipLines) Close() error {
+	return l.file.Close()
+}
+
+func gpioIoctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
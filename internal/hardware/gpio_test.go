@@ -0,0 +1,24 @@
+package hardware
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The ioctl numbers encode the sizes of the kernel's structs
+func TestGPIOStructSizes(t *testing.T) {
+	assert.Equal(t, uintptr(592), unsafe.Sizeof(gpioLineRequest{}))
+	assert.Equal(t, uintptr(272), unsafe.Sizeof(gpioLineConfig{}))
+	assert.Equal(t, uintptr(16), unsafe.Sizeof(gpioLineValues{}))
+	assert.Equal(t, uintptr(592), uintptr(gpioGetLineIoctl>>16&0x3FFF))
+	assert.Equal(t, uintptr(16), uintptr(gpioSetValuesIoctl>>16&0x3FFF))
+}
+
+func TestRequestGPIOOutputs_Errors(t *testing.T) {
+	_, err := RequestGPIOOutputs("/nonexistent/gpiochip9", []int{1}, "test")
+	assert.Error(t, err)
+	_, err = RequestGPIOOutputs("/dev/null", nil, "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package hardware
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// i2cSlave is the I2C_SLAVE ioctl selecting the device later reads and
+// writes on an i2c-dev file go to
+const i2cSlave = 0x0703
+
+// I2CBus talks to devices on an I2C bus
+type I2CBus interface {
+	// Tx writes w to the device at addr, then reads len(r) bytes into r.
+	// Either may be empty.
+	Tx(addr uint16, w, r []byte) error
+	Close() error
+}
+
+// I2CDevBus is an I2C bus opened through the kernel's i2c-dev interface
+type I2CDevBus struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// OpenI2CBus opens /dev/i2c-<bus>. The file stays open, so the bus keeps
+// working after root privileges are dropped.
+func OpenI2CBus(bus int) (*I2CDevBus, error) {
+	path := fmt.Sprintf("/dev/i2c-%d", bus)
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &I2CDevBus{file: file}, nil
+}
+
+// Tx selects the device and performs the write, then the read
+func (b *I2CDevBus) Tx(addr uint16, w, r []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err := unix.IoctlSetInt(int(b.file.Fd()), i2cSlave, int(addr)); err != nil {
+		return fmt.Errorf("failed to select i2c device 0x%02x: %w", addr, err)
+	}
+	if len(w) > 0 {
+		if _, err := b.file.Write(w); err != nil {
+			return fmt.Errorf("failed to write to i2c device 0x%02x: %w", addr, err)
+		}
+	}
+	if len(r) > 0 {
+		if _, err := b.file.Read(r); err != nil {
+			return fmt.Errorf("failed to read from i2c device 0x%02x: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the bus
+func (b *I2CDevBus) Close() error {
+	return b.file.Close()
+}
@@ -12,6 +12,9 @@ import (
 type IOPortAccess struct {
 	port     uint16
 	acquired bool
+	// dev is kept open so reads work after privileges are dropped; nil if
+	// /dev/port could not be opened
+	dev *DevPort
 }
 
 // NewIOPortAccess creates a new I/O port access instance
@@ -28,10 +31,14 @@ func NewIOPortAccess(port uint16) (*IOPortAccess, error) {
 		return nil, fmt.Errorf("failed to acquire I/O port 0x%x permissions: %v", port, errno)
 	}
 
-	return &IOPortAccess{
+	io := &IOPortAccess{
 		port:     port,
 		acquired: true,
-	}, nil
+	}
+	if dev, err := OpenDevPort(); err == nil {
+		io.dev = dev
+	}
+	return io, nil
 }
 
 // Close releases I/O port permissions
@@ -40,6 +47,11 @@ func (io *IOPortAccess) Close() error {
 		return nil
 	}
 
+	if io.dev != nil {
+		io.dev.Close()
+		io.dev = nil
+	}
+
 	// Release I/O port permissions
 	_, _, errno := syscall.Syscall(unix.SYS_IOPERM, uintptr(io.port), 1, 0)
 	if errno != 0 {
@@ -56,6 +68,10 @@ func (io *IOPortAccess) ReadByte() (byte, error) {
 		return 0, fmt.Errorf("I/O port not acquired")
 	}
 
+	if io.dev != nil {
+		return io.dev.ReadPort(io.port)
+	}
+
 	// Use inline assembly to read from I/O port
 	var value byte
 	value = inb(io.port)
@@ -68,6 +84,10 @@ func (io *IOPortAccess) WriteByte(value byte) error {
 		return fmt.Errorf("I/O port not acquired")
 	}
 
+	if io.dev != nil {
+		return io.dev.WritePort(io.port, value)
+	}
+
 	// Use inline assembly to write to I/O port
 	outb(io.port, value)
 	return nil
@@ -0,0 +1,106 @@
+package hardware
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PCF8574 is an 8-bit I/O expander on an I2C bus, as on the backpacks of
+// character LCD modules. A write sets all eight pins at once, so the
+// expander keeps the last byte written and changes only the pins asked for.
+type PCF8574 struct {
+	bus  I2CBus
+	addr uint16
+
+	mutex sync.Mutex
+	pins  byte
+	// open counts the lines not closed yet; the bus closes with the last
+	open int
+}
+
+// NewPCF8574 creates an expander at addr on bus. All pins start low.
+func NewPCF8574(bus I2CBus, addr uint16) *PCF8574 {
+	return &PCF8574{bus: bus, addr: addr}
+}
+
+// Lines returns pins of the expander (0 to 7) as lines: bit i of their
+// values is pins[i]. The bus is closed once every Lines is closed.
+func (p *PCF8574) Lines(pins ...int) (GPIOLines, error) {
+	for _, pin := range pins {
+		if pin < 0 || pin > 7 {
+			return nil, fmt.Errorf("invalid PCF8574 pin %d", pin)
+		}
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.open++
+	return &pcf8574Lines{expander: p, pins: append([]int(nil), pins...)}, nil
+}
+
+// pcf8574Lines are pins of a PCF8574
+type pcf8574Lines struct {
+	expander *PCF8574
+	pins     []int
+	closed   bool
+}
+
+// SetValues drives the pins in mask to the values in bits
+func (l *pcf8574Lines) SetValues(bits, mask uint64) error {
+	p := l.expander
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	pins := p.pins
+	for i, pin := range l.pins {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		if bits&(1<<i) != 0 {
+			pins |= 1 << pin
+		} else {
+			pins &^= 1 << pin
+		}
+	}
+	if err := p.bus.Tx(p.addr, []byte{pins}, nil); err != nil {
+		return err
+	}
+	p.pins = pins
+	return nil
+}
+
+// Values reads the pins. Pins driven low read low whatever is attached.
+func (l *pcf8574Lines) Values() (uint64, error) {
+	p := l.expander
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	state := make([]byte, 1)
+	if err := p.bus.Tx(p.addr, nil, state); err != nil {
+		return 0, err
+	}
+	var values uint64
+	for i, pin := range l.pins {
+		if state[0]&(1<<pin) != 0 {
+			values |= 1 << i
+		}
+	}
+	return values, nil
+}
+
+// Close releases the lines, and the bus with the last of them
+func (l *pcf8574Lines) Close() error {
+	p := l.expander
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	p.open--
+	if p.open == 0 {
+		return p.bus.Close()
+	}
+	return nil
+}
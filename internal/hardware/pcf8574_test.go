@@ -0,0 +1,61 @@
+package hardware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expanderBus is a PCF8574 on a bus: it keeps the last byte written and
+// reads it back
+type expanderBus struct {
+	pins   byte
+	writes int
+	closed bool
+}
+
+func (b *expanderBus) Tx(addr uint16, w, r []byte) error {
+	if len(w) > 0 {
+		b.pins = w[len(w)-1]
+		b.writes++
+	}
+	if len(r) > 0 {
+		r[0] = b.pins
+	}
+	return nil
+}
+
+func (b *expanderBus) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestPCF8574(t *testing.T) {
+	bus := &expanderBus{}
+	expander := NewPCF8574(bus, 0x27)
+	data, err := expander.Lines(0, 2, 4)
+	require.NoError(t, err)
+	backlight, err := expander.Lines(3)
+	require.NoError(t, err)
+
+	require.NoError(t, data.SetValues(0b101, 0b111))
+	assert.Equal(t, byte(0b0001_0001), bus.pins)
+	require.NoError(t, backlight.SetValues(1, 1))
+	assert.Equal(t, byte(0b0001_1001), bus.pins, "other lines keep their pins")
+	require.NoError(t, data.SetValues(0b010, 0b010))
+	assert.Equal(t, byte(0b0001_1101), bus.pins, "pins outside the mask are kept")
+
+	values, err := data.Values()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0b111), values)
+
+	_, err = expander.Lines(8)
+	assert.Error(t, err)
+
+	require.NoError(t, data.Close())
+	require.NoError(t, data.Close())
+	assert.False(t, bus.closed, "the backlight still uses the bus")
+	require.NoError(t, backlight.Close())
+	assert.True(t, bus.closed)
+}
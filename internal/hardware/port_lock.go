@@ -0,0 +1,115 @@
+package hardware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// PortLockPath is the lock file tools driving the embedded controller
+// through /dev/port agree to hold while they select a register and access
+// its value. /var/lock links to /run/lock, so tools using either path share
+// it.
+const PortLockPath = "/run/lock/qnap-ec.lock"
+
+// portLockPoll is how often Lock tries again while another tool holds the
+// lock
+const portLockPoll = 2 * time.Millisecond
+
+// ErrPortLocked is returned by Lock when another tool kept the lock for the
+// whole timeout
+var ErrPortLocked = errors.New("EC ports locked by another tool")
+
+// knownPortUsers are the processes known to drive the embedded controller
+// through /dev/port on their own
+var knownPortUsers = []string{"qcontrol", "hal_daemon", "hal_app"}
+
+// PortLock is a cooperative flock(2) lock on a lock file shared with other
+// tools. Like DevPort it is opened once, so it can still be taken after the
+// process has dropped root.
+type PortLock struct {
+	file *os.File
+}
+
+// OpenPortLock opens the lock file, creating it if needed
+func OpenPortLock() (*PortLock, error) {
+	return openPortLock(PortLockPath)
+}
+
+// openPortLock opens the given lock file (one in a temporary directory in
+// tests)
+func openPortLock(path string) (*PortLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &PortLock{file: file}, nil
+}
+
+// Lock takes the lock, waiting up to timeout while another tool holds it
+func (l *PortLock) Lock(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := unix.Flock(int(l.file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, unix.EWOULDBLOCK) && !errors.Is(err, unix.EINTR) {
+			return fmt.Errorf("failed to lock %s: %w", l.file.Name(), err)
+		}
+		if time.Now().After(deadline) {
+			return ErrPortLocked
+		}
+		time.Sleep(portLockPoll)
+	}
+}
+
+// Unlock releases the lock
+func (l *PortLock) Unlock() error {
+	if err := unix.Flock(int(l.file.Fd()), unix.LOCK_UN); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", l.file.Name(), err)
+	}
+	return nil
+}
+
+// Close releases the lock and closes the lock file
+func (l *PortLock) Close() error {
+	return l.file.Close()
+}
+
+// PortUsers returns the names of the running processes known to drive the
+// embedded controller themselves, such as qcontrol, sorted and without
+// duplicates. They may not take the lock, so their writes can still clash.
+func PortUsers() []string {
+	return portUsers("/proc")
+}
+
+// portUsers looks for knownPortUsers below procDir
+func portUsers(procDir string) []string {
+	comms, _ := filepath.Glob(filepath.Join(procDir, "[0-9]*", "comm"))
+	found := make(map[string]bool)
+	for _, comm := range comms {
+		name, err := os.ReadFile(comm)
+		if err != nil {
+			continue
+		}
+		for _, user := range knownPortUsers {
+			if strings.TrimSpace(string(name)) == user {
+				found[user] = true
+			}
+		}
+	}
+
+	var users []string
+	for user := range found {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
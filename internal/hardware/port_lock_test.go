@@ -0,0 +1,47 @@
+package hardware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortLock(t *testing.T) {
+	// Two opens of the lock file stand in for two tools
+	path := filepath.Join(t.TempDir(), "qnap-ec.lock")
+	ours, err := openPortLock(path)
+	require.NoError(t, err)
+	defer ours.Close()
+	theirs, err := openPortLock(path)
+	require.NoError(t, err)
+	defer theirs.Close()
+
+	require.NoError(t, theirs.Lock(0))
+	assert.ErrorIs(t, ours.Lock(10*time.Millisecond), ErrPortLocked)
+
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		released <- theirs.Unlock()
+	}()
+	require.NoError(t, ours.Lock(time.Second), "the lock is taken once released")
+	require.NoError(t, <-released)
+	require.NoError(t, ours.Unlock())
+	require.NoError(t, theirs.Lock(0))
+}
+
+func TestPortUsers(t *testing.T) {
+	proc := t.TempDir()
+	for pid, comm := range map[string]string{"1": "systemd", "412": "qcontrol", "413": "qcontrol", "980": "hal_daemon"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(proc, pid), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(proc, pid, "comm"), []byte(comm+"\n"), 0644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(proc, "self"), 0755))
+
+	assert.Equal(t, []string{"hal_daemon", "qcontrol"}, portUsers(proc))
+	assert.Empty(t, portUsers(t.TempDir()))
+}
@@ -0,0 +1,207 @@
+// Package hd44780 drives an HD44780 compatible character LCD wired to GPIO
+// lines in 4-bit mode, as on DIY NAS front panels and Raspberry Pi hats.
+//
+// Only RS, E and D4-D7 are connected; R/W is tied to ground, so the busy
+// flag cannot be read and every instruction is followed by its worst-case
+// execution time instead.
+package hd44780
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/hardware"
+)
+
+// Indexes of the lines passed to Open, in the order they are requested
+const (
+	LineRS = iota
+	LineE
+	LineD4
+	LineD5
+	LineD6
+	LineD7
+	// Lines is the number of lines the display needs
+	Lines
+)
+
+// Instructions
+const (
+	cmdClear       = 0x01
+	cmdEntryMode   = 0x06 // cursor moves right, display does not shift
+	cmdDisplay     = 0x08
+	displayOn      = 0x04
+	cmdFunctionSet = 0x28 // 4-bit interface, two line layout, 5x8 font
+	cmdSetCGRAM    = 0x40
+	cmdSetDDRAM    = 0x80
+)
+
+// Execution times: clear takes 1.52 ms, everything else 37 us, both at the
+// slowest clock the datasheet allows
+const (
+	commandDelay = 50 * time.Microsecond
+	clearDelay   = 2 * time.Millisecond
+)
+
+// allLines is the mask of every line of the display
+const allLines = 1<<Lines - 1
+
+// Device is an HD44780 display
+type Device struct {
+	lines      hardware.GPIOLines
+	cols, rows int
+	// sleep waits out execution times; replaced in tests
+	sleep func(time.Duration)
+}
+
+// Open initializes the display in 4-bit mode, clears it and switches it on.
+// cols and rows are its size in characters, up to 40x4.
+func Open(lines hardware.GPIOLines, cols, rows int) (*Device, error) {
+	return open(lines, cols, rows, time.Sleep)
+}
+
+func open(lines hardware.GPIOLines, cols, rows int, sleep func(time.Duration)) (*Device, error) {
+	if cols < 1 || cols > 40 || rows < 1 || rows > 4 {
+		return nil, fmt.Errorf("unsupported HD44780 size %dx%d", cols, rows)
+	}
+	d := &Device{lines: lines, cols: cols, rows: rows, sleep: sleep}
+
+	// The power-on reset may not have happened if the supply rose slowly,
+	// so the interface is reset by instruction: three times 8-bit mode,
+	// then 4-bit mode, each sent as a single nibble
+	d.sleep(50 * time.Millisecond)
+	for _, wait := range []time.Duration{4100 * time.Microsecond, 100 * time.Microsecond, commandDelay} {
+		if err := d.nibble(false, 0x3); err != nil {
+			return nil, fmt.Errorf("failed to initialize HD44780: %w", err)
+		}
+		d.sleep(wait)
+	}
+	if err := d.nibble(false, 0x2); err != nil {
+		return nil, fmt.Errorf("failed to initialize HD44780: %w", err)
+	}
+	d.sleep(commandDelay)
+
+	for _, command := range []byte{cmdFunctionSet, cmdDisplay, cmdClear, cmdEntryMode, cmdDisplay | displayOn} {
+		if err := d.command(command); err != nil {
+			return nil, fmt.Errorf("failed to initialize HD44780: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// Size returns the columns and rows of the display
+func (d *Device) Size() (int, int) {
+	return d.cols, d.rows
+}
+
+// WriteLine replaces a row with text, truncated and padded to the width.
+// Bytes 0 to 7 show the custom characters.
+func (d *Device) WriteLine(row int, text string) error {
+	if row < 0 || row >= d.rows {
+		return fmt.Errorf("invalid row: %d. Must be between 0 and %d", row, d.rows-1)
+	}
+	if len(text) > d.cols {
+		text = text[:d.cols]
+	}
+	text += strings.Repeat(" ", d.cols-len(text))
+
+	if err := d.command(cmdSetDDRAM | d.address(row)); err != nil {
+		return err
+	}
+	for i := 0; i < len(text); i++ {
+		if err := d.data(text[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// address returns the display memory address of a row's first column. Rows
+// 2 and 3 continue rows 0 and 1 in memory.
+func (d *Device) address(row int) byte {
+	base := byte(0)
+	if row%2 == 1 {
+		base = 0x40
+	}
+	if row >= 2 {
+		base += byte(d.cols)
+	}
+	return base
+}
+
+// DefineGlyph defines custom character slot (0 to 7) from eight pixel rows,
+// the lowest five bits of each
+func (d *Device) DefineGlyph(slot int, rows [8]byte) error {
+	if slot < 0 || slot > 7 {
+		return fmt.Errorf("invalid glyph slot %d", slot)
+	}
+	if err := d.command(cmdSetCGRAM | byte(slot)<<3); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := d.data(row & 0x1F); err != nil {
+			return err
+		}
+	}
+	// Leave the address counter in display memory
+	return d.command(cmdSetDDRAM)
+}
+
+// SetDisplay switches the display on or off; its contents are kept
+func (d *Device) SetDisplay(on bool) error {
+	if on {
+		return d.command(cmdDisplay | displayOn)
+	}
+	return d.command(cmdDisplay)
+}
+
+// command sends an instruction and waits for it to execute
+func (d *Device) command(value byte) error {
+	if err := d.write(false, value); err != nil {
+		return err
+	}
+	if value == cmdClear {
+		d.sleep(clearDelay)
+	} else {
+		d.sleep(commandDelay)
+	}
+	return nil
+}
+
+// data writes a byte to display or character memory
+func (d *Device) data(value byte) error {
+	if err := d.write(true, value); err != nil {
+		return err
+	}
+	d.sleep(commandDelay)
+	return nil
+}
+
+// write sends a byte as two nibbles, the high one first
+func (d *Device) write(rs bool, value byte) error {
+	if err := d.nibble(rs, value>>4); err != nil {
+		return err
+	}
+	return d.nibble(rs, value&0x0F)
+}
+
+// nibble puts four bits on D4-D7 and clocks them in with a pulse on E; the
+// display reads them on the falling edge
+func (d *Device) nibble(rs bool, value byte) error {
+	var bits uint64
+	if rs {
+		bits |= 1 << LineRS
+	}
+	for i := 0; i < 4; i++ {
+		if value&(1<<i) != 0 {
+			bits |= 1 << (LineD4 + i)
+		}
+	}
+	for _, e := range []uint64{0, 1 << LineE, 0} {
+		if err := d.lines.SetValues(bits|e, allLines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package hd44780
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// write is a byte or a lone nibble the fake display clocked in
+type write struct {
+	rs    bool
+	value byte
+}
+
+// fakeLines decodes what the lines clock into a display: nibbles are read on
+// the falling edge of E and paired once the display is in 4-bit mode
+type fakeLines struct {
+	last   uint64
+	writes []write
+	// fourBit is set by the nibble switching to 4-bit mode
+	fourBit bool
+	high    *byte
+	fail    error
+}
+
+func (f *fakeLines) SetValues(bits, mask uint64) error {
+	if f.fail != nil {
+		return f.fail
+	}
+	falling := f.last&(1<<LineE) != 0 && bits&(1<<LineE) == 0
+	f.last = bits
+	if !falling {
+		return nil
+	}
+
+	nibble := byte(bits>>LineD4) & 0x0F
+	rs := bits&(1<<LineRS) != 0
+	switch {
+	case !f.fourBit:
+		f.writes = append(f.writes, write{rs, nibble})
+		f.fourBit = nibble == 0x2
+	case f.high == nil:
+		f.high = &nibble
+	default:
+		f.writes = append(f.writes, write{rs, *f.high<<4 | nibble})
+		f.high = nil
+	}
+	return nil
+}
+
+func (f *fakeLines) Values() (uint64, error) { return f.last, nil }
+
+func (f *fakeLines) Close() error { return nil }
+
+// text returns the data bytes written
+func (f *fakeLines) text() string {
+	var text []byte
+	for _, w := range f.writes {
+		if w.rs {
+			text = append(text, w.value)
+		}
+	}
+	return string(text)
+}
+
+func openTest(t *testing.T, lines *fakeLines, cols, rows int) (*Device, *time.Duration) {
+	t.Helper()
+	var slept time.Duration
+	d, err := open(lines, cols, rows, func(wait time.Duration) { slept += wait })
+	require.NoError(t, err)
+	return d, &slept
+}
+
+func TestOpen(t *testing.T) {
+	lines := &fakeLines{}
+	d, slept := openTest(t, lines, 16, 2)
+
+	assert.Equal(t, []write{
+		{false, 0x3}, {false, 0x3}, {false, 0x3}, {false, 0x2},
+		{false, cmdFunctionSet}, {false, cmdDisplay}, {false, cmdClear}, {false, cmdEntryMode},
+		{false, cmdDisplay | displayOn},
+	}, lines.writes)
+	assert.GreaterOrEqual(t, *slept, 50*time.Millisecond+4100*time.Microsecond+clearDelay)
+	cols, rows := d.Size()
+	assert.Equal(t, 16, cols)
+	assert.Equal(t, 2, rows)
+
+	_, err := open(&fakeLines{}, 16, 5, func(time.Duration) {})
+	assert.Error(t, err)
+	_, err = open(&fakeLines{fail: errors.New("line busy")}, 16, 2, func(time.Duration) {})
+	assert.Error(t, err)
+}
+
+func TestDevice_WriteLine(t *testing.T) {
+	lines := &fakeLines{}
+	d, _ := openTest(t, lines, 20, 4)
+
+	tests := []struct {
+		row     int
+		address byte
+	}{{0, 0x00}, {1, 0x40}, {2, 0x14}, {3, 0x54}}
+	for _, tt := range tests {
+		lines.writes = nil
+		require.NoError(t, d.WriteLine(tt.row, "Hello"))
+		assert.Equal(t, write{false, cmdSetDDRAM | tt.address}, lines.writes[0])
+		assert.Equal(t, "Hello               ", lines.text())
+	}
+
+	lines.writes = nil
+	require.NoError(t, d.WriteLine(0, "A line longer than twenty"))
+	assert.Equal(t, "A line longer than t", lines.text())
+	assert.Error(t, d.WriteLine(4, "x"))
+}
+
+func TestDevice_DefineGlyph(t *testing.T) {
+	lines := &fakeLines{}
+	d, _ := openTest(t, lines, 16, 2)
+	lines.writes = nil
+
+	require.NoError(t, d.DefineGlyph(3, [8]byte{0xFF, 0x11, 0, 0, 0, 0, 0, 0x1F}))
+	assert.Equal(t, write{false, cmdSetCGRAM | 3<<3}, lines.writes[0])
+	assert.Equal(t, "\x1F\x11\x00\x00\x00\x00\x00\x1F", lines.text())
+	assert.Equal(t, write{false, cmdSetDDRAM}, lines.writes[len(lines.writes)-1])
+	assert.Error(t, d.DefineGlyph(8, [8]byte{}))
+
+	lines.writes = nil
+	require.NoError(t, d.SetDisplay(false))
+	require.NoError(t, d.SetDisplay(true))
+	assert.Equal(t, []write{{false, cmdDisplay}, {false, cmdDisplay | displayOn}}, lines.writes)
+}
@@ -0,0 +1,193 @@
+// Package homeassistant describes the panel to Home Assistant through MQTT
+// discovery, so it appears there without any YAML: the display text as a
+// text entity, the backlight as a switch, the LEDs as lights and the
+// buttons as device triggers.
+//
+// The entities share one availability topic, which the service sets to
+// "online" once connected and the broker to "offline" through the will.
+package homeassistant
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/qnap/display-control/internal/mqtt"
+)
+
+// DefaultDiscoveryPrefix is the topic Home Assistant looks for discovery
+// payloads below unless configured otherwise
+const DefaultDiscoveryPrefix = "homeassistant"
+
+// MaxText is the longest text Home Assistant lets a text entity hold
+const MaxText = 255
+
+// Payloads of the state and command topics
+const (
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+	PayloadOn      = "ON"
+	PayloadOff     = "OFF"
+)
+
+// Payloads of the button topics, one per device trigger
+const (
+	TriggerShortPress = "short_press"
+	TriggerLongPress  = "long_press"
+)
+
+// Device is the panel as Home Assistant shows it
+type Device struct {
+	// ID identifies the panel in unique IDs and discovery topics, e.g. the
+	// hostname
+	ID        string
+	Name      string
+	Model     string
+	SWVersion string
+}
+
+// Topics are the panel's state and command topics below Base
+type Topics struct {
+	Base string
+}
+
+// Availability is "online" while the service is connected
+func (t Topics) Availability() string { return t.Base + "/status" }
+
+// DisplayState is the text the panel shows
+func (t Topics) DisplayState() string { return t.Base + "/display" }
+
+// DisplayCommand takes a text to show on the panel
+func (t Topics) DisplayCommand() string { return t.Base + "/display/set" }
+
+// BacklightState is "ON" or "OFF"
+func (t Topics) BacklightState() string { return t.Base + "/backlight" }
+
+// BacklightCommand switches the backlight
+func (t Topics) BacklightCommand() string { return t.Base + "/backlight/set" }
+
+// LEDState is "ON" or "OFF" for the LED named led
+func (t Topics) LEDState(led string) string { return t.Base + "/led/" + led }
+
+// LEDCommand switches the LED named led
+func (t Topics) LEDCommand(led string) string { return t.Base + "/led/" + led + "/set" }
+
+// Button gets a trigger payload when the button named button is pressed
+func (t Topics) Button(button string) string { return t.Base + "/button/" + button }
+
+// device is the device block of every discovery payload
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model,omitempty"`
+	SWVersion    string   `json:"sw_version,omitempty"`
+}
+
+// entity is a discovery payload; each component uses some of the fields
+type entity struct {
+	Name              string  `json:"name,omitempty"`
+	UniqueID          string  `json:"unique_id,omitempty"`
+	Icon              string  `json:"icon,omitempty"`
+	AvailabilityTopic string  `json:"availability_topic,omitempty"`
+	StateTopic        string  `json:"state_topic,omitempty"`
+	CommandTopic      string  `json:"command_topic,omitempty"`
+	Max               int     `json:"max,omitempty"`
+	Mode              string  `json:"mode,omitempty"`
+	AutomationType    string  `json:"automation_type,omitempty"`
+	Type              string  `json:"type,omitempty"`
+	Subtype           string  `json:"subtype,omitempty"`
+	Topic             string  `json:"topic,omitempty"`
+	Payload           string  `json:"payload,omitempty"`
+	Device            *device `json:"device"`
+}
+
+// triggers are the device triggers of every button and the payload each is
+// fired by
+var triggers = []struct {
+	kind    string
+	payload string
+}{
+	{"button_short_press", TriggerShortPress},
+	{"button_long_press", TriggerLongPress},
+}
+
+// Discovery returns the retained discovery messages below prefix for the
+// panel's entities. leds and buttons are the names of the LEDs, if the
+// panel has any, and of the buttons.
+func Discovery(prefix string, d Device, topics Topics, leds, buttons []string) []mqtt.Message {
+	if prefix == "" {
+		prefix = DefaultDiscoveryPrefix
+	}
+	id := NodeID(d.ID)
+	dev := &device{
+		Identifiers:  []string{"qnap_display_" + id},
+		Name:         d.Name,
+		Manufacturer: "QNAP",
+		Model:        d.Model,
+		SWVersion:    d.SWVersion,
+	}
+
+	var messages []mqtt.Message
+	add := func(component, object string, e entity) {
+		e.Device = dev
+		if e.AutomationType == "" {
+			e.UniqueID = "qnap_display_" + id + "_" + object
+			e.AvailabilityTopic = topics.Availability()
+		}
+		payload, _ := json.Marshal(e)
+		messages = append(messages, mqtt.Message{
+			Topic:   prefix + "/" + component + "/" + id + "/" + object + "/config",
+			Payload: payload,
+			Retain:  true,
+		})
+	}
+
+	add("text", "display", entity{
+		Name:         "Display",
+		Icon:         "mdi:card-text-outline",
+		StateTopic:   topics.DisplayState(),
+		CommandTopic: topics.DisplayCommand(),
+		Max:          MaxText,
+		Mode:         "text",
+	})
+	add("switch", "backlight", entity{
+		Name:         "Backlight",
+		Icon:         "mdi:brightness-6",
+		StateTopic:   topics.BacklightState(),
+		CommandTopic: topics.BacklightCommand(),
+	})
+	for _, led := range leds {
+		add("light", "led_"+NodeID(led), entity{
+			Name:         "LED " + led,
+			StateTopic:   topics.LEDState(led),
+			CommandTopic: topics.LEDCommand(led),
+		})
+	}
+	for _, button := range buttons {
+		for _, trigger := range triggers {
+			add("device_automation", NodeID(button)+"_"+trigger.payload, entity{
+				AutomationType: "trigger",
+				Type:           trigger.kind,
+				Subtype:        button,
+				Topic:          topics.Button(button),
+				Payload:        trigger.payload,
+			})
+		}
+	}
+	return messages
+}
+
+// NodeID turns a name into one usable in discovery topics and unique IDs,
+// e.g. "status-green" into "status_green"
+func NodeID(name string) string {
+	var id strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			id.WriteRune(r)
+		default:
+			id.WriteRune('_')
+		}
+	}
+	return id.String()
+}
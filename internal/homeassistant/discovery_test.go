@@ -0,0 +1,59 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscovery(t *testing.T) {
+	topics := Topics{Base: "qnap-display/nas"}
+	device := Device{ID: "nas.local", Name: "NAS panel", Model: "TS-453", SWVersion: "1.2.0"}
+	messages := Discovery("", device, topics, []string{"status-green", "usb"}, []string{"enter"})
+
+	configs := make(map[string]map[string]any)
+	for _, msg := range messages {
+		assert.True(t, msg.Retain, msg.Topic)
+		var config map[string]any
+		require.NoError(t, json.Unmarshal(msg.Payload, &config), msg.Topic)
+		configs[msg.Topic] = config
+	}
+	assert.Len(t, configs, 6, "text, switch, two lights, two triggers")
+
+	display := configs["homeassistant/text/nas_local/display/config"]
+	require.NotNil(t, display)
+	assert.Equal(t, "qnap_display_nas_local_display", display["unique_id"])
+	assert.Equal(t, "qnap-display/nas/display", display["state_topic"])
+	assert.Equal(t, "qnap-display/nas/display/set", display["command_topic"])
+	assert.Equal(t, "qnap-display/nas/status", display["availability_topic"])
+	assert.Equal(t, float64(MaxText), display["max"])
+	dev := display["device"].(map[string]any)
+	assert.Equal(t, []any{"qnap_display_nas_local"}, dev["identifiers"])
+	assert.Equal(t, "NAS panel", dev["name"])
+	assert.Equal(t, "1.2.0", dev["sw_version"])
+
+	backlight := configs["homeassistant/switch/nas_local/backlight/config"]
+	require.NotNil(t, backlight)
+	assert.Equal(t, "qnap-display/nas/backlight/set", backlight["command_topic"])
+
+	led := configs["homeassistant/light/nas_local/led_status_green/config"]
+	require.NotNil(t, led)
+	assert.Equal(t, "qnap-display/nas/led/status-green", led["state_topic"])
+	assert.Equal(t, "qnap-display/nas/led/status-green/set", led["command_topic"])
+
+	trigger := configs["homeassistant/device_automation/nas_local/enter_long_press/config"]
+	require.NotNil(t, trigger)
+	assert.Equal(t, "trigger", trigger["automation_type"])
+	assert.Equal(t, "button_long_press", trigger["type"])
+	assert.Equal(t, "enter", trigger["subtype"])
+	assert.Equal(t, "qnap-display/nas/button/enter", trigger["topic"])
+	assert.Equal(t, TriggerLongPress, trigger["payload"])
+	assert.NotContains(t, trigger, "unique_id", "device triggers have none")
+}
+
+func TestNodeID(t *testing.T) {
+	assert.Equal(t, "status_green", NodeID("status-green"))
+	assert.Equal(t, "nas_01", NodeID("NAS 01"))
+}
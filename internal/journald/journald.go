@@ -0,0 +1,228 @@
+// Package journald sends log entries to the systemd journal over its native
+// protocol, so their fields, such as the component or a serial hex dump,
+// are kept as journal fields instead of text to parse, e.g. for
+// "journalctl COMPONENT=display_controller".
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// SocketPath is where journald takes native protocol entries
+const SocketPath = "/run/systemd/journal/socket"
+
+// Priority is a syslog priority
+type Priority int
+
+// The priorities log levels are sent with
+const (
+	PriCrit    Priority = 2
+	PriErr     Priority = 3
+	PriWarning Priority = 4
+	PriInfo    Priority = 6
+	PriDebug   Priority = 7
+)
+
+// Available reports whether journald takes entries at SocketPath, i.e. the
+// system runs systemd
+func Available() bool {
+	info, err := os.Stat(SocketPath)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// Journal sends entries to journald
+type Journal struct {
+	socket     string
+	identifier string
+
+	mutex sync.Mutex
+	conn  *net.UnixConn
+}
+
+// New returns a journal sending to SocketPath, with identifier as
+// SYSLOG_IDENTIFIER
+func New(identifier string) *Journal {
+	return &Journal{socket: SocketPath, identifier: identifier}
+}
+
+// Send writes one entry. Field names are turned into journal field names,
+// e.g. "buffer_hex" into "BUFFER_HEX".
+func (j *Journal) Send(message string, priority Priority, fields map[string]string) error {
+	entry := j.encode(message, priority, fields)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: j.socket, Net: "unixgram"})
+		if err != nil {
+			return fmt.Errorf("failed to reach journald: %w", err)
+		}
+		j.conn = conn
+	}
+	_, err := j.conn.Write(entry)
+	if errors.Is(err, unix.EMSGSIZE) || errors.Is(err, unix.ENOBUFS) {
+		// Entries above the datagram size are passed in a memfd
+		return j.sendFile(entry)
+	}
+	return err
+}
+
+// Close closes the connection to journald
+func (j *Journal) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+// sendFile passes a large entry in a sealed memfd. Caller must hold the
+// mutex.
+func (j *Journal) sendFile(entry []byte) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("failed to create memfd: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), "journal-entry")
+	defer file.Close()
+
+	if _, err := file.Write(entry); err != nil {
+		return err
+	}
+	// journald only takes sealed memfds
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return fmt.Errorf("failed to seal memfd: %w", err)
+	}
+	_, _, err = j.conn.WriteMsgUnix(nil, unix.UnixRights(int(file.Fd())), nil)
+	return err
+}
+
+// encode builds the native protocol entry: KEY=value lines, and values with
+// newlines as KEY, a newline, their length as 64 bit little endian and the
+// value
+func (j *Journal) encode(message string, priority Priority, fields map[string]string) []byte {
+	var entry bytes.Buffer
+	writeField(&entry, "MESSAGE", message)
+	writeField(&entry, "PRIORITY", fmt.Sprint(int(priority)))
+	if j.identifier != "" {
+		writeField(&entry, "SYSLOG_IDENTIFIER", j.identifier)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeField(&entry, FieldName(name), fields[name])
+	}
+	return entry.Bytes()
+}
+
+// writeField appends one field to an entry
+func writeField(entry *bytes.Buffer, name, value string) {
+	entry.WriteString(name)
+	if strings.ContainsRune(value, '\n') {
+		entry.WriteByte('\n')
+		binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	} else {
+		entry.WriteByte('=')
+	}
+	entry.WriteString(value)
+	entry.WriteByte('\n')
+}
+
+// FieldName turns a log field name into a journal field name: upper case
+// letters, digits and underscores, not starting with an underscore or
+// digit, which journald reserves or refuses, and at most 64 characters
+func FieldName(name string) string {
+	var field strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			field.WriteRune(r)
+		default:
+			field.WriteByte('_')
+		}
+	}
+	result := strings.TrimLeft(field.String(), "_")
+	if result == "" || result[0] >= '0' && result[0] <= '9' {
+		result = "F_" + result
+	}
+	if len(result) > 64 {
+		result = result[:64]
+	}
+	return result
+}
+
+// levelPriorities map the log levels to syslog priorities
+var levelPriorities = map[logrus.Level]Priority{
+	logrus.PanicLevel: PriCrit,
+	logrus.FatalLevel: PriCrit,
+	logrus.ErrorLevel: PriErr,
+	logrus.WarnLevel:  PriWarning,
+	logrus.InfoLevel:  PriInfo,
+	logrus.DebugLevel: PriDebug,
+	logrus.TraceLevel: PriDebug,
+}
+
+// Hook sends every log entry to the journal, with its fields. Entries the
+// journal does not take are written as text to Fallback instead, so they
+// are not lost when journald restarts.
+type Hook struct {
+	journal  *Journal
+	fallback io.Writer
+	text     logrus.Formatter
+}
+
+// NewHook returns a hook sending to journal, falling back to fallback
+func NewHook(journal *Journal, fallback io.Writer) *Hook {
+	return &Hook{journal: journal, fallback: fallback, text: &logrus.TextFormatter{FullTimestamp: true}}
+}
+
+// Levels returns every level
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire sends the entry
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	fields := make(map[string]string, len(entry.Data))
+	for name, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			fields[name] = err.Error()
+			continue
+		}
+		fields[name] = fmt.Sprint(value)
+	}
+	priority, ok := levelPriorities[entry.Level]
+	if !ok {
+		priority = PriInfo
+	}
+	if err := h.journal.Send(entry.Message, priority, fields); err != nil {
+		text, formatErr := h.text.Format(entry)
+		if formatErr != nil {
+			return formatErr
+		}
+		_, writeErr := h.fallback.Write(text)
+		return writeErr
+	}
+	return nil
+}
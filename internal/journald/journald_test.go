@@ -0,0 +1,95 @@
+package journald
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenJournal serves a fake journald socket and returns a journal sending
+// to it
+func listenJournal(t *testing.T) (*Journal, *net.UnixConn) {
+	t.Helper()
+
+	// Socket paths are limited to about 100 bytes, too short for TempDir
+	dir, err := os.MkdirTemp("", "journal")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "socket")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	journal := &Journal{socket: socket, identifier: "qnap-display"}
+	t.Cleanup(func() { journal.Close() })
+	return journal, conn
+}
+
+// receive reads the next entry
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	buffer := make([]byte, 64*1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buffer)
+	require.NoError(t, err)
+	return string(buffer[:n])
+}
+
+func TestJournal_Send(t *testing.T) {
+	journal, conn := listenJournal(t)
+
+	require.NoError(t, journal.Send("Button event triggered", PriInfo, map[string]string{
+		"component": "display_controller",
+		"button":    "ENTER",
+	}))
+	assert.Equal(t, "MESSAGE=Button event triggered\nPRIORITY=6\nSYSLOG_IDENTIFIER=qnap-display\n"+
+		"BUTTON=ENTER\nCOMPONENT=display_controller\n", receive(t, conn))
+
+	// Values with newlines are sent with their length
+	require.NoError(t, journal.Send("two\nlines", PriWarning, nil))
+	assert.Equal(t, "MESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\nPRIORITY=4\nSYSLOG_IDENTIFIER=qnap-display\n", receive(t, conn))
+}
+
+func TestJournal_Unreachable(t *testing.T) {
+	journal := &Journal{socket: filepath.Join(t.TempDir(), "missing")}
+	assert.Error(t, journal.Send("lost", PriInfo, nil))
+}
+
+func TestFieldName(t *testing.T) {
+	assert.Equal(t, "BUFFER_HEX", FieldName("buffer_hex"))
+	assert.Equal(t, "HAS_HANDLER", FieldName("has-handler"))
+	assert.Equal(t, "PRIVATE", FieldName("_private"), "underscores are reserved for journald")
+	assert.Equal(t, "F_1ST", FieldName("1st"))
+	assert.Len(t, FieldName(string(bytes.Repeat([]byte("a"), 100))), 64)
+}
+
+func TestHook(t *testing.T) {
+	journal, conn := listenJournal(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	var fallback bytes.Buffer
+	logger.AddHook(NewHook(journal, &fallback))
+
+	logger.WithFields(logrus.Fields{"component": "serial", "hex": "53 05 00 fa"}).WithError(errors.New("timeout")).Debug("Received serial data")
+	assert.Equal(t, "MESSAGE=Received serial data\nPRIORITY=7\nSYSLOG_IDENTIFIER=qnap-display\n"+
+		"COMPONENT=serial\nERROR=timeout\nHEX=53 05 00 fa\n", receive(t, conn))
+	assert.Empty(t, fallback.String())
+
+	// Entries journald does not take are written as text
+	conn.Close()
+	journal.Close()
+	logger.Warn("Journal gone")
+	assert.Contains(t, fallback.String(), `msg="Journal gone"`)
+}
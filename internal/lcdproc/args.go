@@ -0,0 +1,51 @@
+package lcdproc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitArgs splits a command line into its arguments the way LCDd does.
+// Arguments are separated by spaces and tabs; "double quotes" or {braces}
+// group words into one argument, and inside them a backslash makes the next
+// character literal.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var closing rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case closing != 0 && r == '\\' && i+1 < len(runes):
+			i++
+			arg.WriteRune(runes[i])
+		case closing != 0 && r == closing:
+			closing = 0
+		case closing != 0:
+			arg.WriteRune(r)
+		case r == '"':
+			closing, inArg = '"', true
+		case r == '{':
+			closing, inArg = '}', true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if closing != 0 {
+		return nil, fmt.Errorf("unterminated %q", string(closing))
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
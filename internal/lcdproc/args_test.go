@@ -0,0 +1,31 @@
+package lcdproc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"hello", []string{"hello"}},
+		{"  widget_set  s1\tw1 1 2 text ", []string{"widget_set", "s1", "w1", "1", "2", "text"}},
+		{`widget_set s1 w1 1 2 "Load: 0.42"`, []string{"widget_set", "s1", "w1", "1", "2", "Load: 0.42"}},
+		{`widget_set s1 t {CPU {load}}`, []string{"widget_set", "s1", "t", "CPU {load}"}},
+		{`widget_set s1 t "say \"hi\""`, []string{"widget_set", "s1", "t", `say "hi"`}},
+		{`widget_set s1 t ""`, []string{"widget_set", "s1", "t", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		args, err := splitArgs(tt.line)
+		require.NoError(t, err, tt.line)
+		assert.Equal(t, tt.expected, args, tt.line)
+	}
+
+	_, err := splitArgs(`widget_set s1 t "open`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,328 @@
+package lcdproc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+)
+
+// clientScreen is the id of the screen the client adds on the remote LCDd
+const clientScreen = "qnap"
+
+// glyphStandIn is drawn for custom glyphs, which LCDd has no use for
+const glyphStandIn = '*'
+
+const (
+	// dialTimeout bounds connecting and the greeting of the remote LCDd
+	dialTimeout = 5 * time.Second
+	// reconnectDelay is the pause before connecting again after the remote
+	// LCDd went away or could not be reached
+	reconnectDelay = 10 * time.Second
+)
+
+// KeyHandler is told a panel button for every key the remote LCDd sends
+type KeyHandler func(button controller.PanelButton)
+
+// Client draws the panel's frames on a remote LCDd instead, as one screen of
+// string widgets, and turns the keys pressed there into panel buttons. It is
+// a display for the screen manager. While the remote LCDd cannot be reached
+// frames are kept and sent once it is back.
+type Client struct {
+	address string
+	width   int
+	height  int
+	logger  *logrus.Entry
+	stop    chan struct{}
+	wg      sync.WaitGroup
+
+	mutex sync.Mutex
+	// conn is the connection to the remote LCDd, nil while there is none
+	conn net.Conn
+	// rows is how many lines the remote LCDd shows
+	rows      int
+	lines     []string
+	backlight bool
+	onKey     KeyHandler
+	closed    bool
+}
+
+// NewClient creates a client drawing a width x height panel on the LCDd at
+// address. It connects once Start is called.
+func NewClient(address string, width, height int) *Client {
+	return &Client{
+		address:   address,
+		width:     width,
+		height:    height,
+		logger:    logrus.WithFields(logrus.Fields{"component": "lcdproc_client", "server": address}),
+		stop:      make(chan struct{}),
+		lines:     make([]string, height),
+		backlight: true,
+	}
+}
+
+// SetKeyHandler sets the handler told the keys pressed on the remote LCDd
+func (c *Client) SetKeyHandler(handler KeyHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onKey = handler
+}
+
+// Start connects to the remote LCDd in the background, and again whenever
+// the connection is lost, until Close is called
+func (c *Client) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			if err := c.session(); err != nil {
+				c.logger.WithError(err).Warn("lcdproc server not reachable")
+			}
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+}
+
+// Close says goodbye to the remote LCDd and stops connecting to it
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	if c.conn != nil {
+		c.sendLocked("bye")
+		c.conn.Close()
+	}
+	c.mutex.Unlock()
+
+	close(c.stop)
+	c.wg.Wait()
+	return nil
+}
+
+// WriteTextAt replaces part of a line, like the panel does
+func (c *Client) WriteTextAt(text string, row, col int) error {
+	if row < 0 || row >= c.height || col < 0 || col >= c.width {
+		return fmt.Errorf("position %d,%d is off the display", row, col)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cells := []rune(fmt.Sprintf("%-*s", c.width, c.lines[row]))
+	for i, r := range []rune(text) {
+		if col+i >= c.width {
+			break
+		}
+		cells[col+i] = r
+	}
+	c.lines[row] = string(cells)
+	c.sendLine(row)
+	return nil
+}
+
+// WriteLines replaces several lines at once
+func (c *Client) WriteLines(lines map[int]string) error {
+	for row := range lines {
+		if row < 0 || row >= c.height {
+			return fmt.Errorf("row %d is off the display", row)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for row, line := range lines {
+		c.lines[row] = line
+		c.sendLine(row)
+	}
+	return nil
+}
+
+// SetBacklight switches the backlight of the remote display
+func (c *Client) SetBacklight(on bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.backlight = on
+	c.sendBacklight()
+	return nil
+}
+
+// session connects to the remote LCDd, sets up the screen and passes on its
+// keys until the connection is lost or the client closed
+func (c *Client) session() error {
+	conn, err := net.DialTimeout("tcp", c.address, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	rows, err := greet(conn, reader)
+	if err != nil {
+		return err
+	}
+	if rows < c.height {
+		c.logger.WithField("rows", rows).Warn("lcdproc server shows fewer lines than the panel")
+	}
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.conn, c.rows = conn, min(rows, c.height)
+	c.setup()
+	c.mutex.Unlock()
+	c.logger.Info("Drawing on lcdproc server")
+
+	defer func() {
+		c.mutex.Lock()
+		c.conn = nil
+		c.mutex.Unlock()
+	}()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || c.isClosed() {
+				return nil
+			}
+			return fmt.Errorf("lcdproc server went away: %w", err)
+		}
+		c.handle(strings.TrimSpace(line))
+	}
+}
+
+// greet says hello and returns how many lines the remote LCDd shows
+func greet(conn net.Conn, reader *bufio.Reader) (int, error) {
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		return 0, err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("no greeting from lcdproc server: %w", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "connect" {
+		return 0, fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	}
+	for i := 1; i+1 < len(fields); i++ {
+		if fields[i] == "hgt" {
+			var rows int
+			if _, err := fmt.Sscan(fields[i+1], &rows); err == nil && rows > 0 {
+				return rows, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no display size in greeting %q", strings.TrimSpace(line))
+}
+
+// setup adds the screen, one widget per line, reserves the keys and sends
+// what the panel shows. Caller must hold the mutex.
+func (c *Client) setup() {
+	c.sendLocked("client_set -name qnap-display")
+	c.sendLocked("screen_add " + clientScreen)
+	c.sendLocked(fmt.Sprintf("screen_set %s -name {QNAP panel} -priority foreground -heartbeat off", clientScreen))
+	for row := 0; row < c.rows; row++ {
+		c.sendLocked(fmt.Sprintf("widget_add %s line%d string", clientScreen, row+1))
+		c.sendLine(row)
+	}
+	for _, key := range keyNames {
+		c.sendLocked("client_add_key -exclusively " + key)
+	}
+	c.sendBacklight()
+}
+
+// sendLine sends one line to its widget. Caller must hold the mutex.
+func (c *Client) sendLine(row int) {
+	if row >= c.rows {
+		return
+	}
+	text := strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return glyphStandIn
+		}
+		return r
+	}, c.lines[row])
+	c.sendLocked(fmt.Sprintf("widget_set %s line%d 1 %d %s", clientScreen, row+1, row+1, quote(text)))
+}
+
+// sendBacklight sends the backlight state. Caller must hold the mutex.
+func (c *Client) sendBacklight() {
+	state := "off"
+	if c.backlight {
+		state = "on"
+	}
+	c.sendLocked("backlight " + state)
+}
+
+// sendLocked sends a command if connected. Answers are not waited for; a
+// refused command is logged when its answer arrives. Caller must hold the
+// mutex.
+func (c *Client) sendLocked(command string) {
+	if c.conn == nil {
+		return
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write([]byte(command + "\n")); err != nil {
+		c.logger.WithError(err).Warn("Failed to send to lcdproc server")
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// handle takes one line from the remote LCDd
+func (c *Client) handle(line string) {
+	switch {
+	case strings.HasPrefix(line, "key "):
+		button, ok := keyButton(strings.TrimPrefix(line, "key "))
+		c.mutex.Lock()
+		onKey := c.onKey
+		c.mutex.Unlock()
+		if ok && onKey != nil {
+			onKey(button)
+		}
+	case strings.HasPrefix(line, "huh?"):
+		c.logger.WithField("answer", line).Warn("lcdproc server refused a command")
+	}
+}
+
+// isClosed reports whether Close was called
+func (c *Client) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+// keyButton returns the panel button of an LCDd key
+func keyButton(key string) (controller.PanelButton, bool) {
+	for button, name := range keyNames {
+		if name == key {
+			return button, true
+		}
+	}
+	return 0, false
+}
+
+// quote makes text one argument, the way splitArgs reads it
+func quote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
@@ -0,0 +1,63 @@
+package lcdproc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	s, layer := newTestServer(t)
+
+	client := NewClient(s.Addr().String(), 16, 2)
+	pressed := make(chan controller.PanelButton, 1)
+	client.SetKeyHandler(func(button controller.PanelButton) { pressed <- button })
+
+	// Frames written before the connection are sent once it is up
+	require.NoError(t, client.WriteLines(map[int]string{0: "Main Menu       ", 1: "\x01Say \"hi\"\\      "}))
+	client.Start()
+	defer client.Close()
+	require.Eventually(t, func() bool {
+		return layer.shown() == "Main Menu       \n*Say \"hi\"\\      "
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.WriteTextAt("System", 0, 5))
+	require.Eventually(t, func() bool {
+		return layer.shown() == "Main System     \n*Say \"hi\"\\      "
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Error(t, client.WriteTextAt("x", 2, 0))
+
+	// Keys pressed on the remote display are panel buttons
+	assert.True(t, s.HandleButton(controller.ButtonSelect, true))
+	select {
+	case button := <-pressed:
+		assert.Equal(t, controller.ButtonSelect, button)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no key from the lcdproc server")
+	}
+
+	// The screen goes away with the client
+	require.NoError(t, client.Close())
+	require.Eventually(t, func() bool {
+		layer.mutex.Lock()
+		defer layer.mutex.Unlock()
+		return layer.released
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	client := NewClient(address, 16, 2)
+	client.Start()
+	require.NoError(t, client.WriteLines(map[int]string{0: "Main Menu"}), "frames are kept while unreachable")
+	require.NoError(t, client.SetBacklight(false))
+	require.NoError(t, client.Close())
+}
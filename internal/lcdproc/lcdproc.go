@@ -0,0 +1,650 @@
+// Package lcdproc speaks the network protocol of LCDd, the LCDproc server,
+// so existing lcdproc clients (lcdproc, mpdlcd, lcdexec, ...) can drive the
+// panel while the display service keeps the serial port.
+//
+// Clients add screens made of widgets. The screens of the highest priority
+// class present take turns on the panel for their duration, counted in
+// frames of an eighth of a second as in LCDd. They are drawn on their own
+// display layer above the menu; a button press no client reserved hands the
+// panel back to the menu for a while.
+//
+// The other way round, Client draws what the panel would show on a remote
+// LCDd and passes on its keys, for when the panel itself is broken.
+package lcdproc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+)
+
+// The LCDd release and protocol version announced to clients
+const (
+	serverVersion   = "0.5.9"
+	protocolVersion = "0.4"
+)
+
+// frameInterval is the length of an LCDd frame, the unit of durations,
+// timeouts and scroller speeds
+const frameInterval = time.Second / 8
+
+// renderInterval is how often the shown screen is drawn. Slower than the
+// frame rate, so scrollers do not flood the serial link.
+const renderInterval = 250 * time.Millisecond
+
+// maxLineSize bounds a command line
+const maxLineSize = 8 * 1024
+
+// clientQueue is how many lines a slow client may fall behind before
+// further lines are dropped for it
+const clientQueue = 64
+
+// writeTimeout is how long sending a line to a client may take
+const writeTimeout = 5 * time.Second
+
+// Display is the layer the screens are drawn on. screen.Layer satisfies it.
+type Display interface {
+	WriteText(text string) error
+	Release() error
+}
+
+// keyNames are the panel buttons as LCDd's keys. The copy button is kept
+// for copies.
+var keyNames = map[controller.PanelButton]string{
+	controller.ButtonEnter:  "Enter",
+	controller.ButtonSelect: "Down",
+}
+
+// Server answers lcdproc clients and draws their screens
+type Server struct {
+	listener net.Listener
+	display  Display
+	width    int
+	height   int
+	resume   time.Duration
+	logger   *logrus.Entry
+	// start is frame 0
+	start time.Time
+	// wake makes the render loop draw now instead of at its next tick
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	mutex   sync.Mutex
+	clients map[*client]bool
+	closed  bool
+	// added counts the screens added, to keep their order
+	added int
+	shown *lcdScreen
+	// turn is the frame the shown screen's turn started; lastFrame the frame
+	// of the last render
+	turn      int
+	lastFrame int
+	// pausedUntil is when the screens come back after a press no client
+	// reserved
+	pausedUntil time.Time
+	// swallow holds buttons whose release is consumed because their press
+	// handed the panel to the menu or went to a client
+	swallow map[controller.PanelButton]bool
+
+	// drawn is the text last written to the display, "" when released. Only
+	// the render loop uses it.
+	drawn string
+}
+
+// client is one connection
+type client struct {
+	conn    net.Conn
+	name    string
+	greeted bool
+	keys    map[string]bool
+	screens map[string]*lcdScreen
+	out     chan string
+}
+
+// Listen creates a server for a width x height panel on address. Screens
+// step aside for resume after a press no client reserved. Clients are only
+// served once Start is called.
+func Listen(address string, display Display, width, height int, resume time.Duration) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for lcdproc clients: %w", err)
+	}
+
+	return &Server{
+		listener: listener,
+		display:  display,
+		width:    width,
+		height:   height,
+		resume:   resume,
+		logger:   logrus.WithField("component", "lcdproc"),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		clients:  make(map[*client]bool),
+		swallow:  make(map[controller.PanelButton]bool),
+	}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Start accepts clients and draws their screens in the background until
+// Close is called
+func (s *Server) Start() {
+	s.start = time.Now()
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		s.renderLoop()
+	}()
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				if !s.isClosed() {
+					s.logger.WithError(err).Error("Stopped accepting lcdproc clients")
+				}
+				return
+			}
+			c := s.track(conn)
+			if c == nil {
+				conn.Close()
+				return
+			}
+			s.wg.Add(2)
+			go func() {
+				defer s.wg.Done()
+				c.write()
+			}()
+			go func() {
+				defer s.wg.Done()
+				defer s.untrack(c)
+				if err := s.serve(c); err != nil {
+					s.logger.WithError(err).Warn("lcdproc connection failed")
+				}
+			}()
+		}
+	}()
+}
+
+// Close disconnects the clients, stops listening and releases the display
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mutex.Unlock()
+
+	err := s.listener.Close()
+	close(s.stop)
+	s.wg.Wait()
+	if s.drawn != "" {
+		s.display.Release()
+	}
+	return err
+}
+
+// isClosed reports whether Close was called
+func (s *Server) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closed
+}
+
+// track records a new connection; it returns nil once the server is closed
+func (s *Server) track(conn net.Conn) *client {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	c := &client{
+		conn:    conn,
+		keys:    make(map[string]bool),
+		screens: make(map[string]*lcdScreen),
+		out:     make(chan string, clientQueue),
+	}
+	s.clients[c] = true
+	return c
+}
+
+// untrack closes a connection and removes its screens
+func (s *Server) untrack(c *client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c.conn.Close()
+	close(c.out)
+	delete(s.clients, c)
+	if c.greeted {
+		s.logger.WithField("client", c.name).Info("lcdproc client disconnected")
+	}
+	s.redraw()
+}
+
+// redraw makes the render loop draw now. Caller must hold the mutex.
+func (s *Server) redraw() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// send queues a line for a client. Caller must hold the mutex.
+func (s *Server) send(c *client, line string) {
+	select {
+	case c.out <- line:
+	default:
+		s.logger.WithField("client", c.name).Warn("lcdproc client falling behind, line dropped")
+	}
+}
+
+// write sends the queued lines until the connection is untracked
+func (c *client) write() {
+	for line := range c.out {
+		c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := c.conn.Write([]byte(line + "\n")); err != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// serve runs the commands of one connection until the client goes away or
+// says bye
+func (s *Server) serve(c *client) error {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 1024), maxLineSize)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "bye" {
+			return nil
+		}
+		s.handle(c, line)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// handle runs one command and answers it
+func (s *Server) handle(c *client, line string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	response, err := s.run(c, line)
+	if err != nil {
+		s.logger.WithError(err).WithField("client", c.name).Debug("lcdproc command refused")
+		response = "huh? " + err.Error()
+	}
+	s.send(c, response)
+}
+
+// run runs one command and returns its answer. Caller must hold the mutex.
+func (s *Server) run(c *client, line string) (string, error) {
+	args, err := splitArgs(line)
+	if err != nil {
+		return "", err
+	}
+	command, args := args[0], args[1:]
+
+	if command == "hello" {
+		if !c.greeted {
+			c.greeted = true
+			s.logger.WithField("address", c.conn.RemoteAddr().String()).Info("lcdproc client connected")
+		}
+		return fmt.Sprintf("connect LCDproc %s protocol %s lcd wid %d hgt %d cellwid %d cellhgt %d",
+			serverVersion, protocolVersion, s.width, s.height, cellWidth, cellHeight), nil
+	}
+	if !c.greeted {
+		return "", fmt.Errorf("say hello first")
+	}
+
+	switch command {
+	case "client_set":
+		options, err := parseOptions(args)
+		if err != nil {
+			return "", err
+		}
+		for option, value := range options {
+			if option != "name" {
+				return "", fmt.Errorf("invalid option -%s", option)
+			}
+			c.name = value
+		}
+	case "client_add_key", "client_del_key":
+		for _, key := range args {
+			if key == "-shared" || key == "-exclusively" {
+				continue
+			}
+			if command == "client_add_key" {
+				c.keys[key] = true
+			} else {
+				delete(c.keys, key)
+			}
+		}
+	case "screen_add":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: screen_add <screenid>")
+		}
+		if c.screens[args[0]] != nil {
+			return "", fmt.Errorf("screen %q already exists", args[0])
+		}
+		s.added++
+		c.screens[args[0]] = &lcdScreen{
+			id:       args[0],
+			client:   c,
+			priority: priorityInfo,
+			duration: defaultDuration,
+			added:    s.added,
+		}
+		s.redraw()
+	case "screen_del":
+		if _, err := s.screen(c, args); err != nil {
+			return "", err
+		}
+		delete(c.screens, args[0])
+		s.redraw()
+	case "screen_set":
+		scr, err := s.screen(c, args)
+		if err != nil {
+			return "", err
+		}
+		if err := scr.set(args[1:]); err != nil {
+			return "", err
+		}
+		s.redraw()
+	case "widget_add":
+		scr, err := s.screen(c, args)
+		if err != nil {
+			return "", err
+		}
+		if len(args) < 3 {
+			return "", fmt.Errorf("usage: widget_add <screenid> <widgetid> <widgettype>")
+		}
+		if len(args) > 3 || args[2] == "frame" {
+			return "", fmt.Errorf("frames are not supported")
+		}
+		if _, ok := widgetArgs[args[2]]; !ok {
+			return "", fmt.Errorf("invalid widget type %q", args[2])
+		}
+		if w, _ := scr.widget(args[1]); w != nil {
+			return "", fmt.Errorf("widget %q already exists", args[1])
+		}
+		scr.widgets = append(scr.widgets, &widget{id: args[1], kind: args[2]})
+	case "widget_set", "widget_del":
+		scr, err := s.screen(c, args)
+		if err != nil {
+			return "", err
+		}
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: %s <screenid> <widgetid> ...", command)
+		}
+		w, i := scr.widget(args[1])
+		if w == nil {
+			return "", fmt.Errorf("unknown widget %q", args[1])
+		}
+		if command == "widget_del" {
+			scr.widgets = append(scr.widgets[:i], scr.widgets[i+1:]...)
+			break
+		}
+		if err := w.set(args[2:]); err != nil {
+			return "", err
+		}
+	case "info":
+		return fmt.Sprintf("QNAP front panel %dx%d", s.width, s.height), nil
+	case "noop":
+		return "noop complete", nil
+	case "backlight", "output", "sleep":
+		// The service owns the backlight and the LEDs
+	default:
+		if strings.HasPrefix(command, "menu_") {
+			return "", fmt.Errorf("menus are not supported")
+		}
+		return "", fmt.Errorf("invalid command %q", command)
+	}
+	return "success", nil
+}
+
+// screen returns the client's screen named by the first argument. Caller
+// must hold the mutex.
+func (s *Server) screen(c *client, args []string) (*lcdScreen, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no screen given")
+	}
+	scr := c.screens[args[0]]
+	if scr == nil {
+		return nil, fmt.Errorf("unknown screen %q", args[0])
+	}
+	return scr, nil
+}
+
+// parseOptions reads "-option value" pairs
+func parseOptions(args []string) (map[string]string, error) {
+	options := make(map[string]string)
+	for i := 0; i < len(args); i += 2 {
+		if !strings.HasPrefix(args[i], "-") || i+1 == len(args) {
+			return nil, fmt.Errorf("expected -option value, got %q", args[i])
+		}
+		options[args[i][1:]] = args[i+1]
+	}
+	return options, nil
+}
+
+// set applies the options of screen_set. Options without a meaning on this
+// panel, such as the heartbeat and the cursor, are accepted and ignored.
+func (scr *lcdScreen) set(args []string) error {
+	options, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
+	for option, value := range options {
+		switch option {
+		case "name":
+			scr.name = value
+		case "priority":
+			class, err := parsePriority(value)
+			if err != nil {
+				return err
+			}
+			scr.priority = class
+		case "duration", "timeout":
+			var frames int
+			if _, err := fmt.Sscan(value, &frames); err != nil || frames < 0 {
+				return fmt.Errorf("invalid %s %q", option, value)
+			}
+			if option == "duration" {
+				scr.duration = frames
+			} else {
+				scr.timeout = frames
+			}
+		case "wid", "hgt", "heartbeat", "backlight", "cursor", "cursor_x", "cursor_y":
+		default:
+			return fmt.Errorf("invalid option -%s", option)
+		}
+	}
+	return nil
+}
+
+// HandleButton hands a button to the clients. A press goes to the client of
+// the shown screen if it reserved the key, otherwise to any client that did.
+// A press no client reserved makes the screens step aside for the menu;
+// every further press while they do keeps the menu up. It returns true if
+// the event was consumed.
+func (s *Server) HandleButton(button controller.PanelButton, pressed bool) bool {
+	key, ok := keyNames[button]
+	if !ok {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !pressed {
+		if s.swallow[button] {
+			delete(s.swallow, button)
+			return true
+		}
+		return false
+	}
+
+	now := time.Now()
+	if now.Before(s.pausedUntil) {
+		s.pausedUntil = now.Add(s.resume)
+	}
+	if s.shown == nil {
+		return false
+	}
+
+	target := s.shown.client
+	if !s.clients[target] || !target.keys[key] {
+		target = nil
+		for c := range s.clients {
+			if c.keys[key] {
+				target = c
+				break
+			}
+		}
+	}
+	if target != nil {
+		s.send(target, "key "+key)
+	} else {
+		s.logger.Debug("lcdproc screens step aside for the menu")
+		s.pausedUntil = now.Add(s.resume)
+		s.redraw()
+	}
+	s.swallow[button] = true
+	return true
+}
+
+// renderLoop draws the shown screen every renderInterval and when woken
+func (s *Server) renderLoop() {
+	ticker := time.NewTicker(renderInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		s.render(time.Now())
+	}
+}
+
+// render picks the screen to show and draws it
+func (s *Server) render(now time.Time) {
+	s.mutex.Lock()
+	frame := int(now.Sub(s.start) / frameInterval)
+
+	// A screen's timeout only runs down while it is shown
+	if s.shown != nil && s.shown.timeout > 0 {
+		s.shown.timeout -= frame - s.lastFrame
+		if s.shown.timeout <= 0 {
+			delete(s.shown.client.screens, s.shown.id)
+		}
+	}
+	s.lastFrame = frame
+
+	next := s.next(frame, now)
+	if next != s.shown {
+		if s.shown != nil && s.shown.client.screens[s.shown.id] == s.shown && s.clients[s.shown.client] {
+			s.send(s.shown.client, "ignore "+s.shown.id)
+		}
+		if next != nil {
+			s.send(next.client, "listen "+next.id)
+		}
+		s.shown = next
+		s.turn = frame
+	}
+	text := ""
+	if next != nil {
+		text = strings.Join(next.render(s.width, s.height, frame-s.turn), "\n")
+	}
+	s.mutex.Unlock()
+
+	if text == s.drawn {
+		return
+	}
+	var err error
+	if text == "" {
+		err = s.display.Release()
+	} else {
+		err = s.display.WriteText(text)
+	}
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to draw lcdproc screen")
+		return
+	}
+	s.drawn = text
+}
+
+// next returns the screen to show at frame: the shown one until its turn is
+// over, then the next one of the highest priority class present. Hidden and
+// background screens are never shown, the menu is the panel's background;
+// while the screens step aside only alerts are. Caller must hold the mutex.
+func (s *Server) next(frame int, now time.Time) *lcdScreen {
+	lowest := priorityInfo
+	if now.Before(s.pausedUntil) {
+		lowest = priorityAlert
+	}
+
+	var candidates []*lcdScreen
+	top := lowest
+	for c := range s.clients {
+		for _, scr := range c.screens {
+			if scr.priority < top {
+				continue
+			}
+			if scr.priority > top {
+				top = scr.priority
+				candidates = nil
+			}
+			candidates = append(candidates, scr)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].added < candidates[j].added
+	})
+
+	for i, scr := range candidates {
+		if scr != s.shown {
+			continue
+		}
+		if frame-s.turn < scr.duration {
+			return scr
+		}
+		return candidates[(i+1)%len(candidates)]
+	}
+	// The shown screen is gone or outranked: continue after it in turn
+	for _, scr := range candidates {
+		if s.shown != nil && scr.added > s.shown.added {
+			return scr
+		}
+	}
+	return candidates[0]
+}
@@ -0,0 +1,200 @@
+package lcdproc
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLayer remembers what the server drew
+type fakeLayer struct {
+	mutex    sync.Mutex
+	text     string
+	released bool
+}
+
+func (l *fakeLayer) WriteText(text string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.text, l.released = text, false
+	return nil
+}
+
+func (l *fakeLayer) Release() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.text, l.released = "", true
+	return nil
+}
+
+func (l *fakeLayer) shown() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.text
+}
+
+// testClient is an lcdproc client talking to the server
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	// notices are the listen, ignore and key lines that arrived while
+	// waiting for an answer
+	notices []string
+}
+
+func dialTestClient(t *testing.T, s *Server) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// read returns the next line from the server
+func (c *testClient) read() string {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.reader.ReadString('\n')
+	require.NoError(c.t, err)
+	return strings.TrimSuffix(line, "\n")
+}
+
+// call sends a command and returns the answer, keeping the notices sent in
+// between
+func (c *testClient) call(command string) string {
+	c.t.Helper()
+
+	_, err := c.conn.Write([]byte(command + "\n"))
+	require.NoError(c.t, err)
+	for {
+		line := c.read()
+		for _, notice := range []string{"listen ", "ignore ", "key "} {
+			if strings.HasPrefix(line, notice) {
+				c.notices = append(c.notices, line)
+				line = ""
+			}
+		}
+		if line != "" {
+			return line
+		}
+	}
+}
+
+// notice returns the next notice from the server
+func (c *testClient) notice() string {
+	c.t.Helper()
+
+	if len(c.notices) > 0 {
+		notice := c.notices[0]
+		c.notices = c.notices[1:]
+		return notice
+	}
+	return c.read()
+}
+
+// newTestServer starts a server for a 16x2 panel
+func newTestServer(t *testing.T) (*Server, *fakeLayer) {
+	t.Helper()
+
+	layer := &fakeLayer{}
+	s, err := Listen("127.0.0.1:0", layer, 16, 2, time.Hour)
+	require.NoError(t, err)
+	s.Start()
+	t.Cleanup(func() { s.Close() })
+	return s, layer
+}
+
+func TestServer(t *testing.T) {
+	s, layer := newTestServer(t)
+	c := dialTestClient(t, s)
+
+	assert.Equal(t, "huh? say hello first", c.call("screen_add s1"))
+	assert.Equal(t, "connect LCDproc 0.5.9 protocol 0.4 lcd wid 16 hgt 2 cellwid 5 cellhgt 8", c.call("hello"))
+	assert.Equal(t, "success", c.call("client_set -name test"))
+	assert.Equal(t, "success", c.call("screen_add s1"))
+	assert.Equal(t, "huh? screen \"s1\" already exists", c.call("screen_add s1"))
+	assert.Equal(t, "success", c.call("screen_set s1 -name Load -priority foreground -heartbeat off"))
+	assert.Equal(t, "success", c.call("widget_add s1 title title"))
+	assert.Equal(t, "success", c.call("widget_add s1 load string"))
+	assert.Equal(t, "huh? frames are not supported", c.call("widget_add s1 f frame"))
+	assert.Equal(t, "success", c.call("widget_set s1 title {System Load}"))
+	assert.Equal(t, "success", c.call(`widget_set s1 load 1 2 "0.42 0.30 0.12"`))
+	assert.Equal(t, "huh? unknown widget \"nope\"", c.call("widget_set s1 nope 1 1 x"))
+	assert.Equal(t, "huh? menus are not supported", c.call("menu_add_item \"\" m menu"))
+	assert.Equal(t, "noop complete", c.call("noop"))
+
+	require.Eventually(t, func() bool {
+		return layer.shown() == "System Load     \n0.42 0.30 0.12  "
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The screen goes away with its client
+	c.conn.Write([]byte("bye\n"))
+	require.Eventually(t, func() bool {
+		layer.mutex.Lock()
+		defer layer.mutex.Unlock()
+		return layer.released
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_Buttons(t *testing.T) {
+	s, layer := newTestServer(t)
+	c := dialTestClient(t, s)
+	c.call("hello")
+	c.call("screen_add s1")
+	c.call("widget_add s1 w string")
+	c.call("widget_set s1 w 1 1 Playing")
+	assert.Equal(t, "success", c.call("client_add_key -shared Enter"))
+	assert.Equal(t, "listen s1", c.notice())
+	require.Eventually(t, func() bool { return layer.shown() != "" }, 2*time.Second, 10*time.Millisecond)
+
+	// Reserved keys go to the client
+	assert.True(t, s.HandleButton(controller.ButtonEnter, true))
+	assert.Equal(t, "key Enter", c.notice())
+	assert.True(t, s.HandleButton(controller.ButtonEnter, false))
+	assert.False(t, s.HandleButton(controller.ButtonUSBCopy, true), "the copy button is not a key")
+
+	// Any other key makes the screens step aside for the menu
+	assert.True(t, s.HandleButton(controller.ButtonSelect, true))
+	assert.True(t, s.HandleButton(controller.ButtonSelect, false))
+	assert.Equal(t, "ignore s1", c.notice())
+	require.Eventually(t, func() bool { return layer.shown() == "" }, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, s.HandleButton(controller.ButtonSelect, true), "the menu gets presses while the screens step aside")
+	assert.False(t, s.HandleButton(controller.ButtonEnter, true))
+
+	// Alerts are shown anyway
+	c.call("screen_set s1 -priority alert")
+	assert.Equal(t, "listen s1", c.notice())
+}
+
+func TestServer_Rotation(t *testing.T) {
+	s, layer := newTestServer(t)
+	c := dialTestClient(t, s)
+	c.call("hello")
+	for _, id := range []string{"a", "b"} {
+		c.call("screen_add " + id)
+		c.call("screen_set " + id + " -duration 2")
+		c.call("widget_add " + id + " w string")
+		c.call("widget_set " + id + " w 1 1 screen-" + id)
+	}
+	c.call("screen_add bg")
+	c.call("screen_set bg -priority background")
+	c.call("widget_add bg w string")
+	c.call("widget_set bg w 1 1 background")
+
+	seen := make(map[string]bool)
+	require.Eventually(t, func() bool {
+		seen[strings.TrimSpace(strings.Split(layer.shown(), "\n")[0])] = true
+		return seen["screen-a"] && seen["screen-b"]
+	}, 3*time.Second, 10*time.Millisecond, "screens of the same priority take turns")
+	assert.False(t, seen["background"], "background screens are not shown")
+}
@@ -0,0 +1,334 @@
+package lcdproc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qnap/display-control/internal/controller"
+)
+
+// LCDd's character cell, in pixels. Bar lengths are given in pixels.
+const (
+	cellWidth  = 5
+	cellHeight = 8
+)
+
+// barHeights are the heights of the bar glyphs in pixel rows
+var barHeights = []int{3, 5, cellHeight}
+
+// defaultDuration is how many frames a screen is shown before the next one
+// of its priority takes its turn, unless it says otherwise
+const defaultDuration = 32
+
+// priority is a screen's priority class. Only the screens of the highest
+// class present are shown, in turn.
+type priority int
+
+const (
+	priorityHidden priority = iota
+	priorityBackground
+	priorityInfo
+	priorityForeground
+	priorityAlert
+	priorityInput
+)
+
+// priorityNames are the classes as clients name them
+var priorityNames = map[string]priority{
+	"hidden":     priorityHidden,
+	"background": priorityBackground,
+	"info":       priorityInfo,
+	"foreground": priorityForeground,
+	"alert":      priorityAlert,
+	"input":      priorityInput,
+}
+
+// parsePriority reads a priority class. Old clients send numbers: up to 64
+// is foreground, up to 192 info and anything higher background.
+func parsePriority(value string) (priority, error) {
+	if class, ok := priorityNames[value]; ok {
+		return class, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("invalid priority %q", value)
+	}
+	switch {
+	case number <= 64:
+		return priorityForeground, nil
+	case number <= 192:
+		return priorityInfo, nil
+	default:
+		return priorityBackground, nil
+	}
+}
+
+// iconChars stand in for LCDd's icons with the closest ASCII character.
+// BLOCK_FILLED has none and is drawn with the full bar glyph.
+var iconChars = map[string]string{
+	"BLOCK_FILLED":      "",
+	"HEART_OPEN":        "*",
+	"HEART_FILLED":      "*",
+	"ARROW_UP":          "^",
+	"ARROW_DOWN":        "v",
+	"ARROW_LEFT":        "<",
+	"ARROW_RIGHT":       ">",
+	"CHECKBOX_OFF":      "o",
+	"CHECKBOX_ON":       "x",
+	"CHECKBOX_GRAY":     "-",
+	"SELECTOR_AT_LEFT":  ">",
+	"SELECTOR_AT_RIGHT": "<",
+	"ELLIPSIS":          "~",
+	"STOP":              "#",
+	"PAUSE":             "=",
+	"PLAY":              ">",
+	"PLAYR":             "<",
+	"FF":                ">",
+	"FR":                "<",
+	"NEXT":              ">",
+	"PREV":              "<",
+	"REC":               "o",
+}
+
+// widget is one element of a screen
+type widget struct {
+	id   string
+	kind string
+	// x and y are the 1-based column and row; scrollers also use right and
+	// bottom
+	x, y          int
+	right, bottom int
+	text          string
+	// length is the bar length in pixels
+	length int
+	// direction and speed move a scroller: "h" bounces, "m" wraps around and
+	// "v" scrolls lines; speed is frames per step, or steps per frame if
+	// negative
+	direction string
+	speed     int
+}
+
+// widgetArgs is how many arguments widget_set takes for each widget type
+var widgetArgs = map[string]int{
+	"string":   3,
+	"title":    1,
+	"hbar":     3,
+	"vbar":     3,
+	"icon":     3,
+	"scroller": 7,
+	"num":      2,
+}
+
+// set applies the arguments of widget_set
+func (w *widget) set(args []string) error {
+	if len(args) != widgetArgs[w.kind] {
+		return fmt.Errorf("%s widgets take %d arguments", w.kind, widgetArgs[w.kind])
+	}
+
+	numbers := func(values ...string) ([]int, error) {
+		parsed := make([]int, len(values))
+		for i, value := range values {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", value)
+			}
+			parsed[i] = number
+		}
+		return parsed, nil
+	}
+
+	switch w.kind {
+	case "title":
+		w.text = args[0]
+	case "string":
+		position, err := numbers(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		w.x, w.y, w.text = position[0], position[1], args[2]
+	case "hbar", "vbar":
+		values, err := numbers(args...)
+		if err != nil {
+			return err
+		}
+		w.x, w.y, w.length = values[0], values[1], values[2]
+	case "icon":
+		if _, ok := iconChars[args[2]]; !ok {
+			return fmt.Errorf("unknown icon %q", args[2])
+		}
+		position, err := numbers(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		w.x, w.y, w.text = position[0], position[1], args[2]
+	case "scroller":
+		values, err := numbers(args[0], args[1], args[2], args[3], args[5])
+		if err != nil {
+			return err
+		}
+		if args[4] != "h" && args[4] != "m" && args[4] != "v" {
+			return fmt.Errorf("invalid scroller direction %q", args[4])
+		}
+		w.x, w.y, w.right, w.bottom = values[0], values[1], values[2], values[3]
+		w.direction, w.speed, w.text = args[4], values[4], args[6]
+	case "num":
+		values, err := numbers(args...)
+		if err != nil {
+			return err
+		}
+		if values[1] < 0 || values[1] > 10 {
+			return fmt.Errorf("invalid digit %d", values[1])
+		}
+		// 10 is the colon
+		w.x, w.y, w.text = values[0], 1, "0123456789:"[values[1]:values[1]+1]
+	}
+	return nil
+}
+
+// lcdScreen is a screen a client added
+type lcdScreen struct {
+	id       string
+	client   *client
+	name     string
+	priority priority
+	// duration is how many frames the screen is shown per turn
+	duration int
+	// timeout is how many more frames the screen is shown before it is
+	// removed, 0 for no limit
+	timeout int
+	widgets []*widget
+	// added orders screens of the same priority
+	added int
+}
+
+// widget returns the widget with the given id
+func (s *lcdScreen) widget(id string) (*widget, int) {
+	for i, w := range s.widgets {
+		if w.id == id {
+			return w, i
+		}
+	}
+	return nil, -1
+}
+
+// render draws the widgets on a width x height grid at the given frame,
+// frames counting eighths of a second
+func (s *lcdScreen) render(width, height, frame int) []string {
+	grid := make([][]string, height)
+	for row := range grid {
+		grid[row] = make([]string, width)
+		for col := range grid[row] {
+			grid[row][col] = " "
+		}
+	}
+	put := func(x, y int, text string) {
+		if y < 1 || y > height {
+			return
+		}
+		for i, r := range []rune(text) {
+			if col := x - 1 + i; col >= 0 && col < width {
+				grid[y-1][col] = string(r)
+			}
+		}
+	}
+	block, _ := controller.GlyphChar("bar3")
+
+	for _, w := range s.widgets {
+		switch w.kind {
+		case "string", "num":
+			put(w.x, w.y, w.text)
+		case "title":
+			put(1, 1, bounce(w.text, width, frame, 4))
+		case "icon":
+			char := iconChars[w.text]
+			if char == "" {
+				char = block
+			}
+			put(w.x, w.y, char)
+		case "hbar":
+			cells := (w.length + cellWidth/2) / cellWidth
+			put(w.x, w.y, strings.Repeat(block, cells))
+		case "vbar":
+			// Grows upwards from its row, each cell showing the lowest
+			// bar that covers its pixels
+			pixels := w.length
+			for y := w.y; y >= 1 && pixels > 0; y-- {
+				level := 1
+				for level < len(barHeights) && barHeights[level-1] < pixels {
+					level++
+				}
+				bar, _ := controller.GlyphChar(fmt.Sprintf("bar%d", level))
+				put(w.x, y, bar)
+				pixels -= cellHeight
+			}
+		case "scroller":
+			renderScroller(w, put, frame)
+		}
+	}
+
+	lines := make([]string, height)
+	for row := range grid {
+		lines[row] = strings.Join(grid[row], "")
+	}
+	return lines
+}
+
+// renderScroller draws a scroller's text in its area
+func renderScroller(w *widget, put func(x, y int, text string), frame int) {
+	width := w.right - w.x + 1
+	rows := w.bottom - w.y + 1
+	if width < 1 || rows < 1 {
+		return
+	}
+	var steps int
+	switch {
+	case w.speed > 0:
+		steps = frame / w.speed
+	case w.speed < 0:
+		steps = frame * -w.speed
+	}
+
+	switch w.direction {
+	case "h":
+		put(w.x, w.y, bounce(w.text, width, steps, 1))
+	case "m":
+		text := []rune(w.text)
+		if len(text) <= width {
+			put(w.x, w.y, w.text)
+			return
+		}
+		offset := steps % len(text)
+		wrapped := append(text[offset:], text[:offset]...)
+		put(w.x, w.y, string(wrapped[:width]))
+	case "v":
+		var lines []string
+		for text := []rune(w.text); len(text) > 0; {
+			n := min(width, len(text))
+			lines = append(lines, string(text[:n]))
+			text = text[n:]
+		}
+		offset := 0
+		if len(lines) > rows {
+			offset = steps % (len(lines) - rows + 1)
+		}
+		for row := 0; row < rows && offset+row < len(lines); row++ {
+			put(w.x, w.y+row, lines[offset+row])
+		}
+	}
+}
+
+// bounce shows width characters of text; longer text moves one character
+// every step frames, to its end and back
+func bounce(text string, width, frame, step int) string {
+	runes := []rune(text)
+	overhang := len(runes) - width
+	if overhang <= 0 {
+		return text
+	}
+	position := (frame / step) % (2 * overhang)
+	if position > overhang {
+		position = 2*overhang - position
+	}
+	return string(runes[position : position+width])
+}
@@ -0,0 +1,96 @@
+package lcdproc
+
+import (
+	"testing"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestScreen creates a screen with the given widgets, each given by its
+// type and widget_set arguments
+func newTestScreen(t *testing.T, widgets ...[]string) *lcdScreen {
+	t.Helper()
+
+	scr := &lcdScreen{id: "s", priority: priorityInfo, duration: defaultDuration}
+	for i, spec := range widgets {
+		w := &widget{id: string(rune('a' + i)), kind: spec[0]}
+		require.NoError(t, w.set(spec[1:]), spec)
+		scr.widgets = append(scr.widgets, w)
+	}
+	return scr
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := map[string]priority{
+		"foreground": priorityForeground,
+		"alert":      priorityAlert,
+		"1":          priorityForeground,
+		"128":        priorityInfo,
+		"256":        priorityBackground,
+	}
+	for value, expected := range tests {
+		class, err := parsePriority(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, class, value)
+	}
+
+	_, err := parsePriority("urgent")
+	assert.Error(t, err)
+}
+
+func TestWidgetSet(t *testing.T) {
+	w := &widget{kind: "string"}
+	assert.Error(t, w.set([]string{"1", "1"}), "missing text")
+	assert.Error(t, w.set([]string{"x", "1", "text"}))
+
+	w = &widget{kind: "icon"}
+	assert.Error(t, w.set([]string{"1", "1", "SMILEY"}))
+
+	w = &widget{kind: "scroller"}
+	assert.Error(t, w.set([]string{"1", "2", "16", "2", "d", "1", "text"}))
+}
+
+func TestScreenRender(t *testing.T) {
+	block, _ := controller.GlyphChar("bar3")
+	half, _ := controller.GlyphChar("bar2")
+
+	t.Run("Strings, icons and bars", func(t *testing.T) {
+		scr := newTestScreen(t,
+			[]string{"title", "CPU"},
+			[]string{"string", "1", "2", "Load"},
+			[]string{"hbar", "6", "2", "14"},
+			[]string{"icon", "16", "1", "HEART_FILLED"},
+			[]string{"vbar", "15", "1", "4"},
+			// Outside the panel
+			[]string{"string", "1", "3", "hidden"},
+		)
+		assert.Equal(t, []string{
+			"CPU           " + half + "*",
+			"Load " + block + block + block + "        ",
+		}, scr.render(16, 2, 0))
+	})
+
+	t.Run("Long titles bounce", func(t *testing.T) {
+		scr := newTestScreen(t, []string{"title", "0123456789ABCDEFGH"})
+		assert.Equal(t, "0123456789ABCDEF", scr.render(16, 2, 0)[0])
+		assert.Equal(t, "123456789ABCDEFG", scr.render(16, 2, 4)[0])
+		assert.Equal(t, "23456789ABCDEFGH", scr.render(16, 2, 8)[0])
+		assert.Equal(t, "123456789ABCDEFG", scr.render(16, 2, 12)[0])
+	})
+
+	t.Run("Scrollers", func(t *testing.T) {
+		scr := newTestScreen(t,
+			[]string{"scroller", "1", "1", "8", "1", "m", "2", "ABCDEFGHIJ"},
+			[]string{"scroller", "9", "1", "16", "1", "h", "-1", "0123456789"},
+		)
+		assert.Equal(t, "ABCDEFGH01234567", scr.render(16, 2, 0)[0])
+		assert.Equal(t, "BCDEFGHI23456789", scr.render(16, 2, 2)[0])
+		assert.Equal(t, "JABCDEFG23456789", scr.render(16, 2, 18)[0])
+
+		vertical := newTestScreen(t, []string{"scroller", "1", "1", "4", "2", "v", "1", "aaaabbbbcccc"})
+		assert.Equal(t, []string{"aaaa            ", "bbbb            "}, vertical.render(16, 2, 0))
+		assert.Equal(t, []string{"bbbb            ", "cccc            "}, vertical.render(16, 2, 1))
+	})
+}
@@ -0,0 +1,74 @@
+// Package logging formats the service's log entries: text for people
+// reading the console, or JSON lines for log collectors such as Loki or
+// Elasticsearch. Entries with an error also get an error_type field, so
+// errors can be grouped without parsing their text.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorTypeKey is the field naming the type of an entry's error
+const ErrorTypeKey = "error_type"
+
+// Formatter returns the formatter of a log format from the configuration,
+// "text" or "json"; "" is text
+func Formatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", "text":
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	case "json":
+		return &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// ErrorTypeHook adds ErrorTypeKey to every entry with an error
+type ErrorTypeHook struct{}
+
+// Levels returns every level
+func (ErrorTypeHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the error type
+func (ErrorTypeHook) Fire(entry *logrus.Entry) error {
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok && err != nil {
+		entry.Data[ErrorTypeKey] = ErrorType(err)
+	}
+	return nil
+}
+
+// wrapperTypes only add context to the errors they wrap
+var wrapperTypes = map[string]bool{
+	"*fmt.wrapError":    true,
+	"*fmt.wrapErrors":   true,
+	"*errors.joinError": true,
+}
+
+// ErrorType names the type of err below the wrappers adding context, e.g.
+// "*fs.PathError" for an open that failed, however often it was wrapped
+func ErrorType(err error) string {
+	for {
+		name := reflect.TypeOf(err).String()
+		if !wrapperTypes[name] {
+			return name
+		}
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			// joined errors have no single error to look into
+			if joined, ok := err.(interface{ Unwrap() []error }); ok && len(joined.Unwrap()) > 0 {
+				inner = joined.Unwrap()[0]
+			} else {
+				return name
+			}
+		}
+		err = inner
+	}
+}
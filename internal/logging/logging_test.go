@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatter(t *testing.T) {
+	formatter, err := Formatter("")
+	require.NoError(t, err)
+	assert.IsType(t, &logrus.TextFormatter{}, formatter)
+
+	formatter, err = Formatter("json")
+	require.NoError(t, err)
+	assert.IsType(t, &logrus.JSONFormatter{}, formatter)
+
+	_, err = Formatter("xml")
+	assert.Error(t, err)
+}
+
+func TestJSONEntry(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	formatter, err := Formatter("json")
+	require.NoError(t, err)
+	logger.SetFormatter(formatter)
+	logger.AddHook(ErrorTypeHook{})
+
+	_, openErr := os.Open("/nonexistent/config.json")
+	logger.WithFields(logrus.Fields{"component": "display_controller", "button": "enter"}).
+		WithError(fmt.Errorf("failed to load: %w", openErr)).Warn("Button handler failed")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "display_controller", entry["component"])
+	assert.Equal(t, "enter", entry["button"])
+	assert.Equal(t, "warning", entry["level"])
+	assert.Equal(t, "Button handler failed", entry["msg"])
+	assert.Contains(t, entry["error"], "no such file")
+	assert.Equal(t, "*fs.PathError", entry["error_type"])
+}
+
+func TestErrorType(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "/dev/ttyS1", Err: fs.ErrPermission}
+	assert.Equal(t, "*fs.PathError", ErrorType(pathErr))
+	assert.Equal(t, "*fs.PathError", ErrorType(fmt.Errorf("a: %w", fmt.Errorf("b: %w", pathErr))))
+	assert.Equal(t, "*errors.errorString", ErrorType(errors.New("plain")))
+	assert.Equal(t, "*fmt.wrapError", ErrorType(fmt.Errorf("no cause: %w", nil)), "nothing wrapped")
+	assert.Equal(t, "*fs.PathError", ErrorType(errors.Join(pathErr, errors.New("second"))))
+}
@@ -0,0 +1,138 @@
+// Package luks unlocks LUKS encrypted USB devices for the USB copy: it
+// finds the encrypted partition of a USB disk, opens it with a passphrase or
+// key file, mounts it at the copy source and locks it again afterwards.
+//
+// The commands are fixed strings for a configuration, with the device and
+// passphrase passed in $DEVICE and $INPUT, so the privileged helper can allow
+// them.
+package luks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// MapperName is the device mapper name the unlocked device gets
+const MapperName = "qnap-usb-copy"
+
+// ErrNoDevice is returned by FindDevice when no USB disk has a LUKS partition
+var ErrNoDevice = errors.New("no encrypted USB device")
+
+// ErrWrongPassphrase is returned by Unlock when the passphrase or key file
+// does not open the device
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// Commands are the shell commands unlocking, mounting and locking a device
+type Commands struct {
+	// Unlock opens the device in $DEVICE with the passphrase in $INPUT, or
+	// with the key file
+	Unlock string
+	// Mount mounts the opened device at the mount point
+	Mount string
+	// Lock unmounts and closes it again
+	Lock string
+}
+
+// NewCommands returns the commands mounting at mountPoint, unlocking with
+// keyFile or, without one, the passphrase entered
+func NewCommands(keyFile, mountPoint string) Commands {
+	unlock := fmt.Sprintf(`printf '%%s' "$INPUT" | cryptsetup open --type luks --key-file - "$DEVICE" %s`, MapperName)
+	if keyFile != "" {
+		unlock = fmt.Sprintf(`cryptsetup open --type luks --key-file %s "$DEVICE" %s`, quote(keyFile), MapperName)
+	}
+	return Commands{
+		Unlock: unlock,
+		Mount:  fmt.Sprintf("mount /dev/mapper/%s %s", MapperName, quote(mountPoint)),
+		Lock:   fmt.Sprintf("sync; umount %s; cryptsetup close %s", quote(mountPoint), MapperName),
+	}
+}
+
+// quote single quotes s for the shell
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// blockDevice is a device in the output of lsblk --json
+type blockDevice struct {
+	Name     string        `json:"name"`
+	FSType   string        `json:"fstype"`
+	Tran     string        `json:"tran"`
+	Children []blockDevice `json:"children"`
+}
+
+// FindDevice returns the first LUKS partition of a USB disk, e.g.
+// "/dev/sdc1", or ErrNoDevice. lsblk needs no privileges for this.
+func FindDevice() (string, error) {
+	output, err := exec.Command("lsblk", "--json", "--paths", "--output", "NAME,FSTYPE,TRAN").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list block devices: %w", err)
+	}
+	return parseDevices(output)
+}
+
+// parseDevices finds the LUKS partition in the output of lsblk. A stick
+// encrypted as a whole has the LUKS header on the disk itself.
+func parseDevices(output []byte) (string, error) {
+	var list struct {
+		BlockDevices []blockDevice `json:"blockdevices"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return "", fmt.Errorf("failed to parse lsblk output: %w", err)
+	}
+
+	for _, disk := range list.BlockDevices {
+		if disk.Tran != "usb" {
+			continue
+		}
+		if disk.FSType == "crypto_LUKS" {
+			return disk.Name, nil
+		}
+		for _, partition := range disk.Children {
+			if partition.FSType == "crypto_LUKS" {
+				return partition.Name, nil
+			}
+		}
+	}
+	return "", ErrNoDevice
+}
+
+// Unlocker runs the commands, directly or through the privileged helper
+type Unlocker struct {
+	commands Commands
+	run      sysinfo.CommandRunner
+}
+
+// NewUnlocker returns an unlocker running commands with run
+func NewUnlocker(commands Commands, run sysinfo.CommandRunner) *Unlocker {
+	return &Unlocker{commands: commands, run: run}
+}
+
+// Unlock opens device and mounts it. The passphrase is ignored when the
+// commands use a key file.
+func (u *Unlocker) Unlock(device, passphrase string) error {
+	env := []string{"DEVICE=" + device, "INPUT=" + passphrase}
+	if output, err := u.run(u.commands.Unlock, env); err != nil {
+		if strings.Contains(string(output), "No key available") {
+			return ErrWrongPassphrase
+		}
+		return fmt.Errorf("failed to unlock %s: %w: %s", device, err, strings.TrimSpace(string(output)))
+	}
+	if output, err := u.run(u.commands.Mount, nil); err != nil {
+		u.run(u.commands.Lock, nil)
+		return fmt.Errorf("failed to mount %s: %w: %s", device, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Lock flushes and unmounts the device and closes it, so it can be pulled
+func (u *Unlocker) Lock() error {
+	if output, err := u.run(u.commands.Lock, nil); err != nil {
+		return fmt.Errorf("failed to lock USB device: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package luks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCommands(t *testing.T) {
+	commands := NewCommands("", "/share/USB Copy")
+	assert.Equal(t, `printf '%s' "$INPUT" | cryptsetup open --type luks --key-file - "$DEVICE" qnap-usb-copy`, commands.Unlock)
+	assert.Equal(t, `mount /dev/mapper/qnap-usb-copy '/share/USB Copy'`, commands.Mount)
+	assert.Equal(t, `sync; umount '/share/USB Copy'; cryptsetup close qnap-usb-copy`, commands.Lock)
+
+	commands = NewCommands("/etc/qnap-display/usb'key", "/mnt/usb")
+	assert.Equal(t, `cryptsetup open --type luks --key-file '/etc/qnap-display/usb'\''key' "$DEVICE" qnap-usb-copy`, commands.Unlock)
+}
+
+func TestParseDevices(t *testing.T) {
+	output := []byte(`{
+   "blockdevices": [
+      {"name":"/dev/sda", "fstype":null, "tran":"sata",
+         "children": [
+            {"name":"/dev/sda1", "fstype":"crypto_LUKS", "tran":null}
+         ]
+      },
+      {"name":"/dev/sdb", "fstype":null, "tran":"usb",
+         "children": [
+            {"name":"/dev/sdb1", "fstype":"vfat", "tran":null}
+         ]
+      },
+      {"name":"/dev/sdc", "fstype":null, "tran":"usb",
+         "children": [
+            {"name":"/dev/sdc1", "fstype":"crypto_LUKS", "tran":null}
+         ]
+      }
+   ]
+}`)
+	device, err := parseDevices(output)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/sdc1", device)
+
+	device, err = parseDevices([]byte(`{"blockdevices": [{"name":"/dev/sdd", "fstype":"crypto_LUKS", "tran":"usb"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/sdd", device)
+
+	_, err = parseDevices([]byte(`{"blockdevices": [{"name":"/dev/sdb", "fstype":"vfat", "tran":"usb"}]}`))
+	assert.ErrorIs(t, err, ErrNoDevice)
+
+	_, err = parseDevices([]byte("lsblk: unknown column"))
+	assert.Error(t, err)
+}
+
+// fakeRunner records the commands run and fails those in failing with their
+// output
+type fakeRunner struct {
+	run     []string
+	env     [][]string
+	failing map[string]string
+}
+
+func (f *fakeRunner) runner(command string, env []string) ([]byte, error) {
+	f.run = append(f.run, command)
+	f.env = append(f.env, env)
+	if output, ok := f.failing[command]; ok {
+		return []byte(output), errors.New("exit status 1")
+	}
+	return nil, nil
+}
+
+func TestUnlocker(t *testing.T) {
+	commands := NewCommands("", "/mnt/usb")
+	fake := &fakeRunner{}
+	unlocker := NewUnlocker(commands, fake.runner)
+
+	require.NoError(t, unlocker.Unlock("/dev/sdc1", "1234"))
+	require.NoError(t, unlocker.Lock())
+	assert.Equal(t, []string{commands.Unlock, commands.Mount, commands.Lock}, fake.run)
+	assert.Equal(t, []string{"DEVICE=/dev/sdc1", "INPUT=1234"}, fake.env[0])
+}
+
+func TestUnlocker_WrongPassphrase(t *testing.T) {
+	commands := NewCommands("", "/mnt/usb")
+	fake := &fakeRunner{failing: map[string]string{
+		commands.Unlock: "No key available with this passphrase.\n",
+	}}
+
+	err := NewUnlocker(commands, fake.runner).Unlock("/dev/sdc1", "0000")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	assert.Equal(t, []string{commands.Unlock}, fake.run)
+}
+
+func TestUnlocker_MountFails(t *testing.T) {
+	commands := NewCommands("", "/mnt/usb")
+	fake := &fakeRunner{failing: map[string]string{
+		commands.Mount: "mount: wrong fs type\n",
+	}}
+
+	err := NewUnlocker(commands, fake.runner).Unlock("/dev/sdc1", "1234")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong fs type")
+	// The opened device is closed again
+	assert.Equal(t, []string{commands.Unlock, commands.Mount, commands.Lock}, fake.run)
+}
@@ -0,0 +1,142 @@
+// Package maintenance keeps a time-boxed maintenance mode. While it is on,
+// the monitors keep collecting but alerts and the red status LED are held
+// back, so work on the NAS does not set off the panel. The mode ends by
+// itself when its time is up.
+package maintenance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MaxDuration is the longest maintenance window, so a forgotten one does not
+// hide alerts for days
+const MaxDuration = 24 * time.Hour
+
+// Mode is the maintenance mode
+type Mode struct {
+	logger *logrus.Entry
+
+	mutex sync.Mutex
+	until time.Time
+	// timer ends the window, nil while the mode is off; window tells a
+	// timer that fired late for a replaced window from the current one
+	timer   *time.Timer
+	window  int
+	handler func(active bool)
+	now     func() time.Time
+}
+
+// NewMode creates a mode that is off
+func NewMode() *Mode {
+	return &Mode{
+		logger: logrus.WithField("component", "maintenance"),
+		now:    time.Now,
+	}
+}
+
+// OnChange sets the handler called when the mode starts or ends. It is not
+// called when an active window is only extended or shortened.
+func (m *Mode) OnChange(handler func(active bool)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handler = handler
+}
+
+// Start turns the mode on for the given duration, or sets the remaining time
+// if it is already on
+func (m *Mode) Start(duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("maintenance duration must be positive")
+	}
+	if duration > MaxDuration {
+		return fmt.Errorf("maintenance is limited to %s", MaxDuration)
+	}
+
+	m.mutex.Lock()
+	started := m.timer == nil
+	if !started {
+		m.timer.Stop()
+	}
+	m.until = m.now().Add(duration)
+	m.window++
+	window := m.window
+	m.timer = time.AfterFunc(duration, func() { m.expire(window) })
+	handler := m.handler
+	m.mutex.Unlock()
+
+	m.logger.WithField("duration", duration).Info("Maintenance mode on")
+	if started && handler != nil {
+		handler(true)
+	}
+	return nil
+}
+
+// Stop turns the mode off. Stopping it when it is off does nothing.
+func (m *Mode) Stop() {
+	m.mutex.Lock()
+	if m.timer == nil {
+		m.mutex.Unlock()
+		return
+	}
+	m.timer.Stop()
+	m.timer = nil
+	handler := m.handler
+	m.mutex.Unlock()
+
+	m.logger.Info("Maintenance mode off")
+	if handler != nil {
+		handler(false)
+	}
+}
+
+// expire ends a window, unless it was replaced or stopped since
+func (m *Mode) expire(window int) {
+	m.mutex.Lock()
+	if m.timer == nil || m.window != window {
+		m.mutex.Unlock()
+		return
+	}
+	m.timer = nil
+	handler := m.handler
+	m.mutex.Unlock()
+
+	m.logger.Info("Maintenance window over")
+	if handler != nil {
+		handler(false)
+	}
+}
+
+// Active reports whether the mode is on
+func (m *Mode) Active() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.timer != nil
+}
+
+// Remaining returns how long the mode stays on, 0 when it is off
+func (m *Mode) Remaining() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.timer == nil {
+		return 0
+	}
+	return max(m.until.Sub(m.now()), 0)
+}
+
+// Status describes the mode for the panel and the command line
+func (m *Mode) Status() string {
+	remaining := m.Remaining()
+	if remaining == 0 {
+		return "Maintenance off"
+	}
+	// Started minutes count as a whole one
+	minutes := int((remaining + time.Minute - 1) / time.Minute)
+	return fmt.Sprintf("Maintenance on\n%d min left", minutes)
+}
@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder remembers the transitions a mode reported
+type recorder struct {
+	mutex   sync.Mutex
+	changes []bool
+}
+
+func (r *recorder) record(active bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.changes = append(r.changes, active)
+}
+
+func (r *recorder) seen() []bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]bool(nil), r.changes...)
+}
+
+func TestMode(t *testing.T) {
+	m := NewMode()
+	changes := &recorder{}
+	m.OnChange(changes.record)
+
+	assert.False(t, m.Active())
+	assert.Equal(t, "Maintenance off", m.Status())
+	assert.Error(t, m.Start(0))
+	assert.Error(t, m.Start(MaxDuration+time.Minute))
+
+	require.NoError(t, m.Start(90*time.Minute))
+	assert.True(t, m.Active())
+	assert.Equal(t, "Maintenance on\n90 min left", m.Status())
+
+	// Changing the window is not a transition
+	require.NoError(t, m.Start(time.Hour))
+	assert.InDelta(t, time.Hour, m.Remaining(), float64(time.Second))
+
+	m.Stop()
+	m.Stop()
+	assert.False(t, m.Active())
+	assert.Zero(t, m.Remaining())
+	assert.Equal(t, []bool{true, false}, changes.seen())
+}
+
+func TestMode_Expires(t *testing.T) {
+	m := NewMode()
+	changes := &recorder{}
+	m.OnChange(changes.record)
+
+	require.NoError(t, m.Start(20*time.Millisecond))
+	assert.Eventually(t, func() bool { return !m.Active() }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []bool{true, false}, changes.seen())
+
+	// A replaced window does not end the new one
+	require.NoError(t, m.Start(20*time.Millisecond))
+	require.NoError(t, m.Start(time.Hour))
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, m.Active())
+	m.Stop()
+}
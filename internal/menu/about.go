@@ -0,0 +1,22 @@
+package menu
+
+import (
+	"strings"
+
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/qnap/display-control/internal/version"
+)
+
+// showAbout pages through the version, commit, Go runtime and uptime of the
+// running daemon
+func (ms *MenuSystem) showAbout() {
+	info := version.Get()
+	lines := []string{
+		"Version " + info.Version,
+		"Commit " + info.Revision(),
+		"Go " + strings.TrimPrefix(info.GoVersion, "go"),
+		"Arch " + info.Platform,
+		"Up " + sysinfo.FormatUptime(info.Uptime),
+	}
+	ms.displayPagedOutput(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,35 @@
+package menu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbout(t *testing.T) {
+	defer func(v, commit string) { version.Version, version.Commit = v, commit }(version.Version, version.Commit)
+	version.Version, version.Commit = "0.1.0", "3efb9a7"
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"about": {Title: "About", Type: "display_command", Command: "about"},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	ms.HandleEnterButton()
+	assert.Eventually(t, func() bool {
+		return display.text() == "Version 0.1.0\nPage 1/5"
+	}, time.Second, 10*time.Millisecond, display.text())
+	ms.HandleSelectButton()
+	assert.Eventually(t, func() bool {
+		return display.text() == "Commit 3efb9a7\nPage 2/5"
+	}, time.Second, 10*time.Millisecond, display.text())
+}
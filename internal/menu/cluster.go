@@ -0,0 +1,64 @@
+package menu
+
+import (
+	"context"
+	"time"
+
+	"github.com/qnap/display-control/internal/cluster"
+)
+
+// defaultPeerInterval is how long the cluster dashboard shows each peer when
+// the configuration does not say
+const defaultPeerInterval = 5 * time.Second
+
+// Peers reports the health of other nodes. cluster.Monitor satisfies it.
+type Peers interface {
+	Names() []string
+	Status(name string) (cluster.PeerStatus, error)
+}
+
+// SetPeers sets the nodes the cluster dashboard shows (nil = none)
+func (ms *MenuSystem) SetPeers(peers Peers) {
+	ms.peers = peers
+}
+
+// showClusterDashboard cycles through the peers until a button is pressed
+func (ms *MenuSystem) showClusterDashboard() {
+	if ms.peers == nil || len(ms.peers.Names()) == 0 {
+		ms.displayScrollingOutput("No peers configured")
+		return
+	}
+	ms.startOutput(ms.clusterRoutine)
+}
+
+// clusterRoutine shows one peer after the other, each for the configured
+// interval, until ctx is cancelled
+func (ms *MenuSystem) clusterRoutine(ctx context.Context) {
+	defer ms.finishOutput()
+
+	interval := defaultPeerInterval
+	if ms.config.Cluster.Interval > 0 {
+		interval = time.Duration(ms.config.Cluster.Interval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	width, _ := ms.displayGeometry()
+	names := ms.peers.Names()
+	for next := 0; ; next = (next + 1) % len(names) {
+		text := names[next] + "\nunknown peer"
+		if status, err := ms.peers.Status(names[next]); err == nil {
+			text = status.Render(width)
+		}
+		if err := ms.displayController.WriteText(text); err != nil {
+			ms.logger.WithError(err).Error("Failed to display cluster dashboard")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
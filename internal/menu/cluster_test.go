@@ -0,0 +1,57 @@
+package menu
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/cluster"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedPeers reports statuses that never change
+type fixedPeers []cluster.PeerStatus
+
+func (p fixedPeers) Names() []string {
+	names := make([]string, len(p))
+	for i, status := range p {
+		names[i] = status.Name
+	}
+	return names
+}
+
+func (p fixedPeers) Status(name string) (cluster.PeerStatus, error) {
+	for _, status := range p {
+		if status.Name == name {
+			return status, nil
+		}
+	}
+	return cluster.PeerStatus{}, errors.New("unknown peer")
+}
+
+func TestClusterDashboard(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"cluster": {Title: "Cluster", Type: "display_command", Command: "cluster_dashboard"},
+	}
+	cfg.Menu.Shortcuts = nil
+	cfg.Cluster.Interval = 1
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.SetPeers(fixedPeers{
+		{Name: "nas2", Health: cluster.Health{Load: [3]float64{0.52}, VolumePercent: 81}},
+		{Name: "nas3", Err: errors.New("connection refused")},
+	})
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	ms.HandleEnterButton()
+	assert.Eventually(t, func() bool {
+		return display.text() == "nas2          OK\nLoad 0.52    81%"
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return display.text() == "nas3        down\nnever seen"
+	}, 2*time.Second, 10*time.Millisecond)
+}
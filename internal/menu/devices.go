@@ -0,0 +1,127 @@
+package menu
+
+import (
+	"fmt"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// ejectPrefix starts the display command ejecting the removable device
+// mounted at the rest of the command, e.g. "eject:/media/usb"
+const ejectPrefix = "eject:"
+
+// runEjectCommand unmounts a device, through the broker if ejecting is
+// privileged and one is set
+func (ms *MenuSystem) runEjectCommand(command string, env []string) ([]byte, error) {
+	if ms.config.Storage.EjectPrivileged && ms.broker != nil {
+		return ms.broker.Run(command, env)
+	}
+	return sysinfo.RunShell(command, env)
+}
+
+// openDevicesMenu enters a submenu with an Eject item for every mounted
+// removable device. While it is shown, DevicesChanged keeps the items in
+// step with devices plugged in and pulled.
+func (ms *MenuSystem) openDevicesMenu() {
+	devicesMenu := &config.MenuItem{Title: "Devices", Type: "submenu"}
+	if err := ms.fillDevicesMenu(devicesMenu); err != nil {
+		ms.logger.WithError(err).Error("Failed to list removable devices")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	ms.devicesMenu = devicesMenu
+	ms.navigateToSubmenu(devicesMenu)
+}
+
+// fillDevicesMenu replaces the items of the Devices submenu with the
+// devices mounted now
+func (ms *MenuSystem) fillDevicesMenu(devicesMenu *config.MenuItem) error {
+	devices, err := ms.removable.Devices()
+	if err != nil {
+		return err
+	}
+
+	items := make(map[string]config.MenuItem, len(devices))
+	for _, device := range devices {
+		items[device.Mount] = config.MenuItem{
+			Title:   "Eject " + device.Label,
+			Icon:    "disk",
+			Type:    "display_command",
+			Command: ejectPrefix + device.Mount,
+			Confirm: fmt.Sprintf("Eject %s?", device.Label),
+		}
+	}
+	devicesMenu.Items = items
+	devicesMenu.Description = "Eject device"
+	if len(devices) == 0 {
+		devicesMenu.Description = "No devices"
+	}
+	return nil
+}
+
+// DevicesChanged updates the Devices submenu after a device was plugged in,
+// pulled, mounted or unmounted. The selection stays on the same device if it
+// is still there. Nothing happens unless the submenu is shown.
+func (ms *MenuSystem) DevicesChanged() {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	if !ms.running() || ms.devicesMenu == nil || ms.currentMenu != ms.devicesMenu ||
+		ms.displayingOutput.Load() || ms.pager != nil {
+		return
+	}
+
+	selected := ""
+	if ms.selectedIndex < len(ms.menuKeys) {
+		selected = ms.menuKeys[ms.selectedIndex]
+	}
+	if err := ms.fillDevicesMenu(ms.devicesMenu); err != nil {
+		ms.logger.WithError(err).Warn("Failed to update removable devices")
+		return
+	}
+	ms.selectedIndex = 0
+	ms.updateMenuKeys()
+	for i, key := range ms.menuKeys {
+		if key == selected {
+			ms.selectedIndex = i
+		}
+	}
+	ms.logger.WithField("devices", len(ms.devicesMenu.Items)).Debug("Removable devices updated")
+	if err := ms.displayCurrentMenu(); err != nil {
+		ms.logger.WithError(err).Warn("Failed to update display after device change")
+	}
+}
+
+// ejectDevice flushes and unmounts the removable device mounted at mount and
+// shows that it can be pulled
+func (ms *MenuSystem) ejectDevice(mount string) {
+	if err := ms.displayController.WriteText("Ejecting...\nPlease wait"); err != nil {
+		ms.logger.WithError(err).Warn("Failed to display eject message")
+	}
+
+	err := ms.removable.Eject(mount)
+	if ms.events != nil {
+		fields := events.Fields{"mount": mount, "status": "ok"}
+		if err != nil {
+			fields["status"] = "failed"
+			fields["error"] = err.Error()
+		}
+		ms.events.Record(events.KindCommand, ejectPrefix+mount, fields)
+	}
+	if err != nil {
+		ms.logger.WithError(err).WithField("mount", mount).Error("Failed to eject device")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	ms.logger.WithField("mount", mount).Info("Device ejected")
+
+	// The ejected device leaves the list shown after the message
+	if ms.devicesMenu != nil && ms.currentMenu == ms.devicesMenu {
+		if err := ms.fillDevicesMenu(ms.devicesMenu); err == nil {
+			ms.updateMenuKeys()
+		}
+	}
+	ms.showUntilButton("Ejected\nSafe to remove")
+}
@@ -0,0 +1,164 @@
+package menu
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDevices is a fake sysfs with removable disks and a mount table that
+// the eject command edits like umount would
+type fakeDevices struct {
+	t      *testing.T
+	root   string
+	mounts string
+	// busy makes the eject command fail
+	busy bool
+}
+
+func newFakeDevices(t *testing.T) *fakeDevices {
+	t.Helper()
+	devices := &fakeDevices{t: t, root: t.TempDir(), mounts: filepath.Join(t.TempDir(), "mounts")}
+	require.NoError(t, os.MkdirAll(filepath.Join(devices.root, "class", "block"), 0755))
+	require.NoError(t, os.WriteFile(devices.mounts, []byte("/dev/md0 / ext4 rw 0 0\n"), 0644))
+	return devices
+}
+
+// plug adds a removable disk mounted at mount
+func (d *fakeDevices) plug(name, mount string) {
+	d.t.Helper()
+	dir := filepath.Join(d.root, "devices", "platform", name)
+	require.NoError(d.t, os.MkdirAll(dir, 0755))
+	require.NoError(d.t, os.WriteFile(filepath.Join(dir, "removable"), []byte("1\n"), 0644))
+	require.NoError(d.t, os.Symlink(dir, filepath.Join(d.root, "class", "block", name)))
+	d.editMounts(func(table string) string {
+		return table + "/dev/" + name + " " + mount + " vfat rw 0 0\n"
+	})
+}
+
+// run unmounts $MOUNT from the mount table
+func (d *fakeDevices) run(command string, env []string) ([]byte, error) {
+	if d.busy {
+		return []byte("umount: target is busy"), errors.New("exit status 32")
+	}
+	mount := strings.TrimPrefix(env[0], "MOUNT=")
+	d.editMounts(func(table string) string {
+		var kept []string
+		for _, line := range strings.Split(strings.TrimSpace(table), "\n") {
+			if fields := strings.Fields(line); fields[1] != mount {
+				kept = append(kept, line)
+			}
+		}
+		return strings.Join(kept, "\n") + "\n"
+	})
+	return nil, nil
+}
+
+func (d *fakeDevices) editMounts(edit func(table string) string) {
+	table, err := os.ReadFile(d.mounts)
+	require.NoError(d.t, err)
+	require.NoError(d.t, os.WriteFile(d.mounts, []byte(edit(string(table))), 0644))
+}
+
+// newDevicesTestMenu creates a running menu with a Devices item
+func newDevicesTestMenu(t *testing.T) (*MenuSystem, *lockedDisplay, *fakeDevices) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"devices": {Title: "Devices", Type: "display_command", Command: "devices"},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	devices := newFakeDevices(t)
+	ms.removable = sysinfo.NewRemovableProvider(devices.mounts, devices.root, devices.run)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+	return ms, display, devices
+}
+
+func TestDevices(t *testing.T) {
+	t.Run("Devices follow plugs and ejects", func(t *testing.T) {
+		ms, display, devices := newDevicesTestMenu(t)
+		devices.plug("sdx", "/media/stick")
+
+		ms.HandleEnterButton()
+		assert.Equal(t, []string{"back", "/media/stick"}, ms.menuKeys)
+		assert.Equal(t, "Eject stick", ms.currentMenu.Items["/media/stick"].Title)
+
+		// A second stick appears while the menu is shown; the selection
+		// stays on the first
+		ms.HandleSelectButton()
+		devices.plug("sdy", "/media/camera")
+		ms.DevicesChanged()
+		assert.Equal(t, []string{"back", "/media/camera", "/media/stick"}, ms.menuKeys)
+		assert.Equal(t, "/media/stick", ms.menuKeys[ms.selectedIndex])
+		assert.Contains(t, display.text(), "Eject stick")
+
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return display.text() == "Ejected\nSafe to remove" }, time.Second, 10*time.Millisecond, display.text())
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return !ms.displayingOutput.Load() }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"back", "/media/camera"}, ms.menuKeys)
+	})
+
+	t.Run("Failed eject", func(t *testing.T) {
+		ms, display, devices := newDevicesTestMenu(t)
+		devices.plug("sdx", "/media/stick")
+		devices.busy = true
+
+		ms.HandleEnterButton()
+		ms.HandleSelectButton()
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return strings.Contains(display.text(), "Error") }, time.Second, 10*time.Millisecond, display.text())
+		assert.Contains(t, ms.currentMenu.Items, "/media/stick")
+	})
+
+	t.Run("Changes elsewhere are ignored", func(t *testing.T) {
+		ms, display, devices := newDevicesTestMenu(t)
+		ms.HandleEnterButton()
+		assert.Equal(t, "No devices", ms.currentMenu.Description)
+
+		ms.navigateBack()
+		require.NoError(t, ms.RefreshDisplay())
+		shown := display.text()
+		devices.plug("sdx", "/media/stick")
+		ms.DevicesChanged()
+		assert.Equal(t, shown, display.text())
+		assert.Equal(t, []string{"devices"}, ms.menuKeys)
+	})
+
+	t.Run("Refreshes from the settle timer race no press", func(t *testing.T) {
+		ms, _, devices := newDevicesTestMenu(t)
+		devices.plug("sdx", "/media/stick")
+		devices.plug("sdy", "/media/camera")
+		ms.HandleEnterButton()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				ms.DevicesChanged()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				ms.HandleSelectButton()
+			}
+		}()
+		wg.Wait()
+
+		assert.Equal(t, []string{"Main Menu", "Devices"}, ms.GetCurrentMenuPath())
+	})
+}
@@ -0,0 +1,52 @@
+package menu
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxFileSize is how much of a file a "file" item shows; a panel pages
+// through a few lines at a time, so more would never be read
+const maxFileSize = 16 * 1024
+
+// showFile pages through the text file at path. The file is read on every
+// visit, so a status file kept by another service is shown as it is now.
+func (ms *MenuSystem) showFile(path string) {
+	text, err := readTextFile(path)
+	if err != nil {
+		ms.logger.WithError(err).WithField("path", path).Error("Failed to read file for display")
+		ms.displayPagedOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	ms.logger.WithFields(logrus.Fields{
+		"path":  path,
+		"bytes": len(text),
+	}).Debug("Showing file")
+	if strings.TrimSpace(text) == "" {
+		text = "(empty file)"
+	}
+	ms.displayPagedOutput(text)
+}
+
+// readTextFile reads up to maxFileSize bytes of a file
+func readTextFile(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no file configured")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFileSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,55 @@
+package menu
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileItem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status")
+	require.NoError(t, os.WriteFile(path, []byte("Backup running\r\n42%\n"), 0644))
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_status":  {Title: "Backup", Type: "file", Path: path},
+		"b_missing": {Title: "Missing", Type: "file", Path: filepath.Join(t.TempDir(), "none")},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	waitFor := func(want string) {
+		t.Helper()
+		assert.Eventually(t, func() bool { return display.text() == want },
+			time.Second, 10*time.Millisecond, display.text())
+	}
+
+	ms.HandleEnterButton()
+	waitFor("Backup running\nPage 1/2")
+	ms.HandleSelectButton()
+	waitFor("42%\nPage 2/2")
+	ms.HandleEnterButton()
+
+	t.Run("ReadOnEveryVisit", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("Backup done\n"), 0644))
+		ms.HandleEnterButton()
+		waitFor("Backup done\nPage 1/1")
+		ms.HandleEnterButton()
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		ms.HandleSelectButton()
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return strings.HasPrefix(display.text(), "Error:") },
+			time.Second, 10*time.Millisecond, display.text())
+	})
+}
@@ -0,0 +1,367 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Button identifies a front panel button as seen by the menu system
+type Button string
+
+const (
+	ButtonEnter  Button = "enter"
+	ButtonSelect Button = "select"
+)
+
+const (
+	// defaultMultiPressWindow is the maximum gap between presses of a multi-press gesture
+	defaultMultiPressWindow = 400 * time.Millisecond
+	// defaultLongPressDuration is how long a button must be held for a long press
+	defaultLongPressDuration = time.Second
+)
+
+// Gesture describes a button pattern that can trigger a shortcut
+type Gesture struct {
+	Button  Button
+	Presses int
+	Long    bool
+}
+
+// ParseGesture parses gestures such as "double_select", "triple_enter" or "long_enter"
+func ParseGesture(text string) (Gesture, error) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(text)), "_", 2)
+	if len(parts) != 2 {
+		return Gesture{}, fmt.Errorf("invalid gesture %q: expected <pattern>_<button>", text)
+	}
+
+	var gesture Gesture
+	switch Button(parts[1]) {
+	case ButtonEnter, ButtonSelect:
+		gesture.Button = Button(parts[1])
+	default:
+		return Gesture{}, fmt.Errorf("invalid gesture %q: unknown button %q", text, parts[1])
+	}
+
+	switch parts[0] {
+	case "long":
+		gesture.Presses = 1
+		gesture.Long = true
+	case "double":
+		gesture.Presses = 2
+	case "triple":
+		gesture.Presses = 3
+	case "quadruple":
+		gesture.Presses = 4
+	default:
+		return Gesture{}, fmt.Errorf("invalid gesture %q: unknown pattern %q", text, parts[0])
+	}
+
+	return gesture, nil
+}
+
+// String returns the configuration form of the gesture
+func (g Gesture) String() string {
+	if g.Long {
+		return "long_" + string(g.Button)
+	}
+	names := map[int]string{2: "double", 3: "triple", 4: "quadruple"}
+	return names[g.Presses] + "_" + string(g.Button)
+}
+
+// gestureDetector recognizes shortcut gestures at the root menu.
+//
+// SELECT presses are handled immediately even while a gesture is being
+// entered, since moving the selection is harmless and the shortcut starts
+// from the root anyway. ENTER presses are held back until it is clear that
+// they are not part of a gesture, so a triple press never runs the selected
+// command. Buttons without configured gestures are never delayed.
+type gestureDetector struct {
+	// shortcuts maps gestures to the target paths or macros they run;
+	// macros are bound while the menu runs, so it is guarded by the mutex
+	shortcuts        map[Gesture]string
+	multiPressWindow time.Duration
+	longPressTime    time.Duration
+
+	mutex      sync.Mutex
+	active     bool
+	button     Button
+	presses    int
+	held       bool
+	longFired  bool
+	generation int
+	timer      *time.Timer
+
+	// Callbacks into the menu system, invoked without the mutex held
+	dispatch func(button Button)
+	trigger  func(gesture Gesture, target string)
+	atRoot   func() bool
+	logger   *logrus.Logger
+}
+
+// newGestureDetector builds a detector from the configured shortcuts
+func newGestureDetector(shortcuts []config.ShortcutConfig, logger *logrus.Logger) *gestureDetector {
+	gd := &gestureDetector{
+		shortcuts:        make(map[Gesture]string),
+		multiPressWindow: defaultMultiPressWindow,
+		longPressTime:    defaultLongPressDuration,
+		logger:           logger,
+	}
+
+	for _, shortcut := range shortcuts {
+		gesture, err := ParseGesture(shortcut.Gesture)
+		if err != nil {
+			logger.WithError(err).Warn("Ignoring invalid menu shortcut")
+			continue
+		}
+		switch {
+		case shortcut.Macro != "":
+			gd.shortcuts[gesture] = macroTargetPrefix + shortcut.Macro
+		case shortcut.Target != "":
+			gd.shortcuts[gesture] = shortcut.Target
+		default:
+			logger.WithField("gesture", shortcut.Gesture).Warn("Ignoring menu shortcut without target")
+		}
+	}
+
+	return gd
+}
+
+// bind adds a shortcut while the menu runs. A gesture already bound to
+// another target is not rebound.
+func (gd *gestureDetector) bind(gesture Gesture, target string) error {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	if existing, ok := gd.shortcuts[gesture]; ok && existing != target {
+		return fmt.Errorf("%s already runs %q", gesture, strings.TrimPrefix(existing, macroTargetPrefix))
+	}
+	gd.shortcuts[gesture] = target
+	return nil
+}
+
+// target returns what a gesture is bound to
+func (gd *gestureDetector) target(gesture Gesture) (string, bool) {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	target, ok := gd.shortcuts[gesture]
+	return target, ok
+}
+
+// unbind removes the shortcuts to a target
+func (gd *gestureDetector) unbind(target string) {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	for gesture, existing := range gd.shortcuts {
+		if existing == target {
+			delete(gd.shortcuts, gesture)
+		}
+	}
+}
+
+// hasGestures reports whether any shortcut uses the button. Caller must hold
+// the mutex.
+func (gd *gestureDetector) hasGestures(button Button) bool {
+	for gesture := range gd.shortcuts {
+		if gesture.Button == button {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLongGesture reports whether a long press shortcut exists for the button.
+// Caller must hold the mutex.
+func (gd *gestureDetector) hasLongGesture(button Button) bool {
+	_, exists := gd.shortcuts[Gesture{Button: button, Presses: 1, Long: true}]
+	return exists
+}
+
+// expectsMorePresses reports whether a multi-press shortcut needs more than
+// count presses. Caller must hold the mutex.
+func (gd *gestureDetector) expectsMorePresses(button Button, count int) bool {
+	for gesture := range gd.shortcuts {
+		if gesture.Button == button && !gesture.Long && gesture.Presses > count {
+			return true
+		}
+	}
+	return false
+}
+
+// handleEvent feeds a raw press or release into the detector
+func (gd *gestureDetector) handleEvent(button Button, pressed bool) {
+	gd.mutex.Lock()
+	gestures := gd.hasGestures(button)
+	gd.mutex.Unlock()
+
+	if !gestures {
+		gd.finishOther(button)
+		if pressed {
+			gd.dispatch(button)
+		}
+		return
+	}
+
+	if pressed {
+		gd.handlePress(button)
+	} else {
+		gd.handleRelease(button)
+	}
+}
+
+// finishOther resolves a pending gesture of another button before handling a new press
+func (gd *gestureDetector) finishOther(button Button) {
+	gd.mutex.Lock()
+	if !gd.active || gd.button == button {
+		gd.mutex.Unlock()
+		return
+	}
+	gd.resolveLocked()
+}
+
+// handlePress starts or extends a gesture sequence
+func (gd *gestureDetector) handlePress(button Button) {
+	gd.finishOther(button)
+
+	// atRoot takes the menu's navigation lock, which is never taken while
+	// holding the detector's
+	atRoot := gd.atRoot()
+	gd.mutex.Lock()
+	if !gd.active {
+		if !atRoot {
+			gd.mutex.Unlock()
+			gd.dispatch(button)
+			return
+		}
+		gd.active = true
+		gd.button = button
+		gd.presses = 0
+		gd.longFired = false
+	}
+
+	gd.presses++
+	gd.held = true
+	gd.generation++
+	gd.stopTimerLocked()
+
+	if gd.hasLongGesture(button) {
+		generation := gd.generation
+		gd.timer = time.AfterFunc(gd.longPressTime, func() { gd.longPressElapsed(generation) })
+	}
+	gd.mutex.Unlock()
+
+	// SELECT acts immediately; ENTER waits for the gesture to resolve
+	if button == ButtonSelect {
+		gd.dispatch(button)
+	}
+}
+
+// handleRelease ends a press and decides whether to wait for more presses
+func (gd *gestureDetector) handleRelease(button Button) {
+	gd.mutex.Lock()
+	if !gd.active || gd.button != button || !gd.held {
+		gd.mutex.Unlock()
+		return
+	}
+
+	gd.held = false
+	gd.generation++
+	gd.stopTimerLocked()
+
+	if gd.longFired {
+		gd.resetLocked()
+		gd.mutex.Unlock()
+		return
+	}
+
+	if gd.expectsMorePresses(button, gd.presses) {
+		generation := gd.generation
+		gd.timer = time.AfterFunc(gd.multiPressWindow, func() { gd.windowElapsed(generation) })
+		gd.mutex.Unlock()
+		return
+	}
+
+	gd.resolveLocked()
+}
+
+// longPressElapsed fires the long press shortcut if the button is still held
+func (gd *gestureDetector) longPressElapsed(generation int) {
+	gd.mutex.Lock()
+	if generation != gd.generation || !gd.active || !gd.held {
+		gd.mutex.Unlock()
+		return
+	}
+
+	gd.longFired = true
+	gd.timer = nil
+	gesture := Gesture{Button: gd.button, Presses: 1, Long: true}
+	target := gd.shortcuts[gesture]
+	gd.mutex.Unlock()
+
+	gd.trigger(gesture, target)
+}
+
+// windowElapsed resolves the sequence once no further press arrived in time
+func (gd *gestureDetector) windowElapsed(generation int) {
+	gd.mutex.Lock()
+	if generation != gd.generation || !gd.active {
+		gd.mutex.Unlock()
+		return
+	}
+	gd.timer = nil
+	gd.resolveLocked()
+}
+
+// resolveLocked ends the current sequence, firing a matching shortcut or
+// replaying held back ENTER presses. It must be called with the mutex held
+// and releases it.
+func (gd *gestureDetector) resolveLocked() {
+	gesture := Gesture{Button: gd.button, Presses: gd.presses}
+	target, matched := gd.shortcuts[gesture]
+	gd.stopTimerLocked()
+	gd.resetLocked()
+	gd.mutex.Unlock()
+
+	if matched && gesture.Presses > 1 {
+		gd.trigger(gesture, target)
+		return
+	}
+
+	if gesture.Button == ButtonEnter {
+		for i := 0; i < gesture.Presses; i++ {
+			gd.dispatch(ButtonEnter)
+		}
+	}
+}
+
+// stop abandons any sequence in progress so no timer fires after the menu stopped
+func (gd *gestureDetector) stop() {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	gd.stopTimerLocked()
+	gd.resetLocked()
+	gd.generation++
+}
+
+// resetLocked clears the sequence state. Caller must hold the mutex.
+func (gd *gestureDetector) resetLocked() {
+	gd.active = false
+	gd.presses = 0
+	gd.held = false
+	gd.longFired = false
+}
+
+// stopTimerLocked cancels a pending timer. Caller must hold the mutex.
+func (gd *gestureDetector) stopTimerLocked() {
+	if gd.timer != nil {
+		gd.timer.Stop()
+		gd.timer = nil
+	}
+}
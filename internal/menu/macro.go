@@ -0,0 +1,407 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/sirupsen/logrus"
+)
+
+// macroTargetPrefix starts shortcut targets and display commands that replay
+// the macro named by the rest, e.g. "macro:vol1-usage"
+const macroTargetPrefix = "macro:"
+
+// macroRecordCommand is the display command that asks for a name and starts
+// recording a macro
+const macroRecordCommand = "macro_record"
+
+const (
+	// maxMacroButtons ends a recording that was forgotten
+	maxMacroButtons = 100
+	// macroStepDelay is the pause before each replayed press, so the panel
+	// can be followed and command output has started
+	macroStepDelay = 200 * time.Millisecond
+)
+
+// MacroStore keeps the recorded macros. state.Macros satisfies it.
+type MacroStore interface {
+	List() ([]state.Macro, error)
+	Get(name string) (state.Macro, bool, error)
+	Save(macro state.Macro) error
+	Delete(name string) (bool, error)
+}
+
+// SetMacros sets where macros are kept (nil = no macros) and binds the
+// gestures of the stored ones
+func (ms *MenuSystem) SetMacros(store MacroStore) {
+	ms.macros = store
+	if store == nil {
+		return
+	}
+
+	macros, err := store.List()
+	if err != nil {
+		ms.logger.WithError(err).Warn("Failed to read macros")
+		return
+	}
+	for _, macro := range macros {
+		if err := ms.bindMacro(macro); err != nil {
+			ms.logger.WithError(err).WithField("macro", macro.Name).Warn("Macro gesture not bound")
+		}
+	}
+}
+
+// Macros returns the recorded macros sorted by name
+func (ms *MenuSystem) Macros() ([]state.Macro, error) {
+	if ms.macros == nil {
+		return nil, errors.New("macros are not kept")
+	}
+	return ms.macros.List()
+}
+
+// StartRecording returns to the main menu and records the following button
+// presses as a macro of the given name, optionally replayed by a gesture
+// such as "long_select". Holding a button ends the recording, as does
+// StopRecording.
+func (ms *MenuSystem) StartRecording(name, gesture string) error {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	return ms.startRecording(name, gesture)
+}
+
+// startRecording starts recording a macro
+func (ms *MenuSystem) startRecording(name, gesture string) error {
+	if ms.macros == nil {
+		return errors.New("macros are not kept")
+	}
+	if name == "" {
+		return errors.New("a macro needs a name")
+	}
+	if gesture != "" {
+		parsed, err := ParseGesture(gesture)
+		if err != nil {
+			return err
+		}
+		if target, ok := ms.gestures.target(parsed); ok && target != macroTargetPrefix+name {
+			return fmt.Errorf("%s is already bound", parsed)
+		}
+		gesture = parsed.String()
+	}
+	if err := ms.recorder.start(state.Macro{Name: name, Gesture: gesture}); err != nil {
+		return err
+	}
+
+	ms.logger.WithField("macro", name).Info("Recording macro")
+	ms.pager = nil
+	ms.stopOutputDisplay()
+	ms.resetToRoot()
+	if err := ms.displayCurrentMenu(); err != nil {
+		ms.logger.WithError(err).Warn("Failed to update display for macro recording")
+	}
+	return nil
+}
+
+// StopRecording ends the recording and saves the macro
+func (ms *MenuSystem) StopRecording() (state.Macro, error) {
+	macro, ok := ms.recorder.stop()
+	if !ok {
+		return state.Macro{}, errors.New("no macro is being recorded")
+	}
+	return macro, ms.saveMacro(macro)
+}
+
+// saveMacro keeps a recorded macro and binds its gesture
+func (ms *MenuSystem) saveMacro(macro state.Macro) error {
+	if len(macro.Buttons) == 0 {
+		ms.logger.WithField("macro", macro.Name).Info("Empty macro not saved")
+		return errors.New("no buttons were pressed")
+	}
+	if err := ms.macros.Save(macro); err != nil {
+		return fmt.Errorf("failed to save macro: %w", err)
+	}
+	ms.gestures.unbind(macroTargetPrefix + macro.Name)
+	if err := ms.bindMacro(macro); err != nil {
+		return err
+	}
+
+	ms.logger.WithFields(logrus.Fields{
+		"macro":   macro.Name,
+		"buttons": len(macro.Buttons),
+		"gesture": macro.Gesture,
+	}).Info("Macro recorded")
+	return nil
+}
+
+// bindMacro lets the macro's gesture replay it
+func (ms *MenuSystem) bindMacro(macro state.Macro) error {
+	if macro.Gesture == "" {
+		return nil
+	}
+	gesture, err := ParseGesture(macro.Gesture)
+	if err != nil {
+		return err
+	}
+	return ms.gestures.bind(gesture, macroTargetPrefix+macro.Name)
+}
+
+// DeleteMacro removes a macro and its gesture
+func (ms *MenuSystem) DeleteMacro(name string) error {
+	if ms.macros == nil {
+		return errors.New("macros are not kept")
+	}
+	deleted, err := ms.macros.Delete(name)
+	if err != nil {
+		return fmt.Errorf("failed to delete macro: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("no macro %q", name)
+	}
+	ms.gestures.unbind(macroTargetPrefix + name)
+	return nil
+}
+
+// RunMacro returns to the main menu and replays a macro's presses in the
+// background
+func (ms *MenuSystem) RunMacro(name string) error {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	return ms.runMacro(name)
+}
+
+// runMacro starts replaying a macro
+func (ms *MenuSystem) runMacro(name string) error {
+	if ms.macros == nil {
+		return errors.New("macros are not kept")
+	}
+	if ms.recorder.active() {
+		return errors.New("a macro is being recorded")
+	}
+	macro, ok, err := ms.macros.Get(name)
+	if err != nil {
+		return fmt.Errorf("failed to read macro: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("no macro %q", name)
+	}
+
+	ms.logger.WithField("macro", name).Info("Replaying macro")
+	ms.resetToRoot()
+	if err := ms.displayCurrentMenu(); err != nil {
+		ms.logger.WithError(err).Warn("Failed to update display for macro")
+	}
+	ms.goRoutine(func(ctx context.Context) {
+		for _, button := range macro.Buttons {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(macroStepDelay):
+			}
+			ms.dispatchButton(Button(button))
+		}
+	})
+	return nil
+}
+
+// openMacroMenu enters a submenu replaying the recorded macros, below an item
+// recording a new one
+func (ms *MenuSystem) openMacroMenu() {
+	if ms.macros == nil {
+		ms.displayScrollingOutput("No macros kept")
+		return
+	}
+	macros, err := ms.macros.List()
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to read macros")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	macroMenu := &config.MenuItem{
+		Title:       "Macros",
+		Description: "Replay buttons",
+		Type:        "submenu",
+		Items:       make(map[string]config.MenuItem, len(macros)+1),
+	}
+	// Keys keep the record item first and the macros sorted by name
+	macroMenu.Items["0"] = config.MenuItem{
+		Title:   "Record new",
+		Type:    "display_command",
+		Command: macroRecordCommand,
+	}
+	for i, macro := range macros {
+		macroMenu.Items[fmt.Sprintf("1%03d", i)] = config.MenuItem{
+			Title:   macro.Name,
+			Type:    "display_command",
+			Command: macroTargetPrefix + macro.Name,
+		}
+	}
+	ms.navigateToSubmenu(macroMenu)
+}
+
+// recordMacro asks for a name and starts recording
+func (ms *MenuSystem) recordMacro() {
+	name, ok := ms.readInput("Macro name", "name")
+	if !ok {
+		return
+	}
+	if err := ms.startRecording(name, ""); err != nil {
+		ms.logger.WithError(err).Error("Failed to start recording macro")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// finishRecording saves the macro a long press ended and says so, over any
+// output the recorded presses left on the panel
+func (ms *MenuSystem) finishRecording(macro state.Macro) {
+	ms.navMutex.Lock()
+	ms.pager = nil
+	ms.navMutex.Unlock()
+	ms.stopOutputDisplay()
+	// The output routine redraws the menu as it ends, which must not cover
+	// the message. It needs the navigation lock to do so, so the wait is
+	// done without it.
+	for waited := time.Duration(0); ms.displayingOutput.Load() && waited < time.Second; waited += 10 * time.Millisecond {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	if err := ms.saveMacro(macro); err != nil {
+		ms.logger.WithError(err).WithField("macro", macro.Name).Warn("Macro not saved")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	ms.showUntilButton(fmt.Sprintf("Macro saved\n%s, %d presses", macro.Name, len(macro.Buttons)))
+}
+
+// macroRecorder collects the presses of a macro being recorded. A press is
+// performed and recorded on its release; holding a button for the long press
+// time ends the recording instead.
+type macroRecorder struct {
+	menu *MenuSystem
+
+	mutex sync.Mutex
+	// macro is the macro being recorded, nil when none is
+	macro      *state.Macro
+	held       Button
+	generation int
+	timer      *time.Timer
+}
+
+// newMacroRecorder creates a recorder for the menu
+func newMacroRecorder(menu *MenuSystem) *macroRecorder {
+	return &macroRecorder{menu: menu}
+}
+
+// active reports whether a macro is being recorded
+func (r *macroRecorder) active() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.macro != nil
+}
+
+// start begins recording a macro
+func (r *macroRecorder) start(macro state.Macro) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.macro != nil {
+		return fmt.Errorf("macro %q is being recorded", r.macro.Name)
+	}
+	r.macro = &macro
+	r.held = ""
+	return nil
+}
+
+// stop ends the recording and returns the macro. It reports false if none
+// was being recorded.
+func (r *macroRecorder) stop() (state.Macro, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.stopLocked()
+}
+
+// stopLocked ends the recording. Caller must hold the mutex.
+func (r *macroRecorder) stopLocked() (state.Macro, bool) {
+	if r.macro == nil {
+		return state.Macro{}, false
+	}
+	macro := *r.macro
+	r.macro = nil
+	r.held = ""
+	r.generation++
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	return macro, true
+}
+
+// record takes a press or release while recording. It reports false, to
+// leave the event to the gestures, when no macro is being recorded.
+func (r *macroRecorder) record(button Button, pressed bool) bool {
+	r.mutex.Lock()
+	if r.macro == nil {
+		r.mutex.Unlock()
+		return false
+	}
+
+	if pressed {
+		r.held = button
+		r.generation++
+		generation := r.generation
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		r.timer = time.AfterFunc(r.menu.gestures.longPressTime, func() { r.longPressElapsed(generation) })
+		r.mutex.Unlock()
+		return true
+	}
+
+	if r.held != button {
+		r.mutex.Unlock()
+		return true
+	}
+	r.held = ""
+	r.generation++
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.macro.Buttons = append(r.macro.Buttons, string(button))
+	var full *state.Macro
+	if len(r.macro.Buttons) >= maxMacroButtons {
+		macro, _ := r.stopLocked()
+		full = &macro
+	}
+	r.mutex.Unlock()
+
+	r.menu.dispatchButton(button)
+	if full != nil {
+		r.menu.finishRecording(*full)
+	}
+	return true
+}
+
+// longPressElapsed ends the recording if the button is still held
+func (r *macroRecorder) longPressElapsed(generation int) {
+	r.mutex.Lock()
+	if generation != r.generation || r.held == "" {
+		r.mutex.Unlock()
+		return
+	}
+	macro, _ := r.stopLocked()
+	r.mutex.Unlock()
+
+	r.menu.finishRecording(macro)
+}
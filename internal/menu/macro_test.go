@@ -0,0 +1,127 @@
+package menu
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMacroMenu returns a running menu keeping macros in macros, or in a
+// temporary store if nil
+func newMacroMenu(t *testing.T, macros *state.Macros) (*MenuSystem, *lockedDisplay, *state.Macros) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"alpha": {Title: "Alpha", Type: "display_command", Command: "macros"},
+		"storage": {
+			Title: "Storage",
+			Type:  "submenu",
+			Items: map[string]config.MenuItem{
+				"ip": {
+					Title:      "Show IP",
+					Type:       "command",
+					Command:    "echo 192.168.1.10",
+					OutputMode: config.OutputModePaged,
+				},
+			},
+		},
+	}
+	if macros == nil {
+		store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err)
+		macros = state.NewMacros(store)
+	}
+
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.gestures.longPressTime = 50 * time.Millisecond
+	ms.SetMacros(macros)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+	return ms, display, macros
+}
+
+// click presses and releases a button
+func click(ms *MenuSystem, button Button) {
+	ms.HandleButtonEvent(button, true)
+	ms.HandleButtonEvent(button, false)
+}
+
+func TestMacros(t *testing.T) {
+	t.Run("Record and replay with a gesture", func(t *testing.T) {
+		ms, display, macros := newMacroMenu(t, nil)
+
+		require.NoError(t, ms.StartRecording("ip", "long_select"))
+		assert.Error(t, ms.StartRecording("other", ""), "one recording at a time")
+
+		// Storage > Show IP
+		click(ms, ButtonSelect)
+		click(ms, ButtonEnter)
+		click(ms, ButtonSelect)
+		click(ms, ButtonEnter)
+		require.NotNil(t, ms.pager)
+		assert.Equal(t, "192.168.1.10\nPage 1/1", display.text())
+
+		// Holding a button ends the recording
+		ms.HandleButtonEvent(ButtonEnter, true)
+		assert.Eventually(t, func() bool { return !ms.recorder.active() }, time.Second, 5*time.Millisecond)
+		assert.Eventually(t, func() bool { return display.text() == "Macro saved\nip, 4 presses" },
+			time.Second, 5*time.Millisecond)
+		ms.HandleButtonEvent(ButtonEnter, false)
+
+		macro, ok, err := macros.Get("ip")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, state.Macro{
+			Name:    "ip",
+			Buttons: []string{"select", "enter", "select", "enter"},
+			Gesture: "long_select",
+		}, macro)
+
+		// After a restart the gesture replays it from the main menu
+		ms.Stop()
+		ms, display, _ = newMacroMenu(t, macros)
+		ms.HandleButtonEvent(ButtonSelect, true)
+		assert.Eventually(t, func() bool { return display.text() == "192.168.1.10\nPage 1/1" },
+			2*time.Second, 10*time.Millisecond, display.text())
+		ms.HandleButtonEvent(ButtonSelect, false)
+	})
+
+	t.Run("Menu and deletion", func(t *testing.T) {
+		ms, _, macros := newMacroMenu(t, nil)
+		require.NoError(t, macros.Save(state.Macro{Name: "ip", Buttons: []string{"select"}, Gesture: "double_enter"}))
+		ms.SetMacros(macros)
+		target, ok := ms.gestures.target(Gesture{Button: ButtonEnter, Presses: 2})
+		require.True(t, ok, "stored gestures are bound")
+		assert.Equal(t, "macro:ip", target)
+		assert.Error(t, ms.StartRecording("other", "double_enter"), "the gesture is taken")
+
+		ms.HandleEnterButton()
+		require.Equal(t, []string{"back", "0", "1000"}, ms.menuKeys)
+		assert.Equal(t, "Record new", ms.currentMenu.Items["0"].Title)
+		assert.Equal(t, "ip", ms.currentMenu.Items["1000"].Title)
+
+		require.NoError(t, ms.DeleteMacro("ip"))
+		assert.Error(t, ms.DeleteMacro("ip"))
+		_, ok = ms.gestures.target(Gesture{Button: ButtonEnter, Presses: 2})
+		assert.False(t, ok)
+		assert.Error(t, ms.RunMacro("ip"))
+	})
+
+	t.Run("Nothing recorded", func(t *testing.T) {
+		ms, _, _ := newMacroMenu(t, nil)
+
+		require.NoError(t, ms.StartRecording("empty", ""))
+		_, err := ms.StopRecording()
+		assert.Error(t, err)
+		_, err = ms.StopRecording()
+		assert.Error(t, err)
+	})
+}
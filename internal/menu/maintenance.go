@@ -0,0 +1,159 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/events"
+)
+
+// maintenancePrefix starts the display command setting maintenance mode,
+// followed by a duration such as "2h" or "off" to end it, e.g.
+// "maintenance:2h"
+const maintenancePrefix = "maintenance:"
+
+// maintenanceOff ends maintenance mode
+const maintenanceOff = "off"
+
+// restartPanelCommand is the display command restarting the panel, the LEDs
+// and the copy button monitor without restarting the service
+const restartPanelCommand = "restart_panel"
+
+// maintenanceWindows are the durations offered on the panel
+var maintenanceWindows = []struct {
+	title    string
+	duration time.Duration
+}{
+	{"30 minutes", 30 * time.Minute},
+	{"1 hour", time.Hour},
+	{"2 hours", 2 * time.Hour},
+	{"4 hours", 4 * time.Hour},
+}
+
+// Maintenance is the maintenance mode. maintenance.Mode satisfies it.
+type Maintenance interface {
+	Start(duration time.Duration) error
+	Stop()
+	Active() bool
+	Status() string
+}
+
+// SetMaintenance sets the maintenance mode the menu switches (nil = no
+// maintenance mode)
+func (ms *MenuSystem) SetMaintenance(maintenance Maintenance) {
+	ms.maintenance = maintenance
+}
+
+// SetPanelRestart sets the function reopening the panel, the LEDs and the
+// copy button monitor, offered at the end of the maintenance menu (nil = not
+// offered)
+func (ms *MenuSystem) SetPanelRestart(restart func() error) {
+	ms.panelRestart = restart
+}
+
+// openMaintenanceMenu enters a submenu starting a maintenance window, with
+// an item ending it while one is active and one restarting the panel
+func (ms *MenuSystem) openMaintenanceMenu() {
+	if ms.maintenance == nil {
+		ms.displayScrollingOutput("No maintenance mode")
+		return
+	}
+
+	description := "Hold alerts for"
+	maintenanceMenu := &config.MenuItem{
+		Title: "Maintenance",
+		Type:  "submenu",
+		Items: make(map[string]config.MenuItem, len(maintenanceWindows)+1),
+	}
+	if ms.maintenance.Active() {
+		description = "Extend to"
+		// Sorts before the windows
+		maintenanceMenu.Items["0"] = config.MenuItem{
+			Title:   "End maintenance",
+			Type:    "display_command",
+			Command: maintenancePrefix + maintenanceOff,
+		}
+	}
+	maintenanceMenu.Description = description
+	for i, window := range maintenanceWindows {
+		maintenanceMenu.Items[fmt.Sprintf("%d", i+1)] = config.MenuItem{
+			Title:   window.title,
+			Icon:    "wrench",
+			Type:    "display_command",
+			Command: fmt.Sprintf("%s%dm", maintenancePrefix, int(window.duration.Minutes())),
+		}
+	}
+	if ms.panelRestart != nil {
+		maintenanceMenu.Items[fmt.Sprintf("%d", len(maintenanceWindows)+1)] = config.MenuItem{
+			Title:   "Restart panel",
+			Icon:    "wrench",
+			Type:    "display_command",
+			Command: restartPanelCommand,
+		}
+	}
+	ms.navigateToSubmenu(maintenanceMenu)
+}
+
+// restartPanel reopens the panel, the LEDs and the copy button monitor and
+// shows the result until a button is pressed. The restart runs outside the
+// button handler, since the panel delivering the press is closed by it.
+func (ms *MenuSystem) restartPanel() {
+	if ms.panelRestart == nil {
+		ms.displayScrollingOutput(fmt.Sprintf("Error: Unknown command '%s'", restartPanelCommand))
+		return
+	}
+
+	ms.startOutput(func(ctx context.Context) {
+		defer ms.finishOutput()
+
+		if err := ms.displayController.WriteText("Restarting\npanel..."); err != nil {
+			ms.logger.WithError(err).Warn("Failed to display restart message")
+		}
+		err := ms.panelRestart()
+		if ms.events != nil {
+			fields := events.Fields{"status": "ok"}
+			if err != nil {
+				fields["status"] = "failed"
+				fields["error"] = err.Error()
+			}
+			ms.events.Record(events.KindCommand, restartPanelCommand, fields)
+		}
+
+		text := "Panel restarted"
+		if err != nil {
+			ms.logger.WithError(err).Error("Failed to restart panel")
+			text = "Restart failed\nSee the log"
+		}
+		if err := ms.displayController.WriteText(text); err != nil {
+			ms.logger.WithError(err).Error("Failed to display output")
+			return
+		}
+		<-ctx.Done()
+	})
+}
+
+// setMaintenance starts a maintenance window of the given duration, or ends
+// it for "off", and shows the resulting state until a button is pressed
+func (ms *MenuSystem) setMaintenance(spec string) {
+	if ms.maintenance == nil {
+		ms.displayScrollingOutput(fmt.Sprintf("Error: Unknown command '%s%s'", maintenancePrefix, spec))
+		return
+	}
+
+	if spec == maintenanceOff {
+		ms.maintenance.Stop()
+	} else {
+		duration, err := time.ParseDuration(spec)
+		if err == nil {
+			err = ms.maintenance.Start(duration)
+		}
+		if err != nil {
+			ms.logger.WithError(err).Error("Failed to start maintenance mode")
+			ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+			return
+		}
+	}
+	ms.showUntilButton(ms.maintenance.Status())
+}
@@ -0,0 +1,98 @@
+package menu
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/maintenance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMaintenanceTestMenu starts a menu whose only item opens the maintenance
+// menu
+func newMaintenanceTestMenu(t *testing.T, mode Maintenance) (*MenuSystem, *lockedDisplay) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"maintenance": {Title: "Maintenance", Type: "display_command", Command: "maintenance"},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.SetMaintenance(mode)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+	return ms, display
+}
+
+func TestMaintenanceMenu(t *testing.T) {
+	mode := maintenance.NewMode()
+	defer mode.Stop()
+
+	t.Run("Start a window", func(t *testing.T) {
+		ms, display := newMaintenanceTestMenu(t, mode)
+
+		// Back and the four windows
+		ms.HandleEnterButton()
+		require.Len(t, ms.menuKeys, 5)
+		assert.Equal(t, "30 minutes", ms.currentMenu.Items[ms.menuKeys[1]].Title)
+
+		ms.HandleSelectButton()
+		ms.HandleSelectButton()
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return display.text() == "Maintenance on\n60 min left" },
+			time.Second, 10*time.Millisecond, display.text())
+		assert.True(t, mode.Active())
+	})
+
+	t.Run("End it", func(t *testing.T) {
+		ms, _ := newMaintenanceTestMenu(t, mode)
+
+		ms.HandleEnterButton()
+		require.Len(t, ms.menuKeys, 6)
+		assert.Equal(t, "Extend to", ms.currentMenu.Description)
+		assert.Equal(t, "End maintenance", ms.currentMenu.Items[ms.menuKeys[1]].Title)
+
+		ms.HandleSelectButton()
+		ms.HandleEnterButton()
+		assert.False(t, mode.Active())
+	})
+}
+
+func TestMaintenanceMenu_RestartPanel(t *testing.T) {
+	mode := maintenance.NewMode()
+	defer mode.Stop()
+
+	ms, display := newMaintenanceTestMenu(t, mode)
+	restarts := 0
+	failure := error(nil)
+	ms.SetPanelRestart(func() error {
+		restarts++
+		return failure
+	})
+
+	// Back, the four windows and the restart
+	ms.HandleEnterButton()
+	require.Len(t, ms.menuKeys, 6)
+	assert.Equal(t, "Restart panel", ms.currentMenu.Items[ms.menuKeys[5]].Title)
+
+	for i := 0; i < 5; i++ {
+		ms.HandleSelectButton()
+	}
+	ms.HandleEnterButton()
+	assert.Eventually(t, func() bool { return display.text() == "Panel restarted" },
+		time.Second, 10*time.Millisecond, display.text())
+	assert.Equal(t, 1, restarts)
+
+	// Any button returns to the menu, where the restart can be tried again
+	ms.HandleSelectButton()
+	failure = errors.New("no such device")
+	assert.Eventually(t, func() bool { return !ms.displayingOutput.Load() }, time.Second, 10*time.Millisecond)
+	ms.HandleEnterButton()
+	assert.Eventually(t, func() bool { return display.text() == "Restart failed\nSee the log" },
+		time.Second, 10*time.Millisecond, display.text())
+}
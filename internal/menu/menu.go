@@ -1,17 +1,27 @@
 package menu
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sysinfo"
 	"github.com/sirupsen/logrus"
 )
 
-// DisplayController interface for menu system
+// DisplayController is the narrow display interface the menu system depends on.
+// controller.DisplayControllerInterface satisfies it, and tests can substitute
+// MockDisplayController.
 type DisplayController interface {
 	WriteTextAt(text string, row, col int) error
 	WriteText(text string) error
@@ -19,6 +29,51 @@ type DisplayController interface {
 	SetBacklight(on bool) error
 }
 
+// Compile-time check that the controller's display satisfies the interface
+var _ DisplayController = controller.DisplayControllerInterface(nil)
+
+// Prompter asks a question or reads text on the display and waits for the
+// answer. prompt.Prompter satisfies it.
+type Prompter interface {
+	Prompt(ctx context.Context, text string, options ...string) (int, error)
+	Input(ctx context.Context, label string, charset string) (string, error)
+}
+
+// Broker runs commands marked privileged in a helper that keeps root while
+// the service runs unprivileged. broker.Client satisfies it.
+type Broker interface {
+	Run(command string, env []string) ([]byte, error)
+}
+
+// EventLog records the commands the menu runs and its navigation.
+// events.Log satisfies it.
+type EventLog interface {
+	Record(kind events.Kind, message string, fields events.Fields)
+}
+
+// sizedDisplay is a display that knows its own size, such as a screen layer
+// confined to a region of the panel
+type sizedDisplay interface {
+	Size() (width, height int)
+}
+
+// confirmTimeout is how long a confirmation question waits for an answer
+const confirmTimeout = 15 * time.Second
+
+// inputTimeout is how long text input may take in total
+const inputTimeout = 3 * time.Minute
+
+// cpuStatusRefresh is how often the CPU status screen is redrawn
+const cpuStatusRefresh = time.Second
+
+// outputScrollSpeed is how long each step of output scrolling through the
+// first line is shown
+const outputScrollSpeed = controller.DefaultScrollSpeed
+
+// storageVolumePrefix starts the display command showing the volume mounted
+// at the rest of the command, e.g. "storage_volume:/mnt/pool"
+const storageVolumePrefix = "storage_volume:"
+
 // MenuSystem manages the menu navigation and display
 type MenuSystem struct {
 	config         *config.Config
@@ -28,12 +83,104 @@ type MenuSystem struct {
 	selectedIndex  int
 	menuKeys       []string
 	logger         *logrus.Logger
+
+	// navMutex guards the navigation state (the menus above, the pager, the
+	// Devices submenu and the shown item). Presses, gesture and macro timers,
+	// device changes and ending output routines arrive on goroutines of their
+	// own; the exported methods take it and the helpers below expect it held.
+	navMutex sync.Mutex
 	
-	// Output display state
-	displayingOutput bool
+	// Lifecycle: ctx is cancelled by Stop, routines tracks the goroutines
+	// started while running so Stop can wait for them
+	lifecycleMutex sync.Mutex
+	ctx            context.Context
+	cancel         context.CancelFunc
+	routines       sync.WaitGroup
+	activeRoutines atomic.Int32
+
+	// Output display state; displayingOutput is cleared by the output
+	// routine while buttons read it
+	displayingOutput atomic.Bool
 	outputText       string
-	scrollPosition   int
-	stopOutputChan   chan bool
+	// marquees scroll output too long for the display
+	marquees         controller.Marquees
+	stopOutput       context.CancelFunc
+
+	// Paged output state (nil when no paged output is shown)
+	pager *screen.Pager
+
+	// Shortcut gestures recognized at the root menu
+	gestures *gestureDetector
+
+	// prompter asks confirmation questions (nil = run commands without asking)
+	prompter Prompter
+
+	// broker runs privileged commands (nil = run every command directly)
+	broker Broker
+
+	// cpu reads and switches the CPU frequency scaling state
+	cpu *sysinfo.CPUProvider
+
+	// storage lists volumes and shares for the storage browser
+	storage *sysinfo.StorageProvider
+
+	// removable lists and ejects the devices of the Devices submenu, which
+	// devicesMenu is while shown
+	removable   *sysinfo.RemovableProvider
+	devicesMenu *config.MenuItem
+
+	// abbrev shortens menu lines that are too long for the display
+	abbrev *screen.Abbreviator
+
+	// scrub lists pools and starts and follows their scrubs
+	scrub *sysinfo.ScrubProvider
+
+	// leds flash while a scrub runs (nil = no LEDs)
+	leds ActivityLEDs
+
+	// scrubWatches holds the pools whose scrub the LEDs follow
+	scrubMutex   sync.Mutex
+	scrubWatches map[string]bool
+
+	// network lists the network ports and blinks their LEDs
+	network *sysinfo.NetworkProvider
+
+	// peers are the nodes on the cluster dashboard (nil = none)
+	peers Peers
+
+	// events records the commands run and the items moved to (nil = not
+	// recorded)
+	events EventLog
+	// shownItem is the path of the item last recorded as moved to
+	shownItem string
+
+	// smart holds the SMART samples of the drives (nil = no trends)
+	smart SMARTHistory
+
+	// maintenance holds back alerts for a while (nil = no maintenance mode)
+	maintenance Maintenance
+	// panelRestart reopens the panel, LEDs and copy button monitor (nil =
+	// not offered)
+	panelRestart func() error
+
+	// macros keeps the recorded button macros (nil = no macros)
+	macros   MacroStore
+	recorder *macroRecorder
+
+	// usage counts the button presses for the usage screen (nil = none)
+	usage PanelUsage
+
+	// timer is the stopwatch and countdown, sounding buzzer when it runs
+	// out (nil = no buzzer)
+	timer  *panelTimer
+	buzzer Buzzer
+	// bigDigits defines the panel's custom characters for a running timer
+	// shown in big digits (nil = text only)
+	bigDigits controller.CustomChars
+
+	// readOnly keeps command and display_command items from running; it is
+	// switched while buttons read it
+	readOnly atomic.Bool
 }
 
 // NewMenuSystem creates a new menu system
@@ -46,18 +193,41 @@ func NewMenuSystem(cfg *config.Config, displayController DisplayController) *Men
 		displayController: displayController,
 		logger:           logger,
 		menuStack:        make([]*config.MenuItem, 0),
-		stopOutputChan:   make(chan bool),
+		cpu:              sysinfo.NewCPUProvider(""),
+		storage:          sysinfo.NewStorageProvider("", cfg.Storage.Volumes, time.Duration(cfg.Storage.ShareCacheTTL)*time.Second),
+		abbrev:           screen.NewAbbreviator(cfg.Display.Abbreviations),
+		scrubWatches:     make(map[string]bool),
 	}
+	ms.scrub = sysinfo.NewScrubProvider("", ms.runScrubCommand)
+	ms.network = sysinfo.NewNetworkProvider("", ms.runNetworkCommand)
+	ms.removable = sysinfo.NewRemovableProvider("", "", ms.runEjectCommand)
 
 	// Start with the main menu
 	ms.currentMenu = &cfg.Menu.MainMenu
 	ms.updateMenuKeys()
 
+	ms.gestures = newGestureDetector(cfg.Menu.Shortcuts, logger)
+	ms.gestures.dispatch = ms.dispatchButton
+	ms.gestures.trigger = ms.runShortcut
+	ms.gestures.atRoot = ms.isAtRoot
+	ms.recorder = newMacroRecorder(ms)
+	ms.timer = newPanelTimer(ms)
+	ms.readOnly.Store(cfg.Menu.ReadOnly)
+
 	return ms
 }
 
-// Start begins the menu system
+// Start begins the menu system. Starting a running menu does nothing, and a
+// stopped menu can be started again.
 func (ms *MenuSystem) Start() error {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+	ms.lifecycleMutex.Lock()
+	defer ms.lifecycleMutex.Unlock()
+
+	if ms.cancel != nil {
+		return nil
+	}
 	ms.logger.Info("Starting menu system")
 	
 	// Display the main menu
@@ -65,10 +235,42 @@ func (ms *MenuSystem) Start() error {
 		return fmt.Errorf("failed to display main menu: %w", err)
 	}
 
+	ms.ctx, ms.cancel = context.WithCancel(context.Background())
 	ms.logger.Info("Menu system ready for button events")
 	return nil
 }
 
+// running reports whether the menu was started and not stopped since
+func (ms *MenuSystem) running() bool {
+	ms.lifecycleMutex.Lock()
+	defer ms.lifecycleMutex.Unlock()
+
+	return ms.cancel != nil
+}
+
+// goRoutine runs fn in a goroutine that Stop waits for. fn's context is
+// cancelled by Stop or by calling the returned function. Nothing is started
+// and ok is false if the menu is not running.
+func (ms *MenuSystem) goRoutine(fn func(ctx context.Context)) (cancel context.CancelFunc, ok bool) {
+	ms.lifecycleMutex.Lock()
+	defer ms.lifecycleMutex.Unlock()
+
+	if ms.cancel == nil {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(ms.ctx)
+	ms.routines.Add(1)
+	ms.activeRoutines.Add(1)
+	go func() {
+		defer ms.routines.Done()
+		defer ms.activeRoutines.Add(-1)
+		defer cancel()
+		fn(ctx)
+	}()
+	return cancel, true
+}
+
 // readButtons is kept for compatibility but button handling is now done externally
 func (ms *MenuSystem) readButtons() (string, error) {
 	// Button handling is now done through the system controller's unified button events
@@ -103,22 +305,107 @@ func (ms *MenuSystem) handleEnterButton() {
 		"type":         selectedItem.Type,
 	}).Info("ENTER button: selecting option")
 
+	if ms.disabled(&selectedItem) {
+		return
+	}
+
 	switch selectedItem.Type {
 	case "submenu":
 		// Navigate to submenu
 		ms.navigateToSubmenu(&selectedItem)
 	case "command":
-		// Execute system command
-		ms.executeCommand(selectedItem.Command)
+		// Execute system command, reading its input and asking first if the
+		// item requires it
+		var env []string
+		if selectedItem.Input != "" {
+			text, ok := ms.readInput(selectedItem.Input, selectedItem.InputCharset)
+			if !ok {
+				return
+			}
+			env = append(env, "INPUT="+text)
+		}
+		if !ms.confirm(selectedItem.Confirm) {
+			return
+		}
+		ms.executeCommand(&selectedItem, env)
 	case "display_command":
 		// Execute display-specific command
+		if !ms.confirm(selectedItem.Confirm) {
+			return
+		}
 		ms.executeDisplayCommand(selectedItem.Command)
+	case "file":
+		// Show a text file, read anew on every visit
+		ms.showFile(selectedItem.Path)
 	case "back":
 		// Go back to previous menu
 		ms.navigateBack()
 	}
 }
 
+// SetPrompter sets the prompter used for confirmation questions
+func (ms *MenuSystem) SetPrompter(prompter Prompter) {
+	ms.prompter = prompter
+}
+
+// SetBroker sets the helper that runs commands marked privileged
+func (ms *MenuSystem) SetBroker(broker Broker) {
+	ms.broker = broker
+}
+
+// SetEventLog sets the log the commands run and the items moved to are
+// recorded in
+func (ms *MenuSystem) SetEventLog(log EventLog) {
+	ms.events = log
+}
+
+// confirm asks a yes/no question and reports whether the user chose Yes.
+// Items without a question, or menus without a prompter, are confirmed
+// automatically. No answer within confirmTimeout counts as No.
+func (ms *MenuSystem) confirm(question string) bool {
+	if question == "" || ms.prompter == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	choice, err := ms.prompter.Prompt(ctx, question, "No", "Yes")
+	if err != nil {
+		ms.logger.WithError(err).WithField("question", question).Info("Confirmation not given")
+		return false
+	}
+
+	ms.logger.WithFields(logrus.Fields{
+		"question":  question,
+		"confirmed": choice == 1,
+	}).Info("Confirmation answered")
+	return choice == 1
+}
+
+// readInput reads text for a command with the character picker. It reports
+// false if no text was entered within inputTimeout or no prompter is set.
+func (ms *MenuSystem) readInput(label, charset string) (string, bool) {
+	if ms.prompter == nil {
+		ms.logger.WithField("input", label).Warn("Command needs input but no prompter is set")
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inputTimeout)
+	defer cancel()
+
+	text, err := ms.prompter.Input(ctx, label, charset)
+	if err != nil {
+		ms.logger.WithError(err).WithField("input", label).Info("Input not given")
+		return "", false
+	}
+	if text == "" {
+		ms.logger.WithField("input", label).Info("Empty input, command skipped")
+		return "", false
+	}
+	return text, true
+}
+
 // navigateToSubmenu navigates to a submenu
 func (ms *MenuSystem) navigateToSubmenu(item *config.MenuItem) {
 	// Push current menu to stack
@@ -147,9 +434,15 @@ func (ms *MenuSystem) navigateBack() {
 	ms.logger.Info("Navigated back to previous menu")
 }
 
-// executeCommand executes a system command
-func (ms *MenuSystem) executeCommand(command string) {
-	ms.logger.WithField("command", command).Info("Executing system command")
+// executeCommand executes an item's command with additional environment
+// variables and shows its output in the item's mode. Privileged commands go
+// through the broker when one is set.
+func (ms *MenuSystem) executeCommand(item *config.MenuItem, env []string) {
+	command, outputMode := item.Command, item.OutputMode
+	ms.logger.WithFields(logrus.Fields{
+		"command":    command,
+		"privileged": item.Privileged && ms.broker != nil,
+	}).Info("Executing system command")
 
 	// Display "Executing..." message
 	if err := ms.displayController.WriteText("Executing...\nPlease wait"); err != nil {
@@ -157,18 +450,89 @@ func (ms *MenuSystem) executeCommand(command string) {
 	}
 
 	// Execute the command
-	cmd := exec.Command("sh", "-c", command)
-	output, err := cmd.CombinedOutput()
+	started := time.Now()
+	var output []byte
+	var err error
+	if item.Privileged && ms.broker != nil {
+		output, err = ms.broker.Run(command, env)
+	} else {
+		cmd := exec.Command("sh", "-c", command)
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		output, err = cmd.CombinedOutput()
+	}
+	ms.recordCommand(item, time.Since(started), err)
 	
 	if err != nil {
 		ms.logger.WithError(err).Error("Command execution failed")
-		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		ms.displayOutput(fmt.Sprintf("Error: %v", err), outputMode)
 	} else {
 		ms.logger.Info("Command executed successfully")
-		// Clean and prepare output for scrolling display
-		cleanOutput := ms.prepareOutputForDisplay(string(output))
-		ms.displayScrollingOutput(cleanOutput)
+		ms.displayOutput(string(output), outputMode)
+	}
+}
+
+// recordCommand adds a command that ran to the event log
+func (ms *MenuSystem) recordCommand(item *config.MenuItem, took time.Duration, err error) {
+	if ms.events == nil {
+		return
+	}
+	fields := events.Fields{
+		"item":        item.Title,
+		"status":      "ok",
+		"duration_ms": fmt.Sprint(took.Milliseconds()),
+	}
+	if err != nil {
+		fields["status"] = "failed"
+		fields["error"] = err.Error()
 	}
+	ms.events.Record(events.KindCommand, item.Command, fields)
+}
+
+// displayOutput shows command output either paged or as scrolling text
+func (ms *MenuSystem) displayOutput(output string, outputMode string) {
+	if outputMode == config.OutputModePaged {
+		ms.displayPagedOutput(output)
+		return
+	}
+
+	// Clean and prepare output for scrolling display
+	ms.displayScrollingOutput(ms.prepareOutputForDisplay(output))
+}
+
+// displayPagedOutput shows output one page at a time (SELECT=next page, ENTER=exit)
+func (ms *MenuSystem) displayPagedOutput(output string) {
+	width, height := ms.displayGeometry()
+	ms.pager = screen.NewPager(output, width, height)
+
+	ms.logger.WithField("pages", ms.pager.PageCount()).Debug("Starting paged output display")
+	ms.renderPager()
+}
+
+// renderPager draws the current page of paged output
+func (ms *MenuSystem) renderPager() {
+	if err := ms.displayController.WriteText(ms.pager.Render()); err != nil {
+		ms.logger.WithError(err).Error("Failed to display paged output")
+	}
+}
+
+// displayGeometry returns the size of the display the menu draws on: the
+// layer's own size if it reports one, else the configured size, falling back
+// to 16x2
+func (ms *MenuSystem) displayGeometry() (int, int) {
+	if sized, ok := ms.displayController.(sizedDisplay); ok {
+		return sized.Size()
+	}
+
+	width, height := ms.config.Display.Width, ms.config.Display.Height
+	if width <= 0 {
+		width = 16
+	}
+	if height <= 0 {
+		height = 2
+	}
+	return width, height
 }
 
 // executeDisplayCommand handles QNAP display-specific commands
@@ -180,12 +544,186 @@ func (ms *MenuSystem) executeDisplayCommand(command string) {
 		ms.executeBacklightCommand(true)
 	case "backlight_off":
 		ms.executeBacklightCommand(false)
+	case "cpu_status":
+		ms.startOutput(ms.cpuStatusRoutine)
+	case "cpu_governor_toggle":
+		ms.executeGovernorToggle()
+	case "storage_browser":
+		ms.openStorageBrowser()
+	case "devices":
+		ms.openDevicesMenu()
+	case "scrub_pools":
+		ms.openScrubMenu()
+	case "network_ports":
+		ms.openNetworkPorts()
+	case "network_links":
+		ms.showNetworkLinks()
+	case "cluster_dashboard":
+		ms.showClusterDashboard()
+	case "about":
+		ms.showAbout()
+	case "usage_stats":
+		ms.showUsage()
+	case "smart_trends":
+		ms.openSMARTMenu()
+	case "maintenance":
+		ms.openMaintenanceMenu()
+	case restartPanelCommand:
+		ms.restartPanel()
+	case "macros":
+		ms.openMacroMenu()
+	case macroRecordCommand:
+		ms.recordMacro()
+	case "timer":
+		ms.openTimerMenu()
 	default:
+		if mount, ok := strings.CutPrefix(command, storageVolumePrefix); ok {
+			ms.showVolume(mount)
+			return
+		}
+		if spec, ok := strings.CutPrefix(command, scrubPrefix); ok {
+			ms.scrubPool(spec)
+			return
+		}
+		if mount, ok := strings.CutPrefix(command, ejectPrefix); ok {
+			ms.ejectDevice(mount)
+			return
+		}
+		if name, ok := strings.CutPrefix(command, identifyPrefix); ok {
+			ms.identifyPort(name)
+			return
+		}
+		if spec, ok := strings.CutPrefix(command, smartTrendPrefix); ok {
+			ms.showSMARTTrend(spec)
+			return
+		}
+		if spec, ok := strings.CutPrefix(command, maintenancePrefix); ok {
+			ms.setMaintenance(spec)
+			return
+		}
+		if spec, ok := strings.CutPrefix(command, timerPrefix); ok {
+			ms.startTimer(spec)
+			return
+		}
+		if name, ok := strings.CutPrefix(command, macroTargetPrefix); ok {
+			if err := ms.runMacro(name); err != nil {
+				ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+			}
+			return
+		}
 		ms.logger.WithField("command", command).Warn("Unknown display command")
 		ms.displayScrollingOutput(fmt.Sprintf("Error: Unknown command '%s'", command))
 	}
 }
 
+// cpuStatusRoutine shows the CPU status, refreshed every second, until ctx is
+// cancelled
+func (ms *MenuSystem) cpuStatusRoutine(ctx context.Context) {
+	defer ms.finishOutput()
+
+	ticker := time.NewTicker(cpuStatusRefresh)
+	defer ticker.Stop()
+
+	for {
+		text := "CPU info\nunavailable"
+		if status, err := ms.cpu.Status(); err != nil {
+			ms.logger.WithError(err).Debug("Failed to read CPU status")
+		} else {
+			text = status.Render()
+		}
+		if err := ms.displayController.WriteText(text); err != nil {
+			ms.logger.WithError(err).Error("Failed to display CPU status")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// executeGovernorToggle switches between the powersave and performance
+// governors and shows the resulting CPU status
+func (ms *MenuSystem) executeGovernorToggle() {
+	governor, err := ms.cpu.ToggleGovernor()
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to switch CPU governor")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	ms.logger.WithField("governor", governor).Info("CPU governor switched")
+	ms.startOutput(ms.cpuStatusRoutine)
+}
+
+// openStorageBrowser enters a submenu listing the mounted volumes with their
+// usage. It is built on every visit, so it follows mounts and unmounts.
+func (ms *MenuSystem) openStorageBrowser() {
+	volumes, err := ms.storage.Volumes()
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to list volumes")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	browser := &config.MenuItem{
+		Title:       "Storage",
+		Description: "Volumes",
+		Type:        "submenu",
+		Items:       make(map[string]config.MenuItem, len(volumes)),
+	}
+	for _, volume := range volumes {
+		browser.Items[volume.Mount] = config.MenuItem{
+			Title:   fmt.Sprintf("%s %d%%", volume.Name(), volume.Percent()),
+			Icon:    "disk",
+			Type:    "display_command",
+			Command: storageVolumePrefix + volume.Mount,
+		}
+	}
+	ms.navigateToSubmenu(browser)
+}
+
+// showVolume pages through a volume's usage and the size of each share.
+// Shares still being counted show "..." until the volume is opened again.
+func (ms *MenuSystem) showVolume(mount string) {
+	volume, err := ms.storage.Volume(mount)
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to read volume usage")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	shares, err := ms.storage.Shares(mount)
+	if err != nil {
+		ms.logger.WithError(err).WithField("volume", mount).Warn("Failed to list shares")
+	}
+
+	width, _ := ms.displayGeometry()
+	lines := []string{
+		fmt.Sprintf("%s %d%%", volume.Name(), volume.Percent()),
+		fmt.Sprintf("%s/%s used", sysinfo.FormatBytes(volume.Used), sysinfo.FormatBytes(volume.Size)),
+	}
+	for _, share := range shares {
+		size := "..."
+		if share.Counted {
+			size = sysinfo.FormatBytes(share.Size)
+		}
+		lines = append(lines, usageLine(share.Name, size, width))
+	}
+	ms.displayPagedOutput(strings.Join(lines, "\n"))
+}
+
+// usageLine puts a name and a size on one line of the given width,
+// shortening the name so the pager does not wrap the size onto a page of its
+// own
+func usageLine(name, size string, width int) string {
+	if room := width - screen.TextWidth(size) - 1; room > 0 {
+		name = screen.Truncate(name, room)
+	}
+	return name + " " + size
+}
+
 // executeBacklightCommand executes backlight control commands
 func (ms *MenuSystem) executeBacklightCommand(on bool) {
 	status := "off"
@@ -228,105 +766,83 @@ func (ms *MenuSystem) prepareOutputForDisplay(output string) string {
 func (ms *MenuSystem) displayScrollingOutput(output string) {
 	ms.logger.WithField("output", output).Debug("Starting scrolling output display")
 	
-	ms.displayingOutput = true
 	ms.outputText = output
-	ms.scrollPosition = 0
 	
 	// Start the scrolling display routine
-	go ms.scrollOutputRoutine()
+	ms.startOutput(ms.scrollOutputRoutine)
 }
 
-// scrollOutputRoutine handles the scrolling display of output
-func (ms *MenuSystem) scrollOutputRoutine() {
-	defer func() {
-		ms.displayingOutput = false
-		ms.scrollPosition = 0
-		// Return to menu display
-		if err := ms.displayCurrentMenu(); err != nil {
-			ms.logger.WithError(err).Error("Failed to return to menu after output display")
-		}
-	}()
+// showUntilButton shows text as it is until a button is pressed
+func (ms *MenuSystem) showUntilButton(text string) {
+	ms.startOutput(func(ctx context.Context) {
+		defer ms.finishOutput()
 
-	displayWidth := ms.config.Display.Width
-	outputLen := len(ms.outputText)
-	
-	// If output fits on display, just show it statically
-	if outputLen <= displayWidth {
-		if err := ms.displayController.WriteText(ms.outputText + "\nPress any button"); err != nil {
-			ms.logger.WithError(err).Error("Failed to display short output")
-			return
-		}
-		
-		// Wait for button press
-		select {
-		case <-ms.stopOutputChan:
+		if err := ms.displayController.WriteText(text); err != nil {
+			ms.logger.WithError(err).Error("Failed to display output")
 			return
 		}
+		<-ctx.Done()
+	})
+}
+
+// startOutput runs an output routine that owns the display until a button
+// press cancels it
+func (ms *MenuSystem) startOutput(routine func(ctx context.Context)) {
+	ms.displayingOutput.Store(true)
+
+	cancel, started := ms.goRoutine(routine)
+	if !started {
+		ms.displayingOutput.Store(false)
+		return
 	}
-	
-	// For longer output, implement scrolling
-	ticker := time.NewTicker(500 * time.Millisecond) // Scroll every 500ms
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-ms.stopOutputChan:
-			return
-		case <-ticker.C:
-			// Create display window
-			line1 := ms.getScrollingWindow(ms.outputText, ms.scrollPosition, displayWidth)
-			line2 := "Press any button"
-			
-			// Display the current window
-			if err := ms.displayController.WriteText(line1 + "\n" + line2); err != nil {
-				ms.logger.WithError(err).Error("Failed to display scrolling output")
-				return
-			}
-			
-			// Advance scroll position
-			ms.scrollPosition++
-			
-			// Reset scroll position when we've scrolled through the entire text
-			maxScroll := outputLen - displayWidth + 1
-			if maxScroll < 0 {
-				maxScroll = 0
-			}
-			
-			if ms.scrollPosition > maxScroll+displayWidth { // Add pause at end
-				ms.scrollPosition = 0
-			}
-		}
+
+	ms.lifecycleMutex.Lock()
+	ms.stopOutput = cancel
+	ms.lifecycleMutex.Unlock()
+}
+
+// finishOutput returns to the menu once an output routine ends, unless the
+// menu itself stopped
+func (ms *MenuSystem) finishOutput() {
+	ms.displayingOutput.Store(false)
+	if !ms.running() {
+		return
+	}
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+	// Return to menu display
+	if err := ms.displayCurrentMenu(); err != nil {
+		ms.logger.WithError(err).Error("Failed to return to menu after output display")
 	}
 }
 
-// getScrollingWindow extracts a window of text for scrolling display
-func (ms *MenuSystem) getScrollingWindow(text string, position, width int) string {
-	textLen := len(text)
-	
-	if position >= textLen {
-		// We're past the end, show spaces or loop back
-		return strings.Repeat(" ", width)
+// scrollOutputRoutine shows the output on the first line, scrolling if it
+// is too long, until ctx is cancelled
+func (ms *MenuSystem) scrollOutputRoutine(ctx context.Context) {
+	defer ms.finishOutput()
+
+	if err := ms.displayController.WriteText("\nPress any button"); err != nil {
+		ms.logger.WithError(err).Error("Failed to display output")
+		return
 	}
-	
-	end := position + width
-	if end > textLen {
-		// Pad with spaces at the end
-		window := text[position:]
-		padding := width - len(window)
-		return window + strings.Repeat(" ", padding)
+	if err := ms.marquees.ScrollText(ms.displayController, ms.config.Display.Width, 0, ms.outputText, outputScrollSpeed); err != nil {
+		ms.logger.WithError(err).Error("Failed to display output")
+		return
 	}
-	
-	return text[position:end]
+	defer ms.marquees.StopScroll(0)
+
+	// Wait for button press
+	<-ctx.Done()
 }
 
 // stopOutputDisplay stops the current output display
 func (ms *MenuSystem) stopOutputDisplay() {
-	if ms.displayingOutput {
-		select {
-		case ms.stopOutputChan <- true:
-		default:
-			// Channel might be full, that's okay
-		}
+	ms.lifecycleMutex.Lock()
+	defer ms.lifecycleMutex.Unlock()
+
+	if ms.stopOutput != nil {
+		ms.stopOutput()
+		ms.stopOutput = nil
 	}
 }
 
@@ -378,15 +894,24 @@ func (ms *MenuSystem) displayCurrentMenu() error {
 		line1 = ms.currentMenu.Title
 	}
 	
-	// Second line: Current selection with indicator
-	line2 := fmt.Sprintf(">%s", selectedItem.Title)
+	// Second line: Current selection with indicator and icon
+	icon := ""
+	if selectedItem.Icon != "" {
+		var known bool
+		if icon, known = controller.GlyphChar(selectedItem.Icon); !known {
+			ms.logger.WithField("icon", selectedItem.Icon).Debug("Unknown menu icon")
+		}
+	}
+	line2 := fmt.Sprintf(">%s%s", icon, selectedItem.Title)
+	ms.recordNavigation(selectedItem.Title)
 	
-	// Truncate to display width (16 characters)
-	if len(line1) > 16 {
-		line1 = line1[:13] + "..."
+	// Abbreviate, then truncate to display width (16 characters)
+	line1, line2 = ms.abbrev.Fit(line1, 16), ms.abbrev.Fit(line2, 16)
+	if screen.TextWidth(line1) > 16 {
+		line1 = screen.Truncate(line1, 13) + "..."
 	}
-	if len(line2) > 16 {
-		line2 = line2[:13] + "..."
+	if screen.TextWidth(line2) > 16 {
+		line2 = screen.Truncate(line2, 13) + "..."
 	}
 
 	ms.logger.WithFields(logrus.Fields{
@@ -396,11 +921,37 @@ func (ms *MenuSystem) displayCurrentMenu() error {
 		"selectedKey":   selectedKey,
 	}).Info("Displaying menu")
 
+	// On a single line only the selection is shown
+	if _, height := ms.displayGeometry(); height == 1 {
+		return ms.displayController.WriteText(line2)
+	}
 	return ms.displayController.WriteText(line1 + "\n" + line2)
 }
 
+// recordNavigation adds a move to another item to the event log. Redraws of
+// the same item are not moves.
+func (ms *MenuSystem) recordNavigation(title string) {
+	if ms.events == nil {
+		return
+	}
+	path := strings.Join(append(ms.menuPath(), title), " > ")
+	if path == ms.shownItem {
+		return
+	}
+	ms.shownItem = path
+	ms.events.Record(events.KindMenu, path, events.Fields{"menu": ms.currentMenu.Title, "item": title})
+}
+
 // GetCurrentMenuPath returns the current menu path for debugging
 func (ms *MenuSystem) GetCurrentMenuPath() []string {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	return ms.menuPath()
+}
+
+// menuPath returns the titles of the menus leading to the current one
+func (ms *MenuSystem) menuPath() []string {
 	path := make([]string, 0, len(ms.menuStack)+1)
 	
 	for _, menu := range ms.menuStack {
@@ -411,21 +962,51 @@ func (ms *MenuSystem) GetCurrentMenuPath() []string {
 	return path
 }
 
-// Stop stops the menu system
+// Stop stops the menu system and waits for its goroutines to exit. Button
+// events are ignored until the menu is started again. Stopping a stopped
+// menu does nothing.
 func (ms *MenuSystem) Stop() {
-	ms.logger.Info("Stopping menu system")
-	
-	// Stop any ongoing output display
-	ms.stopOutputDisplay()
-	
-	// Close the channel to prevent any further operations
-	close(ms.stopOutputChan)
+	ms.lifecycleMutex.Lock()
+	if ms.cancel == nil {
+		ms.lifecycleMutex.Unlock()
+		return
+	}
+	ms.logger.WithField("routines", ms.activeRoutines.Load()).Info("Stopping menu system")
+
+	// Cancelling the menu context stops any ongoing output display
+	ms.cancel()
+	ms.cancel = nil
+	ms.stopOutput = nil
+	ms.lifecycleMutex.Unlock()
+
+	ms.gestures.stop()
+	ms.routines.Wait()
+	ms.logger.Debug("Menu system stopped")
 }
 
 // HandleSelectButton is a public method to handle SELECT button presses from external sources
 func (ms *MenuSystem) HandleSelectButton() {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	ms.selectPressed()
+}
+
+// selectPressed performs a SELECT press
+func (ms *MenuSystem) selectPressed() {
+	if !ms.running() {
+		return
+	}
+
+	// SELECT turns the page of paged output
+	if ms.pager != nil {
+		ms.pager.Next()
+		ms.renderPager()
+		return
+	}
+
 	// If we're displaying output, stop it and return to menu
-	if ms.displayingOutput {
+	if ms.displayingOutput.Load() {
 		ms.stopOutputDisplay()
 		return
 	}
@@ -439,20 +1020,149 @@ func (ms *MenuSystem) HandleSelectButton() {
 
 // HandleEnterButton is a public method to handle ENTER button presses from external sources
 func (ms *MenuSystem) HandleEnterButton() {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	ms.enterPressed()
+}
+
+// enterPressed performs an ENTER press
+func (ms *MenuSystem) enterPressed() {
+	if !ms.running() {
+		return
+	}
+
+	// ENTER leaves paged output
+	if ms.pager != nil {
+		ms.pager = nil
+		if err := ms.displayCurrentMenu(); err != nil {
+			ms.logger.WithError(err).Warn("Failed to return to menu after paged output")
+		}
+		return
+	}
+
 	// If we're displaying output, stop it and return to menu
-	if ms.displayingOutput {
+	if ms.displayingOutput.Load() {
 		ms.stopOutputDisplay()
 		return
 	}
 	
 	ms.handleEnterButton()
+
+	// Command output now owns the display until a button is pressed
+	if ms.displayingOutput.Load() || ms.pager != nil {
+		return
+	}
+
 	// Update display after button press
 	if err := ms.displayCurrentMenu(); err != nil {
 		ms.logger.WithError(err).Warn("Failed to update display after ENTER")
 	}
 }
 
+// HandleButtonEvent handles a raw ENTER or SELECT press or release. Unlike
+// HandleEnterButton and HandleSelectButton it recognizes shortcut gestures,
+// which need release events to measure long presses.
+func (ms *MenuSystem) HandleButtonEvent(button Button, pressed bool) {
+	if !ms.running() {
+		return
+	}
+	// While a macro is recorded, presses are recorded instead of forming
+	// gestures
+	if ms.recorder.record(button, pressed) {
+		return
+	}
+	// The timer has buttons of its own
+	if ms.timer.handle(button, pressed) {
+		return
+	}
+	ms.gestures.handleEvent(button, pressed)
+}
+
+// dispatchButton performs the regular action of a button press
+func (ms *MenuSystem) dispatchButton(button Button) {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	switch button {
+	case ButtonEnter:
+		ms.enterPressed()
+	case ButtonSelect:
+		ms.selectPressed()
+	}
+}
+
+// isAtRoot reports whether the main menu is shown without command output
+func (ms *MenuSystem) isAtRoot() bool {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	return len(ms.menuStack) == 0 && !ms.displayingOutput.Load() && ms.pager == nil
+}
+
+// resetToRoot goes back to the top of the main menu
+func (ms *MenuSystem) resetToRoot() {
+	ms.menuStack = ms.menuStack[:0]
+	ms.currentMenu = &ms.config.Menu.MainMenu
+	ms.selectedIndex = 0
+	ms.updateMenuKeys()
+}
+
+// runShortcut navigates from the main menu to the target item and activates it
+func (ms *MenuSystem) runShortcut(gesture Gesture, target string) {
+	ms.logger.WithFields(logrus.Fields{
+		"gesture": gesture.String(),
+		"target":  target,
+	}).Info("Menu shortcut triggered")
+
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
+	if name, ok := strings.CutPrefix(target, macroTargetPrefix); ok {
+		if err := ms.runMacro(name); err != nil {
+			ms.logger.WithError(err).WithField("macro", name).Warn("Failed to replay macro")
+		}
+		return
+	}
+
+	ms.resetToRoot()
+	keys := strings.Split(strings.Trim(target, "/"), "/")
+	for i, key := range keys {
+		index := -1
+		for j, candidate := range ms.menuKeys {
+			if candidate == key {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			ms.logger.WithField("target", target).Warn("Menu shortcut target not found")
+			break
+		}
+
+		ms.selectedIndex = index
+		if i == len(keys)-1 {
+			ms.enterPressed()
+			return
+		}
+
+		item := ms.currentMenu.Items[key]
+		if item.Type != "submenu" {
+			ms.logger.WithField("target", target).Warn("Menu shortcut path goes through a non-submenu item")
+			break
+		}
+		ms.navigateToSubmenu(&item)
+	}
+
+	if err := ms.displayCurrentMenu(); err != nil {
+		ms.logger.WithError(err).Warn("Failed to update display after shortcut")
+	}
+}
+
 // RefreshDisplay refreshes the current menu display (public method for external use)
 func (ms *MenuSystem) RefreshDisplay() error {
+	ms.navMutex.Lock()
+	defer ms.navMutex.Unlock()
+
 	return ms.displayCurrentMenu()
 }
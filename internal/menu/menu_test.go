@@ -1,9 +1,20 @@
 package menu
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/events"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/sysinfo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -11,9 +22,9 @@ import (
 func TestNewMenuSystem(t *testing.T) {
 	cfg := config.DefaultConfig()
 	mockDisplay := NewMockDisplayController()
-	
+
 	ms := NewMenuSystem(cfg, mockDisplay)
-	
+
 	assert.NotNil(t, ms)
 	assert.Equal(t, cfg, ms.config)
 	assert.NotNil(t, ms.currentMenu)
@@ -23,13 +34,13 @@ func TestNewMenuSystem(t *testing.T) {
 func TestMenuNavigation(t *testing.T) {
 	cfg := config.DefaultConfig()
 	mockDisplay := NewMockDisplayController()
-	
+
 	ms := NewMenuSystem(cfg, mockDisplay)
-	
+
 	// Test initial state
 	assert.Equal(t, 0, ms.selectedIndex)
 	assert.Greater(t, len(ms.menuKeys), 0)
-	
+
 	// Test SELECT button (move to next option)
 	initialIndex := ms.selectedIndex
 	ms.handleSelectButton()
@@ -40,20 +51,20 @@ func TestMenuNavigation(t *testing.T) {
 func TestMenuPath(t *testing.T) {
 	cfg := config.DefaultConfig()
 	mockDisplay := NewMockDisplayController()
-	
+
 	ms := NewMenuSystem(cfg, mockDisplay)
-	
+
 	// Test initial path
 	path := ms.GetCurrentMenuPath()
 	assert.Equal(t, []string{"Main Menu"}, path)
-	
+
 	// Navigate to submenu (if network submenu exists)
 	if networkItem, exists := ms.currentMenu.Items["network"]; exists && networkItem.Type == "submenu" {
 		ms.navigateToSubmenu(&networkItem)
-		
+
 		path = ms.GetCurrentMenuPath()
 		assert.Equal(t, []string{"Main Menu", "Network"}, path)
-		
+
 		// Test navigation back
 		ms.navigateBack()
 		path = ms.GetCurrentMenuPath()
@@ -64,26 +75,792 @@ func TestMenuPath(t *testing.T) {
 func TestDisplayCurrentMenu(t *testing.T) {
 	cfg := config.DefaultConfig()
 	mockDisplay := NewMockDisplayController()
-	
+
 	ms := NewMenuSystem(cfg, mockDisplay)
-	
+
 	// Test displaying current menu
 	err := ms.displayCurrentMenu()
 	require.NoError(t, err)
-	
+
 	// Check that display was called
 	assert.Contains(t, mockDisplay.Calls, "WriteText")
 	assert.NotEmpty(t, mockDisplay.LastText)
 }
 
+func TestMenuIcons(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_disk":  {Title: "Storage", Icon: "disk", Type: "command"},
+		"b_plain": {Title: "Plain", Type: "command"},
+		"c_bad":   {Title: "Unknown", Icon: "rocket", Type: "command"},
+	}
+	mockDisplay := NewMockDisplayController()
+	ms := NewMenuSystem(cfg, mockDisplay)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	disk, _ := controller.GlyphChar("disk")
+	assert.Equal(t, ">"+disk+"Storage", mockDisplay.LastLines[1])
+
+	ms.HandleSelectButton()
+	assert.Equal(t, ">Plain", mockDisplay.LastLines[1])
+
+	ms.HandleSelectButton()
+	assert.Equal(t, ">Unknown", mockDisplay.LastLines[1], "unknown icons are left out")
+}
+
+func TestMenuAbbreviations(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Display.Abbreviations = map[string]string{"diagnostics": "diag"}
+	cfg.Menu.MainMenu.Description = "Network information"
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_temp": {Title: "Temperature alerts", Type: "command"},
+		"b_diag": {Title: "Run diagnostics now", Type: "command"},
+	}
+	mockDisplay := NewMockDisplayController()
+	ms := NewMenuSystem(cfg, mockDisplay)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	assert.Equal(t, []string{"Net information", ">Temp alerts"}, mockDisplay.LastLines)
+
+	ms.HandleSelectButton()
+	assert.Equal(t, ">Run diag now", mockDisplay.LastLines[1])
+}
+
+func TestSingleLineMenu(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_report": {
+			Title:      "Report",
+			Type:       "command",
+			Command:    "printf 'alpha\\nbeta'",
+			OutputMode: config.OutputModePaged,
+		},
+	}
+
+	// The menu owns only the second panel line
+	panel := NewMockDisplayController()
+	screens := screen.NewScreenManager(panel, 16, 2)
+	require.NoError(t, screens.SetRegion(screen.PriorityMenu, 1, 1))
+	ms := NewMenuSystem(cfg, screens.Layer(screen.PriorityMenu))
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	assert.Equal(t, []string{"                ", ">Report         "}, screens.Snapshot(),
+		"the selection is shown, not the menu title")
+	assert.Equal(t, 1, panel.LastRow)
+
+	// Paged output gets one text line per page
+	ms.HandleEnterButton()
+	require.NotNil(t, ms.pager)
+	assert.Equal(t, 2, ms.pager.PageCount())
+	assert.Equal(t, "alpha           ", screens.Snapshot()[1])
+}
+
 func TestButtonReading(t *testing.T) {
 	cfg := config.DefaultConfig()
 	mockDisplay := NewMockDisplayController()
-	
+
 	ms := NewMenuSystem(cfg, mockDisplay)
-	
+
 	// Test no button press (simplified since button handling moved to display controller)
 	button, err := ms.readButtons()
 	require.NoError(t, err)
 	assert.Equal(t, "", button)
 }
+
+func TestPagedCommandOutput(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"report": {
+			Title:      "Report",
+			Type:       "command",
+			Command:    "printf 'alpha beta\\n\\ngamma\\ndelta'",
+			OutputMode: config.OutputModePaged,
+		},
+	}
+	mockDisplay := NewMockDisplayController()
+
+	ms := NewMenuSystem(cfg, mockDisplay)
+	require.NoError(t, ms.Start())
+
+	// ENTER runs the command and shows the first page
+	ms.HandleEnterButton()
+	require.NotNil(t, ms.pager)
+	assert.Equal(t, []string{"alpha beta", "Page 1/3"}, mockDisplay.LastLines)
+
+	// SELECT turns the page and wraps around after the last one
+	ms.HandleSelectButton()
+	assert.Equal(t, []string{"gamma", "Page 2/3"}, mockDisplay.LastLines)
+	ms.HandleSelectButton()
+	ms.HandleSelectButton()
+	assert.Equal(t, []string{"alpha beta", "Page 1/3"}, mockDisplay.LastLines)
+
+	// ENTER returns to the menu
+	ms.HandleEnterButton()
+	assert.Nil(t, ms.pager)
+	assert.Equal(t, ">Report", mockDisplay.LastLines[1])
+}
+
+func TestParseGesture(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Gesture
+		wantErr  bool
+	}{
+		{"triple_select", Gesture{Button: ButtonSelect, Presses: 3}, false},
+		{"double_enter", Gesture{Button: ButtonEnter, Presses: 2}, false},
+		{"Long_Enter", Gesture{Button: ButtonEnter, Presses: 1, Long: true}, false},
+		{"single_enter", Gesture{}, true},
+		{"triple_copy", Gesture{}, true},
+		{"select", Gesture{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			gesture, err := ParseGesture(tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, gesture)
+			assert.Equal(t, strings.ToLower(tt.text), gesture.String())
+		})
+	}
+}
+
+// newShortcutMenu returns a menu with shortcuts whose firing is reported on the channel
+func newShortcutMenu(t *testing.T) (*MenuSystem, *MockDisplayController, chan Gesture) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = []config.ShortcutConfig{
+		{Gesture: "triple_select", Target: "storage"},
+		{Gesture: "long_enter", Target: "storage/ip"},
+		{Gesture: "bogus", Target: "ignored"},
+	}
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"alpha": {
+			Title:      "Alpha",
+			Type:       "command",
+			Command:    "echo alpha",
+			OutputMode: config.OutputModePaged,
+		},
+		"storage": {
+			Title: "Storage",
+			Type:  "submenu",
+			Items: map[string]config.MenuItem{
+				"back": {Title: "Back", Type: "back"},
+				"ip": {
+					Title:      "Show IP",
+					Type:       "command",
+					Command:    "echo 192.168.1.10",
+					OutputMode: config.OutputModePaged,
+				},
+			},
+		},
+	}
+	mockDisplay := NewMockDisplayController()
+
+	ms := NewMenuSystem(cfg, mockDisplay)
+	require.NoError(t, ms.Start())
+
+	ms.gestures.multiPressWindow = 50 * time.Millisecond
+	ms.gestures.longPressTime = 50 * time.Millisecond
+
+	fired := make(chan Gesture, 1)
+	trigger := ms.gestures.trigger
+	ms.gestures.trigger = func(gesture Gesture, target string) {
+		trigger(gesture, target)
+		fired <- gesture
+	}
+
+	return ms, mockDisplay, fired
+}
+
+func waitForShortcut(t *testing.T, fired chan Gesture) Gesture {
+	select {
+	case gesture := <-fired:
+		return gesture
+	case <-time.After(time.Second):
+		t.Fatal("shortcut did not fire")
+		return Gesture{}
+	}
+}
+
+func TestMenuShortcuts(t *testing.T) {
+	t.Run("Triple SELECT opens a submenu", func(t *testing.T) {
+		ms, mockDisplay, fired := newShortcutMenu(t)
+		assert.Len(t, ms.gestures.shortcuts, 2)
+
+		for i := 0; i < 3; i++ {
+			ms.HandleButtonEvent(ButtonSelect, true)
+			ms.HandleButtonEvent(ButtonSelect, false)
+		}
+
+		assert.Equal(t, "triple_select", waitForShortcut(t, fired).String())
+		assert.Equal(t, []string{"Main Menu", "Storage"}, ms.GetCurrentMenuPath())
+		assert.Equal(t, ">Back", mockDisplay.LastLines[1])
+	})
+
+	t.Run("Long ENTER runs a nested command", func(t *testing.T) {
+		ms, mockDisplay, fired := newShortcutMenu(t)
+
+		ms.HandleButtonEvent(ButtonEnter, true)
+		assert.Equal(t, "long_enter", waitForShortcut(t, fired).String())
+		ms.HandleButtonEvent(ButtonEnter, false)
+
+		require.NotNil(t, ms.pager)
+		assert.Equal(t, []string{"192.168.1.10", "Page 1/1"}, mockDisplay.LastLines)
+	})
+
+	t.Run("Short ENTER keeps its normal meaning", func(t *testing.T) {
+		ms, mockDisplay, _ := newShortcutMenu(t)
+
+		// Without multi-press ENTER shortcuts the press resolves on release
+		ms.HandleButtonEvent(ButtonEnter, true)
+		assert.Nil(t, ms.pager)
+		ms.HandleButtonEvent(ButtonEnter, false)
+
+		require.NotNil(t, ms.pager)
+		assert.Equal(t, []string{"alpha", "Page 1/1"}, mockDisplay.LastLines)
+	})
+
+	t.Run("Gestures only apply at the root menu", func(t *testing.T) {
+		ms, _, fired := newShortcutMenu(t)
+
+		storage := ms.currentMenu.Items["storage"]
+		ms.navigateToSubmenu(&storage)
+
+		ms.HandleButtonEvent(ButtonEnter, true)
+		assert.Equal(t, []string{"Main Menu"}, ms.GetCurrentMenuPath())
+		ms.HandleButtonEvent(ButtonEnter, false)
+
+		select {
+		case gesture := <-fired:
+			t.Fatalf("unexpected shortcut %s", gesture)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Gesture timers and presses share the menu", func(t *testing.T) {
+		ms, _, fired := newShortcutMenu(t)
+		defer ms.Stop()
+		ms.gestures.multiPressWindow = time.Millisecond
+		ms.gestures.longPressTime = time.Millisecond
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-fired:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		// Presses arrive on goroutines of their own, like the controller's
+		// events, while the shortcuts fire from timers
+		var presses sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			presses.Add(1)
+			go func(i int) {
+				defer presses.Done()
+				for j := 0; j < 50; j++ {
+					button := ButtonSelect
+					if (i+j)%3 == 0 {
+						button = ButtonEnter
+					}
+					ms.HandleButtonEvent(button, true)
+					if j%5 == 0 {
+						time.Sleep(2 * time.Millisecond)
+					}
+					ms.HandleButtonEvent(button, false)
+				}
+			}(i)
+		}
+		presses.Wait()
+		time.Sleep(20 * time.Millisecond)
+
+		assert.Equal(t, "Main Menu", ms.GetCurrentMenuPath()[0])
+	})
+}
+
+// fakePrompter answers every prompt with a fixed choice
+type fakePrompter struct {
+	choice    int
+	input     string
+	err       error
+	questions []string
+}
+
+func (f *fakePrompter) Prompt(ctx context.Context, text string, options ...string) (int, error) {
+	f.questions = append(f.questions, text)
+	return f.choice, f.err
+}
+
+func (f *fakePrompter) Input(ctx context.Context, label string, charset string) (string, error) {
+	f.questions = append(f.questions, label+" ("+charset+")")
+	return f.input, f.err
+}
+
+func TestCommandConfirmation(t *testing.T) {
+	newConfirmMenu := func(prompter Prompter) (*MenuSystem, *MockDisplayController) {
+		cfg := config.DefaultConfig()
+		cfg.Menu.Shortcuts = nil
+		cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+			"wipe": {
+				Title:      "Wipe",
+				Type:       "command",
+				Command:    "echo wiped",
+				OutputMode: config.OutputModePaged,
+				Confirm:    "Really wipe?",
+			},
+		}
+		mockDisplay := NewMockDisplayController()
+		ms := NewMenuSystem(cfg, mockDisplay)
+		ms.SetPrompter(prompter)
+		require.NoError(t, ms.Start())
+		return ms, mockDisplay
+	}
+
+	t.Run("Yes runs the command", func(t *testing.T) {
+		prompter := &fakePrompter{choice: 1}
+		ms, mockDisplay := newConfirmMenu(prompter)
+
+		ms.HandleEnterButton()
+		assert.Equal(t, []string{"Really wipe?"}, prompter.questions)
+		require.NotNil(t, ms.pager)
+		assert.Equal(t, "wiped", mockDisplay.LastLines[0])
+	})
+
+	t.Run("No returns to the menu", func(t *testing.T) {
+		ms, mockDisplay := newConfirmMenu(&fakePrompter{choice: 0})
+
+		ms.HandleEnterButton()
+		assert.Nil(t, ms.pager)
+		assert.Equal(t, ">Wipe", mockDisplay.LastLines[1])
+	})
+
+	t.Run("Timeout counts as No", func(t *testing.T) {
+		ms, _ := newConfirmMenu(&fakePrompter{choice: -1, err: context.DeadlineExceeded})
+
+		ms.HandleEnterButton()
+		assert.Nil(t, ms.pager)
+	})
+
+	t.Run("Without a prompter commands run directly", func(t *testing.T) {
+		ms, _ := newConfirmMenu(nil)
+
+		ms.HandleEnterButton()
+		assert.NotNil(t, ms.pager)
+	})
+}
+
+func TestCommandInput(t *testing.T) {
+	newInputMenu := func(prompter Prompter) (*MenuSystem, *MockDisplayController) {
+		cfg := config.DefaultConfig()
+		cfg.Menu.Shortcuts = nil
+		cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+			"mkdir": {
+				Title:        "New Folder",
+				Type:         "command",
+				Command:      "echo made $INPUT",
+				OutputMode:   config.OutputModePaged,
+				Input:        "Folder name",
+				InputCharset: "name",
+				Confirm:      "Create?",
+			},
+		}
+		mockDisplay := NewMockDisplayController()
+		ms := NewMenuSystem(cfg, mockDisplay)
+		if prompter != nil {
+			ms.SetPrompter(prompter)
+		}
+		require.NoError(t, ms.Start())
+		return ms, mockDisplay
+	}
+
+	t.Run("Text is passed in INPUT", func(t *testing.T) {
+		prompter := &fakePrompter{choice: 1, input: "photos"}
+		ms, mockDisplay := newInputMenu(prompter)
+
+		ms.HandleEnterButton()
+		assert.Equal(t, []string{"Folder name (name)", "Create?"}, prompter.questions)
+		require.NotNil(t, ms.pager)
+		assert.Equal(t, "made photos", mockDisplay.LastLines[0])
+	})
+
+	t.Run("Empty text skips the command", func(t *testing.T) {
+		prompter := &fakePrompter{choice: 1}
+		ms, _ := newInputMenu(prompter)
+
+		ms.HandleEnterButton()
+		assert.Equal(t, []string{"Folder name (name)"}, prompter.questions, "no confirmation is asked")
+		assert.Nil(t, ms.pager)
+	})
+
+	t.Run("Without a prompter the command is skipped", func(t *testing.T) {
+		ms, _ := newInputMenu(nil)
+
+		ms.HandleEnterButton()
+		assert.Nil(t, ms.pager)
+	})
+}
+
+// fakeBroker records the commands it is asked to run
+type fakeBroker struct {
+	commands []string
+	env      [][]string
+}
+
+func (f *fakeBroker) Run(command string, env []string) ([]byte, error) {
+	f.commands = append(f.commands, command)
+	f.env = append(f.env, env)
+	return []byte("brokered"), nil
+}
+
+func TestPrivilegedCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_plain": {
+			Title:      "Plain",
+			Type:       "command",
+			Command:    "echo direct",
+			OutputMode: config.OutputModePaged,
+		},
+		"b_root": {
+			Title:      "Root",
+			Type:       "command",
+			Command:    "echo root $INPUT",
+			OutputMode: config.OutputModePaged,
+			Input:      "Name",
+			Privileged: true,
+		},
+	}
+	mockDisplay := NewMockDisplayController()
+	ms := NewMenuSystem(cfg, mockDisplay)
+	ms.SetPrompter(&fakePrompter{choice: 1, input: "disk1"})
+	require.NoError(t, ms.Start())
+
+	// Without a broker privileged commands run directly
+	ms.selectedIndex = 1
+	ms.HandleEnterButton()
+	assert.Equal(t, "root disk1", mockDisplay.LastLines[0])
+	ms.HandleEnterButton()
+
+	broker := &fakeBroker{}
+	ms.SetBroker(broker)
+
+	ms.selectedIndex = 0
+	ms.HandleEnterButton()
+	assert.Equal(t, "direct", mockDisplay.LastLines[0])
+	assert.Empty(t, broker.commands, "plain commands never reach the broker")
+	ms.HandleEnterButton()
+
+	ms.selectedIndex = 1
+	ms.HandleEnterButton()
+	assert.Equal(t, "brokered", mockDisplay.LastLines[0])
+	assert.Equal(t, []string{"echo root $INPUT"}, broker.commands)
+	assert.Equal(t, [][]string{{"INPUT=disk1"}}, broker.env)
+}
+
+func TestCommandsAreRecorded(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_ok":   {Title: "Works", Type: "command", Command: "echo ok", OutputMode: config.OutputModePaged},
+		"b_fail": {Title: "Breaks", Type: "command", Command: "exit 3", OutputMode: config.OutputModePaged},
+	}
+	ms := NewMenuSystem(cfg, NewMockDisplayController())
+	log := events.NewLog(10)
+	ms.SetEventLog(log)
+	require.NoError(t, ms.Start())
+
+	ms.HandleEnterButton()
+	ms.HandleEnterButton()
+	ms.selectedIndex = 1
+	ms.HandleEnterButton()
+
+	recorded := log.Query(events.Filter{Kinds: []events.Kind{events.KindCommand}})
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "echo ok", recorded[0].Message)
+	assert.Equal(t, "Works", recorded[0].Fields["item"])
+	assert.Equal(t, "ok", recorded[0].Fields["status"])
+	assert.Equal(t, "exit 3", recorded[1].Message)
+	assert.Equal(t, "failed", recorded[1].Fields["status"])
+	assert.Equal(t, "exit status 3", recorded[1].Fields["error"])
+}
+
+func TestNavigationIsRecorded(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"a_tools": {Title: "Tools", Type: "submenu", Items: map[string]config.MenuItem{
+			"a_df": {Title: "Disk usage", Type: "command", Command: "df"},
+		}},
+		"b_about": {Title: "About", Type: "command", Command: "uname"},
+	}
+	ms := NewMenuSystem(cfg, NewMockDisplayController())
+	log := events.NewLog(10)
+	ms.SetEventLog(log)
+	require.NoError(t, ms.Start())
+
+	ms.HandleSelectButton()
+	require.NoError(t, ms.RefreshDisplay())
+	ms.HandleSelectButton()
+	ms.HandleEnterButton()
+
+	recorded := log.Query(events.Filter{Kinds: []events.Kind{events.KindMenu}})
+	main := cfg.Menu.MainMenu.Title
+	assert.Equal(t, []string{
+		main + " > Tools",
+		main + " > About",
+		main + " > Tools",
+		main + " > Tools > Back",
+	}, eventMessages(recorded), "redraws are not moves")
+	assert.Equal(t, events.Fields{"menu": "Tools", "item": "Back"}, recorded[3].Fields)
+}
+
+// eventMessages returns the messages of recorded events
+func eventMessages(recorded []events.Event) []string {
+	out := make([]string, len(recorded))
+	for i, event := range recorded {
+		out[i] = event.Message
+	}
+	return out
+}
+
+// lockedDisplay is a display that is safe to write from the scroll goroutine
+type lockedDisplay struct {
+	mutex  sync.Mutex
+	writes int
+	last   string
+}
+
+func (d *lockedDisplay) WriteTextAt(text string, row, col int) error { return d.WriteText(text) }
+func (d *lockedDisplay) ClearDisplay() error                         { return d.WriteText("") }
+func (d *lockedDisplay) SetBacklight(on bool) error                  { return nil }
+
+func (d *lockedDisplay) WriteText(text string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.writes++
+	d.last = text
+	return nil
+}
+
+func (d *lockedDisplay) count() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.writes
+}
+
+func (d *lockedDisplay) text() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.last
+}
+
+func TestMenuLifecycle(t *testing.T) {
+	newScrollingMenu := func() (*MenuSystem, *lockedDisplay) {
+		cfg := config.DefaultConfig()
+		cfg.Menu.Shortcuts = nil
+		cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+			"long": {
+				Title:   "Long",
+				Type:    "command",
+				Command: "echo this output is much wider than the display",
+			},
+		}
+		display := &lockedDisplay{}
+		return NewMenuSystem(cfg, display), display
+	}
+
+	t.Run("Start and Stop are idempotent", func(t *testing.T) {
+		ms, display := newScrollingMenu()
+
+		ms.Stop()
+		require.NoError(t, ms.Start())
+		require.NoError(t, ms.Start())
+		assert.Equal(t, 1, display.count(), "the second Start does not redraw")
+
+		ms.Stop()
+		ms.Stop()
+	})
+
+	t.Run("Stop ends scrolling output", func(t *testing.T) {
+		ms, display := newScrollingMenu()
+		require.NoError(t, ms.Start())
+
+		ms.HandleEnterButton()
+		assert.True(t, ms.displayingOutput.Load())
+		assert.Equal(t, int32(1), ms.activeRoutines.Load())
+
+		ms.Stop()
+		assert.Equal(t, int32(0), ms.activeRoutines.Load())
+
+		// Nothing is drawn and no button is handled once stopped
+		writes := display.count()
+		ms.HandleSelectButton()
+		time.Sleep(600 * time.Millisecond)
+		assert.Equal(t, writes, display.count())
+	})
+
+	t.Run("Repeated cycles leak no goroutines", func(t *testing.T) {
+		ms, _ := newScrollingMenu()
+		baseline := runtime.NumGoroutine()
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, ms.Start())
+			ms.HandleEnterButton()
+			ms.Stop()
+		}
+
+		// Polled by hand: assert.Eventually runs its condition in a goroutine
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), baseline)
+	})
+
+	t.Run("Long output scrolls through the first line", func(t *testing.T) {
+		ms, display := newScrollingMenu()
+		require.NoError(t, ms.Start())
+		defer ms.Stop()
+
+		ms.HandleEnterButton()
+		waitForText(t, display, "his output is mu")
+	})
+
+	t.Run("A button press still returns to the menu", func(t *testing.T) {
+		ms, display := newScrollingMenu()
+		require.NoError(t, ms.Start())
+		defer ms.Stop()
+
+		ms.HandleEnterButton()
+		ms.HandleSelectButton()
+
+		assert.Eventually(t, func() bool {
+			display.mutex.Lock()
+			defer display.mutex.Unlock()
+			return strings.HasSuffix(display.last, ">Long") && ms.activeRoutines.Load() == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestCPUStatusScreen(t *testing.T) {
+	root := t.TempDir()
+	cpufreq := filepath.Join(root, "devices", "system", "cpu", "cpu0", "cpufreq")
+	require.NoError(t, os.MkdirAll(cpufreq, 0755))
+	for name, value := range map[string]string{
+		"scaling_cur_freq":            "2400000",
+		"scaling_governor":            "powersave",
+		"scaling_available_governors": "performance powersave",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(cpufreq, name), []byte(value+"\n"), 0644))
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"governor": {
+			Title:   "Governor",
+			Type:    "display_command",
+			Command: "cpu_governor_toggle",
+		},
+	}
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.cpu = sysinfo.NewCPUProvider(root)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	ms.HandleEnterButton()
+	assert.Eventually(t, func() bool {
+		return display.text() == "CPU 2400 MHz\nperformance"
+	}, time.Second, 10*time.Millisecond)
+
+	governor, err := os.ReadFile(filepath.Join(cpufreq, "scaling_governor"))
+	require.NoError(t, err)
+	assert.Equal(t, "performance", string(governor))
+
+	// The screen follows frequency changes until a button is pressed
+	require.NoError(t, os.WriteFile(filepath.Join(cpufreq, "scaling_cur_freq"), []byte("3000000\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return display.text() == "CPU 3000 MHz\nperformance"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	ms.HandleSelectButton()
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(display.text(), ">Governor") && ms.activeRoutines.Load() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStorageBrowser(t *testing.T) {
+	mount := filepath.Join(t.TempDir(), "pool")
+	for _, share := range []string{"Public", "Multimedia-Archive"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(mount, share), 0755))
+	}
+	mounts := filepath.Join(t.TempDir(), "mounts")
+	require.NoError(t, os.WriteFile(mounts, []byte("proc /proc proc rw 0 0\n/dev/md0 "+mount+" ext4 rw 0 0\n"), 0644))
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"storage": {Title: "Storage", Type: "display_command", Command: "storage_browser"},
+	}
+	mockDisplay := NewMockDisplayController()
+	ms := NewMenuSystem(cfg, mockDisplay)
+	ms.storage = sysinfo.NewStorageProvider(mounts, nil, time.Hour)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	// ENTER lists the volumes after Back
+	ms.HandleEnterButton()
+	assert.Equal(t, []string{"back", mount}, ms.menuKeys)
+	assert.Equal(t, []string{"Main Menu", "Storage"}, ms.GetCurrentMenuPath())
+	ms.HandleSelectButton()
+	disk, _ := controller.GlyphChar("disk")
+	assert.Equal(t, "Volumes", mockDisplay.LastLines[0])
+	assert.Regexp(t, `^>`+disk+`pool \d+%$`, mockDisplay.LastLines[1])
+
+	// Opening a volume pages through its usage and shares
+	ms.HandleEnterButton()
+	require.NotNil(t, ms.pager)
+	assert.Equal(t, 4, ms.pager.PageCount())
+	assert.Regexp(t, `^pool \d+%$`, mockDisplay.LastLines[0])
+	ms.HandleSelectButton()
+	assert.Regexp(t, `^\S+/\S+ used$`, mockDisplay.LastLines[0])
+	ms.HandleSelectButton()
+	assert.Equal(t, "Multimedia-A ...", mockDisplay.LastLines[0], "names make room for the size")
+
+	// The sizes counted meanwhile show on the next visit
+	assert.Eventually(t, func() bool {
+		shares, err := ms.storage.Shares(mount)
+		return err == nil && shares[0].Counted && shares[1].Counted
+	}, time.Second, 10*time.Millisecond)
+	ms.HandleEnterButton()
+	ms.HandleEnterButton()
+	ms.HandleSelectButton()
+	ms.HandleSelectButton()
+	ms.HandleSelectButton()
+	assert.Regexp(t, `^Public \S+$`, mockDisplay.LastLines[0])
+	assert.NotContains(t, mockDisplay.LastLines[0], "...")
+}
+
+func TestUsageLine(t *testing.T) {
+	assert.Equal(t, "Public 340G", usageLine("Public", "340G", 16))
+	assert.Equal(t, "Multimedia-A ...", usageLine("Multimedia-Archive", "...", 16))
+	assert.Equal(t, "Docs 1.5K", usageLine("Docs", "1.5K", 4))
+	assert.Equal(t, "Übersicht-Fot 1G", usageLine("Übersicht-Fotos", "1G", 16), "cut by characters")
+}
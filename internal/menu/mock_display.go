@@ -5,6 +5,7 @@ import "strings"
 // MockDisplayController is a mock implementation for testing
 type MockDisplayController struct {
 	LastText     string
+	LastLines    []string
 	LastRow      int
 	LastCol      int
 	BacklightOn  bool
@@ -37,6 +38,7 @@ func (m *MockDisplayController) WriteText(text string) error {
 	m.LastText = text
 	// Parse multi-line text for testing
 	lines := strings.Split(text, "\n")
+	m.LastLines = lines
 	if len(lines) > 0 {
 		m.LastText = lines[0]
 	}
@@ -60,6 +62,7 @@ func (m *MockDisplayController) SetBacklight(on bool) error {
 // Reset resets the mock state
 func (m *MockDisplayController) Reset() {
 	m.LastText = ""
+	m.LastLines = nil
 	m.LastRow = 0
 	m.LastCol = 0
 	m.BacklightOn = false
@@ -0,0 +1,133 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// identifyPrefix starts the display command blinking the LEDs of the port
+// named by the rest of the command, e.g. "identify_port:eth0"
+const identifyPrefix = "identify_port:"
+
+// runNetworkCommand runs ethtool, through the broker if identifying ports is
+// privileged and one is set
+func (ms *MenuSystem) runNetworkCommand(command string, env []string) ([]byte, error) {
+	if ms.config.Network.IdentifyPrivileged && ms.broker != nil {
+		return ms.broker.Run(command, env)
+	}
+	return sysinfo.RunShell(command, env)
+}
+
+// openNetworkPorts enters a submenu listing the network ports with their
+// link; ENTER on a port blinks its LEDs
+func (ms *MenuSystem) openNetworkPorts() {
+	ports, err := ms.network.Ports()
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to list network ports")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if len(ports) == 0 {
+		ms.displayScrollingOutput("No network ports")
+		return
+	}
+
+	portMenu := &config.MenuItem{
+		Title:       "Ports",
+		Description: "Blink port LEDs",
+		Type:        "submenu",
+		Items:       make(map[string]config.MenuItem, len(ports)),
+	}
+	for _, port := range ports {
+		portMenu.Items[port.Name] = config.MenuItem{
+			Title:   port.Name + " " + port.Link(),
+			Icon:    "network",
+			Type:    "display_command",
+			Command: identifyPrefix + port.Name,
+		}
+	}
+	ms.navigateToSubmenu(portMenu)
+}
+
+// showNetworkLinks pages through the link speed and duplex of every port
+func (ms *MenuSystem) showNetworkLinks() {
+	ports, err := ms.network.Ports()
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to list network ports")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if len(ports) == 0 {
+		ms.displayScrollingOutput("No network ports")
+		return
+	}
+
+	width, _ := ms.displayGeometry()
+	lines := make([]string, 0, len(ports))
+	for _, port := range ports {
+		lines = append(lines, usageLine(port.Name, port.Link(), width))
+	}
+	ms.displayPagedOutput(strings.Join(lines, "\n"))
+}
+
+// identifyPort blinks the port's LEDs and counts down on the display until
+// they stop. Leaving the screen does not stop the blinking.
+func (ms *MenuSystem) identifyPort(name string) {
+	seconds := ms.config.Network.IdentifySeconds
+	if seconds <= 0 {
+		seconds = sysinfo.DefaultIdentifySeconds
+	}
+	ms.logger.WithField("port", name).Info("Identifying network port")
+
+	// ethtool cannot be interrupted through the broker, so it runs outside
+	// the routines Stop waits for; it only reports back on result
+	result := make(chan error, 1)
+	go func() {
+		result <- ms.network.Identify(name, seconds)
+	}()
+	ms.startOutput(func(ctx context.Context) {
+		ms.identifyRoutine(ctx, name, seconds, result)
+	})
+}
+
+// identifyRoutine shows the seconds the port keeps blinking until ethtool
+// returns or ctx is cancelled. A failure stays on the display until ctx is
+// cancelled.
+func (ms *MenuSystem) identifyRoutine(ctx context.Context, name string, seconds int, result <-chan error) {
+	defer ms.finishOutput()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for left := seconds; ; {
+		if err := ms.displayController.WriteText(fmt.Sprintf("Blinking %s\n%ds left", name, left)); err != nil {
+			ms.logger.WithError(err).Error("Failed to display port identification")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-result:
+			if err == nil {
+				return
+			}
+			ms.logger.WithError(err).WithField("port", name).Error("Failed to identify network port")
+			if err := ms.displayController.WriteText(name + "\nidentify failed"); err != nil {
+				ms.logger.WithError(err).Error("Failed to display port identification")
+				return
+			}
+			<-ctx.Done()
+			return
+		case <-ticker.C:
+			if left > 1 {
+				left--
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package menu
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEthtool records the ports it blinks and returns once released
+type fakeEthtool struct {
+	mutex   sync.Mutex
+	ports   []string
+	release chan error
+}
+
+func (e *fakeEthtool) run(command string, env []string) ([]byte, error) {
+	e.mutex.Lock()
+	e.ports = append(e.ports, env...)
+	e.mutex.Unlock()
+	return nil, <-e.release
+}
+
+func (e *fakeEthtool) blinked() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.ports...)
+}
+
+// newNetworkTestMenu creates a running menu with one network display command
+// and two ports, eth0 with a gigabit link and eth1 without one
+func newNetworkTestMenu(t *testing.T, command string) (*MenuSystem, *lockedDisplay, *fakeEthtool) {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, attributes := range map[string]map[string]string{
+		"eth0": {"carrier": "1", "speed": "1000", "duplex": "full"},
+		"eth1": {"carrier": "0"},
+	} {
+		dir := filepath.Join(root, "class", "net", name)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "device"), 0755))
+		for attribute, value := range attributes {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, attribute), []byte(value+"\n"), 0644))
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"ports": {Title: "Ports", Type: "display_command", Command: command},
+	}
+	cfg.Menu.Shortcuts = nil
+	cfg.Network.IdentifySeconds = 3
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ethtool := &fakeEthtool{release: make(chan error, 1)}
+	ms.network = sysinfo.NewNetworkProvider(root, ethtool.run)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+	return ms, display, ethtool
+}
+
+func TestNetworkPorts(t *testing.T) {
+	t.Run("Ports blink until ethtool returns", func(t *testing.T) {
+		ms, display, ethtool := newNetworkTestMenu(t, "network_ports")
+
+		ms.HandleEnterButton()
+		assert.Equal(t, []string{"back", "eth0", "eth1"}, ms.menuKeys)
+		assert.Equal(t, "eth0 1G full", ms.currentMenu.Items["eth0"].Title)
+		assert.Equal(t, "eth1 down", ms.currentMenu.Items["eth1"].Title)
+
+		ms.HandleSelectButton()
+		menuText := display.text()
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool {
+			return display.text() == "Blinking eth0\n3s left"
+		}, time.Second, 10*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			return len(ethtool.blinked()) == 1
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"PORT=eth0"}, ethtool.blinked())
+
+		// The menu comes back once the LEDs stop
+		ethtool.release <- nil
+		assert.Eventually(t, func() bool { return display.text() == menuText }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Failures stay on the display", func(t *testing.T) {
+		ms, display, ethtool := newNetworkTestMenu(t, "network_ports")
+
+		ms.HandleEnterButton()
+		ethtool.release <- errors.New("Operation not supported")
+		ms.HandleSelectButton()
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool {
+			return display.text() == "eth0\nidentify failed"
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestNetworkLinks(t *testing.T) {
+	ms, display, _ := newNetworkTestMenu(t, "network_links")
+
+	ms.HandleEnterButton()
+	assert.Equal(t, "eth0 1G full\nPage 1/2", display.text())
+	ms.HandleSelectButton()
+	assert.Equal(t, "eth1 down\nPage 2/2", display.text())
+}
@@ -0,0 +1,30 @@
+package menu
+
+import "github.com/qnap/display-control/internal/config"
+
+// readOnlyText is shown instead of running an item in read-only mode
+const readOnlyText = "Disabled"
+
+// SetReadOnly switches read-only mode. In it the menu can still be browsed
+// and files shown, but command and display_command items, including those
+// reached by shortcuts and macros, only show "Disabled".
+func (ms *MenuSystem) SetReadOnly(readOnly bool) {
+	if ms.readOnly.Swap(readOnly) != readOnly {
+		ms.logger.WithField("read_only", readOnly).Info("Menu read-only mode switched")
+	}
+}
+
+// ReadOnly reports whether the menu is in read-only mode
+func (ms *MenuSystem) ReadOnly() bool {
+	return ms.readOnly.Load()
+}
+
+// disabled reports whether an item must not run, and shows so if it must not
+func (ms *MenuSystem) disabled(item *config.MenuItem) bool {
+	if !ms.readOnly.Load() || item.Type != "command" && item.Type != "display_command" {
+		return false
+	}
+	ms.logger.WithField("item", item.Title).Info("Item not run in read-only mode")
+	ms.showUntilButton(readOnlyText)
+	return true
+}
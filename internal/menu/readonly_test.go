@@ -0,0 +1,48 @@
+package menu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"echo":  {Title: "Echo", Type: "command", Command: "echo ran"},
+		"timer": {Title: "Timer", Type: "display_command", Command: "timer"},
+	}
+	cfg.Menu.Shortcuts = nil
+	cfg.Menu.ReadOnly = true
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+	assert.True(t, ms.ReadOnly())
+
+	t.Run("Commands are disabled", func(t *testing.T) {
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return display.text() == "Disabled" }, time.Second, 10*time.Millisecond, display.text())
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return !ms.displayingOutput.Load() }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Display commands are disabled", func(t *testing.T) {
+		ms.HandleSelectButton()
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return display.text() == "Disabled" }, time.Second, 10*time.Millisecond, display.text())
+		assert.Empty(t, ms.menuStack, "the timer menu stays closed")
+		ms.HandleEnterButton()
+		assert.Eventually(t, func() bool { return !ms.displayingOutput.Load() }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Switched off", func(t *testing.T) {
+		ms.SetReadOnly(false)
+		assert.False(t, ms.ReadOnly())
+		ms.HandleEnterButton()
+		assert.Len(t, ms.menuStack, 1)
+	})
+}
@@ -0,0 +1,259 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// scrubPrefix starts the display command scrubbing the pool given by the rest
+// of the command as "<kind>:<target>", e.g. "scrub:zfs:tank"
+const scrubPrefix = "scrub:"
+
+// scrubRefresh is how often the scrub progress screen is redrawn
+const scrubRefresh = 2 * time.Second
+
+// scrubPollInterval is how often the disk LEDs check whether the scrub still runs
+const scrubPollInterval = 10 * time.Second
+
+// scrubLEDStep is how long each disk LED stays lit while a scrub runs
+const scrubLEDStep = 250 * time.Millisecond
+
+// defaultScrubLEDs are the disk LEDs used when the configuration lists none
+var defaultScrubLEDs = []int{1, 2, 3, 4}
+
+// ActivityLEDs shows background disk work on the panel's disk LEDs.
+// controller.SystemControllerInterface satisfies it.
+type ActivityLEDs interface {
+	FlashDiskLED(diskNum int, duration time.Duration)
+}
+
+// SetActivityLEDs sets the LEDs that show a running scrub (nil = none)
+func (ms *MenuSystem) SetActivityLEDs(leds ActivityLEDs) {
+	ms.leds = leds
+}
+
+// runScrubCommand runs a scrub command, through the broker if scrubs are
+// privileged and one is set
+func (ms *MenuSystem) runScrubCommand(command string, env []string) ([]byte, error) {
+	if ms.config.Storage.ScrubPrivileged && ms.broker != nil {
+		return ms.broker.Run(command, env)
+	}
+	return sysinfo.RunShell(command, env)
+}
+
+// openScrubMenu enters a submenu listing the pools that can be scrubbed.
+// Pools being scrubbed show their progress and open it without asking;
+// the others ask before a scrub is started.
+func (ms *MenuSystem) openScrubMenu() {
+	pools, err := ms.scrub.Pools()
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to list pools")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if len(pools) == 0 {
+		ms.displayScrollingOutput("No pools to scrub")
+		return
+	}
+
+	scrubMenu := &config.MenuItem{
+		Title:       "Scrub",
+		Description: "Pools",
+		Type:        "submenu",
+		Items:       make(map[string]config.MenuItem, len(pools)),
+	}
+	for _, pool := range pools {
+		spec := pool.Kind + ":" + pool.Target
+		item := config.MenuItem{
+			Title:   pool.Name,
+			Icon:    "disk",
+			Type:    "display_command",
+			Command: scrubPrefix + spec,
+			Confirm: fmt.Sprintf("Scrub %s?", pool.Name),
+		}
+		if status, err := ms.scrub.Status(pool); err == nil && status.State == sysinfo.ScrubRunning {
+			item.Title = fmt.Sprintf("%s %.0f%%", pool.Name, status.Percent)
+			item.Confirm = ""
+		}
+		scrubMenu.Items[spec] = item
+	}
+	ms.navigateToSubmenu(scrubMenu)
+}
+
+// scrubPool starts a scrub of the pool given as "<kind>:<target>", unless
+// one is already running, and shows its progress
+func (ms *MenuSystem) scrubPool(spec string) {
+	pool, err := ms.findPool(spec)
+	if err == nil {
+		err = ms.startScrub(pool)
+	}
+	if err != nil {
+		ms.logger.WithError(err).Error("Failed to start scrub")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	ms.watchScrub(pool)
+	ms.startOutput(func(ctx context.Context) {
+		ms.scrubProgressRoutine(ctx, pool)
+	})
+}
+
+// findPool looks up a pool by "<kind>:<target>"
+func (ms *MenuSystem) findPool(spec string) (sysinfo.Pool, error) {
+	pools, err := ms.scrub.Pools()
+	if err != nil {
+		return sysinfo.Pool{}, err
+	}
+	for _, pool := range pools {
+		if pool.Kind+":"+pool.Target == spec {
+			return pool, nil
+		}
+	}
+	return sysinfo.Pool{}, fmt.Errorf("pool %s not found", spec)
+}
+
+// startScrub starts a scrub unless the pool is already being scrubbed
+func (ms *MenuSystem) startScrub(pool sysinfo.Pool) error {
+	status, err := ms.scrub.Status(pool)
+	if err != nil {
+		return err
+	}
+	if status.State == sysinfo.ScrubRunning {
+		return nil
+	}
+	if err := ms.scrub.Start(pool); err != nil {
+		return err
+	}
+	ms.logger.WithField("pool", pool.Name).Info("Scrub started")
+	return nil
+}
+
+// scrubProgressRoutine shows the scrub progress, refreshed every
+// scrubRefresh, until ctx is cancelled
+func (ms *MenuSystem) scrubProgressRoutine(ctx context.Context, pool sysinfo.Pool) {
+	defer ms.finishOutput()
+
+	ticker := time.NewTicker(scrubRefresh)
+	defer ticker.Stop()
+
+	width, _ := ms.displayGeometry()
+	for {
+		text := pool.Name + "\nstatus unknown"
+		if status, err := ms.scrub.Status(pool); err != nil {
+			ms.logger.WithError(err).Debug("Failed to read scrub status")
+		} else {
+			text = renderScrub(pool.Name, status, width)
+		}
+		if err := ms.displayController.WriteText(text); err != nil {
+			ms.logger.WithError(err).Error("Failed to display scrub progress")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderScrub shows a scrub's progress on two lines: the pool with the
+// percentage and time left, and a progress bar or the result
+func renderScrub(name string, status sysinfo.ScrubStatus, width int) string {
+	percent := int(status.Percent)
+	switch status.State {
+	case sysinfo.ScrubRunning:
+		progress := fmt.Sprintf("%d%%", percent)
+		if remaining := formatRemaining(status.Remaining); remaining != "" {
+			progress += " " + remaining
+		}
+		return usageLine(name, progress, width) + "\n" + controller.RenderProgressBarWidth(percent, width)
+	case sysinfo.ScrubFinished:
+		errors := "errors"
+		if status.Errors == 1 {
+			errors = "error"
+		}
+		return usageLine(name, "done", width) + fmt.Sprintf("\n%d %s", status.Errors, errors)
+	case sysinfo.ScrubStopped:
+		return usageLine(name, "stopped", width) + "\n" + controller.RenderProgressBarWidth(percent, width)
+	}
+	return name + "\nnever scrubbed"
+}
+
+// formatRemaining renders the time left compactly, e.g. "1h03m" or "12m"
+func formatRemaining(remaining time.Duration) string {
+	switch {
+	case remaining <= 0:
+		return ""
+	case remaining < time.Minute:
+		return "<1m"
+	case remaining < time.Hour:
+		return fmt.Sprintf("%dm", int(remaining.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(remaining.Hours()), int(remaining.Minutes())%60)
+}
+
+// watchScrub flashes the disk LEDs in turn until the pool's scrub ends, also
+// after its progress screen was left. One watcher runs per pool.
+func (ms *MenuSystem) watchScrub(pool sysinfo.Pool) {
+	if ms.leds == nil {
+		return
+	}
+
+	spec := pool.Kind + ":" + pool.Target
+	ms.scrubMutex.Lock()
+	if ms.scrubWatches[spec] {
+		ms.scrubMutex.Unlock()
+		return
+	}
+	ms.scrubWatches[spec] = true
+	ms.scrubMutex.Unlock()
+
+	done := func() {
+		ms.scrubMutex.Lock()
+		delete(ms.scrubWatches, spec)
+		ms.scrubMutex.Unlock()
+	}
+	if _, started := ms.goRoutine(func(ctx context.Context) {
+		defer done()
+		ms.flashWhileScrubbing(ctx, pool)
+	}); !started {
+		done()
+	}
+}
+
+// flashWhileScrubbing lights the configured disk LEDs one after the other
+// until the scrub is no longer running or ctx is cancelled
+func (ms *MenuSystem) flashWhileScrubbing(ctx context.Context, pool sysinfo.Pool) {
+	leds := ms.config.Storage.ScrubLEDs
+	if len(leds) == 0 {
+		leds = defaultScrubLEDs
+	}
+
+	step := time.NewTicker(scrubLEDStep)
+	defer step.Stop()
+	poll := time.NewTicker(scrubPollInterval)
+	defer poll.Stop()
+
+	for next := 0; ; {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			status, err := ms.scrub.Status(pool)
+			if err != nil || status.State != sysinfo.ScrubRunning {
+				ms.logger.WithField("pool", pool.Name).WithField("errors", status.Errors).Info("Scrub ended")
+				return
+			}
+		case <-step.C:
+			ms.leds.FlashDiskLED(leds[next%len(leds)], scrubLEDStep)
+			next++
+		}
+	}
+}
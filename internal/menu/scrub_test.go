@@ -0,0 +1,116 @@
+package menu
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeZFS answers the zpool commands for a single pool named tank
+type fakeZFS struct {
+	mutex   sync.Mutex
+	started bool
+}
+
+func (z *fakeZFS) run(command string, env []string) ([]byte, error) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+
+	switch command {
+	case "zpool list -H -o name":
+		return []byte("tank\n"), nil
+	case `zpool scrub "$POOL"`:
+		z.started = true
+		return nil, nil
+	case `zpool status "$POOL"`:
+		if !z.started {
+			return []byte("  pool: tank\n  scan: none requested\n"), nil
+		}
+		return []byte("  pool: tank\n  scan: scrub in progress since Sun Jul 25 16:07:49 2021\n" +
+			"\t0B repaired, 24.32% done, 01:03:37 to go\n"), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// fakeLEDs records which disk LEDs were flashed
+type fakeLEDs struct {
+	mutex   sync.Mutex
+	flashed []int
+}
+
+func (l *fakeLEDs) FlashDiskLED(diskNum int, duration time.Duration) {
+	l.mutex.Lock()
+	l.flashed = append(l.flashed, diskNum)
+	l.mutex.Unlock()
+}
+
+func (l *fakeLEDs) count() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.flashed)
+}
+
+func TestScrubMenu(t *testing.T) {
+	mounts := filepath.Join(t.TempDir(), "mounts")
+	require.NoError(t, os.WriteFile(mounts, []byte("/dev/sda1 / ext4 rw 0 0\n"), 0644))
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"scrub": {Title: "Scrub", Type: "display_command", Command: "scrub_pools"},
+	}
+	cfg.Storage.ScrubLEDs = []int{2, 3}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	zfs := &fakeZFS{}
+	ms.scrub = sysinfo.NewScrubProvider(mounts, zfs.run)
+	leds := &fakeLEDs{}
+	ms.SetActivityLEDs(leds)
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	ms.HandleEnterButton()
+	assert.Equal(t, []string{"back", "zfs:tank"}, ms.menuKeys)
+	assert.Equal(t, "Scrub tank?", ms.currentMenu.Items["zfs:tank"].Confirm)
+
+	// Without a prompter the scrub starts right away and shows its progress
+	ms.HandleSelectButton()
+	ms.HandleEnterButton()
+	assert.True(t, zfs.started)
+	assert.Eventually(t, func() bool {
+		return display.text() == "tank 24% 1h03m\n[===           ]"
+	}, time.Second, 10*time.Millisecond)
+
+	// The disk LEDs take turns while the scrub runs
+	assert.Eventually(t, func() bool { return leds.count() >= 3 }, 2*time.Second, 10*time.Millisecond)
+	leds.mutex.Lock()
+	assert.Equal(t, []int{2, 3, 2}, leds.flashed[:3])
+	leds.mutex.Unlock()
+
+	// A running scrub is listed with its progress and opens without asking
+	ms.HandleEnterButton()
+	ms.openScrubMenu()
+	assert.Equal(t, "tank 24%", ms.currentMenu.Items["zfs:tank"].Title)
+	assert.Empty(t, ms.currentMenu.Items["zfs:tank"].Confirm)
+}
+
+func TestRenderScrub(t *testing.T) {
+	assert.Equal(t, "tank 24% 1h03m\n[===           ]",
+		renderScrub("tank", sysinfo.ScrubStatus{State: sysinfo.ScrubRunning, Percent: 24.3, Remaining: 63 * time.Minute}, 16))
+	assert.Equal(t, "backup 99% <1m\n[============= ]",
+		renderScrub("backup", sysinfo.ScrubStatus{State: sysinfo.ScrubRunning, Percent: 99.9, Remaining: 30 * time.Second}, 16))
+	assert.Equal(t, "tank done\n1 error",
+		renderScrub("tank", sysinfo.ScrubStatus{State: sysinfo.ScrubFinished, Percent: 100, Errors: 1}, 16))
+	assert.Equal(t, "tank\nnever scrubbed", renderScrub("tank", sysinfo.ScrubStatus{State: sysinfo.ScrubNone}, 16))
+
+	assert.Equal(t, "", formatRemaining(0))
+	assert.Equal(t, "12m", formatRemaining(12*time.Minute+40*time.Second))
+	assert.Equal(t, "26h00m", formatRemaining(26*time.Hour))
+}
@@ -0,0 +1,139 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// smartTrendPrefix starts the display command showing the trend of a drive's
+// metric given by the rest of the command as "<drive>:<metric>", e.g.
+// "smart_trend:sda:temperature"
+const smartTrendPrefix = "smart_trend:"
+
+// smartTrendCells is how many cells the sparkline splits the last 24 hours
+// into, 90 minutes each
+const smartTrendCells = 16
+
+// smartMetricNames are the short names of the metrics on the panel
+var smartMetricNames = map[string]string{
+	sysinfo.SMARTTemperature: "temp",
+	sysinfo.SMARTReallocated: "realloc",
+}
+
+// SMARTHistory holds the SMART samples of the drives. state.SMARTHistory
+// satisfies it.
+type SMARTHistory interface {
+	Samples(drive string) ([]sysinfo.SMARTSample, error)
+}
+
+// SetSMARTHistory sets the samples the SMART trends are drawn from (nil =
+// no SMART trends)
+func (ms *MenuSystem) SetSMARTHistory(history SMARTHistory) {
+	ms.smart = history
+}
+
+// openSMARTMenu enters a submenu with the trend of each metric of each
+// sampled drive
+func (ms *MenuSystem) openSMARTMenu() {
+	if ms.smart == nil || len(ms.config.SMART.Drives) == 0 {
+		ms.displayScrollingOutput("No drives sampled")
+		return
+	}
+
+	smartMenu := &config.MenuItem{
+		Title:       "SMART",
+		Description: "Drive trends",
+		Type:        "submenu",
+		Items:       make(map[string]config.MenuItem),
+	}
+	for d, drive := range ms.config.SMART.Drives {
+		for m, metric := range sysinfo.SMARTMetrics {
+			// Keys keep the configured drive order, temperature first
+			smartMenu.Items[fmt.Sprintf("%02d%d", d, m)] = config.MenuItem{
+				Title:   drive + " " + smartMetricNames[metric],
+				Icon:    "disk",
+				Type:    "display_command",
+				Command: smartTrendPrefix + drive + ":" + metric,
+			}
+		}
+	}
+	ms.navigateToSubmenu(smartMenu)
+}
+
+// showSMARTTrend shows the sparkline of a drive's metric over the last 24
+// hours, given as "<drive>:<metric>", until a button is pressed
+func (ms *MenuSystem) showSMARTTrend(spec string) {
+	separator := strings.LastIndex(spec, ":")
+	if ms.smart == nil || separator < 0 {
+		ms.displayScrollingOutput(fmt.Sprintf("Error: Unknown command '%s%s'", smartTrendPrefix, spec))
+		return
+	}
+	drive, metric := spec[:separator], spec[separator+1:]
+
+	samples, err := ms.smart.Samples(drive)
+	if err != nil {
+		ms.logger.WithError(err).WithField("drive", drive).Error("Failed to read SMART samples")
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	text := renderSMARTTrend(drive, metric, samples, time.Now())
+	ms.startOutput(func(ctx context.Context) {
+		defer ms.finishOutput()
+
+		if err := ms.displayController.WriteText(text); err != nil {
+			ms.logger.WithError(err).Error("Failed to display SMART trend")
+			return
+		}
+		<-ctx.Done()
+	})
+}
+
+// renderSMARTTrend draws the latest value of a drive's metric and its range
+// over the last 24 hours, e.g. "sda 38C 31-41", above the sparkline
+func renderSMARTTrend(drive, metric string, samples []sysinfo.SMARTSample, now time.Time) string {
+	trend := state.SMARTTrend(samples, metric, now, smartTrendCells)
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, value := range trend {
+		if !math.IsNaN(value) {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	if math.IsInf(low, 1) {
+		return drive + " " + smartMetricNames[metric] + "\nno samples yet"
+	}
+
+	last := high
+	for i := len(samples) - 1; i >= 0; i-- {
+		if value, ok := samples[i].Value(metric); ok {
+			last = value
+			break
+		}
+	}
+
+	heading := fmt.Sprintf("%s %s %.0f", drive, smartMetricNames[metric], last)
+	if metric == sysinfo.SMARTTemperature {
+		heading = fmt.Sprintf("%s %.0fC %.0f-%.0f", drive, last, low, high)
+	}
+	return heading + "\n" + screen.Sparkline(trend, sparklineBars())
+}
+
+// sparklineBars are the bar glyphs from lowest to highest
+func sparklineBars() []string {
+	var bars []string
+	for _, name := range []string{"bar1", "bar2", "bar3"} {
+		if bar, ok := controller.GlyphChar(name); ok {
+			bars = append(bars, bar)
+		}
+	}
+	return bars
+}
@@ -0,0 +1,68 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMARTHistory returns fixed samples for sda
+type fakeSMARTHistory []sysinfo.SMARTSample
+
+func (h fakeSMARTHistory) Samples(drive string) ([]sysinfo.SMARTSample, error) {
+	if drive != "sda" {
+		return nil, nil
+	}
+	return h, nil
+}
+
+func TestRenderSMARTTrend(t *testing.T) {
+	low, _ := controller.GlyphChar("bar1")
+	high, _ := controller.GlyphChar("bar3")
+	now := time.Now()
+	samples := []sysinfo.SMARTSample{
+		{Time: now.Add(-23 * time.Hour), Values: map[string]float64{sysinfo.SMARTTemperature: 31, sysinfo.SMARTReallocated: 0}},
+		{Time: now.Add(-time.Minute), Values: map[string]float64{sysinfo.SMARTTemperature: 41, sysinfo.SMARTReallocated: 0}},
+	}
+
+	assert.Equal(t, "sda 41C 31-41\n"+low+strings.Repeat(" ", 14)+high,
+		renderSMARTTrend("sda", sysinfo.SMARTTemperature, samples, now))
+	assert.Equal(t, "sda realloc 0\n"+low+strings.Repeat(" ", 14)+low,
+		renderSMARTTrend("sda", sysinfo.SMARTReallocated, samples, now))
+	assert.Equal(t, "sdb temp\nno samples yet", renderSMARTTrend("sdb", sysinfo.SMARTTemperature, nil, now))
+}
+
+func TestSMARTMenu(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SMART.Drives = []string{"sda", "sdb"}
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"smart": {Title: "SMART", Type: "display_command", Command: "smart_trends"},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.SetSMARTHistory(fakeSMARTHistory{
+		{Time: time.Now().Add(-time.Hour), Values: map[string]float64{sysinfo.SMARTTemperature: 38}},
+	})
+	require.NoError(t, ms.Start())
+	defer ms.Stop()
+
+	ms.HandleEnterButton()
+	require.Len(t, ms.menuKeys, 5, "back and two metrics of each drive")
+	var titles []string
+	for _, key := range ms.menuKeys[1:] {
+		titles = append(titles, ms.currentMenu.Items[key].Title)
+	}
+	assert.Equal(t, []string{"sda temp", "sda realloc", "sdb temp", "sdb realloc"}, titles)
+
+	ms.HandleSelectButton()
+	ms.HandleEnterButton()
+	assert.Eventually(t, func() bool { return strings.HasPrefix(display.text(), "sda 38C 38-38\n") },
+		time.Second, 10*time.Millisecond, display.text())
+}
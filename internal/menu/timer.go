@@ -0,0 +1,394 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+)
+
+// timerPrefix starts the display command running the timer, followed by the
+// countdown, e.g. "timer:10m", or 0 for a stopwatch
+const timerPrefix = "timer:"
+
+const (
+	// timerRefresh is how often the running time is checked; the display
+	// is only written when it changes
+	timerRefresh = 100 * time.Millisecond
+	// timerBeepTime is how long each beep of the alarm lasts, once every
+	// timerBeepInterval
+	timerBeepTime     = 300 * time.Millisecond
+	timerBeepInterval = time.Second
+	// timerAlarmTime silences an alarm nobody answers
+	timerAlarmTime = time.Minute
+)
+
+// timerPresets are the timers offered on the panel
+var timerPresets = []struct {
+	title    string
+	duration time.Duration
+}{
+	{"Stopwatch", 0},
+	{"5 minutes", 5 * time.Minute},
+	{"10 minutes", 10 * time.Minute},
+	{"15 minutes", 15 * time.Minute},
+	{"30 minutes", 30 * time.Minute},
+	{"1 hour", time.Hour},
+}
+
+// Buzzer sounds the NAS's buzzer. buzzer.Buzzer satisfies it.
+type Buzzer interface {
+	Beep(ctx context.Context, duration time.Duration) error
+}
+
+// SetBuzzer sets the buzzer a timer sounds when it runs out (nil = the alarm
+// is only shown)
+func (ms *MenuSystem) SetBuzzer(buzzer Buzzer) {
+	ms.buzzer = buzzer
+}
+
+// SetBigDigits sets the panel whose custom characters draw a running timer
+// in big digits, readable across the room (nil = the timer is shown as text)
+func (ms *MenuSystem) SetBigDigits(chars controller.CustomChars) {
+	ms.bigDigits = chars
+}
+
+// TimerActive reports whether the timer is shown, so the idle animation
+// leaves it on the panel
+func (ms *MenuSystem) TimerActive() bool {
+	ms.timer.mutex.Lock()
+	defer ms.timer.mutex.Unlock()
+
+	return ms.timer.active
+}
+
+// openTimerMenu enters a submenu of the stopwatch and the countdowns
+func (ms *MenuSystem) openTimerMenu() {
+	timerMenu := &config.MenuItem{
+		Title:       "Timer",
+		Description: "ENTER start/stop",
+		Type:        "submenu",
+		Items:       make(map[string]config.MenuItem, len(timerPresets)),
+	}
+	for i, preset := range timerPresets {
+		timerMenu.Items[fmt.Sprintf("%d", i+1)] = config.MenuItem{
+			Title:   preset.title,
+			Type:    "display_command",
+			Command: fmt.Sprintf("%s%dm", timerPrefix, int(preset.duration.Minutes())),
+		}
+	}
+	ms.navigateToSubmenu(timerMenu)
+}
+
+// startTimer shows a stopped timer counting down from spec, or a stopwatch
+// for 0. Until it is left with SELECT, ENTER starts and stops it and holding
+// SELECT resets it.
+func (ms *MenuSystem) startTimer(spec string) {
+	countdown, err := time.ParseDuration(spec)
+	if err == nil && countdown < 0 {
+		err = errors.New("negative countdown")
+	}
+	if err != nil {
+		ms.displayScrollingOutput(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	ms.timer.begin(countdown)
+	ms.startOutput(ms.timer.run)
+	if !ms.displayingOutput.Load() {
+		ms.timer.end()
+	}
+}
+
+// panelTimer is the stopwatch or countdown on the panel. While it is shown
+// it takes the raw button events from the gesture detector.
+type panelTimer struct {
+	menu *MenuSystem
+
+	mutex  sync.Mutex
+	active bool
+	// countdown is the time counted down from, 0 for a stopwatch
+	countdown time.Duration
+	// elapsed is the time counted before started, which is zero while the
+	// timer is stopped
+	elapsed time.Duration
+	started time.Time
+	// expired is set once a countdown reached zero, until a button answers
+	// the alarm, which stopAlarm silences
+	expired   bool
+	stopAlarm context.CancelFunc
+
+	// held is the button pressed, heldLong set once SELECT was held long
+	// enough to reset the timer
+	held       Button
+	heldLong   bool
+	generation int
+	hold       *time.Timer
+
+	// changed wakes the output routine to show a change at once
+	changed chan struct{}
+}
+
+// newPanelTimer creates the timer of a menu
+func newPanelTimer(menu *MenuSystem) *panelTimer {
+	return &panelTimer{menu: menu, changed: make(chan struct{}, 1)}
+}
+
+// begin shows a stopped timer
+func (t *panelTimer) begin(countdown time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.resetLocked()
+	t.countdown = countdown
+	t.held = ""
+	t.active = true
+}
+
+// end hands the buttons back to the menu
+func (t *panelTimer) end() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.active = false
+	t.generation++
+	if t.hold != nil {
+		t.hold.Stop()
+		t.hold = nil
+	}
+	t.resetLocked()
+}
+
+// resetLocked stops the timer at its start and silences the alarm
+func (t *panelTimer) resetLocked() {
+	t.elapsed = 0
+	t.started = time.Time{}
+	t.expired = false
+	if t.stopAlarm != nil {
+		t.stopAlarm()
+		t.stopAlarm = nil
+	}
+}
+
+// elapsedLocked returns the time counted so far
+func (t *panelTimer) elapsedLocked(now time.Time) time.Duration {
+	if t.started.IsZero() {
+		return t.elapsed
+	}
+	return t.elapsed + now.Sub(t.started)
+}
+
+// wake asks the output routine to show a change
+func (t *panelTimer) wake() {
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+// handle takes a raw button event while the timer is shown and reports
+// whether it did. Any press answers the alarm; otherwise pressing ENTER
+// starts or stops the timer, holding SELECT resets it and a short SELECT
+// returns to the menu.
+func (t *panelTimer) handle(button Button, pressed bool) bool {
+	t.mutex.Lock()
+	if !t.active {
+		t.mutex.Unlock()
+		return false
+	}
+
+	if pressed {
+		t.generation++
+		t.heldLong = false
+		if t.hold != nil {
+			t.hold.Stop()
+			t.hold = nil
+		}
+		t.held = button
+		switch {
+		case t.expired:
+			// The release is not an action of its own
+			t.held = ""
+			t.resetLocked()
+		case button == ButtonEnter:
+			now := time.Now()
+			if t.started.IsZero() {
+				t.started = now
+			} else {
+				t.elapsed = t.elapsedLocked(now)
+				t.started = time.Time{}
+			}
+		case button == ButtonSelect:
+			generation := t.generation
+			t.hold = time.AfterFunc(t.menu.gestures.longPressTime, func() { t.longPressElapsed(generation) })
+		}
+		t.mutex.Unlock()
+		t.wake()
+		return true
+	}
+
+	leave := button == ButtonSelect && t.held == ButtonSelect && !t.heldLong
+	if t.held == button {
+		t.held = ""
+		t.generation++
+		if t.hold != nil {
+			t.hold.Stop()
+			t.hold = nil
+		}
+	}
+	t.mutex.Unlock()
+
+	if leave {
+		t.menu.stopOutputDisplay()
+	}
+	return true
+}
+
+// longPressElapsed resets the timer once SELECT was held long enough,
+// unless it was released since
+func (t *panelTimer) longPressElapsed(generation int) {
+	t.mutex.Lock()
+	if !t.active || generation != t.generation {
+		t.mutex.Unlock()
+		return
+	}
+	t.heldLong = true
+	t.hold = nil
+	t.resetLocked()
+	t.mutex.Unlock()
+	t.wake()
+}
+
+// run shows the timer until ctx is cancelled, and sounds the alarm when a
+// countdown runs out
+func (t *panelTimer) run(ctx context.Context) {
+	ms := t.menu
+	defer ms.finishOutput()
+	var alarms sync.WaitGroup
+	defer alarms.Wait()
+	defer t.end()
+
+	ticker := time.NewTicker(timerRefresh)
+	defer ticker.Stop()
+
+	// The big font takes the place of the icons until the timer is left
+	width, height := ms.displayGeometry()
+	big := false
+	if ms.bigDigits != nil && height >= controller.BigNumberRows {
+		if err := controller.LoadBigFont(ms.bigDigits); err != nil {
+			ms.logger.WithError(err).Debug("Showing timer as text")
+		} else {
+			big = true
+			defer func() {
+				if err := controller.RestoreGlyphs(ms.bigDigits); err != nil {
+					ms.logger.WithError(err).Warn("Failed to restore icons after timer")
+				}
+			}()
+		}
+	}
+
+	shown := ""
+	for {
+		text, alarm := t.tick(ctx, time.Now(), width, big)
+		if alarm != nil && ms.buzzer != nil {
+			alarms.Add(1)
+			go func() {
+				defer alarms.Done()
+				t.alarm(alarm)
+			}()
+		}
+		if text != shown {
+			if err := ms.displayController.WriteText(text); err != nil {
+				ms.logger.WithError(err).Error("Failed to display timer")
+			}
+			shown = text
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-t.changed:
+		}
+	}
+}
+
+// tick returns the screen of the timer at now, in big digits of width
+// columns while it runs if big is set and they fit. When a countdown ran out
+// it also returns the context of its alarm, cancelled when the alarm is
+// answered, the timer left or after timerAlarmTime.
+func (t *panelTimer) tick(ctx context.Context, now time.Time, width int, big bool) (string, context.Context) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var alarm context.Context
+	elapsed := t.elapsedLocked(now)
+	if t.countdown > 0 && !t.started.IsZero() && elapsed >= t.countdown {
+		t.elapsed = t.countdown
+		t.started = time.Time{}
+		t.expired = true
+		alarm, t.stopAlarm = context.WithTimeout(ctx, timerAlarmTime)
+		t.menu.logger.WithField("countdown", t.countdown).Info("Timer ran out")
+	}
+	if t.expired {
+		return "Time is up!\nPress any button", alarm
+	}
+
+	title := "Stopwatch"
+	shown := elapsed
+	if t.countdown > 0 {
+		title = "Timer " + formatClock(t.countdown)
+		// Counting down, a second is shown until it has passed
+		shown = (t.countdown - elapsed + time.Second - 1).Truncate(time.Second)
+	}
+	if big && !t.started.IsZero() {
+		if lines, err := controller.RenderBigNumber(formatBigClock(shown), width); err == nil {
+			return strings.Join(lines, "\n"), alarm
+		}
+	}
+	status := "Running"
+	switch {
+	case !t.started.IsZero():
+	case elapsed == 0:
+		status = "Ready"
+	default:
+		status = "Stopped"
+	}
+	return title + "\n" + formatClock(shown) + " " + status, alarm
+}
+
+// alarm beeps until ctx is cancelled
+func (t *panelTimer) alarm(ctx context.Context) {
+	for {
+		if err := t.menu.buzzer.Beep(ctx, timerBeepTime); err != nil {
+			t.menu.logger.WithError(err).Warn("Failed to sound timer alarm")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(timerBeepInterval - timerBeepTime):
+		}
+	}
+}
+
+// formatBigClock formats d for big digits: minutes and seconds below an
+// hour, e.g. "05:00", and like formatClock from an hour on
+func formatBigClock(d time.Duration) string {
+	if d >= time.Hour {
+		return formatClock(d)
+	}
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// formatClock formats d as hours, minutes and seconds, e.g. "0:05:00"
+func formatClock(d time.Duration) string {
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
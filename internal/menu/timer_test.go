@@ -0,0 +1,179 @@
+package menu
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingBuzzer counts its beeps
+type countingBuzzer struct {
+	mutex sync.Mutex
+	beeps int
+}
+
+func (b *countingBuzzer) Beep(ctx context.Context, duration time.Duration) error {
+	b.mutex.Lock()
+	b.beeps++
+	b.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+	return nil
+}
+
+func (b *countingBuzzer) count() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.beeps
+}
+
+// newTimerTestMenu starts a menu whose only item runs the given timer
+// command, and opens it
+func newTimerTestMenu(t *testing.T, command string, buzzer Buzzer) (*MenuSystem, *lockedDisplay) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"timer": {Title: "Timer", Type: "display_command", Command: command},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.gestures.longPressTime = 50 * time.Millisecond
+	ms.SetBuzzer(buzzer)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+
+	click(ms, ButtonEnter)
+	return ms, display
+}
+
+// waitForText waits until the display shows text
+func waitForText(t *testing.T, display *lockedDisplay, text string) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return display.text() == text }, 3*time.Second, 10*time.Millisecond, display.text())
+}
+
+func TestTimer_Stopwatch(t *testing.T) {
+	ms, display := newTimerTestMenu(t, "timer:0", nil)
+	waitForText(t, display, "Stopwatch\n0:00:00 Ready")
+	assert.True(t, ms.TimerActive())
+
+	click(ms, ButtonEnter)
+	waitForText(t, display, "Stopwatch\n0:00:01 Running")
+	click(ms, ButtonEnter)
+	waitForText(t, display, "Stopwatch\n0:00:01 Stopped")
+
+	// Holding SELECT resets without leaving
+	ms.HandleButtonEvent(ButtonSelect, true)
+	waitForText(t, display, "Stopwatch\n0:00:00 Ready")
+	ms.HandleButtonEvent(ButtonSelect, false)
+	assert.True(t, ms.TimerActive())
+
+	// A short SELECT returns to the menu
+	click(ms, ButtonSelect)
+	assert.Eventually(t, func() bool { return !ms.TimerActive() && !ms.displayingOutput.Load() },
+		time.Second, 10*time.Millisecond)
+	assert.Contains(t, display.text(), "Timer")
+}
+
+func TestTimer_Countdown(t *testing.T) {
+	buzzer := &countingBuzzer{}
+	ms, display := newTimerTestMenu(t, "timer:1s", buzzer)
+	waitForText(t, display, "Timer 0:00:01\n0:00:01 Ready")
+
+	click(ms, ButtonEnter)
+	waitForText(t, display, "Time is up!\nPress any button")
+	assert.Eventually(t, func() bool { return buzzer.count() > 0 }, time.Second, 10*time.Millisecond)
+
+	// Any button answers the alarm and stops the beeping
+	click(ms, ButtonSelect)
+	waitForText(t, display, "Timer 0:00:01\n0:00:01 Ready")
+	assert.True(t, ms.TimerActive())
+	beeps := buzzer.count()
+	time.Sleep(timerBeepInterval + 100*time.Millisecond)
+	assert.Equal(t, beeps, buzzer.count())
+}
+
+func TestTimer_Menu(t *testing.T) {
+	ms, _ := newTimerTestMenu(t, "timer", nil)
+
+	// Back, the stopwatch and the countdowns
+	require.Len(t, ms.menuKeys, 7)
+	stopwatch := ms.currentMenu.Items[ms.menuKeys[1]]
+	assert.Equal(t, "Stopwatch", stopwatch.Title)
+	assert.Equal(t, "timer:0m", stopwatch.Command)
+	assert.Equal(t, "timer:60m", ms.currentMenu.Items[ms.menuKeys[6]].Command)
+}
+
+func TestFormatClock(t *testing.T) {
+	assert.Equal(t, "05:09", formatBigClock(5*time.Minute+9*time.Second))
+	assert.Equal(t, "1:00:00", formatBigClock(time.Hour))
+
+	assert.Equal(t, "0:00:00", formatClock(0))
+	assert.Equal(t, "0:05:09", formatClock(5*time.Minute+9*time.Second+500*time.Millisecond))
+	assert.Equal(t, "1:30:00", formatClock(90*time.Minute))
+}
+
+// fontChars records the custom characters defined
+type fontChars struct {
+	mutex   sync.Mutex
+	defined map[int][8]byte
+}
+
+func (f *fontChars) DefineCustomChar(slot int, bitmap [8]byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.defined[slot] = bitmap
+	return nil
+}
+
+func (f *fontChars) slot(slot int) [8]byte {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.defined[slot]
+}
+
+func TestTimer_BigDigits(t *testing.T) {
+	chars := &fontChars{defined: make(map[int][8]byte)}
+	cfg := config.DefaultConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"timer": {Title: "Timer", Type: "display_command", Command: "timer:10m"},
+	}
+	cfg.Menu.Shortcuts = nil
+	display := &lockedDisplay{}
+	ms := NewMenuSystem(cfg, display)
+	ms.SetBigDigits(chars)
+	require.NoError(t, ms.Start())
+	t.Cleanup(ms.Stop)
+
+	click(ms, ButtonEnter)
+	waitForText(t, display, "Timer 0:10:00\n0:10:00 Ready")
+	assert.Equal(t, [8]byte{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}, chars.slot(0), "the big font is loaded")
+
+	// Running, the time left fills the panel
+	click(ms, ButtonEnter)
+	lines, err := controller.RenderBigNumber("10:00", 16)
+	require.NoError(t, err)
+	waitForText(t, display, strings.Join(lines, "\n"))
+
+	// Leaving brings back the icons
+	click(ms, ButtonSelect)
+	assert.Eventually(t, func() bool { return !ms.TimerActive() && !ms.displayingOutput.Load() },
+		time.Second, 10*time.Millisecond)
+	gear, _ := controller.GlyphChar("gear")
+	assert.NotEqual(t, [8]byte{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}, chars.slot(int(gear[0])))
+}
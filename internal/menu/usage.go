@@ -0,0 +1,79 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/state"
+)
+
+// usageHoursPerCell is how many hours of the day each sparkline cell of the
+// usage screen covers
+const usageHoursPerCell = 2
+
+// PanelUsage counts the button presses. state.Usage satisfies it.
+type PanelUsage interface {
+	Stats() state.UsageStats
+}
+
+// SetUsage sets the statistics the usage screen shows (nil = none)
+func (ms *MenuSystem) SetUsage(usage PanelUsage) {
+	ms.usage = usage
+}
+
+// showUsage pages through the button press statistics and the idle timeout
+// they suggest
+func (ms *MenuSystem) showUsage() {
+	if ms.usage == nil {
+		ms.displayScrollingOutput("No usage stats")
+		return
+	}
+	ms.displayPagedOutput(renderUsage(ms.usage.Stats(), ms.config))
+}
+
+// renderUsage lists the presses, the busiest hour, the presses over the day
+// as a sparkline from midnight to midnight, the idle timeout and the one
+// the pauses between presses suggest
+func renderUsage(stats state.UsageStats, cfg *config.Config) string {
+	lines := []string{fmt.Sprintf("Presses %d", stats.Presses())}
+	if hour, ok := stats.BusiestHour(); ok {
+		lines = append(lines, fmt.Sprintf("Busiest %02d-%02dh", hour, hour+1))
+	}
+
+	cells := make([]float64, 24/usageHoursPerCell)
+	for hour, count := range stats.Hours {
+		cells[hour/usageHoursPerCell] += float64(count)
+	}
+	lines = append(lines, "0"+screen.Sparkline(cells, sparklineBars())+"24")
+
+	configured := time.Duration(cfg.Display.IdleTimeout) * time.Second
+	if configured <= 0 {
+		configured = state.DefaultIdleTimeout
+	}
+	suggested, ok := stats.SuggestIdleTimeout()
+	switch {
+	case !ok:
+		lines = append(lines, "Idle "+formatTimeout(configured), "Suggest: no data")
+	case cfg.Display.AutoIdleTimeout:
+		lines = append(lines, "Idle auto "+formatTimeout(suggested))
+	default:
+		lines = append(lines, "Idle "+formatTimeout(configured), "Suggest "+formatTimeout(suggested))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTimeout writes a timeout briefly, e.g. "45s", "2m" or "1m30s"
+func formatTimeout(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	default:
+		return fmt.Sprintf("%dm%02ds", int(d/time.Minute), int(d%time.Minute/time.Second))
+	}
+}
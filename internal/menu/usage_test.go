@@ -0,0 +1,37 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderUsage(t *testing.T) {
+	low, _ := controller.GlyphChar("bar1")
+	high, _ := controller.GlyphChar("bar3")
+	cfg := config.DefaultConfig()
+	cfg.Display.IdleTimeout = 300
+
+	assert.Equal(t, "Presses 0\n0"+strings.Repeat(low, 12)+"24\nIdle 5m\nSuggest: no data",
+		renderUsage(state.UsageStats{}, cfg))
+
+	stats := state.UsageStats{Pauses: make([]int, len(state.PauseBounds)+1)}
+	stats.Hours[18] = 60
+	stats.Pauses[7] = 60 // up to 2 minutes
+	assert.Equal(t, "Presses 60\nBusiest 18-19h\n0"+strings.Repeat(low, 9)+high+low+low+"24\nIdle 5m\nSuggest 2m",
+		renderUsage(stats, cfg))
+
+	cfg.Display.AutoIdleTimeout = true
+	assert.True(t, strings.HasSuffix(renderUsage(stats, cfg), "\nIdle auto 2m"))
+}
+
+func TestFormatTimeout(t *testing.T) {
+	assert.Equal(t, "45s", formatTimeout(45*time.Second))
+	assert.Equal(t, "2m", formatTimeout(2*time.Minute))
+	assert.Equal(t, "1m30s", formatTimeout(90*time.Second))
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/qnap/display-control/internal/hardware"
+	"github.com/qnap/display-control/pkg/buttons"
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,9 +27,15 @@ type IOPortReader interface {
 	Close() error
 }
 
+// newLogger returns the monitor's logger; every entry is about the copy
+// button, so it carries the button field like the panel controllers' do
+func newLogger() *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{"component": "usb_copy_monitor", "button": buttons.Copy.String()})
+}
+
 // NewUSBCopyMonitor creates a new USB copy button monitor
 func NewUSBCopyMonitor(port uint16) (*USBCopyMonitor, error) {
-	logger := logrus.WithField("component", "usb_copy_monitor")
+	logger := newLogger()
 
 	ioPort, err := hardware.NewIOPortAccess(port)
 	if err != nil {
@@ -49,7 +56,7 @@ func NewUSBCopyMonitor(port uint16) (*USBCopyMonitor, error) {
 
 // NewUSBCopyMonitorWithIOPort creates a monitor with a custom IOPortReader (for testing)
 func NewUSBCopyMonitorWithIOPort(port uint16, ioPort IOPortReader) *USBCopyMonitor {
-	logger := logrus.WithField("component", "usb_copy_monitor")
+	logger := newLogger()
 
 	monitor := &USBCopyMonitor{
 		ioPort:    ioPort,
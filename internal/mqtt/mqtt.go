@@ -0,0 +1,368 @@
+// Package mqtt is a small MQTT 3.1.1 client for publishing the panel to a
+// broker and taking commands from it.
+//
+// It only speaks QoS 0: the panel's state is published again on every
+// change, retained, so a message lost with a connection is replaced by the
+// next one. The client reconnects on its own and subscribes again.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultKeepAlive is how often the connection is checked unless configured
+// otherwise
+const DefaultKeepAlive = 60 * time.Second
+
+const (
+	// dialTimeout bounds connecting and the broker's acknowledgement
+	dialTimeout = 10 * time.Second
+	// writeTimeout bounds writing one packet
+	writeTimeout = 10 * time.Second
+	// reconnectDelay is the pause before connecting again after the broker
+	// went away or could not be reached
+	reconnectDelay = 10 * time.Second
+)
+
+// ErrNotConnected is returned by Publish while there is no connection
+var ErrNotConnected = errors.New("not connected to MQTT broker")
+
+// Message is a message published to a topic
+type Message struct {
+	Topic   string
+	Payload []byte
+	// Retain keeps the message on the broker for later subscribers
+	Retain bool
+}
+
+// Handler is told the messages received on a subscribed topic
+type Handler func(msg Message)
+
+// Options configure the client
+type Options struct {
+	// Broker is "tcp://host:port" or "tls://host:port"; the port defaults
+	// to 1883 and 8883
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is how often the connection is checked (0 =
+	// DefaultKeepAlive)
+	KeepAlive time.Duration
+	// Will is published by the broker when the connection is lost without
+	// Close, e.g. an "offline" availability
+	Will *Message
+}
+
+// subscription is a topic filter and its handler
+type subscription struct {
+	filter  string
+	handler Handler
+}
+
+// Client keeps a connection to an MQTT broker
+type Client struct {
+	opts    Options
+	network string
+	address string
+	logger  *logrus.Entry
+	stop    chan struct{}
+	wg      sync.WaitGroup
+
+	mutex sync.Mutex
+	// conn is the connection to the broker, nil while there is none
+	conn          net.Conn
+	subscriptions []subscription
+	onConnect     func()
+	closed        bool
+}
+
+// NewClient creates a client for the broker in opts. It connects once Start
+// is called.
+func NewClient(opts Options) (*Client, error) {
+	broker, err := url.Parse(opts.Broker)
+	if err != nil || broker.Host == "" {
+		return nil, fmt.Errorf("invalid MQTT broker %q", opts.Broker)
+	}
+	port := "1883"
+	switch broker.Scheme {
+	case "tcp", "mqtt":
+		broker.Scheme = "tcp"
+	case "tls", "ssl", "mqtts":
+		broker.Scheme = "tls"
+		port = "8883"
+	default:
+		return nil, fmt.Errorf("MQTT broker %q must start with tcp:// or tls://", opts.Broker)
+	}
+	address := broker.Host
+	if broker.Port() == "" {
+		address = net.JoinHostPort(broker.Hostname(), port)
+	}
+	if opts.ClientID == "" {
+		return nil, errors.New("MQTT client ID missing")
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+
+	return &Client{
+		opts:    opts,
+		network: broker.Scheme,
+		address: address,
+		logger:  logrus.WithFields(logrus.Fields{"component": "mqtt", "broker": address}),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Subscribe tells handler the messages on topics matching filter, which may
+// hold the wildcards + and #. Subscriptions are made on every connect, so
+// they are best added before Start.
+func (c *Client) Subscribe(filter string, handler Handler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.subscriptions = append(c.subscriptions, subscription{filter: filter, handler: handler})
+	if c.conn != nil {
+		c.writeLocked(subscribePacket(uint16(len(c.subscriptions)), []string{filter}))
+	}
+}
+
+// SetConnectHandler sets a function run after every connect, e.g. to
+// publish the current state
+func (c *Client) SetConnectHandler(handler func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onConnect = handler
+}
+
+// Publish sends msg to the broker
+func (c *Client) Publish(msg Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return c.writeLocked(publishPacket(msg))
+}
+
+// Start connects to the broker in the background, and again whenever the
+// connection is lost, until Close is called
+func (c *Client) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			if err := c.session(); err != nil {
+				c.logger.WithError(err).Warn("MQTT broker not reachable")
+			}
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+}
+
+// Close disconnects from the broker and stops connecting to it. The will
+// is not published.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	if c.conn != nil {
+		c.writeLocked(packet{header: packetDisconnect})
+		c.conn.Close()
+	}
+	c.mutex.Unlock()
+
+	close(c.stop)
+	c.wg.Wait()
+	return nil
+}
+
+// session connects to the broker and handles its packets until the
+// connection is lost or the client closed
+func (c *Client) session() error {
+	conn, reader, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.conn = conn
+	filters := make([]string, 0, len(c.subscriptions))
+	for _, sub := range c.subscriptions {
+		filters = append(filters, sub.filter)
+	}
+	if len(filters) > 0 {
+		c.writeLocked(subscribePacket(1, filters))
+	}
+	onConnect := c.onConnect
+	c.mutex.Unlock()
+	c.logger.Info("Connected to MQTT broker")
+
+	defer func() {
+		c.mutex.Lock()
+		c.conn = nil
+		c.mutex.Unlock()
+	}()
+
+	if onConnect != nil {
+		onConnect()
+	}
+
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go c.ping(pingDone)
+
+	for {
+		// The broker answers the pings, so a connection silent for longer
+		// than the keep alive is dead
+		conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		p, err := readPacket(reader)
+		if err != nil {
+			select {
+			case <-c.stop:
+				return nil
+			default:
+			}
+			return fmt.Errorf("connection lost: %w", err)
+		}
+		switch p.header & packetTypeMask {
+		case packetPublish:
+			msg, err := parsePublish(p)
+			if err != nil {
+				return err
+			}
+			c.deliver(msg)
+		case packetSuback:
+			for _, code := range p.body[min(2, len(p.body)):] {
+				if code == 0x80 {
+					c.logger.Warn("MQTT broker refused a subscription")
+				}
+			}
+		case packetPingresp:
+		default:
+			c.logger.WithField("type", p.header>>4).Debug("Ignoring MQTT packet")
+		}
+	}
+}
+
+// connect dials the broker and waits for it to accept the connection
+func (c *Client) connect() (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var err error
+	if c.network == "tls" {
+		host, _, _ := net.SplitHostPort(c.address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", c.address)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write(connectPacket(c.opts).encode()); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	ack, err := readPacket(reader)
+	if err == nil {
+		err = parseConnack(ack)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// ping sends PINGREQ every keep alive interval until done is closed
+func (c *Client) ping(done chan struct{}) {
+	ticker := time.NewTicker(c.opts.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.mutex.Lock()
+			if c.conn != nil {
+				c.writeLocked(packet{header: packetPingreq})
+			}
+			c.mutex.Unlock()
+		}
+	}
+}
+
+// deliver tells the handlers whose filter matches the message's topic
+func (c *Client) deliver(msg Message) {
+	c.mutex.Lock()
+	var handlers []Handler
+	for _, sub := range c.subscriptions {
+		if Match(sub.filter, msg.Topic) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+// writeLocked sends a packet. A failed write closes the connection, which
+// ends the session. Caller must hold the mutex.
+func (c *Client) writeLocked(p packet) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(p.encode()); err != nil {
+		c.conn.Close()
+		return fmt.Errorf("failed to write to MQTT broker: %w", err)
+	}
+	return nil
+}
+
+// Match reports whether topic matches filter, where + stands for one level
+// and a trailing # for any number of them
+func Match(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
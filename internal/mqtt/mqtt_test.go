@@ -0,0 +1,178 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker accepts one client, acknowledges its connection and
+// subscriptions, and records what it publishes
+type fakeBroker struct {
+	listener  net.Listener
+	connects  chan packet
+	published chan Message
+	subscribe chan packet
+	conns     chan net.Conn
+}
+
+// startBroker serves a fake broker on a local port
+func startBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	b := &fakeBroker{
+		listener:  listener,
+		connects:  make(chan packet, 4),
+		published: make(chan Message, 16),
+		subscribe: make(chan packet, 4),
+		conns:     make(chan net.Conn, 4),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.conns <- conn
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// serve answers one client
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch p.header & packetTypeMask {
+		case packetConnect:
+			b.connects <- p
+			conn.Write(packet{header: packetConnack, body: []byte{0, 0}}.encode())
+		case packetSubscribe:
+			b.subscribe <- p
+			conn.Write(packet{header: packetSuback, body: append(p.body[:2:2], 0)}.encode())
+		case packetPublish:
+			msg, _ := parsePublish(p)
+			b.published <- msg
+		case packetPingreq:
+			conn.Write(packet{header: packetPingresp}.encode())
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// url is the address of the broker for Options
+func (b *fakeBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func TestClient(t *testing.T) {
+	broker := startBroker(t)
+	client, err := NewClient(Options{
+		Broker:   broker.url(),
+		ClientID: "panel",
+		Username: "user",
+		Password: "secret",
+		Will:     &Message{Topic: "panel/status", Payload: []byte("offline"), Retain: true},
+	})
+	require.NoError(t, err)
+
+	received := make(chan Message, 4)
+	client.Subscribe("panel/+/set", func(msg Message) { received <- msg })
+	connected := make(chan struct{}, 1)
+	client.SetConnectHandler(func() {
+		assert.NoError(t, client.Publish(Message{Topic: "panel/status", Payload: []byte("online"), Retain: true}))
+		connected <- struct{}{}
+	})
+	assert.ErrorIs(t, client.Publish(Message{Topic: "panel/status"}), ErrNotConnected)
+
+	client.Start()
+	defer client.Close()
+
+	connect := <-broker.connects
+	assert.Contains(t, string(connect.body), "panel/status")
+	assert.Contains(t, string(connect.body), "offline")
+	assert.Contains(t, string(connect.body), "secret")
+	assert.Contains(t, string((<-broker.subscribe).body), "panel/+/set")
+
+	<-connected
+	online := <-broker.published
+	assert.Equal(t, Message{Topic: "panel/status", Payload: []byte("online"), Retain: true}, online)
+
+	// Messages from the broker reach the matching handlers
+	conn := <-broker.conns
+	_, err = conn.Write(publishPacket(Message{Topic: "panel/backlight/set", Payload: []byte("OFF")}).encode())
+	require.NoError(t, err)
+	select {
+	case msg := <-received:
+		assert.Equal(t, "panel/backlight/set", msg.Topic)
+		assert.Equal(t, "OFF", string(msg.Payload))
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient(Options{Broker: "tcp://broker", ClientID: "panel"})
+	require.NoError(t, err)
+	assert.Equal(t, "broker:1883", client.address)
+	assert.Equal(t, DefaultKeepAlive, client.opts.KeepAlive)
+
+	client, err = NewClient(Options{Broker: "tls://broker:9883", ClientID: "panel"})
+	require.NoError(t, err)
+	assert.Equal(t, "tls", client.network)
+	assert.Equal(t, "broker:9883", client.address)
+
+	_, err = NewClient(Options{Broker: "http://broker", ClientID: "panel"})
+	assert.Error(t, err)
+	_, err = NewClient(Options{Broker: "broker", ClientID: "panel"})
+	assert.Error(t, err)
+	_, err = NewClient(Options{Broker: "tcp://broker"})
+	assert.Error(t, err)
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	payload := make([]byte, 300)
+	encoded := publishPacket(Message{Topic: "a/b", Payload: payload, Retain: true}).encode()
+	assert.Equal(t, []byte{0x31, 0xB1, 0x02}, encoded[:3], "two byte remaining length")
+
+	reader := bufio.NewReader(bytes.NewReader(encoded))
+	p, err := readPacket(reader)
+	require.NoError(t, err)
+	msg, err := parsePublish(p)
+	require.NoError(t, err)
+	assert.Equal(t, "a/b", msg.Topic)
+	assert.True(t, msg.Retain)
+	assert.Len(t, msg.Payload, 300)
+}
+
+func TestParseConnack(t *testing.T) {
+	assert.NoError(t, parseConnack(packet{header: packetConnack, body: []byte{0, 0}}))
+	assert.EqualError(t, parseConnack(packet{header: packetConnack, body: []byte{0, 4}}), "broker refused connection: bad user name or password")
+	assert.Error(t, parseConnack(packet{header: packetPingresp}))
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("a/b", "a/b"))
+	assert.False(t, Match("a/b", "a/b/c"))
+	assert.True(t, Match("a/+/c", "a/b/c"))
+	assert.False(t, Match("a/+", "a/b/c"))
+	assert.True(t, Match("a/#", "a/b/c"))
+	assert.False(t, Match("b/#", "a/b"))
+}
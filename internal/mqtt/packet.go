@@ -0,0 +1,198 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1, in the upper four bits of the first
+// byte
+const (
+	packetConnect     byte = 0x10
+	packetConnack     byte = 0x20
+	packetPublish     byte = 0x30
+	packetSubscribe   byte = 0x80
+	packetSuback      byte = 0x90
+	packetPingreq     byte = 0xC0
+	packetPingresp    byte = 0xD0
+	packetDisconnect  byte = 0xE0
+	packetTypeMask    byte = 0xF0
+	publishRetainFlag byte = 0x01
+	publishQoSMask    byte = 0x06
+)
+
+// maxPacketSize bounds the packets read from the broker; the panel has no
+// use for large ones
+const maxPacketSize = 1 << 20
+
+// packet is one control packet
+type packet struct {
+	// header is the first byte: the type and its flags
+	header byte
+	body   []byte
+}
+
+// encode returns the packet as sent on the wire
+func (p packet) encode() []byte {
+	out := []byte{p.header}
+	out = appendLength(out, len(p.body))
+	return append(out, p.body...)
+}
+
+// appendLength appends the variable length encoding of a remaining length
+func appendLength(out []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if length == 0 {
+			return out
+		}
+	}
+}
+
+// appendString appends a length prefixed UTF-8 string or binary field
+func appendString(out []byte, s []byte) []byte {
+	out = binary.BigEndian.AppendUint16(out, uint16(len(s)))
+	return append(out, s...)
+}
+
+// readPacket reads the next packet from r
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("packet of %d bytes too large", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{header: header, body: body}, nil
+}
+
+// connectPacket builds the CONNECT packet for opts
+func connectPacket(opts Options) packet {
+	body := appendString(nil, []byte("MQTT"))
+	body = append(body, 4) // protocol level 3.1.1
+
+	flags := byte(0x02) // clean session
+	if opts.Will != nil {
+		flags |= 0x04
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive.Seconds()))
+
+	body = appendString(body, []byte(opts.ClientID))
+	if opts.Will != nil {
+		body = appendString(body, []byte(opts.Will.Topic))
+		body = appendString(body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		body = appendString(body, []byte(opts.Username))
+		if opts.Password != "" {
+			body = appendString(body, []byte(opts.Password))
+		}
+	}
+	return packet{header: packetConnect, body: body}
+}
+
+// connackErrors are the reasons a broker refuses a connection
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// parseConnack returns why the broker refused the connection, if it did
+func parseConnack(p packet) error {
+	if p.header&packetTypeMask != packetConnack || len(p.body) != 2 {
+		return errors.New("broker did not acknowledge the connection")
+	}
+	if code := p.body[1]; code != 0 {
+		if reason, ok := connackErrors[code]; ok {
+			return fmt.Errorf("broker refused connection: %s", reason)
+		}
+		return fmt.Errorf("broker refused connection: code %d", code)
+	}
+	return nil
+}
+
+// publishPacket builds a QoS 0 PUBLISH packet
+func publishPacket(msg Message) packet {
+	header := packetPublish
+	if msg.Retain {
+		header |= publishRetainFlag
+	}
+	body := appendString(nil, []byte(msg.Topic))
+	return packet{header: header, body: append(body, msg.Payload...)}
+}
+
+// parsePublish reads the topic and payload of a PUBLISH packet
+func parsePublish(p packet) (Message, error) {
+	if len(p.body) < 2 {
+		return Message{}, errors.New("malformed publish")
+	}
+	length := int(binary.BigEndian.Uint16(p.body))
+	rest := p.body[2:]
+	if len(rest) < length {
+		return Message{}, errors.New("malformed publish")
+	}
+	msg := Message{Topic: string(rest[:length]), Retain: p.header&publishRetainFlag != 0}
+	rest = rest[length:]
+	// QoS 1 and 2 carry a packet identifier; the client only subscribes with
+	// QoS 0, but a broker may still send it
+	if p.header&publishQoSMask != 0 {
+		if len(rest) < 2 {
+			return Message{}, errors.New("malformed publish")
+		}
+		rest = rest[2:]
+	}
+	msg.Payload = rest
+	return msg, nil
+}
+
+// subscribePacket builds a SUBSCRIBE packet for filters at QoS 0
+func subscribePacket(id uint16, filters []string) packet {
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, filter := range filters {
+		body = appendString(body, []byte(filter))
+		body = append(body, 0)
+	}
+	// SUBSCRIBE has the fixed flags 0010
+	return packet{header: packetSubscribe | 0x02, body: body}
+}
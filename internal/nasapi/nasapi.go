@@ -0,0 +1,294 @@
+// Package nasapi reads pool health, alerts and update availability from the
+// API of the operating system running on the NAS, for boxes running
+// TrueNAS SCALE or OpenMediaVault instead of QTS.
+//
+// A Monitor polls one Source, keeps its latest report for the status items
+// and raises a panel alert for every unhealthy pool and every warning or
+// worse the system itself reports, clearing them once they are gone.
+package nasapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPollInterval is how often the API is asked when no interval is set
+const DefaultPollInterval = time.Minute
+
+// requestTimeout bounds one poll, so a hanging API does not hold up the next
+const requestTimeout = 20 * time.Second
+
+// maxResponseSize bounds a response read from the API
+const maxResponseSize = 1024 * 1024
+
+// errNotPolled is the state of the monitor before its first poll finished
+var errNotPolled = errors.New("not polled yet")
+
+// Severity is how urgent an alert of the NAS is
+type Severity int
+
+const (
+	Warning Severity = iota
+	Critical
+)
+
+// Pool is a storage pool or RAID array
+type Pool struct {
+	Name string
+	// Status is the system's word for the pool's state, e.g. "ONLINE",
+	// "DEGRADED" or "clean"
+	Status  string
+	Healthy bool
+}
+
+// Alert is a warning or worse reported by the system
+type Alert struct {
+	// ID identifies the alert across polls
+	ID       string
+	Text     string
+	Severity Severity
+}
+
+// Update is whether a system update is available
+type Update struct {
+	// Checked is false if availability could not be determined
+	Checked   bool
+	Available bool
+	// Version is the available release, or a count of packages, if known
+	Version string
+}
+
+// Report is the state of the NAS at one poll
+type Report struct {
+	Pools  []Pool
+	Alerts []Alert
+	Update Update
+}
+
+// Source is the API of a NAS operating system
+type Source interface {
+	// Name is the system's name, e.g. "TrueNAS"
+	Name() string
+	// Fetch polls the API once
+	Fetch(ctx context.Context) (Report, error)
+}
+
+// Alerter shows and clears alerts. alert.Manager satisfies it.
+type Alerter interface {
+	Raise(key, text string)
+	RaiseCritical(key, text string)
+	Clear(key string)
+}
+
+// Monitor polls a source and keeps its latest report
+type Monitor struct {
+	source   Source
+	interval time.Duration
+	logger   *logrus.Entry
+
+	mutex  sync.Mutex
+	report Report
+	// updated is when report was fetched; zero before the first success
+	updated time.Time
+	err     error
+	// raised holds the keys of the alerts the monitor has raised
+	raised map[string]bool
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// NewMonitor creates a monitor asking source every interval (0 for
+// DefaultPollInterval)
+func NewMonitor(source Source, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &Monitor{
+		source:   source,
+		interval: interval,
+		logger:   logrus.WithFields(logrus.Fields{"component": "nas_api", "system": source.Name()}),
+		err:      errNotPolled,
+		raised:   make(map[string]bool),
+	}
+}
+
+// Name returns the name of the polled system
+func (m *Monitor) Name() string {
+	return m.source.Name()
+}
+
+// Start polls the source in the background, raising alerts on alerter (nil
+// to only keep reports)
+func (m *Monitor) Start(alerter Alerter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.done.Add(1)
+	go func() {
+		defer m.done.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.poll(ctx, alerter)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends polling and waits for a poll in progress
+func (m *Monitor) Stop() {
+	m.mutex.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		m.done.Wait()
+	}
+}
+
+// Latest returns the last report, or why the last poll failed
+func (m *Monitor) Latest() (Report, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.report, m.err
+}
+
+// Last returns the last report fetched and when, even if polls failed
+// since. It fails only if no poll succeeded yet.
+func (m *Monitor) Last() (Report, time.Time, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.updated.IsZero() {
+		return Report{}, time.Time{}, m.err
+	}
+	return m.report, m.updated, nil
+}
+
+// poll fetches a report and updates the alerts. Losing and regaining the
+// API are logged once each rather than on every poll; the alerts of the last
+// report stay up meanwhile.
+func (m *Monitor) poll(ctx context.Context, alerter Alerter) {
+	pollCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	report, err := m.source.Fetch(pollCtx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err != nil {
+		if m.err == nil || errors.Is(m.err, errNotPolled) {
+			m.logger.WithError(err).Warn("NAS API unreachable")
+		}
+		m.err = err
+		return
+	}
+	if m.err != nil && !errors.Is(m.err, errNotPolled) {
+		m.logger.Info("NAS API reachable again")
+	}
+	m.report, m.updated, m.err = report, time.Now(), nil
+	if alerter != nil {
+		m.raise(alerter, report)
+	}
+}
+
+// raise raises the alerts of a report and clears those no longer in it.
+// Caller must hold the mutex.
+func (m *Monitor) raise(alerter Alerter, report Report) {
+	current := make(map[string]bool)
+	for _, pool := range report.Pools {
+		if pool.Healthy {
+			continue
+		}
+		key := "nas:pool:" + pool.Name
+		current[key] = true
+		alerter.RaiseCritical(key, fmt.Sprintf("Pool %s\n%s", pool.Name, pool.Status))
+	}
+	for _, a := range report.Alerts {
+		key := "nas:alert:" + a.ID
+		current[key] = true
+		text := m.source.Name() + " alert\n" + a.Text
+		if a.Severity == Critical {
+			alerter.RaiseCritical(key, text)
+		} else {
+			alerter.Raise(key, text)
+		}
+	}
+
+	for key := range m.raised {
+		if !current[key] {
+			alerter.Clear(key)
+		}
+	}
+	m.raised = current
+}
+
+// PoolSummary renders the pools on one line, e.g. "Pools 2 OK" or
+// "tank DEGRADED"
+func (r Report) PoolSummary() string {
+	for _, pool := range r.Pools {
+		if !pool.Healthy {
+			return pool.Name + " " + pool.Status
+		}
+	}
+	return fmt.Sprintf("Pools %d OK", len(r.Pools))
+}
+
+// AlertSummary renders the alerts on one line, e.g. "No NAS alerts" or
+// "3 NAS alerts"
+func (r Report) AlertSummary() string {
+	switch len(r.Alerts) {
+	case 0:
+		return "No NAS alerts"
+	case 1:
+		return "1 NAS alert"
+	}
+	return fmt.Sprintf("%d NAS alerts", len(r.Alerts))
+}
+
+// UpdateSummary renders the update availability on one line, e.g.
+// "Update 24.04.2" or "Up to date"
+func (r Report) UpdateSummary() string {
+	switch {
+	case !r.Update.Checked:
+		return "Update unknown"
+	case !r.Update.Available:
+		return "Up to date"
+	case r.Update.Version != "":
+		return "Update " + r.Update.Version
+	}
+	return "Update available"
+}
+
+// markup matches the HTML tags some systems put in alert texts
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips markup and runs of white space from an alert text
+func plainText(text string) string {
+	return strings.Join(strings.Fields(markup.ReplaceAllString(text, "")), " ")
+}
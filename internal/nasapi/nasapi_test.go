@@ -0,0 +1,185 @@
+package nasapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSource returns its report or error
+type stubSource struct {
+	report Report
+	err    error
+}
+
+func (s *stubSource) Name() string {
+	return "Stub"
+}
+
+func (s *stubSource) Fetch(ctx context.Context) (Report, error) {
+	return s.report, s.err
+}
+
+// recordingAlerter keeps the active alerts, critical ones marked with a
+// leading "!"
+type recordingAlerter struct {
+	active map[string]string
+}
+
+func (a *recordingAlerter) Raise(key, text string) {
+	a.active[key] = text
+}
+
+func (a *recordingAlerter) RaiseCritical(key, text string) {
+	a.active[key] = "!" + text
+}
+
+func (a *recordingAlerter) Clear(key string) {
+	delete(a.active, key)
+}
+
+func TestMonitor_Alerts(t *testing.T) {
+	source := &stubSource{report: Report{
+		Pools:  []Pool{{Name: "tank", Status: "DEGRADED"}, {Name: "apps", Status: "ONLINE", Healthy: true}},
+		Alerts: []Alert{{ID: "a1", Text: "Disk sda is hot", Severity: Warning}},
+	}}
+	alerter := &recordingAlerter{active: make(map[string]string)}
+	m := NewMonitor(source, time.Hour)
+
+	_, err := m.Latest()
+	assert.ErrorIs(t, err, errNotPolled)
+	_, _, err = m.Last()
+	assert.ErrorIs(t, err, errNotPolled)
+
+	m.poll(context.Background(), alerter)
+	assert.Equal(t, map[string]string{
+		"nas:pool:tank": "!Pool tank\nDEGRADED",
+		"nas:alert:a1":  "Stub alert\nDisk sda is hot",
+	}, alerter.active)
+	report, err := m.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, "tank DEGRADED", report.PoolSummary())
+	assert.Equal(t, "1 NAS alert", report.AlertSummary())
+
+	// A failed poll keeps the alerts up
+	source.err = errors.New("connection refused")
+	m.poll(context.Background(), alerter)
+	assert.Len(t, alerter.active, 2)
+	_, err = m.Latest()
+	assert.Error(t, err)
+	last, updated, err := m.Last()
+	require.NoError(t, err, "the last report is kept")
+	assert.Equal(t, "tank DEGRADED", last.PoolSummary())
+	assert.False(t, updated.IsZero())
+
+	// Gone from the report, gone from the panel
+	source.err = nil
+	source.report = Report{Pools: []Pool{{Name: "tank", Status: "ONLINE", Healthy: true}}}
+	m.poll(context.Background(), alerter)
+	assert.Empty(t, alerter.active)
+	report, _ = m.Latest()
+	assert.Equal(t, "Pools 1 OK", report.PoolSummary())
+	assert.Equal(t, "No NAS alerts", report.AlertSummary())
+	assert.Equal(t, "Update unknown", report.UpdateSummary())
+}
+
+func TestTrueNAS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v2.0/pool":
+			w.Write([]byte(`[{"name":"tank","status":"DEGRADED","healthy":false},{"name":"apps","status":"ONLINE","healthy":true}]`))
+		case "GET /api/v2.0/alert/list":
+			w.Write([]byte(`[
+				{"uuid":"u1","level":"CRITICAL","formatted":"Pool tank state is <b>DEGRADED</b>:\n One or more devices are faulted.","dismissed":false},
+				{"uuid":"u2","level":"WARNING","formatted":"Old alert","dismissed":true},
+				{"uuid":"u3","level":"INFO","formatted":"Scrub finished","dismissed":false}
+			]`))
+		case "POST /api/v2.0/update/check_available":
+			w.Write([]byte(`{"status":"AVAILABLE","version":"24.04.2"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	report, err := NewTrueNAS(server.URL+"/", "secret", server.Client()).Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Pool{{Name: "tank", Status: "DEGRADED"}, {Name: "apps", Status: "ONLINE", Healthy: true}}, report.Pools)
+	assert.Equal(t, []Alert{{
+		ID:       "u1",
+		Text:     "Pool tank state is DEGRADED: One or more devices are faulted.",
+		Severity: Critical,
+	}}, report.Alerts)
+	assert.Equal(t, "Update 24.04.2", report.UpdateSummary())
+
+	_, err = NewTrueNAS(server.URL, "wrong", server.Client()).Fetch(context.Background())
+	assert.Error(t, err)
+}
+
+func TestOpenMediaVault(t *testing.T) {
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Service string            `json:"service"`
+			Method  string            `json:"method"`
+			Params  map[string]string `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		if request.Service == "Session" && request.Method == "login" {
+			logins++
+			ok := request.Params["username"] == "admin" && request.Params["password"] == "openmediavault"
+			if ok {
+				http.SetCookie(w, &http.Cookie{Name: "OPENMEDIAVAULT-SESSIONID", Value: "s1"})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"response": map[string]any{"authenticated": ok}, "error": nil})
+			return
+		}
+		if cookie, err := r.Cookie("OPENMEDIAVAULT-SESSIONID"); err != nil || cookie.Value != "s1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"response":null,"error":{"code":5001,"message":"Session not authenticated."}}`))
+			return
+		}
+		switch request.Service + "." + request.Method {
+		case "RaidMgmt.getList":
+			w.Write([]byte(`{"response":{"total":2,"data":[
+				{"devicefile":"/dev/md0","name":"data","state":"clean, degraded"},
+				{"devicefile":"/dev/md1","name":"","state":"clean"}
+			]},"error":null}`))
+		case "System.getInformation":
+			w.Write([]byte(`{"response":{"hostname":"omv","availablePkgUpdates":3},"error":null}`))
+		}
+	}))
+	defer server.Close()
+
+	omv := NewOpenMediaVault(server.URL, "admin", "openmediavault", server.Client())
+	report, err := omv.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Pool{{Name: "data", Status: "clean, degraded"}, {Name: "md1", Status: "clean", Healthy: true}}, report.Pools)
+	assert.Empty(t, report.Alerts)
+	assert.Equal(t, "Update 3 pkgs", report.UpdateSummary())
+
+	// An expired session is started again
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	omv.client.Jar.SetCookies(serverURL, []*http.Cookie{{Name: "OPENMEDIAVAULT-SESSIONID", Value: "expired"}})
+	_, err = omv.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, logins)
+
+	_, err = NewOpenMediaVault(server.URL, "admin", "wrong", server.Client()).Fetch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "login failed")
+}
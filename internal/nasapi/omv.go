@@ -0,0 +1,195 @@
+package nasapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+)
+
+// omvPath is the endpoint of the OpenMediaVault RPC API
+const omvPath = "/rpc.php"
+
+// omvSessionErrors are the RPC error codes of a missing or expired session,
+// after which the source logs in again
+var omvSessionErrors = map[int]bool{5001: true, 5002: true}
+
+// omvUnhealthy are the words of a RAID array state that make it unhealthy,
+// e.g. "clean, degraded"
+var omvUnhealthy = []string{"degraded", "failed", "inactive"}
+
+// omvError is an error returned by an RPC
+type omvError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *omvError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// OpenMediaVault reads an OpenMediaVault system through its RPC API, logged
+// in with the credentials of an administrator. Its pools are the RAID
+// arrays; OpenMediaVault keeps no alert list, so there are no other alerts.
+type OpenMediaVault struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+
+	// mutex keeps a login from running twice
+	mutex    sync.Mutex
+	loggedIn bool
+}
+
+// NewOpenMediaVault creates a source for the system at url, e.g.
+// "http://localhost", using the transport and timeout of client for the
+// requests. The session cookie is kept by the source.
+func NewOpenMediaVault(url, username, password string, client *http.Client) *OpenMediaVault {
+	jar, _ := cookiejar.New(nil)
+	return &OpenMediaVault{
+		url:      strings.TrimSuffix(url, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Transport: client.Transport, Timeout: client.Timeout, Jar: jar},
+	}
+}
+
+// Name returns "OpenMediaVault"
+func (o *OpenMediaVault) Name() string {
+	return "OpenMediaVault"
+}
+
+// Fetch reads the RAID arrays and whether package updates are available
+func (o *OpenMediaVault) Fetch(ctx context.Context) (Report, error) {
+	var report Report
+
+	var arrays struct {
+		Data []struct {
+			DeviceFile string `json:"devicefile"`
+			Name       string `json:"name"`
+			State      string `json:"state"`
+		} `json:"data"`
+	}
+	if err := o.call(ctx, "RaidMgmt", "getList", map[string]int{"start": 0, "limit": -1}, &arrays); err != nil {
+		return report, err
+	}
+	for _, array := range arrays.Data {
+		name := array.Name
+		if name == "" {
+			name = strings.TrimPrefix(array.DeviceFile, "/dev/")
+		}
+		healthy := true
+		for _, word := range omvUnhealthy {
+			if strings.Contains(array.State, word) {
+				healthy = false
+			}
+		}
+		report.Pools = append(report.Pools, Pool{Name: name, Status: array.State, Healthy: healthy})
+	}
+
+	// OpenMediaVault 6 says whether there are updates, 7 how many
+	var info struct {
+		PkgUpdatesAvailable *bool `json:"pkgUpdatesAvailable"`
+		AvailablePkgUpdates *int  `json:"availablePkgUpdates"`
+	}
+	if err := o.call(ctx, "System", "getInformation", nil, &info); err != nil {
+		return report, err
+	}
+	switch {
+	case info.AvailablePkgUpdates != nil:
+		report.Update = Update{Checked: true, Available: *info.AvailablePkgUpdates > 0}
+		if report.Update.Available {
+			report.Update.Version = fmt.Sprintf("%d pkgs", *info.AvailablePkgUpdates)
+		}
+	case info.PkgUpdatesAvailable != nil:
+		report.Update = Update{Checked: true, Available: *info.PkgUpdatesAvailable}
+	}
+	return report, nil
+}
+
+// call runs one RPC, logging in first if there is no session or it expired
+func (o *OpenMediaVault) call(ctx context.Context, service, method string, params, result any) error {
+	if err := o.login(ctx, false); err != nil {
+		return err
+	}
+	err := o.rpc(ctx, service, method, params, result)
+	var rpcErr *omvError
+	if errors.As(err, &rpcErr) && omvSessionErrors[rpcErr.Code] {
+		if err := o.login(ctx, true); err != nil {
+			return err
+		}
+		err = o.rpc(ctx, service, method, params, result)
+	}
+	return err
+}
+
+// login starts a session unless there is one, or always when again is set
+func (o *OpenMediaVault) login(ctx context.Context, again bool) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.loggedIn && !again {
+		return nil
+	}
+	o.loggedIn = false
+	credentials := map[string]string{"username": o.username, "password": o.password}
+	var answer struct {
+		Authenticated bool `json:"authenticated"`
+	}
+	if err := o.rpc(ctx, "Session", "login", credentials, &answer); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	if !answer.Authenticated {
+		return errors.New("login failed: not authenticated")
+	}
+	o.loggedIn = true
+	return nil
+}
+
+// rpc sends one request and decodes its response into result
+func (o *OpenMediaVault) rpc(ctx context.Context, service, method string, params, result any) error {
+	payload, err := json.Marshal(map[string]any{
+		"service": service,
+		"method":  method,
+		"params":  params,
+		"options": nil,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url+omvPath, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := o.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	var answer struct {
+		Response json.RawMessage `json:"response"`
+		Error    *omvError       `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(&answer); err != nil {
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s.%s failed: %s", service, method, response.Status)
+		}
+		return fmt.Errorf("malformed answer to %s.%s: %w", service, method, err)
+	}
+	if answer.Error != nil {
+		return answer.Error
+	}
+	if err := json.Unmarshal(answer.Response, result); err != nil {
+		return fmt.Errorf("malformed answer to %s.%s: %w", service, method, err)
+	}
+	return nil
+}
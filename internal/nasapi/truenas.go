@@ -0,0 +1,132 @@
+package nasapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// trueNASPath is the base path of the TrueNAS SCALE REST API
+const trueNASPath = "/api/v2.0"
+
+// trueNASSeverities are the alert levels worth a panel alert. INFO and
+// NOTICE are left to the web interface.
+var trueNASSeverities = map[string]Severity{
+	"WARNING":   Warning,
+	"ERROR":     Critical,
+	"CRITICAL":  Critical,
+	"ALERT":     Critical,
+	"EMERGENCY": Critical,
+}
+
+// TrueNAS reads a TrueNAS SCALE system through its REST API, authenticated
+// with an API key
+type TrueNAS struct {
+	url    string
+	apiKey string
+	client *http.Client
+	logger *logrus.Entry
+}
+
+// NewTrueNAS creates a source for the system at url, e.g.
+// "https://localhost", using client for the requests
+func NewTrueNAS(url, apiKey string, client *http.Client) *TrueNAS {
+	return &TrueNAS{
+		url:    strings.TrimSuffix(url, "/"),
+		apiKey: apiKey,
+		client: client,
+		logger: logrus.WithFields(logrus.Fields{"component": "nas_api", "system": "TrueNAS"}),
+	}
+}
+
+// Name returns "TrueNAS"
+func (t *TrueNAS) Name() string {
+	return "TrueNAS"
+}
+
+// Fetch reads the pools, the alerts not dismissed and whether an update is
+// available. An update check that fails, e.g. without internet access,
+// leaves the update unknown rather than failing the report.
+func (t *TrueNAS) Fetch(ctx context.Context) (Report, error) {
+	var report Report
+
+	var pools []struct {
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Healthy bool   `json:"healthy"`
+	}
+	if err := t.call(ctx, http.MethodGet, "/pool", &pools); err != nil {
+		return report, err
+	}
+	for _, pool := range pools {
+		report.Pools = append(report.Pools, Pool{Name: pool.Name, Status: pool.Status, Healthy: pool.Healthy})
+	}
+
+	var alerts []struct {
+		UUID      string `json:"uuid"`
+		Level     string `json:"level"`
+		Formatted string `json:"formatted"`
+		Dismissed bool   `json:"dismissed"`
+	}
+	if err := t.call(ctx, http.MethodGet, "/alert/list", &alerts); err != nil {
+		return report, err
+	}
+	for _, a := range alerts {
+		severity, ok := trueNASSeverities[a.Level]
+		if !ok || a.Dismissed {
+			continue
+		}
+		report.Alerts = append(report.Alerts, Alert{ID: a.UUID, Text: plainText(a.Formatted), Severity: severity})
+	}
+
+	var update struct {
+		Status  string `json:"status"`
+		Version string `json:"version"`
+	}
+	if err := t.call(ctx, http.MethodPost, "/update/check_available", &update); err != nil {
+		t.logger.WithError(err).Debug("Update check failed")
+		return report, nil
+	}
+	switch update.Status {
+	case "AVAILABLE":
+		report.Update = Update{Checked: true, Available: true, Version: update.Version}
+	case "UNAVAILABLE":
+		report.Update = Update{Checked: true}
+	}
+	return report, nil
+}
+
+// call sends one request and decodes the JSON answer into result
+func (t *TrueNAS) call(ctx context.Context, method, path string, result any) error {
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewReader([]byte("{}"))
+	}
+	request, err := http.NewRequestWithContext(ctx, method, t.url+trueNASPath+path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+t.apiKey)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := t.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s failed: %s", method, path, response.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(result); err != nil {
+		return fmt.Errorf("malformed answer to %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package oled
+
+import "fmt"
+
+// TextGrid shows lines of text on a Device as a character display. Each
+// character gets an equal share of the screen; the font is scaled up by the
+// largest whole factor that fits a cell and centered in it.
+type TextGrid struct {
+	dev        *Device
+	cols, rows int
+	// cell size and font scale in pixels
+	cellW, cellH int
+	scale        int
+	// glyphs are custom 5x8 characters, as uploaded to an LCD's CGRAM
+	glyphs map[rune][8]byte
+}
+
+// NewTextGrid lays out cols by rows characters on the device
+func NewTextGrid(dev *Device, cols, rows int) (*TextGrid, error) {
+	if cols < 1 || rows < 1 {
+		return nil, fmt.Errorf("invalid text grid %dx%d", cols, rows)
+	}
+	width, height := dev.Size()
+	cellW, cellH := width/cols, height/rows
+	if cellW < cellWidth || cellH < cellHeight {
+		return nil, fmt.Errorf("a %dx%d text grid does not fit %dx%d pixels (at most %dx%d)",
+			cols, rows, width, height, width/cellWidth, height/cellHeight)
+	}
+
+	scale := cellW / cellWidth
+	if s := cellH / cellHeight; s < scale {
+		scale = s
+	}
+	return &TextGrid{
+		dev:    dev,
+		cols:   cols,
+		rows:   rows,
+		cellW:  cellW,
+		cellH:  cellH,
+		scale:  scale,
+		glyphs: make(map[rune][8]byte),
+	}, nil
+}
+
+// SetGlyph defines a custom character. Rows are the pixel rows from top to
+// bottom, the lowest five bits of each with the leftmost pixel in bit 4.
+func (g *TextGrid) SetGlyph(char rune, rows [8]byte) {
+	g.glyphs[char] = rows
+}
+
+// SetLine draws a line of text, truncated and padded to the grid width. The
+// device is not flushed.
+func (g *TextGrid) SetLine(row int, text string) error {
+	if row < 0 || row >= g.rows {
+		return fmt.Errorf("invalid row: %d. Must be between 0 and %d", row, g.rows-1)
+	}
+
+	chars := []rune(text)
+	for col := 0; col < g.cols; col++ {
+		char := ' '
+		if col < len(chars) {
+			char = chars[col]
+		}
+		g.drawChar(col, row, char)
+	}
+	return nil
+}
+
+// drawChar draws one character cell, clearing whatever it showed before
+func (g *TextGrid) drawChar(col, row int, char rune) {
+	left, top := col*g.cellW, row*g.cellH
+	for y := 0; y < g.cellH; y++ {
+		for x := 0; x < g.cellW; x++ {
+			g.dev.SetPixel(left+x, top+y, false)
+		}
+	}
+
+	// Center the scaled character in its cell
+	left += (g.cellW - cellWidth*g.scale) / 2
+	top += (g.cellH - cellHeight*g.scale) / 2
+	for y := 0; y < cellHeight; y++ {
+		for x := 0; x < fontWidth; x++ {
+			if !g.pixel(char, x, y) {
+				continue
+			}
+			for sy := 0; sy < g.scale; sy++ {
+				for sx := 0; sx < g.scale; sx++ {
+					g.dev.SetPixel(left+x*g.scale+sx, top+y*g.scale+sy, true)
+				}
+			}
+		}
+	}
+}
+
+// pixel reports whether a character has pixel (x, y) of its 5x8 cell set
+func (g *TextGrid) pixel(char rune, x, y int) bool {
+	if rows, ok := g.glyphs[char]; ok {
+		return rows[y]&(1<<(fontWidth-1-x)) != 0
+	}
+
+	columns := fallback
+	if char >= fontFirst && char <= fontLast {
+		columns = font[char-fontFirst]
+	}
+	return columns[x]&(1<<y) != 0
+}
+
+// Flush sends the changed parts of the grid to the device
+func (g *TextGrid) Flush() error {
+	return g.dev.Flush()
+}
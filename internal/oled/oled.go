@@ -0,0 +1,205 @@
+// Package oled drives SSD1306 and SH1106 OLED modules on an I2C bus.
+//
+// A Device keeps a frame buffer in the controller's memory layout: the
+// display is split into pages of eight pixel rows, and each byte holds one
+// column of a page with the top pixel in bit 0. Flush sends only the pages
+// that changed since the last flush. TextGrid draws a character grid into
+// the frame buffer, so the module can stand in for a character LCD.
+package oled
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/qnap/display-control/internal/hardware"
+)
+
+// Chip identifies the display controller of a module
+type Chip string
+
+const (
+	// SSD1306 modules map the display to columns 0-127
+	SSD1306 Chip = "ssd1306"
+	// SH1106 modules have 132 columns of memory with the display centered
+	SH1106 Chip = "sh1106"
+)
+
+// DefaultAddress is where most modules answer; the others use 0x3D
+const DefaultAddress = 0x3C
+
+// Control bytes that start every I2C write
+const (
+	controlCommand = 0x00
+	controlData    = 0x40
+)
+
+// sh1106ColumnOffset is the first memory column shown by an SH1106
+const sh1106ColumnOffset = 2
+
+// Options describe a module
+type Options struct {
+	Chip Chip
+	// Address is the 7 bit I2C address, 0 for DefaultAddress
+	Address uint16
+	// Width and Height are in pixels, 0 for 128x64
+	Width  int
+	Height int
+	// Flip rotates the picture by 180 degrees for modules mounted upside down
+	Flip bool
+}
+
+// Device is an OLED module
+type Device struct {
+	bus    hardware.I2CBus
+	addr   uint16
+	chip   Chip
+	width  int
+	height int
+	// frame is drawn into, shown is what the module displays; nil until the
+	// first flush
+	frame []byte
+	shown []byte
+}
+
+// Open initializes the module and clears it
+func Open(bus hardware.I2CBus, opts Options) (*Device, error) {
+	if opts.Chip != SSD1306 && opts.Chip != SH1106 {
+		return nil, fmt.Errorf("unknown OLED chip %q (available: %s, %s)", opts.Chip, SSD1306, SH1106)
+	}
+	if opts.Address == 0 {
+		opts.Address = DefaultAddress
+	}
+	if opts.Width == 0 {
+		opts.Width = 128
+	}
+	if opts.Height == 0 {
+		opts.Height = 64
+	}
+	if opts.Width < 1 || opts.Width > 128 || (opts.Height != 32 && opts.Height != 64) {
+		return nil, fmt.Errorf("unsupported OLED size %dx%d", opts.Width, opts.Height)
+	}
+
+	d := &Device{
+		bus:    bus,
+		addr:   opts.Address,
+		chip:   opts.Chip,
+		width:  opts.Width,
+		height: opts.Height,
+		frame:  make([]byte, opts.Width*opts.Height/8),
+	}
+	if err := d.command(d.initSequence(opts.Flip)...); err != nil {
+		return nil, fmt.Errorf("failed to initialize %s at 0x%02x: %w", opts.Chip, opts.Address, err)
+	}
+	if err := d.Flush(); err != nil {
+		return nil, err
+	}
+	if err := d.SetPower(true); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// initSequence configures the module, leaving the display switched off
+func (d *Device) initSequence(flip bool) []byte {
+	// Normal orientation mirrors the segments and scans the rows bottom up,
+	// which is how the glass is wired on common modules
+	segmentRemap, comScan := byte(0xA1), byte(0xC8)
+	if flip {
+		segmentRemap, comScan = 0xA0, 0xC0
+	}
+	comPins := byte(0x12)
+	if d.height == 32 {
+		comPins = 0x02
+	}
+
+	sequence := []byte{
+		0xAE,       // display off
+		0xD5, 0x80, // clock divider
+		0xA8, byte(d.height - 1), // multiplex ratio
+		0xD3, 0x00, // display offset
+		0x40, // start line 0
+		segmentRemap,
+		comScan,
+		0xDA, comPins,
+		0x81, 0xCF, // contrast
+		0xDB, 0x40, // VCOMH level
+		0xA4, // show the memory contents
+		0xA6, // not inverted
+	}
+	if d.chip == SSD1306 {
+		sequence = append(sequence,
+			0x20, 0x02, // page addressing, as the SH1106 always uses
+			0x8D, 0x14, // charge pump on
+			0xD9, 0xF1, // pre-charge period
+		)
+	} else {
+		sequence = append(sequence,
+			0xAD, 0x8B, // DC-DC converter on
+			0xD9, 0x22, // pre-charge period
+		)
+	}
+	return sequence
+}
+
+// Size returns the width and height in pixels
+func (d *Device) Size() (int, int) {
+	return d.width, d.height
+}
+
+// SetPixel sets or clears a pixel in the frame buffer. Pixels outside the
+// display are ignored.
+func (d *Device) SetPixel(x, y int, on bool) {
+	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+		return
+	}
+	index := y/8*d.width + x
+	if on {
+		d.frame[index] |= 1 << (y % 8)
+	} else {
+		d.frame[index] &^= 1 << (y % 8)
+	}
+}
+
+// Flush sends the pages of the frame buffer that changed since the last flush
+func (d *Device) Flush() error {
+	if d.shown == nil {
+		d.shown = make([]byte, len(d.frame))
+		// Force the first flush to send every page
+		for i := range d.shown {
+			d.shown[i] = ^d.frame[i]
+		}
+	}
+
+	column := 0
+	if d.chip == SH1106 {
+		column = sh1106ColumnOffset
+	}
+	for page := 0; page < d.height/8; page++ {
+		start, end := page*d.width, (page+1)*d.width
+		if bytes.Equal(d.frame[start:end], d.shown[start:end]) {
+			continue
+		}
+		if err := d.command(0xB0|byte(page), byte(column&0x0F), 0x10|byte(column>>4)); err != nil {
+			return fmt.Errorf("failed to select page %d: %w", page, err)
+		}
+		data := append([]byte{controlData}, d.frame[start:end]...)
+		if err := d.bus.Tx(d.addr, data, nil); err != nil {
+			return fmt.Errorf("failed to write page %d: %w", page, err)
+		}
+		copy(d.shown[start:end], d.frame[start:end])
+	}
+	return nil
+}
+
+// SetPower switches the display on or off; the memory keeps its contents
+func (d *Device) SetPower(on bool) error {
+	if on {
+		return d.command(0xAF)
+	}
+	return d.command(0xAE)
+}
+
+// command sends controller commands
+func (d *Device) command(commands ...byte) error {
+	return d.bus.Tx(d.addr, append([]byte{controlCommand}, commands...), nil)
+}
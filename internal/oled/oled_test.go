@@ -0,0 +1,130 @@
+package oled
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBus keeps every write
+type recordingBus struct {
+	writes [][]byte
+	err    error
+}
+
+func (b *recordingBus) Tx(addr uint16, w, r []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.writes = append(b.writes, append([]byte(nil), w...))
+	return nil
+}
+
+func (b *recordingBus) Close() error {
+	return nil
+}
+
+// pageWrites returns the data writes, without their control byte
+func (b *recordingBus) pageWrites() [][]byte {
+	var pages [][]byte
+	for _, w := range b.writes {
+		if w[0] == controlData {
+			pages = append(pages, w[1:])
+		}
+	}
+	return pages
+}
+
+func TestOpen(t *testing.T) {
+	bus := &recordingBus{}
+	dev, err := Open(bus, Options{Chip: SSD1306})
+	require.NoError(t, err)
+
+	width, height := dev.Size()
+	assert.Equal(t, 128, width)
+	assert.Equal(t, 64, height)
+	assert.Equal(t, []byte{controlCommand, 0xAE}, bus.writes[0][:2], "initialization starts with the display off")
+	assert.Contains(t, string(bus.writes[0]), string([]byte{0x8D, 0x14}), "SSD1306 needs its charge pump")
+	assert.Len(t, bus.pageWrites(), 8, "the display is cleared")
+	assert.Equal(t, []byte{controlCommand, 0xAF}, bus.writes[len(bus.writes)-1])
+
+	_, err = Open(bus, Options{Chip: "ili9341"})
+	assert.Error(t, err)
+	_, err = Open(bus, Options{Chip: SSD1306, Height: 48})
+	assert.Error(t, err)
+	_, err = Open(&recordingBus{err: errors.New("no ack")}, Options{Chip: SH1106})
+	assert.Error(t, err)
+}
+
+func TestFlush_ChangedPages(t *testing.T) {
+	bus := &recordingBus{}
+	dev, err := Open(bus, Options{Chip: SH1106, Height: 32})
+	require.NoError(t, err)
+	assert.Len(t, bus.pageWrites(), 4)
+
+	bus.writes = nil
+	dev.SetPixel(3, 9, true)
+	dev.SetPixel(500, 9, true)
+	require.NoError(t, dev.Flush())
+
+	// Page 1 is selected at the SH1106 column offset and sent alone
+	require.Len(t, bus.writes, 2)
+	assert.Equal(t, []byte{controlCommand, 0xB1, 0x02, 0x10}, bus.writes[0])
+	assert.Equal(t, byte(0x02), bus.pageWrites()[0][3])
+
+	bus.writes = nil
+	require.NoError(t, dev.Flush())
+	assert.Empty(t, bus.writes, "nothing changed")
+}
+
+func TestTextGrid(t *testing.T) {
+	dev, err := Open(&recordingBus{}, Options{Chip: SSD1306})
+	require.NoError(t, err)
+
+	_, err = NewTextGrid(dev, 22, 2)
+	assert.Error(t, err, "more than 21 columns do not fit 128 pixels")
+
+	// 16x2 on 128x64: 8x32 pixel cells, the font at scale 1 centered vertically
+	grid, err := NewTextGrid(dev, 16, 2)
+	require.NoError(t, err)
+	assert.Error(t, grid.SetLine(2, "x"))
+
+	require.NoError(t, grid.SetLine(0, "I"))
+	left, top := 1, 12
+	for x, column := range font['I'-fontFirst] {
+		for y := 0; y < fontHeight; y++ {
+			assert.Equal(t, column&(1<<y) != 0, dev.frame[(top+y)/8*128+left+x]&(1<<((top+y)%8)) != 0,
+				"pixel %d,%d", x, y)
+		}
+	}
+
+	// Redrawing a cell clears it first
+	require.NoError(t, grid.SetLine(0, ""))
+	assert.Equal(t, make([]byte, len(dev.frame)), dev.frame)
+
+	// Glyphs use the LCD CGRAM layout: rows, leftmost pixel in bit 4
+	grid.SetGlyph(0, [8]byte{0x10, 0, 0, 0, 0, 0, 0, 0x01})
+	require.NoError(t, grid.SetLine(1, "\x00"))
+	bottom := 32 + 12
+	assert.NotZero(t, dev.frame[bottom/8*128+1]&(1<<(bottom%8)), "top left pixel")
+	assert.NotZero(t, dev.frame[(bottom+7)/8*128+5]&(1<<((bottom+7)%8)), "bottom right pixel")
+}
+
+func TestTextGrid_Scaled(t *testing.T) {
+	dev, err := Open(&recordingBus{}, Options{Chip: SSD1306})
+	require.NoError(t, err)
+
+	// 10x4 on 128x64: 12x16 cells fit the font twice as large
+	grid, err := NewTextGrid(dev, 10, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, grid.scale)
+
+	require.NoError(t, grid.SetLine(0, "|"))
+	// The bar is font column 2, scaled to pixel columns 4 and 5 of the cell
+	assert.Equal(t, byte(0xFF), dev.frame[4])
+	assert.Equal(t, byte(0xFF), dev.frame[5])
+	assert.Equal(t, byte(0x3F), dev.frame[128+4])
+	assert.Zero(t, dev.frame[3])
+}
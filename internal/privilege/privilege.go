@@ -0,0 +1,87 @@
+// Package privilege switches the service from root to an unprivileged user
+// once the devices it needs are open.
+package privilege
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// Identity is the user and groups the process switches to
+type Identity struct {
+	Name   string
+	UID    int
+	GID    int
+	Groups []int
+}
+
+// Lookup resolves a user name or numeric uid with its primary and
+// supplementary groups
+func Lookup(name string) (*Identity, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if _, numeric := strconv.Atoi(name); numeric != nil {
+			return nil, fmt.Errorf("failed to look up user %q: %w", name, err)
+		}
+		if u, err = user.LookupId(name); err != nil {
+			return nil, fmt.Errorf("failed to look up uid %s: %w", name, err)
+		}
+	}
+
+	identity := &Identity{Name: u.Username}
+	if identity.UID, err = strconv.Atoi(u.Uid); err != nil {
+		return nil, fmt.Errorf("user %q has a non-numeric uid %q", name, u.Uid)
+	}
+	if identity.GID, err = strconv.Atoi(u.Gid); err != nil {
+		return nil, fmt.Errorf("user %q has a non-numeric gid %q", name, u.Gid)
+	}
+	if identity.UID == 0 {
+		return nil, fmt.Errorf("user %q is root", name)
+	}
+
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		// Without supplementary groups only the primary group is kept
+		groupIDs = []string{u.Gid}
+	}
+	for _, id := range groupIDs {
+		gid, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("user %q has a non-numeric group id %q", name, id)
+		}
+		identity.Groups = append(identity.Groups, gid)
+	}
+	return identity, nil
+}
+
+// Drop switches every thread of the process to the user, which must not be
+// root. Open file descriptors, such as the serial port and /dev/port, stay
+// usable; no capabilities are kept, CAP_SYS_RAWIO included, so nothing new
+// that needs root can be opened, not even the LEDs and I/O ports again, and
+// the commands the service runs start unprivileged.
+func Drop(name string) (*Identity, error) {
+	identity, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Groups go first: changing them needs the root uid about to be given up
+	if err := syscall.Setgroups(identity.Groups); err != nil {
+		return nil, fmt.Errorf("failed to set supplementary groups: %w", err)
+	}
+	if err := syscall.Setgid(identity.GID); err != nil {
+		return nil, fmt.Errorf("failed to set gid %d: %w", identity.GID, err)
+	}
+	if err := syscall.Setuid(identity.UID); err != nil {
+		return nil, fmt.Errorf("failed to set uid %d: %w", identity.UID, err)
+	}
+
+	// A process that can get root back has not dropped anything
+	if os.Geteuid() != identity.UID || syscall.Setuid(0) == nil {
+		return nil, fmt.Errorf("root privileges are still held after switching to %s", identity.Name)
+	}
+	return identity, nil
+}
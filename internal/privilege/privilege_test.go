@@ -0,0 +1,83 @@
+package privilege
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperEnv makes the test binary act as the process that drops privileges,
+// so the test process itself keeps root
+const helperEnv = "PRIVILEGE_TEST_FILE"
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(helperEnv); path != "" {
+		os.Exit(dropHelper(path))
+	}
+	os.Exit(m.Run())
+}
+
+// dropHelper opens a root-only file, drops to nobody and checks that the open
+// file is still readable while reopening it fails
+func dropHelper(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 10
+	}
+	if _, err := Drop("nobody"); err != nil {
+		os.Stderr.WriteString(err.Error())
+		return 11
+	}
+	if contents, err := io.ReadAll(file); err != nil || string(contents) != "secret" {
+		return 12
+	}
+	if _, err := os.Open(path); err == nil {
+		return 13
+	}
+	return 0
+}
+
+func TestDrop(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("dropping privileges needs root")
+	}
+	if _, err := Lookup("nobody"); err != nil {
+		t.Skip("no nobody user")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.Chmod(dir, 0755))
+	path := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(path, []byte("secret"), 0600))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), helperEnv+"="+path)
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "helper failed: %s", output)
+}
+
+func TestLookup(t *testing.T) {
+	_, err := Lookup("no-such-user-here")
+	assert.Error(t, err)
+
+	_, err = Lookup("root")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is root")
+
+	_, err = Lookup("0")
+	assert.Error(t, err)
+
+	if nobody, err := Lookup("nobody"); err == nil {
+		byID, err := Lookup("65534")
+		if nobody.UID == 65534 {
+			require.NoError(t, err)
+			assert.Equal(t, nobody.Name, byID.Name)
+		}
+		assert.Contains(t, nobody.Groups, nobody.GID)
+	}
+}
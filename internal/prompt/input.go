@@ -0,0 +1,214 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+)
+
+const (
+	// MaxInputLength is the longest text Input accepts, enough for a WiFi SSID
+	MaxInputLength = 32
+
+	// inputRepeatDelay is how long SELECT is held before it starts repeating
+	inputRepeatDelay = 500 * time.Millisecond
+	// inputRepeatInterval is the pace of a repeating SELECT
+	inputRepeatInterval = 120 * time.Millisecond
+	// inputFinishHold is how long ENTER is held to finish the input
+	inputFinishHold = time.Second
+
+	// inputWidth is the width of the line showing the text being entered
+	inputWidth = 16
+)
+
+// charsets are the character sets Input cycles through, by name. Lowercase
+// letters come first since they are the most common in names.
+var charsets = map[string]string{
+	// name suits folder and file names
+	"name": "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.",
+	// digits suits PINs and numeric passphrases
+	"digits": "0123456789",
+	// text adds the space and the remaining printable ASCII characters
+	"text": "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_. !\"#$%&'()*+,/:;<=>?@[\\]^`{|}~",
+}
+
+// DefaultCharset is used when Input is given no character set name
+const DefaultCharset = "name"
+
+// Charsets returns the names of the character sets Input accepts
+func Charsets() []string {
+	names := make([]string, 0, len(charsets))
+	for name := range charsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Input reads a short text entered with the buttons. The first line shows
+// label, the second the text so far followed by the candidate character in
+// brackets. SELECT moves to the next candidate (hold it to scroll), ENTER
+// appends the candidate and holding ENTER for a second finishes the input.
+// Before the first character of the set comes DEL, which removes the last
+// character. Input returns the text, or the context error on cancellation or
+// timeout.
+func (p *Prompter) Input(ctx context.Context, label string, charset string) (string, error) {
+	return p.input(ctx, label, charset, false)
+}
+
+// Secret reads a text like Input but shows the characters entered as "*",
+// e.g. for a passphrase. The candidate character is still shown; see
+// SecretOpen to keep it from anything but the panel.
+func (p *Prompter) Secret(ctx context.Context, label string, charset string) (string, error) {
+	return p.input(ctx, label, charset, true)
+}
+
+// input reads a text for Input and Secret
+func (p *Prompter) input(ctx context.Context, label string, charset string, masked bool) (string, error) {
+	if charset == "" {
+		charset = DefaultCharset
+	}
+	chars, exists := charsets[charset]
+	if !exists {
+		return "", fmt.Errorf("unknown charset %q (available: %s)", charset, strings.Join(Charsets(), ", "))
+	}
+
+	p.askMutex.Lock()
+	defer p.askMutex.Unlock()
+
+	events := p.open(masked)
+	defer p.close()
+
+	input := &textInput{label: label, chars: chars, candidate: 1, masked: masked}
+
+	// hold fires while a button is held: SELECT repeats, ENTER finishes
+	hold := time.NewTimer(time.Hour)
+	stopTimer(hold)
+	defer hold.Stop()
+	var held controller.PanelButton
+	holding := false
+
+	for {
+		if err := p.display.WriteText(input.render()); err != nil {
+			return "", fmt.Errorf("failed to show input: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.WithField("input", label).Debug("Input cancelled")
+			return "", ctx.Err()
+
+		case <-hold.C:
+			switch {
+			case !holding:
+			case held == controller.ButtonSelect:
+				input.next()
+				hold.Reset(inputRepeatInterval)
+			case held == controller.ButtonEnter:
+				// Finish once ENTER is let go, so its release does not reach the menu
+				input.finished = true
+			}
+
+		case event := <-events:
+			if event.pressed {
+				if holding {
+					continue
+				}
+				switch event.button {
+				case controller.ButtonSelect:
+					input.next()
+					stopTimer(hold)
+					hold.Reset(inputRepeatDelay)
+				case controller.ButtonEnter:
+					stopTimer(hold)
+					hold.Reset(inputFinishHold)
+				default:
+					continue
+				}
+				held, holding = event.button, true
+				continue
+			}
+
+			if !holding || held != event.button {
+				continue
+			}
+			holding = false
+			stopTimer(hold)
+
+			if event.button != controller.ButtonEnter {
+				continue
+			}
+			if input.finished {
+				p.logger.WithField("input", label).Debug("Input finished")
+				return string(input.text), nil
+			}
+			input.accept()
+		}
+	}
+}
+
+// stopTimer stops t and drains a tick that fired before it was stopped, so a
+// later Reset cannot be followed by a stale tick
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+// textInput is the state of an Input
+type textInput struct {
+	label string
+	chars string
+	text  []byte
+	// candidate indexes the cycle of DEL (0) followed by chars
+	candidate int
+	// finished is set while the ENTER press that ends the input is held
+	finished bool
+	// masked shows the text as "*"
+	masked bool
+}
+
+// next moves to the next candidate
+func (t *textInput) next() {
+	t.candidate = (t.candidate + 1) % (len(t.chars) + 1)
+}
+
+// accept appends the candidate or, for DEL, removes the last character. The
+// candidate stays where it is, so repeated characters and deletes are quick.
+func (t *textInput) accept() {
+	if t.candidate == 0 {
+		if len(t.text) > 0 {
+			t.text = t.text[:len(t.text)-1]
+		}
+		return
+	}
+	if len(t.text) < MaxInputLength {
+		t.text = append(t.text, t.chars[t.candidate-1])
+	}
+}
+
+// render builds the display text. The end of long text is shown.
+func (t *textInput) render() string {
+	cursor := "DEL"
+	if t.finished {
+		cursor = " OK"
+	} else if t.candidate > 0 {
+		cursor = "[" + string(t.chars[t.candidate-1]) + "]"
+	}
+
+	text := string(t.text)
+	if t.masked {
+		text = strings.Repeat("*", len(t.text))
+	}
+	if visible := inputWidth - len(cursor); len(text) > visible {
+		text = text[len(text)-visible:]
+	}
+	return t.label + "\n" + text + cursor
+}
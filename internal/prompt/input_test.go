@@ -0,0 +1,236 @@
+package prompt
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startInput runs Input in the background and returns its result channel
+func startInput(t *testing.T, p *Prompter, ctx context.Context, charset string) chan string {
+	t.Helper()
+
+	result := make(chan string, 1)
+	go func() {
+		text, err := p.Input(ctx, "Folder name", charset)
+		if err != nil {
+			text = "error: " + err.Error()
+		}
+		result <- text
+	}()
+	return result
+}
+
+// showing waits until the display shows text
+func showing(t *testing.T, display *recordingDisplay, text string) {
+	t.Helper()
+
+	require.Eventually(t, func() bool { return display.last() == text }, 2*time.Second, time.Millisecond,
+		"display shows %q", display.last())
+}
+
+func TestInput(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+	result := startInput(t, p, context.Background(), "")
+
+	showing(t, display, "Folder name\n[a]")
+	press(t, p, controller.ButtonEnter)
+	showing(t, display, "Folder name\na[a]")
+	press(t, p, controller.ButtonSelect)
+	press(t, p, controller.ButtonEnter)
+	showing(t, display, "Folder name\nab[b]")
+
+	// DEL comes right before the first character of the cycle
+	chars := charsets[DefaultCharset]
+	expected := &textInput{label: "Folder name", chars: chars, text: []byte("ab"), candidate: 2}
+	for expected.candidate != 0 {
+		press(t, p, controller.ButtonSelect)
+		expected.next()
+		showing(t, display, expected.render())
+	}
+	showing(t, display, "Folder name\nabDEL")
+	press(t, p, controller.ButtonEnter)
+	showing(t, display, "Folder name\naDEL")
+
+	// Holding ENTER finishes once it is released
+	assert.True(t, p.HandleButton(controller.ButtonEnter, true))
+	showing(t, display, "Folder name\na OK")
+	assert.True(t, p.HandleButton(controller.ButtonEnter, false))
+
+	assert.Equal(t, "a", <-result)
+	assert.False(t, p.Active())
+}
+
+func TestInput_HoldSelect(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+	result := startInput(t, p, context.Background(), "text")
+
+	// Held past the repeat delay, SELECT moves on by itself
+	require.Eventually(t, p.Active, time.Second, time.Millisecond)
+	p.HandleButton(controller.ButtonSelect, true)
+	time.Sleep(inputRepeatDelay + 3*inputRepeatInterval)
+	p.HandleButton(controller.ButtonSelect, false)
+	press(t, p, controller.ButtonEnter)
+
+	p.HandleButton(controller.ButtonEnter, true)
+	time.Sleep(inputFinishHold + 100*time.Millisecond)
+	p.HandleButton(controller.ButtonEnter, false)
+
+	text := <-result
+	require.Len(t, text, 1)
+	assert.Greater(t, text, "c")
+}
+
+func TestInput_LongText(t *testing.T) {
+	input := &textInput{label: "SSID", chars: charsets["text"], candidate: 1}
+	for i := 0; i < MaxInputLength+5; i++ {
+		input.accept()
+	}
+	assert.Len(t, input.text, MaxInputLength)
+	assert.Equal(t, "SSID\naaaaaaaaaaaaa[a]", input.render())
+}
+
+func TestSecret(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+	var mutex sync.Mutex
+	var observed []controller.PanelButton
+	observe := p.Observe(func(button controller.PanelButton, pressed bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		observed = append(observed, button)
+	})
+	// The panel's handler tells observers before the prompt takes the event
+	handle := func(button controller.PanelButton, pressed bool) {
+		observe(button, pressed)
+		p.HandleButton(button, pressed)
+	}
+	handle(controller.ButtonSelect, true)
+
+	result := make(chan string, 1)
+	go func() {
+		text, _ := p.Secret(context.Background(), "Passphrase", "digits")
+		result <- text
+	}()
+
+	showing(t, display, "Passphrase\n[0]")
+	assert.True(t, p.SecretOpen())
+	handle(controller.ButtonSelect, true)
+	handle(controller.ButtonSelect, false)
+	handle(controller.ButtonEnter, true)
+	handle(controller.ButtonEnter, false)
+	showing(t, display, "Passphrase\n*[1]")
+	handle(controller.ButtonEnter, true)
+	handle(controller.ButtonEnter, false)
+	showing(t, display, "Passphrase\n**[1]")
+
+	handle(controller.ButtonEnter, true)
+	showing(t, display, "Passphrase\n** OK")
+	handle(controller.ButtonEnter, false)
+	assert.Equal(t, "11", <-result)
+	assert.False(t, p.SecretOpen())
+
+	handle(controller.ButtonEnter, true)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []controller.PanelButton{controller.ButtonSelect, controller.ButtonEnter}, observed,
+		"no event of the secret is observed, only those before and after")
+}
+
+// panel is the front panel behind a screen manager
+type panel struct {
+	mutex sync.Mutex
+	lines [2]string
+}
+
+func (p *panel) WriteTextAt(text string, row, col int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.lines[row] = text
+	return nil
+}
+
+func (p *panel) SetBacklight(on bool) error { return nil }
+
+func (p *panel) row(row int) string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return strings.TrimSpace(p.lines[row])
+}
+
+func TestSecret_Concealed(t *testing.T) {
+	front := &panel{}
+	screens := screen.NewScreenManager(front, 16, 2)
+	var mutex sync.Mutex
+	var frames []string
+	screens.SetFrameHandler(func(lines []string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		frames = append(frames, strings.Join(lines, "|"))
+	})
+	p := NewPrompter(screens.Layer(screen.PriorityConfirmation))
+	screens.SetConcealed(p.SecretOpen)
+	require.NoError(t, screens.Layer(screen.PriorityMenu).WriteText("Main Menu\n>Copy"))
+	menu := screens.Snapshot()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		p.Secret(ctx, "Passphrase", "digits")
+		close(done)
+	}()
+
+	// The panel shows the candidate digits, nothing else sees them
+	var snapshots [][]string
+	for _, candidate := range []string{"[0]", "*[0]", "*[1]", "*[2]"} {
+		require.Eventually(t, func() bool { return strings.HasSuffix(front.row(1), candidate) }, 2*time.Second, time.Millisecond,
+			"panel shows %q", front.row(1))
+		snapshots = append(snapshots, screens.Snapshot())
+		if candidate == "[0]" {
+			press(t, p, controller.ButtonEnter)
+		} else {
+			press(t, p, controller.ButtonSelect)
+		}
+	}
+	for _, snapshot := range snapshots {
+		assert.Equal(t, menu, snapshot)
+	}
+
+	cancel()
+	<-done
+	require.Eventually(t, func() bool { return front.row(0) == "Main Menu" }, 2*time.Second, time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, frame := range frames {
+		assert.NotContains(t, frame, "Passphrase")
+	}
+	assert.Equal(t, strings.Join(menu, "|"), frames[len(frames)-1], "frames are passed on once the secret is entered")
+	assert.Equal(t, menu, screens.Snapshot())
+}
+
+func TestInput_Errors(t *testing.T) {
+	p := NewPrompter(&recordingDisplay{})
+
+	_, err := p.Input(context.Background(), "SSID", "emoji")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown charset")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Input(ctx, "SSID", "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, p.Active())
+}
@@ -0,0 +1,218 @@
+// Package prompt asks questions on the LCD and waits for the answer.
+//
+// A Prompter sits in front of the normal button handling: while a prompt is
+// open, HandleButton consumes every button event so the menu does not react
+// to the answer. Prompts are serialized; a second caller waits until the
+// first prompt is answered or cancelled.
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+)
+
+// Display is where prompts are drawn. If it also has a Release method (as
+// screen.Layer does), it is released when the prompt closes so the screen
+// underneath is restored.
+type Display interface {
+	WriteText(text string) error
+}
+
+// releaser is implemented by displays that can give the panel back
+type releaser interface {
+	Release() error
+}
+
+// Prompter shows prompts and routes button presses to the open prompt
+type Prompter struct {
+	display Display
+	logger  *logrus.Entry
+
+	// askMutex serializes prompts
+	askMutex sync.Mutex
+
+	// mutex guards events, which is non-nil while a prompt is open, and
+	// secret, set while the open prompt reads a Secret
+	mutex  sync.Mutex
+	events chan buttonEvent
+	secret bool
+}
+
+// buttonEvent is a press or release routed to the open prompt
+type buttonEvent struct {
+	button  controller.PanelButton
+	pressed bool
+}
+
+// NewPrompter creates a prompter drawing on the given display
+func NewPrompter(display Display) *Prompter {
+	return &Prompter{
+		display: display,
+		logger:  logrus.WithField("component", "prompt"),
+	}
+}
+
+// HandleButton offers a button event to the open prompt. It returns true if
+// the event was consumed, in which case the caller must not handle it further.
+// Releases are consumed too, so the press that answered a prompt never reaches
+// the menu half-finished.
+func (p *Prompter) HandleButton(button controller.PanelButton, pressed bool) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.events == nil {
+		return false
+	}
+
+	select {
+	case p.events <- buttonEvent{button: button, pressed: pressed}:
+	default:
+		// The prompt is still handling earlier events
+	}
+	return true
+}
+
+// Active reports whether a prompt is currently open
+func (p *Prompter) Active() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.events != nil
+}
+
+// SecretOpen reports whether a Secret is being entered. Its presses spell
+// out the secret, so they must not be logged or passed on.
+func (p *Prompter) SecretOpen() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.secret
+}
+
+// Observer is told of button events, e.g. to log or publish them
+type Observer func(button controller.PanelButton, pressed bool)
+
+// Observe returns observe wrapped so it is not told of the events of a
+// Secret prompt
+func (p *Prompter) Observe(observe Observer) Observer {
+	return func(button controller.PanelButton, pressed bool) {
+		if !p.SecretOpen() {
+			observe(button, pressed)
+		}
+	}
+}
+
+// Prompt shows a question and waits for an answer. The first line shows text
+// and the second the current option: SELECT moves to the next option and ENTER
+// chooses it. Without options the whole text is shown and ENTER or SELECT
+// dismisses it. Prompt returns the index of the chosen option (0 when there
+// are no options), or the context error on cancellation or timeout.
+func (p *Prompter) Prompt(ctx context.Context, text string, options ...string) (int, error) {
+	p.askMutex.Lock()
+	defer p.askMutex.Unlock()
+
+	events := p.open(false)
+	defer p.close()
+
+	selected := 0
+	for {
+		if err := p.display.WriteText(renderPrompt(text, options, selected)); err != nil {
+			return -1, fmt.Errorf("failed to show prompt: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.WithField("prompt", text).Debug("Prompt cancelled")
+			return -1, ctx.Err()
+
+		case event := <-events:
+			if !event.pressed {
+				continue
+			}
+			switch event.button {
+			case controller.ButtonEnter:
+				p.logger.WithField("choice", selected).Debug("Prompt answered")
+				return selected, nil
+			case controller.ButtonSelect:
+				if len(options) == 0 {
+					return 0, nil
+				}
+				selected = (selected + 1) % len(options)
+			}
+		}
+	}
+}
+
+// WaitForButton shows text and waits for one of the given buttons to be
+// pressed, returning the button. With no buttons given, any button counts.
+func (p *Prompter) WaitForButton(ctx context.Context, text string, buttons ...controller.PanelButton) (controller.PanelButton, error) {
+	p.askMutex.Lock()
+	defer p.askMutex.Unlock()
+
+	events := p.open(false)
+	defer p.close()
+
+	if err := p.display.WriteText(text); err != nil {
+		return 0, fmt.Errorf("failed to show prompt: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+
+		case event := <-events:
+			if !event.pressed {
+				continue
+			}
+			if len(buttons) == 0 {
+				return event.button, nil
+			}
+			for _, wanted := range buttons {
+				if event.button == wanted {
+					return event.button, nil
+				}
+			}
+		}
+	}
+}
+
+// open starts routing button presses to a new prompt, which reads a secret
+// if secret is set
+func (p *Prompter) open(secret bool) chan buttonEvent {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.events = make(chan buttonEvent, 8)
+	p.secret = secret
+	return p.events
+}
+
+// close stops routing button presses and gives the display back
+func (p *Prompter) close() {
+	p.mutex.Lock()
+	p.events = nil
+	p.secret = false
+	p.mutex.Unlock()
+
+	if r, ok := p.display.(releaser); ok {
+		if err := r.Release(); err != nil {
+			p.logger.WithError(err).Warn("Failed to release prompt display")
+		}
+	}
+}
+
+// renderPrompt builds the display text for a prompt
+func renderPrompt(text string, options []string, selected int) string {
+	if len(options) == 0 {
+		return text
+	}
+
+	question := strings.SplitN(text, "\n", 2)[0]
+	return question + "\n>" + options[selected]
+}
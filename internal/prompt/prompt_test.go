@@ -0,0 +1,127 @@
+package prompt
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDisplay remembers the prompt texts and releases
+type recordingDisplay struct {
+	mutex    sync.Mutex
+	texts    []string
+	released int
+}
+
+func (d *recordingDisplay) WriteText(text string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.texts = append(d.texts, text)
+	return nil
+}
+
+func (d *recordingDisplay) Release() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.released++
+	return nil
+}
+
+func (d *recordingDisplay) last() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.texts) == 0 {
+		return ""
+	}
+	return d.texts[len(d.texts)-1]
+}
+
+// press sends a press and release once the prompt is open
+func press(t *testing.T, p *Prompter, button controller.PanelButton) {
+	t.Helper()
+
+	require.Eventually(t, p.Active, time.Second, time.Millisecond)
+	assert.True(t, p.HandleButton(button, true))
+	assert.True(t, p.HandleButton(button, false))
+}
+
+func TestPrompt_ChooseOption(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+
+	result := make(chan int, 1)
+	go func() {
+		choice, err := p.Prompt(context.Background(), "Reboot now?", "No", "Yes")
+		assert.NoError(t, err)
+		result <- choice
+	}()
+
+	press(t, p, controller.ButtonSelect)
+	require.Eventually(t, func() bool { return display.last() == "Reboot now?\n>Yes" }, time.Second, time.Millisecond)
+	press(t, p, controller.ButtonEnter)
+
+	assert.Equal(t, 1, <-result)
+	assert.Equal(t, "Reboot now?\n>No", display.texts[0])
+	assert.Equal(t, 1, display.released)
+	assert.False(t, p.Active())
+}
+
+func TestPrompt_Dismiss(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+
+	result := make(chan int, 1)
+	go func() {
+		choice, err := p.Prompt(context.Background(), "Copy complete\n12 files")
+		assert.NoError(t, err)
+		result <- choice
+	}()
+
+	press(t, p, controller.ButtonSelect)
+	assert.Equal(t, 0, <-result)
+	assert.Equal(t, "Copy complete\n12 files", display.texts[0])
+}
+
+func TestPrompt_Timeout(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	choice, err := p.Prompt(ctx, "Start copy?", "No", "Yes")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, -1, choice)
+	assert.Equal(t, 1, display.released)
+}
+
+func TestPrompt_HandleButtonWithoutPrompt(t *testing.T) {
+	p := NewPrompter(&recordingDisplay{})
+	assert.False(t, p.HandleButton(controller.ButtonEnter, true))
+}
+
+func TestWaitForButton(t *testing.T) {
+	display := &recordingDisplay{}
+	p := NewPrompter(display)
+
+	result := make(chan controller.PanelButton, 1)
+	go func() {
+		button, err := p.WaitForButton(context.Background(), "COPY Test\nPress COPY", controller.ButtonUSBCopy)
+		assert.NoError(t, err)
+		result <- button
+	}()
+
+	// Other buttons are consumed but do not end the wait
+	press(t, p, controller.ButtonEnter)
+	press(t, p, controller.ButtonUSBCopy)
+
+	assert.Equal(t, controller.ButtonUSBCopy, <-result)
+}
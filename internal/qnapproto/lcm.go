@@ -0,0 +1,207 @@
+package qnapproto
+
+import "fmt"
+
+// The LCM protocol of the newer A125 front panels frames every message, in
+// both directions, with a start byte, the command, the payload length and a
+// checksum, the low byte of the sum of command, length and payload:
+//
+//	A5 cmd len payload... sum
+//
+// The host sends
+//
+//	A5 10 00                        report key changes unasked
+//	A5 11 00                        request the keys
+//	A5 20 11 row text(16)           write a line
+//	A5 21 01 on                     switch the backlight, 1 = on
+//	A5 22 09 slot rows(8)           define a custom character
+//
+// and the panel answers with
+//
+//	A5 80 01 keys                   the keys held, bit set = pressed
+//	A5 8F 01 cmd                    acknowledges a command
+//
+// The panels run at 115200 baud.
+
+// LCMStart starts every LCM frame
+const LCMStart = 0xA5
+
+// LCM command codes
+const (
+	LCMCodeEnableReporting = 0x10
+	LCMCodeRequestKeys     = 0x11
+	LCMCodeLine            = 0x20
+	LCMCodeBacklight       = 0x21
+	LCMCodeDefineGlyph     = 0x22
+	LCMCodeKeys            = 0x80
+	LCMCodeAck             = 0x8F
+)
+
+// Bits of the keys in an LCM key report
+const (
+	LCMKeyEnter  = 1 << 0
+	LCMKeySelect = 1 << 1
+	LCMKeyCopy   = 1 << 2
+)
+
+// LCMBaudRate is the speed of the LCM panels' serial port
+const LCMBaudRate = 115200
+
+// lcmFrame frames a command and its payload
+func lcmFrame(code byte, payload ...byte) []byte {
+	frame := []byte{LCMStart, code, byte(len(payload))}
+	frame = append(frame, payload...)
+	return append(frame, lcmChecksum(frame[1:]))
+}
+
+// lcmChecksum is the low byte of the sum of data
+func lcmChecksum(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+// LCMEnableReporting makes the panel send a key report whenever a key
+// changes
+type LCMEnableReporting struct{}
+
+// Encode returns the frame bytes
+func (LCMEnableReporting) Encode() []byte {
+	return lcmFrame(LCMCodeEnableReporting)
+}
+
+// LCMRequestKeys asks the panel for a key report
+type LCMRequestKeys struct{}
+
+// Encode returns the frame bytes
+func (LCMRequestKeys) Encode() []byte {
+	return lcmFrame(LCMCodeRequestKeys)
+}
+
+// LCMLine replaces a row of the display
+type LCMLine struct {
+	Row  int
+	Text string
+}
+
+// Encode returns the frame bytes. The text is truncated and padded to the
+// display width.
+func (l LCMLine) Encode() []byte {
+	// The text is laid out like in a QNAP line command
+	text := Line{Text: l.Text}.Encode()[4:]
+	return lcmFrame(LCMCodeLine, append([]byte{byte(l.Row)}, text...)...)
+}
+
+// LCMBacklight switches the backlight
+type LCMBacklight struct {
+	On bool
+}
+
+// Encode returns the frame bytes
+func (b LCMBacklight) Encode() []byte {
+	if b.On {
+		return lcmFrame(LCMCodeBacklight, 0x01)
+	}
+	return lcmFrame(LCMCodeBacklight, 0x00)
+}
+
+// LCMDefineGlyph defines a custom character
+type LCMDefineGlyph struct {
+	Slot int
+	// Rows are the pixel rows, top first, in the lowest five bits
+	Rows [8]byte
+}
+
+// Encode returns the frame bytes
+func (g LCMDefineGlyph) Encode() []byte {
+	return lcmFrame(LCMCodeDefineGlyph, append([]byte{byte(g.Slot)}, g.Rows[:]...)...)
+}
+
+// LCMKeys reports the keys held, LCMKeyEnter and so on
+type LCMKeys struct {
+	Keys byte
+}
+
+// Encode returns the frame bytes
+func (k LCMKeys) Encode() []byte {
+	return lcmFrame(LCMCodeKeys, k.Keys)
+}
+
+// ButtonState returns the report as the state byte of a QNAP button frame,
+// in the layout of the standard hardware profile: ENTER on bit 0 and SELECT
+// on bit 1, both 0 while pressed, and the copy button on bit 2, 1 while
+// pressed
+func (k LCMKeys) ButtonState() ButtonState {
+	state := ^k.Keys&(LCMKeyEnter|LCMKeySelect) | k.Keys&LCMKeyCopy
+	return ButtonState{State: state}
+}
+
+// LCMAck acknowledges a command
+type LCMAck struct {
+	Code byte
+}
+
+// Encode returns the frame bytes
+func (a LCMAck) Encode() []byte {
+	return lcmFrame(LCMCodeAck, a.Code)
+}
+
+// lcmPayloads are the payload lengths of the LCM messages
+var lcmPayloads = map[byte]int{
+	LCMCodeEnableReporting: 0,
+	LCMCodeRequestKeys:     0,
+	LCMCodeLine:            1 + Width,
+	LCMCodeBacklight:       1,
+	LCMCodeDefineGlyph:     9,
+	LCMCodeKeys:            1,
+	LCMCodeAck:             1,
+}
+
+// DecodeLCM decodes the LCM frame at the start of data, sent by either side,
+// and returns it with its length. A frame with a wrong length or checksum is
+// ErrUnknown, so callers drop a byte and resynchronize on the next start
+// byte.
+func DecodeLCM(data []byte) (Command, int, error) {
+	if len(data) == 0 {
+		return nil, 0, ErrShort
+	}
+	if data[0] != LCMStart {
+		return nil, 0, fmt.Errorf("%w: 0x%02x", ErrUnknown, data[0])
+	}
+	if len(data) < 3 {
+		return nil, 0, ErrShort
+	}
+	code, length := data[1], int(data[2])
+	if want, ok := lcmPayloads[code]; !ok || length != want {
+		return nil, 0, fmt.Errorf("%w: LCM frame % 02x", ErrUnknown, data[:3])
+	}
+	n := 3 + length + 1
+	if len(data) < n {
+		return nil, 0, ErrShort
+	}
+	if lcmChecksum(data[1:n-1]) != data[n-1] {
+		return nil, 0, fmt.Errorf("%w: LCM frame % 02x with a bad checksum", ErrUnknown, data[:n])
+	}
+
+	payload := data[3 : n-1]
+	switch code {
+	case LCMCodeEnableReporting:
+		return LCMEnableReporting{}, n, nil
+	case LCMCodeRequestKeys:
+		return LCMRequestKeys{}, n, nil
+	case LCMCodeLine:
+		return LCMLine{Row: int(payload[0]), Text: string(payload[1:])}, n, nil
+	case LCMCodeBacklight:
+		return LCMBacklight{On: payload[0] != 0}, n, nil
+	case LCMCodeDefineGlyph:
+		glyph := LCMDefineGlyph{Slot: int(payload[0])}
+		copy(glyph.Rows[:], payload[1:])
+		return glyph, n, nil
+	case LCMCodeKeys:
+		return LCMKeys{Keys: payload[0]}, n, nil
+	default:
+		return LCMAck{Code: payload[0]}, n, nil
+	}
+}
@@ -0,0 +1,60 @@
+package qnapproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLCM_Encode(t *testing.T) {
+	assert.Equal(t, []byte{0xA5, 0x10, 0x00, 0x10}, LCMEnableReporting{}.Encode())
+	assert.Equal(t, []byte{0xA5, 0x11, 0x00, 0x11}, LCMRequestKeys{}.Encode())
+	assert.Equal(t, []byte{0xA5, 0x21, 0x01, 0x01, 0x23}, LCMBacklight{On: true}.Encode())
+	assert.Equal(t, []byte{0xA5, 0x80, 0x01, 0x05, 0x86}, LCMKeys{Keys: 0x05}.Encode())
+	assert.Equal(t, []byte{0xA5, 0x8F, 0x01, 0x20, 0xB0}, LCMAck{Code: 0x20}.Encode())
+
+	line := LCMLine{Row: 1, Text: "Hi"}.Encode()
+	assert.Equal(t, []byte{0xA5, 0x20, 0x11, 0x01, 'H', 'i', ' '}, line[:7])
+	assert.Len(t, line, 3+1+Width+1)
+
+	glyph := LCMDefineGlyph{Slot: 3, Rows: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}.Encode()
+	assert.Equal(t, []byte{0xA5, 0x22, 0x09, 0x03, 1, 2, 3, 4, 5, 6, 7, 8, 0x22 + 0x09 + 0x03 + 36}, glyph)
+}
+
+func TestDecodeLCM(t *testing.T) {
+	// Every message decodes to itself
+	for _, message := range []Command{
+		LCMEnableReporting{}, LCMRequestKeys{}, LCMBacklight{On: true}, LCMBacklight{},
+		LCMLine{Row: 1, Text: "QNAP Ready      "},
+		LCMDefineGlyph{Slot: 7, Rows: [8]byte{0x1F, 0, 0x1F, 0, 0x1F, 0, 0x1F, 0}},
+		LCMKeys{Keys: LCMKeyEnter | LCMKeyCopy}, LCMAck{Code: LCMCodeLine},
+	} {
+		encoded := message.Encode()
+		decoded, n, err := DecodeLCM(append(encoded, LCMStart))
+		require.NoError(t, err, "%T", message)
+		assert.Equal(t, message, decoded)
+		assert.Equal(t, len(encoded), n)
+	}
+
+	for _, short := range [][]byte{nil, {0xA5}, {0xA5, 0x80}, {0xA5, 0x80, 0x01, 0x05}} {
+		_, _, err := DecodeLCM(short)
+		assert.ErrorIs(t, err, ErrShort, "% 02x", short)
+	}
+	for _, unknown := range [][]byte{
+		{0x4D, 0x05},
+		{0xA5, 0x99, 0x00, 0x99},
+		{0xA5, 0x80, 0x02, 0x05, 0x00, 0x87},
+		{0xA5, 0x80, 0x01, 0x05, 0x00},
+	} {
+		_, _, err := DecodeLCM(unknown)
+		assert.ErrorIs(t, err, ErrUnknown, "% 02x", unknown)
+	}
+}
+
+func TestLCMKeys_ButtonState(t *testing.T) {
+	assert.Equal(t, ButtonState{State: 0x03}, LCMKeys{}.ButtonState(), "nothing held")
+	assert.Equal(t, ButtonState{State: 0x02}, LCMKeys{Keys: LCMKeyEnter}.ButtonState())
+	assert.Equal(t, ButtonState{State: 0x01}, LCMKeys{Keys: LCMKeySelect}.ButtonState())
+	assert.Equal(t, ButtonState{State: 0x07}, LCMKeys{Keys: LCMKeyCopy}.ButtonState())
+}
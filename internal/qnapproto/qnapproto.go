@@ -0,0 +1,235 @@
+// Package qnapproto encodes and decodes the serial protocol of the QNAP front
+// panel, as verified against the qnapctl reference implementation.
+//
+// The host sends commands that start with 'M' (0x4D) followed by a command
+// code:
+//
+//	4D 05                    request the button state
+//	4D 06                    report button changes unasked
+//	4D 0C row len text...    write a line, len (16, 20 on wider panels)
+//	                         bytes of text
+//	4D 5E on                 switch the backlight, 1 = on
+//
+// The panel sends button state frames that start with 'S' (0x53), both when
+// asked and, once reporting is on, whenever a button changes:
+//
+//	53 05 00 state           state holds one bit per button
+//
+// and answers some commands with three byte 'M' frames, which carry nothing
+// the host needs. Custom characters are uploaded with a command whose prefix
+// differs between firmware versions, so it is not decoded here.
+//
+// The newer A125 panels speak the framed LCM protocol instead, see
+// DecodeLCM.
+package qnapproto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Size of the panel's display
+const (
+	Width = 16
+	Rows  = 2
+)
+
+// Leading bytes of commands and panel frames
+const (
+	// CommandPrefix starts every command and the panel's command responses
+	CommandPrefix = 0x4D
+	// StatePrefix starts a button state frame
+	StatePrefix = 0x53
+)
+
+// Command codes, the second byte of a command
+const (
+	CodeRequestState    = 0x05
+	CodeEnableReporting = 0x06
+	CodeLine            = 0x0C
+	CodeBacklight       = 0x5E
+)
+
+// ResponseLength is the length of a command response from the panel
+const ResponseLength = 3
+
+var (
+	// ErrShort means the data holds the start of a message but not all of it
+	ErrShort = errors.New("incomplete message")
+	// ErrUnknown means the data does not start with a message of the protocol
+	ErrUnknown = errors.New("unknown message")
+)
+
+// Reserved reports whether a byte starts messages of the protocol, so other
+// framing (acknowledgements, copy button frames) must not use it
+func Reserved(b byte) bool {
+	return b == CommandPrefix || b == StatePrefix
+}
+
+// Command is a command sent to the panel
+type Command interface {
+	Encode() []byte
+}
+
+// RequestState asks the panel for a button state frame
+type RequestState struct{}
+
+// Encode returns the command bytes
+func (RequestState) Encode() []byte {
+	return []byte{CommandPrefix, CodeRequestState}
+}
+
+// EnableReporting makes the panel send a button state frame whenever a
+// button changes
+type EnableReporting struct{}
+
+// Encode returns the command bytes
+func (EnableReporting) Encode() []byte {
+	return []byte{CommandPrefix, CodeEnableReporting}
+}
+
+// Line replaces a row of the display
+type Line struct {
+	Row  int
+	Text string
+	// Width is the length of the line on panels wider than Width; 0 means
+	// Width
+	Width int
+}
+
+// Encode returns the command bytes. The text is truncated and padded to the
+// line width, which is always sent as the length.
+func (l Line) Encode() []byte {
+	width := l.Width
+	if width <= 0 {
+		width = Width
+	}
+	text := l.Text
+	if len(text) > width {
+		text = text[:width]
+	}
+	text += strings.Repeat(" ", width-len(text))
+
+	command := []byte{CommandPrefix, CodeLine, byte(l.Row), byte(width)}
+	return append(command, text...)
+}
+
+// Backlight switches the backlight
+type Backlight struct {
+	On bool
+}
+
+// Encode returns the command bytes
+func (b Backlight) Encode() []byte {
+	if b.On {
+		return []byte{CommandPrefix, CodeBacklight, 0x01}
+	}
+	return []byte{CommandPrefix, CodeBacklight, 0x00}
+}
+
+// DefineGlyph uploads a custom character to a CGRAM slot behind the
+// firmware's upload prefix
+type DefineGlyph struct {
+	Prefix []byte
+	Slot   int
+	// Rows are the pixel rows, top first, in the lowest five bits
+	Rows [8]byte
+}
+
+// Encode returns the command bytes
+func (g DefineGlyph) Encode() []byte {
+	command := append([]byte(nil), g.Prefix...)
+	command = append(command, byte(g.Slot))
+	return append(command, g.Rows[:]...)
+}
+
+// DecodeCommand decodes the command at the start of data and returns it with
+// its length. Line text is returned as sent, which may be longer or shorter
+// than the display width.
+func DecodeCommand(data []byte) (Command, int, error) {
+	if len(data) == 0 || data[0] != CommandPrefix {
+		return nil, 0, fmt.Errorf("%w: % 02x", ErrUnknown, data)
+	}
+	if len(data) < 2 {
+		return nil, 0, ErrShort
+	}
+
+	switch data[1] {
+	case CodeRequestState:
+		return RequestState{}, 2, nil
+
+	case CodeEnableReporting:
+		return EnableReporting{}, 2, nil
+
+	case CodeLine:
+		if len(data) < 4 || len(data) < 4+int(data[3]) {
+			return nil, 0, fmt.Errorf("%w: truncated line command % 02x", ErrShort, data)
+		}
+		length := int(data[3])
+		return Line{Row: int(data[2]), Text: string(data[4 : 4+length])}, 4 + length, nil
+
+	case CodeBacklight:
+		if len(data) < 3 {
+			return nil, 0, fmt.Errorf("%w: truncated backlight command % 02x", ErrShort, data)
+		}
+		return Backlight{On: data[2] != 0}, 3, nil
+	}
+	return nil, 0, fmt.Errorf("%w: % 02x", ErrUnknown, data)
+}
+
+// Frame is a message from the panel
+type Frame interface {
+	Encode() []byte
+}
+
+// ButtonState reports the buttons, one bit each. Which bit is which button,
+// and whether a set bit means pressed, depends on the panel model.
+type ButtonState struct {
+	State byte
+}
+
+// Encode returns the frame bytes
+func (s ButtonState) Encode() []byte {
+	return []byte{StatePrefix, 0x05, 0x00, s.State}
+}
+
+// Response is the panel's answer to a command
+type Response struct {
+	Data [ResponseLength]byte
+}
+
+// Encode returns the frame bytes
+func (r Response) Encode() []byte {
+	return append([]byte(nil), r.Data[:]...)
+}
+
+// DecodeFrame decodes the panel frame at the start of data and returns it
+// with its length. Data that starts with any other byte, or a state frame
+// with an unexpected header, is ErrUnknown, so callers can try their own
+// framing and otherwise drop a byte to resynchronize.
+func DecodeFrame(data []byte) (Frame, int, error) {
+	if len(data) == 0 {
+		return nil, 0, ErrShort
+	}
+
+	switch data[0] {
+	case StatePrefix:
+		if len(data) < 4 {
+			return nil, 0, ErrShort
+		}
+		if data[1] != 0x05 || data[2] != 0x00 {
+			return nil, 0, fmt.Errorf("%w: state frame % 02x", ErrUnknown, data[:4])
+		}
+		return ButtonState{State: data[3]}, 4, nil
+
+	case CommandPrefix:
+		if len(data) < ResponseLength {
+			return nil, 0, ErrShort
+		}
+		var response Response
+		copy(response.Data[:], data)
+		return response, ResponseLength, nil
+	}
+	return nil, 0, fmt.Errorf("%w: 0x%02x", ErrUnknown, data[0])
+}
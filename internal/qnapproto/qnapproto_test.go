@@ -0,0 +1,101 @@
+package qnapproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_Encode(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		want    []byte
+	}{
+		{"Request state", RequestState{}, []byte{0x4D, 0x05}},
+		{"Enable reporting", EnableReporting{}, []byte{0x4D, 0x06}},
+		{"Backlight on", Backlight{On: true}, []byte{0x4D, 0x5E, 0x01}},
+		{"Backlight off", Backlight{}, []byte{0x4D, 0x5E, 0x00}},
+		{"Line padded", Line{Row: 1, Text: "Hi"}, append([]byte{0x4D, 0x0C, 0x01, 0x10}, "Hi              "...)},
+		{"Line truncated", Line{Text: "A line longer than 16"}, append([]byte{0x4D, 0x0C, 0x00, 0x10}, "A line longer th"...)},
+		{"Wide line", Line{Row: 3, Text: "A line longer than 16", Width: 20}, append([]byte{0x4D, 0x0C, 0x03, 0x14}, "A line longer than 1"...)},
+		{"Glyph", DefineGlyph{Prefix: []byte{0x4D, 0x40}, Slot: 2, Rows: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
+			[]byte{0x4D, 0x40, 0x02, 1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.command.Encode())
+		})
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	// Every command the host sends decodes to itself
+	for _, command := range []Command{
+		RequestState{}, EnableReporting{}, Backlight{On: true}, Backlight{},
+		Line{Row: 0, Text: "QNAP Ready      "}, Line{Row: 1, Text: "\x00 System        "},
+	} {
+		encoded := command.Encode()
+		decoded, n, err := DecodeCommand(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, command, decoded)
+		assert.Equal(t, len(encoded), n)
+	}
+
+	// Commands are decoded one at a time from a batch
+	batch := append(Backlight{On: true}.Encode(), RequestState{}.Encode()...)
+	decoded, n, err := DecodeCommand(batch)
+	require.NoError(t, err)
+	assert.Equal(t, Backlight{On: true}, decoded)
+	assert.Equal(t, 3, n)
+
+	// Line text is returned as sent
+	decoded, n, err = DecodeCommand([]byte{0x4D, 0x0C, 0x01, 0x03, 'a', 'b', 'c'})
+	require.NoError(t, err)
+	assert.Equal(t, Line{Row: 1, Text: "abc"}, decoded)
+	assert.Equal(t, 7, n)
+
+	for _, short := range [][]byte{
+		{0x4D},
+		{0x4D, 0x0C, 0x00},
+		{0x4D, 0x0C, 0x00, 0x10, 'a'},
+		{0x4D, 0x5E},
+	} {
+		_, _, err := DecodeCommand(short)
+		assert.ErrorIs(t, err, ErrShort, "% 02x", short)
+	}
+	for _, unknown := range [][]byte{nil, {0x53, 0x05}, {0x4D, 0x99}} {
+		_, _, err := DecodeCommand(unknown)
+		assert.ErrorIs(t, err, ErrUnknown, "% 02x", unknown)
+	}
+}
+
+func TestDecodeFrame(t *testing.T) {
+	frame, n, err := DecodeFrame([]byte{0x53, 0x05, 0x00, 0x03, 0x53})
+	require.NoError(t, err)
+	assert.Equal(t, ButtonState{State: 0x03}, frame)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{0x53, 0x05, 0x00, 0x03}, frame.Encode())
+
+	frame, n, err = DecodeFrame([]byte{0x4D, 0x06, 0x01})
+	require.NoError(t, err)
+	assert.Equal(t, Response{Data: [3]byte{0x4D, 0x06, 0x01}}, frame)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []byte{0x4D, 0x06, 0x01}, frame.Encode())
+
+	for _, short := range [][]byte{nil, {0x53}, {0x53, 0x05, 0x00}, {0x4D, 0x06}} {
+		_, _, err := DecodeFrame(short)
+		assert.ErrorIs(t, err, ErrShort, "% 02x", short)
+	}
+	for _, unknown := range [][]byte{{0x06}, {0x53, 0x06, 0x00, 0x03}, {0x53, 0x05, 0x01, 0x03}} {
+		_, _, err := DecodeFrame(unknown)
+		assert.ErrorIs(t, err, ErrUnknown, "% 02x", unknown)
+	}
+}
+
+func TestReserved(t *testing.T) {
+	assert.True(t, Reserved(0x4D))
+	assert.True(t, Reserved(0x53))
+	assert.False(t, Reserved(0x06))
+}
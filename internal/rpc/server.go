@@ -0,0 +1,345 @@
+// Package rpc serves the gRPC API defined in api/display.proto. Other
+// services on the NAS push screens, switch LEDs and stream the button
+// events through it while the display service keeps the serial port.
+//
+// Pushed screens are drawn on their own display layer, so alerts, prompts
+// and copies still cover them and the menu comes back when they are gone.
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/api/displaypb"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// subscriberBuffer is how many button events a slow stream may fall behind
+// before further events are dropped for it
+const subscriberBuffer = 32
+
+// captureTimeout is how long a screen capturing the buttons stays up when
+// it is shown without a duration, so a client that died before streaming
+// the buttons cannot keep the panel
+var captureTimeout = 5 * time.Minute
+
+// Screen is the layer pushed screens are drawn on. screen.Layer satisfies
+// it.
+type Screen interface {
+	WriteText(text string) error
+	Release() error
+	SetBacklight(on bool) error
+}
+
+// LEDs are the panel LEDs. controller.LEDControllerInterface satisfies it.
+type LEDs interface {
+	SetLED(led controller.PanelLED, on bool) error
+	GetLEDStates() (map[controller.PanelLED]bool, error)
+}
+
+// ledNames map the API's LEDs to the panel's
+var ledNames = map[displaypb.LED]controller.PanelLED{
+	displaypb.LED_LED_STATUS_GREEN: controller.StatusGreen,
+	displaypb.LED_LED_STATUS_RED:   controller.StatusRed,
+	displaypb.LED_LED_USB:          controller.USB,
+	displaypb.LED_LED_DISK1:        controller.Disk1,
+	displaypb.LED_LED_DISK2:        controller.Disk2,
+	displaypb.LED_LED_DISK3:        controller.Disk3,
+	displaypb.LED_LED_DISK4:        controller.Disk4,
+	displaypb.LED_LED_DISK5:        controller.Disk5,
+	displaypb.LED_LED_DISK6:        controller.Disk6,
+}
+
+// buttonNames map the panel's buttons to the API's
+var buttonNames = map[controller.PanelButton]displaypb.Button{
+	controller.ButtonEnter:   displaypb.Button_BUTTON_ENTER,
+	controller.ButtonSelect:  displaypb.Button_BUTTON_SELECT,
+	controller.ButtonUSBCopy: displaypb.Button_BUTTON_COPY,
+}
+
+// Server implements the Display service
+type Server struct {
+	displaypb.UnimplementedDisplayServer
+
+	screen   Screen
+	snapshot func() []string
+	leds     LEDs
+	logger   *logrus.Entry
+
+	mutex sync.Mutex
+	// shown is the generation of the shown screen, 0 when none is shown;
+	// a timer only clears the screen of its own generation
+	shown      int
+	generation int
+	timer      *time.Timer
+	capture    bool
+	// swallow holds buttons whose release is consumed because their press
+	// dismissed a screen
+	swallow     map[controller.PanelButton]bool
+	subscribers map[chan *displaypb.ButtonEvent]bool
+}
+
+// NewServer creates the service drawing pushed screens on screen. snapshot
+// returns what the panel shows; leds may be nil on panels without LEDs.
+func NewServer(screen Screen, snapshot func() []string, leds LEDs) *Server {
+	return &Server{
+		screen:      screen,
+		snapshot:    snapshot,
+		leds:        leds,
+		logger:      logrus.WithField("component", "rpc"),
+		swallow:     make(map[controller.PanelButton]bool),
+		subscribers: make(map[chan *displaypb.ButtonEvent]bool),
+	}
+}
+
+// ShowScreen shows the requested lines until their time is up, a button is
+// pressed or the screen is cleared. A screen capturing the buttons is not
+// dismissed by them; without a duration it stays up for captureTimeout, and
+// it is cleared when the last button stream closes.
+func (s *Server) ShowScreen(ctx context.Context, request *displaypb.ShowScreenRequest) (*displaypb.ShowScreenResponse, error) {
+	if len(request.Lines) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no lines to show")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.screen.WriteText(strings.Join(request.Lines, "\n")); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to show screen: %v", err)
+	}
+	s.stopTimer()
+	s.generation++
+	s.shown = s.generation
+	s.capture = request.CaptureButtons
+	duration := time.Duration(request.DurationSec) * time.Second
+	if duration <= 0 && s.capture {
+		duration = captureTimeout
+	}
+	if duration > 0 {
+		generation := s.generation
+		s.timer = time.AfterFunc(duration, func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			if s.shown == generation {
+				s.clear()
+			}
+		})
+	}
+	s.logger.WithField("lines", len(request.Lines)).Debug("Showing pushed screen")
+	return &displaypb.ShowScreenResponse{}, nil
+}
+
+// ClearScreen removes the shown screen
+func (s *Server) ClearScreen(ctx context.Context, request *displaypb.ClearScreenRequest) (*displaypb.ClearScreenResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.clear(); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to clear screen: %v", err)
+	}
+	return &displaypb.ClearScreenResponse{}, nil
+}
+
+// clear releases the pushed screen's layer. Caller must hold the mutex.
+func (s *Server) clear() error {
+	s.stopTimer()
+	s.shown = 0
+	s.capture = false
+	return s.screen.Release()
+}
+
+// stopTimer stops the shown screen's timer. Caller must hold the mutex.
+func (s *Server) stopTimer() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// GetScreen returns what the panel shows
+func (s *Server) GetScreen(ctx context.Context, request *displaypb.GetScreenRequest) (*displaypb.Screen, error) {
+	return &displaypb.Screen{Lines: s.snapshot()}, nil
+}
+
+// SetBacklight switches the backlight
+func (s *Server) SetBacklight(ctx context.Context, request *displaypb.SetBacklightRequest) (*displaypb.SetBacklightResponse, error) {
+	if err := s.screen.SetBacklight(request.On); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to switch backlight: %v", err)
+	}
+	return &displaypb.SetBacklightResponse{}, nil
+}
+
+// SetLED switches one LED
+func (s *Server) SetLED(ctx context.Context, request *displaypb.SetLEDRequest) (*displaypb.SetLEDResponse, error) {
+	if s.leds == nil {
+		return nil, status.Error(codes.Unimplemented, "this panel has no LEDs")
+	}
+	led, ok := ledNames[request.Led]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown LED %v", request.Led)
+	}
+	if err := s.leds.SetLED(led, request.On); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to switch LED: %v", err)
+	}
+	return &displaypb.SetLEDResponse{}, nil
+}
+
+// GetLEDs returns the state of every LED, in the order of the LED enum
+func (s *Server) GetLEDs(ctx context.Context, request *displaypb.GetLEDsRequest) (*displaypb.GetLEDsResponse, error) {
+	if s.leds == nil {
+		return nil, status.Error(codes.Unimplemented, "this panel has no LEDs")
+	}
+	states, err := s.leds.GetLEDStates()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to read LEDs: %v", err)
+	}
+
+	response := &displaypb.GetLEDsResponse{}
+	for led := displaypb.LED_LED_STATUS_GREEN; led <= displaypb.LED_LED_DISK6; led++ {
+		response.Leds = append(response.Leds, &displaypb.LEDState{Led: led, On: states[ledNames[led]]})
+	}
+	return response, nil
+}
+
+// StreamButtons sends the button events until the client goes away. When
+// the last stream goes, a screen capturing the buttons goes too, so the
+// panel is not left locked by a client that died.
+func (s *Server) StreamButtons(request *displaypb.StreamButtonsRequest, stream displaypb.Display_StreamButtonsServer) error {
+	events := make(chan *displaypb.ButtonEvent, subscriberBuffer)
+	s.mutex.Lock()
+	s.subscribers[events] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		delete(s.subscribers, events)
+		if len(s.subscribers) == 0 && s.shown != 0 && s.capture {
+			s.logger.Info("Button stream closed, capturing screen released")
+			if err := s.clear(); err != nil {
+				s.logger.WithError(err).Warn("Failed to restore screen after pushed screen")
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// PublishButton sends a button event to every stream. Streams that fall
+// behind lose events rather than hold up the panel.
+func (s *Server) PublishButton(button controller.PanelButton, pressed bool) {
+	name, ok := buttonNames[button]
+	if !ok {
+		return
+	}
+	event := &displaypb.ButtonEvent{Button: name, Pressed: pressed, Time: timestamppb.Now()}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			s.logger.Warn("Button stream falling behind, event dropped")
+		}
+	}
+}
+
+// HandleButton handles a button for the pushed screen. It returns true if
+// the event was consumed: all of them while a screen captures the buttons,
+// otherwise the press dismissing a screen and its release.
+func (s *Server) HandleButton(button controller.PanelButton, pressed bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.shown != 0 && s.capture {
+		return true
+	}
+	if !pressed {
+		if s.swallow[button] {
+			delete(s.swallow, button)
+			return true
+		}
+		return false
+	}
+	if s.shown == 0 {
+		return false
+	}
+
+	s.logger.Info("Pushed screen dismissed")
+	if err := s.clear(); err != nil {
+		s.logger.WithError(err).Warn("Failed to restore screen after pushed screen")
+	}
+	s.swallow[button] = true
+	return true
+}
+
+// Close clears the shown screen and stops its timer
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.shown == 0 {
+		return nil
+	}
+	return s.clear()
+}
+
+// NewGRPCServer creates a gRPC server offering server. With a token set,
+// calls must send it as "authorization: Bearer <token>" metadata.
+func NewGRPCServer(server *Server, token string) *grpc.Server {
+	var options []grpc.ServerOption
+	if token != "" {
+		options = append(options,
+			grpc.UnaryInterceptor(func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := authorize(ctx, token); err != nil {
+					return nil, err
+				}
+				return handler(ctx, request)
+			}),
+			grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := authorize(stream.Context(), token); err != nil {
+					return err
+				}
+				return handler(srv, stream)
+			}))
+	}
+
+	grpcServer := grpc.NewServer(options...)
+	displaypb.RegisterDisplayServer(grpcServer, server)
+	return grpcServer
+}
+
+// authorize checks the token sent with a call
+func authorize(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong token")
+}
+
+// Compile-time check that the server implements the service
+var _ displaypb.DisplayServer = (*Server)(nil)
@@ -0,0 +1,269 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/api/displaypb"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// panel is a display for the screen manager that keeps its lines
+type panel struct {
+	mutex     sync.Mutex
+	lines     [2]string
+	backlight bool
+}
+
+func (p *panel) WriteTextAt(text string, row, col int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.lines[row] = strings.TrimRight(text, " ")
+	return nil
+}
+
+func (p *panel) SetBacklight(on bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.backlight = on
+	return nil
+}
+
+func (p *panel) shown() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.lines[0] + "|" + p.lines[1]
+}
+
+// fakeLEDs records the LEDs switched
+type fakeLEDs struct {
+	mutex  sync.Mutex
+	states map[controller.PanelLED]bool
+}
+
+func (l *fakeLEDs) SetLED(led controller.PanelLED, on bool) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.states[led] = on
+	return nil
+}
+
+func (l *fakeLEDs) GetLEDStates() (map[controller.PanelLED]bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	states := make(map[controller.PanelLED]bool)
+	for led, on := range l.states {
+		states[led] = on
+	}
+	return states, nil
+}
+
+// testService is the service on an in-memory connection, with the menu
+// shown below the pushed screens
+type testService struct {
+	panel  *panel
+	leds   *fakeLEDs
+	server *Server
+	client displaypb.DisplayClient
+}
+
+func startService(t *testing.T, token string) *testService {
+	t.Helper()
+
+	s := &testService{panel: &panel{}, leds: &fakeLEDs{states: make(map[controller.PanelLED]bool)}}
+	screens := screen.NewScreenManager(s.panel, 16, 2)
+	require.NoError(t, screens.Layer(screen.PriorityMenu).WriteText("Main Menu\n>System Info"))
+	s.server = NewServer(screens.Layer(screen.PriorityRemote), screens.Snapshot, s.leds)
+
+	listener := bufconn.Listen(1 << 16)
+	grpcServer := NewGRPCServer(s.server, token)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///panel",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	s.client = displaypb.NewDisplayClient(conn)
+	return s
+}
+
+func TestShowScreen(t *testing.T) {
+	s := startService(t, "")
+	ctx := context.Background()
+
+	_, err := s.client.ShowScreen(ctx, &displaypb.ShowScreenRequest{Lines: []string{"Backup done", "42 GB"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Backup done|42 GB", s.panel.shown())
+
+	screenShown, err := s.client.GetScreen(ctx, &displaypb.GetScreenRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Backup done     ", "42 GB           "}, screenShown.Lines)
+
+	_, err = s.client.ClearScreen(ctx, &displaypb.ClearScreenRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "Main Menu|>System Info", s.panel.shown(), "the menu comes back")
+
+	_, err = s.client.ShowScreen(ctx, &displaypb.ShowScreenRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	t.Run("Duration", func(t *testing.T) {
+		_, err := s.client.ShowScreen(ctx, &displaypb.ShowScreenRequest{Lines: []string{"Short"}, DurationSec: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "Short|", s.panel.shown())
+		assert.Eventually(t, func() bool { return s.panel.shown() == "Main Menu|>System Info" },
+			3*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Backlight", func(t *testing.T) {
+		_, err := s.client.SetBacklight(ctx, &displaypb.SetBacklightRequest{On: true})
+		require.NoError(t, err)
+		assert.True(t, s.panel.backlight)
+	})
+}
+
+func TestHandleButton(t *testing.T) {
+	s := startService(t, "")
+	ctx := context.Background()
+
+	assert.False(t, s.server.HandleButton(controller.ButtonEnter, true), "no screen, the menu gets the button")
+	assert.False(t, s.server.HandleButton(controller.ButtonEnter, false))
+
+	_, err := s.client.ShowScreen(ctx, &displaypb.ShowScreenRequest{Lines: []string{"Hello"}})
+	require.NoError(t, err)
+	assert.True(t, s.server.HandleButton(controller.ButtonSelect, true), "a press dismisses the screen")
+	assert.Equal(t, "Main Menu|>System Info", s.panel.shown())
+	assert.True(t, s.server.HandleButton(controller.ButtonSelect, false), "and its release is consumed")
+	assert.False(t, s.server.HandleButton(controller.ButtonSelect, true))
+
+	_, err = s.client.ShowScreen(ctx, &displaypb.ShowScreenRequest{Lines: []string{"Pick one"}, CaptureButtons: true})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.True(t, s.server.HandleButton(controller.ButtonEnter, true))
+		assert.True(t, s.server.HandleButton(controller.ButtonEnter, false))
+	}
+	assert.Equal(t, "Pick one|", s.panel.shown(), "a capturing screen stays up")
+
+	t.Run("Released when the stream closes", func(t *testing.T) {
+		streamCtx, cancel := context.WithCancel(ctx)
+		_, err := s.client.StreamButtons(streamCtx, &displaypb.StreamButtonsRequest{})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			s.server.mutex.Lock()
+			defer s.server.mutex.Unlock()
+			return len(s.server.subscribers) == 1
+		}, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, "Pick one|", s.panel.shown())
+
+		cancel()
+		assert.Eventually(t, func() bool { return s.panel.shown() == "Main Menu|>System Info" },
+			3*time.Second, 10*time.Millisecond, "the client died, the menu comes back")
+		assert.False(t, s.server.HandleButton(controller.ButtonEnter, true))
+		assert.False(t, s.server.HandleButton(controller.ButtonEnter, false))
+	})
+
+	t.Run("Released after the capture timeout", func(t *testing.T) {
+		original := captureTimeout
+		captureTimeout = 50 * time.Millisecond
+		t.Cleanup(func() { captureTimeout = original })
+
+		_, err := s.client.ShowScreen(ctx, &displaypb.ShowScreenRequest{Lines: []string{"Pick one"}, CaptureButtons: true})
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return s.panel.shown() == "Main Menu|>System Info" },
+			3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestStreamButtons(t *testing.T) {
+	s := startService(t, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := s.client.StreamButtons(ctx, &displaypb.StreamButtonsRequest{})
+	require.NoError(t, err)
+
+	// The stream is subscribed once the server handles the call
+	require.Eventually(t, func() bool {
+		s.server.mutex.Lock()
+		defer s.server.mutex.Unlock()
+		return len(s.server.subscribers) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	s.server.PublishButton(controller.ButtonEnter, true)
+	s.server.PublishButton(controller.ButtonUSBCopy, false)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, displaypb.Button_BUTTON_ENTER, event.Button)
+	assert.True(t, event.Pressed)
+	assert.WithinDuration(t, time.Now(), event.Time.AsTime(), time.Minute)
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, displaypb.Button_BUTTON_COPY, event.Button)
+	assert.False(t, event.Pressed)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		s.server.mutex.Lock()
+		defer s.server.mutex.Unlock()
+		return len(s.server.subscribers) == 0
+	}, 3*time.Second, 10*time.Millisecond, "a cancelled stream is unsubscribed")
+}
+
+func TestLEDs(t *testing.T) {
+	s := startService(t, "")
+	ctx := context.Background()
+
+	_, err := s.client.SetLED(ctx, &displaypb.SetLEDRequest{Led: displaypb.LED_LED_DISK2, On: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[controller.PanelLED]bool{controller.Disk2: true}, s.leds.states)
+
+	_, err = s.client.SetLED(ctx, &displaypb.SetLEDRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	leds, err := s.client.GetLEDs(ctx, &displaypb.GetLEDsRequest{})
+	require.NoError(t, err)
+	require.Len(t, leds.Leds, 9)
+	assert.Equal(t, displaypb.LED_LED_STATUS_GREEN, leds.Leds[0].Led)
+	assert.Equal(t, displaypb.LED_LED_DISK2, leds.Leds[4].Led)
+	assert.True(t, leds.Leds[4].On)
+	assert.False(t, leds.Leds[0].On)
+}
+
+func TestToken(t *testing.T) {
+	s := startService(t, "secret")
+
+	_, err := s.client.GetScreen(context.Background(), &displaypb.GetScreenRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	_, err = s.client.GetScreen(ctx, &displaypb.GetScreenRequest{})
+	assert.NoError(t, err)
+
+	stream, err := s.client.StreamButtons(context.Background(), &displaypb.StreamButtonsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "streams need the token too")
+}
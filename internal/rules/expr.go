@@ -0,0 +1,398 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed rule condition, e.g.
+//
+//	temperature{sensor="rack"} > 40 && humidity{sensor="rack"} > 70
+//
+// A comparison holds if any series of its metrics does. It is unknown while
+// a metric has no series at all, e.g. because its sensor cannot be read;
+// && and || then decide if the other side does, like SQL's NULL.
+type Expr struct {
+	source string
+	root   condition
+}
+
+// Parse reads a condition: comparisons (> >= < <= == !=) of metrics and
+// numbers, joined by &&, || and !, grouped with parentheses. A metric may
+// select series by label, as in disk_temperature{drive="sda"} or
+// pool_healthy{pool!=backup}.
+func Parse(source string) (*Expr, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.peek().kind != tokenEnd {
+		err = p.unexpected("&& or ||")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// String returns the expression as written
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Result is the outcome of evaluating an expression
+type Result struct {
+	// Known is false if metrics the outcome depends on have no series
+	Known bool
+	True  bool
+	// Value is the left side of the comparison that made the expression
+	// true, or of the first one evaluated otherwise; valid if HasValue
+	Value    float64
+	HasValue bool
+}
+
+// Eval evaluates the expression over the samples
+func (e *Expr) Eval(samples []Sample) Result {
+	return e.root.eval(samples)
+}
+
+// condition is a node of the expression that is true or false
+type condition interface {
+	eval(samples []Sample) Result
+}
+
+// operand is a side of a comparison, one value for a number and one for
+// each selected series of a metric
+type operand interface {
+	values(samples []Sample) []float64
+}
+
+type number float64
+
+func (n number) values([]Sample) []float64 {
+	return []float64{float64(n)}
+}
+
+// matcher compares a label of a series to a value
+type matcher struct {
+	label string
+	value string
+	not   bool
+}
+
+// selector picks the series of a metric whose labels match
+type selector struct {
+	name     string
+	matchers []matcher
+}
+
+func (s selector) values(samples []Sample) []float64 {
+	var values []float64
+	for _, sample := range samples {
+		if sample.Name == s.name && s.matches(sample.Labels) {
+			values = append(values, sample.Value)
+		}
+	}
+	return values
+}
+
+func (s selector) matches(labels map[string]string) bool {
+	for _, m := range s.matchers {
+		if (labels[m.label] == m.value) == m.not {
+			return false
+		}
+	}
+	return true
+}
+
+// comparisons are the comparison operators by token
+var comparisons = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+type comparison struct {
+	left, right operand
+	compare     func(a, b float64) bool
+}
+
+func (c comparison) eval(samples []Sample) Result {
+	left, right := c.left.values(samples), c.right.values(samples)
+	if len(left) == 0 || len(right) == 0 {
+		return Result{}
+	}
+	for _, a := range left {
+		for _, b := range right {
+			if c.compare(a, b) {
+				return Result{Known: true, True: true, Value: a, HasValue: true}
+			}
+		}
+	}
+	return Result{Known: true, Value: left[0], HasValue: true}
+}
+
+type and struct{ left, right condition }
+
+func (n and) eval(samples []Sample) Result {
+	left, right := n.left.eval(samples), n.right.eval(samples)
+	switch {
+	case left.Known && !left.True:
+		return left
+	case right.Known && !right.True:
+		return right
+	case left.Known && right.Known:
+		return left
+	}
+	return Result{}
+}
+
+type or struct{ left, right condition }
+
+func (n or) eval(samples []Sample) Result {
+	left, right := n.left.eval(samples), n.right.eval(samples)
+	switch {
+	case left.True:
+		return left
+	case right.True:
+		return right
+	case left.Known && right.Known:
+		return left
+	}
+	return Result{}
+}
+
+type not struct{ inner condition }
+
+func (n not) eval(samples []Sample) Result {
+	result := n.inner.eval(samples)
+	if result.Known {
+		result.True = !result.True
+	}
+	return result
+}
+
+// tokenKind classifies the tokens of an expression
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset in the expression, for errors
+	pos int
+}
+
+// symbols are the operators and punctuation, longest first
+var symbols = []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "=", "(", ")", "{", "}", ","}
+
+// lex splits an expression into tokens
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(source); {
+		c := source[pos]
+		rest := source[pos:]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case isLetter(c):
+			end := pos + 1
+			for end < len(source) && (isLetter(source[end]) || isDigit(source[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+		case isDigit(c) || c == '.' || ((c == '-' || c == '+') && len(rest) > 1 && (isDigit(rest[1]) || rest[1] == '.')):
+			end := pos + 1
+			for end < len(source) && (isLetter(source[end]) || isDigit(source[end]) || source[end] == '.' ||
+				((source[end] == '-' || source[end] == '+') && (source[end-1] == 'e' || source[end-1] == 'E'))) {
+				end++
+			}
+			if _, err := strconv.ParseFloat(source[pos:end], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", source[pos:end], pos+1)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[pos:end], pos: pos})
+			pos = end
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string at %d", pos+1)
+			}
+			text, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			pos += len(quoted)
+		default:
+			matched := false
+			for _, symbol := range symbols {
+				if strings.HasPrefix(rest, symbol) {
+					tokens = append(tokens, token{kind: tokenSymbol, text: symbol, pos: pos})
+					pos += len(symbol)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, pos+1)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(source)}), nil
+}
+
+// isLetter reports whether c may start a name: an ASCII letter or "_"
+func isLetter(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// parser is a recursive descent parser over the tokens of an expression
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+// accept takes the next token if it is the symbol
+func (p *parser) accept(symbol string) bool {
+	if t := p.peek(); t.kind == tokenSymbol && t.text == symbol {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	if t.kind == tokenEnd {
+		return fmt.Errorf("expected %s at the end", expected)
+	}
+	return fmt.Errorf("expected %s at %d, found %q", expected, t.pos+1, t.text)
+}
+
+// or := and { "||" and }
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right condition
+		if right, err = p.and(); err == nil {
+			left = or{left, right}
+		}
+	}
+	return left, err
+}
+
+// and := unary { "&&" unary }
+func (p *parser) and() (condition, error) {
+	left, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var right condition
+		if right, err = p.unary(); err == nil {
+			left = and{left, right}
+		}
+	}
+	return left, err
+}
+
+// unary := "!" unary | "(" or ")" | operand comparison operand
+func (p *parser) unary() (condition, error) {
+	if p.accept("!") {
+		inner, err := p.unary()
+		return not{inner}, err
+	}
+	if p.accept("(") {
+		inner, err := p.or()
+		if err == nil && !p.accept(")") {
+			err = p.unexpected(`")"`)
+		}
+		return inner, err
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	compare, ok := comparisons[t.text]
+	if t.kind != tokenSymbol || !ok {
+		return nil, p.unexpected("a comparison")
+	}
+	p.take()
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return comparison{left: left, right: right, compare: compare}, nil
+}
+
+// operand := number | name [ "{" label ("="|"!=") value { "," ... } "}" ]
+func (p *parser) operand() (operand, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.take()
+		value, _ := strconv.ParseFloat(t.text, 64)
+		return number(value), nil
+	case tokenIdent:
+		p.take()
+	default:
+		return nil, p.unexpected("a metric or number")
+	}
+
+	s := selector{name: t.text}
+	if !p.accept("{") {
+		return s, nil
+	}
+	for !p.accept("}") {
+		if len(s.matchers) > 0 && !p.accept(",") {
+			return nil, p.unexpected(`"," or "}"`)
+		}
+		label := p.peek()
+		if label.kind != tokenIdent {
+			return nil, p.unexpected("a label")
+		}
+		p.take()
+		var m matcher
+		switch {
+		case p.accept("="):
+		case p.accept("!="):
+			m.not = true
+		default:
+			return nil, p.unexpected(`"=" or "!="`)
+		}
+		value := p.peek()
+		if value.kind != tokenIdent && value.kind != tokenNumber && value.kind != tokenString {
+			return nil, p.unexpected("a label value")
+		}
+		p.take()
+		m.label, m.value = label.text, value.text
+		s.matchers = append(s.matchers, m)
+	}
+	return s, nil
+}
@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panelSamples are the metrics of a NAS with a warm third drive
+var panelSamples = []Sample{
+	{Name: "temperature", Labels: map[string]string{"sensor": "rack"}, Value: 31.5},
+	{Name: "disk_temperature", Labels: map[string]string{"drive": "sda", "bay": "1"}, Value: 38},
+	{Name: "disk_temperature", Labels: map[string]string{"drive": "sdc", "bay": "3"}, Value: 57},
+	{Name: "load1", Value: 0.4},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		expr  string
+		known bool
+		true  bool
+		value float64
+	}{
+		{expr: "load1 > 0.2", known: true, true: true, value: 0.4},
+		{expr: "load1>=1e-1", known: true, true: true, value: 0.4},
+		{expr: "load1 < -1", known: true, value: 0.4},
+		{expr: `disk_temperature{bay=3} > 55`, known: true, true: true, value: 57},
+		{expr: `disk_temperature{drive="sda"} > 55`, known: true, value: 38},
+		{expr: `disk_temperature{drive!=sdc} > 55`, known: true, value: 38},
+		{expr: `disk_temperature{drive="sdc", bay="3"} == 57`, known: true, true: true, value: 57},
+		// Any series of a metric
+		{expr: "disk_temperature > 55", known: true, true: true, value: 57},
+		{expr: "55 < disk_temperature", known: true, true: true, value: 55},
+		{expr: `disk_temperature{bay=3} > 55 && temperature{sensor="rack"} > 30`, known: true, true: true, value: 57},
+		{expr: `disk_temperature{bay=3} > 55 && temperature > 40`, known: true, value: 31.5},
+		{expr: `load1 > 1 || temperature > 30`, known: true, true: true, value: 31.5},
+		{expr: `!(load1 > 1)`, known: true, true: true, value: 0.4},
+		{expr: `!load1 > 1 || load1 > 2 && load1 < 3`, known: true, true: true, value: 0.4},
+		// Metrics without series are unknown, unless the other side decides
+		{expr: "fan_rpm < 500", known: false},
+		{expr: "!(fan_rpm < 500)", known: false},
+		{expr: "fan_rpm < 500 && load1 > 0.2", known: false},
+		{expr: "fan_rpm < 500 && load1 > 1", known: true, value: 0.4},
+		{expr: "fan_rpm < 500 || load1 > 0.2", known: true, true: true, value: 0.4},
+		{expr: "fan_rpm < 500 || load1 > 1", known: false},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expr, expr.String())
+			result := expr.Eval(panelSamples)
+			assert.Equal(t, tt.known, result.Known, "known")
+			assert.Equal(t, tt.true, result.True, "true")
+			if tt.known {
+				assert.True(t, result.HasValue)
+				assert.Equal(t, tt.value, result.Value)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"load1",
+		"load1 >",
+		"load1 > 1 &&",
+		"load1 = 1",
+		"(load1 > 1",
+		"load1 > 1)",
+		"load1 > 1 load5 > 1",
+		"disk_temperature{bay} > 1",
+		"disk_temperature{bay=3 > 1",
+		"disk_temperature{bay=3 drive=sda} > 1",
+		`disk_temperature{drive="sda} > 1`,
+		"load1 > 1.2.3",
+		"load1 > 1 & load5 > 1",
+		"temperatur€ > 1",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
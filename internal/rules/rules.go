@@ -0,0 +1,301 @@
+// Package rules raises alerts, switches LEDs and beeps while conditions over
+// the metrics of the panel hold.
+//
+// The metrics are collected from sources, such as the sensors or the SMART
+// sampler, as samples: a metric name, labels telling its series apart and a
+// value. Every interval the engine collects them and evaluates each rule's
+// expression. A rule is raised while its expression holds and cleared once
+// it no longer does, or once its clear expression holds, which gives it
+// hysteresis. While the outcome is unknown, e.g. because a sensor cannot be
+// read, the rule keeps its state.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is how often the rules are evaluated when no interval is
+// set
+const DefaultInterval = 15 * time.Second
+
+// BeepTime is how long each beep of a rule sounds
+const BeepTime = 300 * time.Millisecond
+
+// beepPause separates the beeps of a rule
+const beepPause = 200 * time.Millisecond
+
+// ValuePlaceholder in the text of a rule is replaced by the value of the
+// metric that raised it
+const ValuePlaceholder = "{value}"
+
+// Sample is the value of one series of a metric
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Source collects samples of the metrics it publishes. Metrics it cannot
+// read at the moment are left out.
+type Source func() []Sample
+
+// Alerter shows and clears alerts. alert.Manager satisfies it.
+type Alerter interface {
+	Raise(key, text string)
+	RaiseCritical(key, text string)
+	Clear(key string)
+}
+
+// LEDs switches the LEDs of rules. controller.LEDControllerInterface
+// satisfies it.
+type LEDs interface {
+	SetLED(led controller.PanelLED, on bool) error
+}
+
+// Buzzer sounds the beeps of rules. buzzer.Buzzer satisfies it.
+type Buzzer interface {
+	Beep(ctx context.Context, duration time.Duration) error
+}
+
+// Rule raises an alert, switches LEDs or both while its expression holds
+type Rule struct {
+	// Name identifies the rule in the log
+	Name string
+	When *Expr
+	// Clear ends the rule; nil ends it as soon as When no longer holds
+	Clear *Expr
+	// Key identifies the alert; "" is "rule:" followed by the name
+	Key string
+	// Text is the alert's text, with ValuePlaceholder replaced; "" shows
+	// the name
+	Text     string
+	Critical bool
+	// Silent rules raise no alert, e.g. rules only switching LEDs
+	Silent bool
+	// LEDs are switched on while the rule is raised and off after it
+	LEDs []controller.PanelLED
+	// Beeps sound the buzzer this many times when the rule is raised
+	Beeps int
+}
+
+// key returns the key of the rule's alert
+func (r Rule) key() string {
+	if r.Key != "" {
+		return r.Key
+	}
+	return "rule:" + r.Name
+}
+
+// text returns the alert's text for a result of When
+func (r Rule) text(result Result) string {
+	text := r.Text
+	if text == "" {
+		text = r.Name
+	}
+	value := "?"
+	if result.HasValue {
+		value = strconv.FormatFloat(result.Value, 'f', 1, 64)
+	}
+	return strings.ReplaceAll(text, ValuePlaceholder, value)
+}
+
+// Engine evaluates rules over the samples of its sources
+type Engine struct {
+	logger   *logrus.Entry
+	rules    []Rule
+	sources  []Source
+	interval time.Duration
+
+	mutex   sync.Mutex
+	alerter Alerter
+	leds    LEDs
+	buzzer  Buzzer
+	// raised holds the indexes of the raised rules
+	raised map[int]bool
+	stop   chan struct{}
+	done   sync.WaitGroup
+}
+
+// NewEngine creates an engine evaluating rules every interval (0 for
+// DefaultInterval). Alert keys have to be unique.
+func NewEngine(rules []Rule, sources []Source, interval time.Duration) (*Engine, error) {
+	keys := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.When == nil {
+			return nil, fmt.Errorf("rule %q has no expression", rule.Name)
+		}
+		if keys[rule.key()] {
+			return nil, fmt.Errorf("duplicate rule %q", rule.Name)
+		}
+		keys[rule.key()] = true
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Engine{
+		logger:   logrus.WithField("component", "rules"),
+		rules:    rules,
+		sources:  sources,
+		interval: interval,
+		raised:   make(map[int]bool),
+	}, nil
+}
+
+// Start evaluates the rules now and then every interval in the background,
+// raising alerts on alerter, switching leds and beeping on buzzer; leds and
+// buzzer may be nil
+func (e *Engine) Start(alerter Alerter, leds LEDs, buzzer Buzzer) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.stop != nil {
+		return
+	}
+	e.alerter, e.leds, e.buzzer = alerter, leds, buzzer
+	e.stop = make(chan struct{})
+	stop := e.stop
+	e.done.Add(1)
+	go func() {
+		defer e.done.Done()
+
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			e.Evaluate()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the evaluation. Raised rules stay raised; their LEDs are left
+// on, as after any other LED change.
+func (e *Engine) Stop() {
+	e.mutex.Lock()
+	stop := e.stop
+	e.stop = nil
+	e.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		e.done.Wait()
+	}
+}
+
+// Evaluate collects the samples and evaluates every rule once
+func (e *Engine) Evaluate() {
+	var samples []Sample
+	for _, source := range e.sources {
+		samples = append(samples, source()...)
+	}
+
+	e.mutex.Lock()
+	var beeping []Rule
+	for i, rule := range e.rules {
+		result := rule.When.Eval(samples)
+		switch {
+		case result.True:
+			if e.raise(i, rule, result) && rule.Beeps > 0 {
+				beeping = append(beeping, rule)
+			}
+		case e.raised[i]:
+			end := result
+			end.True = !end.True
+			if rule.Clear != nil {
+				end = rule.Clear.Eval(samples)
+			}
+			if end.Known && end.True {
+				e.clear(i, rule)
+			}
+		}
+	}
+	buzzer := e.buzzer
+	e.mutex.Unlock()
+
+	// Beeping takes a while, Raised need not wait for it
+	for _, rule := range beeping {
+		e.beep(buzzer, rule)
+	}
+}
+
+// Raised returns the names of the raised rules, sorted
+func (e *Engine) Raised() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	names := make([]string, 0, len(e.raised))
+	for i := range e.raised {
+		names = append(names, e.rules[i].Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// raise raises a rule, or updates the text of its alert if it is raised. It
+// returns true if the rule was not raised before.
+func (e *Engine) raise(i int, rule Rule, result Result) bool {
+	raised := !e.raised[i]
+	if raised {
+		e.raised[i] = true
+		e.logger.WithFields(logrus.Fields{"rule": rule.Name, "when": rule.When.String()}).Info("Rule raised")
+		e.switchLEDs(rule, true)
+	}
+	if rule.Silent || e.alerter == nil {
+		return raised
+	}
+	if rule.Critical {
+		e.alerter.RaiseCritical(rule.key(), rule.text(result))
+	} else {
+		e.alerter.Raise(rule.key(), rule.text(result))
+	}
+	return raised
+}
+
+// clear ends a raised rule
+func (e *Engine) clear(i int, rule Rule) {
+	delete(e.raised, i)
+	e.logger.WithField("rule", rule.Name).Info("Rule cleared")
+	e.switchLEDs(rule, false)
+	if !rule.Silent && e.alerter != nil {
+		e.alerter.Clear(rule.key())
+	}
+}
+
+// beep sounds the beeps of a rule just raised on buzzer, which may be nil
+func (e *Engine) beep(buzzer Buzzer, rule Rule) {
+	if buzzer == nil {
+		return
+	}
+	for n := 0; n < rule.Beeps; n++ {
+		if n > 0 {
+			time.Sleep(beepPause)
+		}
+		if err := buzzer.Beep(context.Background(), BeepTime); err != nil {
+			e.logger.WithError(err).WithField("rule", rule.Name).Warn("Failed to beep")
+			return
+		}
+	}
+}
+
+func (e *Engine) switchLEDs(rule Rule, on bool) {
+	if e.leds == nil {
+		return
+	}
+	for _, l := range rule.LEDs {
+		if err := e.leds.SetLED(l, on); err != nil {
+			e.logger.WithError(err).WithFields(logrus.Fields{"rule": rule.Name, "led": l}).Warn("Failed to switch LED")
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAlerter keeps the active alerts, critical ones marked with a
+// leading "!"
+type recordingAlerter struct {
+	active map[string]string
+}
+
+func (a *recordingAlerter) Raise(key, text string) {
+	a.active[key] = text
+}
+
+func (a *recordingAlerter) RaiseCritical(key, text string) {
+	a.active[key] = "!" + text
+}
+
+func (a *recordingAlerter) Clear(key string) {
+	delete(a.active, key)
+}
+
+func (a *recordingAlerter) keys() []string {
+	keys := make([]string, 0, len(a.active))
+	for key := range a.active {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordingLEDs keeps the LEDs switched on
+type recordingLEDs struct {
+	on  map[controller.PanelLED]bool
+	err error
+}
+
+func (l *recordingLEDs) SetLED(led controller.PanelLED, on bool) error {
+	l.on[led] = on
+	return l.err
+}
+
+// countingBuzzer counts the beeps
+type countingBuzzer struct {
+	beeps int
+}
+
+func (b *countingBuzzer) Beep(ctx context.Context, duration time.Duration) error {
+	b.beeps++
+	return nil
+}
+
+func mustParse(t *testing.T, source string) *Expr {
+	t.Helper()
+	expr, err := Parse(source)
+	require.NoError(t, err)
+	return expr
+}
+
+func TestEngine(t *testing.T) {
+	humidity := map[string]float64{}
+	source := func() []Sample {
+		value, ok := humidity["rack"]
+		if !ok {
+			return nil
+		}
+		return []Sample{{Name: "humidity", Labels: map[string]string{"sensor": "rack"}, Value: value}}
+	}
+
+	engine, err := NewEngine([]Rule{
+		{
+			Name:  "rack humid",
+			When:  mustParse(t, `humidity{sensor="rack"} > 70`),
+			Clear: mustParse(t, `humidity{sensor="rack"} <= 65`),
+			Key:   "sensor:rack:humidity:above",
+			Text:  "rack humidity\n{value} above 70.0",
+		},
+		{
+			Name:     "rack wet",
+			When:     mustParse(t, "humidity > 95"),
+			Critical: true,
+			LEDs:     []controller.PanelLED{controller.Disk3},
+		},
+		{
+			Name:   "rack dry",
+			When:   mustParse(t, "humidity < 20"),
+			Silent: true,
+			LEDs:   []controller.PanelLED{controller.StatusRed, controller.USB},
+		},
+	}, []Source{source}, 0)
+	require.NoError(t, err)
+	alerter := &recordingAlerter{active: make(map[string]string)}
+	leds := &recordingLEDs{on: make(map[controller.PanelLED]bool)}
+	engine.alerter, engine.leds = alerter, leds
+	evaluate := func(value float64) {
+		humidity["rack"] = value
+		engine.Evaluate()
+	}
+
+	evaluate(50)
+	assert.Empty(t, alerter.keys())
+	assert.Empty(t, engine.Raised())
+
+	evaluate(72)
+	assert.Equal(t, []string{"sensor:rack:humidity:above"}, alerter.keys())
+	assert.Equal(t, "rack humidity\n72.0 above 70.0", alerter.active["sensor:rack:humidity:above"])
+	evaluate(74)
+	assert.Equal(t, "rack humidity\n74.0 above 70.0", alerter.active["sensor:rack:humidity:above"], "the text follows the value")
+
+	// Inside the limit but not by the hysteresis margin
+	evaluate(68)
+	assert.Equal(t, []string{"sensor:rack:humidity:above"}, alerter.keys())
+	assert.Equal(t, "rack humidity\n74.0 above 70.0", alerter.active["sensor:rack:humidity:above"])
+
+	// A sensor that cannot be read keeps the rules as they are
+	delete(humidity, "rack")
+	engine.Evaluate()
+	assert.Equal(t, []string{"rack humid"}, engine.Raised())
+
+	evaluate(65)
+	assert.Empty(t, alerter.keys())
+
+	// The default key and text, LEDs switched while raised
+	evaluate(96)
+	assert.Equal(t, []string{"rule:rack wet", "sensor:rack:humidity:above"}, alerter.keys())
+	assert.Equal(t, "!rack wet", alerter.active["rule:rack wet"])
+	assert.True(t, leds.on[controller.Disk3])
+	evaluate(90)
+	assert.Equal(t, []string{"sensor:rack:humidity:above"}, alerter.keys())
+	assert.False(t, leds.on[controller.Disk3])
+
+	// Silent rules only switch LEDs; failures to switch them are logged
+	leds.err = errors.New("port busy")
+	evaluate(10)
+	assert.Empty(t, alerter.keys())
+	assert.Equal(t, []string{"rack dry"}, engine.Raised())
+	assert.True(t, leds.on[controller.StatusRed])
+	assert.True(t, leds.on[controller.USB])
+}
+
+func TestNewEngine_Invalid(t *testing.T) {
+	when := mustParse(t, "load1 > 4")
+	_, err := NewEngine([]Rule{{Name: "busy", When: when}, {Name: "busy", When: when}}, nil, 0)
+	assert.Error(t, err, "duplicate names")
+	_, err = NewEngine([]Rule{{Name: "busy", When: when, Key: "load"}, {Name: "loaded", When: when, Key: "load"}}, nil, 0)
+	assert.Error(t, err, "duplicate keys")
+	_, err = NewEngine([]Rule{{Name: "empty"}}, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestEngine_StartStop(t *testing.T) {
+	engine, err := NewEngine([]Rule{{Name: "busy", When: mustParse(t, "load1 > 4")}}, []Source{
+		func() []Sample { return []Sample{{Name: "load1", Value: 6}} },
+	}, time.Hour)
+	require.NoError(t, err)
+	alerter := &recordingAlerter{active: make(map[string]string)}
+
+	engine.Start(alerter, nil, nil)
+	assert.Eventually(t, func() bool { return len(engine.Raised()) == 1 }, time.Second, 10*time.Millisecond,
+		"the rules are evaluated when the engine starts")
+	engine.Stop()
+	engine.Stop()
+	assert.Equal(t, []string{"rule:busy"}, alerter.keys())
+}
+
+func TestEngine_Beep(t *testing.T) {
+	load := 6.0
+	engine, err := NewEngine([]Rule{
+		{Name: "busy", When: mustParse(t, "load1 > 4"), Beeps: 2},
+		{Name: "loaded", When: mustParse(t, "load1 > 2")},
+	}, []Source{
+		func() []Sample { return []Sample{{Name: "load1", Value: load}} },
+	}, 0)
+	require.NoError(t, err)
+	buzzer := &countingBuzzer{}
+	engine.buzzer = buzzer
+
+	engine.Evaluate()
+	assert.Equal(t, 2, buzzer.beeps, "only the beeping rule beeps")
+	engine.Evaluate()
+	assert.Equal(t, 2, buzzer.beeps, "a raised rule does not beep again")
+
+	load = 1
+	engine.Evaluate()
+	load = 6
+	engine.Evaluate()
+	assert.Equal(t, 4, buzzer.beeps, "it beeps again when raised again")
+
+	// Without a buzzer the rule is still raised
+	engine.buzzer = nil
+	load = 1
+	engine.Evaluate()
+	load = 6
+	engine.Evaluate()
+	assert.Equal(t, []string{"busy", "loaded"}, engine.Raised())
+}
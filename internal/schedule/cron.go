@@ -0,0 +1,204 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch bounds how far Next looks ahead; every valid expression matches
+// within it, even "0 0 29 2 *" at the start of a century without leap day
+const maxSearch = 9 * 366 * 24 * time.Hour
+
+// Cron is a parsed five field cron expression:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields take "*", numbers, ranges ("1-5"), steps ("*/15", "8-18/2") and
+// comma separated lists of those. Months and weekdays can be named by their
+// first three letters ("jan", "fri"); Sunday is 0 or 7. As in cron, when both
+// day fields are restricted a day matching either of them matches.
+type Cron struct {
+	expr    string
+	minutes uint64
+	hours   uint64
+	days    uint64
+	months  uint64
+	weekday uint64
+	// anyDay and anyWeekday record which day fields are "*"
+	anyDay     bool
+	anyWeekday bool
+}
+
+// macros are the supported @ shorthands
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// field describes the range and names of one cron field
+type field struct {
+	name     string
+	min, max int
+	// names are the values' names, starting at min
+	names []string
+}
+
+var (
+	minuteField  = field{name: "minute", min: 0, max: 59}
+	hourField    = field{name: "hour", min: 0, max: 23}
+	dayField     = field{name: "day of month", min: 1, max: 31}
+	monthField   = field{name: "month", min: 1, max: 12, names: monthNames}
+	weekdayField = field{name: "day of week", min: 0, max: 7, names: weekdayNames}
+)
+
+// ParseCron parses a cron expression or one of @hourly, @daily, @weekly,
+// @monthly and @yearly
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.ToLower(strings.TrimSpace(expr))
+	if macro, ok := macros[spec]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, has %d", expr, len(fields))
+	}
+
+	cron := &Cron{
+		expr:       expr,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	var err error
+	for i, target := range []struct {
+		bits  *uint64
+		field field
+	}{
+		{&cron.minutes, minuteField},
+		{&cron.hours, hourField},
+		{&cron.days, dayField},
+		{&cron.months, monthField},
+		{&cron.weekday, weekdayField},
+	} {
+		if *target.bits, err = target.field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+	// Sunday may be written as 7
+	if cron.weekday&(1<<7) != 0 {
+		cron.weekday |= 1
+	}
+	return cron, nil
+}
+
+// parse turns a field into a bit set of its values
+func (f field) parse(spec string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(spec, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepSpec, f.name)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rangeSpec != "*" {
+			lowSpec, highSpec, isRange := strings.Cut(rangeSpec, "-")
+			var err error
+			if low, err = f.value(lowSpec); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = f.value(highSpec); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "5/15" means from 5 to the end in steps of 15
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in %s", rangeSpec, f.name)
+			}
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name of the field
+func (f field) value(spec string) (int, error) {
+	for i, name := range f.names {
+		if spec == name {
+			return f.min + i, nil
+		}
+	}
+	value, err := strconv.Atoi(spec)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, spec)
+	}
+	return value, nil
+}
+
+// String returns the expression as it was given
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Matches reports whether the minute of t is scheduled
+func (c *Cron) Matches(t time.Time) bool {
+	return c.minutes&(1<<t.Minute()) != 0 &&
+		c.hours&(1<<t.Hour()) != 0 &&
+		c.months&(1<<int(t.Month())) != 0 &&
+		c.matchesDay(t)
+}
+
+// matchesDay applies the day of month and day of week fields
+func (c *Cron) matchesDay(t time.Time) bool {
+	day := c.days&(1<<t.Day()) != 0
+	weekday := c.weekday&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// Next returns the first scheduled minute after t, or the zero time if there
+// is none (e.g. "0 0 31 2 *")
+func (c *Cron) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxSearch)
+	for next.Before(end) {
+		switch {
+		case c.months&(1<<int(next.Month())) == 0 || !c.matchesDay(next):
+			year, month, day := next.Date()
+			next = time.Date(year, month, day+1, 0, 0, 0, 0, next.Location())
+		case c.hours&(1<<next.Hour()) == 0:
+			year, month, day := next.Date()
+			next = time.Date(year, month, day, next.Hour()+1, 0, 0, 0, next.Location())
+		case c.minutes&(1<<next.Minute()) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// at returns a local time in October 2026; the 16th is a Friday
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 18 * * fri",
+		"*/15 8-18 * * mon-fri",
+		"5/20 0,12 1 jan,jul *",
+		"0 0 * * 7",
+		"@hourly",
+		"@Daily",
+	}
+	for _, expr := range valid {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * * friday",
+		"@reboot",
+	}
+	for _, expr := range invalid {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	cron, err := ParseCron("0 18 * * fri")
+	require.NoError(t, err)
+	assert.True(t, cron.Matches(at(16, 18, 0)))
+	assert.True(t, cron.Matches(at(16, 18, 0).Add(30*time.Second)))
+	assert.False(t, cron.Matches(at(16, 18, 1)))
+	assert.False(t, cron.Matches(at(17, 18, 0)))
+
+	// Sunday can be written as 7
+	cron, err = ParseCron("0 0 * * 7")
+	require.NoError(t, err)
+	assert.True(t, cron.Matches(at(18, 0, 0)))
+
+	// Restricting both day fields matches either
+	cron, err = ParseCron("0 0 1 * fri")
+	require.NoError(t, err)
+	assert.True(t, cron.Matches(at(1, 0, 0)))
+	assert.True(t, cron.Matches(at(16, 0, 0)))
+	assert.False(t, cron.Matches(at(15, 0, 0)))
+
+	cron, err = ParseCron("*/20 8-18/5 * * *")
+	require.NoError(t, err)
+	assert.True(t, cron.Matches(at(15, 13, 40)))
+	assert.False(t, cron.Matches(at(15, 13, 50)))
+	assert.False(t, cron.Matches(at(15, 14, 0)))
+}
+
+func TestCronNext(t *testing.T) {
+	cron, err := ParseCron("0 18 * * fri")
+	require.NoError(t, err)
+	assert.Equal(t, at(16, 18, 0), cron.Next(at(14, 9, 30)))
+	assert.Equal(t, at(23, 18, 0), cron.Next(at(16, 18, 0)))
+
+	cron, err = ParseCron("@hourly")
+	require.NoError(t, err)
+	assert.Equal(t, at(15, 10, 0), cron.Next(at(15, 9, 0).Add(59*time.Minute+59*time.Second)))
+
+	cron, err = ParseCron("0 0 29 2 *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2028, time.February, 29, 0, 0, 0, 0, time.Local), cron.Next(at(15, 0, 0)))
+
+	cron, err = ParseCron("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, cron.Next(at(15, 0, 0)).IsZero())
+}
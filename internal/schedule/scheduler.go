@@ -0,0 +1,272 @@
+// Package schedule shows screens on the LCD at times given by cron
+// expressions, e.g. a backup reminder at 18:00 on Fridays or the system
+// statistics every hour.
+//
+// A due entry is shown on its own display layer for its duration, its pages
+// in turn. An entry coming due while another is shown replaces it. A button
+// press dismisses the shown entry and gives the panel back to whatever was
+// below it.
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/sirupsen/logrus"
+)
+
+// Display is where scheduled screens are drawn. screen.Layer satisfies it.
+type Display interface {
+	WriteText(text string) error
+	Release() error
+}
+
+// Page returns the text of one page of an entry. It is called each time the
+// page comes up, so it always shows current values.
+type Page func() (string, error)
+
+// Entry is a screen shown on a schedule
+type Entry struct {
+	Name string
+	Cron *Cron
+	// Pages are shown in turn, each for Interval; pages that fail are skipped
+	Pages    []Page
+	Interval time.Duration
+	// Duration is how long the entry stays up
+	Duration time.Duration
+}
+
+// Scheduler shows entries when they are due
+type Scheduler struct {
+	display Display
+	entries []Entry
+	logger  *logrus.Entry
+	// now is the clock, replaced by tests
+	now func() time.Time
+
+	mutex sync.Mutex
+	// stopShow ends the shown entry (nil = none shown)
+	stopShow context.CancelFunc
+	// shows counts the entries shown; current is the number of the one on
+	// the display (0 = none), so a replaced or dismissed show stops drawing
+	// and only the latest one releases the display
+	shows   int
+	current int
+	// swallow holds buttons whose release belongs to a dismissing press
+	swallow map[controller.PanelButton]bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	showing sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler drawing the entries on display
+func NewScheduler(display Display, entries []Entry) *Scheduler {
+	return &Scheduler{
+		display: display,
+		entries: entries,
+		logger:  logrus.WithField("component", "schedule"),
+		now:     time.Now,
+		swallow: make(map[controller.PanelButton]bool),
+	}
+}
+
+// Start waits for entries to come due in the background. Starting a running
+// scheduler restarts it.
+func (s *Scheduler) Start() {
+	s.Stop()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.entries) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+}
+
+// Stop ends the scheduler and dismisses the shown entry
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	s.Dismiss()
+	s.showing.Wait()
+}
+
+// run fires the entries due at each scheduled minute until ctx is cancelled
+func (s *Scheduler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	// fired is the last minute fired; it is not fired twice should the
+	// clock be behind the timer
+	var fired time.Time
+	for {
+		now := s.now()
+		if now.Before(fired) {
+			now = fired
+		}
+		var next time.Time
+		for _, entry := range s.entries {
+			if due := entry.Cron.Next(now); !due.IsZero() && (next.IsZero() || due.Before(next)) {
+				next = due
+			}
+		}
+		if next.IsZero() {
+			s.logger.Warn("No scheduled screen will ever be due")
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.fire(next)
+		fired = next
+	}
+}
+
+// fire shows the last entry due at the minute of t, so entries listed later
+// win when several are due at once
+func (s *Scheduler) fire(t time.Time) {
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if s.entries[i].Cron.Matches(t) {
+			s.show(s.entries[i])
+			return
+		}
+	}
+}
+
+// show replaces the shown entry with entry
+func (s *Scheduler) show(entry Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.dismiss()
+	ctx, cancel := context.WithTimeout(context.Background(), entry.Duration)
+	s.stopShow = cancel
+	s.shows++
+	s.current = s.shows
+	show := s.shows
+
+	s.logger.WithField("entry", entry.Name).Info("Showing scheduled screen")
+	s.showing.Add(1)
+	go func() {
+		defer s.showing.Done()
+		s.showPages(ctx, entry, show)
+		s.finish(show)
+	}()
+}
+
+// showPages shows the entry's pages in turn until ctx ends
+func (s *Scheduler) showPages(ctx context.Context, entry Entry, show int) {
+	ticker := time.NewTicker(entry.Interval)
+	defer ticker.Stop()
+
+	next := 0
+	for {
+		for tries := 0; tries < len(entry.Pages); tries++ {
+			page := entry.Pages[next]
+			next = (next + 1) % len(entry.Pages)
+
+			text, err := page()
+			if err != nil {
+				s.logger.WithError(err).WithField("entry", entry.Name).Debug("Skipping scheduled page")
+				continue
+			}
+			s.draw(show, text)
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw writes a page of a show unless it was replaced or dismissed in the
+// meantime
+func (s *Scheduler) draw(show int, text string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if show != s.current {
+		return
+	}
+	if err := s.display.WriteText(text); err != nil {
+		s.logger.WithError(err).Warn("Failed to show scheduled screen")
+	}
+}
+
+// finish releases the display once a show ended, unless another one
+// replaced it
+func (s *Scheduler) finish(show int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if show != s.shows {
+		return
+	}
+	s.stopShow = nil
+	s.current = 0
+	if err := s.display.Release(); err != nil {
+		s.logger.WithError(err).Warn("Failed to release scheduled screen")
+	}
+}
+
+// Dismiss ends the shown entry early
+func (s *Scheduler) Dismiss() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.dismiss()
+}
+
+// dismiss stops the shown entry; its routine releases the display. Caller
+// must hold the mutex.
+func (s *Scheduler) dismiss() {
+	if s.stopShow != nil {
+		s.stopShow()
+		s.stopShow = nil
+	}
+	s.current = 0
+}
+
+// HandleButton dismisses the shown entry on a button press. It returns true
+// if the event was consumed; the release of a dismissing press is consumed
+// too.
+func (s *Scheduler) HandleButton(button controller.PanelButton, pressed bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !pressed {
+		if s.swallow[button] {
+			delete(s.swallow, button)
+			return true
+		}
+		return false
+	}
+	if s.stopShow == nil {
+		return false
+	}
+
+	s.logger.Info("Scheduled screen dismissed")
+	s.dismiss()
+	s.swallow[button] = true
+	return true
+}
@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDisplay keeps the last text written and whether it was released
+type recordingDisplay struct {
+	mutex    sync.Mutex
+	text     string
+	released bool
+}
+
+func (d *recordingDisplay) WriteText(text string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.text, d.released = text, false
+	return nil
+}
+
+func (d *recordingDisplay) Release() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.text, d.released = "", true
+	return nil
+}
+
+func (d *recordingDisplay) shown() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.text
+}
+
+func (d *recordingDisplay) isReleased() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.released
+}
+
+// textEntry is an entry showing fixed pages
+func textEntry(t *testing.T, expr string, duration time.Duration, pages ...string) Entry {
+	t.Helper()
+
+	cron, err := ParseCron(expr)
+	require.NoError(t, err)
+	entry := Entry{Name: expr, Cron: cron, Interval: 20 * time.Millisecond, Duration: duration}
+	for _, page := range pages {
+		page := page
+		entry.Pages = append(entry.Pages, func() (string, error) { return page, nil })
+	}
+	return entry
+}
+
+func TestScheduler_ShowsDueEntries(t *testing.T) {
+	display := &recordingDisplay{}
+	reminder := textEntry(t, "0 18 * * fri", time.Hour, "Backup reminder\nInsert USB disk")
+	stats := textEntry(t, "@hourly", time.Hour, "Up 3d 4h", "Load 0.52 0.40")
+	s := NewScheduler(display, []Entry{stats, reminder})
+	defer s.Stop()
+
+	s.fire(at(15, 17, 30))
+	assert.Equal(t, "", display.shown(), "nothing is due")
+
+	// Both are due; the later entry wins
+	s.fire(at(16, 18, 0))
+	assert.Eventually(t, func() bool { return display.shown() == "Backup reminder\nInsert USB disk" }, time.Second, 5*time.Millisecond)
+
+	// Pages take turns
+	s.fire(at(16, 19, 0))
+	assert.Eventually(t, func() bool { return display.shown() == "Up 3d 4h" }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return display.shown() == "Load 0.52 0.40" }, time.Second, 5*time.Millisecond)
+}
+
+func TestScheduler_EndsAfterDuration(t *testing.T) {
+	display := &recordingDisplay{}
+	s := NewScheduler(display, []Entry{textEntry(t, "@hourly", 50*time.Millisecond, "Stats")})
+	defer s.Stop()
+
+	s.fire(at(16, 18, 0))
+	assert.Eventually(t, func() bool { return display.shown() == "Stats" }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, display.isReleased, time.Second, 5*time.Millisecond)
+}
+
+func TestScheduler_SkipsFailingPages(t *testing.T) {
+	display := &recordingDisplay{}
+	entry := textEntry(t, "@hourly", time.Hour, "Load 0.52 0.40")
+	entry.Pages = append([]Page{func() (string, error) { return "", errors.New("no sensor") }}, entry.Pages...)
+	s := NewScheduler(display, []Entry{entry})
+	defer s.Stop()
+
+	s.fire(at(16, 18, 0))
+	assert.Eventually(t, func() bool { return display.shown() == "Load 0.52 0.40" }, time.Second, 5*time.Millisecond)
+}
+
+func TestScheduler_ButtonDismisses(t *testing.T) {
+	display := &recordingDisplay{}
+	s := NewScheduler(display, []Entry{textEntry(t, "@hourly", time.Hour, "Stats")})
+	defer s.Stop()
+
+	assert.False(t, s.HandleButton(controller.ButtonEnter, true), "nothing shown, the press goes on")
+	assert.False(t, s.HandleButton(controller.ButtonEnter, false))
+
+	s.fire(at(16, 18, 0))
+	assert.Eventually(t, func() bool { return display.shown() == "Stats" }, time.Second, 5*time.Millisecond)
+
+	assert.True(t, s.HandleButton(controller.ButtonSelect, true))
+	assert.Eventually(t, display.isReleased, time.Second, 5*time.Millisecond)
+	assert.True(t, s.HandleButton(controller.ButtonSelect, false), "the release of the dismissing press is consumed")
+	assert.False(t, s.HandleButton(controller.ButtonSelect, true))
+}
+
+func TestScheduler_WaitsForNextMinute(t *testing.T) {
+	display := &recordingDisplay{}
+	s := NewScheduler(display, []Entry{textEntry(t, "0 18 * * fri", time.Hour, "Backup reminder")})
+	s.now = func() time.Time { return at(16, 17, 59).Add(59*time.Second + 950*time.Millisecond) }
+	s.Start()
+	defer s.Stop()
+
+	assert.Eventually(t, func() bool { return display.shown() == "Backup reminder" }, time.Second, 5*time.Millisecond)
+
+	s.Stop()
+	assert.True(t, display.isReleased(), "stopping dismisses the shown entry")
+}
@@ -0,0 +1,96 @@
+package screen
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// DefaultAbbreviations are the shortened forms of words common on status
+// screens. Keys are lower case.
+var DefaultAbbreviations = map[string]string{
+	"address":       "addr",
+	"available":     "avail",
+	"average":       "avg",
+	"capacity":      "cap",
+	"configuration": "config",
+	"frequency":     "freq",
+	"humidity":      "hum",
+	"information":   "info",
+	"interface":     "iface",
+	"maximum":       "max",
+	"memory":        "mem",
+	"minimum":       "min",
+	"network":       "net",
+	"pressure":      "press",
+	"remaining":     "rem",
+	"temperature":   "temp",
+	"utilization":   "util",
+}
+
+// interfaceName matches predictable network interface names with a PCI
+// location, e.g. enp3s0 or wlp2s0f1
+var interfaceName = regexp.MustCompile(`^(en|wl|ww)p(\d+s\d+(?:f\d+)?(?:d\d+)?)$`)
+
+// Abbreviator shortens words of text that is too long for a line, so more of
+// it survives truncation
+type Abbreviator struct {
+	words map[string]string
+}
+
+// NewAbbreviator creates an abbreviator using DefaultAbbreviations with the
+// given words added or replaced. A word mapped to itself is left alone.
+func NewAbbreviator(words map[string]string) *Abbreviator {
+	a := &Abbreviator{words: make(map[string]string, len(DefaultAbbreviations)+len(words))}
+	for word, short := range DefaultAbbreviations {
+		a.words[word] = short
+	}
+	for word, short := range words {
+		a.words[strings.ToLower(word)] = short
+	}
+	return a
+}
+
+// Fit abbreviates the words of text from left to right until it fits width.
+// Text that fits is returned unchanged; text that still does not fit is
+// returned with every known word abbreviated, for the caller to truncate.
+func (a *Abbreviator) Fit(text string, width int) string {
+	if TextWidth(text) <= width {
+		return text
+	}
+
+	words := strings.Split(text, " ")
+	length := TextWidth(text)
+	for i, word := range words {
+		short := a.abbreviate(word)
+		length -= TextWidth(word) - TextWidth(short)
+		words[i] = short
+		if length <= width {
+			break
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// abbreviate shortens a single word. Punctuation around it, such as the
+// menu's selection marker or a colon, is kept, and a capitalized word stays
+// capitalized.
+func (a *Abbreviator) abbreviate(word string) string {
+	if match := interfaceName.FindStringSubmatch(word); match != nil {
+		return match[1][:1] + match[2]
+	}
+
+	start := strings.IndexFunc(word, unicode.IsLetter)
+	if start < 0 {
+		return word
+	}
+	core := strings.TrimRightFunc(word[start:], func(r rune) bool { return !unicode.IsLetter(r) })
+	short, ok := a.words[strings.ToLower(core)]
+	if !ok || TextWidth(short) >= TextWidth(core) {
+		return word
+	}
+	if first := []rune(core)[0]; unicode.IsUpper(first) && short != "" {
+		short = strings.ToUpper(short[:1]) + short[1:]
+	}
+	return word[:start] + short + word[start+len(core):]
+}
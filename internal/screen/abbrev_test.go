@@ -0,0 +1,30 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbbreviator_Fit(t *testing.T) {
+	a := NewAbbreviator(nil)
+
+	assert.Equal(t, "Temperature 40C", a.Fit("Temperature 40C", 16), "text that fits is unchanged")
+	assert.Equal(t, "Temp: 40.5C hot", a.Fit("Temperature: 40.5C hot", 16))
+	assert.Equal(t, "Avail memory 3G", a.Fit("Available memory 3G", 16), "words are shortened only until the text fits")
+	assert.Equal(t, "Avail mem 1234567", a.Fit("Available memory 1234567", 16), "text that still overflows is left to the caller")
+	assert.Equal(t, "e3s0 10.0.0.2", a.Fit("enp3s0 10.0.0.2", 13))
+	assert.Equal(t, "w2s0f1 up", a.Fit("wlp2s0f1 up", 8))
+	assert.Equal(t, ">Temp alerts", a.Fit(">Temperature alerts", 16), "leading punctuation is kept")
+	assert.Equal(t, "eth0 10.0.0.20", a.Fit("eth0 10.0.0.20", 10), "short interface names are kept")
+}
+
+func TestAbbreviator_Custom(t *testing.T) {
+	a := NewAbbreviator(map[string]string{
+		"Volume":      "vol",
+		"temperature": "temperature",
+	})
+
+	assert.Equal(t, "Vol 1 62%", a.Fit("Volume 1 62%", 10))
+	assert.Equal(t, "temperature 40C", a.Fit("temperature 40C", 10), "a word mapped to itself is kept")
+}
@@ -0,0 +1,236 @@
+package screen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Animation draws the frames of an idle animation. Frame is called with a
+// cleared framebuffer and an ever increasing frame number, so an animation
+// can compute every frame from the number alone.
+type Animation interface {
+	Name() string
+	Frame(fb *Framebuffer, frame int)
+}
+
+// animations are the built-in idle animations. The text argument is shown
+// by animations that display text and ignored by the others.
+var animations = map[string]func(text string) Animation{
+	"snake":  func(string) Animation { return &Snake{Length: 4} },
+	"bounce": func(text string) Animation { return &BouncingText{Text: text} },
+}
+
+// AnimationNames returns the names of the built-in animations
+func AnimationNames() []string {
+	names := make([]string, 0, len(animations))
+	for name := range animations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAnimation creates a built-in animation by name
+func NewAnimation(name, text string) (Animation, error) {
+	factory, exists := animations[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown animation %q (available: %s)",
+			name, strings.Join(AnimationNames(), ", "))
+	}
+	return factory(text), nil
+}
+
+// Snake crawls across the display, left to right on even rows and right to
+// left on odd rows, wrapping from the last row back to the first
+type Snake struct {
+	Length int
+}
+
+// Name returns the animation name
+func (s *Snake) Name() string {
+	return "snake"
+}
+
+// Frame draws the snake with its head at the frame's position on the path
+func (s *Snake) Frame(fb *Framebuffer, frame int) {
+	pathLength := fb.Width() * fb.Height()
+	length := s.Length
+	if length < 1 {
+		length = 1
+	}
+	if length > pathLength {
+		length = pathLength
+	}
+
+	// Rows are composed first; SetText at column 0 would replace the whole line
+	rows := make([][]byte, fb.Height())
+	for row := range rows {
+		rows[row] = []byte(fb.Line(row))
+	}
+
+	head := frame % pathLength
+	for i := length - 1; i >= 0; i-- {
+		segment := byte('o')
+		if i == 0 {
+			segment = '@'
+		}
+		row, col := s.position(fb, (head-i+pathLength)%pathLength)
+		rows[row][col] = segment
+	}
+
+	for row, line := range rows {
+		fb.SetLine(row, string(line))
+	}
+}
+
+// position maps a step along the snake's path to a display cell
+func (s *Snake) position(fb *Framebuffer, step int) (int, int) {
+	row, col := step/fb.Width(), step%fb.Width()
+	if row%2 == 1 {
+		col = fb.Width() - 1 - col
+	}
+	return row, col
+}
+
+// BouncingText moves text back and forth across the display, changing rows
+// every time it hits an edge
+type BouncingText struct {
+	Text string
+}
+
+// Name returns the animation name
+func (b *BouncingText) Name() string {
+	return "bounce"
+}
+
+// Frame draws the text at the frame's position
+func (b *BouncingText) Frame(fb *Framebuffer, frame int) {
+	text := Truncate(b.Text, fb.Width())
+	if text == "" {
+		return
+	}
+
+	span := fb.Width() - TextWidth(text)
+	if span == 0 {
+		// Text as wide as the display only changes rows
+		fb.SetText((frame/4)%fb.Height(), 0, text)
+		return
+	}
+
+	col := frame % (2 * span)
+	if col > span {
+		col = 2*span - col
+	}
+	row := (frame / span) % fb.Height()
+	fb.SetText(row, col, text)
+}
+
+// FrameDelay returns the time between animation frames that a panel of the
+// given geometry can sustain at the given baud rate. A frame may rewrite every
+// line, and each line costs a four byte header plus its text at ten bits per
+// byte. Frames are never faster than four per second so the LCD stays readable.
+func FrameDelay(width, height, baud int) time.Duration {
+	if width <= 0 {
+		width = 16
+	}
+	if height <= 0 {
+		height = 2
+	}
+	if baud <= 0 {
+		baud = 1200
+	}
+
+	bits := (4 + width) * height * 10
+	delay := time.Duration(bits) * time.Second / time.Duration(baud)
+	if delay < 250*time.Millisecond {
+		delay = 250 * time.Millisecond
+	}
+	return delay
+}
+
+// Animator plays animations on a layer, one at a time
+type Animator struct {
+	layer      *Layer
+	frameDelay time.Duration
+	logger     *logrus.Entry
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewAnimator creates an animator drawing on the given layer
+func NewAnimator(layer *Layer, frameDelay time.Duration) *Animator {
+	return &Animator{
+		layer:      layer,
+		frameDelay: frameDelay,
+		logger:     logrus.WithField("component", "animator"),
+	}
+}
+
+// Play starts an animation, stopping the one currently playing
+func (a *Animator) Play(animation Animation) {
+	a.Stop()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
+	a.logger.WithField("animation", animation.Name()).Debug("Starting animation")
+	go a.run(ctx, animation, a.done)
+}
+
+// Stop stops the current animation and returns once no more frames will be
+// drawn. The layer keeps the last frame until it is released or redrawn.
+func (a *Animator) Stop() {
+	a.mutex.Lock()
+	cancel, done := a.cancel, a.done
+	a.cancel, a.done = nil, nil
+	a.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Playing reports whether an animation is playing
+func (a *Animator) Playing() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.cancel != nil
+}
+
+// run draws frames until the context is cancelled
+func (a *Animator) run(ctx context.Context, animation Animation, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(a.frameDelay)
+	defer ticker.Stop()
+
+	fb := NewFramebuffer(a.layer.manager.width, a.layer.manager.height)
+	for frame := 0; ctx.Err() == nil; frame++ {
+		fb.Clear()
+		animation.Frame(fb, frame)
+		if err := a.layer.Draw(fb); err != nil {
+			a.logger.WithError(err).Warn("Failed to draw animation frame")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package screen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnake(t *testing.T) {
+	snake := &Snake{Length: 3}
+	fb := NewFramebuffer(4, 2)
+
+	snake.Frame(fb, 0)
+	assert.Equal(t, []string{"@   ", "oo  "}, fb.Lines(), "tail wraps from the end of the path")
+
+	fb.Clear()
+	snake.Frame(fb, 4)
+	assert.Equal(t, []string{"  oo", "   @"}, fb.Lines(), "second row runs right to left")
+
+	fb.Clear()
+	snake.Frame(fb, 8)
+	assert.Equal(t, []string{"@   ", "oo  "}, fb.Lines(), "path loops")
+}
+
+func TestBouncingText(t *testing.T) {
+	bounce := &BouncingText{Text: "ab"}
+	fb := NewFramebuffer(4, 2)
+
+	var frames [][]string
+	for frame := 0; frame < 5; frame++ {
+		fb.Clear()
+		bounce.Frame(fb, frame)
+		frames = append(frames, fb.Lines())
+	}
+
+	assert.Equal(t, [][]string{
+		{"ab  ", "    "},
+		{" ab ", "    "},
+		{"    ", "  ab"},
+		{"    ", " ab "},
+		{"ab  ", "    "},
+	}, frames)
+
+	t.Run("Text wider than the display is cut off", func(t *testing.T) {
+		fb.Clear()
+		(&BouncingText{Text: "abcdef"}).Frame(fb, 0)
+		assert.Equal(t, []string{"abcd", "    "}, fb.Lines())
+	})
+}
+
+func TestNewAnimation(t *testing.T) {
+	assert.Equal(t, []string{"bounce", "snake"}, AnimationNames())
+
+	animation, err := NewAnimation("bounce", "nas01")
+	require.NoError(t, err)
+	assert.Equal(t, "nas01", animation.(*BouncingText).Text)
+
+	_, err = NewAnimation("fireworks", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "available: bounce, snake")
+}
+
+func TestFrameDelay(t *testing.T) {
+	// 2 lines of 20 bytes at 1200 baud take a third of a second
+	assert.Equal(t, 333333333*time.Nanosecond, FrameDelay(16, 2, 1200))
+	assert.Equal(t, 250*time.Millisecond, FrameDelay(16, 2, 115200), "fast links are capped")
+	assert.Equal(t, FrameDelay(16, 2, 1200), FrameDelay(0, 0, 0), "unset values use the defaults")
+}
+
+func TestAnimator(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	menu := sm.Layer(PriorityMenu)
+	require.NoError(t, menu.WriteText("Main Menu\n>System"))
+
+	animator := NewAnimator(sm.Layer(PriorityIdle), time.Millisecond)
+	require.NoError(t, menu.Release())
+	animator.Play(&Snake{Length: 2})
+	assert.True(t, animator.Playing())
+
+	assert.Eventually(t, func() bool {
+		return display.shown() != "|"
+	}, time.Second, time.Millisecond, "frames reach the panel")
+
+	animator.Stop()
+	assert.False(t, animator.Playing())
+
+	display.mutex.Lock()
+	writes := display.writes
+	display.mutex.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	display.mutex.Lock()
+	assert.Equal(t, writes, display.writes, "no frames are drawn after Stop")
+	display.mutex.Unlock()
+
+	t.Run("Higher layers cover the animation", func(t *testing.T) {
+		animator.Play(&BouncingText{Text: "nas"})
+		defer animator.Stop()
+
+		require.NoError(t, menu.WriteText("Main Menu\n>System"))
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, "Main Menu|>System", display.shown())
+	})
+}
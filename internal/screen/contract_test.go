@@ -0,0 +1,457 @@
+package screen
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this file check the concurrency contract in the package
+// documentation against a reference model: whatever the writers do and in
+// whatever order, the panel has to end up as the model says.
+
+const (
+	contractWidth  = 16
+	contractHeight = 2
+)
+
+// contractPriorities are the layers the tests write to: two regions sharing
+// the panel and full-screen layers above and below them
+var contractPriorities = []Priority{PriorityIdle, PriorityStatus, PriorityMenu, PriorityRemote, PriorityAlert}
+
+// contractRegions confines the status layer to the first row and the menu to
+// the second
+var contractRegions = map[Priority]region{
+	PriorityStatus: {row: 0, height: 1},
+	PriorityMenu:   {row: 1, height: 1},
+}
+
+// contractTexts are written by the operations, including too long lines,
+// more lines than a layer has and nothing at all
+var contractTexts = []string{
+	"",
+	"Main Menu\n>Network",
+	"ALERT\nFan stopped",
+	"IP 10.0.0.2",
+	"This line is much too long\nand so is this one here",
+	"a\nb\nc",
+	"42C",
+}
+
+type opKind int
+
+const (
+	opWriteText opKind = iota
+	opWriteTextAt
+	opClear
+	opRelease
+	opBadge
+	opKinds
+)
+
+// op is one call of a writer
+type op struct {
+	priority Priority
+	kind     opKind
+	text     string
+	row, col int
+}
+
+func (o op) String() string {
+	switch o.kind {
+	case opWriteText:
+		return fmt.Sprintf("%s.WriteText(%q)", o.priority, o.text)
+	case opWriteTextAt:
+		return fmt.Sprintf("%s.WriteTextAt(%q, %d, %d)", o.priority, o.text, o.row, o.col)
+	case opClear:
+		return fmt.Sprintf("%s.ClearDisplay()", o.priority)
+	case opRelease:
+		return fmt.Sprintf("%s.Release()", o.priority)
+	default:
+		return fmt.Sprintf("SetBadge(%q)", o.text)
+	}
+}
+
+// decodeOps turns bytes into operations, four bytes each, so the fuzzer can
+// explore the interleavings
+func decodeOps(data []byte) []op {
+	var ops []op
+	for ; len(data) >= 4; data = data[4:] {
+		o := op{
+			priority: contractPriorities[int(data[0])%len(contractPriorities)],
+			kind:     opKind(int(data[1]) % int(opKinds)),
+			text:     contractTexts[int(data[3])%len(contractTexts)],
+			// Rows and columns reach just past the panel, to cover rejected
+			// writes too
+			row: int(data[2]) % (contractHeight + 1),
+			col: int(data[2]) / (contractHeight + 1) % (contractWidth + 2),
+		}
+		if o.kind == opBadge {
+			o.text = []string{"", "W", "M"}[int(data[3])%3]
+		}
+		ops = append(ops, o)
+	}
+	return ops
+}
+
+// randomOps returns n random operations of one layer, or of the badge
+func randomOps(random *rand.Rand, priority Priority, badge bool, n int) []op {
+	ops := make([]op, n)
+	for i := range ops {
+		data := make([]byte, 4)
+		random.Read(data)
+		ops[i] = decodeOps(data)[0]
+		ops[i].priority = priority
+		if badge {
+			ops[i].kind = opBadge
+			ops[i].text = []string{"", "W", "M"}[random.Intn(3)]
+		} else if ops[i].kind == opBadge {
+			ops[i].kind = opWriteText
+		}
+	}
+	return ops
+}
+
+// run applies the operation to the screen manager
+func (o op) run(sm *ScreenManager) error {
+	layer := sm.Layer(o.priority)
+	switch o.kind {
+	case opWriteText:
+		return layer.WriteText(o.text)
+	case opWriteTextAt:
+		return layer.WriteTextAt(o.text, o.row, o.col)
+	case opClear:
+		return layer.ClearDisplay()
+	case opRelease:
+		return layer.Release()
+	default:
+		return sm.SetBadge(o.text)
+	}
+}
+
+// modelLayer is the reference model of a layer
+type modelLayer struct {
+	row     int
+	lines   []string
+	claimed bool
+}
+
+// model is the reference model of the screen manager, written from the
+// package documentation rather than the implementation
+type model struct {
+	layers map[Priority]*modelLayer
+	badge  string
+}
+
+func newModel() *model {
+	m := &model{layers: make(map[Priority]*modelLayer)}
+	for _, priority := range contractPriorities {
+		area, ok := contractRegions[priority]
+		if !ok {
+			area = region{row: 0, height: contractHeight}
+		}
+		m.layers[priority] = &modelLayer{row: area.row, lines: make([]string, area.height)}
+		m.layers[priority].blank()
+	}
+	return m
+}
+
+func (l *modelLayer) blank() {
+	for i := range l.lines {
+		l.lines[i] = strings.Repeat(" ", contractWidth)
+	}
+}
+
+// pad cuts or pads a line to the panel's width
+func pad(line string) string {
+	if len(line) > contractWidth {
+		return line[:contractWidth]
+	}
+	return line + strings.Repeat(" ", contractWidth-len(line))
+}
+
+// apply applies the operation to the model
+func (m *model) apply(o op) {
+	layer := m.layers[o.priority]
+	switch o.kind {
+	case opWriteText:
+		lines := strings.Split(o.text, "\n")
+		for i := range layer.lines {
+			line := ""
+			if i < len(lines) {
+				line = lines[i]
+			}
+			layer.lines[i] = pad(line)
+		}
+		layer.claimed = true
+	case opWriteTextAt:
+		if o.row >= len(layer.lines) || o.col >= contractWidth {
+			return
+		}
+		if o.col == 0 {
+			layer.lines[o.row] = pad(o.text)
+		} else {
+			line := layer.lines[o.row][:o.col] + o.text
+			if len(line) < contractWidth {
+				line += layer.lines[o.row][len(line):]
+			}
+			layer.lines[o.row] = line[:contractWidth]
+		}
+		layer.claimed = true
+	case opClear:
+		layer.blank()
+		layer.claimed = true
+	case opRelease:
+		layer.blank()
+		layer.claimed = false
+	default:
+		m.badge = o.text
+	}
+}
+
+// owner returns the layer shown on a row, if any
+func (m *model) owner(row int) (Priority, bool) {
+	var owner Priority
+	found := false
+	for priority, layer := range m.layers {
+		if !layer.claimed || row < layer.row || row >= layer.row+len(layer.lines) {
+			continue
+		}
+		if !found || priority > owner {
+			owner, found = priority, true
+		}
+	}
+	return owner, found
+}
+
+// frame returns the lines the panel should show
+func (m *model) frame() []string {
+	lines := make([]string, contractHeight)
+	for row := range lines {
+		lines[row] = strings.Repeat(" ", contractWidth)
+		if owner, ok := m.owner(row); ok {
+			layer := m.layers[owner]
+			lines[row] = layer.lines[row-layer.row]
+		}
+	}
+	if m.badge != "" {
+		lines[0] = lines[0][:contractWidth-1] + m.badge
+	}
+	return lines
+}
+
+// newContractManager creates a screen manager with the regions of the model
+// and a blank panel
+func newContractManager(t testing.TB, display Display) *ScreenManager {
+	sm := NewScreenManager(display, contractWidth, contractHeight)
+	for priority, area := range contractRegions {
+		require.NoError(t, sm.SetRegion(priority, area.row, area.height))
+	}
+	// The panel shows whatever it showed before until the first render
+	require.NoError(t, sm.Redraw())
+	return sm
+}
+
+// assertMatchesModel checks the screen manager and the panel against the
+// model
+func assertMatchesModel(t testing.TB, sm *ScreenManager, display *recordingDisplay, m *model, msgAndArgs ...interface{}) {
+	want := m.frame()
+	assert.Equal(t, want, sm.Snapshot(), msgAndArgs...)
+	display.mutex.Lock()
+	assert.Equal(t, want, display.lines, msgAndArgs...)
+	display.mutex.Unlock()
+
+	for _, priority := range contractPriorities {
+		layer := sm.Layer(priority)
+		assert.Equal(t, m.layers[priority].claimed, layer.Claimed(), "%s claimed", priority)
+		visible := false
+		for row := 0; row < contractHeight; row++ {
+			if owner, ok := m.owner(row); ok && owner == priority {
+				visible = true
+			}
+		}
+		assert.Equal(t, visible, layer.IsVisible(), "%s visible", priority)
+	}
+
+	var top Priority
+	claimed := false
+	for priority, layer := range m.layers {
+		if layer.claimed && (!claimed || priority > top) {
+			top, claimed = priority, true
+		}
+	}
+	active, ok := sm.Active()
+	assert.Equal(t, claimed, ok, "a layer is active")
+	if claimed {
+		assert.Equal(t, top, active, "active layer")
+	}
+}
+
+func TestContract_ConcurrentWriters(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			random := rand.New(rand.NewSource(seed))
+			display := newRecordingDisplay()
+			sm := newContractManager(t, display)
+
+			// One writer per layer and one switching the badge. Calls of
+			// different writers commute in the model, so the model only
+			// needs each writer's own order.
+			var writers [][]op
+			for _, priority := range contractPriorities {
+				writers = append(writers, randomOps(random, priority, false, 200))
+			}
+			writers = append(writers, randomOps(random, PriorityIdle, true, 50))
+
+			var wg sync.WaitGroup
+			for _, ops := range writers {
+				wg.Add(1)
+				go func(ops []op) {
+					defer wg.Done()
+					for _, o := range ops {
+						_ = o.run(sm)
+					}
+				}(ops)
+			}
+			wg.Wait()
+
+			m := newModel()
+			for _, ops := range writers {
+				for _, o := range ops {
+					m.apply(o)
+				}
+			}
+			assertMatchesModel(t, sm, display, m)
+		})
+	}
+}
+
+func TestContract_SameLayerLastCallWins(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := newContractManager(t, display)
+	remote := sm.Layer(PriorityRemote)
+
+	// Concurrent calls on one layer are applied whole: whichever comes last,
+	// the panel shows both of its lines and nothing of the others
+	texts := []string{"one\n1", "two\n2", "three\n3", "four\n4"}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		for _, text := range texts {
+			wg.Add(1)
+			go func(text string) {
+				defer wg.Done()
+				assert.NoError(t, remote.WriteText(text))
+			}(text)
+		}
+	}
+	wg.Wait()
+
+	lines := sm.Snapshot()
+	assert.Contains(t, texts, strings.TrimRight(lines[0], " ")+"\n"+strings.TrimRight(lines[1], " "))
+}
+
+func TestContract_NoTornFrames(t *testing.T) {
+	display := &batchDisplay{recordingDisplay: newRecordingDisplay()}
+	sm := NewScreenManager(display, contractWidth, contractHeight)
+
+	// Every writer puts its own token on both lines, so a frame mixing two
+	// tokens was torn
+	var frames [][]string
+	sm.SetFrameHandler(func(lines []string) {
+		frames = append(frames, lines)
+	})
+
+	var wg sync.WaitGroup
+	for _, priority := range []Priority{PriorityMenu, PriorityRemote, PriorityCopy, PriorityAlert} {
+		wg.Add(1)
+		go func(layer *Layer) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				token := fmt.Sprintf("%s %d", layer.Priority(), i)
+				assert.NoError(t, layer.WriteText(token+"\n"+token))
+				if i%10 == 9 {
+					assert.NoError(t, layer.Release())
+				}
+			}
+		}(sm.Layer(priority))
+	}
+	wg.Wait()
+
+	require.NotEmpty(t, frames)
+	for _, frame := range frames {
+		assert.Equal(t, frame[0], frame[1], "torn frame")
+	}
+}
+
+func TestContract_PreemptedWritesAreNotSent(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := newContractManager(t, display)
+	alert := sm.Layer(PriorityAlert)
+	require.NoError(t, alert.WriteText("ALERT\nFan stopped"))
+
+	writes := display.writes
+	var wg sync.WaitGroup
+	for _, priority := range []Priority{PriorityIdle, PriorityStatus, PriorityMenu, PriorityRemote} {
+		wg.Add(1)
+		go func(layer *Layer) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, layer.WriteText(fmt.Sprintf("%s %d", layer.Priority(), i)))
+				assert.False(t, layer.IsVisible())
+			}
+		}(sm.Layer(priority))
+	}
+	wg.Wait()
+	assert.Equal(t, writes, display.writes, "preempted writes reach the panel")
+
+	// Releasing the alert restores the layers as they last drew themselves
+	require.NoError(t, alert.Release())
+	assert.Equal(t, "remote 49|", display.shown())
+	require.NoError(t, sm.Layer(PriorityRemote).Release())
+	assert.Equal(t, "status 49|menu 49", display.shown())
+}
+
+func TestContract_FailedRenderCatchesUp(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := newContractManager(t, display)
+	m := newModel()
+
+	display.writeErr = fmt.Errorf("serial link down")
+	for _, o := range []op{
+		{priority: PriorityMenu, kind: opWriteText, text: ">Network"},
+		{priority: PriorityStatus, kind: opWriteText, text: "IP 10.0.0.2"},
+		{priority: PriorityAlert, kind: opWriteText, text: "ALERT\nFan stopped"},
+		{priority: PriorityAlert, kind: opRelease},
+	} {
+		assert.Error(t, o.run(sm), o.String())
+		m.apply(o)
+	}
+
+	display.writeErr = nil
+	require.NoError(t, sm.Redraw())
+	assertMatchesModel(t, sm, display, m)
+}
+
+func FuzzInterleavings(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{1, 0, 0, 3, 2, 0, 0, 1, 4, 0, 0, 2, 4, 3, 0, 0, 2, 4, 1, 1})
+	f.Add([]byte{3, 1, 5, 6, 3, 1, 4, 1, 0, 4, 0, 1, 3, 3, 0, 0, 1, 2, 0, 4})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		display := newRecordingDisplay()
+		sm := newContractManager(t, display)
+		m := newModel()
+		for i, o := range decodeOps(data) {
+			_ = o.run(sm)
+			m.apply(o)
+			assertMatchesModel(t, sm, display, m, "after %d: %s", i, o)
+			if t.Failed() {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package screen
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Framebuffer holds the intended contents of the character display.
+// Every line is kept padded to the display width. Widths count characters,
+// not bytes, so UTF-8 text takes one cell per character; the display
+// controller transliterates what the panel cannot show.
+type Framebuffer struct {
+	width  int
+	height int
+	lines  []string
+}
+
+// NewFramebuffer creates a blank framebuffer of the given geometry
+func NewFramebuffer(width, height int) *Framebuffer {
+	fb := &Framebuffer{
+		width:  width,
+		height: height,
+		lines:  make([]string, height),
+	}
+	fb.Clear()
+	return fb
+}
+
+// Width returns the number of characters per line
+func (fb *Framebuffer) Width() int {
+	return fb.width
+}
+
+// Height returns the number of lines
+func (fb *Framebuffer) Height() int {
+	return fb.height
+}
+
+// Clear blanks every line
+func (fb *Framebuffer) Clear() {
+	for i := range fb.lines {
+		fb.lines[i] = strings.Repeat(" ", fb.width)
+	}
+}
+
+// SetLine replaces a whole line, truncating or padding it to the display width
+func (fb *Framebuffer) SetLine(row int, text string) error {
+	return fb.SetText(row, 0, text)
+}
+
+// SetText writes text into a line starting at the given column, leaving the
+// rest of the line untouched. Text running past the end of the line is cut off.
+func (fb *Framebuffer) SetText(row, col int, text string) error {
+	if row < 0 || row >= fb.height {
+		return fmt.Errorf("invalid row: %d. Must be between 0 and %d", row, fb.height-1)
+	}
+	if col < 0 || col >= fb.width {
+		return fmt.Errorf("invalid column: %d. Must be between 0 and %d", col, fb.width-1)
+	}
+
+	if col == 0 {
+		// Whole-line writes replace the line, matching WriteTextAt semantics
+		fb.lines[row] = fitLine(text, fb.width)
+		return nil
+	}
+
+	line := []rune(fb.lines[row])
+	copy(line[col:], []rune(text))
+	fb.lines[row] = string(line)
+	return nil
+}
+
+// Line returns the padded contents of a line
+func (fb *Framebuffer) Line(row int) string {
+	if row < 0 || row >= fb.height {
+		return ""
+	}
+	return fb.lines[row]
+}
+
+// Lines returns a copy of all lines
+func (fb *Framebuffer) Lines() []string {
+	lines := make([]string, len(fb.lines))
+	copy(lines, fb.lines)
+	return lines
+}
+
+// Clone returns an independent copy of the framebuffer
+func (fb *Framebuffer) Clone() *Framebuffer {
+	return &Framebuffer{
+		width:  fb.width,
+		height: fb.height,
+		lines:  fb.Lines(),
+	}
+}
+
+// String renders the framebuffer as newline separated lines
+func (fb *Framebuffer) String() string {
+	return strings.Join(fb.lines, "\n")
+}
+
+// TextWidth returns how many cells text takes on the panel, one per
+// character
+func TextWidth(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// Truncate cuts text to at most width characters, never inside one
+func Truncate(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	count := 0
+	for i := range text {
+		if count == width {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
+
+// fitLine truncates or pads text to exactly width characters
+func fitLine(text string, width int) string {
+	text = Truncate(text, width)
+	return text + strings.Repeat(" ", width-TextWidth(text))
+}
@@ -0,0 +1,77 @@
+package screen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mirrorGlyph stands in for custom glyphs and other control characters in
+// mirrored frames
+const mirrorGlyph = '*'
+
+// Mirror copies the frames sent to the panel to the debug log and,
+// optionally, a writer, so what a remote panel shows can be followed without
+// looking at it. Its Frame method is a FrameHandler.
+type Mirror struct {
+	out    io.Writer
+	now    func() time.Time
+	logger *logrus.Entry
+	mutex  sync.Mutex
+}
+
+// NewMirror creates a mirror that also appends every frame to out (nil =
+// the debug log only)
+func NewMirror(out io.Writer) *Mirror {
+	return &Mirror{
+		out:    out,
+		now:    time.Now,
+		logger: logrus.WithField("component", "display_mirror"),
+	}
+}
+
+// Frame mirrors the lines of one frame
+func (m *Mirror) Frame(lines []string) {
+	framed := FrameLines(lines)
+	m.logger.Debug("Display frame " + strings.Join(framed, " "))
+	if m.out == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// The lines below the first are indented under it, after the timestamp
+	stamp := m.now().Format("2006-01-02 15:04:05")
+	indent := strings.Repeat(" ", len(stamp))
+	var text strings.Builder
+	for i, line := range framed {
+		prefix := indent
+		if i == 0 {
+			prefix = stamp
+		}
+		fmt.Fprintf(&text, "%s %s\n", prefix, line)
+	}
+	if _, err := io.WriteString(m.out, text.String()); err != nil {
+		m.logger.WithError(err).Warn("Failed to write display frame")
+	}
+}
+
+// FrameLines brackets each line of a frame, e.g. "[QNAP Starting   ]", with
+// custom glyphs shown as '*'
+func FrameLines(lines []string) []string {
+	framed := make([]string, len(lines))
+	for i, line := range lines {
+		framed[i] = "[" + strings.Map(func(r rune) rune {
+			if r < ' ' {
+				return mirrorGlyph
+			}
+			return r
+		}, line) + "]"
+	}
+	return framed
+}
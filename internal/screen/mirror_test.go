@@ -0,0 +1,35 @@
+package screen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameLines(t *testing.T) {
+	assert.Equal(t, []string{"[QNAP Starting   ]", "[\x7f* Disks       ]"},
+		FrameLines([]string{"QNAP Starting   ", "\x7f\x01 Disks       "}))
+}
+
+func TestMirror(t *testing.T) {
+	var out bytes.Buffer
+	mirror := NewMirror(&out)
+	mirror.now = func() time.Time { return time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC) }
+
+	sm := NewScreenManager(newRecordingDisplay(), 16, 2)
+	sm.SetFrameHandler(mirror.Frame)
+	menu := sm.Layer(PriorityMenu)
+	require.NoError(t, menu.WriteText("Main Menu\n>System Info"))
+	require.NoError(t, menu.WriteText("Main Menu\n>System Info"))
+	require.NoError(t, menu.WriteText("Main Menu\n>Network"))
+
+	assert.Equal(t, ""+
+		"2026-10-17 09:30:00 [Main Menu       ]\n"+
+		"                    [>System Info    ]\n"+
+		"2026-10-17 09:30:00 [Main Menu       ]\n"+
+		"                    [>Network        ]\n",
+		out.String(), "frames the panel already shows are not mirrored again")
+}
@@ -0,0 +1,118 @@
+package screen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pager splits long text into display-sized pages as an alternative to
+// horizontal scrolling. All lines but the last show text; the last line shows
+// a "Page 1/3" indicator. A single line display has no room for it and shows
+// one line of text per page.
+type Pager struct {
+	pages  [][]string
+	page   int
+	width  int
+	height int
+}
+
+// NewPager word-wraps text for a display of the given geometry and splits it
+// into pages. Blank lines are dropped and words longer than a line are broken.
+func NewPager(text string, width, height int) *Pager {
+	if width <= 0 {
+		width = 16
+	}
+	if height <= 0 {
+		height = 2
+	}
+
+	linesPerPage := height - 1
+	if linesPerPage < 1 {
+		linesPerPage = 1
+	}
+
+	var wrapped []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		line = strings.TrimSpace(strings.ReplaceAll(line, "\t", " "))
+		if line == "" {
+			continue
+		}
+		wrapped = append(wrapped, wrapLine(line, width)...)
+	}
+
+	p := &Pager{width: width, height: height}
+	for start := 0; start < len(wrapped); start += linesPerPage {
+		end := start + linesPerPage
+		if end > len(wrapped) {
+			end = len(wrapped)
+		}
+		p.pages = append(p.pages, wrapped[start:end])
+	}
+	if len(p.pages) == 0 {
+		p.pages = [][]string{{""}}
+	}
+
+	return p
+}
+
+// PageCount returns the number of pages
+func (p *Pager) PageCount() int {
+	return len(p.pages)
+}
+
+// Page returns the zero-based index of the current page
+func (p *Pager) Page() int {
+	return p.page
+}
+
+// Next advances to the next page, wrapping around after the last one
+func (p *Pager) Next() {
+	p.page = (p.page + 1) % len(p.pages)
+}
+
+// Render returns the current page as newline separated display lines
+func (p *Pager) Render() string {
+	if p.height == 1 {
+		return p.pages[p.page][0]
+	}
+	lines := make([]string, 0, p.height)
+	lines = append(lines, p.pages[p.page]...)
+	for len(lines) < p.height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, fmt.Sprintf("Page %d/%d", p.page+1, len(p.pages)))
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine breaks a line at word boundaries so no piece exceeds width
+func wrapLine(line string, width int) []string {
+	var result []string
+	current := ""
+
+	for _, word := range strings.Fields(line) {
+		for TextWidth(word) > width {
+			if current != "" {
+				result = append(result, current)
+				current = ""
+			}
+			piece := Truncate(word, width)
+			result = append(result, piece)
+			word = word[len(piece):]
+		}
+
+		switch {
+		case current == "":
+			current = word
+		case TextWidth(current)+1+TextWidth(word) <= width:
+			current += " " + word
+		default:
+			result = append(result, current)
+			current = word
+		}
+	}
+
+	if current != "" {
+		result = append(result, current)
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+package screen
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StatusItem is one entry of a status rotation. Text is called each time the
+// item comes up, so it always shows current values.
+type StatusItem struct {
+	Name string
+	Text func() (string, error)
+	// Stale reports whether Text shows data its provider should have
+	// refreshed by now; the text then ends in StaleMark. nil = never stale.
+	Stale func() bool
+	// Dwell is how long the item stays up (0 = the rotation's interval)
+	Dwell time.Duration
+}
+
+// StaleMark ends the text of a stale status item, so outdated numbers are
+// not taken for current ones
+const StaleMark = "?"
+
+// Render returns the item's text shortened by abbrev to width, ending in
+// StaleMark if it is stale
+func (i StatusItem) Render(abbrev *Abbreviator, width int) (string, error) {
+	text, err := i.Text()
+	if err != nil {
+		return "", err
+	}
+	if i.Stale != nil && i.Stale() {
+		return abbrev.Fit(text, width-len(StaleMark)) + StaleMark, nil
+	}
+	return abbrev.Fit(text, width), nil
+}
+
+// StatusRotation shows status items on a layer one after another
+type StatusRotation struct {
+	layer    *Layer
+	items    []StatusItem
+	interval time.Duration
+	abbrev   *Abbreviator
+	logger   *logrus.Entry
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewStatusRotation creates a rotation showing each item for its dwell time,
+// or for interval if it has none. Texts wider than the layer are shortened
+// with the default abbreviations.
+func NewStatusRotation(layer *Layer, items []StatusItem, interval time.Duration) *StatusRotation {
+	return &StatusRotation{
+		layer:    layer,
+		items:    items,
+		interval: interval,
+		abbrev:   NewAbbreviator(nil),
+		logger:   logrus.WithField("component", "status_rotation"),
+	}
+}
+
+// SetAbbreviator replaces the abbreviations used for texts wider than the
+// layer. Call it before Start.
+func (r *StatusRotation) SetAbbreviator(abbrev *Abbreviator) {
+	r.abbrev = abbrev
+}
+
+// Start shows the first item and moves on once it was shown for its dwell
+// time. Starting a running rotation restarts it.
+func (r *StatusRotation) Start() {
+	r.Stop()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.items) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx, r.done)
+}
+
+// Stop ends the rotation and releases its layer, leaving the rows to the
+// layers below
+func (r *StatusRotation) Stop() {
+	r.mutex.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	if err := r.layer.Release(); err != nil {
+		r.logger.WithError(err).Warn("Failed to release status layer")
+	}
+}
+
+// run shows items until the context is cancelled. Items that fail are
+// skipped; if all fail the last text stays up.
+func (r *StatusRotation) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(r.interval)
+	defer timer.Stop()
+
+	width, _ := r.layer.Size()
+	next := 0
+	for {
+		dwell := r.interval
+		for tries := 0; tries < len(r.items); tries++ {
+			item := r.items[next]
+			next = (next + 1) % len(r.items)
+
+			text, err := item.Render(r.abbrev, width)
+			if err != nil {
+				r.logger.WithError(err).WithField("item", item.Name).Debug("Skipping status item")
+				continue
+			}
+			if err := r.layer.WriteText(text); err != nil {
+				r.logger.WithError(err).Warn("Failed to show status item")
+			}
+			if item.Dwell > 0 {
+				dwell = item.Dwell
+			}
+			break
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(dwell)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package screen
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRotation(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	require.NoError(t, sm.SetRegion(PriorityStatus, 0, 1))
+	require.NoError(t, sm.SetRegion(PriorityMenu, 1, 1))
+	idle := sm.Layer(PriorityIdle)
+	require.NoError(t, idle.WriteText("QNAP Ready"))
+	menu := sm.Layer(PriorityMenu)
+	require.NoError(t, menu.WriteText(">System Info"))
+
+	fixed := func(text string) func() (string, error) {
+		return func() (string, error) { return text, nil }
+	}
+	rotation := NewStatusRotation(sm.Layer(PriorityStatus), []StatusItem{
+		{Name: "host", Text: fixed("nas01")},
+		{Name: "broken", Text: func() (string, error) { return "", errors.New("no data") }},
+		{Name: "ip", Text: fixed("IP 10.0.0.2")},
+	}, 20*time.Millisecond)
+
+	rotation.Start()
+	require.Eventually(t, func() bool { return display.shown() == "nas01|>System Info" },
+		time.Second, time.Millisecond, "first item shows above the menu row")
+	require.Eventually(t, func() bool { return display.shown() == "IP 10.0.0.2|>System Info" },
+		time.Second, time.Millisecond, "failing items are skipped")
+	require.Eventually(t, func() bool { return display.shown() == "nas01|>System Info" },
+		time.Second, time.Millisecond, "rotation wraps around")
+
+	rotation.Stop()
+	assert.Equal(t, "QNAP Ready|>System Info", display.shown(), "stopping hands the row back to the layer below")
+
+	rotation.Stop()
+	rotation.Start()
+	require.Eventually(t, func() bool { return display.shown() == "nas01|>System Info" },
+		time.Second, time.Millisecond, "a stopped rotation can start again")
+	rotation.Stop()
+}
+
+func TestStatusRotation_Dwell(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	require.NoError(t, sm.SetRegion(PriorityStatus, 0, 1))
+
+	fixed := func(text string) func() (string, error) {
+		return func() (string, error) { return text, nil }
+	}
+	rotation := NewStatusRotation(sm.Layer(PriorityStatus), []StatusItem{
+		{Name: "time", Text: fixed("12:00"), Dwell: 20 * time.Millisecond},
+		{Name: "alerts", Text: fixed("No alerts")},
+	}, time.Hour)
+
+	rotation.Start()
+	defer rotation.Stop()
+	require.Eventually(t, func() bool { return display.shown() == "No alerts|" },
+		time.Second, time.Millisecond, "an item moves on after its own dwell time")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "No alerts|", display.shown(), "an item without one stays for the interval")
+}
+
+func TestStatusRotation_Abbreviates(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	require.NoError(t, sm.SetRegion(PriorityStatus, 0, 1))
+
+	rotation := NewStatusRotation(sm.Layer(PriorityStatus), []StatusItem{
+		{Name: "sensor", Text: func() (string, error) { return "Rack humidity 45%", nil }},
+	}, time.Hour)
+	rotation.SetAbbreviator(NewAbbreviator(map[string]string{"rack": "rk"}))
+
+	rotation.Start()
+	defer rotation.Stop()
+	require.Eventually(t, func() bool { return display.shown() == "Rk humidity 45%|" },
+		time.Second, time.Millisecond, "words are shortened from the left until the text fits")
+}
+
+func TestStatusRotation_MarksStale(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	require.NoError(t, sm.SetRegion(PriorityStatus, 0, 1))
+
+	rotation := NewStatusRotation(sm.Layer(PriorityStatus), []StatusItem{
+		{
+			Name:  "sensor",
+			Text:  func() (string, error) { return "Rack humidity 45%", nil },
+			Stale: func() bool { return true },
+		},
+	}, time.Hour)
+	rotation.SetAbbreviator(NewAbbreviator(map[string]string{"rack": "rk"}))
+
+	rotation.Start()
+	defer rotation.Stop()
+	require.Eventually(t, func() bool { return display.shown() == "Rk humidity 45%?|" },
+		time.Second, time.Millisecond, "the mark stays visible after shortening")
+}
@@ -0,0 +1,609 @@
+// Package screen arbitrates access to the LCD between the subsystems that
+// want to show something on it.
+//
+// Every writer draws into its own Layer, identified by a Priority. The
+// ScreenManager always shows the highest priority layer that currently holds
+// content:
+//
+//	alert > confirmation > splash > copy > remote > scheduled > lcdproc > menu > status > idle
+//
+// Writing to a layer claims it. If a higher priority layer is already shown,
+// the write is kept in the layer's framebuffer but not sent to the panel
+// (the writer is preempted). Releasing a layer dismisses it and restores the
+// next highest claimed layer exactly as that layer last drew itself.
+//
+// A layer can be confined to a region, a band of rows, with SetRegion. Each
+// row of the panel shows the highest priority claimed layer covering it, so
+// two region layers (say the status rotation on the first line and the menu
+// on the second) are drawn side by side, while a full-screen layer above
+// them, such as a confirmation, still covers both.
+//
+// # Concurrency contract
+//
+// The ScreenManager and its layers are safe for concurrent use, and writers
+// need not coordinate with each other:
+//
+//   - Each layer belongs to one writer. Calls on a layer are applied whole
+//     and one at a time, so concurrent calls on the same layer leave it as
+//     the last of them drew it.
+//   - A write is never refused because another layer is shown. It returns
+//     once the panel shows the result, or once it is clear that the panel
+//     does not need to change because the layer is covered.
+//   - A preempted write sends nothing to the panel. The layer keeps what it
+//     drew and is shown as it last drew itself once the layers above it are
+//     released.
+//   - Whatever the interleaving of writes, releases, region and badge
+//     changes, once the calls have returned the panel shows on each row the
+//     highest priority claimed layer covering it, with the badge on top.
+//   - On a BatchDisplay the lines of a frame go out in one write, so the
+//     panel never shows half of one layer's frame and half of another's.
+//   - A render that fails leaves the panel behind; the next render or
+//     Redraw catches up with what the layers hold.
+//
+// Switch and frame handlers run while the manager is locked and must not
+// call back into it.
+package screen
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Priority orders the layers competing for the display. Higher values win.
+type Priority int
+
+const (
+	// PriorityIdle is used for idle screens shown when nothing else is active
+	PriorityIdle Priority = iota
+	// PriorityStatus is used for rotating status information
+	PriorityStatus
+	// PriorityMenu is used by the interactive menu system
+	PriorityMenu
+	// PriorityLCDproc is used for screens of lcdproc clients
+	PriorityLCDproc
+	// PriorityScheduled is used for screens shown at scheduled times
+	PriorityScheduled
+	// PriorityRemote is used for screens pushed by other services over the API
+	PriorityRemote
+	// PriorityCopy is used while a USB copy operation is running
+	PriorityCopy
+	// PrioritySplash is used for the splash shown at startup, see ShowSplash
+	PrioritySplash
+	// PriorityConfirmation is used for questions awaiting a button press
+	PriorityConfirmation
+	// PriorityAlert is used for alerts that must be seen immediately
+	PriorityAlert
+)
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityIdle:
+		return "idle"
+	case PriorityStatus:
+		return "status"
+	case PriorityMenu:
+		return "menu"
+	case PriorityLCDproc:
+		return "lcdproc"
+	case PriorityScheduled:
+		return "scheduled"
+	case PriorityRemote:
+		return "remote"
+	case PriorityCopy:
+		return "copy"
+	case PrioritySplash:
+		return "splash"
+	case PriorityConfirmation:
+		return "confirmation"
+	case PriorityAlert:
+		return "alert"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// Display is the panel the ScreenManager renders to
+type Display interface {
+	WriteTextAt(text string, row, col int) error
+	SetBacklight(on bool) error
+}
+
+// BatchDisplay is a Display that can replace several lines in one write.
+// The ScreenManager uses it so a frame never reaches the panel half drawn.
+type BatchDisplay interface {
+	Display
+	WriteLines(lines map[int]string) error
+}
+
+// ScreenManager decides which layer is visible and renders it to the display
+type ScreenManager struct {
+	display Display
+	width   int
+	height  int
+	layers  map[Priority]*Layer
+	regions map[Priority]region
+	shown   *Framebuffer
+	active  *Layer
+	// owners is the layer each panel row was last drawn from (nil = blank)
+	owners []*Layer
+	// onSwitch is told when another layer becomes visible (nil = no one)
+	onSwitch SwitchHandler
+	// onFrame is told every frame sent to the panel (nil = no one)
+	onFrame FrameHandler
+	// concealed reports whether the panel shows a secret being entered
+	// (nil = never); published is the last frame shown before
+	concealed func() bool
+	published []string
+	// badge is drawn over the top right cell of every frame ("" = none)
+	badge string
+	// splash is the startup splash being shown, see ShowSplash
+	splash splashState
+	mutex  sync.Mutex
+	logger *logrus.Entry
+}
+
+// SwitchHandler is told when the visible layer changes, with the names of
+// the layers ("none" when no layer is claimed). It runs while the screen
+// manager is locked and must not use it.
+type SwitchHandler func(from, to string)
+
+// FrameHandler is told the lines of every frame sent to the panel, once the
+// panel accepted it. It runs while the screen manager is locked and must not
+// use it.
+type FrameHandler func(lines []string)
+
+// region is the band of panel rows a layer is confined to
+type region struct {
+	row    int
+	height int
+}
+
+// NewScreenManager creates a screen manager for a display of the given geometry
+func NewScreenManager(display Display, width, height int) *ScreenManager {
+	if width <= 0 {
+		width = 16
+	}
+	if height <= 0 {
+		height = 2
+	}
+
+	sm := &ScreenManager{
+		display: display,
+		width:   width,
+		height:  height,
+		layers:  make(map[Priority]*Layer),
+		regions: make(map[Priority]region),
+		shown:   NewFramebuffer(width, height),
+		owners:  make([]*Layer, height),
+		logger:  logrus.WithField("component", "screen_manager"),
+	}
+
+	// The panel contents are unknown until the first render
+	sm.invalidate()
+	return sm
+}
+
+// invalidate forgets what the panel shows so the next render rewrites every
+// line. Caller must hold the mutex (or own the manager exclusively).
+func (sm *ScreenManager) invalidate() {
+	for i := range sm.shown.lines {
+		sm.shown.lines[i] = ""
+	}
+}
+
+// Redraw rewrites every line of the visible layer, for when the panel may have
+// lost its contents (e.g. after the serial link recovered)
+func (sm *ScreenManager) Redraw() error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.invalidate()
+	return sm.render()
+}
+
+// RedrawEvery calls Redraw every interval until the returned function is
+// called, so junk another process wrote to the panel does not stay there
+// until the next change. Stopping waits for a redraw in progress.
+func (sm *ScreenManager) RedrawEvery(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := sm.Redraw(); err != nil {
+					sm.logger.WithError(err).Debug("Periodic redraw failed")
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
+
+// SetSwitchHandler sets the handler told when the visible layer changes
+func (sm *ScreenManager) SetSwitchHandler(handler SwitchHandler) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.onSwitch = handler
+}
+
+// SetFrameHandler sets the handler told every frame sent to the panel
+func (sm *ScreenManager) SetFrameHandler(handler FrameHandler) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.onFrame = handler
+}
+
+// SetConcealed sets the function reporting whether the panel shows a
+// secret being entered, e.g. Prompter.SecretOpen. Meanwhile the frame
+// handler is not told of frames and Snapshot returns the last frame shown
+// before, so the secret does not reach logs or other hosts. It runs while
+// the screen manager is locked and must not use it.
+func (sm *ScreenManager) SetConcealed(concealed func() bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.concealed = concealed
+}
+
+// SetBadge draws a character over the top right cell of every frame, whatever
+// layer is visible, e.g. to mark maintenance. An empty badge removes it.
+func (sm *ScreenManager) SetBadge(badge string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.badge = badge
+	return sm.render()
+}
+
+// Layer returns the layer for a priority, creating it on first use
+func (sm *ScreenManager) Layer(priority Priority) *Layer {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	layer, exists := sm.layers[priority]
+	if !exists {
+		area := sm.regionOf(priority)
+		layer = &Layer{
+			manager:  sm,
+			priority: priority,
+			row:      area.row,
+			fb:       NewFramebuffer(sm.width, area.height),
+		}
+		sm.layers[priority] = layer
+	}
+	return layer
+}
+
+// SetRegion confines the layer of a priority to height rows starting at row.
+// The layer's framebuffer shrinks to the region, so its writers address rows
+// relative to the region and see only its height. Changing the region of an
+// existing layer clears it.
+func (sm *ScreenManager) SetRegion(priority Priority, row, height int) error {
+	if row < 0 || height < 1 || row+height > sm.height {
+		return fmt.Errorf("invalid region: %d rows from row %d on a %d row display", height, row, sm.height)
+	}
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	area := region{row: row, height: height}
+	sm.regions[priority] = area
+	if layer, exists := sm.layers[priority]; exists {
+		layer.row = area.row
+		layer.fb = NewFramebuffer(sm.width, area.height)
+		return sm.render()
+	}
+	return nil
+}
+
+// regionOf returns the rows a priority's layer covers, the whole display
+// unless a region was set. Caller must hold the mutex.
+func (sm *ScreenManager) regionOf(priority Priority) region {
+	if area, exists := sm.regions[priority]; exists {
+		return area
+	}
+	return region{row: 0, height: sm.height}
+}
+
+// Active returns the priority of the visible layer, if any layer is claimed
+func (sm *ScreenManager) Active() (Priority, bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if sm.active == nil {
+		return PriorityIdle, false
+	}
+	return sm.active.priority, true
+}
+
+// Snapshot returns the lines currently shown on the panel, or while a
+// secret is entered the last lines shown before
+func (sm *ScreenManager) Snapshot() []string {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if sm.isConcealed() {
+		if sm.published == nil {
+			return NewFramebuffer(sm.width, sm.height).Lines()
+		}
+		return append([]string(nil), sm.published...)
+	}
+	return sm.shown.Lines()
+}
+
+// isConcealed reports whether the panel shows a secret. Caller must hold
+// the mutex.
+func (sm *ScreenManager) isConcealed() bool {
+	return sm.concealed != nil && sm.concealed()
+}
+
+// topLayer returns the highest priority claimed layer. Caller must hold the mutex.
+func (sm *ScreenManager) topLayer() *Layer {
+	var top *Layer
+	for _, layer := range sm.layers {
+		if !layer.claimed {
+			continue
+		}
+		if top == nil || layer.priority > top.priority {
+			top = layer
+		}
+	}
+	return top
+}
+
+// rowOwner returns the highest priority claimed layer covering a panel row.
+// Caller must hold the mutex.
+func (sm *ScreenManager) rowOwner(row int) *Layer {
+	var owner *Layer
+	for _, layer := range sm.layers {
+		if !layer.claimed || !layer.covers(row) {
+			continue
+		}
+		if owner == nil || layer.priority > owner.priority {
+			owner = layer
+		}
+	}
+	return owner
+}
+
+// render sends the visible layers to the display, writing only the lines that
+// differ from what the panel already shows. Several changed lines go out as
+// one batch when the display supports it. Caller must hold the mutex.
+func (sm *ScreenManager) render() error {
+	top := sm.topLayer()
+
+	if top != sm.active {
+		from, to := "none", "none"
+		if sm.active != nil {
+			from = sm.active.priority.String()
+		}
+		if top != nil {
+			to = top.priority.String()
+		}
+		sm.logger.WithFields(logrus.Fields{
+			"from": from,
+			"to":   to,
+		}).Debug("Switching visible screen")
+		sm.active = top
+		if sm.onSwitch != nil {
+			sm.onSwitch(from, to)
+		}
+	}
+
+	blank := strings.Repeat(" ", sm.width)
+	changed := make(map[int]string)
+	for row := 0; row < sm.height; row++ {
+		owner := sm.rowOwner(row)
+		sm.owners[row] = owner
+
+		line := blank
+		if owner != nil {
+			line = owner.fb.Line(row - owner.row)
+		}
+		if row == 0 && sm.badge != "" {
+			cells := []rune(line)
+			cells[len(cells)-1] = []rune(sm.badge)[0]
+			line = string(cells)
+		}
+		if line != sm.shown.Line(row) {
+			changed[row] = line
+		}
+	}
+
+	if batch, ok := sm.display.(BatchDisplay); ok && len(changed) > 1 {
+		if err := batch.WriteLines(changed); err != nil {
+			return fmt.Errorf("failed to render frame: %w", err)
+		}
+		for row, line := range changed {
+			sm.shown.SetLine(row, line)
+		}
+		sm.frameSent()
+		return nil
+	}
+
+	for row := 0; row < sm.height; row++ {
+		line, ok := changed[row]
+		if !ok {
+			continue
+		}
+		if err := sm.display.WriteTextAt(line, row, 0); err != nil {
+			return fmt.Errorf("failed to render line %d: %w", row, err)
+		}
+		sm.shown.SetLine(row, line)
+	}
+
+	if len(changed) > 0 {
+		sm.frameSent()
+	}
+	return nil
+}
+
+// frameSent tells the frame handler what the panel shows now. Caller must
+// hold the mutex.
+func (sm *ScreenManager) frameSent() {
+	if sm.isConcealed() {
+		return
+	}
+	sm.published = sm.shown.Lines()
+	if sm.onFrame != nil {
+		sm.onFrame(sm.shown.Lines())
+	}
+}
+
+// Writer is what a subsystem needs to draw on the panel. Layer implements
+// it; writers taking a Writer instead of a Layer can be tested without a
+// ScreenManager.
+type Writer interface {
+	WriteText(text string) error
+	WriteTextAt(text string, row, col int) error
+	ClearDisplay() error
+	Release() error
+	IsVisible() bool
+	Size() (int, int)
+}
+
+var _ Writer = (*Layer)(nil)
+
+// Layer is one writer's view of the display. It implements the same text
+// methods as the display controller, so it can be handed to the menu system
+// or any other writer in place of the real display.
+type Layer struct {
+	manager  *ScreenManager
+	priority Priority
+	// row is the first panel row of the layer's region
+	row     int
+	fb      *Framebuffer
+	claimed bool
+}
+
+// Priority returns the priority of the layer
+func (l *Layer) Priority() Priority {
+	return l.priority
+}
+
+// Size returns the width and height of the layer, which is smaller than the
+// display if the layer is confined to a region
+func (l *Layer) Size() (int, int) {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	return l.fb.Width(), l.fb.Height()
+}
+
+// IsVisible reports whether this layer is shown on at least one panel row
+func (l *Layer) IsVisible() bool {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	for _, owner := range l.manager.owners {
+		if owner == l {
+			return true
+		}
+	}
+	return false
+}
+
+// Claimed reports whether the layer holds content, i.e. whether it was
+// written since it was created or last released, shown or not
+func (l *Layer) Claimed() bool {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	return l.claimed
+}
+
+// covers reports whether a panel row lies in the layer's region
+func (l *Layer) covers(row int) bool {
+	return row >= l.row && row < l.row+l.fb.Height()
+}
+
+// WriteText replaces the layer contents with newline separated text
+func (l *Layer) WriteText(text string) error {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	lines := strings.Split(text, "\n")
+	for row := 0; row < l.fb.Height(); row++ {
+		line := ""
+		if row < len(lines) {
+			line = lines[row]
+		}
+		l.fb.SetLine(row, line)
+	}
+
+	l.claimed = true
+	return l.manager.render()
+}
+
+// WriteTextAt writes text into the layer at a specific position
+func (l *Layer) WriteTextAt(text string, row, col int) error {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	if err := l.fb.SetText(row, col, text); err != nil {
+		return err
+	}
+
+	l.claimed = true
+	return l.manager.render()
+}
+
+// Draw replaces the layer contents with a framebuffer of the same geometry
+func (l *Layer) Draw(fb *Framebuffer) error {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	for row := 0; row < l.fb.Height(); row++ {
+		l.fb.SetLine(row, fb.Line(row))
+	}
+
+	l.claimed = true
+	return l.manager.render()
+}
+
+// ClearDisplay blanks the layer while keeping it claimed
+func (l *Layer) ClearDisplay() error {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	l.fb.Clear()
+	l.claimed = true
+	return l.manager.render()
+}
+
+// SetBacklight controls the panel backlight. The backlight is shared by all
+// layers, so the call is passed straight through to the display.
+func (l *Layer) SetBacklight(on bool) error {
+	return l.manager.display.SetBacklight(on)
+}
+
+// Release dismisses the layer and restores the next highest claimed layer
+func (l *Layer) Release() error {
+	l.manager.mutex.Lock()
+	defer l.manager.mutex.Unlock()
+
+	if !l.claimed {
+		return nil
+	}
+
+	l.claimed = false
+	l.fb.Clear()
+	return l.manager.render()
+}
@@ -0,0 +1,441 @@
+package screen
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDisplay remembers the lines sent to the panel
+type recordingDisplay struct {
+	mutex     sync.Mutex
+	lines     []string
+	writes    int
+	backlight bool
+	writeErr  error
+}
+
+func newRecordingDisplay() *recordingDisplay {
+	return &recordingDisplay{lines: make([]string, 2)}
+}
+
+func (d *recordingDisplay) WriteTextAt(text string, row, col int) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.writeErr != nil {
+		return d.writeErr
+	}
+	d.lines[row] = text
+	d.writes++
+	return nil
+}
+
+func (d *recordingDisplay) SetBacklight(on bool) error {
+	d.backlight = on
+	return nil
+}
+
+func (d *recordingDisplay) shown() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return strings.TrimRight(d.lines[0], " ") + "|" + strings.TrimRight(d.lines[1], " ")
+}
+
+func TestFramebuffer(t *testing.T) {
+	fb := NewFramebuffer(16, 2)
+
+	assert.Equal(t, "                ", fb.Line(0))
+
+	require.NoError(t, fb.SetLine(0, "Hello"))
+	assert.Equal(t, "Hello           ", fb.Line(0))
+
+	require.NoError(t, fb.SetLine(1, "This line is much too long"))
+	assert.Equal(t, "This line is muc", fb.Line(1))
+
+	require.NoError(t, fb.SetText(0, 10, "World"))
+	assert.Equal(t, "Hello     World ", fb.Line(0))
+
+	assert.Error(t, fb.SetLine(2, "x"))
+	assert.Error(t, fb.SetText(0, 16, "x"))
+
+	// Characters, not bytes, fill the cells
+	require.NoError(t, fb.SetLine(1, "Größe München ÄÖÜ"))
+	assert.Equal(t, "Größe München ÄÖ", fb.Line(1))
+	require.NoError(t, fb.SetText(1, 6, "Köln"))
+	assert.Equal(t, "Größe Kölnhen ÄÖ", fb.Line(1))
+	require.NoError(t, fb.SetLine(1, "Straße"))
+	assert.Equal(t, "Straße          ", fb.Line(1))
+
+	clone := fb.Clone()
+	fb.Clear()
+	assert.Equal(t, "Hello     World ", clone.Line(0))
+	assert.Equal(t, "                ", fb.Line(0))
+}
+
+func TestPriorityOrdering(t *testing.T) {
+	ordered := []Priority{
+		PriorityIdle,
+		PriorityStatus,
+		PriorityMenu,
+		PriorityLCDproc,
+		PriorityScheduled,
+		PriorityRemote,
+		PriorityCopy,
+		PrioritySplash,
+		PriorityConfirmation,
+		PriorityAlert,
+	}
+
+	for i := 1; i < len(ordered); i++ {
+		assert.Greater(t, ordered[i], ordered[i-1], "%s must outrank %s", ordered[i], ordered[i-1])
+	}
+}
+
+func TestScreenManager_Preemption(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+
+	menu := sm.Layer(PriorityMenu)
+	copyScreen := sm.Layer(PriorityCopy)
+	alert := sm.Layer(PriorityAlert)
+
+	_, active := sm.Active()
+	assert.False(t, active)
+
+	require.NoError(t, menu.WriteText("QNAP Control\n>System Info"))
+	assert.Equal(t, "QNAP Control|>System Info", display.shown())
+	assert.True(t, menu.IsVisible())
+
+	// Copy preempts the menu
+	require.NoError(t, copyScreen.WriteText("Copy in progress\nStarting..."))
+	assert.Equal(t, "Copy in progress|Starting...", display.shown())
+	assert.False(t, menu.IsVisible())
+
+	// Menu updates while preempted are buffered, not shown
+	require.NoError(t, menu.WriteText("QNAP Control\n>Network"))
+	assert.Equal(t, "Copy in progress|Starting...", display.shown())
+
+	// An alert preempts the copy
+	require.NoError(t, alert.WriteText("ALERT\nDisk 3 failing"))
+	assert.Equal(t, "ALERT|Disk 3 failing", display.shown())
+
+	// Copy progress continues underneath the alert
+	require.NoError(t, copyScreen.WriteTextAt("50%", 1, 0))
+	assert.Equal(t, "ALERT|Disk 3 failing", display.shown())
+
+	priority, active := sm.Active()
+	assert.True(t, active)
+	assert.Equal(t, PriorityAlert, priority)
+
+	// Dismissing the alert restores the latest copy screen
+	require.NoError(t, alert.Release())
+	assert.Equal(t, "Copy in progress|50%", display.shown())
+
+	// Finishing the copy restores the latest menu screen
+	require.NoError(t, copyScreen.Release())
+	assert.Equal(t, "QNAP Control|>Network", display.shown())
+	assert.True(t, menu.IsVisible())
+
+	// Releasing the last layer blanks the panel
+	require.NoError(t, menu.Release())
+	assert.Equal(t, "|", display.shown())
+	_, active = sm.Active()
+	assert.False(t, active)
+}
+
+func TestScreenManager_Regions(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+
+	require.NoError(t, sm.SetRegion(PriorityStatus, 0, 1))
+	require.NoError(t, sm.SetRegion(PriorityMenu, 1, 1))
+	status := sm.Layer(PriorityStatus)
+	menu := sm.Layer(PriorityMenu)
+	prompt := sm.Layer(PriorityConfirmation)
+
+	width, height := menu.Size()
+	assert.Equal(t, 16, width)
+	assert.Equal(t, 1, height)
+
+	// Both regions are shown at once and each writes to its own row 0
+	require.NoError(t, status.WriteText("IP 10.0.0.2"))
+	require.NoError(t, menu.WriteText(">System Info\nignored"))
+	assert.Equal(t, "IP 10.0.0.2|>System Info", display.shown())
+	assert.True(t, status.IsVisible())
+	assert.True(t, menu.IsVisible())
+
+	require.NoError(t, status.WriteTextAt("Up 3d 4h", 0, 0))
+	assert.Equal(t, "Up 3d 4h|>System Info", display.shown())
+	assert.Error(t, menu.WriteTextAt("x", 1, 0), "rows outside the region are rejected")
+
+	// A full-screen layer above both covers them, then restores them
+	require.NoError(t, prompt.WriteText("Reboot now?\n>No  Yes"))
+	assert.Equal(t, "Reboot now?|>No  Yes", display.shown())
+	assert.False(t, status.IsVisible())
+	require.NoError(t, menu.WriteText(">Network"))
+	require.NoError(t, prompt.Release())
+	assert.Equal(t, "Up 3d 4h|>Network", display.shown())
+
+	// A released region leaves its row to lower layers, here none
+	require.NoError(t, status.Release())
+	assert.Equal(t, "|>Network", display.shown())
+
+	// Moving a region clears the layer
+	require.NoError(t, sm.SetRegion(PriorityMenu, 0, 1))
+	assert.Equal(t, "|", display.shown())
+
+	assert.Error(t, sm.SetRegion(PriorityMenu, 1, 2))
+	assert.Error(t, sm.SetRegion(PriorityMenu, -1, 1))
+}
+
+func TestScreenManager_ReleaseHiddenLayer(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+
+	status := sm.Layer(PriorityStatus)
+	alert := sm.Layer(PriorityAlert)
+
+	require.NoError(t, status.WriteText("CPU 12%\nRAM 40%"))
+	require.NoError(t, alert.WriteText("ALERT\nFan stopped"))
+
+	// Releasing a preempted layer does not disturb the visible one
+	writes := display.writes
+	require.NoError(t, status.Release())
+	assert.Equal(t, "ALERT|Fan stopped", display.shown())
+	assert.Equal(t, writes, display.writes)
+
+	// Releasing twice is harmless
+	require.NoError(t, status.Release())
+}
+
+func TestScreenManager_SwitchHandler(t *testing.T) {
+	sm := NewScreenManager(newRecordingDisplay(), 16, 2)
+	var switches []string
+	sm.SetSwitchHandler(func(from, to string) {
+		switches = append(switches, from+" -> "+to)
+	})
+
+	menu := sm.Layer(PriorityMenu)
+	copying := sm.Layer(PriorityCopy)
+	require.NoError(t, menu.WriteText("Main Menu"))
+	require.NoError(t, menu.WriteText(">System Info"))
+	require.NoError(t, copying.WriteText("Copy in progress"))
+	require.NoError(t, copying.Release())
+	require.NoError(t, menu.Release())
+
+	assert.Equal(t, []string{"none -> menu", "menu -> copy", "copy -> menu", "menu -> none"}, switches,
+		"only changes of the visible layer are reported")
+}
+
+func TestScreenManager_Badge(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	menu := sm.Layer(PriorityMenu)
+	require.NoError(t, menu.WriteText("Main Menu\n>Network"))
+
+	require.NoError(t, sm.SetBadge("W"))
+	assert.Equal(t, "Main Menu      W|>Network", display.shown())
+
+	// It stays over whatever layer is visible
+	alert := sm.Layer(PriorityAlert)
+	require.NoError(t, alert.WriteText("Rack temperature\n41 above 40"))
+	assert.Equal(t, "Rack temperaturW|41 above 40", display.shown())
+	require.NoError(t, alert.Release())
+
+	require.NoError(t, sm.SetBadge(""))
+	assert.Equal(t, "Main Menu|>Network", display.shown())
+}
+
+func TestScreenManager_OnlyChangedLinesAreWritten(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	menu := sm.Layer(PriorityMenu)
+
+	// The first render always writes every line
+	require.NoError(t, menu.WriteText("\n"))
+	assert.Equal(t, 2, display.writes)
+
+	require.NoError(t, menu.WriteText("Title\nItem"))
+	assert.Equal(t, 4, display.writes)
+
+	require.NoError(t, menu.WriteText("Title\nOther"))
+	assert.Equal(t, 5, display.writes)
+
+	require.NoError(t, menu.WriteText("Title\nOther"))
+	assert.Equal(t, 5, display.writes)
+}
+
+func TestScreenManager_RedrawEvery(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	require.NoError(t, sm.Layer(PriorityMenu).WriteText("Title\nItem"))
+
+	// Another process overwrites the panel behind the manager's back
+	display.mutex.Lock()
+	display.lines[1] = "#$%garbage"
+	display.mutex.Unlock()
+
+	stop := sm.RedrawEvery(10 * time.Millisecond)
+	assert.Eventually(t, func() bool { return display.shown() == "Title|Item" }, time.Second, 5*time.Millisecond)
+	stop()
+	stop()
+
+	display.mutex.Lock()
+	writes := display.writes
+	display.mutex.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	display.mutex.Lock()
+	assert.Equal(t, writes, display.writes, "no redraws after stop")
+	display.mutex.Unlock()
+}
+
+// The screen manager sends whole frames through the controller's batch writes
+var _ BatchDisplay = controller.DisplayControllerInterface(nil)
+
+// batchDisplay also accepts whole frames and counts them
+type batchDisplay struct {
+	*recordingDisplay
+	batches int
+}
+
+func (d *batchDisplay) WriteLines(lines map[int]string) error {
+	d.batches++
+	for row, line := range lines {
+		d.lines[row] = line
+	}
+	return nil
+}
+
+func TestScreenManager_BatchDisplay(t *testing.T) {
+	display := &batchDisplay{recordingDisplay: newRecordingDisplay()}
+	sm := NewScreenManager(display, 16, 2)
+	menu := sm.Layer(PriorityMenu)
+
+	require.NoError(t, menu.WriteText("Title\nItem"))
+	assert.Equal(t, 1, display.batches, "both lines go out together")
+	assert.Equal(t, 0, display.writes)
+	assert.Equal(t, "Title|Item", display.shown())
+
+	require.NoError(t, menu.WriteText("Title\nOther"))
+	assert.Equal(t, 1, display.batches, "a single changed line is written on its own")
+	assert.Equal(t, 1, display.writes)
+}
+
+func TestScreenManager_WriteTextAtColumn(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	layer := sm.Layer(PriorityStatus)
+
+	require.NoError(t, layer.WriteTextAt("Temp", 0, 0))
+	require.NoError(t, layer.WriteTextAt("42C", 0, 13))
+	assert.Equal(t, "Temp         42C", sm.Snapshot()[0])
+
+	assert.Error(t, layer.WriteTextAt("x", 5, 0))
+}
+
+func TestScreenManager_RenderError(t *testing.T) {
+	display := newRecordingDisplay()
+	display.writeErr = fmt.Errorf("serial failure")
+	sm := NewScreenManager(display, 16, 2)
+
+	err := sm.Layer(PriorityMenu).WriteText("Hello")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "serial failure")
+}
+
+func TestScreenManager_Backlight(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+
+	require.NoError(t, sm.Layer(PriorityMenu).SetBacklight(true))
+	assert.True(t, display.backlight)
+}
+
+func TestScreenManager_ConcurrentWriters(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+
+	var wg sync.WaitGroup
+	for _, priority := range []Priority{PriorityStatus, PriorityMenu, PriorityCopy} {
+		wg.Add(1)
+		go func(layer *Layer) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				layer.WriteText(fmt.Sprintf("%s\n%d", layer.Priority(), i))
+			}
+		}(sm.Layer(priority))
+	}
+	wg.Wait()
+
+	// The highest priority writer always ends up on the panel
+	assert.Equal(t, "copy|49", display.shown())
+}
+
+func TestPager(t *testing.T) {
+	t.Run("Word wrapping and indicator", func(t *testing.T) {
+		p := NewPager("The quick brown fox jumps\n\nover the lazy dog", 16, 2)
+
+		assert.Equal(t, 4, p.PageCount())
+		assert.Equal(t, "The quick brown\nPage 1/4", p.Render())
+
+		p.Next()
+		assert.Equal(t, 1, p.Page())
+		assert.Equal(t, "fox jumps\nPage 2/4", p.Render())
+
+		p.Next()
+		assert.Equal(t, "over the lazy\nPage 3/4", p.Render())
+
+		// The last word wraps onto a page of its own
+		p.Next()
+		assert.Equal(t, "dog\nPage 4/4", p.Render())
+
+		p.Next()
+		assert.Equal(t, 0, p.Page())
+	})
+
+	t.Run("Long words are broken", func(t *testing.T) {
+		p := NewPager("/dev/mapper/cachedev1-very-long-name", 16, 2)
+		assert.Equal(t, 3, p.PageCount())
+		assert.Equal(t, "/dev/mapper/cach\nPage 1/3", p.Render())
+	})
+
+	t.Run("Characters are counted, not bytes", func(t *testing.T) {
+		p := NewPager("Größe über München\nÄÄÄÄÄÄÄÄÄÄÄÄÄÄÄÄÄÄ", 16, 2)
+		assert.Equal(t, "Größe über\nPage 1/4", p.Render())
+		p.Next()
+		p.Next()
+		assert.Equal(t, "ÄÄÄÄÄÄÄÄÄÄÄÄÄÄÄÄ\nPage 3/4", p.Render())
+	})
+
+	t.Run("Taller displays show more lines per page", func(t *testing.T) {
+		p := NewPager("one\ntwo\nthree\nfour", 20, 4)
+		assert.Equal(t, 2, p.PageCount())
+		assert.Equal(t, "one\ntwo\nthree\nPage 1/2", p.Render())
+
+		p.Next()
+		assert.Equal(t, "four\n\n\nPage 2/2", p.Render())
+	})
+
+	t.Run("A single line display has no indicator", func(t *testing.T) {
+		p := NewPager("one\ntwo", 16, 1)
+		assert.Equal(t, 2, p.PageCount())
+		assert.Equal(t, "one", p.Render())
+
+		p.Next()
+		assert.Equal(t, "two", p.Render())
+	})
+
+	t.Run("Empty text", func(t *testing.T) {
+		p := NewPager("  \n", 16, 2)
+		assert.Equal(t, 1, p.PageCount())
+		assert.Equal(t, "\nPage 1/1", p.Render())
+	})
+}
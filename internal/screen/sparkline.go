@@ -0,0 +1,38 @@
+package screen
+
+import (
+	"math"
+	"strings"
+)
+
+// Sparkline renders one character per value, picking from bars, ordered from
+// lowest to highest, by where the value lies between the smallest and the
+// largest value. NaN values, gaps in the data, are blank. Without any
+// spread every value gets the lowest bar.
+func Sparkline(values []float64, bars []string) string {
+	if len(bars) == 0 {
+		return strings.Repeat(" ", len(values))
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
+		low, high = math.Min(low, value), math.Max(high, value)
+	}
+
+	var line strings.Builder
+	for _, value := range values {
+		if math.IsNaN(value) {
+			line.WriteByte(' ')
+			continue
+		}
+		level := 0
+		if high > low {
+			level = int(math.Round((value - low) / (high - low) * float64(len(bars)-1)))
+		}
+		line.WriteString(bars[level])
+	}
+	return line.String()
+}
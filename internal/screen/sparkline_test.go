@@ -0,0 +1,19 @@
+package screen
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	bars := []string{"_", "-", "="}
+	nan := math.NaN()
+
+	assert.Equal(t, "_- =", Sparkline([]float64{30, 35, nan, 40}, bars))
+	assert.Equal(t, "__ _", Sparkline([]float64{7, 7, nan, 7}, bars), "a flat line stays low")
+	assert.Equal(t, "   ", Sparkline([]float64{nan, nan, nan}, bars))
+	assert.Equal(t, "", Sparkline(nil, bars))
+	assert.Equal(t, "  ", Sparkline([]float64{1, 2}, nil))
+}
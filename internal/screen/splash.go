@@ -0,0 +1,115 @@
+package screen
+
+import (
+	"sync"
+	"time"
+)
+
+// Splash is a screen shown for a while at startup, while the rest of the
+// service comes up behind it
+type Splash struct {
+	// Text is shown on the splash layer, rows separated by "\n"
+	Text string
+	// Duration is how long the splash is shown unless a button skips it
+	Duration time.Duration
+	// Done is called once the splash is gone, e.g. to restore the glyphs a
+	// logo replaced (nil = nothing to do)
+	Done func()
+}
+
+// splashState is the splash being shown
+type splashState struct {
+	mutex sync.Mutex
+	shown *shownSplash
+	// swallow counts the releases of presses that skipped a splash
+	swallow int
+}
+
+// shownSplash ends a splash once its time is up
+type shownSplash struct {
+	timer *time.Timer
+	done  func()
+}
+
+// ShowSplash shows a splash on the splash layer, above copies and below
+// confirmations and alerts, and returns at once. The splash is released once
+// its duration passed or a button press skipped it, see HandleSplashButton.
+// A splash shown while another is up replaces it.
+func (sm *ScreenManager) ShowSplash(splash Splash) error {
+	sm.endSplash(sm.currentSplash())
+
+	if err := sm.Layer(PrioritySplash).WriteText(splash.Text); err != nil {
+		sm.releaseSplash(splash.Done)
+		return err
+	}
+
+	shown := &shownSplash{done: splash.Done}
+	sm.splash.mutex.Lock()
+	sm.splash.shown = shown
+	shown.timer = time.AfterFunc(splash.Duration, func() { sm.endSplash(shown) })
+	sm.splash.mutex.Unlock()
+	return nil
+}
+
+// HandleSplashButton skips the splash being shown on a button press. It
+// returns true if the event was consumed; the release of a skipping press is
+// consumed too.
+func (sm *ScreenManager) HandleSplashButton(pressed bool) bool {
+	if !pressed {
+		sm.splash.mutex.Lock()
+		defer sm.splash.mutex.Unlock()
+
+		if sm.splash.swallow > 0 {
+			sm.splash.swallow--
+			return true
+		}
+		return false
+	}
+
+	if !sm.endSplash(sm.currentSplash()) {
+		return false
+	}
+	sm.splash.mutex.Lock()
+	sm.splash.swallow++
+	sm.splash.mutex.Unlock()
+	return true
+}
+
+// SplashShown reports whether a splash is being shown
+func (sm *ScreenManager) SplashShown() bool {
+	return sm.currentSplash() != nil
+}
+
+// currentSplash returns the splash being shown (nil = none)
+func (sm *ScreenManager) currentSplash() *shownSplash {
+	sm.splash.mutex.Lock()
+	defer sm.splash.mutex.Unlock()
+
+	return sm.splash.shown
+}
+
+// endSplash releases the splash if it is still the one shown, reporting
+// whether it was. Only the first of its timer and a button press ends it.
+func (sm *ScreenManager) endSplash(shown *shownSplash) bool {
+	sm.splash.mutex.Lock()
+	if shown == nil || sm.splash.shown != shown {
+		sm.splash.mutex.Unlock()
+		return false
+	}
+	sm.splash.shown = nil
+	sm.splash.mutex.Unlock()
+
+	shown.timer.Stop()
+	sm.releaseSplash(shown.done)
+	return true
+}
+
+// releaseSplash dismisses the splash layer and calls done
+func (sm *ScreenManager) releaseSplash(done func()) {
+	if err := sm.Layer(PrioritySplash).Release(); err != nil {
+		sm.logger.WithError(err).Warn("Failed to release splash screen")
+	}
+	if done != nil {
+		done()
+	}
+}
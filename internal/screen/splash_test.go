@@ -0,0 +1,64 @@
+package screen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenManager_SplashTimesOut(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+	require.NoError(t, sm.Layer(PriorityMenu).WriteText("Main Menu"))
+
+	done := make(chan struct{})
+	require.NoError(t, sm.ShowSplash(Splash{
+		Text:     "QNAP Starting\nPlease wait...",
+		Duration: 20 * time.Millisecond,
+		Done:     func() { close(done) },
+	}))
+	assert.Equal(t, "QNAP Starting|Please wait...", display.shown())
+	assert.True(t, sm.SplashShown())
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("splash was not released after its duration")
+	}
+	assert.Equal(t, "Main Menu|", display.shown())
+	assert.False(t, sm.SplashShown())
+	assert.False(t, sm.HandleSplashButton(true), "presses after the splash are not consumed")
+}
+
+func TestScreenManager_SplashSkipped(t *testing.T) {
+	display := newRecordingDisplay()
+	sm := NewScreenManager(display, 16, 2)
+
+	dones := 0
+	require.NoError(t, sm.ShowSplash(Splash{Text: "Hello", Duration: time.Hour, Done: func() { dones++ }}))
+
+	// An alert covers the splash
+	alert := sm.Layer(PriorityAlert)
+	require.NoError(t, alert.WriteText("Fan failed"))
+	assert.Equal(t, "Fan failed|", display.shown())
+	require.NoError(t, alert.Release())
+	assert.Equal(t, "Hello|", display.shown())
+
+	// Any button skips it, and its release is consumed too
+	assert.True(t, sm.HandleSplashButton(true))
+	assert.Equal(t, "|", display.shown())
+	assert.Equal(t, 1, dones)
+	assert.True(t, sm.HandleSplashButton(false))
+	assert.False(t, sm.HandleSplashButton(false))
+	assert.False(t, sm.HandleSplashButton(true))
+
+	// A second splash replaces the first
+	require.NoError(t, sm.ShowSplash(Splash{Text: "One", Duration: time.Hour, Done: func() { dones++ }}))
+	require.NoError(t, sm.ShowSplash(Splash{Text: "Two", Duration: time.Hour}))
+	assert.Equal(t, 2, dones)
+	assert.Equal(t, "Two|", display.shown())
+	assert.True(t, sm.HandleSplashButton(true))
+	assert.Equal(t, 2, dones)
+}
@@ -0,0 +1,191 @@
+package sensor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/hardware"
+)
+
+// BME280 registers
+const (
+	bme280RegChipID    = 0xD0
+	bme280RegCalib1    = 0x88 // 26 bytes: temperature, pressure, H1
+	bme280RegCalib2    = 0xE1 // 7 bytes: H2 to H6
+	bme280RegCtrlHum   = 0xF2
+	bme280RegCtrlMeas  = 0xF4
+	bme280RegData      = 0xF7 // 8 bytes: pressure, temperature, humidity
+	bme280ChipID       = 0x60
+	bmp280ChipID       = 0x58 // the same sensor without humidity
+	bme280MeasureTime  = 10 * time.Millisecond
+	bme280ForcedX1     = 0x25 // temperature and pressure oversampling x1, forced mode
+	bme280HumidityX1   = 0x01
+	bme280Calib1Length = 26
+	bme280Calib2Length = 7
+)
+
+// bme280Calibration holds the factory trimming parameters
+type bme280Calibration struct {
+	t1             uint16
+	t2, t3         int16
+	p1             uint16
+	p2, p3, p4, p5 int16
+	p6, p7, p8, p9 int16
+	h1, h3         uint8
+	h2, h4, h5     int16
+	h6             int8
+}
+
+// BME280 reads a Bosch BME280 (temperature, humidity, pressure) or BMP280
+// (temperature, pressure)
+type BME280 struct {
+	bus         hardware.I2CBus
+	addr        uint16
+	calibration bme280Calibration
+	humidity    bool
+	// wait lets tests skip the measurement delay
+	wait func(time.Duration)
+}
+
+// NewBME280 identifies the sensor at addr (0x76 or 0x77) and reads its
+// calibration
+func NewBME280(bus hardware.I2CBus, addr uint16) (*BME280, error) {
+	s := &BME280{bus: bus, addr: addr, wait: time.Sleep}
+
+	id := make([]byte, 1)
+	if err := bus.Tx(addr, []byte{bme280RegChipID}, id); err != nil {
+		return nil, fmt.Errorf("bme280: %w", err)
+	}
+	switch id[0] {
+	case bme280ChipID:
+		s.humidity = true
+	case bmp280ChipID:
+	default:
+		return nil, fmt.Errorf("bme280: unexpected chip id 0x%02x at 0x%02x", id[0], addr)
+	}
+
+	if err := s.readCalibration(); err != nil {
+		return nil, fmt.Errorf("bme280: %w", err)
+	}
+	return s, nil
+}
+
+// readCalibration reads the trimming parameters from the sensor's NVM
+func (s *BME280) readCalibration() error {
+	calib1 := make([]byte, bme280Calib1Length)
+	if err := s.bus.Tx(s.addr, []byte{bme280RegCalib1}, calib1); err != nil {
+		return err
+	}
+
+	le := binary.LittleEndian
+	c := &s.calibration
+	c.t1 = le.Uint16(calib1[0:])
+	c.t2 = int16(le.Uint16(calib1[2:]))
+	c.t3 = int16(le.Uint16(calib1[4:]))
+	c.p1 = le.Uint16(calib1[6:])
+	c.p2 = int16(le.Uint16(calib1[8:]))
+	c.p3 = int16(le.Uint16(calib1[10:]))
+	c.p4 = int16(le.Uint16(calib1[12:]))
+	c.p5 = int16(le.Uint16(calib1[14:]))
+	c.p6 = int16(le.Uint16(calib1[16:]))
+	c.p7 = int16(le.Uint16(calib1[18:]))
+	c.p8 = int16(le.Uint16(calib1[20:]))
+	c.p9 = int16(le.Uint16(calib1[22:]))
+	c.h1 = calib1[25]
+
+	if !s.humidity {
+		return nil
+	}
+	calib2 := make([]byte, bme280Calib2Length)
+	if err := s.bus.Tx(s.addr, []byte{bme280RegCalib2}, calib2); err != nil {
+		return err
+	}
+	c.h2 = int16(le.Uint16(calib2[0:]))
+	c.h3 = calib2[2]
+	// H4 and H5 are 12 bit values sharing the nibbles of 0xE5
+	c.h4 = int16(int8(calib2[3]))<<4 | int16(calib2[4]&0x0F)
+	c.h5 = int16(int8(calib2[5]))<<4 | int16(calib2[4]>>4)
+	c.h6 = int8(calib2[6])
+	return nil
+}
+
+// Read takes a forced mode measurement
+func (s *BME280) Read() (Reading, error) {
+	if s.humidity {
+		// Humidity settings only apply after the next ctrl_meas write
+		if err := s.bus.Tx(s.addr, []byte{bme280RegCtrlHum, bme280HumidityX1}, nil); err != nil {
+			return nil, fmt.Errorf("bme280: %w", err)
+		}
+	}
+	if err := s.bus.Tx(s.addr, []byte{bme280RegCtrlMeas, bme280ForcedX1}, nil); err != nil {
+		return nil, fmt.Errorf("bme280: %w", err)
+	}
+	s.wait(bme280MeasureTime)
+
+	data := make([]byte, 8)
+	if err := s.bus.Tx(s.addr, []byte{bme280RegData}, data); err != nil {
+		return nil, fmt.Errorf("bme280: %w", err)
+	}
+	adcP := int32(data[0])<<12 | int32(data[1])<<4 | int32(data[2])>>4
+	adcT := int32(data[3])<<12 | int32(data[4])<<4 | int32(data[5])>>4
+	adcH := int32(data[6])<<8 | int32(data[7])
+
+	temperature, tFine := s.compensateTemperature(adcT)
+	reading := Reading{
+		MetricTemperature: temperature,
+		MetricPressure:    s.compensatePressure(adcP, tFine) / 100,
+	}
+	if s.humidity {
+		reading[MetricHumidity] = s.compensateHumidity(adcH, tFine)
+	}
+	return reading, nil
+}
+
+// compensateTemperature converts a raw temperature to degrees Celsius. The
+// fine temperature it also returns feeds the other compensations. The
+// formulas are the floating point versions from the datasheet.
+func (s *BME280) compensateTemperature(adc int32) (float64, float64) {
+	c := &s.calibration
+	raw := float64(adc)
+	var1 := (raw/16384 - float64(c.t1)/1024) * float64(c.t2)
+	var2 := (raw/131072 - float64(c.t1)/8192) * (raw/131072 - float64(c.t1)/8192) * float64(c.t3)
+	tFine := var1 + var2
+	return tFine / 5120, tFine
+}
+
+// compensatePressure converts a raw pressure to pascal
+func (s *BME280) compensatePressure(adc int32, tFine float64) float64 {
+	c := &s.calibration
+	var1 := tFine/2 - 64000
+	var2 := var1 * var1 * float64(c.p6) / 32768
+	var2 += var1 * float64(c.p5) * 2
+	var2 = var2/4 + float64(c.p4)*65536
+	var1 = (float64(c.p3)*var1*var1/524288 + float64(c.p2)*var1) / 524288
+	var1 = (1 + var1/32768) * float64(c.p1)
+	if var1 == 0 {
+		// Avoids a division by zero on an unconfigured sensor
+		return 0
+	}
+	p := 1048576 - float64(adc)
+	p = (p - var2/4096) * 6250 / var1
+	var1 = float64(c.p9) * p * p / 2147483648
+	var2 = p * float64(c.p8) / 32768
+	return p + (var1+var2+float64(c.p7))/16
+}
+
+// compensateHumidity converts a raw humidity to percent, clamped to 0-100
+func (s *BME280) compensateHumidity(adc int32, tFine float64) float64 {
+	c := &s.calibration
+	h := tFine - 76800
+	h = (float64(adc) - (float64(c.h4)*64 + float64(c.h5)/16384*h)) *
+		(float64(c.h2) / 65536 * (1 + float64(c.h6)/67108864*h*(1+float64(c.h3)/67108864*h)))
+	h *= 1 - float64(c.h1)*h/524288
+	switch {
+	case h > 100:
+		return 100
+	case h < 0:
+		return 0
+	}
+	return h
+}
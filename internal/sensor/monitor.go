@@ -0,0 +1,175 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPollInterval is how often a sensor is read when no interval is set
+const DefaultPollInterval = 30 * time.Second
+
+// source is a sensor polled by the monitor
+type source struct {
+	name     string
+	sensor   Sensor
+	interval time.Duration
+
+	// latest is the last successful reading, taken at updated, err the
+	// error of the last poll
+	latest  Reading
+	updated time.Time
+	err     error
+}
+
+// Monitor polls sensors and keeps their latest readings. Alerts on the
+// readings are raised by rules over them.
+type Monitor struct {
+	logger *logrus.Entry
+
+	mutex   sync.Mutex
+	sources map[string]*source
+	order   []string
+	cancel  context.CancelFunc
+	done    sync.WaitGroup
+}
+
+// NewMonitor creates a monitor without sensors
+func NewMonitor() *Monitor {
+	return &Monitor{
+		logger:  logrus.WithField("component", "sensor_monitor"),
+		sources: make(map[string]*source),
+	}
+}
+
+// Add registers a sensor under a unique name, polled every interval (0 for
+// DefaultPollInterval)
+func (m *Monitor) Add(name string, sensor Sensor, interval time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.sources[name]; exists {
+		return fmt.Errorf("duplicate sensor name %q", name)
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	m.sources[name] = &source{
+		name:     name,
+		sensor:   sensor,
+		interval: interval,
+		err:      fmt.Errorf("sensor %q not read yet", name),
+	}
+	m.order = append(m.order, name)
+	return nil
+}
+
+// Names returns the sensor names in the order they were added
+func (m *Monitor) Names() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]string(nil), m.order...)
+}
+
+// Start polls every sensor in the background
+func (m *Monitor) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	for _, name := range m.order {
+		src := m.sources[name]
+		m.done.Add(1)
+		go func() {
+			defer m.done.Done()
+			m.run(ctx, src)
+		}()
+	}
+}
+
+// Stop ends polling and waits for reads in progress
+func (m *Monitor) Stop() {
+	m.mutex.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		m.done.Wait()
+	}
+}
+
+// Latest returns the last reading of a sensor, or the error of its last poll
+func (m *Monitor) Latest(name string) (Reading, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	src, exists := m.sources[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown sensor %q", name)
+	}
+	if src.err != nil {
+		return nil, src.err
+	}
+	return src.latest, nil
+}
+
+// Last returns the last successful reading of a sensor and when it was
+// taken, even if polls failed or hang since. It fails only if there was no
+// successful reading yet.
+func (m *Monitor) Last(name string) (Reading, time.Time, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	src, exists := m.sources[name]
+	if !exists {
+		return nil, time.Time{}, fmt.Errorf("unknown sensor %q", name)
+	}
+	if src.updated.IsZero() {
+		return nil, time.Time{}, src.err
+	}
+	return src.latest, src.updated, nil
+}
+
+// run polls a sensor until ctx is cancelled
+func (m *Monitor) run(ctx context.Context, src *source) {
+	ticker := time.NewTicker(src.interval)
+	defer ticker.Stop()
+
+	for {
+		m.poll(src)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads a sensor once. A failed read keeps the last good reading for
+// Last.
+func (m *Monitor) poll(src *source) {
+	reading, err := src.sensor.Read()
+
+	m.mutex.Lock()
+	src.err = err
+	if err == nil {
+		src.latest, src.updated = reading, time.Now()
+	}
+	m.mutex.Unlock()
+
+	if err != nil {
+		m.logger.WithError(err).WithField("sensor", src.name).Debug("Sensor read failed")
+	}
+}
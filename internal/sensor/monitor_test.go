@@ -0,0 +1,67 @@
+package sensor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSensor returns its reading or error
+type stubSensor struct {
+	reading Reading
+	err     error
+}
+
+func (s *stubSensor) Read() (Reading, error) {
+	return s.reading, s.err
+}
+
+func TestMonitor(t *testing.T) {
+	rack := &stubSensor{reading: Reading{MetricHumidity: 50}}
+	m := NewMonitor()
+	require.NoError(t, m.Add("rack", rack, 0))
+	assert.Error(t, m.Add("rack", rack, 0), "names are unique")
+
+	_, err := m.Latest("rack")
+	assert.Error(t, err, "no reading before the first poll")
+	_, _, err = m.Last("rack")
+	assert.Error(t, err)
+	_, err = m.Latest("attic")
+	assert.Error(t, err)
+
+	src := m.sources["rack"]
+	m.poll(src)
+	reading, err := m.Latest("rack")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, reading[MetricHumidity])
+
+	// A failed read reports the error and keeps the last good reading
+	rack.reading = Reading{MetricHumidity: 68}
+	m.poll(src)
+	rack.err = errors.New("bus error")
+	m.poll(src)
+	_, err = m.Latest("rack")
+	assert.Error(t, err)
+	last, updated, err := m.Last("rack")
+	require.NoError(t, err, "the last good reading is kept")
+	assert.Equal(t, 68.0, last[MetricHumidity])
+	assert.WithinDuration(t, time.Now(), updated, time.Minute)
+
+	assert.Equal(t, []string{"rack"}, m.Names())
+}
+
+func TestMonitor_StartStop(t *testing.T) {
+	m := NewMonitor()
+	require.NoError(t, m.Add("rack", &stubSensor{reading: Reading{MetricTemperature: 21}}, 0))
+
+	m.Start()
+	m.Stop()
+	m.Stop()
+
+	reading, err := m.Latest("rack")
+	require.NoError(t, err, "the first poll happens when polling starts")
+	assert.Equal(t, 21.0, reading[MetricTemperature])
+}
@@ -0,0 +1,76 @@
+// Package sensor reads ambient sensors attached to the I2C header and raises
+// alerts when their readings cross configured thresholds.
+package sensor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qnap/display-control/internal/hardware"
+)
+
+// Metrics a sensor may report
+const (
+	MetricTemperature = "temperature" // degrees Celsius
+	MetricHumidity    = "humidity"    // percent relative humidity
+	MetricPressure    = "pressure"    // hectopascal
+)
+
+// Reading maps metric names to values
+type Reading map[string]float64
+
+// Render formats a reading for one panel line: the temperature, then the
+// humidity or, for sensors without one, the pressure
+func (r Reading) Render() string {
+	parts := make([]string, 0, 2)
+	if temperature, ok := r[MetricTemperature]; ok {
+		parts = append(parts, fmt.Sprintf("%.1fC", temperature))
+	}
+	if humidity, ok := r[MetricHumidity]; ok {
+		parts = append(parts, fmt.Sprintf("%.0f%%", humidity))
+	} else if pressure, ok := r[MetricPressure]; ok {
+		parts = append(parts, fmt.Sprintf("%.0fhPa", pressure))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Sensor takes readings from one device
+type Sensor interface {
+	Read() (Reading, error)
+}
+
+// drivers create sensors by type name for a device on a bus
+var drivers = map[string]func(bus hardware.I2CBus, addr uint16) (Sensor, error){
+	"sht3x":  func(bus hardware.I2CBus, addr uint16) (Sensor, error) { return NewSHT3x(bus, addr), nil },
+	"bme280": func(bus hardware.I2CBus, addr uint16) (Sensor, error) { return NewBME280(bus, addr) },
+}
+
+// defaultAddresses are used when the configuration gives no address
+var defaultAddresses = map[string]uint16{
+	"sht3x":  0x44,
+	"bme280": 0x76,
+}
+
+// Types returns the names of the supported sensor types
+func Types() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a sensor of the given type; addr 0 selects the type's usual
+// address
+func New(sensorType string, bus hardware.I2CBus, addr uint16) (Sensor, error) {
+	driver, exists := drivers[sensorType]
+	if !exists {
+		return nil, fmt.Errorf("unknown sensor type %q (available: %s)", sensorType, strings.Join(Types(), ", "))
+	}
+	if addr == 0 {
+		addr = defaultAddresses[sensorType]
+	}
+	return driver(bus, addr)
+}
@@ -0,0 +1,148 @@
+package sensor
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBus is a register mapped device: a one byte write selects a register
+// that the following read starts at, longer writes set registers
+type fakeBus struct {
+	addr      uint16
+	registers [256]byte
+	// reads is returned by reads without a register write (SHT3x style)
+	reads [][]byte
+	// writes records every write
+	writes [][]byte
+	err    error
+}
+
+func (b *fakeBus) Tx(addr uint16, w, r []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	if addr != b.addr {
+		return errors.New("no device")
+	}
+	if len(w) > 0 {
+		b.writes = append(b.writes, append([]byte(nil), w...))
+	}
+	switch {
+	case len(w) == 1:
+		copy(r, b.registers[w[0]:])
+	case len(w) > 1:
+		copy(b.registers[w[0]:], w[1:])
+	case len(r) > 0:
+		copy(r, b.reads[0])
+		b.reads = b.reads[1:]
+	}
+	return nil
+}
+
+func (b *fakeBus) Close() error {
+	return nil
+}
+
+// sht3xWord encodes a raw value with its checksum
+func sht3xWord(raw uint16) []byte {
+	word := []byte{byte(raw >> 8), byte(raw)}
+	return append(word, crc8(word))
+}
+
+func TestSHT3x(t *testing.T) {
+	assert.Equal(t, byte(0x92), crc8([]byte{0xBE, 0xEF}), "checksum example from the datasheet")
+
+	bus := &fakeBus{addr: 0x44}
+	bus.reads = [][]byte{append(sht3xWord(0x6666), sht3xWord(0x8000)...)}
+	sensor, err := New("sht3x", bus, 0)
+	require.NoError(t, err)
+	sensor.(*SHT3x).wait = func(time.Duration) {}
+
+	reading, err := sensor.Read()
+	require.NoError(t, err)
+	assert.InDelta(t, 25.0, reading[MetricTemperature], 0.01)
+	assert.InDelta(t, 50.0, reading[MetricHumidity], 0.01)
+	assert.Equal(t, [][]byte{{0x24, 0x00}}, bus.writes)
+	assert.Equal(t, "25.0C 50%", reading.Render())
+
+	corrupt := append(sht3xWord(0x6666), sht3xWord(0x8000)...)
+	corrupt[5] ^= 0xFF
+	bus.reads = [][]byte{corrupt}
+	_, err = sensor.Read()
+	assert.Error(t, err, "checksum errors are reported")
+}
+
+// bmp280Example loads the calibration and raw values of the compensation
+// example in the BMP280 datasheet
+func bmp280Example(bus *fakeBus) {
+	calibration := []uint16{27504, 26435, 0xFFFF & uint16(0x10000-1000), 36477, 0xFFFF & uint16(0x10000-10685),
+		3024, 2855, 140, 0xFFFF & uint16(0x10000-7), 15500, 0xFFFF & uint16(0x10000-14600), 6000}
+	for i, value := range calibration {
+		binary.LittleEndian.PutUint16(bus.registers[bme280RegCalib1+2*i:], value)
+	}
+	// adc_P = 415148, adc_T = 519888
+	copy(bus.registers[bme280RegData:], []byte{0x65, 0x5A, 0xC0, 0x7E, 0xED, 0x00})
+}
+
+func TestBMP280(t *testing.T) {
+	bus := &fakeBus{addr: 0x76}
+	bus.registers[bme280RegChipID] = bmp280ChipID
+	bmp280Example(bus)
+
+	sensor, err := NewBME280(bus, 0x76)
+	require.NoError(t, err)
+	sensor.wait = func(time.Duration) {}
+
+	reading, err := sensor.Read()
+	require.NoError(t, err)
+	assert.InDelta(t, 25.08, reading[MetricTemperature], 0.01)
+	assert.InDelta(t, 1006.53, reading[MetricPressure], 0.01)
+	assert.NotContains(t, reading, MetricHumidity, "the BMP280 has no humidity sensor")
+	assert.Equal(t, "25.1C 1007hPa", reading.Render())
+	assert.Equal(t, []byte{bme280RegCtrlMeas, bme280ForcedX1}, bus.writes[len(bus.writes)-2])
+}
+
+func TestBME280(t *testing.T) {
+	bus := &fakeBus{addr: 0x77}
+	bus.registers[bme280RegChipID] = bme280ChipID
+	bmp280Example(bus)
+	bus.registers[bme280RegCalib1+25] = 75 // H1
+	// H2 = 362, H3 = 0, H4 = 313, H5 = 50, H6 = 30
+	copy(bus.registers[bme280RegCalib2:], []byte{0x6A, 0x01, 0x00, 0x13, 0x29, 0x03, 0x1E})
+	copy(bus.registers[bme280RegData+6:], []byte{0x6E, 0x8F})
+
+	sensor, err := NewBME280(bus, 0x77)
+	require.NoError(t, err)
+	sensor.wait = func(time.Duration) {}
+	assert.Equal(t, int16(313), sensor.calibration.h4)
+	assert.Equal(t, int16(50), sensor.calibration.h5)
+
+	reading, err := sensor.Read()
+	require.NoError(t, err)
+	assert.InDelta(t, 25.08, reading[MetricTemperature], 0.01)
+	assert.InDelta(t, 45.55, reading[MetricHumidity], 0.01)
+	assert.Equal(t, []byte{bme280RegCtrlHum, bme280HumidityX1}, bus.writes[len(bus.writes)-3],
+		"humidity is configured before the measurement starts")
+
+	t.Run("Unknown chip", func(t *testing.T) {
+		bus.registers[bme280RegChipID] = 0x55
+		_, err := NewBME280(bus, 0x77)
+		assert.Error(t, err)
+	})
+
+	t.Run("No device", func(t *testing.T) {
+		_, err := New("bme280", bus, 0)
+		assert.Error(t, err, "nothing answers at the default address 0x76")
+	})
+}
+
+func TestNew_UnknownType(t *testing.T) {
+	_, err := New("dht22", &fakeBus{}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bme280, sht3x")
+}
@@ -0,0 +1,71 @@
+package sensor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qnap/display-control/internal/hardware"
+)
+
+// sht3xMeasure starts a single high repeatability measurement without clock
+// stretching
+var sht3xMeasure = []byte{0x24, 0x00}
+
+// sht3xMeasureTime is the longest a high repeatability measurement takes
+const sht3xMeasureTime = 16 * time.Millisecond
+
+// SHT3x reads a Sensirion SHT30/31/35 temperature and humidity sensor
+type SHT3x struct {
+	bus  hardware.I2CBus
+	addr uint16
+	// wait lets tests skip the measurement delay
+	wait func(time.Duration)
+}
+
+// NewSHT3x creates a driver for the SHT3x at addr (0x44 or 0x45)
+func NewSHT3x(bus hardware.I2CBus, addr uint16) *SHT3x {
+	return &SHT3x{bus: bus, addr: addr, wait: time.Sleep}
+}
+
+// Read triggers a measurement and returns temperature and humidity
+func (s *SHT3x) Read() (Reading, error) {
+	if err := s.bus.Tx(s.addr, sht3xMeasure, nil); err != nil {
+		return nil, fmt.Errorf("sht3x: %w", err)
+	}
+	s.wait(sht3xMeasureTime)
+
+	data := make([]byte, 6)
+	if err := s.bus.Tx(s.addr, nil, data); err != nil {
+		return nil, fmt.Errorf("sht3x: %w", err)
+	}
+
+	// Each value is two bytes followed by their checksum
+	for _, word := range [][]byte{data[0:3], data[3:6]} {
+		if crc8(word[:2]) != word[2] {
+			return nil, fmt.Errorf("sht3x: checksum mismatch")
+		}
+	}
+
+	rawTemperature := float64(uint16(data[0])<<8 | uint16(data[1]))
+	rawHumidity := float64(uint16(data[3])<<8 | uint16(data[4]))
+	return Reading{
+		MetricTemperature: -45 + 175*rawTemperature/65535,
+		MetricHumidity:    100 * rawHumidity / 65535,
+	}, nil
+}
+
+// crc8 is the Sensirion checksum: polynomial 0x31, initial value 0xFF
+func crc8(data []byte) byte {
+	crc := byte(0xFF)
+	for _, b := range data {
+		crc ^= b
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -2,6 +2,7 @@ package serial
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/tarm/serial"
@@ -197,81 +198,6 @@ func (sp *SerialPort) NewDisplayTester() *DisplayTester {
 	return &DisplayTester{port: sp}
 }
 
-// DisplayTester provides simple display testing functionality
-type DisplayTester struct {
-	port SerialPortInterface
-}
-
-// TestDisplay attempts different methods to write to the display
-func (dt *DisplayTester) TestDisplay(line1, line2 string) error {
-	if dt.port == nil {
-		return fmt.Errorf("no serial port available")
-	}
-
-	// Method 1: HD44780 Direct Commands
-	if err := dt.testHD44780(line1, line2); err == nil {
-		return nil
-	}
-
-	// Method 2: Simple Text
-	if err := dt.testSimpleText(line1, line2); err == nil {
-		return nil
-	}
-
-	// Method 3: Raw Text
-	if err := dt.testRawText(line1, line2); err == nil {
-		return nil
-	}
-
-	return fmt.Errorf("all display methods failed")
-}
-
-// testHD44780 tests HD44780 compatible commands
-func (dt *DisplayTester) testHD44780(line1, line2 string) error {
-	// Initialize display
-	initCmds := []byte{
-		0x38, // Function set: 8-bit, 2 line, 5x7 dots
-		0x0C, // Display on, cursor off
-		0x06, // Entry mode: increment cursor
-		0x01, // Clear display
-	}
-	
-	for _, cmd := range initCmds {
-		if err := dt.port.Write([]byte{cmd}); err != nil {
-			return err
-		}
-		time.Sleep(2 * time.Millisecond)
-	}
-	
-	// Wait for clear
-	time.Sleep(5 * time.Millisecond)
-	
-	// Write first line
-	if err := dt.port.WriteString(line1); err != nil {
-		return err
-	}
-	
-	// Move to second line
-	if err := dt.port.Write([]byte{0xC0}); err != nil {
-		return err
-	}
-	
-	// Write second line
-	return dt.port.WriteString(line2)
-}
-
-// testSimpleText tests simple text with basic formatting
-func (dt *DisplayTester) testSimpleText(line1, line2 string) error {
-	text := fmt.Sprintf("%s\n%s", line1, line2)
-	return dt.port.WriteString(text)
-}
-
-// testRawText tests raw text without any formatting
-func (dt *DisplayTester) testRawText(line1, line2 string) error {
-	text := line1 + line2
-	return dt.port.WriteString(text)
-}
-
 // Flush ensures all pending data is written
 func (sp *SerialPort) Flush() error {
 	if sp.port == nil {
@@ -288,8 +214,10 @@ func (sp *SerialPort) IsOpen() bool {
 	return sp.port != nil
 }
 
-// MockSerialPort provides a mock implementation for testing
+// MockSerialPort provides a mock implementation for testing.
+// It is safe for concurrent use so it can back a live DisplayController.
 type MockSerialPort struct {
+	mutex       sync.Mutex
 	writeBuffer []byte
 	readBuffer  []byte
 	readIndex   int
@@ -308,6 +236,9 @@ func NewMockSerialPort() *MockSerialPort {
 
 // SetReadData sets the data that will be returned by Read operations
 func (msp *MockSerialPort) SetReadData(data []byte) {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	msp.readBuffer = make([]byte, len(data))
 	copy(msp.readBuffer, data)
 	msp.readIndex = 0
@@ -315,16 +246,25 @@ func (msp *MockSerialPort) SetReadData(data []byte) {
 
 // SetWriteError sets an error that will be returned by Write operations
 func (msp *MockSerialPort) SetWriteError(err error) {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	msp.writeError = err
 }
 
 // SetReadError sets an error that will be returned by Read operations
 func (msp *MockSerialPort) SetReadError(err error) {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	msp.readError = err
 }
 
 // Write simulates writing to the serial port
 func (msp *MockSerialPort) Write(data []byte) error {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	if msp.closed {
 		return fmt.Errorf("serial port is closed")
 	}
@@ -339,6 +279,9 @@ func (msp *MockSerialPort) Write(data []byte) error {
 
 // Read simulates reading from the serial port
 func (msp *MockSerialPort) Read(buffer []byte) (int, error) {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	if msp.closed {
 		return 0, fmt.Errorf("serial port is closed")
 	}
@@ -370,14 +313,6 @@ func (msp *MockSerialPort) WriteString(text string) error {
 
 // WriteText writes text to the mock LCD display (line1 and line2)
 func (msp *MockSerialPort) WriteText(line1, line2 string, col, row int) error {
-	if msp.closed {
-		return fmt.Errorf("serial port is closed")
-	}
-	
-	if msp.writeError != nil {
-		return msp.writeError
-	}
-
 	// Simulate writing display commands
 	displayData := fmt.Sprintf("%s\n%s", line1, line2)
 	return msp.Write([]byte(displayData))
@@ -385,6 +320,9 @@ func (msp *MockSerialPort) WriteText(line1, line2 string, col, row int) error {
 
 // ReadAvailable reads available data from the mock serial port
 func (msp *MockSerialPort) ReadAvailable() ([]byte, error) {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	if msp.closed {
 		return nil, fmt.Errorf("serial port is closed")
 	}
@@ -407,17 +345,26 @@ func (msp *MockSerialPort) ReadAvailable() ([]byte, error) {
 
 // IsConnected returns whether the mock serial port is connected
 func (msp *MockSerialPort) IsConnected() bool {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	return !msp.closed
 }
 
 // Close simulates closing the serial port
 func (msp *MockSerialPort) Close() error {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	msp.closed = true
 	return nil
 }
 
 // GetWrittenData returns all data written to the mock serial port
 func (msp *MockSerialPort) GetWrittenData() []byte {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	result := make([]byte, len(msp.writeBuffer))
 	copy(result, msp.writeBuffer)
 	return result
@@ -425,11 +372,17 @@ func (msp *MockSerialPort) GetWrittenData() []byte {
 
 // ClearWrittenData clears the write buffer
 func (msp *MockSerialPort) ClearWrittenData() {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	msp.writeBuffer = msp.writeBuffer[:0]
 }
 
 // IsOpen returns whether the mock serial port is open
 func (msp *MockSerialPort) IsOpen() bool {
+	msp.mutex.Lock()
+	defer msp.mutex.Unlock()
+
 	return !msp.closed
 }
 
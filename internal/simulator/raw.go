@@ -0,0 +1,54 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// IsTerminal reports whether fd is a terminal
+func IsTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	return err == nil
+}
+
+// MakeRaw switches the terminal at fd to raw mode, so every key is read as
+// it is pressed, without echo and without Ctrl-C raising a signal. The
+// returned function restores the previous mode.
+func MakeRaw(fd int) (func() error, error) {
+	saved, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, fmt.Errorf("not a terminal: %w", err)
+	}
+
+	raw := *saved
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return nil, fmt.Errorf("failed to switch the terminal to raw mode: %w", err)
+	}
+	return func() error {
+		return unix.IoctlSetTermios(fd, unix.TCSETS, saved)
+	}, nil
+}
+
+// LogTo points stderr at a new file in dir, so log lines written while the
+// panel is drawn do not scroll it away, and returns the file's path
+func LogTo(dir string) (string, error) {
+	file, err := os.CreateTemp(dir, "qnap-display-simulate-*.log")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the log file: %w", err)
+	}
+	defer file.Close()
+
+	if err := unix.Dup3(int(file.Fd()), 2, 0); err != nil {
+		return "", fmt.Errorf("failed to redirect the log: %w", err)
+	}
+	return file.Name(), nil
+}
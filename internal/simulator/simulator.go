@@ -0,0 +1,251 @@
+// Package simulator shows a simulated front panel in a terminal: the display,
+// the status, USB and disk LEDs, and the buttons, pressed with keys.
+// It lets menu configurations be tried on a machine without a QNAP panel.
+//
+// The terminal is redrawn whenever the panel or an LED changed. Keys are
+// read one at a time, so the terminal has to be in raw mode (see MakeRaw).
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/pkg/led"
+)
+
+// refreshInterval is how often the panel is checked for changes
+const refreshInterval = 100 * time.Millisecond
+
+// LongPress is how long a button is held for the long press keys, longer
+// than the menu's long press
+const LongPress = 1500 * time.Millisecond
+
+// keyHelp explains the keys below the panel
+const keyHelp = "ENTER: Enter or e   SELECT: Space or s   COPY: c\r\n" +
+	"Long press: E or S   Quit: q or Ctrl-C\r\n"
+
+// Terminal control sequences
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	dim         = "\x1b[2m"
+	reset       = "\x1b[0m"
+)
+
+// ctrlC is the byte Ctrl-C sends in raw mode
+const ctrlC = 0x03
+
+// glyphSymbols stand in for the panel's custom characters
+var glyphSymbols = map[string]string{
+	"gear":    "*",
+	"disk":    "o",
+	"network": "#",
+	"power":   "!",
+	"wrench":  "%",
+	"bar1":    "▂",
+	"bar2":    "▅",
+	"bar3":    "█",
+}
+
+// Panel is the simulated hardware a Terminal shows and presses
+type Panel interface {
+	Lines() []string
+	Backlight() bool
+	Press(button controller.PanelButton) error
+	Release(button controller.PanelButton) error
+}
+
+// Terminal draws a simulated panel and presses its buttons for keys
+type Terminal struct {
+	panel Panel
+	leds  led.Controller
+	in    io.Reader
+	out   io.Writer
+	// hold is how long the long press keys hold a button
+	hold time.Duration
+
+	// holds waits for the releases of long presses
+	holds sync.WaitGroup
+}
+
+// NewTerminal creates a terminal reading keys from in and drawing on out.
+// leds may be nil for a panel without LEDs.
+func NewTerminal(panel Panel, leds led.Controller, in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{panel: panel, leds: leds, in: in, out: out, hold: LongPress}
+}
+
+// Run draws the panel until the quit key is pressed, in is closed or ctx is
+// cancelled. A read of in still waiting when ctx is cancelled is left
+// behind, so in should be the process's terminal.
+func (t *Terminal) Run(ctx context.Context) error {
+	defer t.holds.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan byte)
+	readErr := make(chan error, 1)
+	go func() {
+		buffer := make([]byte, 16)
+		for {
+			n, err := t.in.Read(buffer)
+			for _, key := range buffer[:n] {
+				select {
+				case keys <- key:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	shown := ""
+	for {
+		if screen := t.Render(); screen != shown {
+			if _, err := io.WriteString(t.out, clearScreen+screen); err != nil {
+				return fmt.Errorf("failed to draw the panel: %w", err)
+			}
+			shown = screen
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read keys: %w", err)
+		case key := <-keys:
+			if quit, err := t.key(key); quit || err != nil {
+				return err
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// key presses the button of a key and reports whether it was the quit key
+func (t *Terminal) key(key byte) (bool, error) {
+	switch key {
+	case '\r', '\n', 'e':
+		return false, t.click(controller.ButtonEnter)
+	case ' ', 's':
+		return false, t.click(controller.ButtonSelect)
+	case 'c':
+		return false, t.click(controller.ButtonUSBCopy)
+	case 'E':
+		return false, t.longPress(controller.ButtonEnter)
+	case 'S':
+		return false, t.longPress(controller.ButtonSelect)
+	case 'q', ctrlC:
+		return true, nil
+	}
+	return false, nil
+}
+
+// click presses and releases a button
+func (t *Terminal) click(button controller.PanelButton) error {
+	if err := t.panel.Press(button); err != nil {
+		return err
+	}
+	return t.panel.Release(button)
+}
+
+// longPress presses a button and releases it after the hold time, while
+// other keys are still read
+func (t *Terminal) longPress(button controller.PanelButton) error {
+	if err := t.panel.Press(button); err != nil {
+		return err
+	}
+	t.holds.Add(1)
+	time.AfterFunc(t.hold, func() {
+		defer t.holds.Done()
+		t.panel.Release(button)
+	})
+	return nil
+}
+
+// Render returns the panel as drawn: the display framed, dimmed while the
+// backlight is off, the LEDs and the keys
+func (t *Terminal) Render() string {
+	lines := t.panel.Lines()
+	width := 0
+	for i, line := range lines {
+		lines[i] = ShowGlyphs(line)
+		width = max(width, len([]rune(lines[i])))
+	}
+
+	var screen strings.Builder
+	border := "+" + strings.Repeat("-", width) + "+\r\n"
+	screen.WriteString(border)
+	for _, line := range lines {
+		line += strings.Repeat(" ", width-len([]rune(line)))
+		if !t.panel.Backlight() {
+			line = dim + line + reset
+		}
+		screen.WriteString("|" + line + "|\r\n")
+	}
+	screen.WriteString(border)
+	if !t.panel.Backlight() {
+		screen.WriteString("Backlight off\r\n")
+	}
+	screen.WriteString("\r\n")
+	if t.leds != nil {
+		screen.WriteString(renderLEDs(t.leds) + "\r\n\r\n")
+	}
+	screen.WriteString(keyHelp)
+	return screen.String()
+}
+
+// renderLEDs shows the LED states, e.g. "Status green  USB [ ]  Disks [*][ ]..."
+func renderLEDs(leds led.Controller) string {
+	states, err := leds.GetLEDStates()
+	if err != nil {
+		return "LEDs unavailable"
+	}
+	lamp := func(on bool) string {
+		if on {
+			return "[*]"
+		}
+		return "[ ]"
+	}
+
+	status := "off"
+	switch {
+	case states[led.StatusRed] && states[led.StatusGreen]:
+		status = "orange"
+	case states[led.StatusRed]:
+		status = "red"
+	case states[led.StatusGreen]:
+		status = "green"
+	}
+	text := fmt.Sprintf("Status %-6s  USB %s  Disks ", status, lamp(states[led.USB]))
+	for disk := led.Disk1; disk <= led.Disk6; disk++ {
+		text += lamp(states[disk])
+	}
+	return text
+}
+
+// ShowGlyphs replaces the panel's custom characters with symbols a terminal
+// has
+func ShowGlyphs(line string) string {
+	for _, name := range controller.GlyphNames() {
+		char, _ := controller.GlyphChar(name)
+		symbol, ok := glyphSymbols[name]
+		if !ok {
+			symbol = "?"
+		}
+		line = strings.ReplaceAll(line, char, symbol)
+	}
+	return line
+}
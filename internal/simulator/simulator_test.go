@@ -0,0 +1,129 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/pkg/led"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePanel records the button changes
+type fakePanel struct {
+	mutex     sync.Mutex
+	lines     []string
+	backlight bool
+	changes   []string
+}
+
+func (p *fakePanel) Lines() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.lines...)
+}
+
+func (p *fakePanel) Backlight() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.backlight
+}
+
+func (p *fakePanel) Press(button controller.PanelButton) error {
+	p.record("+" + button.String())
+	return nil
+}
+
+func (p *fakePanel) Release(button controller.PanelButton) error {
+	p.record("-" + button.String())
+	return nil
+}
+
+func (p *fakePanel) record(change string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.changes = append(p.changes, change)
+}
+
+func (p *fakePanel) recorded() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.changes...)
+}
+
+func TestTerminal_Render(t *testing.T) {
+	gear, _ := controller.GlyphChar("gear")
+	panel := &fakePanel{lines: []string{"QNAP Control    ", ">" + gear + "System        "}, backlight: true}
+	leds := led.NewMock()
+	require.NoError(t, leds.SetStatusLED(false, true))
+	require.NoError(t, leds.SetDiskLEDs(map[int]bool{2: true}))
+	terminal := NewTerminal(panel, leds, nil, nil)
+
+	screen := terminal.Render()
+	assert.Equal(t, "+----------------+\r\n"+
+		"|QNAP Control    |\r\n"+
+		"|>*System        |\r\n"+
+		"+----------------+\r\n"+
+		"\r\n"+
+		"Status green   USB [ ]  Disks [ ][*][ ][ ][ ][ ]\r\n"+
+		"\r\n"+
+		keyHelp, screen)
+
+	panel.backlight = false
+	screen = terminal.Render()
+	assert.Contains(t, screen, "|"+dim+"QNAP Control    "+reset+"|")
+	assert.Contains(t, screen, "Backlight off")
+
+	// Without LEDs only the display and the keys are drawn
+	assert.NotContains(t, NewTerminal(panel, nil, nil, nil).Render(), "Status")
+}
+
+func TestTerminal_Run(t *testing.T) {
+	t.Run("Keys press buttons", func(t *testing.T) {
+		panel := &fakePanel{lines: []string{"", ""}}
+		var out bytes.Buffer
+		terminal := NewTerminal(panel, nil, strings.NewReader("e\r sxcq e"), &out)
+
+		require.NoError(t, terminal.Run(context.Background()))
+		assert.Equal(t, []string{
+			"+enter", "-enter", "+enter", "-enter",
+			"+select", "-select", "+select", "-select",
+			"+copy", "-copy",
+		}, panel.recorded(), "keys after q are not read")
+		assert.True(t, strings.HasPrefix(out.String(), clearScreen))
+	})
+
+	t.Run("Long press", func(t *testing.T) {
+		panel := &fakePanel{lines: []string{"", ""}}
+		terminal := NewTerminal(panel, nil, strings.NewReader("S"), &bytes.Buffer{})
+		terminal.hold = 50 * time.Millisecond
+
+		start := time.Now()
+		require.NoError(t, terminal.Run(context.Background()))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "the release is waited for")
+		assert.Equal(t, []string{"+select", "-select"}, panel.recorded())
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		panel := &fakePanel{lines: []string{"", ""}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		// No key ever arrives, so only the context ends the run
+		in := blockingReader{done: make(chan struct{})}
+		defer close(in.done)
+		require.NoError(t, NewTerminal(panel, nil, in, &bytes.Buffer{}).Run(ctx))
+	})
+}
+
+// blockingReader blocks every read until closed
+type blockingReader struct{ done chan struct{} }
+
+func (r blockingReader) Read(p []byte) (int, error) {
+	<-r.done
+	return 0, context.Canceled
+}
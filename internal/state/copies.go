@@ -0,0 +1,118 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// copiesKey is the store section holding the copy counters
+const copiesKey = "usb_copies"
+
+// DeviceCopies counts the copies of one USB device
+type DeviceCopies struct {
+	// Copies is the number of successful copies
+	Copies int `json:"copies"`
+	// Bytes is the total of the new data over all copies
+	Bytes uint64 `json:"bytes"`
+	// LastSize is how much data the device held at its last copy
+	LastSize uint64    `json:"last_size"`
+	LastCopy time.Time `json:"last_copy"`
+}
+
+// CopyCounters tracks the copies of each USB device by its filesystem UUID
+type CopyCounters struct {
+	store *Store
+
+	mutex sync.Mutex
+}
+
+// NewCopyCounters keeps the counters in store
+func NewCopyCounters(store *Store) *CopyCounters {
+	return &CopyCounters{store: store}
+}
+
+// Get returns the counters of a device, zero if it was never copied
+func (c *CopyCounters) Get(uuid string) (DeviceCopies, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	devices, err := c.load()
+	if err != nil {
+		return DeviceCopies{}, err
+	}
+	return devices[uuid], nil
+}
+
+// Record counts a successful copy of a device holding size bytes. It returns
+// the updated counters and how much of the data is new, that is how much the
+// device grew since its last copy; everything is new on the first copy.
+func (c *CopyCounters) Record(uuid string, size uint64) (DeviceCopies, uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	devices, err := c.load()
+	if err != nil {
+		return DeviceCopies{}, 0, err
+	}
+	device := devices[uuid]
+	var fresh uint64
+	if size > device.LastSize {
+		fresh = size - device.LastSize
+	}
+
+	device.Copies++
+	device.Bytes += fresh
+	device.LastSize = size
+	device.LastCopy = time.Now()
+	devices[uuid] = device
+	if err := c.store.Put(copiesKey, devices); err != nil {
+		return DeviceCopies{}, 0, err
+	}
+	return device, fresh, nil
+}
+
+// load reads all counters from the store
+func (c *CopyCounters) load() (map[string]DeviceCopies, error) {
+	devices := make(map[string]DeviceCopies)
+	if _, err := c.store.Get(copiesKey, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// Ordinal renders a count as "1st", "2nd", "3rd", "4th", "11th" and so on
+func Ordinal(n int) string {
+	suffix := "th"
+	switch n % 100 {
+	case 11, 12, 13:
+	default:
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// CopySummary renders the copy count and the new data, e.g. "3rd copy, 2.4GB
+// new", dropping words until it fits the width
+func CopySummary(copies int, fresh uint64, width int) string {
+	ordinal, size := Ordinal(copies), sysinfo.FormatBytes(fresh)+"B"
+	for _, summary := range []string{
+		fmt.Sprintf("%s copy, %s new", ordinal, size),
+		fmt.Sprintf("%s copy %s new", ordinal, size),
+		fmt.Sprintf("%s, %s new", ordinal, size),
+	} {
+		if len(summary) <= width {
+			return summary
+		}
+	}
+	return ordinal + " " + size
+}
@@ -0,0 +1,39 @@
+package state
+
+import "sync"
+
+// ledsKey is the store section holding the LED states
+const ledsKey = "leds"
+
+// LEDStates keeps the last state of every panel LED, by LED name, so the
+// LEDs show what they showed before a restart
+type LEDStates struct {
+	store *Store
+
+	mutex sync.Mutex
+}
+
+// NewLEDStates keeps the LED states in store
+func NewLEDStates(store *Store) *LEDStates {
+	return &LEDStates{store: store}
+}
+
+// Load returns the kept states; empty if none were kept yet
+func (l *LEDStates) Load() (map[string]bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	states := make(map[string]bool)
+	if _, err := l.store.Get(ledsKey, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// Save replaces the kept states
+func (l *LEDStates) Save(states map[string]bool) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.store.Put(ledsKey, states)
+}
@@ -0,0 +1,28 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLEDStates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(path)
+	require.NoError(t, err)
+	leds := NewLEDStates(store)
+
+	states, err := leds.Load()
+	require.NoError(t, err)
+	assert.Empty(t, states, "nothing kept yet")
+
+	require.NoError(t, leds.Save(map[string]bool{"status-red": true, "usb": false}))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	states, err = NewLEDStates(reopened).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"status-red": true, "usb": false}, states)
+}
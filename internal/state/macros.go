@@ -0,0 +1,100 @@
+package state
+
+import (
+	"sort"
+	"sync"
+)
+
+// macrosKey is the store section holding the button macros
+const macrosKey = "macros"
+
+// Macro is a recorded sequence of button presses, replayed from the main
+// menu
+type Macro struct {
+	Name string `json:"name"`
+	// Buttons are the presses in order, "enter" or "select"
+	Buttons []string `json:"buttons"`
+	// Gesture replays the macro at the main menu, e.g. "long_select"
+	Gesture string `json:"gesture,omitempty"`
+}
+
+// Macros keeps the button macros by name
+type Macros struct {
+	store *Store
+
+	mutex sync.Mutex
+}
+
+// NewMacros keeps the macros in store
+func NewMacros(store *Store) *Macros {
+	return &Macros{store: store}
+}
+
+// List returns all macros sorted by name
+func (m *Macros) List() ([]Macro, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	macros, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Macro, 0, len(macros))
+	for _, macro := range macros {
+		list = append(list, macro)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns the macro of the given name. It reports false if there is
+// none.
+func (m *Macros) Get(name string) (Macro, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	macros, err := m.load()
+	if err != nil {
+		return Macro{}, false, err
+	}
+	macro, ok := macros[name]
+	return macro, ok, nil
+}
+
+// Save adds a macro or replaces the one of the same name
+func (m *Macros) Save(macro Macro) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	macros, err := m.load()
+	if err != nil {
+		return err
+	}
+	macros[macro.Name] = macro
+	return m.store.Put(macrosKey, macros)
+}
+
+// Delete removes a macro. It reports false if there was none.
+func (m *Macros) Delete(name string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	macros, err := m.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := macros[name]; !ok {
+		return false, nil
+	}
+	delete(macros, name)
+	return true, m.store.Put(macrosKey, macros)
+}
+
+// load reads all macros from the store
+func (m *Macros) load() (map[string]Macro, error) {
+	macros := make(map[string]Macro)
+	if _, err := m.store.Get(macrosKey, &macros); err != nil {
+		return nil, err
+	}
+	return macros, nil
+}
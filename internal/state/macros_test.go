@@ -0,0 +1,43 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMacros(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(path)
+	require.NoError(t, err)
+	macros := NewMacros(store)
+
+	usage := Macro{Name: "usage", Buttons: []string{"select", "enter", "enter"}, Gesture: "long_select"}
+	require.NoError(t, macros.Save(usage))
+	require.NoError(t, macros.Save(Macro{Name: "ip", Buttons: []string{"enter"}}))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	list, err := NewMacros(reopened).List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "ip", list[0].Name, "sorted by name")
+	assert.Equal(t, usage, list[1])
+
+	found, ok, err := macros.Get("usage")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, usage, found)
+
+	deleted, err := macros.Delete("usage")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = macros.Delete("usage")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	_, ok, err = macros.Get("usage")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
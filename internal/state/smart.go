@@ -0,0 +1,100 @@
+package state
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/sysinfo"
+)
+
+// smartKey is the store section holding the SMART samples
+const smartKey = "smart_samples"
+
+// SMARTWindow is how long SMART samples are kept
+const SMARTWindow = 24 * time.Hour
+
+// SMARTHistory keeps the SMART samples of each drive for SMARTWindow, so
+// trends survive restarts
+type SMARTHistory struct {
+	store *Store
+
+	mutex sync.Mutex
+}
+
+// NewSMARTHistory keeps the samples in store
+func NewSMARTHistory(store *Store) *SMARTHistory {
+	return &SMARTHistory{store: store}
+}
+
+// Record adds a sample of drive and drops the drive's samples older than
+// SMARTWindow
+func (h *SMARTHistory) Record(drive string, sample sysinfo.SMARTSample) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	drives, err := h.load()
+	if err != nil {
+		return err
+	}
+	cutoff := sample.Time.Add(-SMARTWindow)
+	var kept []sysinfo.SMARTSample
+	for _, old := range drives[drive] {
+		if old.Time.After(cutoff) {
+			kept = append(kept, old)
+		}
+	}
+	drives[drive] = append(kept, sample)
+	return h.store.Put(smartKey, drives)
+}
+
+// Samples returns the samples of drive, oldest first
+func (h *SMARTHistory) Samples(drive string) ([]sysinfo.SMARTSample, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	drives, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	return drives[drive], nil
+}
+
+// load reads all samples from the store
+func (h *SMARTHistory) load() (map[string][]sysinfo.SMARTSample, error) {
+	drives := make(map[string][]sysinfo.SMARTSample)
+	if _, err := h.store.Get(smartKey, &drives); err != nil {
+		return nil, err
+	}
+	return drives, nil
+}
+
+// SMARTTrend splits the SMARTWindow before now into cells of equal length
+// and returns the highest value of metric sampled in each, oldest first.
+// Cells without a sample are NaN.
+func SMARTTrend(samples []sysinfo.SMARTSample, metric string, now time.Time, cells int) []float64 {
+	trend := make([]float64, cells)
+	for i := range trend {
+		trend[i] = math.NaN()
+	}
+	if cells <= 0 {
+		return trend
+	}
+
+	start := now.Add(-SMARTWindow)
+	cell := SMARTWindow / time.Duration(cells)
+	for _, sample := range samples {
+		value, ok := sample.Value(metric)
+		if !ok || !sample.Time.After(start) || sample.Time.After(now) {
+			continue
+		}
+		i := int(sample.Time.Sub(start) / cell)
+		if i >= cells {
+			i = cells - 1
+		}
+		if math.IsNaN(trend[i]) || value > trend[i] {
+			trend[i] = value
+		}
+	}
+	return trend
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/sysinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func temperature(at time.Time, value float64) sysinfo.SMARTSample {
+	return sysinfo.SMARTSample{Time: at, Values: map[string]float64{sysinfo.SMARTTemperature: value}}
+}
+
+func TestSMARTHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(path)
+	require.NoError(t, err)
+	history := NewSMARTHistory(store)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, history.Record("sda", temperature(now.Add(-25*time.Hour), 30)))
+	require.NoError(t, history.Record("sda", temperature(now.Add(-time.Hour), 35)))
+	require.NoError(t, history.Record("sda", temperature(now, 38)))
+	require.NoError(t, history.Record("sdb", temperature(now, 40)))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	samples, err := NewSMARTHistory(reopened).Samples("sda")
+	require.NoError(t, err)
+	assert.Equal(t, []sysinfo.SMARTSample{temperature(now.Add(-time.Hour), 35), temperature(now, 38)}, samples,
+		"samples older than a day are dropped")
+
+	samples, err = history.Samples("sdc")
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func TestSMARTTrend(t *testing.T) {
+	now := time.Now()
+	samples := []sysinfo.SMARTSample{
+		temperature(now.Add(-25*time.Hour), 99),
+		temperature(now.Add(-23*time.Hour), 31),
+		temperature(now.Add(-22*time.Hour), 33),
+		{Time: now.Add(-7 * time.Hour), Values: map[string]float64{sysinfo.SMARTReallocated: 8}},
+		temperature(now.Add(-time.Minute), 38),
+	}
+
+	trend := SMARTTrend(samples, sysinfo.SMARTTemperature, now, 4)
+	require.Len(t, trend, 4)
+	assert.Equal(t, 33.0, trend[0], "the highest value of a cell, the sample from before the window left out")
+	assert.True(t, math.IsNaN(trend[1]))
+	assert.True(t, math.IsNaN(trend[2]), "samples without the metric are gaps")
+	assert.Equal(t, 38.0, trend[3])
+}
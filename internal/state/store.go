@@ -0,0 +1,116 @@
+// Package state keeps small pieces of data across restarts, such as how often
+// each USB device was copied.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultPath is where the state is kept when the configuration does not say
+const DefaultPath = "/var/lib/qnap-display/state.json"
+
+// Store is a JSON file of named sections. Every change is written through to
+// disk, replacing the file atomically so a crash never leaves half of it.
+type Store struct {
+	path string
+
+	mutex    sync.Mutex
+	sections map[string]json.RawMessage
+}
+
+// Open loads the store at path. A missing file is an empty store; it is
+// created with its directory on the first change.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, sections: make(map[string]json.RawMessage)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.sections); err != nil {
+		return nil, fmt.Errorf("malformed state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get decodes the section key into value. It reports false, leaving value
+// alone, if there is no such section.
+func (s *Store) Get(key string, value any) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, ok := s.sections[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("malformed state %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// Put replaces the section key with value and saves the store
+func (s *Store) Put(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %q: %w", key, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, existed := s.sections[key]
+	s.sections[key] = data
+	if err := s.save(); err != nil {
+		if existed {
+			s.sections[key] = previous
+		} else {
+			delete(s.sections, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// save writes the sections to a temporary file next to the store and renames
+// it over the old one
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.sections, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(append(data, '\n')); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib", "state.json")
+	store, err := Open(path)
+	require.NoError(t, err, "a missing file is an empty store")
+
+	var value map[string]int
+	found, err := store.Get("counts", &value)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, store.Put("counts", map[string]int{"a": 1}))
+	require.NoError(t, store.Put("name", "nas1"))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	found, err = reopened.Get("counts", &value)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]int{"a": 1}, value)
+	var name string
+	_, err = reopened.Get("name", &name)
+	require.NoError(t, err)
+	assert.Equal(t, "nas1", name)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestOpenMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err := Open(path)
+	assert.Error(t, err)
+}
+
+func TestCopyCounters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(path)
+	require.NoError(t, err)
+	counters := NewCopyCounters(store)
+
+	device, fresh, err := counters.Record("1234-ABCD", 10<<30)
+	require.NoError(t, err)
+	assert.Equal(t, 1, device.Copies)
+	assert.Equal(t, uint64(10<<30), fresh, "everything is new on the first copy")
+
+	device, fresh, err = counters.Record("1234-ABCD", 12<<30)
+	require.NoError(t, err)
+	assert.Equal(t, 2, device.Copies)
+	assert.Equal(t, uint64(2<<30), fresh)
+	assert.Equal(t, uint64(12<<30), device.Bytes)
+
+	// Deleting files from the device brings nothing new
+	_, fresh, err = counters.Record("1234-ABCD", 4<<30)
+	require.NoError(t, err)
+	assert.Zero(t, fresh)
+
+	// The counters survive a restart
+	store, err = Open(path)
+	require.NoError(t, err)
+	device, err = NewCopyCounters(store).Get("1234-ABCD")
+	require.NoError(t, err)
+	assert.Equal(t, 3, device.Copies)
+	assert.Equal(t, uint64(4<<30), device.LastSize)
+
+	device, err = NewCopyCounters(store).Get("other")
+	require.NoError(t, err)
+	assert.Zero(t, device.Copies)
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, expected := range map[int]string{
+		1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th",
+		21: "21st", 22: "22nd", 101: "101st", 111: "111th",
+	} {
+		assert.Equal(t, expected, Ordinal(n))
+	}
+}
+
+func TestCopySummary(t *testing.T) {
+	fresh := uint64(24<<30) / 10
+	assert.Equal(t, "3rd copy, 2.4GB new", CopySummary(3, fresh, 20))
+	assert.Equal(t, "3rd, 2.4GB new", CopySummary(3, fresh, 16))
+	assert.Equal(t, "1st copy, 0B new", CopySummary(1, 0, 16))
+	assert.Equal(t, "112th 2.4GB", CopySummary(112, fresh, 12))
+}
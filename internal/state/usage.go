@@ -0,0 +1,175 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// usageKey is the store section holding the panel usage statistics
+const usageKey = "usage"
+
+// PauseBounds are the upper bounds of the pause histogram buckets; the last
+// bucket takes the pauses above the last bound
+var PauseBounds = []time.Duration{
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	45 * time.Second,
+	time.Minute,
+	90 * time.Second,
+	2 * time.Minute,
+	3 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+}
+
+// DefaultIdleTimeout is the idle timeout when the configuration sets none
+const DefaultIdleTimeout = 5 * time.Minute
+
+// Limits of the suggested idle timeout
+const (
+	MinIdleTimeout = 30 * time.Second
+	MaxIdleTimeout = 30 * time.Minute
+)
+
+// minPauses is how many pauses have to be counted before an idle timeout is
+// suggested
+const minPauses = 50
+
+// idleCoverage is the share of the pauses between presses the suggested
+// idle timeout waits out, so the panel rarely goes idle while in use
+const idleCoverage = 0.95
+
+// UsageStats counts how the panel is used, without anything to tell the
+// presses apart: neither the buttons nor the days
+type UsageStats struct {
+	// Hours counts the button presses by hour of the day, local time
+	Hours [24]int `json:"hours"`
+	// Pauses counts the pauses between one press and the next by
+	// PauseBounds; pauses above the last bound are counted last
+	Pauses []int `json:"pauses"`
+}
+
+// Presses returns how many presses were counted
+func (u UsageStats) Presses() int {
+	total := 0
+	for _, count := range u.Hours {
+		total += count
+	}
+	return total
+}
+
+// BusiestHour returns the hour of the day with the most presses; false if
+// none were counted
+func (u UsageStats) BusiestHour() (int, bool) {
+	busiest := 0
+	for hour, count := range u.Hours {
+		if count > u.Hours[busiest] {
+			busiest = hour
+		}
+	}
+	return busiest, u.Hours[busiest] > 0
+}
+
+// SuggestIdleTimeout returns the shortest bucket bound most pauses between
+// presses stay below, between MinIdleTimeout and MaxIdleTimeout. Pauses
+// above MaxIdleTimeout are not counted: the panel was left alone. It
+// reports false until enough pauses are counted.
+func (u UsageStats) SuggestIdleTimeout() (time.Duration, bool) {
+	total := 0
+	for i, count := range u.Pauses {
+		if i < len(PauseBounds) && PauseBounds[i] <= MaxIdleTimeout {
+			total += count
+		}
+	}
+	if total < minPauses {
+		return 0, false
+	}
+
+	covered := 0
+	for i, bound := range PauseBounds {
+		if i < len(u.Pauses) {
+			covered += u.Pauses[i]
+		}
+		if float64(covered) >= idleCoverage*float64(total) {
+			return min(max(bound, MinIdleTimeout), MaxIdleTimeout), true
+		}
+	}
+	return MaxIdleTimeout, true
+}
+
+// Usage keeps the panel usage statistics. Presses are counted in memory and
+// written by Save, so pressing a button does not write the store.
+type Usage struct {
+	store *Store
+
+	mutex sync.Mutex
+	stats UsageStats
+	// last is the time of the previous press; zero after a restart, so the
+	// downtime is not counted as a pause
+	last  time.Time
+	dirty bool
+}
+
+// NewUsage loads the statistics kept in store
+func NewUsage(store *Store) (*Usage, error) {
+	u := &Usage{store: store}
+	if _, err := store.Get(usageKey, &u.stats); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Record counts a button press at the given time
+func (u *Usage) Record(at time.Time) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.stats.Hours[at.Hour()]++
+	if !u.last.IsZero() && at.After(u.last) {
+		if len(u.stats.Pauses) < len(PauseBounds)+1 {
+			pauses := make([]int, len(PauseBounds)+1)
+			copy(pauses, u.stats.Pauses)
+			u.stats.Pauses = pauses
+		}
+		pause := at.Sub(u.last)
+		bucket := len(PauseBounds)
+		for i, bound := range PauseBounds {
+			if pause <= bound {
+				bucket = i
+				break
+			}
+		}
+		u.stats.Pauses[bucket]++
+	}
+	u.last = at
+	u.dirty = true
+}
+
+// Stats returns a copy of the statistics
+func (u *Usage) Stats() UsageStats {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	stats := u.stats
+	stats.Pauses = append([]int(nil), u.stats.Pauses...)
+	return stats
+}
+
+// Save writes the statistics if presses were counted since the last Save
+func (u *Usage) Save() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !u.dirty {
+		return nil
+	}
+	if err := u.store.Put(usageKey, u.stats); err != nil {
+		return err
+	}
+	u.dirty = false
+	return nil
+}
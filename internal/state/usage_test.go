@@ -0,0 +1,86 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(path)
+	require.NoError(t, err)
+	usage, err := NewUsage(store)
+	require.NoError(t, err)
+
+	morning := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	usage.Record(morning)
+	usage.Record(morning.Add(3 * time.Second))
+	usage.Record(morning.Add(time.Minute))
+	usage.Record(morning.Add(10 * time.Hour))
+
+	stats := usage.Stats()
+	assert.Equal(t, 4, stats.Presses())
+	assert.Equal(t, 3, stats.Hours[9])
+	assert.Equal(t, 1, stats.Hours[19])
+	hour, ok := stats.BusiestHour()
+	assert.True(t, ok)
+	assert.Equal(t, 9, hour)
+	require.Len(t, stats.Pauses, len(PauseBounds)+1)
+	assert.Equal(t, 1, stats.Pauses[0], "3s")
+	assert.Equal(t, 1, stats.Pauses[5], "57s")
+	assert.Equal(t, 1, stats.Pauses[len(PauseBounds)], "10h")
+
+	// Kept across restarts, without the pause over the downtime
+	require.NoError(t, usage.Save())
+	store, err = Open(path)
+	require.NoError(t, err)
+	usage, err = NewUsage(store)
+	require.NoError(t, err)
+	usage.Record(morning.Add(24 * time.Hour))
+	stats = usage.Stats()
+	assert.Equal(t, 4, stats.Hours[9])
+	assert.Equal(t, 3, stats.Pauses[0]+stats.Pauses[5]+stats.Pauses[len(PauseBounds)])
+}
+
+func TestUsageStats_SuggestIdleTimeout(t *testing.T) {
+	pauses := func(counts map[time.Duration]int) UsageStats {
+		stats := UsageStats{Pauses: make([]int, len(PauseBounds)+1)}
+		for bound, count := range counts {
+			for i, b := range PauseBounds {
+				if b == bound {
+					stats.Pauses[i] = count
+				}
+			}
+		}
+		return stats
+	}
+
+	_, ok := UsageStats{}.SuggestIdleTimeout()
+	assert.False(t, ok, "no pauses")
+	_, ok = pauses(map[time.Duration]int{5 * time.Second: 10}).SuggestIdleTimeout()
+	assert.False(t, ok, "too few pauses")
+
+	// 95% of the pauses are up to 2 minutes
+	timeout, ok := pauses(map[time.Duration]int{
+		5 * time.Second: 60,
+		time.Minute:     30,
+		2 * time.Minute: 5,
+		5 * time.Minute: 5,
+	}).SuggestIdleTimeout()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, timeout)
+
+	// Quick presses only still leave MinIdleTimeout
+	timeout, _ = pauses(map[time.Duration]int{5 * time.Second: 100}).SuggestIdleTimeout()
+	assert.Equal(t, MinIdleTimeout, timeout)
+
+	// Pauses longer than MaxIdleTimeout are the panel left alone
+	stats := pauses(map[time.Duration]int{10 * time.Second: 100})
+	stats.Pauses[len(PauseBounds)] = 1000
+	timeout, _ = stats.SuggestIdleTimeout()
+	assert.Equal(t, MinIdleTimeout, timeout)
+}
@@ -0,0 +1,182 @@
+// Package sysinfo reads system state for the status screens.
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Governors offered by the governor toggle
+const (
+	GovernorPowersave   = "powersave"
+	GovernorPerformance = "performance"
+)
+
+// CPUStatus is a snapshot of the CPU frequency scaling state
+type CPUStatus struct {
+	// FrequencyMHz is the current frequency of the first CPU
+	FrequencyMHz int
+	// Governor is the scaling governor of the first CPU
+	Governor string
+	// Throttled reports whether thermal throttling happened since the last snapshot
+	Throttled bool
+	// ThrottleKnown is false when the kernel exposes no throttle counters
+	ThrottleKnown bool
+}
+
+// Render formats the status for the 16x2 panel: frequency on the first line,
+// governor and a throttling marker on the second
+func (s CPUStatus) Render() string {
+	governor := s.Governor
+	if len(governor) > 11 {
+		governor = governor[:11]
+	}
+	line2 := governor
+	if s.Throttled {
+		line2 = fmt.Sprintf("%-11s THRT", governor)
+	}
+	return fmt.Sprintf("CPU %d MHz\n%s", s.FrequencyMHz, line2)
+}
+
+// CPUProvider reads CPU frequency, governor and throttling from sysfs
+type CPUProvider struct {
+	root string
+
+	// mutex guards throttleCounts, the counter values seen by the last snapshot
+	mutex          sync.Mutex
+	throttleCounts map[string]int64
+}
+
+// NewCPUProvider creates a provider reading sysfs below root ("" = /sys)
+func NewCPUProvider(root string) *CPUProvider {
+	if root == "" {
+		root = "/sys"
+	}
+	return &CPUProvider{root: root}
+}
+
+// cpuDir returns the sysfs directory of the CPUs
+func (p *CPUProvider) cpuDir() string {
+	return filepath.Join(p.root, "devices", "system", "cpu")
+}
+
+// Status takes a snapshot of the CPU state
+func (p *CPUProvider) Status() (CPUStatus, error) {
+	cpufreq := filepath.Join(p.cpuDir(), "cpu0", "cpufreq")
+
+	khz, err := readInt(filepath.Join(cpufreq, "scaling_cur_freq"))
+	if err != nil {
+		return CPUStatus{}, fmt.Errorf("cpu frequency scaling not available: %w", err)
+	}
+	governor, err := readString(filepath.Join(cpufreq, "scaling_governor"))
+	if err != nil {
+		return CPUStatus{}, fmt.Errorf("failed to read cpu governor: %w", err)
+	}
+
+	status := CPUStatus{
+		FrequencyMHz: int(khz / 1000),
+		Governor:     governor,
+	}
+	status.Throttled, status.ThrottleKnown = p.throttled()
+	return status, nil
+}
+
+// throttled compares the thermal throttle counters with the last snapshot.
+// The first snapshot only records the counters and reports no throttling.
+func (p *CPUProvider) throttled() (throttled bool, known bool) {
+	counters, _ := filepath.Glob(filepath.Join(p.cpuDir(), "cpu[0-9]*", "thermal_throttle", "*_throttle_count"))
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	first := p.throttleCounts == nil
+	counts := make(map[string]int64, len(counters))
+	for _, counter := range counters {
+		count, err := readInt(counter)
+		if err != nil {
+			continue
+		}
+		counts[counter] = count
+		if previous, seen := p.throttleCounts[counter]; !first && (!seen || count > previous) {
+			throttled = true
+		}
+	}
+	p.throttleCounts = counts
+
+	return throttled, len(counts) > 0
+}
+
+// Governors returns the governors the first CPU can switch to
+func (p *CPUProvider) Governors() ([]string, error) {
+	available, err := readString(filepath.Join(p.cpuDir(), "cpu0", "cpufreq", "scaling_available_governors"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read available governors: %w", err)
+	}
+	governors := strings.Fields(available)
+	sort.Strings(governors)
+	return governors, nil
+}
+
+// SetGovernor switches every CPU to the given governor
+func (p *CPUProvider) SetGovernor(governor string) error {
+	governors, err := p.Governors()
+	if err != nil {
+		return err
+	}
+	index := sort.SearchStrings(governors, governor)
+	if index == len(governors) || governors[index] != governor {
+		return fmt.Errorf("governor %q not available (available: %s)", governor, strings.Join(governors, ", "))
+	}
+
+	files, err := filepath.Glob(filepath.Join(p.cpuDir(), "cpu[0-9]*", "cpufreq", "scaling_governor"))
+	if err != nil || len(files) == 0 {
+		return fmt.Errorf("no cpu governors found")
+	}
+	for _, file := range files {
+		if err := os.WriteFile(file, []byte(governor), 0644); err != nil {
+			return fmt.Errorf("failed to set governor: %w", err)
+		}
+	}
+	return nil
+}
+
+// ToggleGovernor switches between powersave and performance and returns the
+// new governor. Any governor other than performance switches to performance.
+func (p *CPUProvider) ToggleGovernor() (string, error) {
+	status, err := p.Status()
+	if err != nil {
+		return "", err
+	}
+
+	next := GovernorPerformance
+	if status.Governor == GovernorPerformance {
+		next = GovernorPowersave
+	}
+	if err := p.SetGovernor(next); err != nil {
+		return "", err
+	}
+	return next, nil
+}
+
+// readString reads a sysfs attribute without the trailing newline
+func readString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readInt reads a numeric sysfs attribute
+func readInt(path string) (int64, error) {
+	value, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
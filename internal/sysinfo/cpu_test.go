@@ -0,0 +1,83 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSysfs builds a sysfs tree with two CPUs below a temporary root
+func fakeSysfs(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for _, cpu := range []string{"cpu0", "cpu1"} {
+		writeAttr(t, root, cpu+"/cpufreq/scaling_cur_freq", "1600000\n")
+		writeAttr(t, root, cpu+"/cpufreq/scaling_governor", "powersave\n")
+		writeAttr(t, root, cpu+"/cpufreq/scaling_available_governors", "performance powersave\n")
+		writeAttr(t, root, cpu+"/thermal_throttle/core_throttle_count", "3\n")
+	}
+	return root
+}
+
+// writeAttr writes a sysfs attribute below the CPU directory
+func writeAttr(t *testing.T, root, name, value string) {
+	t.Helper()
+
+	path := filepath.Join(root, "devices", "system", "cpu", name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(value), 0644))
+}
+
+func TestCPUProvider_Status(t *testing.T) {
+	root := fakeSysfs(t)
+	provider := NewCPUProvider(root)
+
+	status, err := provider.Status()
+	require.NoError(t, err)
+	assert.Equal(t, CPUStatus{FrequencyMHz: 1600, Governor: "powersave", ThrottleKnown: true}, status)
+	assert.Equal(t, "CPU 1600 MHz\npowersave", status.Render())
+
+	// A growing counter means the CPU was throttled since the last snapshot
+	writeAttr(t, root, "cpu1/thermal_throttle/core_throttle_count", "4\n")
+	status, err = provider.Status()
+	require.NoError(t, err)
+	assert.True(t, status.Throttled)
+	assert.Equal(t, "CPU 1600 MHz\npowersave   THRT", status.Render())
+
+	status, err = provider.Status()
+	require.NoError(t, err)
+	assert.False(t, status.Throttled, "throttling ended")
+
+	t.Run("Missing cpufreq", func(t *testing.T) {
+		_, err := NewCPUProvider(t.TempDir()).Status()
+		assert.Error(t, err)
+	})
+}
+
+func TestCPUProvider_Governor(t *testing.T) {
+	root := fakeSysfs(t)
+	provider := NewCPUProvider(root)
+
+	governors, err := provider.Governors()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"performance", "powersave"}, governors)
+
+	governor, err := provider.ToggleGovernor()
+	require.NoError(t, err)
+	assert.Equal(t, GovernorPerformance, governor)
+	for _, cpu := range []string{"cpu0", "cpu1"} {
+		value, err := readString(filepath.Join(root, "devices", "system", "cpu", cpu, "cpufreq", "scaling_governor"))
+		require.NoError(t, err)
+		assert.Equal(t, "performance", value)
+	}
+
+	governor, err = provider.ToggleGovernor()
+	require.NoError(t, err)
+	assert.Equal(t, GovernorPowersave, governor)
+
+	assert.Error(t, provider.SetGovernor("ondemand"))
+}
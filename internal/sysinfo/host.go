@@ -0,0 +1,100 @@
+package sysinfo
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostProvider reads uptime and load from procfs
+type HostProvider struct {
+	root string
+}
+
+// NewHostProvider creates a provider reading procfs at root ("" = /proc)
+func NewHostProvider(root string) *HostProvider {
+	if root == "" {
+		root = "/proc"
+	}
+	return &HostProvider{root: root}
+}
+
+// Uptime returns how long the system has been running
+func (p *HostProvider) Uptime() (time.Duration, error) {
+	uptime, err := readString(filepath.Join(p.root, "uptime"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read uptime: %w", err)
+	}
+	fields := strings.Fields(uptime)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty uptime")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed uptime %q: %w", fields[0], err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// LoadAverage returns the 1, 5 and 15 minute load averages
+func (p *HostProvider) LoadAverage() ([3]float64, error) {
+	var averages [3]float64
+
+	loadavg, err := readString(filepath.Join(p.root, "loadavg"))
+	if err != nil {
+		return averages, fmt.Errorf("failed to read load average: %w", err)
+	}
+	fields := strings.Fields(loadavg)
+	if len(fields) < len(averages) {
+		return averages, fmt.Errorf("malformed load average %q", loadavg)
+	}
+	for i := range averages {
+		if averages[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return averages, fmt.Errorf("malformed load average %q: %w", fields[i], err)
+		}
+	}
+	return averages, nil
+}
+
+// FormatUptime renders an uptime in at most two units, e.g. "3d 4h" or "12m"
+func FormatUptime(uptime time.Duration) string {
+	days := int(uptime / (24 * time.Hour))
+	hours := int(uptime/time.Hour) % 24
+	minutes := int(uptime/time.Minute) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// PrimaryIPv4 returns the first IPv4 address of an interface that is up and
+// not a loopback
+func PrimaryIPv4() (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				return ipNet.IP.String(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no IPv4 address")
+}
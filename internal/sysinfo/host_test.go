@@ -0,0 +1,39 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostProvider(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "uptime"), []byte("273845.71 1059432.12\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "loadavg"), []byte("0.52 0.40 0.31 1/245 12345\n"), 0644))
+	provider := NewHostProvider(root)
+
+	uptime, err := provider.Uptime()
+	require.NoError(t, err)
+	assert.Equal(t, 273845*time.Second, uptime.Truncate(time.Second))
+
+	load, err := provider.LoadAverage()
+	require.NoError(t, err)
+	assert.Equal(t, [3]float64{0.52, 0.40, 0.31}, load)
+
+	_, err = NewHostProvider(t.TempDir()).Uptime()
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "loadavg"), []byte("0.52\n"), 0644))
+	_, err = provider.LoadAverage()
+	assert.Error(t, err)
+}
+
+func TestFormatUptime(t *testing.T) {
+	assert.Equal(t, "3d 4h", FormatUptime(76*time.Hour+5*time.Minute))
+	assert.Equal(t, "2h 15m", FormatUptime(2*time.Hour+15*time.Minute+30*time.Second))
+	assert.Equal(t, "0m", FormatUptime(42*time.Second))
+}
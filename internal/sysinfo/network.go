@@ -0,0 +1,130 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultIdentifySeconds is how long a port's LEDs blink when the
+// configuration does not say
+const DefaultIdentifySeconds = 10
+
+// IdentifyCommand returns the shell command blinking the LEDs of the port
+// passed in $PORT for the given number of seconds (0 =
+// DefaultIdentifySeconds). It is a fixed string for a configuration, so the
+// privileged helper can allow it.
+func IdentifyCommand(seconds int) string {
+	if seconds <= 0 {
+		seconds = DefaultIdentifySeconds
+	}
+	return fmt.Sprintf(`ethtool --identify "$PORT" %d`, seconds)
+}
+
+// Port is the link state of a network port
+type Port struct {
+	Name string
+	// Up reports whether the port has a link
+	Up bool
+	// SpeedMbps is the negotiated speed; 0 when down or unknown
+	SpeedMbps int
+	// Duplex is "full", "half" or "" when unknown
+	Duplex string
+}
+
+// Link renders the link state compactly, e.g. "1G full", "100M half" or
+// "down"
+func (p Port) Link() string {
+	if !p.Up {
+		return "down"
+	}
+	if p.SpeedMbps <= 0 {
+		return "up"
+	}
+
+	speed := fmt.Sprintf("%dM", p.SpeedMbps)
+	if p.SpeedMbps >= 1000 {
+		speed = strconv.FormatFloat(float64(p.SpeedMbps)/1000, 'f', -1, 64) + "G"
+	}
+	if p.Duplex == "" {
+		return speed
+	}
+	return speed + " " + p.Duplex
+}
+
+// NetworkProvider reads the link state of the network ports from sysfs and
+// blinks their LEDs
+type NetworkProvider struct {
+	root string
+	run  CommandRunner
+}
+
+// NewNetworkProvider creates a provider reading sysfs below root ("" = /sys)
+// and running ethtool with run (nil = RunShell)
+func NewNetworkProvider(root string, run CommandRunner) *NetworkProvider {
+	if root == "" {
+		root = "/sys"
+	}
+	if run == nil {
+		run = RunShell
+	}
+	return &NetworkProvider{root: root, run: run}
+}
+
+// Ports returns the physical network ports sorted by name. Virtual
+// interfaces (loopback, bridges, bonds, VLANs) have no device and are left
+// out.
+func (p *NetworkProvider) Ports() ([]Port, error) {
+	entries, err := os.ReadDir(filepath.Join(p.root, "class", "net"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var ports []Port
+	for _, entry := range entries {
+		dir := filepath.Join(p.root, "class", "net", entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			continue
+		}
+		ports = append(ports, p.port(entry.Name(), dir))
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+	return ports, nil
+}
+
+// port reads the link state of the interface in dir. The kernel fails reads
+// of speed and duplex while there is no link, so those are only read when
+// the carrier is up.
+func (p *NetworkProvider) port(name, dir string) Port {
+	port := Port{Name: name}
+	if carrier, err := readString(filepath.Join(dir, "carrier")); err != nil || carrier != "1" {
+		return port
+	}
+	port.Up = true
+
+	if speed, err := readString(filepath.Join(dir, "speed")); err == nil {
+		if mbps, err := strconv.Atoi(speed); err == nil && mbps > 0 {
+			port.SpeedMbps = mbps
+		}
+	}
+	if duplex, err := readString(filepath.Join(dir, "duplex")); err == nil && duplex != "unknown" {
+		port.Duplex = duplex
+	}
+	return port
+}
+
+// Identify blinks the LEDs of the named port for the given number of seconds
+// (0 = DefaultIdentifySeconds) and returns when they stop
+func (p *NetworkProvider) Identify(name string, seconds int) error {
+	if name == "" || strings.ContainsAny(name, "/ ") {
+		return fmt.Errorf("invalid port name %q", name)
+	}
+	output, err := p.run(IdentifyCommand(seconds), []string{"PORT=" + name})
+	if err != nil {
+		return fmt.Errorf("failed to identify %s: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package sysinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeInterface creates a sysfs network interface; physical ones get a
+// device link, and the other attributes are written as given
+func writeInterface(t *testing.T, root, name string, physical bool, attributes map[string]string) {
+	t.Helper()
+
+	dir := filepath.Join(root, "class", "net", name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	if physical {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "device"), 0755))
+	}
+	for attribute, value := range attributes {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, attribute), []byte(value+"\n"), 0644))
+	}
+}
+
+func TestNetworkProviderPorts(t *testing.T) {
+	root := t.TempDir()
+	writeInterface(t, root, "lo", false, map[string]string{"carrier": "1", "speed": "-1"})
+	writeInterface(t, root, "eth1", true, map[string]string{"carrier": "0"})
+	writeInterface(t, root, "eth0", true, map[string]string{"carrier": "1", "speed": "1000", "duplex": "full"})
+	writeInterface(t, root, "eth2", true, map[string]string{"carrier": "1", "speed": "-1", "duplex": "unknown"})
+	writeInterface(t, root, "br0", false, map[string]string{"carrier": "1"})
+
+	ports, err := NewNetworkProvider(root, nil).Ports()
+	require.NoError(t, err)
+	assert.Equal(t, []Port{
+		{Name: "eth0", Up: true, SpeedMbps: 1000, Duplex: "full"},
+		{Name: "eth1"},
+		{Name: "eth2", Up: true},
+	}, ports)
+
+	_, err = NewNetworkProvider(t.TempDir(), nil).Ports()
+	assert.Error(t, err)
+}
+
+func TestPortLink(t *testing.T) {
+	assert.Equal(t, "down", Port{Name: "eth0"}.Link())
+	assert.Equal(t, "up", Port{Name: "eth0", Up: true}.Link())
+	assert.Equal(t, "100M half", Port{Up: true, SpeedMbps: 100, Duplex: "half"}.Link())
+	assert.Equal(t, "1G full", Port{Up: true, SpeedMbps: 1000, Duplex: "full"}.Link())
+	assert.Equal(t, "2.5G full", Port{Up: true, SpeedMbps: 2500, Duplex: "full"}.Link())
+	assert.Equal(t, "10G", Port{Up: true, SpeedMbps: 10000}.Link())
+}
+
+func TestNetworkProviderIdentify(t *testing.T) {
+	var command string
+	var env []string
+	provider := NewNetworkProvider("", func(c string, e []string) ([]byte, error) {
+		command, env = c, e
+		return nil, nil
+	})
+
+	require.NoError(t, provider.Identify("eth0", 5))
+	assert.Equal(t, `ethtool --identify "$PORT" 5`, command)
+	assert.Equal(t, []string{"PORT=eth0"}, env)
+
+	require.NoError(t, provider.Identify("eth1", 0))
+	assert.Equal(t, `ethtool --identify "$PORT" 10`, command)
+
+	assert.Error(t, provider.Identify("../eth0", 5))
+
+	failing := NewNetworkProvider("", func(string, []string) ([]byte, error) {
+		return []byte("Cannot identify NIC: Operation not supported\n"), errors.New("exit status 1")
+	})
+	err := failing.Identify("eth0", 5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Operation not supported")
+}
@@ -0,0 +1,179 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLabelDir holds a link named after each filesystem label to its
+// block device
+const DefaultLabelDir = "/dev/disk/by-label"
+
+// EjectCommand flushes and unmounts the filesystem mounted at $MOUNT. It is
+// a fixed string, so the privileged helper can allow it.
+const EjectCommand = `sync && umount "$MOUNT"`
+
+// RemovableDevice is a mounted filesystem on a removable or USB attached
+// disk
+type RemovableDevice struct {
+	Device string
+	Mount  string
+	FSType string
+	// Label is the filesystem label, or the last element of the mount point
+	// for filesystems without one
+	Label string
+}
+
+// RemovableProvider lists the mounted removable devices and ejects them
+type RemovableProvider struct {
+	mounts   string
+	root     string
+	labelDir string
+	run      CommandRunner
+}
+
+// NewRemovableProvider creates a provider reading the mount table at mounts
+// ("" = /proc/mounts) and sysfs below root ("" = /sys), and unmounting with
+// run (nil = RunShell)
+func NewRemovableProvider(mounts, root string, run CommandRunner) *RemovableProvider {
+	if mounts == "" {
+		mounts = "/proc/mounts"
+	}
+	if root == "" {
+		root = "/sys"
+	}
+	if run == nil {
+		run = RunShell
+	}
+	return &RemovableProvider{mounts: mounts, root: root, labelDir: DefaultLabelDir, run: run}
+}
+
+// Devices returns the mounted removable devices sorted by label. A device
+// mounted several times is listed at its first mount.
+func (p *RemovableProvider) Devices() ([]RemovableDevice, error) {
+	table, err := os.ReadFile(p.mounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount table: %w", err)
+	}
+
+	labels := p.labels()
+	seen := make(map[string]bool)
+	var devices []RemovableDevice
+	for _, line := range strings.Split(string(table), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		device, mount := unescapeMount(fields[0]), unescapeMount(fields[1])
+		if !strings.HasPrefix(device, "/dev/") || seen[device] {
+			continue
+		}
+		name := blockName(device)
+		if !p.removable(name) {
+			continue
+		}
+		seen[device] = true
+
+		label := labels[name]
+		if label == "" {
+			label = filepath.Base(mount)
+		}
+		devices = append(devices, RemovableDevice{Device: device, Mount: mount, FSType: fields[2], Label: label})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Label != devices[j].Label {
+			return devices[i].Label < devices[j].Label
+		}
+		return devices[i].Mount < devices[j].Mount
+	})
+	return devices, nil
+}
+
+// Eject flushes and unmounts the removable device mounted at mount, so it
+// can be pulled. Other filesystems are refused.
+func (p *RemovableProvider) Eject(mount string) error {
+	devices, err := p.Devices()
+	if err != nil {
+		return err
+	}
+	mount = filepath.Clean(mount)
+	for _, device := range devices {
+		if device.Mount != mount {
+			continue
+		}
+		output, err := p.run(EjectCommand, []string{"MOUNT=" + mount})
+		if err != nil {
+			return fmt.Errorf("failed to eject %s: %w: %s", device.Label, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+	return fmt.Errorf("no removable device mounted at %s", mount)
+}
+
+// removable reports whether the block device, or the disk holding the
+// partition, is removable or attached by USB. USB hard disks report
+// themselves as fixed, so the path to the device counts too.
+func (p *RemovableProvider) removable(name string) bool {
+	dir, err := filepath.EvalSymlinks(filepath.Join(p.root, "class", "block", name))
+	if err != nil {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+		dir = filepath.Dir(dir)
+	}
+	if strings.Contains(dir, "/usb") {
+		return true
+	}
+	flag, err := os.ReadFile(filepath.Join(dir, "removable"))
+	return err == nil && strings.TrimSpace(string(flag)) == "1"
+}
+
+// labels maps block device names to the labels of their filesystems
+func (p *RemovableProvider) labels() map[string]string {
+	labels := make(map[string]string)
+	entries, err := os.ReadDir(p.labelDir)
+	if err != nil {
+		return labels
+	}
+	for _, entry := range entries {
+		target, err := filepath.EvalSymlinks(filepath.Join(p.labelDir, entry.Name()))
+		if err == nil {
+			labels[filepath.Base(target)] = unescapeLabel(entry.Name())
+		}
+	}
+	return labels
+}
+
+// blockName returns the kernel name of a block device, following links such
+// as /dev/disk/by-uuid/...
+func blockName(device string) string {
+	if target, err := filepath.EvalSymlinks(device); err == nil {
+		device = target
+	}
+	return filepath.Base(device)
+}
+
+// unescapeLabel decodes the hex escapes (\x20 for a space) udev uses in the
+// names of label links
+func unescapeLabel(name string) string {
+	if !strings.Contains(name, `\x`) {
+		return name
+	}
+	var decoded strings.Builder
+	for i := 0; i < len(name); i++ {
+		if strings.HasPrefix(name[i:], `\x`) && i+4 <= len(name) {
+			if value, err := strconv.ParseUint(name[i+2:i+4], 16, 8); err == nil {
+				decoded.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		decoded.WriteByte(name[i])
+	}
+	return decoded.String()
+}
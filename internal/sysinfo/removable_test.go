@@ -0,0 +1,111 @@
+package sysinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBlockDevice adds a block device below dir to a fake sysfs at root and
+// links it from class/block
+func writeBlockDevice(t *testing.T, root, dir string, removable bool) {
+	t.Helper()
+	path := filepath.Join(root, "devices", dir)
+	require.NoError(t, os.MkdirAll(path, 0755))
+	flag := "0"
+	if removable {
+		flag = "1"
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(path, "removable"), []byte(flag+"\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "class", "block"), 0755))
+	require.NoError(t, os.Symlink(path, filepath.Join(root, "class", "block", filepath.Base(path))))
+}
+
+// writePartition adds a partition of a block device written by
+// writeBlockDevice
+func writePartition(t *testing.T, root, disk, name string) {
+	t.Helper()
+	path := filepath.Join(root, "devices", disk, name)
+	require.NoError(t, os.MkdirAll(path, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "partition"), []byte("1\n"), 0644))
+	require.NoError(t, os.Symlink(path, filepath.Join(root, "class", "block", name)))
+}
+
+// newTestRemovableProvider creates a provider on a fake sysfs with a fixed
+// SATA disk, a USB hard disk reporting itself as fixed and a card reader
+func newTestRemovableProvider(t *testing.T, run CommandRunner) *RemovableProvider {
+	t.Helper()
+	root := t.TempDir()
+	writeBlockDevice(t, root, "pci0000:00/ata1/block/sda", false)
+	writePartition(t, root, "pci0000:00/ata1/block/sda", "sda1")
+	writeBlockDevice(t, root, "pci0000:00/usb2/2-1/block/sdb", false)
+	writePartition(t, root, "pci0000:00/usb2/2-1/block/sdb", "sdb1")
+	writeBlockDevice(t, root, "platform/mmc0/block/mmcblk0", true)
+
+	devices := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(devices, "sdb1"), nil, 0644))
+	labels := t.TempDir()
+	require.NoError(t, os.Symlink(filepath.Join(devices, "sdb1"), filepath.Join(labels, `BACKUP\x20STICK`)))
+
+	mounts := writeMounts(t,
+		"/dev/sda1 / ext4 rw 0 0",
+		"/dev/sdb1 /media/backup vfat rw 0 0",
+		"/dev/sdb1 /srv/bind vfat rw 0 0",
+		"/dev/mmcblk0 /media/card exfat rw 0 0",
+		"tmpfs /run tmpfs rw 0 0",
+	)
+	p := NewRemovableProvider(mounts, root, run)
+	p.labelDir = labels
+	return p
+}
+
+func TestRemovableProvider_Devices(t *testing.T) {
+	devices, err := newTestRemovableProvider(t, nil).Devices()
+	require.NoError(t, err)
+	assert.Equal(t, []RemovableDevice{
+		{Device: "/dev/sdb1", Mount: "/media/backup", FSType: "vfat", Label: "BACKUP STICK"},
+		{Device: "/dev/mmcblk0", Mount: "/media/card", FSType: "exfat", Label: "card"},
+	}, devices)
+
+	_, err = NewRemovableProvider("/nonexistent", "", nil).Devices()
+	assert.Error(t, err)
+}
+
+func TestRemovableProvider_Eject(t *testing.T) {
+	var ran []string
+	fail := false
+	p := newTestRemovableProvider(t, func(command string, env []string) ([]byte, error) {
+		ran = append(ran, command)
+		ran = append(ran, env...)
+		if fail {
+			return []byte("umount: target is busy\n"), errors.New("exit status 32")
+		}
+		return nil, nil
+	})
+
+	require.NoError(t, p.Eject("/media/card/"))
+	assert.Equal(t, []string{EjectCommand, "MOUNT=/media/card"}, ran)
+
+	// Fixed disks are never unmounted
+	ran = nil
+	err := p.Eject("/")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no removable device")
+	assert.Empty(t, ran)
+
+	fail = true
+	err = p.Eject("/media/backup")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BACKUP STICK")
+	assert.Contains(t, err.Error(), "target is busy")
+}
+
+func TestUnescapeLabel(t *testing.T) {
+	assert.Equal(t, "NO NAME", unescapeLabel(`NO\x20NAME`))
+	assert.Equal(t, "plain", unescapeLabel("plain"))
+	assert.Equal(t, `odd\x2`, unescapeLabel(`odd\x2`))
+}
@@ -0,0 +1,258 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of pools that can be scrubbed
+const (
+	PoolZFS   = "zfs"
+	PoolBtrfs = "btrfs"
+)
+
+// States of a scrub
+const (
+	// ScrubNone means the pool was never scrubbed
+	ScrubNone     = "none"
+	ScrubRunning  = "running"
+	ScrubFinished = "finished"
+	// ScrubStopped means the last scrub was canceled, paused or interrupted
+	ScrubStopped = "stopped"
+)
+
+// zpoolListCommand lists the names of the imported zpools
+const zpoolListCommand = "zpool list -H -o name"
+
+// scrubCommands start a scrub and report its progress. The pool name or
+// mount point is passed in $POOL, so the commands are fixed strings the
+// privileged helper can allow.
+var scrubCommands = map[string]struct{ start, status string }{
+	PoolZFS:   {start: `zpool scrub "$POOL"`, status: `zpool status "$POOL"`},
+	PoolBtrfs: {start: `btrfs scrub start "$POOL"`, status: `btrfs scrub status "$POOL"`},
+}
+
+// ScrubCommands returns every shell command a ScrubProvider runs
+func ScrubCommands() []string {
+	commands := []string{zpoolListCommand}
+	for _, kind := range []string{PoolZFS, PoolBtrfs} {
+		commands = append(commands, scrubCommands[kind].start, scrubCommands[kind].status)
+	}
+	return commands
+}
+
+// CommandRunner runs a shell command with extra environment variables and
+// returns its combined output
+type CommandRunner func(command string, env []string) ([]byte, error)
+
+// RunShell runs command with sh -c in the current environment plus env
+func RunShell(command string, env []string) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.CombinedOutput()
+}
+
+// Pool is a filesystem that can be scrubbed
+type Pool struct {
+	Kind string
+	// Name is the zpool name or the last element of the btrfs mount point
+	Name string
+	// Target is what the scrub commands work on: the zpool name or the btrfs
+	// mount point
+	Target string
+}
+
+// ScrubStatus is the progress of a pool's current or last scrub
+type ScrubStatus struct {
+	State string
+	// Percent is how much of the pool has been checked, 0-100
+	Percent float64
+	// Remaining is the estimated time left while running; 0 when unknown
+	Remaining time.Duration
+	// Errors is the number of errors the last finished scrub found
+	Errors int
+}
+
+// ScrubProvider lists pools and starts and follows their scrubs
+type ScrubProvider struct {
+	mounts string
+	run    CommandRunner
+}
+
+// NewScrubProvider creates a provider finding btrfs filesystems in the mount
+// table at mounts ("" = /proc/mounts) and running its commands with run (nil
+// = RunShell)
+func NewScrubProvider(mounts string, run CommandRunner) *ScrubProvider {
+	if mounts == "" {
+		mounts = "/proc/mounts"
+	}
+	if run == nil {
+		run = RunShell
+	}
+	return &ScrubProvider{mounts: mounts, run: run}
+}
+
+// Pools returns the imported zpools and mounted btrfs filesystems, sorted by
+// name. A btrfs filesystem mounted several times is listed at its first
+// mount.
+func (p *ScrubProvider) Pools() ([]Pool, error) {
+	table, err := os.ReadFile(p.mounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount table: %w", err)
+	}
+
+	var pools []Pool
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(table), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] != PoolBtrfs {
+			continue
+		}
+		device, mount := unescapeMount(fields[0]), unescapeMount(fields[1])
+		if seen[device] {
+			continue
+		}
+		seen[device] = true
+		pools = append(pools, Pool{Kind: PoolBtrfs, Name: filepath.Base(mount), Target: mount})
+	}
+
+	// Hosts without ZFS have no zpool command; they just have no zpools
+	if output, err := p.run(zpoolListCommand, nil); err == nil {
+		for _, name := range strings.Fields(string(output)) {
+			pools = append(pools, Pool{Kind: PoolZFS, Name: name, Target: name})
+		}
+	}
+
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return pools, nil
+}
+
+// Start starts a scrub of pool
+func (p *ScrubProvider) Start(pool Pool) error {
+	commands, ok := scrubCommands[pool.Kind]
+	if !ok {
+		return fmt.Errorf("unknown pool kind %q", pool.Kind)
+	}
+	if output, err := p.run(commands.start, poolEnv(pool)); err != nil {
+		return fmt.Errorf("failed to start scrub of %s: %w: %s", pool.Name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Status reports the progress of the pool's current or last scrub
+func (p *ScrubProvider) Status(pool Pool) (ScrubStatus, error) {
+	commands, ok := scrubCommands[pool.Kind]
+	if !ok {
+		return ScrubStatus{}, fmt.Errorf("unknown pool kind %q", pool.Kind)
+	}
+	output, err := p.run(commands.status, poolEnv(pool))
+	if err != nil {
+		return ScrubStatus{}, fmt.Errorf("failed to read scrub status of %s: %w: %s", pool.Name, err, strings.TrimSpace(string(output)))
+	}
+	if pool.Kind == PoolZFS {
+		return parseZpoolStatus(string(output)), nil
+	}
+	return parseBtrfsScrubStatus(string(output)), nil
+}
+
+// poolEnv passes the pool to the scrub commands
+func poolEnv(pool Pool) []string {
+	return []string{"POOL=" + pool.Target}
+}
+
+var (
+	// percentDone matches "24.32% done" in zpool status and "(29.30%)" in
+	// btrfs scrub status
+	percentDone = regexp.MustCompile(`([\d.]+)%(?: done|\))`)
+	// zpoolTimeLeft matches "01:03:37 to go", older releases prefix days
+	zpoolTimeLeft = regexp.MustCompile(`(?:(\d+) days? )?(\d+):(\d+):(\d+) to go`)
+	// zpoolErrors matches the end of a finished scan line, "with 0 errors"
+	zpoolErrors = regexp.MustCompile(`with (\d+) errors`)
+	// btrfsTimeLeft matches "Time left: 0:25:01"
+	btrfsTimeLeft = regexp.MustCompile(`Time left:\s+(\d+):(\d+):(\d+)`)
+	// btrfsErrorCounts matches the counters of a btrfs error summary, e.g. "csum=3"
+	btrfsErrorCounts = regexp.MustCompile(`\w+=(\d+)`)
+)
+
+// parseZpoolStatus reads the scan line of zpool status output
+func parseZpoolStatus(output string) ScrubStatus {
+	status := ScrubStatus{State: ScrubNone}
+	switch {
+	case strings.Contains(output, "scrub in progress"):
+		status.State = ScrubRunning
+		if match := zpoolTimeLeft.FindStringSubmatch(output); match != nil {
+			days, _ := strconv.Atoi(match[1])
+			status.Remaining = hms(match[2], match[3], match[4]) + time.Duration(days)*24*time.Hour
+		}
+	case strings.Contains(output, "scrub repaired"):
+		status.State = ScrubFinished
+		status.Percent = 100
+		if match := zpoolErrors.FindStringSubmatch(output); match != nil {
+			status.Errors, _ = strconv.Atoi(match[1])
+		}
+		return status
+	case strings.Contains(output, "scrub canceled"), strings.Contains(output, "scrub paused"):
+		status.State = ScrubStopped
+	}
+	if match := percentDone.FindStringSubmatch(output); match != nil {
+		status.Percent, _ = strconv.ParseFloat(match[1], 64)
+	}
+	return status
+}
+
+// parseBtrfsScrubStatus reads btrfs scrub status output as printed by
+// btrfs-progs 5.1 and later
+func parseBtrfsScrubStatus(output string) ScrubStatus {
+	status := ScrubStatus{State: ScrubNone}
+	for _, line := range strings.Split(output, "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(name) {
+		case "Status":
+			switch value {
+			case "running":
+				status.State = ScrubRunning
+			case "finished":
+				status.State = ScrubFinished
+			case "aborted", "interrupted":
+				status.State = ScrubStopped
+			}
+		case "Error summary":
+			for _, count := range btrfsErrorCounts.FindAllStringSubmatch(value, -1) {
+				errors, _ := strconv.Atoi(count[1])
+				status.Errors += errors
+			}
+		}
+	}
+
+	if match := percentDone.FindStringSubmatch(output); match != nil {
+		status.Percent, _ = strconv.ParseFloat(match[1], 64)
+	}
+	switch status.State {
+	case ScrubRunning:
+		if match := btrfsTimeLeft.FindStringSubmatch(output); match != nil {
+			status.Remaining = hms(match[1], match[2], match[3])
+		}
+	case ScrubFinished:
+		status.Percent = 100
+	}
+	return status
+}
+
+// hms converts hours, minutes and seconds to a duration
+func hms(hours, minutes, seconds string) time.Duration {
+	h, _ := strconv.Atoi(hours)
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+}
@@ -0,0 +1,144 @@
+package sysinfo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const zpoolScrubbing = `  pool: tank
+ state: ONLINE
+  scan: scrub in progress since Sun Jul 25 16:07:49 2021
+	1.23T scanned at 1.02G/s, 612G issued at 508M/s, 2.46T total
+	0B repaired, 24.32% done, 01:03:37 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+`
+
+const zpoolScrubbed = `  pool: tank
+ state: ONLINE
+  scan: scrub repaired 0B in 00:12:34 with 2 errors on Sun Jul 25 16:20:23 2021
+`
+
+const btrfsScrubbing = `UUID:             5d1b1d5f-2b14-4f43-9c1a-2f5c4e7d8a90
+Scrub started:    Sun Jul 25 16:07:49 2021
+Status:           running
+Duration:         0:10:12
+Time left:        0:25:01
+ETA:              Sun Jul 25 16:43:02 2021
+Total to scrub:   1.00TiB
+Bytes scrubbed:   300.00GiB  (29.30%)
+Rate:             501.96MiB/s
+Error summary:    no errors found
+`
+
+const btrfsScrubbed = `UUID:             5d1b1d5f-2b14-4f43-9c1a-2f5c4e7d8a90
+Scrub started:    Sun Jul 25 16:07:49 2021
+Status:           finished
+Duration:         0:35:13
+Total to scrub:   1.00TiB
+Rate:             496.12MiB/s
+Error summary:    read=1 csum=3
+  Corrected:      4
+  Uncorrectable:  0
+  Unverified:     0
+`
+
+func TestParseZpoolStatus(t *testing.T) {
+	status := parseZpoolStatus(zpoolScrubbing)
+	assert.Equal(t, ScrubRunning, status.State)
+	assert.InDelta(t, 24.32, status.Percent, 0.001)
+	assert.Equal(t, time.Hour+3*time.Minute+37*time.Second, status.Remaining)
+
+	status = parseZpoolStatus(zpoolScrubbed)
+	assert.Equal(t, ScrubStatus{State: ScrubFinished, Percent: 100, Errors: 2}, status)
+
+	status = parseZpoolStatus("  scan: scrub in progress since Sun Jul 25\n\t0B repaired, 1.50% done, 1 days 02:00:00 to go\n")
+	assert.Equal(t, 26*time.Hour, status.Remaining, "older releases count days")
+
+	assert.Equal(t, ScrubStopped, parseZpoolStatus("  scan: scrub canceled on Sun Jul 25 16:20:23 2021\n").State)
+	assert.Equal(t, ScrubNone, parseZpoolStatus("  scan: none requested\n").State)
+}
+
+func TestParseBtrfsScrubStatus(t *testing.T) {
+	status := parseBtrfsScrubStatus(btrfsScrubbing)
+	assert.Equal(t, ScrubRunning, status.State)
+	assert.InDelta(t, 29.30, status.Percent, 0.001)
+	assert.Equal(t, 25*time.Minute+time.Second, status.Remaining)
+	assert.Zero(t, status.Errors)
+
+	status = parseBtrfsScrubStatus(btrfsScrubbed)
+	assert.Equal(t, ScrubStatus{State: ScrubFinished, Percent: 100, Errors: 4}, status)
+
+	assert.Equal(t, ScrubStopped, parseBtrfsScrubStatus("Status:           aborted\n").State)
+	assert.Equal(t, ScrubNone, parseBtrfsScrubStatus("UUID: 5d1b1d5f\n\tno stats available\n").State)
+}
+
+// fakeRunner answers commands from a table and records what it ran
+type fakeRunner struct {
+	outputs map[string]string
+	ran     []string
+}
+
+func (r *fakeRunner) run(command string, env []string) ([]byte, error) {
+	r.ran = append(r.ran, fmt.Sprint(command, env))
+	output, ok := r.outputs[command]
+	if !ok {
+		return []byte("sh: zpool: not found"), fmt.Errorf("exit status 127")
+	}
+	return []byte(output), nil
+}
+
+func TestScrubProvider(t *testing.T) {
+	mounts := writeMounts(t,
+		"/dev/sda1 / ext4 rw 0 0",
+		"/dev/sdb1 /mnt/backup btrfs rw 0 0",
+		"/dev/sdb1 /srv/snapshots btrfs rw 0 0",
+	)
+
+	t.Run("Without ZFS", func(t *testing.T) {
+		runner := &fakeRunner{}
+		pools, err := NewScrubProvider(mounts, runner.run).Pools()
+		require.NoError(t, err)
+		assert.Equal(t, []Pool{{Kind: PoolBtrfs, Name: "backup", Target: "/mnt/backup"}}, pools)
+	})
+
+	runner := &fakeRunner{outputs: map[string]string{
+		zpoolListCommand:             "tank\narchive\n",
+		`zpool scrub "$POOL"`:        "",
+		`zpool status "$POOL"`:       zpoolScrubbing,
+		`btrfs scrub status "$POOL"`: btrfsScrubbed,
+	}}
+	provider := NewScrubProvider(mounts, runner.run)
+
+	pools, err := provider.Pools()
+	require.NoError(t, err)
+	require.Len(t, pools, 3)
+	assert.Equal(t, []string{"archive", "backup", "tank"}, []string{pools[0].Name, pools[1].Name, pools[2].Name})
+
+	tank := pools[2]
+	require.NoError(t, provider.Start(tank))
+	assert.Contains(t, runner.ran, `zpool scrub "$POOL"[POOL=tank]`)
+
+	status, err := provider.Status(tank)
+	require.NoError(t, err)
+	assert.Equal(t, ScrubRunning, status.State)
+
+	status, err = provider.Status(pools[1])
+	require.NoError(t, err)
+	assert.Equal(t, ScrubFinished, status.State)
+
+	err = provider.Start(pools[1])
+	require.Error(t, err, "btrfs scrub start failed")
+	assert.Contains(t, err.Error(), "not found")
+
+	_, err = provider.Status(Pool{Kind: "ext4", Name: "root"})
+	assert.Error(t, err)
+
+	assert.Len(t, ScrubCommands(), 5)
+}
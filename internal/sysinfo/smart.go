@@ -0,0 +1,128 @@
+package sysinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SMART metrics kept for a drive
+const (
+	// SMARTTemperature is the drive temperature in °C
+	SMARTTemperature = "temperature"
+	// SMARTReallocated is the raw count of reallocated sectors (attribute 5)
+	SMARTReallocated = "reallocated"
+)
+
+// SMARTMetrics lists the SMART metrics in display order
+var SMARTMetrics = []string{SMARTTemperature, SMARTReallocated}
+
+// smartCommand reads the attributes of the drive passed in $DRIVE as JSON.
+// It is a fixed string, so the privileged helper can allow it.
+const smartCommand = `smartctl -j -A "/dev/$DRIVE"`
+
+// SMARTCommand returns the shell command a SMARTProvider runs
+func SMARTCommand() string {
+	return smartCommand
+}
+
+// SMART attribute ids read from ATA drives
+const (
+	smartAttrReallocated = 5
+	smartAttrAirflowTemp = 190
+	smartAttrTemperature = 194
+)
+
+// SMARTSample is one reading of a drive's SMART metrics. Values holds the
+// metrics the drive reports; NVMe drives have no reallocated sectors, for
+// example.
+type SMARTSample struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// Value returns a metric of the sample and whether the drive reported it
+func (s SMARTSample) Value(metric string) (float64, bool) {
+	value, ok := s.Values[metric]
+	return value, ok
+}
+
+// SMARTProvider reads SMART attributes with smartctl
+type SMARTProvider struct {
+	run CommandRunner
+}
+
+// NewSMARTProvider creates a provider running smartctl with run (nil =
+// RunShell)
+func NewSMARTProvider(run CommandRunner) *SMARTProvider {
+	if run == nil {
+		run = RunShell
+	}
+	return &SMARTProvider{run: run}
+}
+
+// Read samples the SMART metrics of drive, a device name below /dev such as
+// "sda"
+func (p *SMARTProvider) Read(drive string) (SMARTSample, error) {
+	if drive == "" || strings.HasPrefix(drive, "/") || strings.Contains(drive, "..") || strings.ContainsAny(drive, " \"$`") {
+		return SMARTSample{}, fmt.Errorf("invalid drive name %q", drive)
+	}
+
+	// smartctl exits non-zero when the drive has logged errors, but still
+	// reports its attributes
+	output, err := p.run(smartCommand, []string{"DRIVE=" + drive})
+	values, parseErr := parseSMARTOutput(output)
+	if parseErr != nil {
+		if err != nil {
+			return SMARTSample{}, fmt.Errorf("failed to read SMART attributes of %s: %w: %s", drive, err, strings.TrimSpace(string(output)))
+		}
+		return SMARTSample{}, fmt.Errorf("failed to read SMART attributes of %s: %w", drive, parseErr)
+	}
+	return SMARTSample{Time: time.Now(), Values: values}, nil
+}
+
+// smartctlOutput is the part of smartctl's JSON output that is read
+type smartctlOutput struct {
+	Temperature *struct {
+		Current *float64 `json:"current"`
+	} `json:"temperature"`
+	ATAAttributes struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+}
+
+// parseSMARTOutput reads the metrics from smartctl -j -A output. The
+// temperature comes from smartctl's own summary and, if that is missing, the
+// lowest byte of the raw temperature attribute; its other bytes hold the
+// lifetime minimum and maximum on many drives.
+func parseSMARTOutput(output []byte) (map[string]float64, error) {
+	var parsed smartctlOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, fmt.Errorf("malformed smartctl output: %w", err)
+	}
+
+	values := make(map[string]float64)
+	if parsed.Temperature != nil && parsed.Temperature.Current != nil {
+		values[SMARTTemperature] = *parsed.Temperature.Current
+	}
+	for _, attribute := range parsed.ATAAttributes.Table {
+		switch attribute.ID {
+		case smartAttrReallocated:
+			values[SMARTReallocated] = float64(attribute.Raw.Value)
+		case smartAttrTemperature, smartAttrAirflowTemp:
+			if _, ok := values[SMARTTemperature]; !ok {
+				values[SMARTTemperature] = float64(attribute.Raw.Value & 0xFF)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no temperature or reallocated sectors reported")
+	}
+	return values, nil
+}
@@ -0,0 +1,73 @@
+package sysinfo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const smartctlATA = `{
+  "smartctl": {"exit_status": 64},
+  "device": {"name": "/dev/sda", "type": "sat"},
+  "ata_smart_attributes": {
+    "table": [
+      {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 12, "string": "12"}},
+      {"id": 194, "name": "Temperature_Celsius", "raw": {"value": 193274839078, "string": "38 (Min/Max 20/45)"}}
+    ]
+  },
+  "temperature": {"current": 38}
+}`
+
+const smartctlNVMe = `{
+  "device": {"name": "/dev/nvme0", "type": "nvme"},
+  "nvme_smart_health_information_log": {"temperature": 41},
+  "temperature": {"current": 41}
+}`
+
+func TestParseSMARTOutput(t *testing.T) {
+	values, err := parseSMARTOutput([]byte(smartctlATA))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{SMARTTemperature: 38, SMARTReallocated: 12}, values)
+
+	values, err = parseSMARTOutput([]byte(smartctlNVMe))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{SMARTTemperature: 41}, values, "NVMe drives have no reallocated sectors")
+
+	values, err = parseSMARTOutput([]byte(`{"ata_smart_attributes": {"table": [{"id": 194, "raw": {"value": 193274839078}}]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{SMARTTemperature: 38}, values, "the attribute's lowest byte without a summary")
+
+	_, err = parseSMARTOutput([]byte(`{"smartctl": {"exit_status": 2}}`))
+	assert.Error(t, err)
+}
+
+func TestSMARTProvider_Read(t *testing.T) {
+	var ran []string
+	provider := NewSMARTProvider(func(command string, env []string) ([]byte, error) {
+		ran = append(ran, command)
+		ran = append(ran, env...)
+		if env[0] == "DRIVE=sdb" {
+			return []byte("smartctl: command not found"), errors.New("exit status 127")
+		}
+		// Errors logged by the drive set bits of the exit status
+		return []byte(smartctlATA), errors.New("exit status 64")
+	})
+
+	sample, err := provider.Read("sda")
+	require.NoError(t, err)
+	assert.Equal(t, []string{SMARTCommand(), "DRIVE=sda"}, ran)
+	value, ok := sample.Value(SMARTReallocated)
+	assert.True(t, ok)
+	assert.Equal(t, 12.0, value)
+	assert.False(t, sample.Time.IsZero())
+
+	_, err = provider.Read("sdb")
+	assert.Error(t, err)
+
+	for _, drive := range []string{"", "/dev/sda", "../sda", "sda; reboot"} {
+		_, err = provider.Read(drive)
+		assert.Error(t, err, drive)
+	}
+}
@@ -0,0 +1,359 @@
+package sysinfo
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultShareCacheTTL is how long a counted share size is reused before it
+// is counted again
+const DefaultShareCacheTTL = 10 * time.Minute
+
+// DefaultUUIDDir holds a link named after each filesystem UUID to its block
+// device
+const DefaultUUIDDir = "/dev/disk/by-uuid"
+
+// volumeFSTypes are filesystems without a block device that still hold data
+var volumeFSTypes = map[string]bool{
+	"zfs":  true,
+	"nfs":  true,
+	"nfs4": true,
+	"cifs": true,
+}
+
+// Volume is a mounted filesystem and its usage in bytes
+type Volume struct {
+	Device string
+	Mount  string
+	FSType string
+	Size   uint64
+	Used   uint64
+	Avail  uint64
+}
+
+// Name is the last element of the mount point, or "/" for the root
+func (v Volume) Name() string {
+	return filepath.Base(v.Mount)
+}
+
+// Percent is the used share of the space available to users, rounded up as
+// df does
+func (v Volume) Percent() int {
+	total := v.Used + v.Avail
+	if total == 0 {
+		return 0
+	}
+	return int((v.Used*100 + total - 1) / total)
+}
+
+// Share is a top level directory of a volume
+type Share struct {
+	Name string
+	Path string
+	// Size is the disk space of its files; Counted is false until the first
+	// count has finished
+	Size    uint64
+	Counted bool
+}
+
+// StorageProvider lists mounted volumes and the shares on them. Share sizes
+// are counted in the background and cached, since walking a large share can
+// take minutes.
+type StorageProvider struct {
+	mounts  string
+	uuidDir string
+	volumes map[string]bool
+	shares  *ShareCache
+}
+
+// NewStorageProvider creates a provider reading the mount table at mounts
+// ("" = /proc/mounts). volumes limits the listing to these mount points; when
+// empty every disk backed or network filesystem is listed. Share sizes are
+// reused for ttl (0 = DefaultShareCacheTTL).
+func NewStorageProvider(mounts string, volumes []string, ttl time.Duration) *StorageProvider {
+	if mounts == "" {
+		mounts = "/proc/mounts"
+	}
+	p := &StorageProvider{mounts: mounts, uuidDir: DefaultUUIDDir, shares: NewShareCache(ttl, DirSize)}
+	if len(volumes) > 0 {
+		p.volumes = make(map[string]bool, len(volumes))
+		for _, mount := range volumes {
+			p.volumes[filepath.Clean(mount)] = true
+		}
+	}
+	return p
+}
+
+// Volumes returns the mounted volumes sorted by mount point. A device
+// mounted several times, e.g. by bind mounts, is listed at its first mount.
+func (p *StorageProvider) Volumes() ([]Volume, error) {
+	table, err := os.ReadFile(p.mounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount table: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var volumes []Volume
+	for _, line := range strings.Split(string(table), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		device, mount, fsType := unescapeMount(fields[0]), unescapeMount(fields[1]), fields[2]
+		if !p.isVolume(device, mount, fsType) || seen[device] {
+			continue
+		}
+		seen[device] = true
+
+		volume := Volume{Device: device, Mount: mount, FSType: fsType}
+		var stat unix.Statfs_t
+		if err := unix.Statfs(mount, &stat); err != nil {
+			continue
+		}
+		blockSize := uint64(stat.Bsize)
+		volume.Size = stat.Blocks * blockSize
+		volume.Used = (stat.Blocks - stat.Bfree) * blockSize
+		volume.Avail = stat.Bavail * blockSize
+		volumes = append(volumes, volume)
+	}
+
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Mount < volumes[j].Mount })
+	return volumes, nil
+}
+
+// Volume returns the volume mounted at mount
+func (p *StorageProvider) Volume(mount string) (Volume, error) {
+	volumes, err := p.Volumes()
+	if err != nil {
+		return Volume{}, err
+	}
+	for _, volume := range volumes {
+		if volume.Mount == mount {
+			return volume, nil
+		}
+	}
+	return Volume{}, fmt.Errorf("no volume mounted at %s", mount)
+}
+
+// DeviceUUID returns the filesystem UUID of the block device mounted at
+// mount, which identifies a USB stick whatever port or device name it gets
+func (p *StorageProvider) DeviceUUID(mount string) (string, error) {
+	table, err := os.ReadFile(p.mounts)
+	if err != nil {
+		return "", fmt.Errorf("failed to read mount table: %w", err)
+	}
+
+	mount = filepath.Clean(mount)
+	var device string
+	for _, line := range strings.Split(string(table), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && unescapeMount(fields[1]) == mount {
+			device = unescapeMount(fields[0])
+		}
+	}
+	if device == "" {
+		return "", fmt.Errorf("nothing mounted at %s", mount)
+	}
+	device, err = filepath.EvalSymlinks(device)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", device, err)
+	}
+
+	entries, err := os.ReadDir(p.uuidDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list filesystem UUIDs: %w", err)
+	}
+	for _, entry := range entries {
+		target, err := filepath.EvalSymlinks(filepath.Join(p.uuidDir, entry.Name()))
+		if err == nil && target == device {
+			return entry.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("no UUID for %s", device)
+}
+
+// isVolume reports whether a mount table entry should be listed
+func (p *StorageProvider) isVolume(device, mount, fsType string) bool {
+	if p.volumes != nil {
+		return p.volumes[mount]
+	}
+	if volumeFSTypes[fsType] {
+		return true
+	}
+	return strings.HasPrefix(device, "/dev/") && !strings.HasPrefix(device, "/dev/loop")
+}
+
+// Shares returns the top level directories of the volume at mount, sorted by
+// name, with their cached sizes. Shares without a current size are counted
+// in the background; ask again later for the result.
+func (p *StorageProvider) Shares(mount string) ([]Share, error) {
+	entries, err := os.ReadDir(mount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+
+	var shares []Share
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || name == "lost+found" {
+			continue
+		}
+		share := Share{Name: name, Path: filepath.Join(mount, name)}
+		share.Size, share.Counted = p.shares.Size(share.Path)
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+// unescapeMount decodes the octal escapes (\040 for a space) the kernel uses
+// in the mount table
+func unescapeMount(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var decoded strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if value, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				decoded.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		decoded.WriteByte(field[i])
+	}
+	return decoded.String()
+}
+
+// ShareCache remembers directory sizes and recounts them in the background
+// once they are older than its TTL
+type ShareCache struct {
+	ttl   time.Duration
+	count func(path string) (uint64, error)
+
+	mutex   sync.Mutex
+	entries map[string]*shareEntry
+}
+
+// shareEntry is the last count of a directory
+type shareEntry struct {
+	size     uint64
+	counted  time.Time
+	valid    bool
+	counting bool
+}
+
+// NewShareCache creates a cache that counts sizes with count and reuses them
+// for ttl (0 = DefaultShareCacheTTL)
+func NewShareCache(ttl time.Duration, count func(path string) (uint64, error)) *ShareCache {
+	if ttl <= 0 {
+		ttl = DefaultShareCacheTTL
+	}
+	return &ShareCache{ttl: ttl, count: count, entries: make(map[string]*shareEntry)}
+}
+
+// Size returns the last counted size of path and whether there is one. A
+// missing or expired size starts a count unless one is already running; the
+// previous size is returned meanwhile.
+func (c *ShareCache) Size(path string) (uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[path]
+	if !ok {
+		entry = &shareEntry{}
+		c.entries[path] = entry
+	}
+	if !entry.counting && (entry.counted.IsZero() || time.Since(entry.counted) >= c.ttl) {
+		entry.counting = true
+		go c.recount(path, entry)
+	}
+	return entry.size, entry.valid
+}
+
+// recount counts path and stores the result. A failed count keeps the
+// previous size and is retried after the TTL.
+func (c *ShareCache) recount(path string, entry *shareEntry) {
+	size, err := c.count(path)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry.counting = false
+	entry.counted = time.Now()
+	if err == nil {
+		entry.size = size
+		entry.valid = true
+	}
+}
+
+// DirSize returns the disk space used by the files below path, like du -s.
+// Hard linked files are counted once and unreadable directories are skipped.
+func DirSize(path string) (uint64, error) {
+	type inode struct{ dev, ino uint64 }
+	linked := make(map[inode]bool)
+
+	var total uint64
+	err := filepath.WalkDir(path, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if current == path {
+				return err
+			}
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			total += uint64(info.Size())
+			return nil
+		}
+		if stat.Nlink > 1 && !entry.IsDir() {
+			key := inode{uint64(stat.Dev), stat.Ino}
+			if linked[key] {
+				return nil
+			}
+			linked[key] = true
+		}
+		total += uint64(stat.Blocks) * 512
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", path, err)
+	}
+	return total, nil
+}
+
+// FormatBytes renders a size with a binary unit as df -h does, e.g. "512",
+// "3.4G" or "120T"
+func FormatBytes(size uint64) string {
+	const units = "KMGTPE"
+	if size < 1024 {
+		return strconv.FormatUint(size, 10)
+	}
+	value := float64(size)
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, units[unit])
+	}
+	return fmt.Sprintf("%.0f%c", value, units[unit])
+}
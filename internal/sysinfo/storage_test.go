@@ -0,0 +1,174 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMounts writes a mount table and returns its path
+func writeMounts(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mounts")
+	var table string
+	for _, line := range lines {
+		table += line + "\n"
+	}
+	require.NoError(t, os.WriteFile(path, []byte(table), 0644))
+	return path
+}
+
+func TestStorageProvider_Volumes(t *testing.T) {
+	pool := t.TempDir()
+	backup := filepath.Join(t.TempDir(), "usb backup")
+	require.NoError(t, os.Mkdir(backup, 0755))
+	escaped := filepath.Join(filepath.Dir(backup), `usb\040backup`)
+
+	mounts := writeMounts(t,
+		"proc /proc proc rw 0 0",
+		"tmpfs /run tmpfs rw 0 0",
+		"/dev/loop0 /snap/core squashfs ro 0 0",
+		"/dev/md0 "+pool+" ext4 rw 0 0",
+		"/dev/md0 /srv/bind ext4 rw 0 0",
+		"/dev/sdb1 "+escaped+" vfat rw 0 0",
+	)
+
+	volumes, err := NewStorageProvider(mounts, nil, 0).Volumes()
+	require.NoError(t, err)
+	require.Len(t, volumes, 2)
+	mountPoints := []string{volumes[0].Mount, volumes[1].Mount}
+	assert.ElementsMatch(t, []string{pool, backup}, mountPoints)
+	for _, volume := range volumes {
+		assert.NotZero(t, volume.Size)
+		assert.LessOrEqual(t, volume.Used, volume.Size)
+	}
+
+	// Listed mount points override the filesystem filter
+	volumes, err = NewStorageProvider(mounts, []string{"/proc"}, 0).Volumes()
+	require.NoError(t, err)
+	require.Len(t, volumes, 1)
+	assert.Equal(t, "proc", volumes[0].Name())
+
+	_, err = NewStorageProvider(mounts, nil, 0).Volume("/srv/bind")
+	assert.Error(t, err)
+
+	_, err = NewStorageProvider(filepath.Join(t.TempDir(), "missing"), nil, 0).Volumes()
+	assert.Error(t, err)
+}
+
+func TestStorageProvider_DeviceUUID(t *testing.T) {
+	dev := t.TempDir()
+	for _, name := range []string{"md0", "sdb1", "sdc1"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dev, name), nil, 0644))
+	}
+	uuids := t.TempDir()
+	require.NoError(t, os.Symlink(filepath.Join(dev, "sdb1"), filepath.Join(uuids, "1234-ABCD")))
+	require.NoError(t, os.Symlink(filepath.Join(dev, "sdc1"), filepath.Join(uuids, "5678-EF01")))
+
+	mounts := writeMounts(t,
+		filepath.Join(dev, "md0")+" /share ext4 rw 0 0",
+		filepath.Join(dev, "sdc1")+" /media/usb vfat rw 0 0",
+	)
+	provider := NewStorageProvider(mounts, nil, 0)
+	provider.uuidDir = uuids
+
+	uuid, err := provider.DeviceUUID("/media/usb/")
+	require.NoError(t, err)
+	assert.Equal(t, "5678-EF01", uuid)
+
+	_, err = provider.DeviceUUID("/media/other")
+	assert.Error(t, err, "nothing is mounted there")
+	_, err = provider.DeviceUUID("/share")
+	assert.Error(t, err, "the device has no UUID link")
+}
+
+func TestVolume_Percent(t *testing.T) {
+	assert.Equal(t, 62, Volume{Used: 615, Avail: 385}.Percent())
+	assert.Equal(t, 1, Volume{Used: 1, Avail: 999}.Percent(), "rounded up as df does")
+	assert.Equal(t, 0, Volume{}.Percent())
+	assert.Equal(t, "/", Volume{Mount: "/"}.Name())
+}
+
+func TestStorageProvider_Shares(t *testing.T) {
+	mount := t.TempDir()
+	for _, dir := range []string{"Public", "Multimedia", ".@snapshots", "lost+found"} {
+		require.NoError(t, os.Mkdir(filepath.Join(mount, dir), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(mount, "Public", "notes.txt"), make([]byte, 10000), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mount, "README"), nil, 0644))
+
+	provider := NewStorageProvider(writeMounts(t), nil, time.Hour)
+	shares, err := provider.Shares(mount)
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	assert.Equal(t, "Multimedia", shares[0].Name)
+	assert.Equal(t, "Public", shares[1].Name)
+
+	// Sizes arrive once the background count has finished
+	assert.Eventually(t, func() bool {
+		shares, err = provider.Shares(mount)
+		return err == nil && shares[1].Counted
+	}, time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, shares[1].Size, uint64(10000))
+}
+
+func TestShareCache(t *testing.T) {
+	var counts atomic.Int32
+	release := make(chan struct{})
+	cache := NewShareCache(50*time.Millisecond, func(path string) (uint64, error) {
+		<-release
+		if counts.Add(1) == 2 {
+			return 0, fmt.Errorf("share went away")
+		}
+		return uint64(counts.Load()) * 100, nil
+	})
+
+	_, ok := cache.Size("/share/a")
+	assert.False(t, ok)
+	_, ok = cache.Size("/share/a")
+	assert.False(t, ok, "a running count is not started again")
+
+	release <- struct{}{}
+	assert.Eventually(t, func() bool {
+		size, ok := cache.Size("/share/a")
+		return ok && size == 100
+	}, time.Second, time.Millisecond)
+
+	// After the TTL a failed recount keeps the previous size
+	time.Sleep(60 * time.Millisecond)
+	cache.Size("/share/a")
+	release <- struct{}{}
+	time.Sleep(10 * time.Millisecond)
+	size, ok := cache.Size("/share/a")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(100), size)
+	assert.Equal(t, int32(2), counts.Load())
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data")
+	require.NoError(t, os.WriteFile(file, make([]byte, 64*1024), 0644))
+	require.NoError(t, os.Link(file, filepath.Join(dir, "link")))
+
+	size, err := DirSize(dir)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, size, uint64(64*1024))
+	assert.Less(t, size, uint64(2*64*1024), "hard links are counted once")
+
+	_, err = DirSize(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512", FormatBytes(512))
+	assert.Equal(t, "1.5K", FormatBytes(1536))
+	assert.Equal(t, "340G", FormatBytes(340<<30))
+	assert.Equal(t, "3.6T", FormatBytes(4000000000000))
+}
@@ -0,0 +1,72 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("Not started by systemd", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		sent, err := Notify(NotifyReady)
+		require.NoError(t, err)
+		assert.False(t, sent)
+	})
+
+	t.Run("Sends the state", func(t *testing.T) {
+		// Socket paths are limited to about 100 bytes, too short for TempDir
+		dir, err := os.MkdirTemp("", "notify")
+		require.NoError(t, err)
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		socket := filepath.Join(dir, "notify")
+
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+		require.NoError(t, err)
+		defer conn.Close()
+		t.Setenv("NOTIFY_SOCKET", socket)
+
+		sent, err := Notify(NotifyWatchdog)
+		require.NoError(t, err)
+		assert.True(t, sent)
+
+		buffer := make([]byte, 64)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(buffer)
+		require.NoError(t, err)
+		assert.Equal(t, "WATCHDOG=1", string(buffer[:n]))
+	})
+
+	t.Run("Socket gone", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing"))
+		sent, err := Notify(NotifyReady)
+		assert.Error(t, err)
+		assert.False(t, sent)
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	t.Setenv("WATCHDOG_PID", "")
+	_, ok := WatchdogInterval()
+	assert.False(t, ok, "watchdog off")
+
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	interval, ok := WatchdogInterval()
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, interval)
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	_, ok = WatchdogInterval()
+	assert.True(t, ok)
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	_, ok = WatchdogInterval()
+	assert.False(t, ok, "meant for another process")
+}
@@ -0,0 +1,262 @@
+// Package systemd generates the systemd unit that runs the display service.
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultUnitName is the name the unit is installed under
+const DefaultUnitName = "qnap-display.service"
+
+// PowerOffUnitName is the name the unit showing the power-off screen is
+// installed under
+const PowerOffUnitName = "qnap-display-poweroff.service"
+
+// UnitOptions describe the service the unit runs
+type UnitOptions struct {
+	// Binary is the absolute path of the qnap-display-control executable
+	Binary string
+	// ConfigFile is passed to the service with --config
+	ConfigFile string
+	// User runs the service; "" or "root" keeps root but with a reduced
+	// capability set
+	User string
+	// Devices are the device nodes the service may open, e.g. the serial port
+	Devices []string
+	// WritablePaths may be written by the service and the commands it runs,
+	// e.g. copy destinations and watch folders; the rest of the file system
+	// is read-only
+	WritablePaths []string
+	// RuntimeDirectory is a directory below /run that systemd creates for
+	// the service and removes when it stops, e.g. "qnap-display" for the
+	// control socket
+	RuntimeDirectory string
+	// DropPrivileges keeps CAP_SETUID and CAP_SETGID so a service started as
+	// root can switch to its configured user once the hardware is open
+	DropPrivileges bool
+	// MountDevices lets the service unlock and mount encrypted USB devices:
+	// it keeps CAP_SYS_ADMIN and may use USB disks, the device mapper and
+	// the mount system calls
+	MountDevices bool
+}
+
+// unitTemplate is the hardened unit. The only capability kept is
+// CAP_SYS_RAWIO, which ioperm and /dev/port need for the copy button and
+// LEDs; devices are limited to an allow-list. The service reports ready
+// and feeds the watchdog itself, so systemd restarts it when the panel
+// reader hangs.
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=QNAP Display Controller
+After=network.target
+Wants=network.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{.Binary}} --config {{.ConfigFile}}
+Restart=always
+RestartSec=5
+# The panel may take a while to answer at cold boot
+TimeoutStartSec=300
+WatchdogSec=30
+{{- if .Unprivileged}}
+User={{.User}}
+# dialout opens the panel's tty; /dev/port is root:kmem 0640
+SupplementaryGroups=dialout kmem
+AmbientCapabilities=CAP_SYS_RAWIO
+{{- end}}
+
+# Privileges
+CapabilityBoundingSet=CAP_SYS_RAWIO{{if .DropPrivileges}} CAP_SETUID CAP_SETGID{{end}}{{if .MountDevices}} CAP_SYS_ADMIN{{end}}
+NoNewPrivileges=yes
+
+# Devices
+DevicePolicy=closed
+{{- range .Devices}}
+DeviceAllow={{.}} rw
+{{- end}}
+{{- if .MountDevices}}
+DeviceAllow=block-sd rw
+DeviceAllow=block-device-mapper rw
+DeviceAllow=/dev/mapper/control rw
+{{- end}}
+
+# Sandboxing
+ProtectSystem=strict
+ProtectHome=yes
+PrivateTmp=yes
+# The lock file shared with other tools driving the embedded controller
+ReadWritePaths=-/run/lock
+{{- range .WritablePaths}}
+ReadWritePaths=-{{.}}
+{{- end}}
+{{- if .RuntimeDirectory}}
+RuntimeDirectory={{.RuntimeDirectory}}
+{{- end}}
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectKernelLogs=yes
+ProtectControlGroups=yes
+ProtectClock=yes
+ProtectHostname=yes
+RestrictNamespaces=yes
+RestrictRealtime=yes
+RestrictSUIDSGID=yes
+LockPersonality=yes
+MemoryDenyWriteExecute=yes
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6 AF_NETLINK{{if .MountDevices}} AF_ALG{{end}}
+SystemCallArchitectures=native
+SystemCallFilter=@system-service @raw-io{{if .MountDevices}} @mount{{end}}
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// powerOffTemplate runs the power-off screen once the service has stopped
+// at shutdown. Without default dependencies it is not stopped with the other
+// services and holds the panel until the final kill before poweroff; it
+// needs no capabilities, only the panel's devices.
+var powerOffTemplate = template.Must(template.New("poweroff").Parse(`[Unit]
+Description=QNAP Display power-off screen
+DefaultDependencies=no
+Conflicts=` + DefaultUnitName + `
+After=` + DefaultUnitName + `
+
+[Service]
+Type=simple
+ExecStart={{.Binary}} --config {{.ConfigFile}} poweroff-screen
+TimeoutStopSec=5
+
+# Privileges
+CapabilityBoundingSet=
+NoNewPrivileges=yes
+
+# Devices
+DevicePolicy=closed
+{{- range .Devices}}
+DeviceAllow={{.}} rw
+{{- end}}
+
+[Install]
+WantedBy=poweroff.target halt.target
+`))
+
+// RenderUnit builds the unit file. Paths must be absolute; duplicates are
+// dropped and the lists are sorted so the output is stable.
+func RenderUnit(opts UnitOptions) (string, error) {
+	for _, path := range []string{opts.Binary, opts.ConfigFile} {
+		if err := checkPath(path); err != nil {
+			return "", err
+		}
+	}
+	if strings.ContainsAny(opts.User, " \t\n") {
+		return "", fmt.Errorf("invalid user %q", opts.User)
+	}
+	unprivileged := opts.User != "" && opts.User != "root"
+	if unprivileged && opts.DropPrivileges {
+		return "", fmt.Errorf("a service started as %s cannot drop privileges itself", opts.User)
+	}
+	if unprivileged && opts.MountDevices {
+		return "", fmt.Errorf("a service running as %s cannot mount devices", opts.User)
+	}
+
+	if dir := opts.RuntimeDirectory; dir != "" {
+		if filepath.IsAbs(dir) || filepath.Clean(dir) != dir || strings.HasPrefix(dir, "..") ||
+			strings.ContainsAny(dir, " \t\n\"'\\") {
+			return "", fmt.Errorf("invalid runtime directory %q", dir)
+		}
+	}
+
+	devices, err := cleanPaths(opts.Devices)
+	if err != nil {
+		return "", err
+	}
+	writable, err := cleanPaths(opts.WritablePaths)
+	if err != nil {
+		return "", err
+	}
+
+	var unit bytes.Buffer
+	err = unitTemplate.Execute(&unit, struct {
+		UnitOptions
+		Unprivileged bool
+	}{
+		UnitOptions: UnitOptions{
+			Binary:           opts.Binary,
+			ConfigFile:       opts.ConfigFile,
+			User:             opts.User,
+			Devices:          devices,
+			WritablePaths:    writable,
+			RuntimeDirectory: opts.RuntimeDirectory,
+			DropPrivileges:   opts.DropPrivileges,
+			MountDevices:     opts.MountDevices,
+		},
+		Unprivileged: unprivileged,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render unit: %w", err)
+	}
+	return unit.String(), nil
+}
+
+// checkPath rejects paths that are relative or would break the unit syntax
+func checkPath(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("path %q must be absolute", path)
+	}
+	if strings.ContainsAny(path, " \t\n\"'\\") {
+		return fmt.Errorf("path %q contains whitespace, quotes or backslashes", path)
+	}
+	return nil
+}
+
+// cleanPaths checks, cleans, deduplicates and sorts paths
+func cleanPaths(paths []string) ([]string, error) {
+	seen := make(map[string]bool, len(paths))
+	cleaned := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := checkPath(path); err != nil {
+			return nil, err
+		}
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			cleaned = append(cleaned, path)
+		}
+	}
+	sort.Strings(cleaned)
+	return cleaned, nil
+}
+
+// RenderPowerOffUnit builds the unit file of the power-off screen. Only the
+// binary, configuration file and devices of opts are used.
+func RenderPowerOffUnit(opts UnitOptions) (string, error) {
+	for _, path := range []string{opts.Binary, opts.ConfigFile} {
+		if err := checkPath(path); err != nil {
+			return "", err
+		}
+	}
+	devices, err := cleanPaths(opts.Devices)
+	if err != nil {
+		return "", err
+	}
+
+	var unit bytes.Buffer
+	err = powerOffTemplate.Execute(&unit, UnitOptions{
+		Binary:     opts.Binary,
+		ConfigFile: opts.ConfigFile,
+		Devices:    devices,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render unit: %w", err)
+	}
+	return unit.String(), nil
+}
@@ -0,0 +1,120 @@
+package systemd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/qnap/display-control/internal/hardware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderUnit(t *testing.T) {
+	opts := UnitOptions{
+		Binary:        "/usr/local/bin/qnap-display-control",
+		ConfigFile:    "/etc/qnap-display/config.json",
+		Devices:       []string{"/dev/ttyS1", "/dev/port", "/dev/ttyS1"},
+		WritablePaths: []string{"/share/Inbox/", "/share", ""},
+	}
+
+	unit, err := RenderUnit(opts)
+	require.NoError(t, err)
+	assert.Contains(t, unit, "ExecStart=/usr/local/bin/qnap-display-control --config /etc/qnap-display/config.json\n")
+	assert.Contains(t, unit, "CapabilityBoundingSet=CAP_SYS_RAWIO\n")
+	assert.Contains(t, unit, "DevicePolicy=closed\nDeviceAllow=/dev/port rw\nDeviceAllow=/dev/ttyS1 rw\n")
+	assert.Contains(t, unit, "ReadWritePaths=-/share\nReadWritePaths=-/share/Inbox\n")
+	assert.NotContains(t, unit, "User=", "root is kept by default")
+	assert.Contains(t, unit, "Type=notify\nNotifyAccess=main\n")
+	assert.Contains(t, unit, "WatchdogSec=30\n")
+
+	t.Run("EC port lock", func(t *testing.T) {
+		// ProtectSystem=strict leaves /run read-only unless allowed
+		assert.Contains(t, unit, "ProtectSystem=strict\n")
+		assert.Contains(t, unit, "ReadWritePaths=-"+filepath.Dir(hardware.PortLockPath)+"\n")
+	})
+
+	t.Run("Unprivileged user", func(t *testing.T) {
+		opts := opts
+		opts.User = "qnapdisplay"
+		unit, err := RenderUnit(opts)
+		require.NoError(t, err)
+		// The LEDs and the copy button need /dev/port, owned by kmem
+		assert.Contains(t, unit, "User=qnapdisplay\n")
+		assert.Contains(t, unit, "SupplementaryGroups=dialout kmem\nAmbientCapabilities=CAP_SYS_RAWIO\n")
+		assert.Contains(t, unit, "DeviceAllow=/dev/port rw\n")
+	})
+
+	t.Run("Drop privileges", func(t *testing.T) {
+		opts := opts
+		opts.DropPrivileges = true
+		unit, err := RenderUnit(opts)
+		require.NoError(t, err)
+		assert.Contains(t, unit, "CapabilityBoundingSet=CAP_SYS_RAWIO CAP_SETUID CAP_SETGID\n")
+		assert.NotContains(t, unit, "User=")
+
+		opts.User = "qnapdisplay"
+		_, err = RenderUnit(opts)
+		assert.Error(t, err, "only root can switch users")
+	})
+
+	t.Run("Mount devices", func(t *testing.T) {
+		opts := opts
+		opts.MountDevices = true
+		unit, err := RenderUnit(opts)
+		require.NoError(t, err)
+		assert.Contains(t, unit, "CapabilityBoundingSet=CAP_SYS_RAWIO CAP_SYS_ADMIN\n")
+		assert.Contains(t, unit, "DeviceAllow=/dev/ttyS1 rw\nDeviceAllow=block-sd rw\n")
+		assert.Contains(t, unit, "SystemCallFilter=@system-service @raw-io @mount\n")
+
+		opts.User = "qnapdisplay"
+		_, err = RenderUnit(opts)
+		assert.Error(t, err, "only root can mount")
+	})
+
+	t.Run("Runtime directory", func(t *testing.T) {
+		opts := opts
+		opts.RuntimeDirectory = "qnap-display"
+		unit, err := RenderUnit(opts)
+		require.NoError(t, err)
+		assert.Contains(t, unit, "ReadWritePaths=-/share/Inbox\nRuntimeDirectory=qnap-display\n")
+	})
+
+	t.Run("Invalid paths", func(t *testing.T) {
+		for _, broken := range []func(o *UnitOptions){
+			func(o *UnitOptions) { o.Binary = "qnap-display-control" },
+			func(o *UnitOptions) { o.ConfigFile = "/etc/my config.json" },
+			func(o *UnitOptions) { o.WritablePaths = []string{"/share\nExecStartPre=/bin/sh"} },
+			func(o *UnitOptions) { o.User = "root\nUser=nobody" },
+			func(o *UnitOptions) { o.RuntimeDirectory = "/run/qnap-display" },
+			func(o *UnitOptions) { o.RuntimeDirectory = "../etc" },
+		} {
+			o := opts
+			broken(&o)
+			_, err := RenderUnit(o)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestRenderPowerOffUnit(t *testing.T) {
+	opts := UnitOptions{
+		Binary:     "/usr/local/bin/qnap-display-control",
+		ConfigFile: "/etc/qnap-display/config.json",
+		Devices:    []string{"/dev/ttyS1", "/dev/ttyS1"},
+		User:       "qnapdisplay",
+	}
+
+	unit, err := RenderPowerOffUnit(opts)
+	require.NoError(t, err)
+	assert.Contains(t, unit, "ExecStart=/usr/local/bin/qnap-display-control --config /etc/qnap-display/config.json poweroff-screen\n")
+	assert.Contains(t, unit, "DefaultDependencies=no\nConflicts=qnap-display.service\nAfter=qnap-display.service\n",
+		"the service lets go of the panel first")
+	assert.Contains(t, unit, "CapabilityBoundingSet=\n")
+	assert.Contains(t, unit, "DevicePolicy=closed\nDeviceAllow=/dev/ttyS1 rw\n\n")
+	assert.Contains(t, unit, "WantedBy=poweroff.target halt.target\n")
+	assert.NotContains(t, unit, "User=")
+
+	opts.Devices = []string{"/dev/tty S1"}
+	_, err = RenderPowerOffUnit(opts)
+	assert.Error(t, err)
+}
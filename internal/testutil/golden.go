@@ -0,0 +1,77 @@
+package testutil
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites golden files instead of comparing with them, e.g.
+//
+//	go test ./test/integration -update
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// GoldenDir is where golden files are kept, relative to the test's package
+const GoldenDir = "testdata"
+
+// AssertGolden compares got with testdata/<name>.golden. With -update the
+// file is written from got instead.
+func AssertGolden(t testing.TB, name, got string) bool {
+	t.Helper()
+
+	path := filepath.Join(GoldenDir, name+".golden")
+	if *update {
+		if err := os.MkdirAll(GoldenDir, 0755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return true
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+	}
+	if string(golden) == got {
+		return true
+	}
+	t.Errorf("%s differs from the golden file (run with -update if the change is intended)\n%s",
+		path, diff(string(golden), got))
+	return false
+}
+
+// AssertGoldenScreen compares the framed screen with testdata/<name>.golden
+func AssertGoldenScreen(t testing.TB, name string, screen Screen) bool {
+	t.Helper()
+	return AssertGolden(t, name, Frame(screen.Lines()))
+}
+
+// diff lists the lines that differ, "-" from the golden file and "+" from
+// the test. Lines are compared by position, which is enough for screens.
+func diff(golden, got string) string {
+	want, have := strings.Split(golden, "\n"), strings.Split(got, "\n")
+	var out strings.Builder
+	for i := 0; i < max(len(want), len(have)); i++ {
+		var w, h string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(have) {
+			h = have[i]
+		}
+		if w == h {
+			continue
+		}
+		if i < len(want) {
+			out.WriteString("- " + w + "\n")
+		}
+		if i < len(have) {
+			out.WriteString("+ " + h + "\n")
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,116 @@
+// Package testutil helps tests check what the panel shows without hardware:
+// assertions on whole screens and golden files of rendered frames.
+package testutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// pollInterval is how often EventuallyScreen looks at the screen
+const pollInterval = 10 * time.Millisecond
+
+// Screen is anything showing lines of text, such as screen.Framebuffer or
+// controller.PanelSimulator
+type Screen interface {
+	Lines() []string
+}
+
+// ScreenFunc adapts a function returning lines, e.g. ScreenManager.Snapshot
+type ScreenFunc func() []string
+
+// Lines calls f
+func (f ScreenFunc) Lines() []string {
+	return f()
+}
+
+// TextScreen is a screen given as newline separated text, as recorded by the
+// fake displays of unit tests
+type TextScreen string
+
+// Lines splits the text into lines
+func (s TextScreen) Lines() []string {
+	return strings.Split(string(s), "\n")
+}
+
+// AssertScreen checks that screen shows exactly lines, one per row. Trailing
+// spaces are ignored, so lines need not be padded to the display width. On a
+// mismatch both screens are printed framed.
+func AssertScreen(t testing.TB, screen Screen, lines ...string) bool {
+	t.Helper()
+
+	actual := screen.Lines()
+	if sameLines(actual, lines) {
+		return true
+	}
+	width := max(widest(lines), widest(actual))
+	t.Errorf("unexpected screen\nexpected:\n%sactual:\n%s", frame(lines, width), frame(actual, width))
+	return false
+}
+
+// EventuallyScreen waits up to timeout for screen to show lines, for screens
+// drawn by background routines
+func EventuallyScreen(t testing.TB, screen Screen, timeout time.Duration, lines ...string) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		actual := screen.Lines()
+		if sameLines(actual, lines) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			width := max(widest(lines), widest(actual))
+			t.Errorf("screen not shown within %v\nexpected:\n%slast shown:\n%s",
+				timeout, frame(lines, width), frame(actual, width))
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// sameLines compares two screens ignoring trailing spaces
+func sameLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.TrimRight(a[i], " ") != strings.TrimRight(b[i], " ") {
+			return false
+		}
+	}
+	return true
+}
+
+// Frame draws lines inside a border, which makes padding visible:
+//
+//	+----------------+
+//	|QNAP Ready      |
+//	|>System Info    |
+//	+----------------+
+func Frame(lines []string) string {
+	return frame(lines, widest(lines))
+}
+
+// widest returns the length of the longest line
+func widest(lines []string) int {
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+	return width
+}
+
+// frame draws lines inside a border, padded to width
+func frame(lines []string, width int) string {
+	border := "+" + strings.Repeat("-", width) + "+\n"
+
+	var out strings.Builder
+	out.WriteString(border)
+	for _, line := range lines {
+		out.WriteString("|" + line + strings.Repeat(" ", width-len(line)) + "|\n")
+	}
+	out.WriteString(border)
+	return out.String()
+}
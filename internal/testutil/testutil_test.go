@@ -0,0 +1,93 @@
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertScreen(t *testing.T) {
+	fb := screen.NewFramebuffer(16, 2)
+	require.NoError(t, fb.SetLine(0, "QNAP Ready"))
+	require.NoError(t, fb.SetLine(1, ">System Info"))
+
+	assert.True(t, AssertScreen(t, fb, "QNAP Ready      ", ">System Info    "))
+	assert.True(t, AssertScreen(t, fb, "QNAP Ready", ">System Info"), "trailing spaces are ignored")
+
+	recorder := &recordingT{TB: t}
+	assert.False(t, AssertScreen(recorder, fb, "QNAP Ready", ">Network"))
+	require.Len(t, recorder.errors, 1)
+	assert.Contains(t, recorder.errors[0], "|>Network        |")
+	assert.Contains(t, recorder.errors[0], "|>System Info    |")
+
+	assert.False(t, AssertScreen(recorder, fb, "QNAP Ready"), "the number of lines must match")
+	assert.True(t, AssertScreen(t, TextScreen("USB Copy\nCopy complete"), "USB Copy", "Copy complete"))
+}
+
+func TestEventuallyScreen(t *testing.T) {
+	var mutex sync.Mutex
+	fb := screen.NewFramebuffer(16, 2)
+	snapshot := ScreenFunc(func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return fb.Lines()
+	})
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		mutex.Lock()
+		defer mutex.Unlock()
+		fb.SetLine(0, "Copy complete")
+	}()
+	assert.True(t, EventuallyScreen(t, snapshot, time.Second, "Copy complete", ""))
+
+	recorder := &recordingT{TB: t}
+	assert.False(t, EventuallyScreen(recorder, snapshot, 20*time.Millisecond, "Copy failed", ""))
+	assert.Len(t, recorder.errors, 1)
+}
+
+func TestFrame(t *testing.T) {
+	assert.Equal(t, "+-----+\n|Hello|\n|Go   |\n+-----+\n", Frame([]string{"Hello", "Go"}))
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	fb := screen.NewFramebuffer(16, 2)
+	require.NoError(t, fb.SetLine(0, "QNAP Ready"))
+
+	*update = true
+	assert.True(t, AssertGoldenScreen(t, "ready", fb))
+	*update = false
+	golden, err := os.ReadFile(filepath.Join(dir, GoldenDir, "ready.golden"))
+	require.NoError(t, err)
+	assert.Equal(t, Frame(fb.Lines()), string(golden))
+	assert.True(t, AssertGoldenScreen(t, "ready", fb))
+
+	require.NoError(t, fb.SetLine(1, ">System Info"))
+	recorder := &recordingT{TB: t}
+	assert.False(t, AssertGoldenScreen(recorder, "ready", fb))
+	require.Len(t, recorder.errors, 1)
+	assert.Contains(t, recorder.errors[0], "- |                |\n+ |>System Info    |\n")
+}
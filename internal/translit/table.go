@@ -0,0 +1,70 @@
+package translit
+
+import "sort"
+
+// builtin maps characters to ASCII stand-ins, unless a class or character
+// replacement of the configuration comes first
+var builtin = buildTable(map[string]string{
+	// Latin letters with diacritics, and ligatures
+	"ÀÁÂÃÄÅĀĂĄ": "A", "àáâãäåāăą": "a", "Æ": "AE", "æ": "ae",
+	"ÇĆĈĊČ": "C", "çćĉċč": "c", "ĎĐÐ": "D", "ďđð": "d",
+	"ÈÉÊËĒĔĖĘĚ": "E", "èéêëēĕėęě": "e", "ĜĞĠĢ": "G", "ĝğġģ": "g",
+	"ĤĦ": "H", "ĥħ": "h", "ÌÍÎÏĨĪĬĮİ": "I", "ìíîïĩīĭįı": "i",
+	"Ĵ": "J", "ĵ": "j", "Ķ": "K", "ķ": "k", "ĹĻĽĿŁ": "L", "ĺļľŀł": "l",
+	"ÑŃŅŇ": "N", "ñńņň": "n", "ÒÓÔÕÖØŌŎŐ": "O", "òóôõöøōŏő": "o",
+	"Œ": "OE", "œ": "oe", "ŔŖŘ": "R", "ŕŗř": "r", "ŚŜŞŠȘ": "S", "śŝşšș": "s",
+	"ß": "ss", "ŢŤŦȚ": "T", "ţťŧț": "t", "Þ": "Th", "þ": "th",
+	"ÙÚÛÜŨŪŬŮŰŲ": "U", "ùúûüũūŭůűų": "u", "Ŵ": "W", "ŵ": "w",
+	"ÝŶŸ": "Y", "ýÿŷ": "y", "ŹŻŽ": "Z", "źżž": "z",
+
+	// Punctuation and spaces
+	"‘’‚‛′": "'", "“”„‟″": "\"", "‐‑‒–—―−": "-", "…": "...",
+	"«": "<<", "»": ">>", "‹": "<", "›": ">", "¡": "!", "¿": "?",
+	"•◦‣": "*", "·": ".",
+	"\u00a0\u2002\u2003\u2004\u2005\u2006\u2007\u2008\u2009\u200a\u202f\u205f\u3000": " ",
+	// Zero width characters and emoji variation selectors
+	"\u200b\u200c\u200d\u2060\ufeff\ufe0e\ufe0f": "",
+
+	// Arrows
+	"←⬅⟵": "<", "→➡⟶➔➜": ">", "↑⬆": "^", "↓⬇": "v", "↔⟷": "<>", "↕": "^v",
+	"⇐⟸": "<=", "⇒⟹": "=>", "⇔⟺": "<=>",
+
+	// Symbols
+	"°": "o", "℃": "oC", "℉": "oF", "×": "x", "÷": "/", "±": "+-", "µ": "u",
+	"©": "(c)", "®": "(R)", "™": "TM", "€": "EUR", "£": "GBP", "¥": "JPY", "¢": "c",
+	"§": "S", "¶": "P", "½": "1/2", "¼": "1/4", "¾": "3/4", "¹": "1", "²": "2", "³": "3",
+	"‰": "0/00", "Ω": "Ohm", "≤": "<=", "≥": ">=", "≠": "!=", "≈": "~", "∞": "inf",
+
+	// Emoji with an obvious ASCII stand-in
+	"✓✔✅": "v", "✗✘❌": "x", "★☆⭐": "*", "⚠": "!",
+})
+
+// languages spell letters the way their language writes them without
+// diacritics, before the classes and the built-in table
+var languages = map[string]map[rune]string{
+	// German umlauts
+	"de": buildTable(map[string]string{"Ä": "Ae", "Ö": "Oe", "Ü": "Ue", "ä": "ae", "ö": "oe", "ü": "ue", "ß": "ss"}),
+	// Danish and Norwegian
+	"da": buildTable(map[string]string{"Å": "Aa", "å": "aa", "Ø": "Oe", "ø": "oe", "Æ": "Ae", "æ": "ae"}),
+}
+
+// Languages lists the languages Options.Language takes
+func Languages() []string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildTable expands a map from groups of characters to their replacement
+func buildTable(groups map[string]string) map[rune]string {
+	table := make(map[rune]string)
+	for chars, replacement := range groups {
+		for _, r := range chars {
+			table[r] = replacement
+		}
+	}
+	return table
+}
@@ -0,0 +1,242 @@
+// Package translit maps text to the characters a panel can show. The panels
+// have ASCII and a handful of custom characters, so accented Latin letters
+// become their base letters, typographic punctuation its ASCII form, and
+// arrows, symbols such as the degree sign and emoji ASCII stand-ins or the
+// panel's glyphs.
+//
+// Whole classes of characters can be given one replacement and single
+// characters their own, on top of the built-in table; a language's
+// conventions, such as German "ä" as "ae", can replace the base letters.
+// What nothing maps becomes a fallback.
+package translit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Classes of characters that can be replaced as a whole
+const (
+	ClassArrows  = "arrows"
+	ClassEmoji   = "emoji"
+	ClassSymbols = "symbols"
+)
+
+// Classes lists the class names
+var Classes = []string{ClassArrows, ClassEmoji, ClassSymbols}
+
+// DefaultFallback replaces characters nothing maps
+const DefaultFallback = "?"
+
+// Options configure a Transliterator
+type Options struct {
+	// Classes map a class to the replacement of each of its characters
+	Classes map[string]string
+	// Characters map single characters to their replacement. They come
+	// before the classes and the built-in table and may replace ASCII too.
+	Characters map[string]string
+	// Language selects the spelling of one of Languages for its letters,
+	// e.g. "de" for "ä" as "ae" instead of "a"; "" for none
+	Language string
+	// Fallback replaces characters nothing maps; nil for DefaultFallback
+	Fallback *string
+	// Glyph returns the character showing a named glyph, for replacements
+	// written as "{name}"
+	Glyph func(name string) (string, bool)
+}
+
+// Transliterator replaces the characters of text a panel cannot show
+type Transliterator struct {
+	characters map[rune]string
+	language   map[rune]string
+	classes    map[string]string
+	fallback   string
+}
+
+// Replacement is a character and what it is shown as
+type Replacement struct {
+	From rune
+	To   string
+}
+
+// New creates a transliterator. It fails for unknown classes and languages,
+// keys that are not a single character and glyphs the panel does not have.
+func New(options Options) (*Transliterator, error) {
+	t := &Transliterator{
+		characters: make(map[rune]string, len(options.Characters)),
+		classes:    make(map[string]string, len(options.Classes)),
+		fallback:   DefaultFallback,
+	}
+	if options.Language != "" {
+		language, ok := languages[options.Language]
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q, use %s", options.Language, strings.Join(Languages(), ", "))
+		}
+		t.language = language
+	}
+	for class, replacement := range options.Classes {
+		if !isClass(class) {
+			return nil, fmt.Errorf("unknown character class %q, use %s", class, strings.Join(Classes, ", "))
+		}
+		resolved, err := resolve(replacement, options.Glyph)
+		if err != nil {
+			return nil, fmt.Errorf("class %s: %w", class, err)
+		}
+		t.classes[class] = resolved
+	}
+	for key, replacement := range options.Characters {
+		r, size := utf8.DecodeRuneInString(key)
+		if key == "" || size != len(key) {
+			return nil, fmt.Errorf("%q is not a single character", key)
+		}
+		resolved, err := resolve(replacement, options.Glyph)
+		if err != nil {
+			return nil, fmt.Errorf("character %s: %w", key, err)
+		}
+		t.characters[r] = resolved
+	}
+	if options.Fallback != nil {
+		resolved, err := resolve(*options.Fallback, options.Glyph)
+		if err != nil {
+			return nil, fmt.Errorf("fallback: %w", err)
+		}
+		t.fallback = resolved
+	}
+	return t, nil
+}
+
+// resolve replaces a "{name}" replacement with the glyph's character
+func resolve(replacement string, glyph func(name string) (string, bool)) (string, error) {
+	name, ok := strings.CutPrefix(replacement, "{")
+	if !ok || !strings.HasSuffix(name, "}") {
+		return replacement, nil
+	}
+	name = strings.TrimSuffix(name, "}")
+	if glyph != nil {
+		if char, ok := glyph(name); ok {
+			return char, nil
+		}
+	}
+	return "", fmt.Errorf("unknown glyph %q", name)
+}
+
+// Apply returns text with every character the panel cannot show replaced
+func (t *Transliterator) Apply(text string) string {
+	if t == nil {
+		return text
+	}
+	needed := false
+	for _, r := range text {
+		if _, mapped := t.characters[r]; mapped || r >= utf8.RuneSelf {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return text
+	}
+
+	var out strings.Builder
+	for _, r := range text {
+		if replacement, ok := t.replace(r); ok {
+			out.WriteString(replacement)
+		} else {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// Replacements lists the characters of text Apply replaces, in the order
+// they first appear
+func (t *Transliterator) Replacements(text string) []Replacement {
+	var replacements []Replacement
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		if replacement, ok := t.replace(r); ok {
+			replacements = append(replacements, Replacement{From: r, To: replacement})
+		}
+	}
+	return replacements
+}
+
+// replace returns the replacement of a character, false if it is shown as
+// it is. Control characters are the panel's glyphs and always stay.
+func (t *Transliterator) replace(r rune) (string, bool) {
+	if r < ' ' {
+		return "", false
+	}
+	if replacement, ok := t.characters[r]; ok {
+		return replacement, true
+	}
+	if r < utf8.RuneSelf {
+		return "", false
+	}
+	if replacement, ok := t.language[r]; ok {
+		return replacement, true
+	}
+	if replacement, ok := t.classes[Class(r)]; ok {
+		return replacement, true
+	}
+	if replacement, ok := builtin[r]; ok {
+		return replacement, true
+	}
+	return t.fallback, true
+}
+
+// Class returns the class of a character, "" if it is in none
+func Class(r rune) string {
+	switch {
+	case r < utf8.RuneSelf:
+		return ""
+	case inRanges(r, arrowRanges):
+		return ClassArrows
+	case inRanges(r, emojiRanges):
+		return ClassEmoji
+	case unicode.IsSymbol(r) || unicode.Is(unicode.No, r) || r == 'µ':
+		return ClassSymbols
+	}
+	return ""
+}
+
+// isClass reports whether name is one of Classes
+func isClass(name string) bool {
+	for _, class := range Classes {
+		if class == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runeRange is an inclusive range of characters
+type runeRange struct{ first, last rune }
+
+// arrowRanges are the arrow blocks, and the arrows among the dingbats and
+// miscellaneous symbols
+var arrowRanges = []runeRange{
+	{0x2190, 0x21FF}, {0x27F0, 0x27FF}, {0x2900, 0x297F}, {0x2B00, 0x2B11},
+	{0x2794, 0x2794}, {0x2798, 0x27AF}, {0x27B1, 0x27BE},
+}
+
+// emojiRanges are the pictographic blocks, including the dingbats and
+// miscellaneous symbols emoji are drawn from
+var emojiRanges = []runeRange{
+	{0x1F000, 0x1FAFF}, {0x2600, 0x27BF}, {0x231A, 0x231B}, {0x23E9, 0x23FA},
+	{0x2B50, 0x2B55},
+}
+
+func inRanges(r rune, ranges []runeRange) bool {
+	for _, rr := range ranges {
+		if r >= rr.first && r <= rr.last {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package translit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// glyph knows the glyphs "gear" and "bar3"
+func glyph(name string) (string, bool) {
+	switch name {
+	case "gear":
+		return "\x00", true
+	case "bar3":
+		return "\x07", true
+	}
+	return "", false
+}
+
+func TestTransliterator_Apply(t *testing.T) {
+	builtIn, err := New(Options{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, text, want string
+	}{
+		{"ASCII stays", "CPU 42% load", "CPU 42% load"},
+		{"Latin letters", "Größe Ærø façade", "Grosse AEro facade"},
+		{"Punctuation", "“Backup” – done…", "\"Backup\" - done..."},
+		{"Degree sign", "Temp 21°C", "Temp 21oC"},
+		{"Arrows", "↑ 3 ↓ 5 → eth0", "^ 3 v 5 > eth0"},
+		{"Known emoji", "✅ ok ⚠️", "v ok !"},
+		{"Unknown emoji", "🔥 hot", "? hot"},
+		{"Unknown letter", "Ελλάδα", "??????"},
+		{"Glyphs stay", "\x00 System", "\x00 System"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, builtIn.Apply(tt.text))
+		})
+	}
+
+	// A nil transliterator leaves text alone
+	var none *Transliterator
+	assert.Equal(t, "21°C", none.Apply("21°C"))
+}
+
+func TestTransliterator_Configured(t *testing.T) {
+	empty := ""
+	tr, err := New(Options{
+		Classes:    map[string]string{ClassEmoji: "*", ClassArrows: "{gear}"},
+		Characters: map[string]string{"°": "{bar3}", "~": "-", "✅": "OK"},
+		Fallback:   &empty,
+		Glyph:      glyph,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "21\x07C 1-2", tr.Apply("21°C 1~2"), "characters come first, ASCII included")
+	assert.Equal(t, "\x00 up * OK", tr.Apply("↑ up 🔥 ✅"), "classes come before the built-in table")
+	assert.Equal(t, "ae", tr.Apply("æ"), "the built-in table still applies")
+	assert.Equal(t, "Gree", tr.Apply("Greeλ"), "the fallback drops")
+
+	assert.Equal(t, []Replacement{{'°', "\x07"}, {'→', "\x00"}, {'é', "e"}},
+		tr.Replacements("°C → °F é"))
+}
+
+func TestTransliterator_Language(t *testing.T) {
+	german, err := New(Options{Language: "de", Characters: map[string]string{"ü": "{bar3}"}, Glyph: glyph})
+	require.NoError(t, err)
+	assert.Equal(t, "Groesse Aerger M\x07nchen", german.Apply("Größe Ärger München"), "characters come before the language")
+	assert.Equal(t, "facade AEro", german.Apply("façade Ærø"), "other letters keep the built-in table")
+
+	danish, err := New(Options{Language: "da"})
+	require.NoError(t, err)
+	assert.Equal(t, "Aeroeskoebing Aarhus", danish.Apply("Ærøskøbing Århus"))
+}
+
+func TestNew_Errors(t *testing.T) {
+	_, err := New(Options{Classes: map[string]string{"greek": "?"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "arrows, emoji, symbols")
+
+	_, err = New(Options{Language: "fr"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "da, de")
+
+	_, err = New(Options{Characters: map[string]string{"->": ">"}})
+	assert.Error(t, err, "not a single character")
+
+	_, err = New(Options{Characters: map[string]string{"°": "{degree}"}, Glyph: glyph})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown glyph "degree"`)
+}
+
+func TestClass(t *testing.T) {
+	assert.Equal(t, ClassArrows, Class('→'))
+	assert.Equal(t, ClassArrows, Class('➡'))
+	assert.Equal(t, ClassEmoji, Class('🔥'))
+	assert.Equal(t, ClassEmoji, Class('☀'))
+	assert.Equal(t, ClassSymbols, Class('°'))
+	assert.Equal(t, ClassSymbols, Class('€'))
+	assert.Equal(t, "", Class('é'))
+	assert.Equal(t, "", Class('a'))
+}
@@ -0,0 +1,111 @@
+// Package uevent receives the device events the kernel sends to udev, e.g.
+// when a USB stick is plugged in or pulled.
+//
+// The events arrive on a netlink socket that needs no privileges. Events
+// name devices, not mounts: a stick is mounted by the automounter shortly
+// after its "add" event.
+package uevent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelGroup is the netlink multicast group of the kernel's events, as
+// opposed to the ones udev sends on after processing them
+const kernelGroup = 1
+
+// messageSize is the largest event read; the kernel sends at most a few KB
+const messageSize = 64 * 1024
+
+// Event is a device event
+type Event struct {
+	// Action is "add", "remove", "change", "bind" and so on
+	Action string
+	// DevPath is the device's path below /sys
+	DevPath string
+	// Subsystem is e.g. "block" or "usb"
+	Subsystem string
+	// DevType is e.g. "disk" or "partition"
+	DevType string
+	// DevName is the device node below /dev, e.g. "sdb1"
+	DevName string
+	// Vars holds every variable of the event
+	Vars map[string]string
+}
+
+// Parse decodes a kernel event: a "action@devpath" header and KEY=VALUE
+// variables, each ended by a NUL byte
+func Parse(message []byte) (Event, error) {
+	parts := bytes.Split(bytes.TrimRight(message, "\x00"), []byte{0})
+	action, devPath, ok := strings.Cut(string(parts[0]), "@")
+	if !ok || action == "" {
+		return Event{}, fmt.Errorf("not a kernel event: %q", parts[0])
+	}
+
+	event := Event{Action: action, DevPath: devPath, Vars: make(map[string]string, len(parts)-1)}
+	for _, part := range parts[1:] {
+		if key, value, ok := strings.Cut(string(part), "="); ok {
+			event.Vars[key] = value
+		}
+	}
+	event.Subsystem = event.Vars["SUBSYSTEM"]
+	event.DevType = event.Vars["DEVTYPE"]
+	event.DevName = event.Vars["DEVNAME"]
+	return event, nil
+}
+
+// Listener reads device events as they happen
+type Listener struct {
+	// messages delivers one event per read, the socket except in tests
+	messages io.ReadCloser
+	buffer   []byte
+}
+
+// Listen opens a netlink socket receiving the kernel's device events
+func Listen() (*Listener, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uevent socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: kernelGroup}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind uevent socket: %w", err)
+	}
+	// A non-blocking file is read through the runtime's poller, so Close
+	// wakes a pending Read
+	return newListener(os.NewFile(uintptr(fd), "uevent")), nil
+}
+
+// newListener creates a listener reading events from messages
+func newListener(messages io.ReadCloser) *Listener {
+	return &Listener{messages: messages, buffer: make([]byte, messageSize)}
+}
+
+// Read waits for the next event. Messages that are not kernel events are
+// skipped. After Close it returns an error wrapping os.ErrClosed.
+func (l *Listener) Read() (Event, error) {
+	for {
+		n, err := l.messages.Read(l.buffer)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = os.ErrClosed
+			}
+			return Event{}, fmt.Errorf("failed to read device event: %w", err)
+		}
+		if event, err := Parse(l.buffer[:n]); err == nil {
+			return event, nil
+		}
+	}
+}
+
+// Close closes the socket, ending a pending Read
+func (l *Listener) Close() error {
+	return l.messages.Close()
+}
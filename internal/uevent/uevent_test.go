@@ -0,0 +1,93 @@
+package uevent
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stickAdded is the event of a USB stick's partition appearing
+const stickAdded = "add@/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb/sdb1\x00" +
+	"ACTION=add\x00" +
+	"DEVPATH=/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb/sdb1\x00" +
+	"SUBSYSTEM=block\x00" +
+	"MAJOR=8\x00" +
+	"MINOR=17\x00" +
+	"DEVNAME=sdb1\x00" +
+	"DEVTYPE=partition\x00" +
+	"PARTN=1\x00" +
+	"SEQNUM=4242\x00"
+
+// messages hands out one message per read, like a datagram socket
+type messages struct {
+	queue  []string
+	closed bool
+}
+
+func (m *messages) Read(p []byte) (int, error) {
+	if m.closed || len(m.queue) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, m.queue[0])
+	m.queue = m.queue[1:]
+	return n, nil
+}
+
+func (m *messages) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	event, err := Parse([]byte(stickAdded))
+	require.NoError(t, err)
+	assert.Equal(t, "add", event.Action)
+	assert.Equal(t, "block", event.Subsystem)
+	assert.Equal(t, "partition", event.DevType)
+	assert.Equal(t, "sdb1", event.DevName)
+	assert.Contains(t, event.DevPath, "/usb2/")
+	assert.Equal(t, "4242", event.Vars["SEQNUM"])
+
+	// udev's own messages start with a binary header instead
+	_, err = Parse([]byte("libudev\x00\xfe\xed\xca\xfe"))
+	assert.Error(t, err)
+	_, err = Parse(nil)
+	assert.Error(t, err)
+}
+
+func TestListener_Read(t *testing.T) {
+	source := &messages{queue: []string{"libudev\x00junk", stickAdded, "remove@/devices/virtual/block/loop0\x00SUBSYSTEM=block\x00"}}
+	listener := newListener(source)
+
+	event, err := listener.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "sdb1", event.DevName)
+
+	event, err = listener.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "remove", event.Action)
+	assert.Empty(t, event.DevName)
+
+	require.NoError(t, listener.Close())
+	_, err = listener.Read()
+	assert.True(t, errors.Is(err, os.ErrClosed), err)
+}
+
+func TestListen(t *testing.T) {
+	listener, err := Listen()
+	if err != nil {
+		t.Skipf("no uevent socket here: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := listener.Read()
+		done <- err
+	}()
+	require.NoError(t, listener.Close())
+	assert.True(t, errors.Is(<-done, os.ErrClosed))
+}
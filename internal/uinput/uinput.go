@@ -0,0 +1,153 @@
+// Package uinput creates a virtual keyboard through the kernel's uinput
+// module, so key presses can be injected where any input consumer sees them.
+package uinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// DevicePath is the uinput control device
+const DevicePath = "/dev/uinput"
+
+// Key codes from linux/input-event-codes.h
+const (
+	KeyEnter uint16 = 28
+	KeyUp    uint16 = 103
+	KeyDown  uint16 = 108
+	KeyCopy  uint16 = 133
+)
+
+// Event types and codes from linux/input-event-codes.h
+const (
+	evSyn      = 0x00
+	evKey      = 0x01
+	synReport  = 0
+	busVirtual = 0x06
+)
+
+// uinput ioctls from linux/uinput.h
+const (
+	uiDevCreate  = 0x5501     // _IO('U', 1)
+	uiDevDestroy = 0x5502     // _IO('U', 2)
+	uiSetEvBit   = 0x40045564 // _IOW('U', 100, int)
+	uiSetKeyBit  = 0x40045565 // _IOW('U', 101, int)
+)
+
+// nameSize and absSize are the array sizes of struct uinput_user_dev
+const (
+	nameSize = 80
+	absSize  = 64
+)
+
+// userDev is struct uinput_user_dev, written to describe the device
+type userDev struct {
+	Name         [nameSize]byte
+	BusType      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	FFEffectsMax uint32
+	AbsMax       [absSize]int32
+	AbsMin       [absSize]int32
+	AbsFuzz      [absSize]int32
+	AbsFlat      [absSize]int32
+}
+
+// inputEvent is struct input_event
+type inputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Keyboard is a virtual keyboard with a fixed set of keys
+type Keyboard struct {
+	file *os.File
+	// events receives the input events, the file except in tests
+	events io.Writer
+	mutex  sync.Mutex
+}
+
+// Open creates a keyboard named name that can send the given keys. The
+// control device stays open, so keys can still be sent after root privileges
+// are dropped.
+func Open(name string, keys []uint16) (*Keyboard, error) {
+	file, err := os.OpenFile(DevicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", DevicePath, err)
+	}
+	if err := create(file, name, keys); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Keyboard{file: file, events: file}, nil
+}
+
+// create registers the keys and the device description with the kernel
+func create(file *os.File, name string, keys []uint16) error {
+	fd := int(file.Fd())
+	if err := unix.IoctlSetInt(fd, uiSetEvBit, evKey); err != nil {
+		return fmt.Errorf("failed to enable key events: %w", err)
+	}
+	for _, key := range keys {
+		if err := unix.IoctlSetInt(fd, uiSetKeyBit, int(key)); err != nil {
+			return fmt.Errorf("failed to enable key %d: %w", key, err)
+		}
+	}
+
+	dev := userDev{BusType: busVirtual, Version: 1}
+	copy(dev.Name[:nameSize-1], name)
+	var description bytes.Buffer
+	binary.Write(&description, binary.NativeEndian, &dev)
+	if _, err := file.Write(description.Bytes()); err != nil {
+		return fmt.Errorf("failed to describe device: %w", err)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uiDevCreate, 0); errno != 0 {
+		return fmt.Errorf("failed to create device: %w", errno)
+	}
+	return nil
+}
+
+// Key sends a key press or release followed by a sync, so consumers see it
+// at once
+func (k *Keyboard) Key(code uint16, pressed bool) error {
+	value := int32(0)
+	if pressed {
+		value = 1
+	}
+
+	var batch bytes.Buffer
+	binary.Write(&batch, binary.NativeEndian, &inputEvent{Type: evKey, Code: code, Value: value})
+	binary.Write(&batch, binary.NativeEndian, &inputEvent{Type: evSyn, Code: synReport})
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if _, err := k.events.Write(batch.Bytes()); err != nil {
+		return fmt.Errorf("failed to send key %d: %w", code, err)
+	}
+	return nil
+}
+
+// Close removes the device
+func (k *Keyboard) Close() error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.file == nil {
+		return nil
+	}
+	unix.Syscall(unix.SYS_IOCTL, k.file.Fd(), uiDevDestroy, 0)
+	err := k.file.Close()
+	k.file = nil
+	return err
+}
@@ -0,0 +1,37 @@
+package uinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructSizes(t *testing.T) {
+	// sizeof(struct uinput_user_dev) is the same on every architecture
+	assert.Equal(t, 1116, binary.Size(userDev{}))
+}
+
+func TestKeyboard_Key(t *testing.T) {
+	var events bytes.Buffer
+	k := &Keyboard{events: &events}
+
+	require.NoError(t, k.Key(KeyEnter, true))
+	require.NoError(t, k.Key(KeyEnter, false))
+
+	var got []inputEvent
+	for events.Len() > 0 {
+		var event inputEvent
+		require.NoError(t, binary.Read(&events, binary.NativeEndian, &event))
+		got = append(got, event)
+	}
+	assert.Equal(t, []inputEvent{
+		{Type: evKey, Code: KeyEnter, Value: 1},
+		{Type: evSyn, Code: synReport},
+		{Type: evKey, Code: KeyEnter, Value: 0},
+		{Type: evSyn, Code: synReport},
+	}, got)
+	assert.NoError(t, k.Close())
+}
@@ -0,0 +1,74 @@
+package usbexport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// FollowInterval is how often Follow measures a copy when given no interval
+const FollowInterval = time.Second
+
+// space returns the size of the file system holding dir and the space free
+// there, replaced by tests
+var space = func(dir string) (size, free uint64, err error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return 0, 0, fmt.Errorf("failed to read free space of %s: %w", dir, err)
+	}
+	blockSize := uint64(stat.Bsize)
+	return stat.Blocks * blockSize, stat.Bavail * blockSize, nil
+}
+
+// Follow reports the progress of a copy another program makes from the
+// device mounted at source into the file system holding destination, such
+// as the command of an import, every interval (FollowInterval if not
+// positive) until ctx is cancelled. Total is the space used on the device
+// and Copied how much the free space at destination shrank since Follow
+// started. Other writes to that file system count too and the two file
+// systems round sizes to their own blocks, so the status is an estimate;
+// Copied never exceeds Total.
+func Follow(ctx context.Context, source, destination string, interval time.Duration, progress Progress) error {
+	if err := mountCheck(source); err != nil {
+		return err
+	}
+	size, free, err := space(source)
+	if err != nil {
+		return err
+	}
+	total := size - free
+	_, start, err := space(destination)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = FollowInterval
+	}
+
+	var rate throughput
+	report := func() {
+		_, free, err := space(destination)
+		if err != nil {
+			return
+		}
+		var copied uint64
+		if free < start {
+			copied = min(start-free, total)
+		}
+		progress(Status{Copied: copied, Total: total, Rate: rate.add(copied, time.Now())})
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	report()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			report()
+		}
+	}
+}
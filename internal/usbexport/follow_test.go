@@ -0,0 +1,88 @@
+package usbexport
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSpace stands in for the file systems: a stick with 400 bytes used and
+// a share losing free space as the copy writes
+type fakeSpace struct {
+	mutex sync.Mutex
+	free  uint64
+}
+
+func (f *fakeSpace) space(dir string) (uint64, uint64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if dir == "/media/usb" {
+		return 1000, 600, nil
+	}
+	return 1 << 20, f.free, nil
+}
+
+func (f *fakeSpace) write(n uint64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.free -= n
+}
+
+func TestFollow(t *testing.T) {
+	withoutMountCheck(t)
+	fake := &fakeSpace{free: 1 << 19}
+	original := space
+	space = fake.space
+	t.Cleanup(func() { space = original })
+
+	statuses := make(chan Status, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Follow(ctx, "/media/usb", "/share/Inbox", 10*time.Millisecond, func(status Status) {
+			select {
+			case statuses <- status:
+			default:
+			}
+		})
+	}()
+
+	// waitFor returns the first status reported that done accepts
+	waitFor := func(done func(Status) bool) Status {
+		t.Helper()
+		deadline := time.After(time.Second)
+		for {
+			select {
+			case status := <-statuses:
+				if done(status) {
+					return status
+				}
+			case <-deadline:
+				t.Fatal("progress not reported")
+				return Status{}
+			}
+		}
+	}
+	assert.Equal(t, Status{Total: 400}, waitFor(func(Status) bool { return true }))
+
+	fake.write(100)
+	assert.Equal(t, 25, waitFor(func(s Status) bool { return s.Copied > 0 }).Percent())
+
+	// Other writes to the share cannot take it past the stick's data
+	fake.write(1000)
+	assert.Equal(t, uint64(400), waitFor(func(s Status) bool { return s.Copied > 100 }).Copied)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestFollow_NotMounted(t *testing.T) {
+	err := Follow(context.Background(), t.TempDir(), t.TempDir(), 0, nil)
+	assert.ErrorIs(t, err, ErrNotMounted)
+}
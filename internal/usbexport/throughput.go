@@ -0,0 +1,79 @@
+package usbexport
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// sampleInterval is the shortest time a throughput sample covers, so
+	// the page cache absorbing a burst of chunks does not count as speed
+	sampleInterval = 500 * time.Millisecond
+	// smoothing is the time constant of the throughput average: a change
+	// of speed is mostly reflected after this long
+	smoothing = 5 * time.Second
+)
+
+// Status is the progress of a running export
+type Status struct {
+	// Copied and Total are the bytes copied so far and to copy
+	Copied uint64
+	Total  uint64
+	// Rate is the smoothed throughput in bytes per second, 0 until the
+	// first sample was taken
+	Rate float64
+}
+
+// Percent returns the share copied, 100 for an export without data
+func (s Status) Percent() int {
+	if s.Total == 0 {
+		return 100
+	}
+	return int(s.Copied * 100 / s.Total)
+}
+
+// ETA returns the time the rest of the export takes at the current rate.
+// It is false while the rate is unknown.
+func (s Status) ETA() (time.Duration, bool) {
+	if s.Rate <= 0 {
+		return 0, false
+	}
+	if s.Copied >= s.Total {
+		return 0, true
+	}
+	seconds := float64(s.Total-s.Copied) / s.Rate
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second), true
+}
+
+// throughput averages the copy rate exponentially over time
+type throughput struct {
+	// rate is in bytes per second, 0 until the first sample
+	rate float64
+	// at and copied start the sample being taken
+	at     time.Time
+	copied uint64
+}
+
+// add records the bytes copied so far at now and returns the smoothed rate
+func (t *throughput) add(copied uint64, now time.Time) float64 {
+	if t.at.IsZero() {
+		t.at, t.copied = now, copied
+		return t.rate
+	}
+	elapsed := now.Sub(t.at)
+	if elapsed < sampleInterval {
+		return t.rate
+	}
+
+	sample := float64(copied-t.copied) / elapsed.Seconds()
+	if t.rate == 0 {
+		t.rate = sample
+	} else {
+		// Longer samples weigh more, so the average follows time rather
+		// than the number of reports
+		weight := 1 - math.Exp(-elapsed.Seconds()/smoothing.Seconds())
+		t.rate += weight * (sample - t.rate)
+	}
+	t.at, t.copied = now, copied
+	return t.rate
+}
@@ -0,0 +1,52 @@
+package usbexport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThroughput(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var rate throughput
+	assert.Zero(t, rate.add(0, start))
+
+	// Reports closer than the sample interval do not count yet
+	assert.Zero(t, rate.add(50<<20, start.Add(100*time.Millisecond)))
+
+	// The first sample is taken as it is
+	assert.InDelta(t, 20<<20, rate.add(20<<20, start.Add(time.Second)), 1)
+
+	// A slower second moves the average towards it, but not all the way
+	smoothed := rate.add(30<<20, start.Add(2*time.Second))
+	assert.Less(t, smoothed, float64(20<<20))
+	assert.Greater(t, smoothed, float64(10<<20))
+
+	// Long enough at the new speed, the average settles on it
+	copied := uint64(30 << 20)
+	at := start.Add(2 * time.Second)
+	for i := 0; i < 60; i++ {
+		copied += 10 << 20
+		at = at.Add(time.Second)
+		smoothed = rate.add(copied, at)
+	}
+	assert.InDelta(t, 10<<20, smoothed, 1<<10)
+}
+
+func TestStatus(t *testing.T) {
+	status := Status{Copied: 42, Total: 100}
+	assert.Equal(t, 42, status.Percent())
+	_, ok := status.ETA()
+	assert.False(t, ok, "unknown before the rate")
+
+	status.Rate = 0.5
+	eta, ok := status.ETA()
+	assert.True(t, ok)
+	assert.Equal(t, 116*time.Second, eta)
+
+	status.Copied = 100
+	eta, _ = status.ETA()
+	assert.Zero(t, eta)
+	assert.Equal(t, 100, Status{}.Percent())
+}
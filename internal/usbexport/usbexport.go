@@ -0,0 +1,288 @@
+// Package usbexport copies a directory of the NAS, e.g. a share or its
+// latest snapshot, to the USB device mounted at the copy source, for copy
+// profiles that go NAS to USB.
+//
+// Prepare measures the directory and checks the device can hold it before
+// anything is written; Run copies it, reporting progress in bytes along with
+// the smoothed throughput, from which Status estimates the time left. Follow
+// reports the same for the copies of profiles going USB to NAS, which their
+// command makes, from the space they take.
+package usbexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// bufferSize is how much is copied between two progress reports
+const bufferSize = 1024 * 1024
+
+// ErrNoSpace is returned by Prepare when the device is too small for the
+// export
+var ErrNoSpace = errors.New("not enough space on USB device")
+
+// ErrNotMounted is returned by Prepare when nothing is mounted at the
+// destination, so the export would fill the NAS's own disk instead
+var ErrNotMounted = errors.New("no USB device mounted")
+
+// Progress is told the status of the export after every chunk
+type Progress func(status Status)
+
+// Job is an export measured and checked by Prepare
+type Job struct {
+	// Source is the directory exported
+	Source string
+	// Target is the directory created on the device
+	Target string
+	// Files and Bytes count the regular files below Source
+	Files int
+	Bytes uint64
+	// Needed is the space the files take on the device, rounded up to its
+	// blocks, and Free the space available there
+	Needed uint64
+	Free   uint64
+	// Skipped names the entries Run could not copy, relative to Source, e.g.
+	// symbolic links on a FAT formatted stick, sockets or device files
+	Skipped []string
+}
+
+// LatestSnapshot returns the newest directory directly below dir, e.g. the
+// last snapshot in a share's snapshot directory
+func LatestSnapshot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	type snapshot struct {
+		path    string
+		modTime int64
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{filepath.Join(dir, entry.Name()), info.ModTime().UnixNano()})
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no snapshot in %s", dir)
+	}
+
+	// Equal times fall back to the name, which snapshot tools date
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].modTime != snapshots[j].modTime {
+			return snapshots[i].modTime > snapshots[j].modTime
+		}
+		return snapshots[i].path > snapshots[j].path
+	})
+	return snapshots[0].path, nil
+}
+
+// Prepare measures source and checks that the device mounted at
+// destination can hold it. The copy goes to destination/name, which must not
+// exist yet.
+func Prepare(source, destination, name string) (*Job, error) {
+	if err := mountCheck(destination); err != nil {
+		return nil, err
+	}
+	target := filepath.Join(destination, name)
+	if _, err := os.Lstat(target); err == nil {
+		return nil, fmt.Errorf("%s already exists", target)
+	}
+
+	var stat unix.Statfs_t
+	if err := unix.Statfs(destination, &stat); err != nil {
+		return nil, fmt.Errorf("failed to read free space of %s: %w", destination, err)
+	}
+	blockSize := uint64(stat.Bsize)
+	job := &Job{Source: source, Target: target, Free: stat.Bavail * blockSize}
+
+	err := filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Every directory takes at least a block too
+		if !entry.Type().IsRegular() {
+			if entry.IsDir() {
+				job.Needed += blockSize
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size := uint64(info.Size())
+		job.Files++
+		job.Bytes += size
+		job.Needed += (size + blockSize - 1) / blockSize * blockSize
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", source, err)
+	}
+
+	if err := job.checkSpace(); err != nil {
+		return job, err
+	}
+	return job, nil
+}
+
+// checkSpace fails if the files need more space than is free
+func (j *Job) checkSpace() error {
+	if j.Needed > j.Free {
+		return fmt.Errorf("%w: %s needs %d bytes, %d free", ErrNoSpace, j.Source, j.Needed, j.Free)
+	}
+	return nil
+}
+
+// mountCheck is CheckMounted, replaced by tests writing to a temporary
+// directory
+var mountCheck = CheckMounted
+
+// CheckMounted fails with ErrNotMounted unless a file system other than its
+// parent's is mounted at dir
+func CheckMounted(dir string) error {
+	var own, parent unix.Stat_t
+	if err := unix.Stat(dir, &own); err != nil {
+		return fmt.Errorf("%w at %s: %v", ErrNotMounted, dir, err)
+	}
+	if err := unix.Stat(filepath.Dir(filepath.Clean(dir)), &parent); err != nil {
+		return fmt.Errorf("%w at %s: %v", ErrNotMounted, dir, err)
+	}
+	if own.Dev == parent.Dev {
+		return fmt.Errorf("%w at %s", ErrNotMounted, dir)
+	}
+	return nil
+}
+
+// Run copies the files, keeping their modes and modification times, and
+// flushes the device so it can be pulled once Run returns. A failed or
+// cancelled export removes what it copied rather than leave half a share on
+// the device.
+func (j *Job) Run(ctx context.Context, progress Progress) error {
+	if progress == nil {
+		progress = func(status Status) {}
+	}
+	var rate throughput
+	status := Status{Total: j.Bytes, Rate: rate.add(0, time.Now())}
+	progress(status)
+
+	buffer := make([]byte, bufferSize)
+	err := filepath.WalkDir(j.Source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(j.Source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(j.Target, relative)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.Mkdir(target, info.Mode().Perm()|0o700)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err == nil {
+				err = os.Symlink(link, target)
+			}
+			if err != nil {
+				j.Skipped = append(j.Skipped, relative)
+			}
+			return nil
+		case !entry.Type().IsRegular():
+			j.Skipped = append(j.Skipped, relative)
+			return nil
+		}
+
+		return copyFile(ctx, path, target, info, buffer, func(n int) {
+			status.Copied += uint64(n)
+			status.Rate = rate.add(status.Copied, time.Now())
+			progress(status)
+		})
+	})
+	if err == nil {
+		err = syncDir(j.Target)
+	}
+	if err != nil {
+		os.RemoveAll(j.Target)
+		return err
+	}
+	return nil
+}
+
+// copyFile copies one regular file, telling written about every chunk
+func copyFile(ctx context.Context, source, target string, info fs.FileInfo, buffer []byte, written func(int)) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			out.Close()
+			return err
+		}
+		n, err := in.Read(buffer)
+		if n > 0 {
+			if _, err := out.Write(buffer[:n]); err != nil {
+				out.Close()
+				return err
+			}
+			written(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// FAT file systems keep times at two second precision; close enough
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+// syncDir flushes the file system holding dir
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := unix.Syncfs(int(f.Fd())); err != nil {
+		return fmt.Errorf("failed to flush USB device: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package usbexport
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// withoutMountCheck lets the tests export to a temporary directory
+func withoutMountCheck(t *testing.T) {
+	mountCheck = func(string) error { return nil }
+	t.Cleanup(func() { mountCheck = CheckMounted })
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestLatestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LatestSnapshot(dir)
+	assert.Error(t, err)
+
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"GMT-2024.05.01", "GMT-2024.05.02", "GMT-2024.04.30"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0o755))
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), old, old))
+	}
+	writeFile(t, filepath.Join(dir, "README"), "not a snapshot")
+
+	latest, err := LatestSnapshot(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "GMT-2024.05.02"), latest)
+}
+
+func TestExport(t *testing.T) {
+	withoutMountCheck(t)
+	source := filepath.Join(t.TempDir(), "Public")
+	writeFile(t, filepath.Join(source, "a.txt"), "hello")
+	writeFile(t, filepath.Join(source, "sub", "b.txt"), "world!")
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(source, "link")))
+	require.NoError(t, unix.Mkfifo(filepath.Join(source, "sub", "pipe"), 0o644))
+	stamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(source, "a.txt"), stamp, stamp))
+	stick := t.TempDir()
+
+	job, err := Prepare(source, stick, "Public")
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Files)
+	assert.Equal(t, uint64(11), job.Bytes)
+	assert.Greater(t, job.Needed, job.Bytes, "rounded up to blocks")
+
+	var reports []uint64
+	require.NoError(t, job.Run(context.Background(), func(status Status) {
+		assert.Equal(t, uint64(11), status.Total)
+		reports = append(reports, status.Copied)
+	}))
+	assert.Equal(t, uint64(0), reports[0])
+	assert.Equal(t, uint64(11), reports[len(reports)-1])
+
+	content, err := os.ReadFile(filepath.Join(stick, "Public", "sub", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "world!", string(content))
+	info, err := os.Stat(filepath.Join(stick, "Public", "a.txt"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(stamp))
+	link, err := os.Readlink(filepath.Join(stick, "Public", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", link)
+	assert.Equal(t, []string{filepath.Join("sub", "pipe")}, job.Skipped)
+
+	// The same export again would overwrite the first
+	_, err = Prepare(source, stick, "Public")
+	assert.Error(t, err)
+}
+
+func TestExport_Cancelled(t *testing.T) {
+	withoutMountCheck(t)
+	source := t.TempDir()
+	writeFile(t, filepath.Join(source, "a.txt"), "hello")
+	stick := t.TempDir()
+
+	job, err := Prepare(source, stick, "copy")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, job.Run(ctx, nil), context.Canceled)
+	assert.NoDirExists(t, filepath.Join(stick, "copy"), "half an export is removed")
+}
+
+func TestPrepare_NoSpace(t *testing.T) {
+	withoutMountCheck(t)
+	source := t.TempDir()
+	writeFile(t, filepath.Join(source, "a.txt"), "hello")
+
+	job, err := Prepare(source, t.TempDir(), "copy")
+	require.NoError(t, err)
+	require.NoError(t, job.checkSpace())
+	job.Free = job.Needed - 1
+	assert.ErrorIs(t, job.checkSpace(), ErrNoSpace)
+
+	// A destination that is no mount point is refused outright
+	mountCheck = CheckMounted
+	_, err = Prepare(source, t.TempDir(), "copy")
+	assert.ErrorIs(t, err, ErrNotMounted)
+}
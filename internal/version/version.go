@@ -0,0 +1,97 @@
+// Package version reports which build of the daemon is running
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Version and Commit are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/qnap/display-control/internal/version.Version=0.1.0 \
+//	    -X github.com/qnap/display-control/internal/version.Commit=$(git rev-parse --short HEAD)"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// shortCommit is the length a commit hash is shortened to
+const shortCommit = 7
+
+// started is when the process started, close enough for an uptime
+var started = time.Now()
+
+// Info describes the running build
+type Info struct {
+	Version string
+	// Commit is the abbreviated git commit, "unknown" if it was not recorded
+	Commit string
+	// Modified is set when the build had uncommitted changes
+	Modified  bool
+	GoVersion string
+	Platform  string
+	// Uptime is how long the process has been running
+	Uptime time.Duration
+}
+
+// Get returns the running build. Without a commit set at link time the one Go
+// recorded from the source tree is used.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Uptime:    time.Since(started),
+	}
+	if info.Commit == "" {
+		info.Commit, info.Modified = vcsCommit()
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if len(info.Commit) > shortCommit {
+		info.Commit = info.Commit[:shortCommit]
+	}
+	return info
+}
+
+// vcsCommit reads the commit Go stamped into the binary, if any
+func vcsCommit() (string, bool) {
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", false
+	}
+	var commit string
+	var modified bool
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			commit = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	return commit, modified
+}
+
+// Revision is the commit with a "+" when the tree was modified, e.g. "3efb9a7+"
+func (i Info) Revision() string {
+	if i.Modified {
+		return i.Commit + "+"
+	}
+	return i.Commit
+}
+
+// String renders the build on one line, e.g.
+// "0.1.0 (3efb9a7, go1.21.5 linux/amd64)"
+func (i Info) String() string {
+	return fmt.Sprintf("%s (%s, %s %s)", i.Version, i.Revision(), i.GoVersion, i.Platform)
+}
+
+// Panel renders the version and commit on two lines, as written to the LCD
+func (i Info) Panel() string {
+	return "v" + i.Version + "\n" + i.Revision()
+}
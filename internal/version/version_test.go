@@ -0,0 +1,31 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+
+	Version, Commit = "0.1.0", "3efb9a7c0ffee"
+	info := Get()
+	assert.Equal(t, "0.1.0", info.Version)
+	assert.Equal(t, "3efb9a7", info.Commit, "the commit is abbreviated")
+	assert.False(t, info.Modified, "a commit set at link time is taken as is")
+	assert.NotEmpty(t, info.GoVersion)
+	assert.Equal(t, "v0.1.0\n3efb9a7", info.Panel())
+
+	Commit = ""
+	assert.NotEmpty(t, Get().Commit, "falls back to the recorded commit or unknown")
+}
+
+func TestInfoString(t *testing.T) {
+	info := Info{Version: "0.1.0", Commit: "3efb9a7", GoVersion: "go1.21.5", Platform: "linux/amd64"}
+	assert.Equal(t, "0.1.0 (3efb9a7, go1.21.5 linux/amd64)", info.String())
+
+	info.Modified = true
+	assert.Equal(t, "3efb9a7+", info.Revision())
+	assert.Equal(t, "v0.1.0\n3efb9a7+", info.Panel())
+}
@@ -0,0 +1,235 @@
+// Package watcher polls directories and reports files that appear in them.
+//
+// Files already present when a watcher starts are not reported. A new file is
+// reported once its size and modification time stayed the same for one poll
+// interval, so a file that is still being copied into the directory is only
+// reported after the copy finished. Hidden files, which rsync and Samba use
+// for partial transfers, are ignored.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPollInterval is used when no poll interval is given
+const DefaultPollInterval = 2 * time.Second
+
+// Event reports a file that arrived in a watched directory
+type Event struct {
+	// Path is the full path of the file
+	Path string
+	// Name is the file name without the directory
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Handler is called for every arrived file. Events of one watcher are
+// delivered one at a time from its polling goroutine, so a slow handler (such
+// as one waiting for an operator's answer) delays the next poll. ctx is
+// cancelled when the watcher is closed.
+type Handler func(ctx context.Context, event Event)
+
+// fileState is what a poll saw of a file
+type fileState struct {
+	size    int64
+	modTime time.Time
+	// reported is set once the file was passed to the handler
+	reported bool
+}
+
+// Watcher polls one directory for new files matching a pattern
+type Watcher struct {
+	dir      string
+	pattern  string
+	interval time.Duration
+	handler  Handler
+	logger   *logrus.Entry
+
+	// files is only touched by the polling goroutine
+	files map[string]fileState
+
+	mutex   sync.Mutex
+	started bool
+	closed  bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewWatcher creates a watcher for dir. Only files whose name matches pattern
+// (a filepath.Match pattern, "" = all files) are reported. The directory must
+// exist.
+func NewWatcher(dir, pattern string, interval time.Duration, handler Handler) (*Watcher, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access watch directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("watch path %s is not a directory", dir)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid watch pattern %q: %w", pattern, err)
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Watcher{
+		dir:      dir,
+		pattern:  pattern,
+		interval: interval,
+		handler:  handler,
+		logger:   logrus.WithFields(logrus.Fields{"component": "watcher", "dir": dir}),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start records the files already in the directory and starts polling
+func (w *Watcher) Start() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("watcher is closed")
+	}
+	if w.started {
+		return nil
+	}
+
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+	for name, state := range files {
+		state.reported = true
+		files[name] = state
+	}
+	w.files = files
+	w.started = true
+
+	go w.run()
+
+	w.logger.WithField("existing_files", len(files)).Info("Watching directory")
+	return nil
+}
+
+// Close stops polling, cancels a running handler and waits for it to return
+func (w *Watcher) Close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	started := w.started
+	w.cancel()
+	w.mutex.Unlock()
+
+	if started {
+		<-w.done
+	}
+	return nil
+}
+
+// run polls the directory until the watcher is closed
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll compares the directory with the previous poll and reports every new
+// file whose size and modification time settled
+func (w *Watcher) poll() {
+	current, err := w.scan()
+	if err != nil {
+		w.logger.WithError(err).Warn("Failed to scan watch directory")
+		return
+	}
+
+	var arrived []Event
+	for name, state := range current {
+		previous, seen := w.files[name]
+		switch {
+		case !seen:
+			// New file: wait one more poll in case it is still being written
+		case previous.reported:
+			state.reported = true
+		case previous.size == state.size && previous.modTime.Equal(state.modTime):
+			state.reported = true
+			arrived = append(arrived, Event{
+				Path:    filepath.Join(w.dir, name),
+				Name:    name,
+				Size:    state.size,
+				ModTime: state.modTime,
+			})
+		}
+		current[name] = state
+	}
+	// Removed files are forgotten, so a file arriving again under the same
+	// name is reported again
+	w.files = current
+
+	sort.Slice(arrived, func(i, j int) bool { return arrived[i].Name < arrived[j].Name })
+	for _, event := range arrived {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		w.logger.WithFields(logrus.Fields{
+			"file": event.Name,
+			"size": event.Size,
+		}).Info("New file arrived")
+		w.handler(w.ctx, event)
+	}
+}
+
+// scan lists the regular files in the directory matching the pattern
+func (w *Watcher) scan() (map[string]fileState, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch directory: %w", err)
+	}
+
+	files := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if w.pattern != "" {
+			if matched, _ := filepath.Match(w.pattern, entry.Name()); !matched {
+				continue
+			}
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed between listing and stat
+			continue
+		}
+		files[entry.Name()] = fileState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return files, nil
+}
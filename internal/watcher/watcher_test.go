@@ -0,0 +1,113 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startWatcher watches a temporary directory and sends arrived file names
+func startWatcher(t *testing.T, pattern string) (string, chan string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.pdf"), []byte("old"), 0644))
+
+	arrived := make(chan string, 16)
+	w, err := NewWatcher(dir, pattern, 10*time.Millisecond, func(ctx context.Context, event Event) {
+		arrived <- event.Name
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Start())
+	t.Cleanup(func() { w.Close() })
+
+	return dir, arrived
+}
+
+// nextFile waits for the next arrived file or fails the test
+func nextFile(t *testing.T, arrived chan string) string {
+	t.Helper()
+
+	select {
+	case name := <-arrived:
+		return name
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a file")
+		return ""
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, arrived := startWatcher(t, "*.pdf")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".scan.pdf.part"), []byte("partial"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("skip"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scan.pdf"), []byte("new"), 0644))
+	assert.Equal(t, "scan.pdf", nextFile(t, arrived))
+
+	// Neither the existing, hidden or unmatched files nor a repeat are reported
+	select {
+	case name := <-arrived:
+		t.Fatalf("unexpected file %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A file arriving again after removal is reported again
+	require.NoError(t, os.Remove(filepath.Join(dir, "scan.pdf")))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scan.pdf"), []byte("again"), 0644))
+	assert.Equal(t, "scan.pdf", nextFile(t, arrived))
+}
+
+func TestWatcher_GrowingFile(t *testing.T) {
+	dir, arrived := startWatcher(t, "")
+
+	file, err := os.Create(filepath.Join(dir, "big.iso"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	// The file is not reported while it keeps growing
+	deadline := time.Now().Add(150 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		_, err := file.Write([]byte("data"))
+		require.NoError(t, err)
+		select {
+		case name := <-arrived:
+			t.Fatalf("%s reported while still being written", name)
+		case <-time.After(2 * time.Millisecond):
+		}
+	}
+
+	assert.Equal(t, "big.iso", nextFile(t, arrived))
+}
+
+func TestWatcher_Close(t *testing.T) {
+	dir := t.TempDir()
+	handling := make(chan struct{})
+	w, err := NewWatcher(dir, "", 10*time.Millisecond, func(ctx context.Context, event Event) {
+		close(handling)
+		// Waits like an unanswered prompt until the watcher closes
+		<-ctx.Done()
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Start())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+	select {
+	case <-handling:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the handler")
+	}
+
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+	assert.Error(t, w.Start())
+
+	_, err = NewWatcher(filepath.Join(dir, "missing"), "", 0, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,273 @@
+// Package webhook posts panel events, such as the copy button pressed or a
+// copy finished, as JSON to user defined URLs.
+//
+// Every hook is posted to in the background, in the order of the events,
+// so a slow or unreachable URL delays neither the panel nor the other hooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The events posted
+const (
+	// EventCopyButton is the copy button pressed
+	EventCopyButton = "copy_button"
+	// EventCopyDone is a USB copy or export that succeeded
+	EventCopyDone = "copy_done"
+	// EventCopyFailed is a USB copy or export that failed
+	EventCopyFailed = "copy_failed"
+	// EventLongPress is a panel button held and released
+	EventLongPress = "long_press"
+)
+
+// Events are all events that can be posted
+var Events = []string{EventCopyButton, EventCopyDone, EventCopyFailed, EventLongPress}
+
+// DefaultTimeout bounds one request unless the hook says otherwise
+const DefaultTimeout = 10 * time.Second
+
+// DefaultRetryDelay is the wait before a failed request is repeated unless
+// the hook says otherwise
+const DefaultRetryDelay = 5 * time.Second
+
+// queueSize is how many events wait for a hook before new ones are dropped
+const queueSize = 64
+
+// Hook is a URL the events are posted to
+type Hook struct {
+	URL string
+	// Events are the events posted; empty posts all of them
+	Events []string
+	// Headers are sent with every request, e.g. an Authorization header
+	Headers map[string]string
+	// Timeout bounds one request (0 = DefaultTimeout)
+	Timeout time.Duration
+	// Retries is how often a failed request is repeated
+	Retries int
+	// RetryDelay is the wait before a repeat (0 = DefaultRetryDelay)
+	RetryDelay time.Duration
+}
+
+// Validate checks the URL and the events of the hook
+func (h Hook) Validate() error {
+	address, err := url.Parse(h.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url %q: %w", h.URL, err)
+	}
+	if (address.Scheme != "http" && address.Scheme != "https") || address.Host == "" {
+		return fmt.Errorf("invalid webhook url %q: expected http:// or https://", h.URL)
+	}
+	for _, event := range h.Events {
+		if !known(event) {
+			return fmt.Errorf("unknown webhook event %q (available: copy_button, copy_done, copy_failed, long_press)", event)
+		}
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("webhook %s: retries must not be negative", h.URL)
+	}
+	return nil
+}
+
+// known reports whether event is one of Events
+func known(event string) bool {
+	for _, name := range Events {
+		if name == event {
+			return true
+		}
+	}
+	return false
+}
+
+// wants reports whether the hook posts event
+func (h Hook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, name := range h.Events {
+		if name == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body posted
+type Payload struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// Host is the name of the NAS, for receivers serving several
+	Host string `json:"host"`
+	// Fields are details of the event, such as the copy profile
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Dispatcher posts events to the hooks
+type Dispatcher struct {
+	hooks  []Hook
+	host   string
+	client *http.Client
+	logger *logrus.Entry
+
+	mutex  sync.Mutex
+	queues []chan Payload
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher for hooks, not yet posting. The hooks
+// must be valid.
+func NewDispatcher(hooks []Hook) *Dispatcher {
+	host, _ := os.Hostname()
+	return &Dispatcher{
+		hooks:  hooks,
+		host:   host,
+		client: &http.Client{},
+		logger: logrus.WithField("component", "webhook"),
+	}
+}
+
+// Start posts the events dispatched from now on in the background
+func (d *Dispatcher) Start() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.queues = make([]chan Payload, len(d.hooks))
+	for i, hook := range d.hooks {
+		hook, queue := hook, make(chan Payload, queueSize)
+		d.queues[i] = queue
+		d.done.Add(1)
+		go func() {
+			defer d.done.Done()
+			d.run(ctx, hook, queue)
+		}()
+	}
+}
+
+// Stop ends posting and waits for requests in progress, which are cancelled.
+// Events still queued are dropped.
+func (d *Dispatcher) Stop() {
+	d.mutex.Lock()
+	cancel := d.cancel
+	d.cancel = nil
+	d.queues = nil
+	d.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		d.done.Wait()
+	}
+}
+
+// Dispatch queues event for the hooks that post it. It does not wait for
+// them; when a hook has fallen too far behind, the event is dropped for it.
+func (d *Dispatcher) Dispatch(event string, fields map[string]string) {
+	payload := Payload{Event: event, Time: time.Now(), Host: d.host, Fields: fields}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, queue := range d.queues {
+		if !d.hooks[i].wants(event) {
+			continue
+		}
+		select {
+		case queue <- payload:
+		default:
+			d.logger.WithFields(logrus.Fields{"url": d.hooks[i].URL, "event": event}).Warn("Webhook queue full, event dropped")
+		}
+	}
+}
+
+// run posts the queued events to one hook until ctx ends
+func (d *Dispatcher) run(ctx context.Context, hook Hook, queue <-chan Payload) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-queue:
+			logger := d.logger.WithFields(logrus.Fields{"url": hook.URL, "event": payload.Event})
+			if err := d.deliver(ctx, hook, payload); err != nil && ctx.Err() == nil {
+				logger.WithError(err).Warn("Failed to post webhook")
+			} else if err == nil {
+				logger.Debug("Posted webhook")
+			}
+		}
+	}
+}
+
+// deliver posts payload, repeating failed requests as often as the hook
+// allows. Requests the receiver rejects with a 4xx status other than 429
+// are not repeated.
+func (d *Dispatcher) deliver(ctx context.Context, hook Hook, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	delay := hook.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := d.post(ctx, hook, body)
+		if err == nil || !retry || attempt >= hook.Retries {
+			return err
+		}
+		d.logger.WithError(err).WithField("url", hook.URL).Debug("Webhook failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// post sends one request. It reports whether a failure is worth repeating.
+func (d *Dispatcher) post(ctx context.Context, hook Hook, body []byte) (bool, error) {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range hook.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	retry := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook answered %s", response.Status)
+}
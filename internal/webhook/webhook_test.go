@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver answers webhooks with the statuses given in turn, then 200, and
+// keeps the payloads and headers received
+type receiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	payloads []Payload
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var payload Payload
+	json.NewDecoder(req.Body).Decode(&payload)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.payloads = append(r.payloads, payload)
+	r.headers = append(r.headers, req.Header)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// received returns the payloads received so far
+func (r *receiver) received() []Payload {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Payload(nil), r.payloads...)
+}
+
+func TestDispatcher(t *testing.T) {
+	copies, all := &receiver{}, &receiver{}
+	copyServer, allServer := httptest.NewServer(copies), httptest.NewServer(all)
+	defer copyServer.Close()
+	defer allServer.Close()
+
+	dispatcher := NewDispatcher([]Hook{
+		{URL: copyServer.URL, Events: []string{EventCopyDone, EventCopyFailed}, Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{URL: allServer.URL},
+	})
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	dispatcher.Dispatch(EventCopyButton, nil)
+	dispatcher.Dispatch(EventCopyDone, map[string]string{"profile": "Import photos"})
+
+	require.Eventually(t, func() bool { return len(all.received()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(copies.received()) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	payload := copies.received()[0]
+	assert.Equal(t, EventCopyDone, payload.Event)
+	assert.Equal(t, "Import photos", payload.Fields["profile"])
+	assert.False(t, payload.Time.IsZero())
+	assert.Equal(t, "Bearer secret", copies.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/json", copies.headers[0].Get("Content-Type"))
+
+	assert.Equal(t, EventCopyButton, all.received()[0].Event, "events arrive in order")
+	assert.Equal(t, EventCopyDone, all.received()[1].Event)
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	flaky := &receiver{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	dispatcher := NewDispatcher([]Hook{{URL: server.URL, Retries: 2, RetryDelay: time.Millisecond}})
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	dispatcher.Dispatch(EventLongPress, nil)
+	require.Eventually(t, func() bool { return len(flaky.received()) == 3 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcher_RejectedNotRetried(t *testing.T) {
+	rejecting := &receiver{statuses: []int{http.StatusBadRequest, http.StatusBadRequest}}
+	server := httptest.NewServer(rejecting)
+	defer server.Close()
+
+	dispatcher := NewDispatcher([]Hook{{URL: server.URL, Retries: 3, RetryDelay: time.Millisecond}})
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	dispatcher.Dispatch(EventCopyFailed, nil)
+	dispatcher.Dispatch(EventCopyDone, nil)
+	require.Eventually(t, func() bool { return len(rejecting.received()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, EventCopyDone, rejecting.received()[1].Event, "the next event follows without a repeat")
+}
+
+func TestDispatcher_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	dispatcher := NewDispatcher([]Hook{{URL: server.URL, Timeout: 20 * time.Millisecond}})
+	retry, err := dispatcher.post(context.Background(), dispatcher.hooks[0], []byte("{}"))
+	require.Error(t, err)
+	assert.True(t, retry, "timeouts are repeated")
+}
+
+func TestHookValidate(t *testing.T) {
+	assert.NoError(t, Hook{URL: "https://hooks.example.com/nas", Events: []string{EventCopyDone}}.Validate())
+	assert.Error(t, Hook{URL: "hooks.example.com/nas"}.Validate())
+	assert.Error(t, Hook{URL: "ftp://hooks.example.com/"}.Validate())
+	assert.Error(t, Hook{URL: "http://hooks.example.com/", Events: []string{"copy_started"}}.Validate())
+	assert.Error(t, Hook{URL: "http://hooks.example.com/", Retries: -1}.Validate())
+}
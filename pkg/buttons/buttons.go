@@ -0,0 +1,51 @@
+// Package buttons names the buttons of the QNAP front panel and the
+// handlers told about presses and releases.
+package buttons
+
+import "fmt"
+
+// Button is one of the panel buttons
+type Button int
+
+const (
+	Enter Button = iota
+	Select
+	// Copy is the USB copy button
+	Copy
+)
+
+// String returns the name of the button
+func (b Button) String() string {
+	switch b {
+	case Enter:
+		return "enter"
+	case Select:
+		return "select"
+	case Copy:
+		return "copy"
+	default:
+		return fmt.Sprintf("button(%d)", int(b))
+	}
+}
+
+// Parse returns the button of a name String returns
+func Parse(name string) (Button, error) {
+	for _, b := range []Button{Enter, Select, Copy} {
+		if b.String() == name {
+			return b, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown button %q (available: enter, select, copy)", name)
+}
+
+// Handler is told every press and release. Calls come one at a time, in the
+// order the panel reported the events, so it should return quickly: later
+// events wait for it.
+type Handler func(button Button, pressed bool)
+
+// Source reports button events to a handler. The displays of pkg/display
+// are sources.
+type Source interface {
+	// SetButtonHandler sets the handler (nil = none)
+	SetButtonHandler(handler Handler)
+}
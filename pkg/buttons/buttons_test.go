@@ -0,0 +1,32 @@
+package buttons
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, b := range []Button{Enter, Select, Copy} {
+		parsed, err := Parse(b.String())
+		require.NoError(t, err)
+		assert.Equal(t, b, parsed)
+	}
+	_, err := Parse("power")
+	assert.Error(t, err)
+	assert.Equal(t, "button(7)", Button(7).String())
+}
+
+func TestMockSource(t *testing.T) {
+	var m MockSource
+	m.Click(Enter) // no handler yet
+
+	var events []string
+	m.SetButtonHandler(func(button Button, pressed bool) {
+		events = append(events, button.String()+map[bool]string{true: " down", false: " up"}[pressed])
+	})
+	m.Click(Copy)
+	m.Press(Select)
+	assert.Equal(t, []string{"copy down", "copy up", "select down"}, events)
+}
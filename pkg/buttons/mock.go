@@ -0,0 +1,45 @@
+package buttons
+
+import "sync"
+
+// MockSource is a Source whose buttons are pressed by the caller, for tests
+// of code that takes button events
+type MockSource struct {
+	mutex   sync.Mutex
+	handler Handler
+}
+
+// SetButtonHandler sets the handler told the events
+func (m *MockSource) SetButtonHandler(handler Handler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handler = handler
+}
+
+// Press reports a press of button
+func (m *MockSource) Press(button Button) {
+	m.send(button, true)
+}
+
+// Release reports a release of button
+func (m *MockSource) Release(button Button) {
+	m.send(button, false)
+}
+
+// Click reports a press and a release of button
+func (m *MockSource) Click(button Button) {
+	m.Press(button)
+	m.Release(button)
+}
+
+// send tells the handler, if there is one, outside the lock
+func (m *MockSource) send(button Button, pressed bool) {
+	m.mutex.Lock()
+	handler := m.handler
+	m.mutex.Unlock()
+
+	if handler != nil {
+		handler(button, pressed)
+	}
+}
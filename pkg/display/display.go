@@ -0,0 +1,129 @@
+// Package display writes to the front panel display of a QNAP NAS: the
+// stock serial character LCD, the SSD1306 and SH1106 I2C OLEDs and the
+// Matrix Orbital and Crystalfontz serial LCDs some boxes are fitted with.
+//
+// The panel has one owner. While the display-control service runs it holds
+// the serial port, so programs next to it should go through its control
+// socket or gRPC API instead of calling Open.
+package display
+
+import (
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/qnapproto"
+	"github.com/qnap/display-control/pkg/buttons"
+)
+
+// DefaultScrollSpeed is the step of ScrollText when it is given no speed
+const DefaultScrollSpeed = controller.DefaultScrollSpeed
+
+// Drivers selectable with Options.Driver
+const (
+	QNAP          = controller.DriverQNAP
+	QNAPLCM       = controller.DriverQNAPLCM
+	SSD1306       = controller.DriverSSD1306
+	SH1106        = controller.DriverSH1106
+	MatrixOrbital = controller.DriverMatrixOrbital
+	CrystalFontz  = controller.DriverCrystalFontz
+)
+
+// Display is a panel of text rows. Rows and columns count from 0; text past
+// the end of a row is cut off. Open returns the real panel and NewMock one
+// that records what it is told.
+type Display interface {
+	// WriteText replaces the whole display, rows separated by "\n"
+	WriteText(text string) error
+	// WriteTextAt writes text on row starting at col
+	WriteTextAt(text string, row, col int) error
+	// WriteLines replaces the given rows in one frame
+	WriteLines(lines map[int]string) error
+	ClearDisplay() error
+	SetBacklight(on bool) error
+	// ShowProgress draws a progress bar of percent (0 to 100) on the
+	// bottom row
+	ShowProgress(percent int) error
+	// DefineCustomChar replaces the custom character in slot (0 to 7),
+	// shown in text as "{slotN}"; bitmap holds its pixel rows from the top
+	// in the lowest five bits
+	DefineCustomChar(slot int, bitmap [8]byte) error
+	// ScrollText shows text on row, scrolling it across the panel every
+	// speed (DefaultScrollSpeed if not positive) until StopScroll or the next
+	// ScrollText on that row; text that fits is written once
+	ScrollText(row int, text string, speed time.Duration) error
+	StopScroll(row int)
+	// The panel's buttons are reported to the handler set with
+	// SetButtonHandler
+	buttons.Source
+	Close() error
+}
+
+// ErrNoGlyphs is returned by DefineCustomChar on a panel that has no custom
+// characters, such as the stock LCD without a glyph command configured
+var ErrNoGlyphs = controller.ErrNoGlyphs
+
+// ShowBigNumber draws text, such as "23:59", in digits two rows high on the
+// top rows of a display width columns wide, centred, for clocks and
+// countdowns readable across the room. The digits are built of custom
+// characters, which replace the first glyphs until RestoreGlyphs. Digits,
+// ':', '.', '-' and ' ' can be drawn; a 16 column panel fits "23:59".
+func ShowBigNumber(d Display, text string, width int) error {
+	lines, err := controller.RenderBigNumber(text, width)
+	if err != nil {
+		return err
+	}
+	if err := controller.LoadBigFont(d); err != nil {
+		return err
+	}
+	return d.WriteLines(map[int]string{0: lines[0], 1: lines[1]})
+}
+
+// RestoreGlyphs defines the glyphs ShowBigNumber replaced again
+func RestoreGlyphs(d Display) error {
+	return controller.RestoreGlyphs(d)
+}
+
+// The service's displays satisfy Display
+var _ Display = controller.DisplayControllerInterface(nil)
+
+// Options select and size the panel. Zero values keep the defaults of the
+// stock QNAP panel: a 16x2 LCD on /dev/ttyS1 at 1200 baud.
+type Options struct {
+	// Driver is one of the driver constants (default QNAP)
+	Driver string
+	// Device and BaudRate are the serial port of the LCD drivers; QNAPLCM
+	// panels default to 115200 baud
+	Device   string
+	BaudRate int
+	// I2CBus is the N of /dev/i2c-N and I2CAddress the 7 bit address
+	// (default 0x3C) of the OLED drivers
+	I2CBus     int
+	I2CAddress uint16
+	Width      int
+	Height     int
+}
+
+// Open opens the panel described by opts
+func Open(opts Options) (Display, error) {
+	cfg := config.DefaultConfig()
+	cfg.Display.Driver = opts.Driver
+	if opts.Device != "" {
+		cfg.SerialPort.Device = opts.Device
+	}
+	if opts.Driver == QNAPLCM {
+		cfg.SerialPort.BaudRate = qnapproto.LCMBaudRate
+	}
+	if opts.BaudRate > 0 {
+		cfg.SerialPort.BaudRate = opts.BaudRate
+	}
+	cfg.Display.OLED.Bus = opts.I2CBus
+	cfg.Display.OLED.Address = opts.I2CAddress
+	if opts.Width > 0 {
+		cfg.Display.Width = opts.Width
+	}
+	if opts.Height > 0 {
+		cfg.Display.Height = opts.Height
+	}
+	return controller.OpenDisplay(cfg)
+}
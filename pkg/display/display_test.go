@@ -0,0 +1,73 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/pkg/buttons"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	var d Display = NewMock(16, 2)
+	mock := d.(*Mock)
+
+	require.NoError(t, d.WriteText("Hello\nWorld"))
+	require.NoError(t, d.WriteTextAt("NAS", 1, 6))
+	assert.Equal(t, "Hello\nWorld NAS", mock.Text())
+	assert.Error(t, d.WriteTextAt("x", 2, 0))
+
+	require.NoError(t, d.WriteLines(map[int]string{0: "A line far longer than the panel"}))
+	assert.Equal(t, []string{"A line far longe", "World NAS       "}, mock.Lines())
+
+	require.NoError(t, d.ShowProgress(50))
+	assert.Equal(t, "[=======       ]", mock.Lines()[1])
+
+	degree := [8]byte{0x06, 0x09, 0x09, 0x06}
+	require.NoError(t, d.DefineCustomChar(2, degree))
+	bitmap, ok := mock.CustomChar(2)
+	assert.True(t, ok)
+	assert.Equal(t, degree, bitmap)
+	assert.Error(t, d.DefineCustomChar(8, degree))
+	require.NoError(t, d.WriteTextAt("21{slot2}C", 0, 10))
+	assert.Equal(t, "A line far21\x02Cge", mock.Lines()[0])
+
+	require.NoError(t, ShowBigNumber(d, "1", 16))
+	assert.Equal(t, []string{"      \x01\x00        ", "      \x02\x00\x02       "}, mock.Lines())
+	full, _ := mock.CustomChar(0)
+	assert.Equal(t, [8]byte{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}, full)
+	assert.Error(t, ShowBigNumber(d, "12:34:56", 16))
+	require.NoError(t, RestoreGlyphs(d))
+
+	require.NoError(t, d.ScrollText(1, "Short", 0))
+	assert.Equal(t, "Short", strings.TrimSpace(mock.Lines()[1]))
+	require.NoError(t, d.ScrollText(0, "Scrolling far past the panel", 20*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		return strings.HasPrefix(mock.Lines()[0], "crolling")
+	}, time.Second, time.Millisecond)
+	d.StopScroll(0)
+
+	require.NoError(t, d.SetBacklight(false))
+	assert.False(t, mock.Backlight())
+
+	var got []buttons.Button
+	d.SetButtonHandler(func(button buttons.Button, pressed bool) {
+		if pressed {
+			got = append(got, button)
+		}
+	})
+	mock.Click(buttons.Select)
+	mock.Click(buttons.Enter)
+	assert.Equal(t, []buttons.Button{buttons.Select, buttons.Enter}, got)
+
+	require.NoError(t, d.Close())
+	assert.Error(t, d.ClearDisplay())
+}
+
+func TestOpen_UnknownDriver(t *testing.T) {
+	_, err := Open(Options{Driver: "st7920"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown display driver")
+}
@@ -0,0 +1,219 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/pkg/buttons"
+)
+
+// Mock is a Display keeping its rows in memory, for tests of code that
+// draws on the panel. Its buttons are pressed with the methods of
+// buttons.MockSource.
+type Mock struct {
+	buttons.MockSource
+
+	mutex     sync.Mutex
+	width     int
+	rows      []string
+	backlight bool
+	closed    bool
+	glyphs    map[int][8]byte
+	marquees  controller.Marquees
+}
+
+// NewMock creates a blank mock of width columns and height rows with the
+// backlight on
+func NewMock(width, height int) *Mock {
+	m := &Mock{width: width, rows: make([]string, height), backlight: true, glyphs: make(map[int][8]byte)}
+	m.clear()
+	return m
+}
+
+// WriteText replaces the whole display
+func (m *Mock) WriteText(text string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.check(0); err != nil {
+		return err
+	}
+	m.clear()
+	for row, line := range strings.Split(text, "\n") {
+		if row < len(m.rows) {
+			m.write(line, row, 0)
+		}
+	}
+	return nil
+}
+
+// WriteTextAt writes text on row starting at col
+func (m *Mock) WriteTextAt(text string, row, col int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.check(row); err != nil {
+		return err
+	}
+	m.write(text, row, col)
+	return nil
+}
+
+// WriteLines replaces the given rows
+func (m *Mock) WriteLines(lines map[int]string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for row := range lines {
+		if err := m.check(row); err != nil {
+			return err
+		}
+	}
+	for row, line := range lines {
+		m.rows[row] = strings.Repeat(" ", m.width)
+		m.write(line, row, 0)
+	}
+	return nil
+}
+
+// ClearDisplay blanks all rows
+func (m *Mock) ClearDisplay() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.check(0); err != nil {
+		return err
+	}
+	m.clear()
+	return nil
+}
+
+// SetBacklight switches the backlight
+func (m *Mock) SetBacklight(on bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.check(0); err != nil {
+		return err
+	}
+	m.backlight = on
+	return nil
+}
+
+// ShowProgress draws the bar of the real panel on the bottom row
+func (m *Mock) ShowProgress(percent int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	row := len(m.rows) - 1
+	if err := m.check(row); err != nil {
+		return err
+	}
+	m.rows[row] = strings.Repeat(" ", m.width)
+	m.write(controller.RenderProgressBarWidth(percent, m.width), row, 0)
+	return nil
+}
+
+// DefineCustomChar keeps bitmap for slot, see CustomChar
+func (m *Mock) DefineCustomChar(slot int, bitmap [8]byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.check(0); err != nil {
+		return err
+	}
+	if slot < 0 || slot >= controller.GlyphSlots {
+		return fmt.Errorf("invalid custom character slot %d", slot)
+	}
+	m.glyphs[slot] = bitmap
+	return nil
+}
+
+// ScrollText scrolls text on row like the real panel, see Lines
+func (m *Mock) ScrollText(row int, text string, speed time.Duration) error {
+	return m.marquees.ScrollText(m, m.width, row, text, speed)
+}
+
+// StopScroll stops scrolling row
+func (m *Mock) StopScroll(row int) {
+	m.marquees.StopScroll(row)
+}
+
+// Close stops scrolling and makes further writes fail
+func (m *Mock) Close() error {
+	m.marquees.StopAll()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.closed = true
+	return nil
+}
+
+// Lines returns the rows, each padded to the width
+func (m *Mock) Lines() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]string(nil), m.rows...)
+}
+
+// Text returns the rows without trailing blanks, separated by "\n"
+func (m *Mock) Text() string {
+	lines := m.Lines()
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Backlight returns whether the backlight is on
+func (m *Mock) Backlight() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.backlight
+}
+
+// CustomChar returns the bitmap last defined for slot, false if none was
+func (m *Mock) CustomChar(slot int) ([8]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	bitmap, ok := m.glyphs[slot]
+	return bitmap, ok
+}
+
+// check fails writes to a closed mock or a row it does not have. Caller must
+// hold the mutex.
+func (m *Mock) check(row int) error {
+	if m.closed {
+		return fmt.Errorf("display closed")
+	}
+	if row < 0 || row >= len(m.rows) {
+		return fmt.Errorf("invalid row: %d", row)
+	}
+	return nil
+}
+
+// clear blanks all rows. Caller must hold the mutex.
+func (m *Mock) clear() {
+	for row := range m.rows {
+		m.rows[row] = strings.Repeat(" ", m.width)
+	}
+}
+
+// write puts text on row from col, cut off at the width, with its glyph
+// escapes expanded like on the real panels. Caller must hold the mutex.
+func (m *Mock) write(text string, row, col int) {
+	line := []rune(m.rows[row])
+	for i, r := range []rune(controller.ExpandGlyphs(text)) {
+		if col+i < 0 || col+i >= len(line) {
+			continue
+		}
+		line[col+i] = r
+	}
+	m.rows[row] = string(line)
+}
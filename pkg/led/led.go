@@ -0,0 +1,431 @@
+// Package led switches the LEDs of the QNAP front panel: the status LED,
+// the USB LED and the disk LEDs. They are driven through I/O ports, which
+// needs root or CAP_SYS_RAWIO when the Panel is opened.
+//
+// The Panel does its port I/O on a worker goroutine: switching an LED only
+// queues the change, so callers such as a copy animation never wait on
+// /dev/port. Changes are written in the order they were made, those of one
+// tick together, and Flush waits until they are written.
+//
+// Other tools, such as qcontrol, may drive the same embedded controller
+// registers. The worker holds hardware.PortLockPath while it reads and writes
+// them and reads each register back after writing it, so changes of tools
+// taking the lock are kept and clashes with the others are logged.
+package led
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/qnap/display-control/internal/hardware"
+	"github.com/sirupsen/logrus"
+)
+
+// LED is one of the panel LEDs
+type LED int
+
+const (
+	StatusGreen LED = iota
+	StatusRed
+	USB
+	Disk1
+	Disk2
+	Disk3
+	Disk4
+	Disk5
+	Disk6
+)
+
+// ledNames are the names String returns
+var ledNames = map[LED]string{
+	StatusGreen: "status-green",
+	StatusRed:   "status-red",
+	USB:         "usb",
+	Disk1:       "disk1",
+	Disk2:       "disk2",
+	Disk3:       "disk3",
+	Disk4:       "disk4",
+	Disk5:       "disk5",
+	Disk6:       "disk6",
+}
+
+// String returns the name of the LED, e.g. "status-red" or "disk1"
+func (l LED) String() string {
+	if name, ok := ledNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("led(%d)", int(l))
+}
+
+// Parse returns the LED of a name String returns, e.g. "disk3"
+func Parse(name string) (LED, error) {
+	for led, ledName := range ledNames {
+		if ledName == name {
+			return led, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown LED %q", name)
+}
+
+// Controller switches the panel LEDs. Panel drives the real ones and Mock
+// records what it is told.
+type Controller interface {
+	SetLED(led LED, on bool) error
+	// SetDiskLEDs switches disk LEDs by disk number, 1 to 6
+	SetDiskLEDs(states map[int]bool) error
+	SetStatusLED(red bool, green bool) error
+	GetLEDStates() (map[LED]bool, error)
+	Close() error
+}
+
+// Panel manages QNAP panel LEDs using hardware I/O ports
+type Panel struct {
+	logger    *logrus.Entry
+	portPerms bool
+	// devPort stays open so LEDs keep working after privileges are dropped;
+	// nil if /dev/port could not be opened up front
+	devPort ports
+	// lock is shared with other tools driving the registers; nil if the
+	// lock file could not be opened
+	lock portLock
+	// written is the last value of each register seen by the worker after
+	// switching its LEDs, to notice changes by other tools
+	written map[byte]byte
+
+	// commands carry the changes to the worker, which does all port I/O
+	commands chan command
+	done     chan struct{}
+	// mutex keeps commands from being sent once closed
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// ports reads and writes I/O ports; *hardware.DevPort or a fake in tests
+type ports interface {
+	ReadPort(port uint16) (byte, error)
+	WritePort(port uint16, value byte) error
+	Close() error
+}
+
+// portLock is taken around each batch of register accesses;
+// *hardware.PortLock or a fake in tests
+type portLock interface {
+	Lock(timeout time.Duration) error
+	Unlock() error
+	Close() error
+}
+
+const (
+	regPort   = 0xa05
+	valuePort = 0xa06
+	portCount = 2
+)
+
+// Port configuration for different LED groups
+type portConfig struct {
+	register byte
+	leds     map[LED]byte // LED -> bit position
+}
+
+var (
+	statusLEDPort = portConfig{
+		register: 0x91,
+		leds: map[LED]byte{
+			StatusGreen: 2,
+			StatusRed:   3,
+		},
+	}
+
+	diskLEDPort = portConfig{
+		register: 0x81,
+		leds: map[LED]byte{
+			Disk1: 0,
+			Disk2: 1,
+			Disk3: 2,
+			Disk4: 3,
+			Disk5: 4, // Extended to use more bits on same port
+			Disk6: 5, // Extended to use more bits on same port
+		},
+	}
+
+	usbLEDPort = portConfig{
+		register: 0xE1,
+		leds: map[LED]byte{
+			USB: 7,
+		},
+	}
+)
+
+// Open gets access to the LED I/O ports. Without it the Panel is returned
+// anyway and switching LEDs does nothing.
+func Open() (*Panel, error) {
+	logger := logrus.WithField("component", "led_controller")
+
+	lc := &Panel{
+		logger: logger,
+	}
+
+	// Try to get I/O port permissions
+	if err := lc.requestPortPermissions(); err != nil {
+		logger.WithError(err).Warn("Failed to get I/O port permissions, LED control will be disabled")
+		return lc, nil // Return controller but mark as non-functional
+	}
+
+	if users := hardware.PortUsers(); len(users) > 0 {
+		logger.WithField("tools", users).Warn("Other tools drive the LED registers, LED changes they do not coordinate may clash")
+	}
+
+	lc.start()
+	logger.Info("LED controller initialized with I/O port access")
+	return lc, nil
+}
+
+// requestPortPermissions requests access to the hardware I/O ports
+func (lc *Panel) requestPortPermissions() error {
+	// Check if running as root
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("LED control requires root privileges")
+	}
+
+	// Request I/O port permissions using ioperm syscall
+	// ioperm(from, num, turn_on)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPERM, regPort, portCount, 1)
+	if errno != 0 {
+		return fmt.Errorf("ioperm failed: %v", errno)
+	}
+
+	lc.portPerms = true
+
+	devPort, err := hardware.OpenDevPort()
+	if err != nil {
+		lc.logger.WithError(err).Debug("Opening /dev/port for each access")
+	} else {
+		lc.devPort = devPort
+	}
+
+	lock, err := hardware.OpenPortLock()
+	if err != nil {
+		lc.logger.WithError(err).Warn("Switching LEDs without the EC port lock")
+	} else {
+		lc.lock = lock
+	}
+	return nil
+}
+
+// Close writes the queued LED changes and releases I/O port permissions
+func (lc *Panel) Close() error {
+	lc.stop()
+	if lc.devPort != nil {
+		lc.devPort.Close()
+		lc.devPort = nil
+	}
+	if lc.lock != nil {
+		lc.lock.Close()
+		lc.lock = nil
+	}
+	if lc.portPerms {
+		// Release I/O port permissions
+		syscall.Syscall(syscall.SYS_IOPERM, regPort, portCount, 0)
+		lc.portPerms = false
+	}
+	return nil
+}
+
+// SetLED switches a specific LED. It returns once the change is queued;
+// the worker writes it within a tick, in the order changes were made.
+func (lc *Panel) SetLED(led LED, on bool) error {
+	if !lc.portPerms {
+		lc.logger.Debug("I/O port permissions not available, skipping LED control")
+		return nil
+	}
+
+	for _, port := range []portConfig{statusLEDPort, diskLEDPort, usbLEDPort} {
+		if _, exists := port.leds[led]; exists {
+			lc.logger.WithFields(logrus.Fields{
+				"led": led,
+				"on":  on,
+			}).Debug("Setting LED state")
+			return lc.send(switchLEDs(port, map[LED]bool{led: on}))
+		}
+	}
+	return fmt.Errorf("unknown LED: %v", led)
+}
+
+// SetDiskLEDs controls all disk LEDs at once
+func (lc *Panel) SetDiskLEDs(states map[int]bool) error {
+	if !lc.portPerms {
+		lc.logger.Debug("I/O port permissions not available, skipping LED control")
+		return nil
+	}
+
+	ledStates := make(map[LED]bool)
+	diskLEDs := []LED{Disk1, Disk2, Disk3, Disk4, Disk5, Disk6}
+
+	for diskNum, state := range states {
+		if diskNum >= 1 && diskNum <= 6 {
+			ledStates[diskLEDs[diskNum-1]] = state
+		}
+	}
+
+	if len(ledStates) == 0 {
+		return nil
+	}
+
+	return lc.send(switchLEDs(diskLEDPort, ledStates))
+}
+
+// SetStatusLED controls the status LED (green or red)
+func (lc *Panel) SetStatusLED(red bool, green bool) error {
+	if !lc.portPerms {
+		lc.logger.Debug("I/O port permissions not available, skipping LED control")
+		return nil
+	}
+
+	ledStates := map[LED]bool{
+		StatusRed:   red,
+		StatusGreen: green,
+	}
+
+	return lc.send(switchLEDs(statusLEDPort, ledStates))
+}
+
+// readPort reads the current state of a hardware port
+func (lc *Panel) readPort(register byte) (byte, error) {
+	// Set register
+	if err := lc.outb(register, regPort); err != nil {
+		return 0, err
+	}
+
+	// Read value
+	return lc.inb(valuePort)
+}
+
+// writePort writes a value to a hardware port
+func (lc *Panel) writePort(register byte, value byte) error {
+	// Set register
+	if err := lc.outb(register, regPort); err != nil {
+		return err
+	}
+
+	// Write value
+	return lc.outb(value, valuePort)
+}
+
+// outb writes a byte to an I/O port using syscall
+func (lc *Panel) outb(value byte, port uint16) error {
+	if lc.devPort != nil {
+		return lc.devPort.WritePort(port, value)
+	}
+
+	// On Linux, we can use /dev/port for I/O port access
+	file, err := os.OpenFile("/dev/port", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open /dev/port: %w", err)
+	}
+	defer file.Close()
+
+	// Seek to the port address
+	if _, err := file.Seek(int64(port), 0); err != nil {
+		return fmt.Errorf("failed to seek to port %x: %w", port, err)
+	}
+
+	// Write the value
+	if _, err := file.Write([]byte{value}); err != nil {
+		return fmt.Errorf("failed to write to port %x: %w", port, err)
+	}
+
+	return nil
+}
+
+// inb reads a byte from an I/O port using syscall
+func (lc *Panel) inb(port uint16) (byte, error) {
+	if lc.devPort != nil {
+		return lc.devPort.ReadPort(port)
+	}
+
+	// On Linux, we can use /dev/port for I/O port access
+	file, err := os.OpenFile("/dev/port", os.O_RDONLY, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open /dev/port: %w", err)
+	}
+	defer file.Close()
+
+	// Seek to the port address
+	if _, err := file.Seek(int64(port), 0); err != nil {
+		return 0, fmt.Errorf("failed to seek to port %x: %w", port, err)
+	}
+
+	// Read the value
+	buffer := make([]byte, 1)
+	if _, err := file.Read(buffer); err != nil {
+		return 0, fmt.Errorf("failed to read from port %x: %w", port, err)
+	}
+
+	return buffer[0], nil
+}
+
+// GetLEDStates returns the current state of all LEDs, including the
+// changes queued before
+func (lc *Panel) GetLEDStates() (map[LED]bool, error) {
+	if !lc.portPerms {
+		return make(map[LED]bool), nil
+	}
+
+	read := make(chan map[LED]bool, 1)
+	if err := lc.send(command{read: read}); err != nil {
+		return nil, err
+	}
+	return <-read, nil
+}
+
+// registerLEDs returns the bits of register that drive LEDs
+func registerLEDs(register byte) byte {
+	var bits byte
+	for _, port := range []portConfig{statusLEDPort, diskLEDPort, usbLEDPort} {
+		if port.register == register {
+			for _, bit := range port.leds {
+				bits |= 1 << bit
+			}
+		}
+	}
+	return bits
+}
+
+// readStates reads the state of all LEDs from the ports. Only the worker
+// calls it.
+func (lc *Panel) readStates() map[LED]bool {
+	states := make(map[LED]bool)
+
+	unlock, err := lc.lockPorts()
+	if err != nil {
+		lc.logger.WithError(err).Warn("Failed to read LED states")
+		return states
+	}
+	defer unlock()
+
+	// Read status LEDs
+	if mask, err := lc.readPort(statusLEDPort.register); err == nil {
+		for led, bit := range statusLEDPort.leds {
+			states[led] = (mask & (1 << bit)) == 0 // Inverted logic
+		}
+	}
+
+	// Read disk LEDs
+	if mask, err := lc.readPort(diskLEDPort.register); err == nil {
+		for led, bit := range diskLEDPort.leds {
+			states[led] = (mask & (1 << bit)) == 0 // Inverted logic
+		}
+	}
+
+	// Read USB LED
+	if mask, err := lc.readPort(usbLEDPort.register); err == nil {
+		for led, bit := range usbLEDPort.leds {
+			states[led] = (mask & (1 << bit)) == 0 // Inverted logic
+		}
+	}
+
+	return states
+}
@@ -0,0 +1,47 @@
+package led
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The real panel and the mock are interchangeable
+var (
+	_ Controller = (*Panel)(nil)
+	_ Controller = (*Mock)(nil)
+)
+
+func TestMock(t *testing.T) {
+	m := NewMock()
+
+	require.NoError(t, m.SetStatusLED(true, false))
+	require.NoError(t, m.SetDiskLEDs(map[int]bool{1: true, 3: false, 7: true}))
+	require.NoError(t, m.SetLED(USB, true))
+	assert.Error(t, m.SetLED(LED(42), true))
+
+	states, err := m.GetLEDStates()
+	require.NoError(t, err)
+	assert.Equal(t, map[LED]bool{StatusRed: true, StatusGreen: false, Disk1: true, Disk3: false, USB: true}, states)
+
+	require.NoError(t, m.Flush())
+	require.NoError(t, m.Close())
+	assert.Error(t, m.SetLED(Disk2, true))
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "status-red", StatusRed.String())
+	assert.Equal(t, "disk6", Disk6.String())
+	assert.Equal(t, "led(42)", LED(42).String())
+}
+
+func TestParse(t *testing.T) {
+	for led := StatusGreen; led <= Disk6; led++ {
+		parsed, err := Parse(led.String())
+		assert.NoError(t, err)
+		assert.Equal(t, led, parsed)
+	}
+	_, err := Parse("disk7")
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package led
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Mock is a Controller keeping the LED states in memory, for tests of code
+// that switches LEDs
+type Mock struct {
+	mutex  sync.Mutex
+	states map[LED]bool
+	closed bool
+}
+
+// NewMock creates a mock with all LEDs off
+func NewMock() *Mock {
+	return &Mock{states: make(map[LED]bool)}
+}
+
+// SetLED switches one LED
+func (m *Mock) SetLED(led LED, on bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return fmt.Errorf("LED controller closed")
+	}
+	if led < StatusGreen || led > Disk6 {
+		return fmt.Errorf("unknown LED: %v", led)
+	}
+	m.states[led] = on
+	return nil
+}
+
+// SetDiskLEDs switches disk LEDs by disk number, ignoring numbers other
+// than 1 to 6 like Panel does
+func (m *Mock) SetDiskLEDs(states map[int]bool) error {
+	for diskNum, on := range states {
+		if diskNum < 1 || diskNum > 6 {
+			continue
+		}
+		if err := m.SetLED(Disk1+LED(diskNum-1), on); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStatusLED switches both colours of the status LED
+func (m *Mock) SetStatusLED(red bool, green bool) error {
+	if err := m.SetLED(StatusRed, red); err != nil {
+		return err
+	}
+	return m.SetLED(StatusGreen, green)
+}
+
+// GetLEDStates returns the state of every LED switched so far
+func (m *Mock) GetLEDStates() (map[LED]bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	states := make(map[LED]bool, len(m.states))
+	for led, on := range m.states {
+		states[led] = on
+	}
+	return states, nil
+}
+
+// Flush does nothing, the mock switches LEDs right away; it is there so
+// tests can treat the mock like a Panel
+func (m *Mock) Flush() error {
+	return nil
+}
+
+// Close makes further switching fail
+func (m *Mock) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.closed = true
+	return nil
+}
@@ -0,0 +1,261 @@
+package led
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ledTick is how long the worker collects changes before writing them, so a
+// copy animation switching several disk LEDs writes their register once
+const ledTick = 10 * time.Millisecond
+
+// ledQueue is how many commands wait for the worker before senders block
+const ledQueue = 64
+
+// portLockTimeout is how long the worker waits for another tool to release
+// the EC port lock before giving up on a batch
+const portLockTimeout = time.Second
+
+// writeRetries is how often a write is repeated when reading the register
+// back shows that another tool switched the same LEDs in between
+const writeRetries = 1
+
+// errClosed is returned for LEDs switched after Close
+var errClosed = errors.New("LED controller closed")
+
+// command is one request to the worker. Commands are carried out in the
+// order they were sent, so the last change of an LED wins.
+type command struct {
+	// register and bits switch LEDs: bits are the bits of the LEDs switched
+	// and off those of them switched off (a set bit turns the LED off)
+	register byte
+	bits     byte
+	off      byte
+	// flush, if set, gets the first write error since the last flush once
+	// everything sent before is written
+	flush chan error
+	// read, if set, gets the LED states once everything sent before is
+	// written
+	read chan map[LED]bool
+}
+
+// switchLEDs returns the command switching LEDs of one port
+func switchLEDs(port portConfig, states map[LED]bool) command {
+	cmd := command{register: port.register}
+	for led, on := range states {
+		if bit, exists := port.leds[led]; exists {
+			cmd.bits |= 1 << bit
+			if !on {
+				cmd.off |= 1 << bit
+			}
+		}
+	}
+	return cmd
+}
+
+// batch is the changes collected during one tick, per register
+type batch struct {
+	// registers are in the order they were first changed
+	registers []byte
+	bits      map[byte]byte
+	off       map[byte]byte
+}
+
+func newBatch() *batch {
+	return &batch{bits: make(map[byte]byte), off: make(map[byte]byte)}
+}
+
+// add merges a change into the batch, replacing earlier changes of its LEDs
+func (b *batch) add(cmd command) {
+	if _, exists := b.bits[cmd.register]; !exists {
+		b.registers = append(b.registers, cmd.register)
+	}
+	b.bits[cmd.register] |= cmd.bits
+	b.off[cmd.register] = b.off[cmd.register]&^cmd.bits | cmd.off
+}
+
+// start runs the worker
+func (lc *Panel) start() {
+	lc.commands = make(chan command, ledQueue)
+	lc.done = make(chan struct{})
+	lc.written = make(map[byte]byte)
+	go lc.run()
+}
+
+// stop writes the queued changes and ends the worker
+func (lc *Panel) stop() {
+	lc.mutex.Lock()
+	if lc.commands == nil || lc.closed {
+		lc.mutex.Unlock()
+		return
+	}
+	lc.closed = true
+	close(lc.commands)
+	lc.mutex.Unlock()
+	<-lc.done
+}
+
+// send queues a command for the worker
+func (lc *Panel) send(cmd command) error {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+
+	if lc.closed {
+		return errClosed
+	}
+	lc.commands <- cmd
+	return nil
+}
+
+// Flush waits until every change made before is written to the ports and
+// returns the first write error since the last Flush. Switching LEDs does
+// not wait for the write, so tests flush before looking at the ports.
+func (lc *Panel) Flush() error {
+	if !lc.portPerms {
+		return nil
+	}
+	result := make(chan error, 1)
+	if err := lc.send(command{flush: result}); err != nil {
+		return err
+	}
+	return <-result
+}
+
+// run carries out the commands until the channel is closed. It is the only
+// goroutine touching the ports, so selecting a register and writing its
+// value cannot be interleaved by another caller.
+func (lc *Panel) run() {
+	defer close(lc.done)
+
+	pending := newBatch()
+	var tick <-chan time.Time
+	var failed error
+	write := func() {
+		if err := lc.write(pending); err != nil {
+			lc.logger.WithError(err).Warn("Failed to switch LEDs")
+			if failed == nil {
+				failed = err
+			}
+		}
+		pending = newBatch()
+		tick = nil
+	}
+
+	for {
+		select {
+		case cmd, ok := <-lc.commands:
+			if !ok {
+				write()
+				return
+			}
+			switch {
+			case cmd.flush != nil:
+				write()
+				cmd.flush <- failed
+				failed = nil
+			case cmd.read != nil:
+				write()
+				cmd.read <- lc.readStates()
+			default:
+				pending.add(cmd)
+				if tick == nil {
+					tick = time.After(ledTick)
+				}
+			}
+		case <-tick:
+			write()
+		}
+	}
+}
+
+// lockPorts takes the EC port lock shared with other tools, if there is
+// one, and returns its release
+func (lc *Panel) lockPorts() (func(), error) {
+	if lc.lock == nil {
+		return func() {}, nil
+	}
+	if err := lc.lock.Lock(portLockTimeout); err != nil {
+		return nil, err
+	}
+	return func() { lc.lock.Unlock() }, nil
+}
+
+// write updates each register changed in the batch with one read and at
+// most one write, read back to check it, while holding the EC port lock
+func (lc *Panel) write(b *batch) error {
+	if len(b.registers) == 0 {
+		return nil
+	}
+	unlock, err := lc.lockPorts()
+	if err != nil {
+		return fmt.Errorf("failed to lock LED ports: %w", err)
+	}
+	defer unlock()
+
+	var errs []error
+	for _, register := range b.registers {
+		currentMask, err := lc.readPort(register)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read port 0x%x: %w", register, err))
+			continue
+		}
+		if last, ok := lc.written[register]; ok && (currentMask^last)&registerLEDs(register) != 0 {
+			lc.logger.WithFields(logrus.Fields{
+				"port":     fmt.Sprintf("0x%x", register),
+				"expected": fmt.Sprintf("0x%x", last),
+				"found":    fmt.Sprintf("0x%x", currentMask),
+			}).Info("LED port changed by another tool")
+		}
+
+		// QNAP LEDs are inverted - set bit means OFF
+		mask := currentMask&^b.bits[register] | b.off[register]
+		if mask == currentMask {
+			lc.written[register] = currentMask
+			continue
+		}
+		if err := lc.writeChecked(register, b.bits[register], b.off[register], mask); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lc.logger.WithFields(logrus.Fields{
+			"port":     fmt.Sprintf("0x%x", register),
+			"old_mask": fmt.Sprintf("0x%x", currentMask),
+			"new_mask": fmt.Sprintf("0x%x", mask),
+		}).Debug("Updated LED port")
+	}
+	return errors.Join(errs...)
+}
+
+// writeChecked writes mask to register and reads it back. If another tool
+// switched the LEDs in bits in between, the clash is logged and the change
+// is written again on top of what the register holds, up to writeRetries
+// times.
+func (lc *Panel) writeChecked(register, bits, off, mask byte) error {
+	for attempt := 0; ; attempt++ {
+		if err := lc.writePort(register, mask); err != nil {
+			return fmt.Errorf("failed to write port 0x%x: %w", register, err)
+		}
+		readBack, err := lc.readPort(register)
+		if err != nil {
+			return fmt.Errorf("failed to read back port 0x%x: %w", register, err)
+		}
+		lc.written[register] = readBack
+		if (readBack^mask)&bits == 0 {
+			return nil
+		}
+
+		lc.logger.WithFields(logrus.Fields{
+			"port":    fmt.Sprintf("0x%x", register),
+			"written": fmt.Sprintf("0x%x", mask),
+			"read":    fmt.Sprintf("0x%x", readBack),
+			"attempt": attempt + 1,
+		}).Warn("LED port changed by another tool while switching LEDs")
+		if attempt == writeRetries {
+			return fmt.Errorf("port 0x%x reads 0x%x after writing 0x%x", register, readBack, mask)
+		}
+		mask = readBack&^bits | off
+	}
+}
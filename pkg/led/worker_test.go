@@ -0,0 +1,245 @@
+package led
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePorts emulates the register and value ports, with every LED off
+type fakePorts struct {
+	mutex     sync.Mutex
+	selected  byte
+	registers map[byte]byte
+	writes    map[byte]int
+	failWrite error
+	// clash flips these bits of a register after each of the next clashes
+	// writes to it, like another tool writing in between
+	clash   map[byte]byte
+	clashes int
+}
+
+func newFakePorts() *fakePorts {
+	return &fakePorts{
+		registers: map[byte]byte{0x91: 0xff, 0x81: 0xff, 0xe1: 0xff},
+		writes:    make(map[byte]int),
+	}
+}
+
+func (f *fakePorts) ReadPort(port uint16) (byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.registers[f.selected], nil
+}
+
+func (f *fakePorts) WritePort(port uint16, value byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if port == regPort {
+		f.selected = value
+		return nil
+	}
+	if f.failWrite != nil {
+		return f.failWrite
+	}
+	f.registers[f.selected] = value
+	f.writes[f.selected]++
+	if bits, ok := f.clash[f.selected]; ok && f.clashes > 0 {
+		f.registers[f.selected] ^= bits
+		f.clashes--
+	}
+	return nil
+}
+
+func (f *fakePorts) Close() error { return nil }
+
+// clashWith makes another tool flip bits of register after our next writes
+func (f *fakePorts) clashWith(register, bits byte, writes int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.clash = map[byte]byte{register: bits}
+	f.clashes = writes
+}
+
+func (f *fakePorts) register(register byte) (byte, int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.registers[register], f.writes[register]
+}
+
+// fakeLock counts how often the EC port lock is taken
+type fakeLock struct {
+	mutex sync.Mutex
+	locks int
+	held  bool
+	fail  error
+}
+
+func (f *fakeLock) Lock(timeout time.Duration) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.fail != nil {
+		return f.fail
+	}
+	f.locks++
+	f.held = true
+	return nil
+}
+
+func (f *fakeLock) Unlock() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.held = false
+	return nil
+}
+
+func (f *fakeLock) Close() error { return nil }
+
+func (f *fakeLock) state() (int, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.locks, f.held
+}
+
+// openFake returns a panel on fake ports
+func openFake(t *testing.T) (*Panel, *fakePorts) {
+	t.Helper()
+
+	fake := newFakePorts()
+	panel := &Panel{logger: logrus.WithField("component", "led_controller"), portPerms: true, devPort: fake}
+	panel.start()
+	t.Cleanup(func() { panel.stop() })
+	return panel, fake
+}
+
+func TestPanel_Flush(t *testing.T) {
+	panel, fake := openFake(t)
+
+	require.NoError(t, panel.SetLED(Disk2, true))
+	require.NoError(t, panel.SetStatusLED(false, true))
+	require.NoError(t, panel.Flush())
+
+	disks, _ := fake.register(0x81)
+	assert.Equal(t, byte(0xfd), disks)
+	status, _ := fake.register(0x91)
+	assert.Equal(t, byte(0xfb), status)
+
+	assert.Error(t, panel.SetLED(LED(42), true))
+}
+
+func TestPanel_Batching(t *testing.T) {
+	panel, fake := openFake(t)
+
+	// An animation step switching every disk LED one by one writes the
+	// register once, with the last state of each LED
+	for i := 0; i < 3; i++ {
+		for disk := Disk1; disk <= Disk6; disk++ {
+			require.NoError(t, panel.SetLED(disk, i%2 == 0))
+		}
+	}
+	require.NoError(t, panel.SetDiskLEDs(map[int]bool{6: false}))
+	require.NoError(t, panel.Flush())
+
+	disks, writes := fake.register(0x81)
+	assert.Equal(t, byte(0xe0), disks)
+	assert.Equal(t, 1, writes)
+}
+
+func TestPanel_GetLEDStates(t *testing.T) {
+	panel, _ := openFake(t)
+
+	// Reads see the changes made before them without a flush
+	require.NoError(t, panel.SetLED(USB, true))
+	require.NoError(t, panel.SetLED(USB, false))
+	require.NoError(t, panel.SetLED(Disk3, true))
+	states, err := panel.GetLEDStates()
+	require.NoError(t, err)
+	assert.False(t, states[USB])
+	assert.True(t, states[Disk3])
+	assert.False(t, states[StatusRed])
+}
+
+func TestPanel_WriteError(t *testing.T) {
+	panel, fake := openFake(t)
+	fake.failWrite = errors.New("EIO")
+
+	require.NoError(t, panel.SetLED(USB, true), "write errors are not known yet")
+	assert.Error(t, panel.Flush())
+	assert.NoError(t, panel.Flush(), "each error is returned once")
+}
+
+func TestPanel_Close(t *testing.T) {
+	panel, fake := openFake(t)
+
+	require.NoError(t, panel.SetLED(USB, true))
+	require.NoError(t, panel.Close())
+	usb, _ := fake.register(0xe1)
+	assert.Equal(t, byte(0x7f), usb, "queued changes are written on close")
+
+	panel.portPerms = true
+	assert.Error(t, panel.SetLED(USB, false))
+	assert.Error(t, panel.Flush())
+}
+
+func TestPanel_Clash(t *testing.T) {
+	panel, fake := openFake(t)
+
+	// Another tool switches disk 2 back off right after our write; reading
+	// the register back notices and writes it once more
+	fake.clashWith(0x81, 0x02, 1)
+	require.NoError(t, panel.SetLED(Disk2, true))
+	require.NoError(t, panel.Flush())
+	disks, writes := fake.register(0x81)
+	assert.Equal(t, byte(0xfd), disks)
+	assert.Equal(t, 2, writes)
+
+	// Its changes to other LEDs are kept
+	fake.clashWith(0x81, 0x01, 1)
+	require.NoError(t, panel.SetLED(Disk3, true))
+	require.NoError(t, panel.Flush())
+	disks, writes = fake.register(0x81)
+	assert.Equal(t, byte(0xf8), disks)
+	assert.Equal(t, 3, writes)
+
+	// A tool that keeps writing wins after the retry
+	fake.clashWith(0x81, 0x08, 2)
+	require.NoError(t, panel.SetLED(Disk4, true))
+	assert.Error(t, panel.Flush())
+	disks, _ = fake.register(0x81)
+	assert.Equal(t, byte(0xf8), disks)
+}
+
+func TestPanel_PortLock(t *testing.T) {
+	panel, fake := openFake(t)
+	lock := &fakeLock{}
+	panel.lock = lock
+
+	require.NoError(t, panel.SetLED(USB, true))
+	require.NoError(t, panel.Flush())
+	_, err := panel.GetLEDStates()
+	require.NoError(t, err)
+	locks, held := lock.state()
+	assert.Equal(t, 2, locks, "one lock for the write and one for the read")
+	assert.False(t, held)
+
+	lock.mutex.Lock()
+	lock.fail = errors.New("EC ports locked by another tool")
+	lock.mutex.Unlock()
+	require.NoError(t, panel.SetLED(USB, false))
+	assert.Error(t, panel.Flush())
+	usb, writes := fake.register(0xe1)
+	assert.Equal(t, byte(0x7f), usb, "nothing is written without the lock")
+	assert.Equal(t, 1, writes)
+}
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/alert"
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/menu"
+	"github.com/qnap/display-control/internal/prompt"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/qnap/display-control/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// settleQuiet is how long the panel must stay unchanged before a frame
+	// is taken; the controller reads the panel every 50ms
+	settleQuiet = 300 * time.Millisecond
+	// settleTimeout bounds the wait for a panel that keeps changing
+	settleTimeout = 5 * time.Second
+)
+
+// panelDaemon is the service stack the daemon builds, on top of a simulated
+// front panel: the real display controller speaking the serial protocol,
+// the screen manager with its layers, alerts, prompts and the menu, with
+// button presses dispatched in the daemon's order
+type panelDaemon struct {
+	t          *testing.T
+	panel      *controller.PanelSimulator
+	display    *controller.DisplayController
+	screens    *screen.ScreenManager
+	alerts     *alert.Manager
+	prompter   *prompt.Prompter
+	menu       *menu.MenuSystem
+	transcript strings.Builder
+}
+
+// bootDaemon starts the stack with cfg and waits for the menu to show
+func bootDaemon(t *testing.T, cfg *config.Config) *panelDaemon {
+	t.Helper()
+
+	d := &panelDaemon{t: t, panel: controller.NewPanelSimulator()}
+	display, err := controller.NewDisplayControllerWithPort(cfg, d.panel)
+	require.NoError(t, err)
+	d.display = display
+	t.Cleanup(func() { display.Close() })
+
+	d.screens = screen.NewScreenManager(display, cfg.Display.Width, cfg.Display.Height)
+	d.alerts = alert.NewManager(d.screens.Layer(screen.PriorityAlert))
+	d.prompter = prompt.NewPrompter(d.screens.Layer(screen.PriorityConfirmation))
+	d.menu = menu.NewMenuSystem(cfg, d.screens.Layer(screen.PriorityMenu))
+	d.menu.SetPrompter(d.prompter)
+	require.NoError(t, d.menu.Start())
+	t.Cleanup(d.menu.Stop)
+
+	// Like the daemon, the menu gets its presses in order on a goroutine of
+	// its own, since a prompt it opens waits for the following presses
+	type menuPress struct {
+		button  menu.Button
+		pressed bool
+	}
+	menuPresses := make(chan menuPress, 32)
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case press := <-menuPresses:
+				d.menu.HandleButtonEvent(press.button, press.pressed)
+			}
+		}
+	}()
+
+	display.SetButtonHandler(func(button controller.PanelButton, pressed bool) {
+		if d.alerts.HandleButton(button, pressed) || d.prompter.HandleButton(button, pressed) {
+			return
+		}
+		press := menuPress{menu.ButtonEnter, pressed}
+		switch button {
+		case controller.ButtonEnter:
+		case controller.ButtonSelect:
+			press.button = menu.ButtonSelect
+		default:
+			return
+		}
+		select {
+		case menuPresses <- press:
+		case <-done:
+		}
+	})
+
+	d.record("boot")
+	return d
+}
+
+// testConfig is the default configuration without hardware, animations or
+// shortcuts, whose multi-press windows would slow down every SELECT
+func testConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Display.IdleAnimation = ""
+	cfg.Display.IdleTimeout = 0
+	cfg.Menu.Shortcuts = nil
+	return cfg
+}
+
+// click presses and releases a button on the panel, waits for the screen to
+// settle and records it under step
+func (d *panelDaemon) click(button controller.PanelButton, step string) {
+	d.t.Helper()
+
+	require.NoError(d.t, d.panel.Press(button))
+	require.NoError(d.t, d.panel.Release(button))
+	d.record(step)
+}
+
+// record waits for the screen to settle and adds it to the transcript
+func (d *panelDaemon) record(step string) {
+	d.t.Helper()
+
+	d.settle()
+	d.transcript.WriteString(step + "\n" + testutil.Frame(d.panel.Lines()) + "\n")
+}
+
+// settle waits until the panel has shown the same screen for settleQuiet
+func (d *panelDaemon) settle() {
+	d.t.Helper()
+
+	deadline := time.Now().Add(settleTimeout)
+	frames := len(d.panel.Frames())
+	quietSince := time.Now()
+	for time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		if current := len(d.panel.Frames()); current != frames {
+			frames, quietSince = current, time.Now()
+			continue
+		}
+		if time.Since(quietSince) >= settleQuiet {
+			return
+		}
+	}
+	d.t.Fatalf("panel did not settle within %v", settleTimeout)
+}
+
+// checkGolden compares the transcript with testdata/<name>.golden
+func (d *panelDaemon) checkGolden(name string) {
+	d.t.Helper()
+	testutil.AssertGolden(d.t, name, d.transcript.String())
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qnap/display-control/internal/config"
+	"github.com/qnap/display-control/internal/controller"
+	"github.com/qnap/display-control/internal/screen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanelNavigation(t *testing.T) {
+	d := bootDaemon(t, testConfig())
+
+	d.click(controller.ButtonSelect, "select moves to the next item")
+	d.click(controller.ButtonEnter, "enter opens the Display submenu")
+	d.click(controller.ButtonSelect, "select moves within the submenu")
+	d.click(controller.ButtonSelect, "select moves to the Back item")
+	d.click(controller.ButtonEnter, "enter on Back returns to the main menu")
+	d.checkGolden("navigation")
+}
+
+func TestPanelConfirmation(t *testing.T) {
+	cfg := testConfig()
+	cfg.Menu.MainMenu.Items = map[string]config.MenuItem{
+		"hello": {Title: "Say Hello", Type: "command", Command: "echo hello", Confirm: "Say hello?"},
+		"quiet": {Title: "Stay Quiet", Type: "command", Command: "echo quiet", Confirm: "Run it?"},
+	}
+	d := bootDaemon(t, cfg)
+
+	d.click(controller.ButtonEnter, "enter asks first")
+	d.click(controller.ButtonSelect, "select moves to Yes")
+	d.click(controller.ButtonEnter, "yes runs the command")
+	d.click(controller.ButtonEnter, "any button returns to the menu")
+
+	d.click(controller.ButtonSelect, "select moves to the next item")
+	d.click(controller.ButtonEnter, "enter asks first")
+	d.click(controller.ButtonEnter, "no returns to the menu without running it")
+	d.checkGolden("confirmation")
+}
+
+func TestPanelCopyAndAlerts(t *testing.T) {
+	d := bootDaemon(t, testConfig())
+
+	// The copy screen covers the menu the way the copy routine draws it
+	copyScreen := d.screens.Layer(screen.PriorityCopy)
+	require.NoError(t, copyScreen.WriteText("Copying demo.iso\n"+controller.RenderProgressBar(0)))
+	d.record("copy starts above the menu")
+	require.NoError(t, copyScreen.WriteTextAt(controller.RenderProgressBar(50), 1, 0))
+	d.record("copy progress")
+
+	d.alerts.Raise("rack/temperature", "Temp high\n41.2C")
+	d.record("an alert covers the copy")
+	require.NoError(t, copyScreen.WriteTextAt(controller.RenderProgressBar(100), 1, 0))
+	d.record("progress under the alert is not shown")
+	d.click(controller.ButtonEnter, "any button acknowledges the alert")
+
+	// The result is a prompt dismissed by any button, as after a real copy
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := d.prompter.Prompt(ctx, "USB Copy\nCopy complete")
+		result <- err
+	}()
+	d.record("copy result")
+	require.NoError(t, copyScreen.Release())
+	d.click(controller.ButtonSelect, "any button dismisses the result")
+	assert.NoError(t, <-result)
+
+	d.alerts.Clear("rack/temperature")
+	d.record("clearing the acknowledged alert leaves the menu")
+	assert.Empty(t, d.alerts.Active())
+	d.checkGolden("copy_and_alerts")
+}